@@ -3,6 +3,7 @@ module github.com/damianoneill/net
 go 1.18
 
 require (
+	github.com/damianoneill/net/v2 v2.0.0
 	github.com/git-chglog/git-chglog v0.15.4
 	github.com/google/addlicense v1.1.1
 	github.com/google/uuid v1.5.0
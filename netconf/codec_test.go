@@ -1,7 +1,9 @@
 package netconf
 
 import (
+	"context"
 	"errors"
+	"io"
 	"testing"
 
 	mocks "github.com/damianoneill/net/netconf/mocks"
@@ -9,16 +11,13 @@ import (
 	assert "github.com/stretchr/testify/require"
 )
 
-type testStr struct {
-	Field string
-}
-
 func TestEncoderFailures(t *testing.T) {
-	// Failure on write of message
+	// Failure on write of hello message
 	mockt := &mocks.Transport{}
 	mockt.On("Write", mock.Anything).Return(0, errors.New("failed"))
-	enc := newEncoder(mockt)
-	err := enc.encode(&testStr{})
+	mockt.On("Read", mock.Anything).Return(0, io.EOF)
+	mockt.On("Close").Return(nil)
+	_, err := NewSession(context.Background(), mockt, defaultConfig)
 	assert.Error(t, err, "Expect failure")
 
 	// Failure on write of message delimiter
@@ -27,7 +26,8 @@ func TestEncoderFailures(t *testing.T) {
 		return len(buf)
 	}, nil).Once()
 	mockt.On("Write", mock.Anything).Return(0, errors.New("failed"))
-	enc = newEncoder(mockt)
-	err = enc.encode(&testStr{})
+	mockt.On("Read", mock.Anything).Return(0, io.EOF)
+	mockt.On("Close").Return(nil)
+	_, err = NewSession(context.Background(), mockt, defaultConfig)
 	assert.Error(t, err, "Expect failure")
 }
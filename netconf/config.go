@@ -1,5 +1,9 @@
 package netconf
 
+import (
+	"github.com/damianoneill/net/v2/netconf/client"
+)
+
 // Defines structs describing netconf configuration.
 
 // ClientConfig defines properties that configure netconf session behaviour.
@@ -11,3 +15,11 @@ type ClientConfig struct {
 var defaultConfig = &ClientConfig{
 	setupTimeoutSecs: 5,
 }
+
+// Delivers the equivalent v2 configuration.
+func (c *ClientConfig) v2() *client.Config {
+	if c == nil {
+		c = defaultConfig
+	}
+	return &client.Config{SetupTimeoutSecs: c.setupTimeoutSecs}
+}
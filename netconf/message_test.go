@@ -45,6 +45,19 @@ func TestExecute(t *testing.T) {
 	assert.Equal(t, "<response/>", sh.LastReq().Body, "Expected request body")
 }
 
+func TestAsV2(t *testing.T) {
+	ncs := newNCClientSession(t, NewTestNetconfServer(t))
+	defer ncs.Close()
+
+	v2, ok := AsV2(ncs)
+	assert.True(t, ok, "Expecting v2 session")
+	assert.Equal(t, ncs.ID(), v2.ID(), "Expecting same session")
+
+	reply, err := v2.Execute(`<get><response/></get>`)
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><response/></data>`, reply.Data, "Reply should contain response data")
+}
+
 func TestExecuteWithFailingRequest(t *testing.T) {
 	ncs := newNCClientSession(t, NewTestNetconfServer(t).WithRequestHandler(FailingRequestHandler))
 	defer ncs.Close()
@@ -73,7 +86,7 @@ func TestExecuteFailure(t *testing.T) {
 func TestNewSessionWithEndOfMessageEncoding(t *testing.T) {
 	ncs := newNCClientSession(t, NewTestNetconfServer(t).WithCapabilities([]string{CapBase10}))
 
-	assert.False(t, peerSupportsChunkedFraming(ncs.(*sesImpl).hello.Capabilities), "Server not expected to support chunked framing")
+	assert.NotContains(t, ncs.ServerCapabilities(), CapBase11, "Server not expected to support chunked framing")
 
 	reply, _ := ncs.Execute(Request(`<get><response/></get>`))
 	assert.NotNil(t, reply, "Reply should be non-nil")
@@ -129,7 +142,11 @@ func TestExecuteAsyncInterrupted(t *testing.T) {
 
 func TestSubscribe(t *testing.T) {
 	ts := NewTestNetconfServer(t)
-	ncs := newNCClientSession(t, ts)
+	var dropCount uint64
+	ctx := WithClientTrace(context.Background(), &ClientTrace{
+		NotificationDropped: func(m *Notification) { atomic.AddUint64(&dropCount, 1) },
+	})
+	ncs := newNCClientSessionWithContext(ctx, t, ts)
 	sh := ts.SessionHandler(ncs.ID())
 
 	nch := make(chan *Notification)
@@ -162,7 +179,7 @@ func TestSubscribe(t *testing.T) {
 	sh.SendNotification(notificationEvent())
 	sh.SendNotification(notificationEvent())
 	time.Sleep(time.Millisecond * time.Duration(500))
-	assert.Equal(t, uint64(2), atomic.LoadUint64(&(ncs.(*sesImpl).notificationDropCount)), "Expected notification to have been dropped")
+	assert.Equal(t, uint64(2), atomic.LoadUint64(&dropCount), "Expected notification to have been dropped")
 
 	ts.Close()
 	result = <-nch
@@ -246,13 +263,17 @@ func notificationEvent() string {
 }
 
 func newNCClientSession(t assert.TestingT, ts *TestNCServer) Session {
+	return newNCClientSessionWithContext(context.Background(), t, ts)
+}
+
+func newNCClientSessionWithContext(ctx context.Context, t assert.TestingT, ts *TestNCServer) Session {
 	serverAddress := fmt.Sprintf("localhost:%d", ts.Port())
 	sshConfig := &ssh.ClientConfig{
 		User:            TestUserName,
 		Auth:            []ssh.AuthMethod{ssh.Password(TestPassword)},
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
 	}
-	s, err := NewRPCSession(context.Background(), sshConfig, serverAddress)
+	s, err := NewRPCSession(ctx, sshConfig, serverAddress)
 	assert.NoError(t, err, "Failed to create session")
 	return s
 }
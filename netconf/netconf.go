@@ -1,8 +1,29 @@
-package netconf
-
 // The Network Configuration Protocol (NETCONF)
 // provides mechanisms to install, manipulate, and delete the
 // configuration of network devices.  It uses an Extensible Markup
 // Language (XML)-based data encoding for the configuration data as well
 // as the protocol messages.  The NETCONF protocol operations are
 // realised as remote procedure calls (RPCs).
+//
+// This package is a compatibility layer over github.com/damianoneill/net/v2/netconf, retained so that existing
+// clients continue to work; all behaviour is delegated to the v2 implementation.
+// See MIGRATION.md for details of migrating to v2.
+//
+// Deprecated: use github.com/damianoneill/net/v2/netconf/client.
+package netconf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MigrationGuide locates the guide describing migration from this package to v2.
+const MigrationGuide = "https://github.com/damianoneill/net/blob/master/netconf/MIGRATION.md"
+
+// ErrIncompatible is returned (wrapped) when legacy behaviour that cannot be supported by the v2 implementation
+// is requested.
+var ErrIncompatible = errors.New("not supported by the v2 compatibility layer, see " + MigrationGuide)
+
+func migrationError(feature string) error {
+	return fmt.Errorf("netconf %s: %w", feature, ErrIncompatible)
+}
@@ -2,12 +2,18 @@
 package rfc6242
 
 import (
+	"errors"
 	"io"
 	"strings"
 	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
 )
 
-var EOM = string(tokenEOM)
+var EOM = "]]>]]>"
+
+// The chunked framer, which is not exported by the v2 package.
+var decoderChunked = rfc6242.LookupFraming("chunked").Framer()
 
 func TestEOMDecoding(t *testing.T) {
 	type decresp struct {
@@ -310,3 +316,11 @@ func (t *transport) Write(inputs []string, shouldClose bool) {
 		}
 	}
 }
+
+func TestDecoderErrorAliases(t *testing.T) {
+	d := NewDecoder(strings.NewReader("\n#42949672978"), WithFramer(decoderChunked))
+	_, err := d.Read(make([]byte, 10))
+	if !errors.Is(err, ErrChunkSizeInvalid) {
+		t.Errorf("Decoder: error mismatch wanted %s got %s", ErrChunkSizeInvalid, err)
+	}
+}
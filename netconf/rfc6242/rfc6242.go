@@ -0,0 +1,68 @@
+// Package rfc6242 is a compatibility layer over github.com/damianoneill/net/v2/netconf/common/codec/rfc6242.
+//
+// Deprecated: use github.com/damianoneill/net/v2/netconf/common/codec/rfc6242.
+package rfc6242
+
+import (
+	"io"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
+)
+
+type (
+	// Decoder is an alias of the v2 rfc6242.Decoder.
+	Decoder = rfc6242.Decoder
+	// Encoder is an alias of the v2 rfc6242.Encoder.
+	Encoder = rfc6242.Encoder
+	// FramerFn is an alias of the v2 rfc6242.FramerFn.
+	FramerFn = rfc6242.FramerFn
+	// DecoderOption is an alias of the v2 rfc6242.DecoderOption.
+	DecoderOption = rfc6242.DecoderOption
+	// EncoderOption is an alias of the v2 rfc6242.EncoderOption.
+	EncoderOption = rfc6242.EncoderOption
+)
+
+var (
+	// ErrZeroChunks is a protocol error indicating that no chunk was seen prior to the end-of-chunks token.
+	ErrZeroChunks = rfc6242.ErrZeroChunks
+	// ErrChunkSizeInvalid is a protocol error indicating that a chunk frame introduction was seen, but
+	// chunk-size decoding failed.
+	ErrChunkSizeInvalid = rfc6242.ErrChunkSizeInvalid
+	// ErrChunkSizeTokenTooLong is a protocol error indicating a chunk size token was too long to be valid.
+	ErrChunkSizeTokenTooLong = rfc6242.ErrChunkSizeTokenTooLong
+	// ErrChunkSizeTooLarge is a protocol error indicating that the chunk size was larger than the maximum.
+	ErrChunkSizeTooLarge = rfc6242.ErrChunkSizeTooLarge
+)
+
+// DecoderMinScannerBufferSize is the scanner buffer size floor.
+const DecoderMinScannerBufferSize = rfc6242.DecoderMinScannerBufferSize
+
+// NewDecoder creates a new RFC6242 transport decoder reading from the input io.Reader.
+func NewDecoder(input io.Reader, options ...DecoderOption) *Decoder {
+	return rfc6242.NewDecoder(input, options...)
+}
+
+// NewEncoder creates a RFC6242 encoder writing to the output io.Writer.
+func NewEncoder(output io.Writer, opts ...EncoderOption) *Encoder {
+	return rfc6242.NewEncoder(output, opts...)
+}
+
+// WithScannerBufferSize sets the bufio.Scanner buffer size of the Decoder.
+func WithScannerBufferSize(bytes int) DecoderOption {
+	return rfc6242.WithScannerBufferSize(bytes)
+}
+
+// WithFramer sets the Decoder's framing function.
+func WithFramer(f FramerFn) DecoderOption {
+	return rfc6242.WithFramer(f)
+}
+
+// WithMaximumChunkSize sets the Encoder's maximum chunk size.
+func WithMaximumChunkSize(size uint32) EncoderOption {
+	return rfc6242.WithMaximumChunkSize(size)
+}
+
+// SetChunkedFraming enables chunked framing mode on any passed Decoder or Encoder.
+func SetChunkedFraming(objects ...interface{}) {
+	rfc6242.SetChunkedFraming(objects...)
+}
@@ -5,6 +5,9 @@ import (
 	"log"
 	"time"
 
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+
 	"github.com/imdario/mergo"
 	"golang.org/x/crypto/ssh"
 )
@@ -29,7 +32,44 @@ func ContextClientTrace(ctx context.Context) *ClientTrace {
 // the provided trace hooks
 func WithClientTrace(ctx context.Context, trace *ClientTrace) context.Context {
 	ctx = context.WithValue(ctx, clientEventContextKey{}, trace)
-	return ctx
+	// The hooks are invoked by the v2 implementation, so are also registered in their v2 form.
+	return client.WithClientTrace(ctx, trace.v2())
+}
+
+// Delivers the v2 equivalent of the trace hooks.
+// The legacy ConnectStart and ConnectDone hooks are mapped to the v2 DialStart and DialDone hooks, which
+// receive the ssh client configuration.
+func (t *ClientTrace) v2() *client.ClientTrace {
+	v2 := &client.ClientTrace{
+		DialStart:            t.ConnectStart,
+		DialDone:             t.ConnectDone,
+		HelloDone:            t.HelloDone,
+		ConnectionClosed:     t.ConnectionClosed,
+		ReadStart:            t.ReadStart,
+		ReadDone:             t.ReadDone,
+		WriteStart:           t.WriteStart,
+		WriteDone:            t.WriteDone,
+		Error:                t.Error,
+		NotificationReceived: t.NotificationReceived,
+		NotificationDropped:  t.NotificationDropped,
+	}
+	if t.ExecuteStart != nil {
+		v2.ExecuteStart = func(req common.Request, async bool) {
+			t.ExecuteStart(legacyRequest(req), async)
+		}
+	}
+	if t.ExecuteDone != nil {
+		v2.ExecuteDone = func(req common.Request, async bool, res *common.RPCReply, err error, d time.Duration) {
+			t.ExecuteDone(legacyRequest(req), async, res, err, d)
+		}
+	}
+	return v2
+}
+
+// Requests are always passed to the v2 implementation as strings.
+func legacyRequest(req common.Request) Request {
+	s, _ := req.(string)
+	return Request(s)
 }
 
 // ClientTrace defines a structure for handling trace events
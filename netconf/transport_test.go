@@ -44,6 +44,13 @@ func TestFailingConnection(t *testing.T) {
 	assert.Nil(t, tr, "Transport should not be defined")
 }
 
+func TestUnsupportedSubsystem(t *testing.T) {
+	tr, err := NewSSHTransport(context.Background(), &ssh.ClientConfig{}, "localhost:0", "cli")
+	assert.ErrorIs(t, err, ErrIncompatible)
+	assert.Contains(t, err.Error(), MigrationGuide)
+	assert.Nil(t, tr, "Transport should not be defined")
+}
+
 func TestWriteRead(t *testing.T) {
 	ts := testutil.NewSSHServer(t, "testUser", "testPassword")
 	defer ts.Close()
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"github.com/pkg/errors"
+)
+
+// BatchSender is implemented by sessions that send a batch of commands themselves, for example so that no other
+// commands are sent during the batch.
+type BatchSender interface {
+	// SendBatch sends the commands as described by the SendBatch function.
+	SendBatch(cmds []string, opts ...BatchOption) ([]BatchResult, error)
+}
+
+// BatchOption implements options for configuring SendBatch behaviour.
+type BatchOption func(*batchConfig)
+
+// WithBatchSendOptions defines the options applied to the send of each command in the batch.
+func WithBatchSendOptions(opts ...SendOption) BatchOption {
+	return func(c *batchConfig) {
+		c.sendOpts = opts
+	}
+}
+
+// AbortOnCommandError stops the batch at the first command whose response matches one of the session error
+// patterns, so that the remaining commands are not sent.
+// Default is to send all commands, recording any *CommandError in the result of the command.
+func AbortOnCommandError() BatchOption {
+	return func(c *batchConfig) {
+		c.abortOnCommandError = true
+	}
+}
+
+type batchConfig struct {
+	sendOpts            []SendOption
+	abortOnCommandError bool
+}
+
+// BatchResult defines the outcome of a command sent by SendBatch.
+type BatchResult struct {
+	// The command that was sent.
+	Command string
+	// The response to the command.
+	Output string
+	// The error returned by the send of the command, if any.
+	Err error
+}
+
+// SendBatch sends the commands in order on s, as described by Session.Send, and delivers the results of the commands
+// that were sent, in order, writing each command as soon as the prompt ending the response to the previous command is
+// detected. The behaviour can be modified by opts - see BatchOption variants. If s implements BatchSender, the batch
+// is sent by s.
+// A failure other than a *CommandError or *OutputLimitError, such as the loss of the connection, stops the batch,
+// as does a *CommandError if AbortOnCommandError is specified; the error is returned along with the results of the
+// commands sent, the last of which is the failed command.
+func SendBatch(s Session, cmds []string, opts ...BatchOption) ([]BatchResult, error) {
+	if bs, ok := s.(BatchSender); ok {
+		return bs.SendBatch(cmds, opts...)
+	}
+	return sendBatch(s.Send, cmds, opts...)
+}
+
+// Sends the commands in order using send, as described by SendBatch.
+func sendBatch(send func(string, ...SendOption) (string, error), cmds []string, opts ...BatchOption,
+) ([]BatchResult, error) {
+	cfg := &batchConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	results := make([]BatchResult, 0, len(cmds))
+	for _, cmd := range cmds {
+		output, err := send(cmd, cfg.sendOpts...)
+		results = append(results, BatchResult{Command: cmd, Output: output, Err: err})
+		if err == nil {
+			continue
+		}
+		var cmdErr *CommandError
+		var limitErr *OutputLimitError
+		if errors.As(err, &cmdErr) {
+			if cfg.abortOnCommandError {
+				return results, err
+			}
+		} else if !errors.As(err, &limitErr) {
+			return results, err
+		}
+	}
+	return results, nil
+}
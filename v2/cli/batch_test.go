@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSendBatch(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithErrorPatterns(`^GOT:bad`))
+	assert.NoError(t, err)
+	defer session.Close()
+	s := session
+
+	results, err := SendBatch(s, []string{"first", "bad command", "last"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, BatchResult{Command: "first", Output: "GOT:first\n"}, results[0])
+	assert.Equal(t, "GOT:bad command\n", results[1].Output)
+	assert.IsType(t, &CommandError{}, results[1].Err, "Expecting the command error to be recorded")
+	assert.Equal(t, BatchResult{Command: "last", Output: "GOT:last\n"}, results[2])
+
+	results, err = SendBatch(s, []string{"first", "bad command", "last"}, AbortOnCommandError())
+	assert.IsType(t, &CommandError{}, err)
+	assert.Len(t, results, 2, "Expecting the batch to stop at the failed command")
+	assert.Equal(t, err, results[1].Err)
+
+	results, err = SendBatch(s, []string{"bad command"}, AbortOnCommandError(), WithBatchSendOptions(IgnoreErrors()))
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchResult{{Command: "bad command", Output: "GOT:bad command\n"}}, results)
+
+	// The session remains usable after a batch.
+	resp, err := s.Send("Command")
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:Command\n", resp)
+}
+
+func TestSendBatchClosed(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	_ = session.Close()
+
+	results, err := SendBatch(session, []string{"first", "second"})
+	assert.Error(t, err)
+	assert.Len(t, results, 1, "Expecting the batch to stop once the session fails")
+}
@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"log"
+)
+
+// WithDryRun configures the session so that commands matching any of the patterns, regular expressions identifying
+// commands that change the device, such as `^configure` or `^(no )?interface`, are passed to recorder rather than
+// being sent, and Send and Exec return an empty response. All other commands are sent, so that automation can be
+// validated against a device without changing it. Profile and initialisation commands are subject to the patterns.
+// If recorder is nil, suppressed commands are logged.
+// Default is to send all commands.
+func WithDryRun(recorder func(command string), patterns ...string) SessionOption {
+	return func(c *SessionConfig) {
+		if recorder == nil {
+			recorder = func(command string) {
+				log.Printf("DryRun command:%s\n", command)
+			}
+		}
+		c.dryRun = recorder
+		c.dryRunPatterns = patterns
+	}
+}
+
+// Determines whether the command is suppressed by WithDryRun, recording it if so.
+func (s *SessionImpl) suppressed(command string) bool {
+	for _, re := range s.dryRunPatterns {
+		if re.MatchString(command) {
+			s.cfg.dryRun(command)
+			return true
+		}
+	}
+	return false
+}
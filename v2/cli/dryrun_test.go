@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSessionDryRun(t *testing.T) {
+	dummySh, ts := dummyServerWithExec(t)
+	defer ts.Close()
+
+	var suppressed []string
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithDryRun(func(command string) { suppressed = append(suppressed, command) }, `^configure`, `^(no )?interface`))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	resp, err := session.Send("show version")
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:show version\n", resp)
+
+	for _, cmd := range []string{"configure terminal", "interface ge-0/0/1", "no interface ge-0/0/2"} {
+		resp, err = session.Send(cmd)
+		assert.NoError(t, err)
+		assert.Empty(t, resp)
+	}
+	resp, err = session.Exec("configure private")
+	assert.NoError(t, err)
+	assert.Empty(t, resp)
+
+	assert.Equal(t, []string{"configure terminal", "interface ge-0/0/1", "no interface ge-0/0/2", "configure private"},
+		suppressed)
+	assert.Equal(t, []string{"show version\n"}, dummySh.lines, "Expecting suppressed commands not to be sent")
+}
+
+func TestSessionInvalidDryRunPattern(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	_, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithDryRun(nil, `(`))
+	assert.ErrorContains(t, err, "invalid dry-run pattern")
+}
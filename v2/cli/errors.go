@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CommandError is returned by Send when the response to a command matches one of the session error patterns.
+type CommandError struct {
+	// The command that was sent.
+	Command string
+	// The response line that matched the error pattern.
+	Line string
+	// The error pattern that was matched.
+	Pattern string
+	// The complete response to the command.
+	Output string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command %q rejected: %s", e.Command, e.Line)
+}
+
+// ConnectError is returned by SessionFactory.NewSession when a session could not be established after the attempts
+// permitted by WithConnectRetries.
+type ConnectError struct {
+	// The target of the connection attempts.
+	Target string
+	// The errors reported by each attempt, in order.
+	Attempts []error
+}
+
+func (e *ConnectError) Error() string {
+	msgs := make([]string, len(e.Attempts))
+	for i, err := range e.Attempts {
+		msgs[i] = fmt.Sprintf("attempt %d: %v", i+1, err)
+	}
+	return fmt.Sprintf("failed to connect to %s after %d attempts: %s", e.Target, len(e.Attempts), strings.Join(msgs, "; "))
+}
+
+// Unwrap delivers the error reported by the last attempt.
+func (e *ConnectError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1]
+}
+
+// Identifies a failure to establish the transport of a session.
+type transportError struct{ error }
+
+func (e *transportError) Unwrap() error { return e.error }
+
+func (e *transportError) Cause() error { return e.error }
+
+// Identifies a failure to capture the cli prompt at session startup.
+type promptError struct{ error }
+
+func (e *promptError) Unwrap() error { return e.error }
+
+func (e *promptError) Cause() error { return e.error }
+
+// The message reported by the ssh package when the server rejects every authentication method.
+const sshAuthFailure = "ssh: unable to authenticate"
+
+// Determines whether a failure to establish a session may be resolved by another attempt. Authentication failures
+// are permanent, as repeated attempts may lock out the account.
+func isRetryable(err error) bool {
+	var terr *transportError
+	var perr *promptError
+	if errors.As(err, &terr) {
+		return !strings.Contains(terr.Error(), sshAuthFailure)
+	}
+	return errors.As(err, &perr)
+}
+
+// CommonErrorPatterns defines error patterns emitted by commonly deployed device cli implementations.
+var CommonErrorPatterns = []string{
+	`^\s*% ?Invalid input`,
+	`^\s*% ?Incomplete command`,
+	`^\s*% ?Ambiguous command`,
+	`^\s*% ?Unknown command`,
+	`(?i)syntax error`,
+	`^\s*(?i)error:`,
+}
+
+// Compiles the error or dry-run patterns defined in the session configuration.
+func compileErrorPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled[i] = re
+	}
+	return compiled, nil
+}
+
+// Checks the response to a command against the error patterns, returning a CommandError for the first
+// matching line.
+func checkResponse(command, response string, patterns []*regexp.Regexp) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	for _, line := range strings.Split(response, "\n") {
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				return &CommandError{Command: strings.TrimSpace(command), Line: line, Pattern: re.String(), Output: response}
+			}
+		}
+	}
+	return nil
+}
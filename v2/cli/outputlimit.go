@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// The length beyond which an unterminated line is committed to a limited response, so that the line does not grow
+// without bound; the end of a response is identified within the remainder.
+const maxUnterminatedLine = 4096
+
+// OutputTruncatedMarker is appended to a response that has been truncated because it exceeded an output limit.
+const OutputTruncatedMarker = "\n... output truncated ..."
+
+// OutputLimitError is returned by Send, along with the truncated response, when the response to a command exceeds
+// an output limit defined by WithOutputLimits or MaxOutput.
+type OutputLimitError struct {
+	// The command that was sent.
+	Command string
+	// The limit that was exceeded, in bytes.
+	Limit int64
+	// Indicates that the limit exceeded was the session limit, rather than the limit for a single send.
+	Session bool
+}
+
+func (e *OutputLimitError) Error() string {
+	scope := "send"
+	if e.Session {
+		scope = "session"
+	}
+	return fmt.Sprintf("output of command %q exceeded the %s limit of %d bytes", e.Command, scope, e.Limit)
+}
+
+// WithOutputLimits defines the maximum number of bytes of output retained for the response to a single send, and
+// the maximum number retained across all sends on the session, so that a runaway command, such as debug all, cannot
+// exhaust memory; a value of zero or less removes the limit.
+// When a response exceeds a limit, the session continues to read, discarding output, until the end of the response
+// is detected, and Send returns the response truncated to the limit, followed by OutputTruncatedMarker, along with
+// an *OutputLimitError. Alternatively, OverflowTo streams the excess output to a writer.
+// Limits apply to responses read from the interactive shell, but not to commands run on an exec channel.
+// Default is no limits.
+func WithOutputLimits(perSend, perSession int64) SessionOption {
+	return func(c *SessionConfig) {
+		c.maxSendOutput = perSend
+		c.maxSessionOutput = perSession
+	}
+}
+
+// MaxOutput overrides the limit defined by WithOutputLimits on the output retained for the response to the send; a
+// value of zero or less removes the limit. The session limit continues to apply.
+func MaxOutput(limit int64) SendOption {
+	return func(c *SendConfig) {
+		c.maxOutput = limit
+		c.maxOutputSet = true
+	}
+}
+
+// OverflowTo writes the output that exceeds an output limit to w, rather than truncating the response, in which case
+// Send returns the output up to the limit without error, unless writing to w fails. The end of the response, as
+// identified by the prompt or WaitFor, is not written to w.
+func OverflowTo(w io.Writer) SendOption {
+	return func(c *SendConfig) {
+		c.overflow = w
+	}
+}
+
+// Defines how the output of a send is limited.
+type outputLimit struct {
+	// The number of bytes that may be retained, or -1 if unlimited.
+	limit int64
+	// Indicates that the limit is derived from the session limit.
+	session bool
+	// Receives output beyond the limit, nil if it is discarded.
+	overflow io.Writer
+}
+
+// Delivers the output limit applying to a send.
+func (s *SessionImpl) sendLimit(config *SendConfig) *outputLimit {
+	lim := &outputLimit{limit: -1, overflow: config.overflow}
+	perSend := s.cfg.maxSendOutput
+	if config.maxOutputSet {
+		perSend = config.maxOutput
+	}
+	if perSend > 0 {
+		lim.limit = perSend
+	}
+	if perSession := s.cfg.maxSessionOutput; perSession > 0 {
+		remaining := perSession - s.outputTotal
+		if remaining < 0 {
+			remaining = 0
+		}
+		if lim.limit < 0 || remaining < lim.limit {
+			lim.limit, lim.session = remaining, true
+		}
+	}
+	return lim
+}
+
+// Accumulates the response to a send, retaining output up to the limit.
+type limitedResponse struct {
+	lim *outputLimit
+	// The retained output, normalised to use newline line endings.
+	retained bytes.Buffer
+	// The normalised output following the last newline, which is matched against the sentinel.
+	line []byte
+	// A carriage return at the end of the last input, whose interpretation depends on the next input.
+	cr bool
+	// Indicates that output has exceeded the limit.
+	overflowed bool
+	// The first error reported writing to the overflow writer, if any.
+	err error
+}
+
+// Adds input to the response, delivering true if the sentinel matches the unterminated last line.
+func (r *limitedResponse) add(b []byte, sentinel *regexp.Regexp) bool {
+	if r.cr {
+		b = append([]byte("\r"), b...)
+	}
+	r.cr = len(b) > 0 && b[len(b)-1] == '\r'
+	if r.cr {
+		b = b[:len(b)-1]
+	}
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	b = bytes.ReplaceAll(b, []byte("\r"), []byte("\n"))
+
+	r.line = append(r.line, b...)
+	if nl := bytes.LastIndexByte(r.line, '\n'); nl >= 0 {
+		r.commit(r.line[:nl+1])
+		r.line = append([]byte(nil), r.line[nl+1:]...)
+	} else if r.lim.limit >= 0 && len(r.line) > maxUnterminatedLine {
+		excess := len(r.line) - maxUnterminatedLine
+		r.commit(r.line[:excess])
+		r.line = append([]byte(nil), r.line[excess:]...)
+	}
+	return !r.cr && sentinel.Match(r.line)
+}
+
+// Commits output that precedes the last line, retaining it up to the limit.
+func (r *limitedResponse) commit(b []byte) {
+	if r.lim.limit >= 0 {
+		if room := r.lim.limit - int64(r.retained.Len()); int64(len(b)) > room {
+			r.overflowed = true
+			if r.lim.overflow != nil && r.err == nil {
+				_, r.err = r.lim.overflow.Write(b[room:])
+			}
+			b = b[:room]
+		}
+	}
+	r.retained.Write(b)
+}
+
+// Delivers the response, excluding the newline preceding the last line, and any error resulting from the limit.
+func (r *limitedResponse) response(command string) (string, error) {
+	b := r.retained.Bytes()
+	if !r.overflowed {
+		return string(bytes.TrimSuffix(b, []byte("\n"))), nil
+	}
+	if r.lim.overflow != nil {
+		return string(b), errors.Wrap(r.err, "failed to write overflow output")
+	}
+	err := &OutputLimitError{Command: command, Limit: r.lim.limit, Session: r.lim.session}
+	return string(b) + OutputTruncatedMarker, err
+}
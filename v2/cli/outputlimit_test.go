@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSessionSendOutputLimit(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithOutputLimits(20, 0))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	command := strings.Repeat("x", 100)
+	resp, err := session.Send(command)
+	var lerr *OutputLimitError
+	assert.True(t, errors.As(err, &lerr))
+	assert.Equal(t, &OutputLimitError{Command: command, Limit: 20}, lerr)
+	assert.Equal(t, "GOT:"+command[:16]+OutputTruncatedMarker, resp)
+
+	resp, err = session.Send("Command")
+	assert.NoError(t, err, "Expecting the session to remain in step following truncation")
+	assert.Equal(t, "GOT:Command\n", resp)
+
+	resp, err = session.Send(command, MaxOutput(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:"+command+"\n", resp)
+}
+
+func TestSessionSendOverflowTo(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	command := strings.Repeat("y", 100)
+	var overflow bytes.Buffer
+	resp, err := session.Send(command, MaxOutput(20), OverflowTo(&overflow))
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:"+command[:16], resp)
+	assert.Equal(t, command[16:]+"\n\n", overflow.String())
+}
+
+func TestSessionOutputLimit(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithOutputLimits(0, 30))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := session.Send("Command")
+		assert.NoError(t, err)
+		assert.Equal(t, "GOT:Command\n", resp)
+	}
+
+	resp, err := session.Send("Command")
+	var lerr *OutputLimitError
+	assert.True(t, errors.As(err, &lerr))
+	assert.Equal(t, &OutputLimitError{Command: "Command", Limit: 4, Session: true}, lerr)
+	assert.EqualError(t, err, `output of command "Command" exceeded the session limit of 4 bytes`)
+	assert.Equal(t, "GOT:"+OutputTruncatedMarker, resp)
+
+	resp, err = session.Send("Command", MaxOutput(0))
+	assert.True(t, errors.As(err, &lerr), "Expecting the session limit to apply")
+	assert.Equal(t, OutputTruncatedMarker, resp)
+}
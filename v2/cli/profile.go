@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Profile defines the shell initialisation applied to sessions with a type of device.
+type Profile struct {
+	// The device type identified by the profile, for example "cisco_ios".
+	Name string
+	// The commands executed once the cli prompt has been captured, before any defined by WithCommands; typically
+	// to disable paging and line wrapping.
+	Commands []string
+	// The environment variables requested when the session is established, before any defined by WithEnv.
+	Env map[string]string
+}
+
+// Preset profiles, registered by name.
+var (
+	// CiscoIOSProfile disables paging and line wrapping on Cisco IOS devices.
+	CiscoIOSProfile = Profile{Name: "cisco_ios", Commands: []string{"terminal length 0", "terminal width 0"}}
+	// JunosProfile disables paging and line wrapping on Juniper Junos devices.
+	JunosProfile = Profile{Name: "junos", Commands: []string{"set cli screen-length 0", "set cli screen-width 0"}}
+	// LinuxProfile disables paging and widens the terminal of Linux shells.
+	LinuxProfile = Profile{Name: "linux", Commands: []string{"stty cols 512"}, Env: map[string]string{"PAGER": "cat"}}
+)
+
+// The registered profiles, initially the presets.
+var (
+	profilesMu sync.RWMutex
+	profiles   = map[string]Profile{
+		CiscoIOSProfile.Name: CiscoIOSProfile,
+		JunosProfile.Name:    JunosProfile,
+		LinuxProfile.Name:    LinuxProfile,
+	}
+)
+
+// RegisterProfile registers the profile, so that it can be selected by WithDeviceType, replacing any profile
+// registered with the same name.
+func RegisterProfile(p Profile) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[p.Name] = p
+}
+
+// LookupProfile delivers the profile registered for the device type, and whether it was found.
+func LookupProfile(deviceType string) (Profile, bool) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	p, ok := profiles[deviceType]
+	return p, ok
+}
+
+// Resolves the profile selected by WithDeviceType, if any.
+func (c *SessionConfig) resolveProfile() error {
+	if c.deviceType == "" {
+		return nil
+	}
+	p, ok := LookupProfile(c.deviceType)
+	if !ok {
+		return errors.Errorf("unknown device type %q", c.deviceType)
+	}
+	c.profile = &p
+	return nil
+}
+
+// Delivers the environment variables requested by the session, those defined by WithEnv taking precedence over
+// those defined by the profile.
+func (c *SessionConfig) env() map[string]string {
+	if c.profile == nil || len(c.profile.Env) == 0 {
+		return c.envVars
+	}
+	env := map[string]string{}
+	for k, v := range c.profile.Env {
+		env[k] = v
+	}
+	for k, v := range c.envVars {
+		env[k] = v
+	}
+	return env
+}
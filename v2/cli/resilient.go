@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	perrors "github.com/pkg/errors"
+)
+
+// Dialer establishes a new session for a ResilientSession, typically by calling SessionFactory.NewSession, which
+// captures the prompt and executes the profile and initialization commands, such as those setting the terminal
+// length.
+type Dialer func(ctx context.Context) (Session, error)
+
+// ResumeGuard is called before a command that was interrupted by the loss of the connection is resent on the
+// re-established session s, and reports whether the command should be resent. A guard for a command that is not
+// idempotent may use s to determine whether the interrupted command took effect.
+type ResumeGuard func(s Session, command string) (bool, error)
+
+// ErrNotResumed is returned by ResilientSession when the ResumeGuard declines to resend an interrupted command.
+var ErrNotResumed = errors.New("cli command not resumed after reconnection")
+
+// ResilientSessionOption implements options for configuring a ResilientSession.
+type ResilientSessionOption func(*resilientConfig)
+
+// WithReconnectAttempts defines the number of attempts made to re-establish the session each time the connection is
+// lost while a command is in progress. The first attempt is made after the backoff delay, which is doubled for each
+// subsequent attempt. Defaults to 3 attempts, with a backoff of 1 second.
+func WithReconnectAttempts(attempts int, backoff time.Duration) ResilientSessionOption {
+	return func(c *resilientConfig) {
+		c.attempts = attempts
+		c.backoff = backoff
+	}
+}
+
+// WithResumeGuard defines the guard that determines whether an interrupted command is resent once the session has
+// been re-established. By default, all interrupted commands are resent.
+func WithResumeGuard(guard ResumeGuard) ResilientSessionOption {
+	return func(c *resilientConfig) {
+		c.guard = guard
+	}
+}
+
+// ReplayOnReconnect records the command, once sent successfully by a ResilientSession, so that it is replayed, with
+// the same options, whenever the session is re-established. It is used for commands that change the state that
+// determines the prompt, such as "enable" or "configure terminal". Ignored by other sessions.
+func ReplayOnReconnect() SendOption {
+	return func(c *SendConfig) {
+		c.replay = true
+	}
+}
+
+type resilientConfig struct {
+	attempts int
+	backoff  time.Duration
+	guard    ResumeGuard
+}
+
+// ResilientSession wraps the sessions delivered by a Dialer, so that long interactive workflows survive the loss of
+// the connection. When a command fails because the connection has been lost (the transport has reached EOF), the
+// session is re-established, the commands sent with ReplayOnReconnect are replayed, and the command is resent,
+// subject to the ResumeGuard.
+//
+// Commands are sent one at a time; a ResilientSession may be shared between goroutines.
+type ResilientSession struct {
+	ctx   context.Context
+	dial  Dialer
+	cfg   resilientConfig
+	trace *CliTrace
+
+	// Serialises commands.
+	sendMu sync.Mutex
+	// Guards the current session and closed state.
+	mu     sync.Mutex
+	s      Session
+	closed bool
+	// Commands sent with ReplayOnReconnect, in order.
+	replay []replayedCommand
+}
+
+type replayedCommand struct {
+	value string
+	opts  []SendOption
+}
+
+const (
+	defaultReconnectAttempts = 3
+	defaultReconnectBackoff  = time.Second
+)
+
+// NewResilientSession establishes the initial session using dial, configured by opts. The session is re-established
+// using dial with ctx, so ctx should remain valid for the lifetime of the session; trace hooks defined by
+// WithCliTrace on ctx are applied.
+func NewResilientSession(ctx context.Context, dial Dialer, opts ...ResilientSessionOption) (*ResilientSession, error) {
+	cfg := resilientConfig{
+		attempts: defaultReconnectAttempts,
+		backoff:  defaultReconnectBackoff,
+		guard:    func(Session, string) (bool, error) { return true, nil },
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ResilientSession{ctx: ctx, dial: dial, cfg: cfg, trace: ContextCliTrace(ctx), s: s}, nil
+}
+
+// Send sends the value as described by Session.Send, re-establishing the session and resending the value if the
+// connection is lost.
+func (rs *ResilientSession) Send(value string, opts ...SendOption) (string, error) {
+	rs.sendMu.Lock()
+	defer rs.sendMu.Unlock()
+	return rs.send(value, opts...)
+}
+
+// SendBatch sends the commands in order, as described by the SendBatch function, re-establishing the session and resending
+// the interrupted command if the connection is lost. No other commands are sent on the session during the batch.
+func (rs *ResilientSession) SendBatch(cmds []string, opts ...BatchOption) ([]BatchResult, error) {
+	rs.sendMu.Lock()
+	defer rs.sendMu.Unlock()
+	return sendBatch(rs.send, cmds, opts...)
+}
+
+// Sends the value on the current session, recording it for replay if requested; the caller holds sendMu.
+func (rs *ResilientSession) send(value string, opts ...SendOption) (string, error) {
+	config := &SendConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	response, err := rs.do(value, func(s Session) (string, error) { return s.Send(value, opts...) })
+	if err == nil && config.replay {
+		rs.replay = append(rs.replay, replayedCommand{value: value, opts: opts})
+	}
+	return response, err
+}
+
+// Exec runs the command as described by Session.Exec, re-establishing the session and rerunning the command if the
+// connection is lost.
+func (rs *ResilientSession) Exec(command string) (string, error) {
+	rs.sendMu.Lock()
+	defer rs.sendMu.Unlock()
+	return rs.do(command, func(s Session) (string, error) { return s.Exec(command) })
+}
+
+// Profile delivers the profile applied to the current session, or nil if none.
+func (rs *ResilientSession) Profile() *Profile {
+	return rs.session().Profile()
+}
+
+// Close closes the current session; any command in progress fails, and is not resent.
+func (rs *ResilientSession) Close() error {
+	rs.mu.Lock()
+	if rs.closed {
+		rs.mu.Unlock()
+		return nil
+	}
+	rs.closed = true
+	s := rs.s
+	rs.mu.Unlock()
+	return s.Close()
+}
+
+func (rs *ResilientSession) session() Session {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.s
+}
+
+// Sends the command on the current session, re-establishing the session and resending the command, if permitted by
+// the guard, while the connection is lost.
+func (rs *ResilientSession) do(command string, send func(Session) (string, error)) (string, error) {
+	if rs.isClosed() {
+		return "", ErrSessionClosed
+	}
+	response, err := send(rs.session())
+	attempt := 0
+	for isConnectionLost(err) {
+		if rs.isClosed() {
+			return "", ErrSessionClosed
+		}
+		if err = rs.reconnect(&attempt, err); err != nil {
+			return "", err
+		}
+		resume, gerr := rs.cfg.guard(rs.session(), command)
+		if gerr != nil {
+			return "", gerr
+		}
+		if !resume {
+			return "", ErrNotResumed
+		}
+		response, err = send(rs.session())
+	}
+	return response, err
+}
+
+// Re-establishes the session following the loss of the connection, replaying the recorded commands, using the
+// attempts remaining.
+func (rs *ResilientSession) reconnect(attempt *int, cause error) error {
+	_ = rs.session().Close()
+
+	delay := rs.cfg.backoff << *attempt
+	var err error
+	for *attempt < rs.cfg.attempts {
+		*attempt++
+		select {
+		case <-rs.ctx.Done():
+			return rs.ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		err = rs.redial()
+		rs.trace.Reconnect(*attempt, cause, err)
+		if err == nil || !isConnectionLost(err) && !isRetryable(err) {
+			return err
+		}
+	}
+	if err == nil {
+		err = cause
+	}
+	return perrors.Wrapf(err, "failed to reconnect after %d attempts", *attempt)
+}
+
+// Dials a new session, replaying the recorded commands, and makes it the current session.
+func (rs *ResilientSession) redial() error {
+	s, err := rs.dial(rs.ctx)
+	if err != nil {
+		return err
+	}
+	for _, cmd := range rs.replay {
+		if _, err = s.Send(cmd.value, cmd.opts...); err != nil {
+			_ = s.Close()
+			return perrors.Wrap(err, "failed to replay command "+cmd.value)
+		}
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.closed {
+		_ = s.Close()
+		return ErrSessionClosed
+	}
+	rs.s = s
+	return nil
+}
+
+func (rs *ResilientSession) isClosed() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.closed
+}
+
+// Determines whether a command failed because the connection was lost.
+func isConnectionLost(err error) bool {
+	return errors.Is(err, io.EOF)
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Session that records the commands sent, failing with io.EOF once the connection is dropped.
+type droppingSession struct {
+	mu     sync.Mutex
+	sent   []string
+	dropOn string
+	closed bool
+}
+
+func (d *droppingSession) Send(value string, opts ...SendOption) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return "", io.EOF
+	}
+	d.sent = append(d.sent, value)
+	if value == d.dropOn {
+		d.closed = true
+		return "", io.EOF
+	}
+	return "OK:" + value, nil
+}
+
+func (d *droppingSession) Exec(command string) (string, error) {
+	return d.Send(command)
+}
+
+func (d *droppingSession) Profile() *Profile {
+	return nil
+}
+
+func (d *droppingSession) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return nil
+}
+
+func (d *droppingSession) commands() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.sent...)
+}
+
+// Delivers a dialer that delivers the sessions in turn, failing once they have been used.
+func sessionDialer(sessions ...*droppingSession) Dialer {
+	var mu sync.Mutex
+	return func(ctx context.Context) (Session, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(sessions) == 0 {
+			return nil, &transportError{errors.New("connection refused")}
+		}
+		s := sessions[0]
+		sessions = sessions[1:]
+		return s, nil
+	}
+}
+
+func TestResilientSessionResumes(t *testing.T) {
+	first := &droppingSession{dropOn: "show running-config"}
+	second := &droppingSession{}
+	var reconnects []error
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		Reconnect: func(attempt int, cause, err error) {
+			assert.Equal(t, io.EOF, cause)
+			reconnects = append(reconnects, err)
+		},
+	})
+	rs, err := NewResilientSession(ctx, sessionDialer(first, second), WithReconnectAttempts(2, time.Millisecond))
+	assert.NoError(t, err)
+	defer rs.Close()
+
+	_, err = rs.Send("enable", ReplayOnReconnect())
+	assert.NoError(t, err)
+	_, err = rs.Send("show version")
+	assert.NoError(t, err)
+
+	response, err := rs.Send("show running-config")
+	assert.NoError(t, err)
+	assert.Equal(t, "OK:show running-config", response)
+	assert.Equal(t, []string{"enable", "show version", "show running-config"}, first.commands())
+	assert.Equal(t, []string{"enable", "show running-config"}, second.commands(),
+		"Expecting recorded commands to be replayed before the interrupted command is resent")
+	assert.Equal(t, []error{nil}, reconnects)
+
+	response, err = rs.Exec("show clock")
+	assert.NoError(t, err)
+	assert.Equal(t, "OK:show clock", response)
+}
+
+func TestResilientSessionSendBatch(t *testing.T) {
+	first := &droppingSession{dropOn: "show running-config"}
+	second := &droppingSession{}
+	rs, err := NewResilientSession(context.Background(), sessionDialer(first, second),
+		WithReconnectAttempts(1, time.Millisecond))
+	assert.NoError(t, err)
+	defer rs.Close()
+
+	results, err := SendBatch(rs, []string{"show version", "show running-config", "show clock"})
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchResult{
+		{Command: "show version", Output: "OK:show version"},
+		{Command: "show running-config", Output: "OK:show running-config"},
+		{Command: "show clock", Output: "OK:show clock"},
+	}, results)
+	assert.Equal(t, []string{"show running-config", "show clock"}, second.commands(),
+		"Expecting the interrupted command to be resent, and the batch to continue on the new session")
+}
+
+func TestResilientSessionResumeGuard(t *testing.T) {
+	first := &droppingSession{dropOn: "reload"}
+	second := &droppingSession{}
+	var guarded []string
+	guard := func(s Session, command string) (bool, error) {
+		guarded = append(guarded, command)
+		_, err := s.Send("show uptime")
+		return false, err
+	}
+	rs, err := NewResilientSession(context.Background(), sessionDialer(first, second),
+		WithReconnectAttempts(1, time.Millisecond), WithResumeGuard(guard))
+	assert.NoError(t, err)
+	defer rs.Close()
+
+	_, err = rs.Send("reload")
+	assert.Equal(t, ErrNotResumed, err)
+	assert.Equal(t, []string{"reload"}, guarded)
+	assert.Equal(t, []string{"show uptime"}, second.commands(), "Expecting guard to use the new session")
+
+	_, err = rs.Send("show version")
+	assert.NoError(t, err, "Expecting the new session to be retained")
+}
+
+func TestResilientSessionReconnectFailure(t *testing.T) {
+	var attempts []int
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		Reconnect: func(attempt int, cause, err error) {
+			assert.Error(t, err)
+			attempts = append(attempts, attempt)
+		},
+	})
+	rs, err := NewResilientSession(ctx, sessionDialer(&droppingSession{dropOn: "show version"}),
+		WithReconnectAttempts(2, time.Millisecond))
+	assert.NoError(t, err)
+	defer rs.Close()
+
+	_, err = rs.Send("show version")
+	assert.EqualError(t, err, "failed to reconnect after 2 attempts: connection refused")
+	assert.Equal(t, []int{1, 2}, attempts)
+}
+
+func TestResilientSessionReplayFailure(t *testing.T) {
+	first := &droppingSession{dropOn: "show version"}
+	second := &droppingSession{dropOn: "enable"}
+	third := &droppingSession{}
+	rs, err := NewResilientSession(context.Background(), sessionDialer(first, second, third),
+		WithReconnectAttempts(2, time.Millisecond))
+	assert.NoError(t, err)
+	defer rs.Close()
+
+	_, err = rs.Send("enable", ReplayOnReconnect())
+	assert.NoError(t, err)
+	response, err := rs.Send("show version")
+	assert.NoError(t, err, "Expecting connection loss during replay to be retried")
+	assert.Equal(t, "OK:show version", response)
+	assert.Equal(t, []string{"enable", "show version"}, third.commands())
+}
+
+func TestResilientSessionClose(t *testing.T) {
+	dialed := 0
+	dial := func(ctx context.Context) (Session, error) {
+		dialed++
+		return newGatedSession(), nil
+	}
+	rs, err := NewResilientSession(context.Background(), dial)
+	assert.NoError(t, err)
+
+	assert.NoError(t, rs.Close())
+	_, err = rs.Send("show version")
+	assert.Equal(t, ErrSessionClosed, err)
+	assert.NoError(t, rs.Close())
+	assert.Equal(t, 1, dialed)
+
+	_, err = NewResilientSession(context.Background(), sessionDialer())
+	assert.EqualError(t, err, "connection refused")
+}
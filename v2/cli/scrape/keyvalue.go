@@ -0,0 +1,76 @@
+package scrape
+
+import (
+	"regexp"
+	"strings"
+)
+
+// KeyValueOption implements options for configuring key:value parsing.
+type KeyValueOption func(*keyValueConfig)
+
+// Separator defines the string that separates a key from its value.
+// Defaults to ":".
+func Separator(sep string) KeyValueOption {
+	return func(c *keyValueConfig) {
+		c.separator = sep
+	}
+}
+
+// RecordStart defines a pattern identifying the first line of each record, for output in which records are not
+// separated by blank lines. Lines preceding the first match are ignored.
+// Defaults to starting a new record after each blank line.
+func RecordStart(pattern string) KeyValueOption {
+	return func(c *keyValueConfig) {
+		c.recordStart, c.err = regexp.Compile(pattern)
+	}
+}
+
+type keyValueConfig struct {
+	separator   string
+	recordStart *regexp.Regexp
+	err         error
+}
+
+// ParseKeyValue parses blocks of "key: value" lines, such as the output of "show version", returning a record for
+// each block. Keys and values are trimmed of surrounding whitespace; lines that do not contain the separator are
+// ignored. If a key is repeated within a block, the last value is retained.
+func ParseKeyValue(output string, opts ...KeyValueOption) ([]Record, error) {
+	config := &keyValueConfig{separator: ":"}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.err != nil {
+		return nil, config.err
+	}
+
+	var records []Record
+	var current Record
+	started := config.recordStart == nil
+	for _, line := range splitLines(output) {
+		switch {
+		case config.recordStart != nil && config.recordStart.MatchString(line):
+			started, current = true, nil
+		case config.recordStart == nil && strings.TrimSpace(line) == "":
+			current = nil
+			continue
+		}
+		if !started {
+			continue
+		}
+
+		pos := strings.Index(line, config.separator)
+		if pos < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:pos])
+		if key == "" {
+			continue
+		}
+		if current == nil {
+			current = Record{}
+			records = append(records, current)
+		}
+		current[key] = strings.TrimSpace(line[pos+len(config.separator):])
+	}
+	return records, nil
+}
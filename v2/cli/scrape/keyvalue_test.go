@@ -0,0 +1,42 @@
+package scrape
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestParseKeyValue(t *testing.T) {
+	output := `
+Name: GigabitEthernet0/0
+  Description : uplink
+  MTU: 1500
+
+Name: GigabitEthernet0/1
+MTU: 9000
+`
+	records, err := ParseKeyValue(output)
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{
+		{"Name": "GigabitEthernet0/0", "Description": "uplink", "MTU": "1500"},
+		{"Name": "GigabitEthernet0/1", "MTU": "9000"},
+	}, records)
+}
+
+func TestParseKeyValueRecordStart(t *testing.T) {
+	output := `show neighbors detail
+Device ID = switch1
+  Platform = C9300
+Device ID = switch2
+  Platform = C9500
+`
+	records, err := ParseKeyValue(output, Separator("="), RecordStart(`^Device ID`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{
+		{"Device ID": "switch1", "Platform": "C9300"},
+		{"Device ID": "switch2", "Platform": "C9500"},
+	}, records)
+
+	_, err = ParseKeyValue(output, RecordStart(`(`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+// Package scrape provides helpers for parsing the structured output of device cli commands, such as fixed-width
+// tables and key:value blocks, into records.
+package scrape
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Record defines a parsed record, mapping field names to values.
+type Record = map[string]string
+
+// TableOption implements options for configuring table parsing.
+type TableOption func(*tableConfig)
+
+// Headers defines the column headers, which are located in the header line to determine the column positions.
+// This is required when headers contain spaces, for example "Port Name".
+// Defaults to the whitespace separated words of the header line.
+func Headers(names ...string) TableOption {
+	return func(c *tableConfig) {
+		c.headers = names
+	}
+}
+
+// HeaderPattern defines a pattern that identifies the header line; preceding lines are ignored.
+// Defaults to the first non-blank line.
+func HeaderPattern(pattern string) TableOption {
+	return func(c *tableConfig) {
+		var err error
+		c.headerPattern, err = regexp.Compile(pattern)
+		c.setErr(err)
+	}
+}
+
+// SkipPattern defines a pattern identifying lines following the header line that are not table rows.
+// Defaults to lines made up of separator characters, such as "---- -----".
+func SkipPattern(pattern string) TableOption {
+	return func(c *tableConfig) {
+		var err error
+		c.skipPattern, err = regexp.Compile(pattern)
+		c.setErr(err)
+	}
+}
+
+type tableConfig struct {
+	headers       []string
+	headerPattern *regexp.Regexp
+	skipPattern   *regexp.Regexp
+	err           error
+}
+
+// Records the first invalid option.
+func (c *tableConfig) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+var defaultSkipPattern = regexp.MustCompile(`^[\s\-=+|]*$`)
+
+// Defines a table column, which starts at the position of the header in the header line.
+type column struct {
+	name  string
+	start int
+}
+
+// ParseTable parses fixed-width tabular output, such as that of "show interface brief", returning a record for each
+// row keyed by the column headers.
+// Values are left-aligned with their header; a value that extends beyond the start of the next column is assigned
+// to the column in which it starts.
+// Parsing ends at the first blank line following the table rows.
+func ParseTable(output string, opts ...TableOption) ([]Record, error) {
+	config := &tableConfig{skipPattern: defaultSkipPattern}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.err != nil {
+		return nil, config.err
+	}
+
+	lines := splitLines(output)
+	i := 0
+	for ; i < len(lines); i++ {
+		if config.headerPattern != nil && config.headerPattern.MatchString(lines[i]) ||
+			config.headerPattern == nil && strings.TrimSpace(lines[i]) != "" {
+			break
+		}
+	}
+	if i == len(lines) {
+		return nil, errors.New("table header not found")
+	}
+
+	columns, err := parseHeader(lines[i], config.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, line := range lines[i+1:] {
+		if strings.TrimSpace(line) == "" {
+			if len(records) > 0 {
+				break
+			}
+			continue
+		}
+		if config.skipPattern.MatchString(line) {
+			continue
+		}
+		records = append(records, parseRow(line, columns))
+	}
+	return records, nil
+}
+
+// Determines the columns from the header line.
+func parseHeader(line string, headers []string) ([]column, error) {
+	var columns []column
+	if len(headers) == 0 {
+		for _, loc := range regexp.MustCompile(`\S+`).FindAllStringIndex(line, -1) {
+			columns = append(columns, column{name: line[loc[0]:loc[1]], start: loc[0]})
+		}
+		return columns, nil
+	}
+
+	from := 0
+	for _, h := range headers {
+		pos := strings.Index(line[from:], h)
+		if pos < 0 {
+			return nil, errors.Errorf("header %q not found in %q", h, line)
+		}
+		columns = append(columns, column{name: h, start: from + pos})
+		from += pos + len(h)
+	}
+	return columns, nil
+}
+
+// Splits a row into column values.
+func parseRow(line string, columns []column) Record {
+	record := make(Record, len(columns))
+	start := 0
+	for i, col := range columns {
+		end := len(line)
+		if i+1 < len(columns) {
+			end = boundary(line, columns[i+1].start)
+		}
+		if start < end {
+			record[col.name] = strings.TrimSpace(line[start:end])
+		} else {
+			record[col.name] = ""
+		}
+		if end > start {
+			start = end
+		}
+	}
+	return record
+}
+
+// Delivers the position at which the column starting at pos begins in the line, moving past any value that
+// straddles the column start.
+func boundary(line string, pos int) int {
+	if pos >= len(line) {
+		return len(line)
+	}
+	for pos > 0 && pos < len(line) && line[pos-1] != ' ' && line[pos] != ' ' {
+		pos++
+	}
+	return pos
+}
+
+func splitLines(output string) []string {
+	return strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,66 @@
+package scrape
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const showIPInterfaceBrief = `
+Interface              IP-Address      OK? Method Status                Protocol
+GigabitEthernet0/0     10.0.0.1        YES NVRAM  up                    up
+GigabitEthernet0/1     unassigned      YES NVRAM  administratively down down
+Loopback0              192.168.1.1     YES manual up                    up
+
+router#`
+
+func TestParseTable(t *testing.T) {
+	records, err := ParseTable(showIPInterfaceBrief)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, Record{"Interface": "GigabitEthernet0/1", "IP-Address": "unassigned", "OK?": "YES",
+		"Method": "NVRAM", "Status": "administratively down", "Protocol": "down"}, records[1])
+	assert.Equal(t, "manual", records[2]["Method"])
+}
+
+func TestParseTableStraddlingValue(t *testing.T) {
+	output := "Port   Vlan Status\n" +
+		"Gi1/0/10 10  connected\n" +
+		"Gi1/0/2  200 notconnect\n"
+
+	records, err := ParseTable(output)
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{
+		{"Port": "Gi1/0/10", "Vlan": "10", "Status": "connected"},
+		{"Port": "Gi1/0/2", "Vlan": "200", "Status": "notconnect"},
+	}, records)
+}
+
+func TestParseTableHeaders(t *testing.T) {
+	output := `show interfaces status
+Port      Name               Status       Vlan
+--------- ------------------ ------------ ----
+Gi1/0/1   uplink to core     connected    trunk
+Gi1/0/2                      notconnect   1
+`
+	records, err := ParseTable(output, HeaderPattern(`^Port\s`), Headers("Port", "Name", "Status", "Vlan"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{
+		{"Port": "Gi1/0/1", "Name": "uplink to core", "Status": "connected", "Vlan": "trunk"},
+		{"Port": "Gi1/0/2", "Name": "", "Status": "notconnect", "Vlan": "1"},
+	}, records)
+}
+
+func TestParseTableFailures(t *testing.T) {
+	_, err := ParseTable("\n\n")
+	assert.EqualError(t, err, "table header not found")
+
+	_, err = ParseTable("Port Status", Headers("Port", "Vlan"))
+	assert.EqualError(t, err, `header "Vlan" not found in "Port Status"`)
+
+	_, err = ParseTable("Port Status", HeaderPattern(`(`))
+	assert.Error(t, err)
+
+	_, err = ParseTable("Port Status", HeaderPattern(`(`), SkipPattern(`^-+$`))
+	assert.Error(t, err, "Expecting an invalid option not to be masked by a later valid option")
+}
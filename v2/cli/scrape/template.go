@@ -0,0 +1,337 @@
+package scrape
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Template defines a state machine for parsing cli output, loaded from a template in the TextFSM format described
+// at https://github.com/google/textfsm/wiki/TextFSM.
+//
+// The following subset of TextFSM is supported:
+//   - Value definitions, with the Filldown, Required and Key (which is accepted but has no effect) options.
+//   - State definitions, which must include Start. An empty EOF state suppresses the implicit record at the end of
+//     the output, and a transition to End terminates parsing.
+//   - Rules, with the Next and Continue line actions, the Record, NoRecord, Clear and Clearall record actions,
+//     state transitions and the Error action.
+//
+// A Template is safe for concurrent use.
+type Template struct {
+	values []*templateValue
+	states map[string][]*templateRule
+	// Indicates that an EOF state is defined, suppressing the implicit record at the end of the output.
+	hasEOF bool
+}
+
+type templateValue struct {
+	name     string
+	pattern  string
+	filldown bool
+	required bool
+}
+
+type templateRule struct {
+	re           *regexp.Regexp
+	lineAction   string
+	recordAction string
+	newState     string
+	// Indicates that a match is an error, reported with the message.
+	isError  bool
+	errorMsg string
+}
+
+// Template actions and reserved states.
+const (
+	actionNext     = "Next"
+	actionContinue = "Continue"
+	actionRecord   = "Record"
+	actionNoRecord = "NoRecord"
+	actionClear    = "Clear"
+	actionClearall = "Clearall"
+	actionError    = "Error"
+
+	stateStart = "Start"
+	stateEOF   = "EOF"
+	stateEnd   = "End"
+)
+
+var (
+	lineActions   = map[string]bool{actionNext: true, actionContinue: true}
+	recordActions = map[string]bool{actionRecord: true, actionNoRecord: true, actionClear: true, actionClearall: true}
+	valueRef      = regexp.MustCompile(`\$\{(\w+)\}`)
+	ruleAction    = regexp.MustCompile(`\s+->\s+`)
+)
+
+// LoadTemplate loads a template in the TextFSM format.
+func LoadTemplate(r io.Reader) (*Template, error) {
+	t := &Template{states: map[string][]*templateRule{}}
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	inValues := true
+	var state string
+	for scanner.Scan() {
+		lineno++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		var err error
+		switch {
+		case inValues && trimmed == "":
+			inValues = len(t.values) == 0
+		case inValues:
+			err = t.addValue(trimmed)
+		case trimmed == "":
+		case line[0] != ' ' && line[0] != '\t':
+			state = trimmed
+			if _, ok := t.states[state]; ok {
+				err = errors.Errorf("duplicate state %s", state)
+			}
+			t.states[state] = nil
+			t.hasEOF = t.hasEOF || state == stateEOF
+		default:
+			err = t.addRule(state, trimmed)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "template line %d", lineno)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Adds a value definition, of the form: Value [option[,option...]] name (regex).
+func (t *Template) addValue(line string) error {
+	fields := strings.Fields(line)
+	pos := strings.Index(line, "(")
+	if len(fields) < 3 || fields[0] != "Value" || pos < 0 || !strings.HasSuffix(line, ")") {
+		return errors.Errorf("invalid value definition %q", line)
+	}
+
+	head := strings.Fields(line[len("Value"):pos])
+	v := &templateValue{name: head[len(head)-1], pattern: strings.TrimSpace(line[pos:])}
+	if len(head) > 2 {
+		return errors.Errorf("invalid value definition %q", line)
+	}
+	if len(head) == 2 {
+		for _, opt := range strings.Split(head[0], ",") {
+			switch opt {
+			case "Filldown":
+				v.filldown = true
+			case "Required":
+				v.required = true
+			case "Key":
+			default:
+				return errors.Errorf("unsupported value option %s", opt)
+			}
+		}
+	}
+	if _, err := regexp.Compile(v.pattern); err != nil {
+		return errors.Wrapf(err, "value %s", v.name)
+	}
+	for _, existing := range t.values {
+		if existing.name == v.name {
+			return errors.Errorf("duplicate value %s", v.name)
+		}
+	}
+	t.values = append(t.values, v)
+	return nil
+}
+
+// Adds a rule to the state, of the form: ^regex [-> action].
+func (t *Template) addRule(state, line string) error {
+	if state == "" || !strings.HasPrefix(line, "^") {
+		return errors.Errorf("invalid rule %q", line)
+	}
+
+	parts := ruleAction.Split(line, 2)
+	pattern, err := t.expandPattern(parts[0])
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	r := &templateRule{re: re, lineAction: actionNext}
+	if len(parts) == 2 {
+		if err = r.parseAction(strings.Fields(parts[1])); err != nil {
+			return err
+		}
+	}
+	t.states[state] = append(t.states[state], r)
+	return nil
+}
+
+// Substitutes value references with named capture groups, and $$ with the end of line anchor.
+func (t *Template) expandPattern(pattern string) (string, error) {
+	var err error
+	expanded := valueRef.ReplaceAllStringFunc(pattern, func(ref string) string {
+		name := valueRef.FindStringSubmatch(ref)[1]
+		for _, v := range t.values {
+			if v.name == name {
+				return "(?P<" + name + ">" + v.pattern[1:len(v.pattern)-1] + ")"
+			}
+		}
+		err = errors.Errorf("undefined value %s", name)
+		return ref
+	})
+	return strings.ReplaceAll(expanded, "$$", "$"), err
+}
+
+// Parses the action of a rule, of the form: [LineAction[.RecordAction]|RecordAction] [NewState], or
+// Error [message].
+func (r *templateRule) parseAction(fields []string) error {
+	if len(fields) == 0 {
+		return errors.New("missing rule action")
+	}
+	if fields[0] == actionError {
+		r.isError, r.errorMsg = true, strings.Join(fields[1:], " ")
+		return nil
+	}
+
+	actions := strings.Split(fields[0], ".")
+	switch {
+	case len(actions) == 2 && lineActions[actions[0]] && recordActions[actions[1]]:
+		r.lineAction, r.recordAction = actions[0], actions[1]
+	case len(actions) == 1 && lineActions[actions[0]]:
+		r.lineAction = actions[0]
+	case len(actions) == 1 && recordActions[actions[0]]:
+		r.recordAction = actions[0]
+	case len(actions) == 1 && len(fields) == 1:
+		r.newState = actions[0]
+		return nil
+	default:
+		return errors.Errorf("invalid rule action %q", strings.Join(fields, " "))
+	}
+
+	switch {
+	case len(fields) == 2:
+		r.newState = fields[1]
+	case len(fields) > 2:
+		return errors.Errorf("invalid rule action %q", strings.Join(fields, " "))
+	}
+	if r.newState != "" && r.lineAction == actionContinue {
+		return errors.New("state transition not permitted with Continue")
+	}
+	return nil
+}
+
+// Checks that the Start state and all transition targets are defined.
+func (t *Template) validate() error {
+	if _, ok := t.states[stateStart]; !ok {
+		return errors.New("template has no Start state")
+	}
+	if len(t.states[stateEOF]) > 0 {
+		return errors.New("template EOF state must be empty")
+	}
+	for _, rules := range t.states {
+		for _, r := range rules {
+			if _, ok := t.states[r.newState]; r.newState != "" && r.newState != stateEnd && r.newState != stateEOF && !ok {
+				return errors.Errorf("undefined state %s", r.newState)
+			}
+		}
+	}
+	return nil
+}
+
+// Parse applies the template to the output, returning the records it delivers.
+// Each record holds a field for every template value.
+func (t *Template) Parse(output string) ([]Record, error) {
+	p := &templateParser{t: t, row: Record{}}
+	state := stateStart
+lines:
+	for _, line := range splitLines(output) {
+		for _, r := range t.states[state] {
+			loc := r.re.FindStringSubmatchIndex(line)
+			if loc == nil {
+				continue
+			}
+			if r.isError {
+				return nil, errors.Errorf("template error %q at line %q", r.errorMsg, line)
+			}
+			for i, name := range r.re.SubexpNames() {
+				if name != "" && loc[2*i] >= 0 {
+					p.row[name] = line[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			p.apply(r.recordAction)
+			if r.newState != "" {
+				state = r.newState
+			}
+			if r.lineAction != actionContinue {
+				break
+			}
+		}
+		switch state {
+		case stateEnd:
+			return p.records, nil
+		case stateEOF:
+			break lines
+		}
+	}
+	if !t.hasEOF {
+		p.apply(actionRecord)
+	}
+	return p.records, nil
+}
+
+// Holds the state of a single Parse.
+type templateParser struct {
+	t       *Template
+	row     Record
+	records []Record
+}
+
+func (p *templateParser) apply(action string) {
+	switch action {
+	case actionRecord:
+		p.record()
+		p.clear(false)
+	case actionClear:
+		p.clear(false)
+	case actionClearall:
+		p.clear(true)
+	}
+}
+
+// Records the current row, provided it holds a value and all required values are present.
+func (p *templateParser) record() {
+	empty := true
+	for _, v := range p.t.values {
+		if v.required && p.row[v.name] == "" {
+			return
+		}
+		empty = empty && p.row[v.name] == ""
+	}
+	if empty {
+		return
+	}
+
+	record := make(Record, len(p.t.values))
+	for _, v := range p.t.values {
+		record[v.name] = p.row[v.name]
+	}
+	p.records = append(p.records, record)
+}
+
+func (p *templateParser) clear(all bool) {
+	for _, v := range p.t.values {
+		if all || !v.filldown {
+			delete(p.row, v.name)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package scrape
+
+import (
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const interfaceTemplate = `# Parses show interfaces.
+Value Filldown Hostname (\S+)
+Value Required Interface (\S+)
+Value Status (up|down|administratively down)
+Value MTU (\d+)
+
+Start
+  ^${Hostname}#
+  ^${Interface} is ${Status}, -> Continue
+  ^.*line protocol -> Next
+  ^\s+MTU ${MTU} bytes -> Record
+  ^\s*$$
+  ^-- End -> End
+`
+
+const showInterfaces = `router1#
+GigabitEthernet0/0 is up, line protocol is up
+  MTU 1500 bytes, BW 1000000 Kbit/sec
+GigabitEthernet0/1 is administratively down, line protocol is down
+  MTU 9000 bytes, BW 1000000 Kbit/sec
+-- End
+Loopback0 is up, line protocol is up
+  MTU 1514 bytes, BW 8000000 Kbit/sec
+`
+
+func TestTemplateParse(t *testing.T) {
+	tmpl, err := LoadTemplate(strings.NewReader(interfaceTemplate))
+	assert.NoError(t, err)
+
+	records, err := tmpl.Parse(showInterfaces)
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{
+		{"Hostname": "router1", "Interface": "GigabitEthernet0/0", "Status": "up", "MTU": "1500"},
+		{"Hostname": "router1", "Interface": "GigabitEthernet0/1", "Status": "administratively down", "MTU": "9000"},
+	}, records)
+}
+
+func TestTemplateStatesAndEOF(t *testing.T) {
+	tmpl, err := LoadTemplate(strings.NewReader(`Value Required Name (\w+)
+Value Value (\S+)
+
+Start
+  ^Section -> Section
+
+Section
+  ^${Name}=${Value} -> Record
+  ^Error -> Error unexpected output
+
+EOF
+`))
+	assert.NoError(t, err)
+
+	records, err := tmpl.Parse("a=1\nSection\nb=2\nc\nd=4\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{{"Name": "b", "Value": "2"}, {"Name": "d", "Value": "4"}}, records)
+
+	_, err = tmpl.Parse("Section\nError\n")
+	assert.EqualError(t, err, `template error "unexpected output" at line "Error"`)
+}
+
+func TestTemplateImplicitRecord(t *testing.T) {
+	tmpl, err := LoadTemplate(strings.NewReader("Value Version (\\S+)\n\nStart\n  ^Version ${Version}\n"))
+	assert.NoError(t, err)
+
+	records, err := tmpl.Parse("Version 17.3.1\nUptime 3 days\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []Record{{"Version": "17.3.1"}}, records)
+}
+
+func TestLoadTemplateFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      string
+	}{
+		{"no start", "Value A (\\S+)\n\nOther\n  ^x\n", "template has no Start state"},
+		{"undefined value", "Value A (\\S+)\n\nStart\n  ^${B}\n", "template line 4: undefined value B"},
+		{"unsupported option", "Value List A (\\S+)\n\nStart\n", "template line 1: unsupported value option List"},
+		{"invalid value", "Value A\n\nStart\n", `template line 1: invalid value definition "Value A"`},
+		{"undefined state", "Value A (\\S+)\n\nStart\n  ^x -> Next Other\n", "undefined state Other"},
+		{"continue transition", "Value A (\\S+)\n\nStart\n  ^x -> Continue Start\n",
+			"template line 4: state transition not permitted with Continue"},
+		{"invalid rule", "Value A (\\S+)\n\nStart\n  x\n", `template line 4: invalid rule "x"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadTemplate(strings.NewReader(test.template))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -17,7 +17,18 @@ import (
 type Session interface {
 	// Send writes the supplied value to the server and returns the response.
 	// The behaviour can be modified by opts - see SendOption variants below.
+	// If the response matches any of the session error patterns, a *CommandError is returned along with the response.
 	Send(value string, opts ...SendOption) (string, error)
+
+	// Exec runs the command on a separate exec channel of the session connection, rather than the interactive shell,
+	// returning its output once the command completes, so no prompt matching is required. Devices that do not
+	// support exec channels cause ErrExecUnsupported to be returned; a command that exits with a non-zero status
+	// returns its output along with an error wrapping the *ssh.ExitError.
+	// If the output matches any of the session error patterns, a *CommandError is returned along with the output.
+	Exec(command string) (string, error)
+
+	// Profile delivers the profile applied to the session, selected by WithProfile or WithDeviceType, or nil if none.
+	Profile() *Profile
 	io.Closer
 }
 
@@ -46,6 +57,13 @@ func ResetPrompt() SendOption {
 	}
 }
 
+// IgnoreErrors suppresses the checking of the response against the session error patterns.
+func IgnoreErrors() SendOption {
+	return func(c *SendConfig) {
+		c.ignoreErrors = true
+	}
+}
+
 // NoWait indicates the Send should not wait for a response.
 func NoWait() SendOption {
 	return func(c *SendConfig) {
@@ -53,12 +71,33 @@ func NoWait() SendOption {
 	}
 }
 
+// ViaExec runs the command on an exec channel, as described by Session.Exec, if the device supports exec channels,
+// and otherwise sends it to the interactive shell. The WaitFor, NoNewline, ResetPrompt and NoWait options do not
+// apply to commands run on an exec channel.
+func ViaExec() SendOption {
+	return func(c *SendConfig) {
+		c.viaExec = true
+	}
+}
+
 // SendConfig defines properties controlling Send behaviour.
 type SendConfig struct {
+	viaExec          bool
 	suppressNewline  bool
 	resetPrompt      bool
 	noResponse       bool
+	ignoreErrors     bool
 	responseSentinel string
+	// See AtPriority and SendTimeout, applied by SharedSession.
+	priority    Priority
+	prioritySet bool
+	timeout     time.Duration
+	// See ReplayOnReconnect, applied by ResilientSession.
+	replay bool
+	// See MaxOutput and OverflowTo.
+	maxOutput    int64
+	maxOutputSet bool
+	overflow     io.Writer
 }
 
 type SessionImpl struct {
@@ -66,16 +105,29 @@ type SessionImpl struct {
 	tport SSHTransport
 	// promptPattern defines the regex used to determine the end of a response.
 	promptPattern *regexp.Regexp
+	// errorPatterns define the regexes used to detect device errors in a response.
+	errorPatterns []*regexp.Regexp
+	// dryRunPatterns define the regexes that identify commands suppressed by WithDryRun.
+	dryRunPatterns []*regexp.Regexp
 	// Used to queue the inputs received from the server.
 	inputs chan []byte
+	trace  *CliTrace
+	// Set once the device has rejected an exec request, so that subsequent ViaExec commands are sent to the shell.
+	execUnsupported bool
+	// The number of bytes of output retained for responses, counted against the session output limit.
+	outputTotal int64
 }
 
 // NewCliSession establishes a client connection to a cli session running on the server associated with the supplied
 // transport.
+// Trace hooks defined by WithCliTrace on ctx are applied to the session.
 func NewCliSession(ctx context.Context, tport SSHTransport, cfg *SessionConfig) (s *SessionImpl, err error) {
 	// Use supplied config, but apply any defaults to unspecified values.
 	resolvedConfig := *cfg
 	_ = mergo.Merge(&resolvedConfig, DefaultConfig)
+	if err = resolvedConfig.resolveProfile(); err != nil {
+		return nil, err
+	}
 
 	// If caller has specified a specific prompt pattern, check it's valid.
 	var pattern *regexp.Regexp
@@ -86,7 +138,19 @@ func NewCliSession(ctx context.Context, tport SSHTransport, cfg *SessionConfig)
 		}
 	}
 
-	sess := &SessionImpl{cfg: &resolvedConfig, tport: tport, inputs: make(chan []byte), promptPattern: pattern}
+	errorPatterns, err := compileErrorPatterns(resolvedConfig.errorPatterns)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid error pattern")
+	}
+	dryRunPatterns, err := compileErrorPatterns(resolvedConfig.dryRunPatterns)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid dry-run pattern")
+	}
+
+	sess := &SessionImpl{
+		cfg: &resolvedConfig, tport: tport, inputs: make(chan []byte), promptPattern: pattern, errorPatterns: errorPatterns,
+		dryRunPatterns: dryRunPatterns, trace: ContextCliTrace(ctx),
+	}
 
 	// Launch the reader to capture input from the server.
 	sess.launchReader()
@@ -99,11 +163,16 @@ func NewCliSession(ctx context.Context, tport SSHTransport, cfg *SessionConfig)
 		_, err = sess.readUntilValue(pattern)
 	}
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to capture cli prompt")
+		sess.trace.Error("Capture Prompt", err)
+		return nil, errors.Wrap(&promptError{err}, "failed to capture cli prompt")
 	}
 
-	// Execute any initial commands, ignoring any response values.
-	for _, cmd := range sess.cfg.initCmds {
+	// Execute any profile and initial commands, ignoring any response values.
+	var cmds []string
+	if sess.cfg.profile != nil {
+		cmds = append(cmds, sess.cfg.profile.Commands...)
+	}
+	for _, cmd := range append(cmds, sess.cfg.initCmds...) {
 		_, err = sess.Send(cmd)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to execute initial command "+cmd)
@@ -123,6 +192,7 @@ func (s *SessionImpl) capturePrompt() error {
 	}
 	pbytes := b[bytes.LastIndex(b, []byte("\n"))+1:]
 	s.promptPattern = regexp.MustCompile(regexp.QuoteMeta(string(pbytes)))
+	s.trace.PromptDetected(string(pbytes))
 	return nil
 }
 
@@ -142,11 +212,31 @@ func (s *SessionImpl) readUntilTimeout() ([]byte, error) {
 	}
 }
 
-func (s *SessionImpl) Send(output string, opts ...SendOption) (string, error) {
+func (s *SessionImpl) Send(output string, opts ...SendOption) (response string, err error) {
 	config := &SendConfig{}
 	for _, opt := range opts {
 		opt(config)
 	}
+	if s.suppressed(output) {
+		return "", nil
+	}
+
+	s.trace.SendStart(output)
+	defer func(begin time.Time) {
+		s.trace.SendDone(output, response, err, time.Since(begin))
+	}(time.Now())
+
+	return s.send(output, config)
+}
+
+func (s *SessionImpl) send(output string, config *SendConfig) (string, error) {
+	if config.viaExec && !s.execUnsupported {
+		response, err := s.exec(output, config.ignoreErrors)
+		if !errors.Is(err, ErrExecUnsupported) {
+			return response, err
+		}
+		s.execUnsupported = true
+	}
 
 	// If a response is expected, check that a prompt has been defined or the WaitFor option has been specified.
 	if !config.noResponse && s.promptPattern == nil && config.responseSentinel == "" {
@@ -164,12 +254,14 @@ func (s *SessionImpl) Send(output string, opts ...SendOption) (string, error) {
 	}
 
 	// Write any output to the server.
+	command := output
 	if len(output) > 0 {
 		if !config.suppressNewline {
 			output += "\n"
 		}
 		_, err = s.tport.Write([]byte(output))
 		if err != nil {
+			s.trace.Error("Send Command", err)
 			return "", errors.Wrap(err, "failed to send command")
 		}
 	}
@@ -188,7 +280,52 @@ func (s *SessionImpl) Send(output string, opts ...SendOption) (string, error) {
 	if sentinel == nil {
 		sentinel = s.promptPattern
 	}
-	return s.readUntilValue(sentinel)
+	response, err := s.readResponse(command, sentinel, s.sendLimit(config))
+	if err != nil || config.ignoreErrors {
+		return response, err
+	}
+	return response, checkResponse(command, response, s.errorPatterns)
+}
+
+func (s *SessionImpl) Exec(command string) (response string, err error) {
+	if s.suppressed(command) {
+		return "", nil
+	}
+	s.trace.ExecStart(command)
+	defer func(begin time.Time) {
+		s.trace.ExecDone(command, response, err, time.Since(begin))
+	}(time.Now())
+
+	return s.exec(command, false)
+}
+
+// Runs the command on an exec channel, checking the output against the error patterns unless ignoreErrors is set.
+func (s *SessionImpl) exec(command string, ignoreErrors bool) (string, error) {
+	et, ok := s.tport.(ExecTransport)
+	if !ok {
+		return "", ErrExecUnsupported
+	}
+	b, err := et.Exec(command)
+	response := string(bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n")))
+	if err != nil {
+		if !errors.Is(err, ErrExecUnsupported) {
+			s.trace.Error("Exec Command", err)
+		}
+		return response, err
+	}
+	if ignoreErrors {
+		return response, nil
+	}
+	return response, checkResponse(command, response, s.errorPatterns)
+}
+
+// Profile delivers the profile applied to the session, selected by WithProfile or WithDeviceType, or nil if none.
+func (s *SessionImpl) Profile() *Profile {
+	if s.cfg.profile == nil {
+		return nil
+	}
+	p := *s.cfg.profile
+	return &p
 }
 
 func (s *SessionImpl) Close() error {
@@ -197,25 +334,20 @@ func (s *SessionImpl) Close() error {
 
 // readUntilValue reads until the specified regex is found and returns the read data.
 func (s *SessionImpl) readUntilValue(sentinel *regexp.Regexp) (string, error) {
-	output := new(bytes.Buffer)
+	return s.readResponse("", sentinel, &outputLimit{limit: -1})
+}
+
+// readResponse reads the response to a command until the specified regex is found, retaining output up to the limit.
+func (s *SessionImpl) readResponse(command string, sentinel *regexp.Regexp, lim *outputLimit) (string, error) {
+	r := &limitedResponse{lim: lim}
 	for {
 		b := <-s.inputs
 		if b == nil {
 			return "", io.EOF
 		}
-
-		output.Write(b)
-		tempSlice := bytes.ReplaceAll(output.Bytes(), []byte("\r\n"), []byte("\n"))
-		tempSlice = bytes.ReplaceAll(tempSlice, []byte("\r"), []byte("\n"))
-		lastNl := bytes.LastIndex(tempSlice, []byte("\n"))
-		lastLine := tempSlice
-		if lastNl >= 0 {
-			lastLine = tempSlice[lastNl+1:]
-		} else {
-			lastNl = 0
-		}
-		if sentinel.Match(lastLine) {
-			return string(tempSlice[0:lastNl]), nil
+		if r.add(b, sentinel) {
+			s.outputTotal += int64(r.retained.Len())
+			return r.response(command)
 		}
 	}
 }
@@ -227,6 +359,7 @@ func (s *SessionImpl) launchReader() {
 			const bufLength = 10000
 			stdoutBuf := make([]byte, bufLength)
 			byteCount, err := s.tport.Read(stdoutBuf)
+			s.trace.ReadChunk(stdoutBuf[:byteCount], err)
 			if err != nil {
 				return
 			}
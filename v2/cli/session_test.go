@@ -2,10 +2,16 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/testserver"
 
 	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestSessionSendDefault(t *testing.T) {
@@ -125,3 +131,173 @@ func TestSessionWithPrompt(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "GOT:command\n", resp)
 }
+
+func TestSessionSendErrorPatterns(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithErrorPatterns(append(CommonErrorPatterns, `^GOT:bad`)...))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	resp, err := session.Send("Command")
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:Command\n", resp)
+
+	resp, err = session.Send("bad command")
+	assert.Equal(t, "GOT:bad command\n", resp)
+	cmdErr, ok := err.(*CommandError)
+	assert.True(t, ok, "Expecting a CommandError")
+	assert.Equal(t, "bad command", cmdErr.Command)
+	assert.Equal(t, "GOT:bad command", cmdErr.Line)
+	assert.Equal(t, "^GOT:bad", cmdErr.Pattern)
+	assert.Equal(t, resp, cmdErr.Output)
+	assert.Equal(t, `command "bad command" rejected: GOT:bad command`, err.Error())
+
+	resp, err = session.Send("bad command", IgnoreErrors())
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:bad command\n", resp)
+}
+
+func TestSessionTraceHooks(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	var events []string
+	var lock sync.Mutex
+	record := func(event string) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, event)
+	}
+	trace := &CliTrace{
+		ConnectStart: func(target string) { record("ConnectStart") },
+		ConnectDone: func(target string, err error, d time.Duration) {
+			record(fmt.Sprintf("ConnectDone err:%v", err))
+		},
+		PromptDetected: func(prompt string) { record("PromptDetected " + prompt) },
+		SendStart:      func(command string) { record("SendStart " + command) },
+		SendDone: func(command, response string, err error, d time.Duration) {
+			record(fmt.Sprintf("SendDone %s %q err:%v", command, response, err))
+		},
+	}
+	ctx := WithCliTrace(context.Background(), trace)
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(ctx, validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.Send("Command")
+	assert.NoError(t, err)
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{
+		"ConnectStart",
+		"ConnectDone err:<nil>",
+		"PromptDetected ABC> ",
+		"SendStart Command",
+		`SendDone Command "GOT:Command\n" err:<nil>`,
+	}, events)
+}
+
+func TestSessionInvalidErrorPattern(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithErrorPatterns("BadRegex("))
+	assert.Contains(t, err.Error(), "invalid error pattern")
+	assert.Nil(t, session)
+}
+
+func TestCheckResponseCommonPatterns(t *testing.T) {
+	patterns, err := compileErrorPatterns(CommonErrorPatterns)
+	assert.NoError(t, err)
+
+	for _, response := range []string{
+		"show foo\n% Invalid input detected at '^' marker.\n",
+		"    ^\n% Incomplete command.\n",
+		"syntax error, expecting <command>\n",
+		"ERROR: unknown parameter\n",
+	} {
+		assert.Error(t, checkResponse("cmd", response, patterns), response)
+	}
+	assert.NoError(t, checkResponse("cmd", "interface up\nno errors\n", patterns))
+}
+
+// Delivers a server that serves exec requests, as well as an interactive shell.
+func dummyServerWithExec(t *testing.T) (*dummyShell, *testserver.SSHServer) {
+	dummySh := &dummyShell{}
+	ts := testserver.NewSSHServerHandler(t, testserver.TestUserName, testserver.TestPassword,
+		func(t assert.TestingT) testserver.SSHHandler {
+			return dummySh
+		},
+		testserver.RequestTypes([]string{"pty-req", "shell"}),
+		testserver.ExecHandler(func(command string) (string, int) {
+			if command == "fail" {
+				return "failed\r\n", 1
+			}
+			return fmt.Sprintf("EXEC:%s\r\n", command), 0
+		}))
+	return dummySh, ts
+}
+
+func TestSessionExec(t *testing.T) {
+	dummySh, ts := dummyServerWithExec(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()),
+		WithErrorPatterns(`^EXEC:bad`))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	resp, err := session.Exec("show version")
+	assert.NoError(t, err)
+	assert.Equal(t, "EXEC:show version\n", resp)
+
+	// The shell remains available alongside exec channels.
+	resp, err = session.Send("Command")
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:Command\n", resp)
+
+	resp, err = session.Send("show interfaces", ViaExec())
+	assert.NoError(t, err)
+	assert.Equal(t, "EXEC:show interfaces\n", resp)
+	assert.Equal(t, []string{"Command\n"}, dummySh.lines, "Expecting exec commands not to be sent to the shell")
+
+	resp, err = session.Exec("fail")
+	assert.Equal(t, "failed\n", resp)
+	var exitErr *ssh.ExitError
+	assert.True(t, errors.As(err, &exitErr), "Expecting an ExitError")
+	assert.Equal(t, 1, exitErr.ExitStatus())
+
+	resp, err = session.Exec("bad command")
+	assert.Equal(t, "EXEC:bad command\n", resp)
+	var cmdErr *CommandError
+	assert.True(t, errors.As(err, &cmdErr), "Expecting a CommandError")
+}
+
+func TestSessionExecUnsupported(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	_, err = session.Exec("show version")
+	assert.True(t, errors.Is(err, ErrExecUnsupported), "Expecting exec to be unsupported")
+
+	resp, err := session.Send("Command", ViaExec())
+	assert.NoError(t, err)
+	assert.Equal(t, "GOT:Command\n", resp, "Expecting command to be sent to the shell")
+}
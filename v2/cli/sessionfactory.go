@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/damianoneill/net/v2/target"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -11,6 +12,17 @@ type SessionFactory interface {
 	NewSession(ctx context.Context, sshcfg *ssh.ClientConfig, target string, opts ...SessionOption) (s Session, err error)
 }
 
+// NewSessionForTarget uses the factory to establish a cli session with the target's cli endpoint, using the ssh
+// configuration built from the credentials resolved by r.
+func NewSessionForTarget(ctx context.Context, factory SessionFactory, t *target.Target, r target.CredentialResolver,
+	opts ...SessionOption) (Session, error) {
+	sshcfg, err := target.SSHClientConfig(ctx, r, t, target.CLI)
+	if err != nil {
+		return nil, err
+	}
+	return factory.NewSession(ctx, sshcfg, t.Endpoint(target.CLI), opts...)
+}
+
 // SessionOption implements options for configuring session behaviour.
 type SessionOption func(*SessionConfig)
 
@@ -41,6 +53,64 @@ func WithTimeout(timeout time.Duration) SessionOption {
 	}
 }
 
+// WithErrorPatterns defines regular expressions that identify device error messages in command responses.
+// If any line of a response matches one of the patterns, Send returns a *CommandError, along with the response.
+// CommonErrorPatterns defines a set of patterns suitable for many devices.
+func WithErrorPatterns(patterns ...string) SessionOption {
+	return func(c *SessionConfig) {
+		c.errorPatterns = patterns
+	}
+}
+
+// WithKeepalive enables SSH keepalive requests at the interval; the session transport is closed if maxMissed
+// consecutive requests go unanswered.
+func WithKeepalive(interval time.Duration, maxMissed int) SessionOption {
+	return func(c *SessionConfig) {
+		c.transport.KeepaliveInterval = interval
+		c.transport.KeepaliveMaxMissed = maxMissed
+	}
+}
+
+// WithEnv defines environment variables that are requested when the session is established, overriding any
+// defined by the profile. Servers commonly reject environment variables that they have not been configured to
+// accept, in which case the rejection is reported to the Error trace hook and the session is established without
+// them.
+func WithEnv(env map[string]string) SessionOption {
+	return func(c *SessionConfig) {
+		c.envVars = env
+	}
+}
+
+// WithProfile defines the shell initialisation applied to the session; its commands are executed before any defined
+// by WithCommands.
+func WithProfile(p Profile) SessionOption {
+	return func(c *SessionConfig) {
+		c.profile = &p
+		c.deviceType = ""
+	}
+}
+
+// WithDeviceType selects the profile registered for the device type, such as "cisco_ios", "junos" or "linux", as
+// for WithProfile. Establishing the session fails if no profile is registered for the device type.
+func WithDeviceType(deviceType string) SessionOption {
+	return func(c *SessionConfig) {
+		c.deviceType = deviceType
+		c.profile = nil
+	}
+}
+
+// WithConnectRetries defines the number of times that establishing a session is retried, following a failure to
+// connect to the server or to capture the cli prompt; devices sometimes drop the first connection after a reboot.
+// Authentication failures are not retried.
+// The first retry is made after the backoff delay, which is doubled for each subsequent retry.
+// If all attempts fail, the error returned is a *ConnectError that lists the failure of each attempt.
+func WithConnectRetries(retries int, backoff time.Duration) SessionOption {
+	return func(c *SessionConfig) {
+		c.connectRetries = retries
+		c.connectBackoff = backoff
+	}
+}
+
 // SessionConfig defines properties controlling session behaviour.
 type SessionConfig struct {
 	// Any commands that should be executed after establishing a new session.
@@ -52,6 +122,24 @@ type SessionConfig struct {
 	pattern string
 	// See WithTimeout above.
 	readTimeout time.Duration
+	// See WithErrorPatterns above.
+	errorPatterns []string
+	// See WithKeepalive above.
+	transport TransportConfig
+	// See WithEnv above.
+	envVars map[string]string
+	// See WithProfile and WithDeviceType above.
+	profile    *Profile
+	deviceType string
+	// See WithConnectRetries above.
+	connectRetries int
+	connectBackoff time.Duration
+	// See WithDryRun.
+	dryRunPatterns []string
+	dryRun         func(command string)
+	// See WithOutputLimits.
+	maxSendOutput    int64
+	maxSessionOutput int64
 }
 
 var DefaultConfig = SessionConfig{
@@ -70,13 +158,54 @@ func (f FactoryImpl) NewSession(ctx context.Context, sshcfg *ssh.ClientConfig, t
 	for _, opt := range opts {
 		opt(&config)
 	}
+	if err = config.resolveProfile(); err != nil {
+		return nil, err
+	}
 
-	t, err := NewSSHTransport(ctx, sshcfg, &TransportConfig{}, target)
+	if config.connectRetries <= 0 {
+		return f.connect(ctx, sshcfg, target, &config)
+	}
+
+	trace := ContextCliTrace(ctx)
+	cerr := &ConnectError{Target: target}
+	delay := config.connectBackoff
+	for attempt := 1; ; attempt++ {
+		s, err = f.connect(ctx, sshcfg, target, &config)
+		if err == nil {
+			return s, nil
+		}
+		cerr.Attempts = append(cerr.Attempts, err)
+		if !isRetryable(err) || attempt > config.connectRetries {
+			return nil, cerr
+		}
+
+		trace.ConnectRetry(target, attempt, err, delay)
+		select {
+		case <-ctx.Done():
+			cerr.Attempts = append(cerr.Attempts, ctx.Err())
+			return nil, cerr
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Makes a single attempt to establish a session.
+func (f FactoryImpl) connect(ctx context.Context, sshcfg *ssh.ClientConfig, target string, config *SessionConfig,
+) (Session, error) {
+	tcfg := config.transport
+	tcfg.Env = config.env()
+	t, err := NewSSHTransport(ctx, sshcfg, &tcfg, target)
 	if err != nil {
-		return nil, err
+		return nil, &transportError{err}
 	}
 
-	return NewCliSession(ctx, t, &config)
+	s, err := NewCliSession(ctx, t, config)
+	if err != nil {
+		_ = t.Close()
+		return nil, err
+	}
+	return s, nil
 }
 
 func NewSessionFactory(cfg *SessionConfig) SessionFactory {
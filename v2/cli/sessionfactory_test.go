@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/damianoneill/net/v2/netconf/testserver"
+	"github.com/damianoneill/net/v2/target"
 
 	assert "github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
@@ -45,6 +47,33 @@ func TestSessionSetupWithInitCommands(t *testing.T) {
 	assert.Equal(t, "Init2\n", dummySh.lines[1])
 }
 
+func TestSessionForTarget(t *testing.T) {
+	dummySh, ts := dummyServer(t)
+	defer ts.Close()
+
+	tgt := &target.Target{
+		Address:         "localhost",
+		Ports:           map[target.Protocol]int{target.CLI: ts.Port()},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	creds := &target.Credentials{Username: testserver.TestUserName, Password: testserver.TestPassword}
+
+	session, err := NewSessionForTarget(context.Background(), NewSessionFactory(nil), tgt,
+		target.StaticCredentials(creds), WithCommands("Init1"))
+	assert.NoError(t, err)
+	assert.NotNil(t, session, "Session should not be nil")
+	defer session.Close()
+
+	assert.Equal(t, "Init1\n", dummySh.lines[0])
+}
+
+func TestSessionForTargetResolveFailure(t *testing.T) {
+	session, err := NewSessionForTarget(context.Background(), NewSessionFactory(nil),
+		&target.Target{Address: "localhost"}, target.StaticCredentials(nil))
+	assert.EqualError(t, err, "no cli credentials for localhost")
+	assert.Nil(t, session, "Session should be nil")
+}
+
 func TestSessionSetupWithFailingInitCommands(t *testing.T) {
 	dummySh, ts := dummyServer(t)
 	defer ts.Close()
@@ -79,6 +108,31 @@ func TestSessionSetupWithTimeout(t *testing.T) {
 	assert.Equal(t, time.Millisecond*250, session.(*SessionImpl).cfg.readTimeout)
 }
 
+func TestSessionSetupWithKeepalive(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	var closedErrs []error
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		ConnectionClosed: func(target string, err error) {
+			closedErrs = append(closedErrs, err)
+		},
+	})
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(ctx, validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithKeepalive(5*time.Millisecond, 2))
+	assert.NoError(t, err)
+	assert.NotNil(t, session, "Session should not be nil")
+
+	// The server replies to keepalive requests, so the session should remain open.
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, closedErrs)
+
+	session.Close()
+	assert.Equal(t, []error{nil}, closedErrs)
+}
+
 func TestSessionSetupInvalidOptions(t *testing.T) {
 	_, ts := dummyServer(t)
 	defer ts.Close()
@@ -93,6 +147,212 @@ func TestSessionSetupInvalidOptions(t *testing.T) {
 	assert.Nil(t, session)
 }
 
+// Delivers a server that accepts env requests, recording the environment variables requested.
+func dummyServerWithEnv(t *testing.T, accept bool) (*dummyShell, *testserver.SSHServer, func() []string) {
+	var mu sync.Mutex
+	var env []string
+	types := []string{"pty-req", "shell"}
+	if accept {
+		types = append(types, "env")
+	}
+	dummySh := &dummyShell{}
+	ts := testserver.NewSSHServerHandler(t, testserver.TestUserName, testserver.TestPassword,
+		func(t assert.TestingT) testserver.SSHHandler {
+			return dummySh
+		},
+		testserver.RequestTypes(types),
+		testserver.RequestObserver(func(req *ssh.Request) {
+			if req.Type == "env" {
+				var nv struct{ Name, Value string }
+				assert.NoError(t, ssh.Unmarshal(req.Payload, &nv))
+				mu.Lock()
+				env = append(env, nv.Name+"="+nv.Value)
+				mu.Unlock()
+			}
+		}))
+	return dummySh, ts, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, env...)
+	}
+}
+
+func TestSessionSetupWithDeviceType(t *testing.T) {
+	dummySh, ts, env := dummyServerWithEnv(t, true)
+	defer ts.Close()
+
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithDeviceType("linux"), WithEnv(map[string]string{"LANG": "C"}),
+		WithCommands("Init1"))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	assert.Equal(t, []string{"stty cols 512\n", "Init1\n"}, dummySh.lines, "Expecting profile commands first")
+	assert.Equal(t, []string{"LANG=C", "PAGER=cat"}, env())
+	assert.Equal(t, &LinuxProfile, session.Profile())
+	shared, err := NewSharedSession(session)
+	assert.NoError(t, err)
+	assert.Equal(t, &LinuxProfile, shared.Profile())
+	rs, err := NewResilientSession(context.Background(), func(context.Context) (Session, error) { return session, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, &LinuxProfile, rs.Profile())
+}
+
+func TestSessionSetupWithProfile(t *testing.T) {
+	dummySh, ts, env := dummyServerWithEnv(t, true)
+	defer ts.Close()
+
+	profile := Profile{Name: "custom", Commands: []string{"paging off"}, Env: map[string]string{"A": "1", "B": "2"}}
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithProfile(profile), WithEnv(map[string]string{"B": "3"}))
+	assert.NoError(t, err)
+	defer session.Close()
+
+	assert.Equal(t, []string{"paging off\n"}, dummySh.lines)
+	assert.Equal(t, []string{"A=1", "B=3"}, env(), "Expecting WithEnv to override profile")
+	assert.Equal(t, "custom", session.Profile().Name)
+}
+
+func TestSessionSetupWithRejectedEnv(t *testing.T) {
+	_, ts, env := dummyServerWithEnv(t, false)
+	defer ts.Close()
+
+	var errs []string
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		Error: func(context string, err error) {
+			errs = append(errs, context)
+		},
+	})
+	session, err := NewSessionFactory(nil).NewSession(ctx, validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithEnv(map[string]string{"LANG": "C"}))
+	assert.NoError(t, err, "Expecting session to be established without the environment variable")
+	defer session.Close()
+
+	assert.Equal(t, []string{"LANG=C"}, env())
+	assert.Equal(t, []string{"Setenv LANG"}, errs)
+	assert.Nil(t, session.Profile())
+}
+
+func TestSessionSetupUnknownDeviceType(t *testing.T) {
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(), "localhost:0",
+		WithDeviceType("unknown"))
+	assert.EqualError(t, err, `unknown device type "unknown"`)
+	assert.Nil(t, session)
+
+	p, ok := LookupProfile("cisco_ios")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"terminal length 0", "terminal width 0"}, p.Commands)
+}
+
+func TestSessionSetupWithConnectRetries(t *testing.T) {
+	// The shell of the first two connections fails, so that the prompt cannot be captured.
+	connections := 0
+	dummySh := &dummyShell{}
+	ts := testserver.NewSSHServerHandler(t, testserver.TestUserName, testserver.TestPassword,
+		func(t assert.TestingT) testserver.SSHHandler {
+			connections++
+			if connections <= 2 {
+				return &dummyShell{fail: true}
+			}
+			return dummySh
+		},
+		testserver.RequestTypes([]string{"pty-req", "shell"}))
+	defer ts.Close()
+
+	var attempts []int
+	var delays []time.Duration
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		ConnectRetry: func(target string, attempt int, err error, delay time.Duration) {
+			attempts = append(attempts, attempt)
+			delays = append(delays, delay)
+			assert.Error(t, err)
+		},
+	})
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(ctx, validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithConnectRetries(3, 10*time.Millisecond), WithCommands("Init1"))
+	assert.NoError(t, err)
+	assert.NotNil(t, session, "Session should not be nil")
+	defer session.Close()
+
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, delays)
+	assert.Equal(t, []string{"Init1\n"}, dummySh.lines)
+}
+
+func TestSessionSetupConnectRetriesExhausted(t *testing.T) {
+	_, ts := dummyServerWithFailingShell(t)
+	defer ts.Close()
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(context.Background(), validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithConnectRetries(2, time.Millisecond))
+	assert.Nil(t, session, "Session should be nil")
+
+	var cerr *ConnectError
+	assert.ErrorAs(t, err, &cerr)
+	assert.Len(t, cerr.Attempts, 3)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+	assert.Contains(t, err.Error(), "attempt 3: ")
+	assert.Contains(t, err.Error(), "EOF")
+}
+
+func TestSessionSetupConnectRetriesDialFailure(t *testing.T) {
+	ts := testserver.NewSSHServer(t, testserver.TestUserName, testserver.TestPassword)
+	target := fmt.Sprintf("localhost:%d", ts.Port())
+	ts.Close()
+
+	var retries int
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		ConnectRetry: func(target string, attempt int, err error, delay time.Duration) { retries++ },
+	})
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(ctx, validSSHConfig(), target, WithConnectRetries(1, time.Millisecond))
+	assert.Nil(t, session, "Session should be nil")
+	assert.Equal(t, 1, retries)
+
+	var cerr *ConnectError
+	assert.ErrorAs(t, err, &cerr)
+	assert.Len(t, cerr.Attempts, 2)
+	assert.Contains(t, err.Error(), "new Clisession failed")
+}
+
+func TestSessionSetupInitCommandFailureNotRetried(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	var retries int
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		ConnectRetry: func(target string, attempt int, err error, delay time.Duration) { retries++ },
+	})
+
+	factory := NewSessionFactory(nil)
+	session, err := factory.NewSession(ctx, validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithConnectRetries(2, time.Millisecond), WithCommands("close"))
+	assert.Nil(t, session, "Session should be nil")
+	assert.Zero(t, retries, "Expecting init command failure not to be retried")
+	assert.Contains(t, err.Error(), "after 1 attempts")
+}
+
+func TestSessionSetupAuthFailureNotRetried(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	var retries int
+	ctx := WithCliTrace(context.Background(), &CliTrace{
+		ConnectRetry: func(target string, attempt int, err error, delay time.Duration) { retries++ },
+	})
+
+	session, err := NewSessionFactory(nil).NewSession(ctx, sshConfigWithPassword("wrong"),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithConnectRetries(2, time.Millisecond))
+	assert.Nil(t, session, "Session should be nil")
+	assert.Zero(t, retries, "Expecting authentication failure not to be retried")
+	assert.Contains(t, err.Error(), "after 1 attempts")
+	assert.Contains(t, err.Error(), sshAuthFailure)
+}
+
 type dummyShell struct {
 	// Prompt that should be emitted.
 	prompt string
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Priority defines the order in which commands queued on a SharedSession are sent.
+type Priority int
+
+const (
+	// Batch commands are sent when no interactive command is waiting, subject to WithInteractiveBurst.
+	Batch Priority = iota
+	// Interactive commands are sent ahead of batch commands. Interactive is the default priority.
+	Interactive
+)
+
+// ErrCommandTimeout is returned by a SharedSession when a command does not complete within its timeout.
+var ErrCommandTimeout = errors.New("cli command timed out")
+
+// ErrSessionClosed is returned by a SharedSession for commands sent after, or queued at the time of, Close.
+var ErrSessionClosed = errors.New("cli session closed")
+
+// AtPriority defines the priority with which the command is queued by a SharedSession.
+// Defaults to Interactive. Ignored by other sessions.
+func AtPriority(p Priority) SendOption {
+	return func(c *SendConfig) {
+		c.priority = p
+		c.prioritySet = true
+	}
+}
+
+// SendTimeout defines the time allowed for a command sent by a SharedSession to complete, including the time spent
+// waiting in the queue, in place of the session's default timeout. Ignored by other sessions.
+func SendTimeout(timeout time.Duration) SendOption {
+	return func(c *SendConfig) {
+		c.timeout = timeout
+	}
+}
+
+// SharedSessionOption implements options for configuring SharedSession behaviour.
+type SharedSessionOption func(*sharedConfig)
+
+// WithCommandTimeout defines the default time allowed for a command to complete, including the time spent waiting
+// in the queue. Zero, the default, means no timeout.
+func WithCommandTimeout(timeout time.Duration) SharedSessionOption {
+	return func(c *sharedConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithInteractiveBurst defines the maximum number of consecutive interactive commands that are sent while a batch
+// command is waiting, so that batch commands are not starved. Must be at least 1. Defaults to 4.
+func WithInteractiveBurst(n int) SharedSessionOption {
+	return func(c *sharedConfig) {
+		if n < 1 {
+			c.setErr(errors.Errorf("invalid interactive burst %d, must be at least 1", n))
+			return
+		}
+		c.burst = n
+	}
+}
+
+type sharedConfig struct {
+	timeout time.Duration
+	burst   int
+	err     error
+}
+
+// Records the first invalid option.
+func (c *sharedConfig) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+const defaultInteractiveBurst = 4
+
+// SharedSession serialises the commands sent by multiple goroutines over a single session, for devices that permit
+// only a single VTY per credential.
+//
+// Commands are queued in order of arrival at each priority, and sent one at a time. Interactive commands are sent
+// ahead of batch commands, but a waiting batch command is sent after each burst of interactive commands.
+//
+// A command that times out while queued is never sent. A command that times out once sent returns
+// ErrCommandTimeout to the caller, but the session waits for its response before sending the next command, so that
+// responses are not confused.
+type SharedSession struct {
+	s   Session
+	cfg sharedConfig
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queues [2][]*sharedCommand
+	// The number of consecutive interactive commands sent while batch commands were waiting.
+	run    int
+	closed bool
+	done   chan struct{}
+}
+
+// Encapsulates a queued command.
+type sharedCommand struct {
+	send   func() (string, error)
+	result chan sharedResult
+}
+
+type sharedResult struct {
+	response string
+	err      error
+}
+
+// NewSharedSession delivers a session that serialises commands over s. Closing the shared session closes s.
+// If an option is invalid, an error is returned and s is left open.
+func NewSharedSession(s Session, opts ...SharedSessionOption) (*SharedSession, error) {
+	cfg := sharedConfig{burst: defaultInteractiveBurst}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.err != nil {
+		return nil, cfg.err
+	}
+
+	ss := &SharedSession{s: s, cfg: cfg, done: make(chan struct{})}
+	ss.cond = sync.NewCond(&ss.mu)
+	go ss.dispatch()
+	return ss, nil
+}
+
+// Send queues the value to be sent to the server as described by Session.Send, and returns the response once it has
+// been received. The AtPriority and SendTimeout options control the queueing of the command.
+func (ss *SharedSession) Send(value string, opts ...SendOption) (string, error) {
+	config := &SendConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	priority := Interactive
+	if config.prioritySet {
+		priority = config.priority
+	}
+	timeout := ss.cfg.timeout
+	if config.timeout > 0 {
+		timeout = config.timeout
+	}
+
+	return ss.enqueue(value, priority, timeout, func() (string, error) { return ss.s.Send(value, opts...) })
+}
+
+// Exec queues the command to be run on an exec channel, as described by Session.Exec, at Interactive priority.
+func (ss *SharedSession) Exec(command string) (string, error) {
+	return ss.enqueue(command, Interactive, ss.cfg.timeout, func() (string, error) { return ss.s.Exec(command) })
+}
+
+// Profile delivers the profile applied to the underlying session, or nil if none.
+func (ss *SharedSession) Profile() *Profile {
+	return ss.s.Profile()
+}
+
+// Close fails any queued commands with ErrSessionClosed and closes the underlying session.
+func (ss *SharedSession) Close() error {
+	ss.mu.Lock()
+	if ss.closed {
+		ss.mu.Unlock()
+		return nil
+	}
+	ss.closed = true
+	for p := range ss.queues {
+		for _, cmd := range ss.queues[p] {
+			cmd.result <- sharedResult{err: ErrSessionClosed}
+		}
+		ss.queues[p] = nil
+	}
+	ss.cond.Broadcast()
+	ss.mu.Unlock()
+
+	err := ss.s.Close()
+	<-ss.done
+	return err
+}
+
+// Queued delivers the number of commands waiting to be sent at the priority.
+func (ss *SharedSession) Queued(p Priority) int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return len(ss.queues[p])
+}
+
+// Queues the command and waits for its result, or for the timeout to elapse.
+func (ss *SharedSession) enqueue(command string, p Priority, timeout time.Duration, send func() (string, error),
+) (string, error) {
+	if p != Batch && p != Interactive {
+		return "", errors.Errorf("invalid priority %d", p)
+	}
+	cmd := &sharedCommand{send: send, result: make(chan sharedResult, 1)}
+
+	ss.mu.Lock()
+	if ss.closed {
+		ss.mu.Unlock()
+		return "", ErrSessionClosed
+	}
+	ss.queues[p] = append(ss.queues[p], cmd)
+	ss.cond.Signal()
+	ss.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case r := <-cmd.result:
+		return r.response, r.err
+	case <-expired:
+	}
+
+	// The command may complete as the timeout elapses.
+	select {
+	case r := <-cmd.result:
+		return r.response, r.err
+	default:
+	}
+	if ss.remove(p, cmd) {
+		return "", errors.Wrapf(ErrCommandTimeout, "command %q not sent within %s", command, timeout)
+	}
+	return "", errors.Wrapf(ErrCommandTimeout, "no response to command %q within %s", command, timeout)
+}
+
+// Removes the command from the queue, returning false if it is no longer queued.
+func (ss *SharedSession) remove(p Priority, cmd *sharedCommand) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for i, c := range ss.queues[p] {
+		if c == cmd {
+			ss.queues[p] = append(ss.queues[p][:i], ss.queues[p][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Sends queued commands, one at a time, until the session is closed.
+func (ss *SharedSession) dispatch() {
+	defer close(ss.done)
+	for {
+		cmd := ss.next()
+		if cmd == nil {
+			return
+		}
+		response, err := cmd.send()
+		cmd.result <- sharedResult{response: response, err: err}
+	}
+}
+
+// Waits for and dequeues the next command to be sent, returning nil once the session is closed.
+func (ss *SharedSession) next() *sharedCommand {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for !ss.closed && len(ss.queues[Interactive]) == 0 && len(ss.queues[Batch]) == 0 {
+		ss.cond.Wait()
+	}
+	if ss.closed {
+		return nil
+	}
+
+	p := Interactive
+	switch {
+	case len(ss.queues[Batch]) == 0:
+		ss.run = 0
+	case len(ss.queues[Interactive]) == 0 || ss.run >= ss.cfg.burst:
+		p = Batch
+		ss.run = 0
+	default:
+		ss.run++
+	}
+	cmd := ss.queues[p][0]
+	ss.queues[p] = ss.queues[p][1:]
+	return cmd
+}
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSharedSessionConcurrentSends(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	shared, err := NewSharedSession(session, WithCommandTimeout(5*time.Second))
+	assert.NoError(t, err)
+	defer shared.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			priority := Interactive
+			if i%2 == 0 {
+				priority = Batch
+			}
+			cmd := fmt.Sprintf("Command%d", i)
+			resp, err := shared.Send(cmd, AtPriority(priority))
+			assert.NoError(t, err)
+			assert.Equal(t, "GOT:"+cmd+"\n", resp)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSharedSessionSendBatch(t *testing.T) {
+	_, ts := dummyServer(t)
+	defer ts.Close()
+
+	session, err := NewSessionFactory(nil).NewSession(context.Background(), validSSHConfig(),
+		fmt.Sprintf("localhost:%d", ts.Port()), WithErrorPatterns(`^GOT:bad`))
+	assert.NoError(t, err)
+	shared, err := NewSharedSession(session)
+	assert.NoError(t, err)
+	defer shared.Close()
+
+	results, err := SendBatch(shared, []string{"first", "bad command", "last"}, AbortOnCommandError(),
+		WithBatchSendOptions(AtPriority(Batch)))
+	assert.IsType(t, &CommandError{}, err)
+	assert.Len(t, results, 2, "Expecting the batch to stop at the failed command")
+	assert.Equal(t, BatchResult{Command: "first", Output: "GOT:first\n"}, results[0])
+}
+
+// Session that records the commands sent, blocking each send until released.
+type gatedSession struct {
+	mu      sync.Mutex
+	sent    []string
+	started chan string
+	release chan struct{}
+	closed  chan struct{}
+}
+
+func newGatedSession() *gatedSession {
+	return &gatedSession{started: make(chan string, 100), release: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (g *gatedSession) Send(value string, opts ...SendOption) (string, error) {
+	g.mu.Lock()
+	g.sent = append(g.sent, value)
+	g.mu.Unlock()
+	g.started <- value
+	select {
+	case <-g.release:
+		return "OK:" + value, nil
+	case <-g.closed:
+		return "", errors.New("closed")
+	}
+}
+
+func (g *gatedSession) Exec(command string) (string, error) {
+	return g.Send(command)
+}
+
+func (g *gatedSession) Profile() *Profile {
+	return nil
+}
+
+func (g *gatedSession) Close() error {
+	close(g.closed)
+	return nil
+}
+
+func (g *gatedSession) commands() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.sent...)
+}
+
+// Sends the command asynchronously, expecting the response delivered by gatedSession.
+func sendAsync(t *testing.T, shared *SharedSession, wg *sync.WaitGroup, cmd string, p Priority) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err := shared.Send(cmd, AtPriority(p))
+		assert.NoError(t, err)
+		assert.Equal(t, "OK:"+cmd, resp)
+	}()
+}
+
+func waitQueued(t *testing.T, shared *SharedSession, p Priority, n int) {
+	assert.Eventually(t, func() bool { return shared.Queued(p) == n }, time.Second, time.Millisecond)
+}
+
+func TestSharedSessionPriorities(t *testing.T) {
+	gs := newGatedSession()
+	shared, err := NewSharedSession(gs, WithInteractiveBurst(2))
+	assert.NoError(t, err)
+	defer shared.Close()
+
+	var wg sync.WaitGroup
+	// Occupy the session, so that the following commands are queued.
+	sendAsync(t, shared, &wg, "first", Batch)
+	<-gs.started
+
+	sendAsync(t, shared, &wg, "b1", Batch)
+	waitQueued(t, shared, Batch, 1)
+	sendAsync(t, shared, &wg, "b2", Batch)
+	waitQueued(t, shared, Batch, 2)
+	for i := 1; i <= 4; i++ {
+		sendAsync(t, shared, &wg, fmt.Sprintf("i%d", i), Interactive)
+		waitQueued(t, shared, Interactive, i)
+	}
+
+	for i := 0; i < 7; i++ {
+		gs.release <- struct{}{}
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"first", "i1", "i2", "b1", "i3", "i4", "b2"}, gs.commands(),
+		"Expecting interactive commands first, with a batch command after each burst")
+}
+
+func TestSharedSessionTimeouts(t *testing.T) {
+	gs := newGatedSession()
+	shared, err := NewSharedSession(gs, WithCommandTimeout(50*time.Millisecond))
+	assert.NoError(t, err)
+	defer shared.Close()
+
+	// The first command times out once sent, and the second while queued.
+	_, err = shared.Send("slow")
+	assert.True(t, errors.Is(err, ErrCommandTimeout), "Expecting command to time out")
+	assert.Contains(t, err.Error(), `no response to command "slow"`)
+
+	_, err = shared.Send("queued", SendTimeout(10*time.Millisecond))
+	assert.True(t, errors.Is(err, ErrCommandTimeout), "Expecting command to time out")
+	assert.Contains(t, err.Error(), `command "queued" not sent`)
+	assert.Zero(t, shared.Queued(Interactive), "Expecting timed out command to be removed from the queue")
+
+	// The next command is sent once the response to the timed out command has been received.
+	<-gs.started
+	gs.release <- struct{}{}
+	var wg sync.WaitGroup
+	sendAsync(t, shared, &wg, "next", Interactive)
+	assert.Equal(t, "next", <-gs.started)
+	gs.release <- struct{}{}
+	wg.Wait()
+	assert.Equal(t, []string{"slow", "next"}, gs.commands())
+}
+
+func TestInvalidInteractiveBurst(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		_, err := NewSharedSession(newGatedSession(), WithInteractiveBurst(n))
+		assert.EqualError(t, err, fmt.Sprintf("invalid interactive burst %d, must be at least 1", n))
+	}
+}
+
+func TestSharedSessionClose(t *testing.T) {
+	gs := newGatedSession()
+	shared, err := NewSharedSession(gs)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make(chan error, 2)
+	for _, cmd := range []string{"running", "queued"} {
+		wg.Add(1)
+		go func(cmd string) {
+			defer wg.Done()
+			_, err := shared.Send(cmd)
+			results <- err
+		}(cmd)
+		if cmd == "running" {
+			<-gs.started
+		}
+	}
+	waitQueued(t, shared, Interactive, 1)
+
+	assert.NoError(t, shared.Close())
+	wg.Wait()
+	close(results)
+	var errs []error
+	for err := range results {
+		errs = append(errs, err)
+	}
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs, ErrSessionClosed, "Expecting queued command to fail")
+
+	_, err = shared.Send("after")
+	assert.Equal(t, ErrSessionClosed, err)
+	_, err = shared.Exec("after")
+	assert.Equal(t, ErrSessionClosed, err)
+}
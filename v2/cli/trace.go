@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/imdario/mergo"
+)
+
+// unique type to prevent assignment.
+type cliEventContextKey struct{}
+
+// ContextCliTrace returns the Trace associated with the
+// provided context. If none, it returns NoOpLoggingHooks.
+func ContextCliTrace(ctx context.Context) *CliTrace {
+	trace, _ := ctx.Value(cliEventContextKey{}).(*CliTrace)
+	if trace == nil {
+		trace = NoOpLoggingHooks
+	} else {
+		_ = mergo.Merge(trace, NoOpLoggingHooks)
+	}
+	return trace
+}
+
+// WithCliTrace returns a new context based on the provided parent
+// ctx. Cli sessions created with the returned context will use
+// the provided trace hooks
+func WithCliTrace(ctx context.Context, trace *CliTrace) context.Context {
+	ctx = context.WithValue(ctx, cliEventContextKey{}, trace)
+	return ctx
+}
+
+// CliTrace defines a structure for handling trace events.
+
+//nolint:golint,revive
+type CliTrace struct {
+	// ConnectStart is called when starting to create an ssh connection to a remote server.
+	ConnectStart func(target string)
+
+	// ConnectDone is called when the connection attempt completes, with err indicating
+	// whether it was successful.
+	ConnectDone func(target string, err error, d time.Duration)
+
+	// ConnectRetry is called when an attempt to establish a session fails and will be retried, with attempt
+	// identifying the failed attempt and delay the period that will elapse before the next attempt.
+	ConnectRetry func(target string, attempt int, err error, delay time.Duration)
+
+	// Reconnect is called after each attempt by a ResilientSession to re-establish a session following the loss of
+	// the connection, reported by cause, with err indicating whether the attempt was successful.
+	Reconnect func(attempt int, cause, err error)
+
+	// ConnectionClosed is called after a connection has been closed, with err indicating the cause if the
+	// connection was closed because the server was unresponsive.
+	ConnectionClosed func(target string, err error)
+
+	// PromptDetected is called when the cli prompt has been captured from the server.
+	PromptDetected func(prompt string)
+
+	// SendStart is called before a command is sent to the server.
+	SendStart func(command string)
+
+	// SendDone is called after the response to a command has been received, or the send has failed.
+	SendDone func(command, response string, err error, d time.Duration)
+
+	// ExecStart is called before a command is run on an exec channel by Session.Exec.
+	ExecStart func(command string)
+
+	// ExecDone is called after a command run by Session.Exec has completed, or failed.
+	ExecDone func(command, response string, err error, d time.Duration)
+
+	// ReadChunk is called for each block of data read from the server.
+	ReadChunk func(buf []byte, err error)
+
+	// Error is called after an error condition has been detected.
+	Error func(context string, err error)
+}
+
+// DefaultLoggingHooks provides a default logging hook to report errors.
+var DefaultLoggingHooks = &CliTrace{
+	Error: func(context string, err error) {
+		log.Printf("CLI-Error context:%s err:%v\n", context, err)
+	},
+}
+
+// DiagnosticLoggingHooks provides a set of default diagnostic hooks
+var DiagnosticLoggingHooks = &CliTrace{
+	ConnectStart: func(target string) {
+		log.Printf("CLI-ConnectStart target:%s\n", target)
+	},
+	ConnectDone: func(target string, err error, d time.Duration) {
+		log.Printf("CLI-ConnectDone target:%s err:%v took:%dms\n", target, err, d.Milliseconds())
+	},
+	ConnectRetry: func(target string, attempt int, err error, delay time.Duration) {
+		log.Printf("CLI-ConnectRetry target:%s attempt:%d err:%v delay:%dms\n", target, attempt, err, delay.Milliseconds())
+	},
+	Reconnect: func(attempt int, cause, err error) {
+		log.Printf("CLI-Reconnect attempt:%d cause:%v err:%v\n", attempt, cause, err)
+	},
+	ConnectionClosed: func(target string, err error) {
+		log.Printf("CLI-ConnectionClosed target:%s err:%v\n", target, err)
+	},
+	PromptDetected: func(prompt string) {
+		log.Printf("CLI-PromptDetected prompt:%q\n", prompt)
+	},
+	SendStart: func(command string) {
+		log.Printf("CLI-SendStart command:%q\n", command)
+	},
+	SendDone: func(command, response string, err error, d time.Duration) {
+		log.Printf("CLI-SendDone command:%q len:%d err:%v took:%dms\n", command, len(response), err, d.Milliseconds())
+	},
+	ExecStart: func(command string) {
+		log.Printf("CLI-ExecStart command:%q\n", command)
+	},
+	ExecDone: func(command, response string, err error, d time.Duration) {
+		log.Printf("CLI-ExecDone command:%q len:%d err:%v took:%dms\n", command, len(response), err, d.Milliseconds())
+	},
+	ReadChunk: func(buf []byte, err error) {
+		log.Printf("CLI-ReadChunk len:%d err:%v\n", len(buf), err)
+	},
+
+	Error: DefaultLoggingHooks.Error,
+}
+
+// NoOpLoggingHooks provides set of hooks that do nothing.
+var NoOpLoggingHooks = &CliTrace{
+	ConnectStart:     func(target string) {},
+	ConnectDone:      func(target string, err error, d time.Duration) {},
+	ConnectRetry:     func(target string, attempt int, err error, delay time.Duration) {},
+	Reconnect:        func(attempt int, cause, err error) {},
+	ConnectionClosed: func(target string, err error) {},
+	PromptDetected:   func(prompt string) {},
+	SendStart:        func(command string) {},
+	SendDone:         func(command, response string, err error, d time.Duration) {},
+	ExecStart:        func(command string) {},
+	ExecDone:         func(command, response string, err error, d time.Duration) {},
+	ReadChunk:        func(buf []byte, err error) {},
+	Error:            func(context string, err error) {},
+}
@@ -1,8 +1,14 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/damianoneill/net/v2/internal/keepalive"
 
 	"github.com/pkg/errors"
 
@@ -16,9 +22,37 @@ type SSHTransport interface {
 	io.Reader
 }
 
-type TransportConfig struct{}
+// ExecTransport is implemented by transports that can run commands on exec channels of the ssh connection,
+// alongside the interactive shell.
+type ExecTransport interface {
+	// Exec runs the command on a new exec channel, delivering its combined stdout and stderr output once the command
+	// completes. ErrExecUnsupported is returned if the server rejects the exec request.
+	Exec(command string) ([]byte, error)
+}
 
-var DefaultTransportConfig = TransportConfig{}
+// ErrExecUnsupported is returned when a command cannot be run on an exec channel, because the transport does not
+// implement ExecTransport, or the server rejects the exec request.
+var ErrExecUnsupported = errors.New("exec channel not supported")
+
+// ErrPeerUnresponsive is reported to the ConnectionClosed trace hook when a transport is closed because the
+// server failed to reply to keepalive requests.
+var ErrPeerUnresponsive = keepalive.ErrPeerUnresponsive
+
+// TransportConfig defines properties controlling transport behaviour.
+type TransportConfig struct {
+	// Defines the interval at which SSH keepalive requests are sent to the server. Zero disables keepalive requests.
+	KeepaliveInterval time.Duration
+	// Defines the number of consecutive keepalive requests that may go unanswered before the server is deemed
+	// unresponsive, and the transport is closed.
+	KeepaliveMaxMissed int
+	// Defines environment variables requested before the shell is started. Variables that the server rejects are
+	// reported to the Error trace hook, and otherwise ignored.
+	Env map[string]string
+}
+
+var DefaultTransportConfig = TransportConfig{
+	KeepaliveMaxMissed: 3,
+}
 
 type transportImpl struct {
 	cfg     *TransportConfig
@@ -26,15 +60,29 @@ type transportImpl struct {
 	session *ssh.Session
 	io.Reader
 	io.WriteCloser
+	trace     *CliTrace
+	target    string
+	closeOnce sync.Once
+	// Closed when the transport is closed, to stop keepalive requests.
+	done chan struct{}
 }
 
+// NewSSHTransport creates a transport connected to a login shell on target.
+// Trace hooks defined by WithCliTrace on ctx are applied to the connection.
 func NewSSHTransport(ctx context.Context, sshcfg *ssh.ClientConfig, cfg *TransportConfig, target string) (SSHTransport, error) {
 	// Use supplied config, but apply any defaults to unspecified values.
 	resolvedConfig := *cfg
 	_ = mergo.Merge(&resolvedConfig, DefaultTransportConfig)
 
+	trace := ContextCliTrace(ctx)
+
 	var err error
-	t := &transportImpl{cfg: &resolvedConfig}
+	t := &transportImpl{cfg: &resolvedConfig, trace: trace, target: target, done: make(chan struct{})}
+	trace.ConnectStart(target)
+	defer func(begin time.Time) {
+		trace.ConnectDone(target, err, time.Since(begin))
+	}(time.Now())
+
 	t.client, err = ssh.Dial("tcp", target, sshcfg)
 	if err != nil {
 		return nil, errors.Wrap(err, "new Clisession failed")
@@ -46,6 +94,8 @@ func NewSSHTransport(ctx context.Context, sshcfg *ssh.ClientConfig, cfg *Transpo
 		return nil, errors.Wrap(err, "new ssh session failed")
 	}
 
+	t.requestEnv(resolvedConfig.Env)
+
 	t.Reader, _ = t.session.StdoutPipe()
 	// TODO Handle stderr
 	// ereader, _ := session.StderrPipe()
@@ -69,18 +119,78 @@ func NewSSHTransport(ctx context.Context, sshcfg *ssh.ClientConfig, cfg *Transpo
 		return nil, errors.Wrap(err, "login shell failed")
 	}
 
+	if resolvedConfig.KeepaliveInterval > 0 {
+		go keepalive.Run(t.client, resolvedConfig.KeepaliveInterval, resolvedConfig.KeepaliveMaxMissed, t.done,
+			func(err error) {
+				trace.Error("Keepalive failed", err)
+				t.closeWithCause(err)
+			})
+	}
+
 	return t, nil
 }
 
-func (t *transportImpl) Close() error {
-	if t.WriteCloser != nil {
-		_ = t.WriteCloser.Close()
+// Requests the environment variables, in name order.
+func (t *transportImpl) requestEnv(env map[string]string) {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := t.session.Setenv(name, env[name]); err != nil {
+			t.trace.Error("Setenv "+name, err)
+		}
 	}
-	if t.session != nil {
-		_ = t.session.Close()
+}
+
+func (t *transportImpl) Exec(command string) ([]byte, error) {
+	session, err := t.client.NewSession()
+	if err != nil {
+		return nil, errors.Wrap(err, "new ssh session failed")
 	}
-	if t.client != nil {
-		_ = t.client.Close()
+	defer session.Close()
+
+	output := &combinedOutput{}
+	session.Stdout = output
+	session.Stderr = output
+	if err = session.Start(command); err != nil {
+		return nil, errors.Wrap(ErrExecUnsupported, err.Error())
 	}
+	err = session.Wait()
+	return output.b.Bytes(), errors.Wrap(err, "exec command failed")
+}
+
+// Collects the stdout and stderr output of an exec channel, which are written concurrently.
+type combinedOutput struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (o *combinedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.b.Write(p)
+}
+
+func (t *transportImpl) Close() error {
+	t.closeWithCause(nil)
 	return nil
 }
+
+// Closes the transport, reporting cause to the ConnectionClosed trace hook.
+func (t *transportImpl) closeWithCause(cause error) {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		if t.WriteCloser != nil {
+			_ = t.WriteCloser.Close()
+		}
+		if t.session != nil {
+			_ = t.session.Close()
+		}
+		if t.client != nil {
+			_ = t.client.Close()
+		}
+		t.trace.ConnectionClosed(t.target, cause)
+	})
+}
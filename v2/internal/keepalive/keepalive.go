@@ -0,0 +1,62 @@
+// Package keepalive implements SSH transport keepalive and dead-peer detection, shared by the netconf and cli
+// transports.
+package keepalive
+
+import (
+	"errors"
+	"time"
+)
+
+// RequestType is the global request type used for keepalive requests, as used by OpenSSH.
+// Servers reply to unrecognised request types with a failure, which is sufficient to demonstrate liveness.
+const RequestType = "keepalive@openssh.com"
+
+// ErrPeerUnresponsive is reported when the peer has failed to reply to consecutive keepalive requests.
+var ErrPeerUnresponsive = errors.New("ssh keepalive: peer unresponsive")
+
+// Requester is the interface used to issue keepalive requests, as implemented by ssh.Client.
+type Requester interface {
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+}
+
+// Run issues a keepalive request on conn every interval, until stop is closed or conn fails.
+// A request that has not been replied to when the next is due is counted as missed; once maxMissed consecutive
+// requests have been missed, dead is called with ErrPeerUnresponsive and Run returns.
+// Run blocks, so is typically called as a goroutine.
+func Run(conn Requester, interval time.Duration, maxMissed int, stop <-chan struct{}, dead func(error)) {
+	if maxMissed < 1 {
+		maxMissed = 1
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Delivers the outcome of the outstanding request; buffered so the sender never blocks.
+	var replies chan error
+	missed := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case err := <-replies:
+			if err != nil {
+				// The connection has failed, which will be detected by the transport.
+				return
+			}
+			replies, missed = nil, 0
+		case <-ticker.C:
+			if replies != nil {
+				if missed++; missed >= maxMissed {
+					dead(ErrPeerUnresponsive)
+					return
+				}
+				continue
+			}
+			replies = make(chan error, 1)
+			go func(replies chan<- error) {
+				_, _, err := conn.SendRequest(RequestType, true, nil)
+				replies <- err
+			}(replies)
+		}
+	}
+}
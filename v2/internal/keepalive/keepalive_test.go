@@ -0,0 +1,70 @@
+package keepalive
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type requester struct {
+	count int32
+	block chan struct{}
+	err   error
+}
+
+func (r *requester) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	atomic.AddInt32(&r.count, 1)
+	if r.block != nil {
+		<-r.block
+	}
+	return false, nil, r.err
+}
+
+func TestResponsivePeer(t *testing.T) {
+	r := &requester{}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(r, time.Millisecond, 100, stop, func(err error) { t.Error("Not expecting peer to be declared dead") })
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(stop)
+	<-done
+	assert.Greater(t, atomic.LoadInt32(&r.count), int32(2), "Expecting repeated keepalive requests")
+}
+
+func TestUnresponsivePeer(t *testing.T) {
+	r := &requester{block: make(chan struct{})}
+	defer close(r.block)
+
+	dead := make(chan error, 1)
+	go Run(r, time.Millisecond, 3, make(chan struct{}), func(err error) { dead <- err })
+
+	select {
+	case err := <-dead:
+		assert.Equal(t, ErrPeerUnresponsive, err)
+	case <-time.After(time.Second):
+		t.Fatal("Expecting peer to be declared dead")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&r.count), "Expecting a single outstanding request")
+}
+
+func TestFailedConnection(t *testing.T) {
+	r := &requester{err: errors.New("closed")}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Run(r, time.Millisecond, 100, make(chan struct{}), func(err error) { t.Error("Not expecting peer to be declared dead") })
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expecting keepalive to terminate")
+	}
+}
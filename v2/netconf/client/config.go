@@ -1,16 +1,131 @@
 package client
 
+import (
+	"fmt"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
+)
+
 // Defines structs describing netconf configuration.
 
+// ReplyPolicy defines how a reply to an asynchronous request is delivered to a reply channel whose reader is not
+// ready. Replies to synchronous requests are always delivered, as the caller is bound to read them.
+type ReplyPolicy int
+
+const (
+	// ReplyWait holds the reply for up to ReplyTimeoutSecs, without delaying the processing of other messages,
+	// after which it is dropped.
+	ReplyWait ReplyPolicy = iota
+	// ReplyDrop drops the reply immediately.
+	ReplyDrop
+	// ReplyBlock suspends the processing of incoming messages until the reply has been read.
+	ReplyBlock
+)
+
+// Unbuffered is the value of Config.ReadBufferSize or Config.WriteBufferSize that disables buffering, as zero means
+// the default size.
+const Unbuffered = -1
+
 // Config defines properties that configure netconf session behaviour.
 type Config struct {
 	// Defines the time in seconds that the client will wait to receive a hello message from the server.
 	SetupTimeoutSecs int
 	// Indicates that the client should not advertised chunked encoding capability.
 	DisableChunkedCodec bool
+	// Defines the time in seconds that a reply to an asynchronous request will be held waiting for the
+	// caller to read it from the reply channel, before it is dropped.
+	ReplyTimeoutSecs int
+	// Defines the interval in seconds at which SSH keepalive requests are sent to the server. Zero disables
+	// keepalive requests.
+	KeepaliveIntervalSecs int
+	// Defines the number of consecutive keepalive requests that may go unanswered before the server is deemed
+	// unresponsive, and the session is closed.
+	KeepaliveMaxMissed int
+	// Defines the number of notifications that will be buffered by the session waiting for the subscriber to
+	// read them from the notification channel, before they are dropped. Zero means notifications are delivered
+	// directly to the notification channel, and dropped if it is not ready.
+	NotificationBufferSize int
+	// Defines the maximum number of requests that may be awaiting a reply, after which requests fail with
+	// ErrTooManyRequests. Zero means no limit.
+	MaxOutstandingRequests int
+	// Defines how replies to asynchronous requests are delivered to reply channels whose reader is not ready.
+	ReplyPolicy ReplyPolicy
+	// Defines the size in bytes of the buffer used to read from the transport. Zero means the size defined by
+	// DefaultConfig, and Unbuffered means unbuffered.
+	ReadBufferSize int
+	// Defines the size in bytes of the buffer used to write each message to the transport. Zero means the size
+	// defined by DefaultConfig, and Unbuffered means unbuffered.
+	WriteBufferSize int
+	// Indicates that only one request may be awaiting a reply at a time. Further requests are queued by the
+	// session, and sent in turn as each reply is received.
+	SerializeRequests bool
+	// Indicates that incoming messages should be decoded incrementally by a streaming decoder, rather than
+	// scanned as tokens, so that large messages do not need to be buffered by the framing decoder.
+	StreamingDecoder bool
+	// Defines the maximum size in bytes of a message received from the server, such as an rpc-reply. Zero means
+	// no limit.
+	MaxReplySize int
+	// Defines the maximum nesting depth of elements within a message received from the server. Zero means no limit.
+	MaxXMLDepth int
+	// Defines the maximum number of xml tokens within a message received from the server. Zero means no limit.
+	MaxTokenCount int
+	// Defines the name of the framing used for all messages, including hello messages, in place of the framing
+	// negotiated from the capabilities exchanged in the hello messages; for example "raw", for transports that
+	// delimit messages themselves. Empty means hello messages use end-of-message framing, after which the framing
+	// is negotiated. See rfc6242.RegisterFraming.
+	Framing string
+	// Defines the time in seconds allowed for each message to be written to the transport, after which the
+	// session is closed; for example, if the server stops reading. A deadline defined by the context used to
+	// establish the session also applies to the hello message. Zero means no limit.
+	WriteTimeoutSecs int
+	// Defines how a server hello whose session-id is missing or is not a number is handled. Default is
+	// SessionIDWarn.
+	SessionIDPolicy SessionIDPolicy
 }
 
 var DefaultConfig = &Config{
 	SetupTimeoutSecs:    5,
 	DisableChunkedCodec: false,
+	ReplyTimeoutSecs:    60,
+	KeepaliveMaxMissed:  3,
+	ReplyPolicy:         ReplyWait,
+	ReadBufferSize:      4096,
+	WriteBufferSize:     4096,
+}
+
+// Delivers the value, or def if the value is zero.
+func orDefault(value, def int) int {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+// Checks that the configuration values are valid.
+func (c *Config) validate() error {
+	switch {
+	case c.NotificationBufferSize < 0:
+		return fmt.Errorf("invalid NotificationBufferSize %d", c.NotificationBufferSize)
+	case c.MaxOutstandingRequests < 0:
+		return fmt.Errorf("invalid MaxOutstandingRequests %d", c.MaxOutstandingRequests)
+	case c.ReplyPolicy < ReplyWait || c.ReplyPolicy > ReplyBlock:
+		return fmt.Errorf("invalid ReplyPolicy %d", c.ReplyPolicy)
+	case c.ReadBufferSize < Unbuffered:
+		return fmt.Errorf("invalid ReadBufferSize %d", c.ReadBufferSize)
+	case c.WriteBufferSize < Unbuffered:
+		return fmt.Errorf("invalid WriteBufferSize %d", c.WriteBufferSize)
+	case c.MaxReplySize < 0:
+		return fmt.Errorf("invalid MaxReplySize %d", c.MaxReplySize)
+	case c.MaxXMLDepth < 0:
+		return fmt.Errorf("invalid MaxXMLDepth %d", c.MaxXMLDepth)
+	case c.MaxTokenCount < 0:
+		return fmt.Errorf("invalid MaxTokenCount %d", c.MaxTokenCount)
+	case c.WriteTimeoutSecs < 0:
+		return fmt.Errorf("invalid WriteTimeoutSecs %d", c.WriteTimeoutSecs)
+	case c.SessionIDPolicy < SessionIDWarn || c.SessionIDPolicy > SessionIDReject:
+		return fmt.Errorf("invalid SessionIDPolicy %d", c.SessionIDPolicy)
+	case c.Framing != "" && rfc6242.LookupFraming(c.Framing) == nil:
+		return fmt.Errorf("invalid Framing %q", c.Framing)
+	}
+	return nil
 }
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// BufferPolicy defines how a notification consumer behaves when its buffer is full.
+type BufferPolicy int
+
+const (
+	// DropNewest discards the incoming notification when the consumer buffer is full.
+	DropNewest BufferPolicy = iota
+	// DropOldest discards the oldest buffered notification to make room for the incoming notification.
+	DropOldest
+	// Block waits until the consumer has room for the notification.
+	// Note that a blocked consumer will delay delivery to all other consumers.
+	Block
+)
+
+// NotificationDispatcher replicates notifications received on a single channel to multiple consumers, each
+// with independent buffering, so that a slow consumer does not starve the others.
+//
+// The channel delivered by Channel() should be supplied to Session.Subscribe; when the session closes the
+// channel, all consumer channels are closed.
+type NotificationDispatcher struct {
+	in chan *common.Notification
+
+	lock      sync.Mutex
+	consumers []*NotificationConsumer
+	closed    bool
+}
+
+// NotificationConsumer represents a consumer registered with a NotificationDispatcher.
+type NotificationConsumer struct {
+	// C delivers the notifications replicated to the consumer.
+	C <-chan *common.Notification
+
+	ch      chan *common.Notification
+	policy  BufferPolicy
+	dropped uint64
+}
+
+// NewNotificationDispatcher creates a dispatcher whose input channel has the specified buffer size, and starts
+// dispatching.
+func NewNotificationDispatcher(size int) *NotificationDispatcher {
+	d := &NotificationDispatcher{in: make(chan *common.Notification, size)}
+	go d.dispatch()
+	return d
+}
+
+// Channel delivers the input channel of the dispatcher, to be supplied to Session.Subscribe.
+func (d *NotificationDispatcher) Channel() chan *common.Notification {
+	return d.in
+}
+
+// Register adds a consumer with the specified buffer size and policy.
+// The consumer will receive all notifications dispatched after registration.
+func (d *NotificationDispatcher) Register(size int, policy BufferPolicy) *NotificationConsumer {
+	ch := make(chan *common.Notification, size)
+	c := &NotificationConsumer{C: ch, ch: ch, policy: policy}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.closed {
+		close(ch)
+	} else {
+		d.consumers = append(d.consumers, c)
+	}
+	return c
+}
+
+// RegisterHandler adds a consumer with the specified buffer size and policy, whose notifications will be passed
+// to handler on a dedicated goroutine.
+func (d *NotificationDispatcher) RegisterHandler(size int, policy BufferPolicy, handler func(*common.Notification)) *NotificationConsumer {
+	c := d.Register(size, policy)
+	go func() {
+		for n := range c.C {
+			handler(n)
+		}
+	}()
+	return c
+}
+
+// Dropped delivers the number of notifications that have been discarded for the consumer.
+func (c *NotificationConsumer) Dropped() uint64 {
+	return atomic.LoadUint64(&c.dropped)
+}
+
+func (d *NotificationDispatcher) dispatch() {
+	for n := range d.in {
+		d.lock.Lock()
+		consumers := d.consumers
+		d.lock.Unlock()
+
+		for _, c := range consumers {
+			c.send(n)
+		}
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.closed = true
+	for _, c := range d.consumers {
+		close(c.ch)
+	}
+}
+
+func (c *NotificationConsumer) send(n *common.Notification) {
+	switch c.policy {
+	case Block:
+		c.ch <- n
+	case DropOldest:
+		if cap(c.ch) == 0 {
+			// Nothing buffered to discard.
+			c.trySend(n)
+			return
+		}
+		for {
+			select {
+			case c.ch <- n:
+				return
+			default:
+			}
+			// Buffer is full; discard the oldest entry, unless the consumer got there first.
+			select {
+			case <-c.ch:
+				atomic.AddUint64(&c.dropped, 1)
+			default:
+			}
+		}
+	default:
+		c.trySend(n)
+	}
+}
+
+// Sends the notification to the consumer, discarding it if the consumer is not ready.
+func (c *NotificationConsumer) trySend(n *common.Notification) {
+	select {
+	case c.ch <- n:
+	default:
+		atomic.AddUint64(&c.dropped, 1)
+	}
+}
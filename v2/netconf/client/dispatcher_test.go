@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func notification(i int) *common.Notification {
+	return &common.Notification{Event: fmt.Sprintf("<event>%d</event>", i)}
+}
+
+func TestDispatcherFanOut(t *testing.T) {
+	d := NewNotificationDispatcher(0)
+	c1 := d.Register(10, Block)
+	c2 := d.Register(10, Block)
+
+	for i := 0; i < 5; i++ {
+		d.Channel() <- notification(i)
+	}
+	close(d.Channel())
+
+	for _, c := range []*NotificationConsumer{c1, c2} {
+		count := 0
+		for n := range c.C {
+			assert.Equal(t, notification(count), n)
+			count++
+		}
+		assert.Equal(t, 5, count)
+		assert.Zero(t, c.Dropped())
+	}
+}
+
+func TestDispatcherSlowConsumerDoesNotStarveOthers(t *testing.T) {
+	d := NewNotificationDispatcher(0)
+	newest := d.Register(2, DropNewest)
+	oldest := d.Register(2, DropOldest)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+	var handled []*common.Notification
+	d.RegisterHandler(0, Block, func(n *common.Notification) {
+		handled = append(handled, n)
+		wg.Done()
+	})
+
+	for i := 0; i < 5; i++ {
+		d.Channel() <- notification(i)
+	}
+	wg.Wait()
+	close(d.Channel())
+
+	assert.Len(t, handled, 5)
+
+	assert.Equal(t, uint64(3), newest.Dropped())
+	assert.Equal(t, notification(0), <-newest.C)
+	assert.Equal(t, notification(1), <-newest.C)
+
+	assert.Equal(t, uint64(3), oldest.Dropped())
+	assert.Equal(t, notification(3), <-oldest.C)
+	assert.Equal(t, notification(4), <-oldest.C)
+
+	_, ok := <-oldest.C
+	assert.False(t, ok, "channel should be closed")
+}
+
+func TestDispatcherUnbufferedDropOldest(t *testing.T) {
+	d := NewNotificationDispatcher(0)
+	c := d.Register(0, DropOldest)
+
+	d.Channel() <- notification(0)
+	close(d.Channel())
+
+	_, ok := <-c.C
+	assert.False(t, ok, "channel should be closed")
+	assert.Equal(t, uint64(1), c.Dropped())
+}
+
+func TestDispatcherRegisterAfterClose(t *testing.T) {
+	d := NewNotificationDispatcher(0)
+	c := d.Register(1, Block)
+	close(d.Channel())
+
+	_, ok := <-c.C
+	assert.False(t, ok, "channel should be closed")
+
+	c = d.Register(1, Block)
+	_, ok = <-c.C
+	assert.False(t, ok, "channel should be closed")
+}
+
+func TestDispatcherWithSession(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	s := newNCClientSession(t, ts)
+
+	d := NewNotificationDispatcher(10)
+	raw := d.Register(10, Block)
+	correlated := d.Register(10, DropOldest)
+
+	_, err := s.Subscribe(common.Request(`<ncEvent:create-subscription xmlns:ncEvent="urn:ietf:params:xml:ns:netconf:notification:1.0">`+
+		`</ncEvent:create-subscription>`), d.Channel())
+	assert.NoError(t, err)
+
+	ts.SessionHandler(s.ID()).SendNotification(`<typeA xmlns="urn:test"><name>XXX</name></typeA>`)
+
+	for _, c := range []*NotificationConsumer{raw, correlated} {
+		select {
+		case n := <-c.C:
+			assert.Equal(t, "typeA", n.XMLName.Local)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "Failed to receive notification")
+		}
+	}
+
+	s.Close()
+	_, ok := <-raw.C
+	assert.False(t, ok, "channel should be closed when session closes")
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/xml"
+	"sync"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// EventFactory delivers a new value, typically the address of a struct with xml tags, into which a notification
+// event is unmarshalled.
+type EventFactory func() interface{}
+
+// The registered event factories, initially that of the netconf-config-change event.
+var eventRegistry = struct {
+	sync.RWMutex
+	factories map[xml.Name]EventFactory
+}{factories: map[xml.Name]EventFactory{
+	{Space: NetconfNotificationsNS, Local: "netconf-config-change"}: func() interface{} { return &NetconfConfigChange{} },
+}}
+
+// RegisterEvent registers the factory that creates the value into which received notification events with the
+// element name xmlName are unmarshalled, delivered by Notification.Value. A name with an empty namespace matches
+// events in any namespace that are not otherwise registered. A registration replaces any earlier registration of
+// the same name.
+// The event XML is retained by Notification.Event, and is the only content delivered for unregistered events.
+func RegisterEvent(xmlName xml.Name, factory EventFactory) {
+	eventRegistry.Lock()
+	defer eventRegistry.Unlock()
+	eventRegistry.factories[xmlName] = factory
+}
+
+// Delivers the factory registered for the event name, if any.
+func lookupEvent(name xml.Name) EventFactory {
+	eventRegistry.RLock()
+	defer eventRegistry.RUnlock()
+	if f, ok := eventRegistry.factories[name]; ok {
+		return f
+	}
+	return eventRegistry.factories[xml.Name{Local: name.Local}]
+}
+
+// Unmarshals the notification event into the value created by the registered factory, if any.
+func (si *sesImpl) decodeEvent(n *common.Notification) {
+	factory := lookupEvent(n.XMLName)
+	if factory == nil {
+		return
+	}
+	value := factory()
+	if err := xml.Unmarshal([]byte(n.Event), value); err != nil {
+		si.trace.Error("Failed to unmarshal event "+n.XMLName.Local, si.target, err)
+		return
+	}
+	n.Value = value
+}
+
+// NetconfNotificationsNS is the namespace of the notifications defined by RFC 6470.
+const NetconfNotificationsNS = "urn:ietf:params:xml:ns:yang:ietf-netconf-notifications"
+
+// NetconfConfigChange defines the netconf-config-change event of RFC 6470, which is registered by default.
+type NetconfConfigChange struct {
+	XMLName   xml.Name     `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-notifications netconf-config-change"`
+	ChangedBy ChangedBy    `xml:"changed-by"`
+	Datastore string       `xml:"datastore"`
+	Edits     []ConfigEdit `xml:"edit"`
+}
+
+// ChangedBy identifies the originator of a change; either the server, or a management session.
+type ChangedBy struct {
+	Server     *struct{} `xml:"server"`
+	Username   string    `xml:"username"`
+	SessionID  uint64    `xml:"session-id"`
+	SourceHost string    `xml:"source-host"`
+}
+
+// ConfigEdit defines an edit to the configuration reported by a netconf-config-change event.
+type ConfigEdit struct {
+	// The instance identifier of the edited node.
+	Target    string `xml:"target"`
+	Operation string `xml:"operation"`
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type sessionStart struct {
+	Username  string `xml:"username"`
+	SessionID uint64 `xml:"session-id"`
+}
+
+type invalidEvent struct {
+	SessionID uint64 `xml:"session-id"`
+}
+
+func TestRegisteredEvents(t *testing.T) {
+	RegisterEvent(xml.Name{Space: NetconfNotificationsNS, Local: "netconf-session-start"}, func() interface{} {
+		return &sessionStart{}
+	})
+	// Matches events in any namespace.
+	RegisterEvent(xml.Name{Local: "link-event"}, func() interface{} { return &invalidEvent{} })
+
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+	ncs := newNCClientSession(t, ts)
+	defer ncs.Close()
+	sh := ts.SessionHandler(ncs.ID())
+
+	nch := make(chan *common.Notification, 4)
+	_, err := ncs.Subscribe(common.Request(`<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"/>`), nch)
+	assert.NoError(t, err)
+
+	configChange := `<netconf-config-change xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-notifications">` +
+		`<changed-by><username>admin</username><session-id>7</session-id></changed-by>` +
+		`<datastore>running</datastore>` +
+		`<edit><target>/interfaces/interface[name='eth0']</target><operation>merge</operation></edit>` +
+		`</netconf-config-change>`
+	sh.SendNotification(notificationEvent())
+	sh.SendNotification(configChange)
+	sh.SendNotification(`<link-event xmlns="urn:example:link"><session-id>invalid</session-id></link-event>`)
+	sh.SendNotification(`<unregistered xmlns="urn:example:other"><value>1</value></unregistered>`)
+
+	n := <-nch
+	assert.Equal(t, &sessionStart{Username: "XXxxxx", SessionID: 321}, n.Value)
+	assert.Equal(t, notificationEvent(), n.Event, "Expecting event XML to be retained")
+
+	n = <-nch
+	change, ok := n.Value.(*NetconfConfigChange)
+	assert.True(t, ok, "Expecting netconf-config-change to be registered by default")
+	assert.Equal(t, "admin", change.ChangedBy.Username)
+	assert.Equal(t, uint64(7), change.ChangedBy.SessionID)
+	assert.Nil(t, change.ChangedBy.Server)
+	assert.Equal(t, "running", change.Datastore)
+	assert.Equal(t, []ConfigEdit{{Target: "/interfaces/interface[name='eth0']", Operation: "merge"}}, change.Edits)
+
+	n = <-nch
+	assert.Nil(t, n.Value, "Expecting no value when the event cannot be unmarshalled")
+	assert.Contains(t, n.Event, "<session-id>invalid</session-id>")
+
+	n = <-nch
+	assert.Nil(t, n.Value)
+	assert.Equal(t, `<unregistered xmlns="urn:example:other"><value>1</value></unregistered>`, n.Event)
+}
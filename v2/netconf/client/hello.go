@@ -0,0 +1,80 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// SessionIDPolicy defines how a server hello whose session-id is missing or is not a number is handled. Such hellos
+// do not conform to RFC 6241, but are sent by some devices.
+type SessionIDPolicy int
+
+const (
+	// SessionIDWarn continues session setup with a session id of zero, reporting the anomaly.
+	SessionIDWarn SessionIDPolicy = iota
+	// SessionIDSynthesize continues session setup with a session id synthesized by the client, reporting the
+	// anomaly. Synthesized ids are unique within the process, and lie beyond the 32-bit range of the ids allocated
+	// by servers.
+	SessionIDSynthesize
+	// SessionIDReject fails session setup with a *HelloAnomaly error.
+	SessionIDReject
+)
+
+// The last session id synthesized by SessionIDSynthesize (accessed atomically).
+var lastSyntheticSessionID uint64 = 1 << 32
+
+// HelloAnomaly describes a server hello whose session-id is missing or is not a number. It is reported to the
+// HelloAnomaly trace hook and by SessionStats, unless the session is rejected, when it is the setup error.
+type HelloAnomaly struct {
+	// Indicates that the session-id element was missing.
+	Missing bool
+	// The content of the session-id element, if present.
+	SessionID string
+	// The session id assigned by the client, which is zero unless synthesized.
+	AssignedID uint64
+}
+
+func (a *HelloAnomaly) Error() string {
+	if a.Missing {
+		return "server hello has no session-id"
+	}
+	return fmt.Sprintf("server hello has invalid session-id %q", a.SessionID)
+}
+
+// serverHello defines a hello message received from the server, whose session-id is decoded as a string so that an
+// invalid session-id is handled by the SessionIDPolicy, rather than failing to decode.
+type serverHello struct {
+	XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 hello"`
+	Capabilities []string `xml:"capabilities>capability"`
+	SessionID    *string  `xml:"session-id"`
+}
+
+// Delivers the hello message, assigning the session id according to the policy, and any anomaly. An error is
+// returned if the anomaly is rejected.
+func (h *serverHello) resolve(policy SessionIDPolicy) (*common.HelloMessage, *HelloAnomaly, error) {
+	hello := &common.HelloMessage{XMLName: h.XMLName, Capabilities: h.Capabilities}
+	anomaly := &HelloAnomaly{Missing: h.SessionID == nil}
+	if !anomaly.Missing {
+		anomaly.SessionID = *h.SessionID
+		id, err := strconv.ParseUint(strings.TrimSpace(anomaly.SessionID), 10, 64)
+		if err == nil {
+			hello.SessionID = id
+			return hello, nil, nil
+		}
+	}
+
+	switch policy {
+	case SessionIDReject:
+		return nil, anomaly, anomaly
+	case SessionIDSynthesize:
+		anomaly.AssignedID = atomic.AddUint64(&lastSyntheticSessionID, 1)
+		hello.SessionID = anomaly.AssignedID
+	case SessionIDWarn:
+	}
+	return hello, anomaly, nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func newHelloTestSession(t *testing.T, v testserver.HelloVariation, policy SessionIDPolicy) (Session, []*HelloAnomaly,
+	error,
+) {
+	ts := testserver.NewTestNetconfServer(t).WithHelloVariation(func(uint64) testserver.HelloVariation { return v })
+	t.Cleanup(ts.Close)
+
+	var anomalies []*HelloAnomaly
+	ctx := WithClientTrace(context.Background(), &ClientTrace{
+		HelloAnomaly: func(target string, anomaly *HelloAnomaly) { anomalies = append(anomalies, anomaly) },
+	})
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	s, err := NewRPCSessionWithConfig(ctx, sshConfig, fmt.Sprintf("localhost:%d", ts.Port()),
+		&Config{SessionIDPolicy: policy})
+	if s != nil {
+		t.Cleanup(s.Close)
+	}
+	return s, anomalies, err
+}
+
+func TestHelloSessionIDWarn(t *testing.T) {
+	s, anomalies, err := newHelloTestSession(t, testserver.HelloVariation{OmitSessionID: true}, SessionIDWarn)
+	assert.NoError(t, err)
+	assert.Zero(t, s.ID())
+	assert.Equal(t, []*HelloAnomaly{{Missing: true}}, anomalies)
+	assert.Equal(t, &HelloAnomaly{Missing: true}, s.Stats().HelloAnomaly)
+
+	s, anomalies, err = newHelloTestSession(t, testserver.HelloVariation{SessionID: "sess-42"}, SessionIDWarn)
+	assert.NoError(t, err)
+	assert.Zero(t, s.ID())
+	assert.Equal(t, []*HelloAnomaly{{SessionID: "sess-42"}}, anomalies)
+	assert.Equal(t, `server hello has invalid session-id "sess-42"`, s.Stats().HelloAnomaly.Error())
+}
+
+func TestHelloSessionIDSynthesize(t *testing.T) {
+	first, _, err := newHelloTestSession(t, testserver.HelloVariation{SessionID: "abc"}, SessionIDSynthesize)
+	assert.NoError(t, err)
+	second, anomalies, err := newHelloTestSession(t, testserver.HelloVariation{OmitSessionID: true}, SessionIDSynthesize)
+	assert.NoError(t, err)
+
+	assert.Greater(t, first.ID(), uint64(1<<32))
+	assert.Greater(t, second.ID(), first.ID())
+	assert.Equal(t, second.ID(), anomalies[0].AssignedID)
+	assert.Equal(t, second.ID(), second.Stats().HelloAnomaly.AssignedID)
+}
+
+func TestHelloSessionIDReject(t *testing.T) {
+	_, anomalies, err := newHelloTestSession(t, testserver.HelloVariation{SessionID: "abc"}, SessionIDReject)
+	assert.EqualError(t, err, `server hello has invalid session-id "abc"`)
+	assert.Len(t, anomalies, 1)
+
+	_, _, err = newHelloTestSession(t, testserver.HelloVariation{OmitSessionID: true}, SessionIDReject)
+	var anomaly *HelloAnomaly
+	assert.ErrorAs(t, err, &anomaly)
+	assert.True(t, anomaly.Missing)
+}
+
+func TestHelloSessionIDValid(t *testing.T) {
+	s, anomalies, err := newHelloTestSession(t, testserver.HelloVariation{SessionID: " 17 "}, SessionIDReject)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(17), s.ID())
+	assert.Empty(t, anomalies)
+	assert.Nil(t, s.Stats().HelloAnomaly)
+}
+
+func TestInvalidSessionIDPolicy(t *testing.T) {
+	assert.EqualError(t, (&Config{SessionIDPolicy: SessionIDReject + 1}).validate(), "invalid SessionIDPolicy 3")
+}
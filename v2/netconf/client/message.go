@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bufio"
 	"context"
 	"encoding/xml"
 	"errors"
@@ -11,12 +12,18 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/damianoneill/net/v2/netconf/common"
 
 	"github.com/damianoneill/net/v2/netconf/common/codec"
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
 )
 
+// ErrTooManyRequests is returned when a request is made while the number of requests awaiting a reply is at the
+// limit defined by Config.MaxOutstandingRequests.
+var ErrTooManyRequests = errors.New("too many outstanding requests")
+
 // The Message layer defines a set of base protocol operations
 // invoked as RPC methods with XML-encoded parameters.
 
@@ -25,6 +32,11 @@ type Session interface {
 	// Execute executes an RPC request on the server and returns the reply.
 	Execute(req common.Request) (*common.RPCReply, error)
 
+	// ExecuteContext executes an RPC request on the server and returns the reply, as Execute, with the deadline
+	// of ctx bounding the write of the request, together with the configured write timeout. If ctx is done before
+	// the reply is received, ctx.Err() is returned and the reply is discarded when it arrives.
+	ExecuteContext(ctx context.Context, req common.Request) (*common.RPCReply, error)
+
 	// ExecuteAsync submits an RPC request for execution on the server, arranging for the
 	// reply to be sent to the supplied channel.
 	ExecuteAsync(req common.Request, rchan chan *common.RPCReply) (err error)
@@ -45,6 +57,18 @@ type Session interface {
 
 	// Capabilities delivers the server-supplied capabilities.
 	ServerCapabilities() []string
+
+	// Stats delivers a snapshot of the state of the session, including its queue depths, framing, traffic and last
+	// error.
+	Stats() SessionStats
+}
+
+// RequestStats defines the request queue statistics of a session.
+type RequestStats struct {
+	// The number of requests waiting to be sent, when requests are serialized.
+	Queued int
+	// The number of requests that have been sent and are awaiting a reply.
+	Outstanding int
 }
 
 type sesImpl struct {
@@ -53,40 +77,117 @@ type sesImpl struct {
 	dec   *codec.Decoder
 	enc   *codec.Encoder
 	trace *ClientTrace
+	// Records the messages of the session, nil if not logging.
+	log *common.SessionLogWriter
+
+	// Buffers writes to the transport, nil if unbuffered.
+	wbuf *bufio.Writer
 
 	pool []chan *common.RPCReply
+	// The reply channels allocated to synchronous requests; guarded by pchLock.
+	syncChans map[chan *common.RPCReply]bool
 
 	hellochan chan bool
 	responseq []chan *common.RPCReply
 	subchan   chan *common.Notification
+	// Buffers notifications waiting for the subscriber, nil if unbuffered.
+	notifq chan *common.Notification
+	// Requests waiting to be sent, when requests are serialized; guarded by reqLock.
+	pending []pendingRequest
+
+	hello *common.HelloMessage
+	// The anomaly in the server hello tolerated by the SessionIDPolicy, if any, or the error that rejected it.
+	helloAnomaly *HelloAnomaly
+	helloErr     error
 
-	hello   *common.HelloMessage
 	reqLock sync.Mutex
 	pchLock sync.Mutex
 	rchLock sync.Mutex
 
 	notificationDropCount uint64
+	replyDropCount        uint64
+
+	// The sequence number of the last message received; accessed only by the receive loop.
+	seq uint64
+
+	// The limit error that terminated the session, if any; set before the reply channels are closed.
+	limitErr *codec.LimitError
+
+	// Transport traffic counters, the session start time, the last error, and whether the receive loop has ended
+	// (accessed atomically).
+	in      countingReader
+	out     countingWriter
+	started time.Time
+	lastErr lastError
+	closed  uint32
 
 	target string
 }
 
+// Defines a request queued waiting to be sent.
+type pendingRequest struct {
+	// The context of the caller, whose deadline bounds the write of the request.
+	ctx   context.Context
+	msg   *common.RPCMessage
+	rchan chan *common.RPCReply
+}
+
 // NewSession creates a new Netconf session, using the supplied Transport.
 func NewSession(ctx context.Context, t Transport, cfg *Config) (Session, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	// Transports other than those established by NewSSHTransport have no target, and do not support keepalives.
+	ti, _ := t.(*tImpl)
 	si := &sesImpl{
 		cfg:    cfg,
 		t:      t,
-		target: t.(*tImpl).target,
-		dec:    codec.NewDecoder(t),
-		enc:    codec.NewEncoder(t),
+		target: ti.targetName(),
 		trace:  ContextClientTrace(ctx),
+		log:    ContextSessionLog(ctx),
 
-		hellochan: make(chan bool),
+		hellochan: make(chan bool, 1),
+		started:   time.Now(),
+	}
+	si.in.r, si.out.w = t, t
+
+	var r io.Reader = &si.in
+	if size := orDefault(cfg.ReadBufferSize, DefaultConfig.ReadBufferSize); size > 0 {
+		r = bufio.NewReaderSize(r, size)
+	}
+	var w io.Writer = &si.out
+	if size := orDefault(cfg.WriteBufferSize, DefaultConfig.WriteBufferSize); size > 0 {
+		si.wbuf = bufio.NewWriterSize(w, size)
+		w = si.wbuf
+	}
+	var decoderOptions []rfc6242.DecoderOption
+	var encoderOptions []rfc6242.EncoderOption
+	if cfg.StreamingDecoder {
+		decoderOptions = append(decoderOptions, rfc6242.WithStreaming())
+	}
+	if cfg.Framing != "" {
+		framing := rfc6242.LookupFraming(cfg.Framing)
+		decoderOptions = append(decoderOptions, rfc6242.WithFraming(framing))
+		encoderOptions = append(encoderOptions, rfc6242.WithEncoderFraming(framing))
+	}
+	limits := codec.Limits{MaxSize: cfg.MaxReplySize, MaxDepth: cfg.MaxXMLDepth, MaxTokens: cfg.MaxTokenCount}
+	if limits == (codec.Limits{}) {
+		si.dec = codec.NewDecoder(r, decoderOptions...)
+	} else {
+		si.dec = codec.NewLimitedDecoder(r, limits, decoderOptions...)
+	}
+	si.enc = codec.NewEncoder(w, encoderOptions...)
+
+	if cfg.NotificationBufferSize > 0 {
+		si.notifq = make(chan *common.Notification, cfg.NotificationBufferSize)
+		go si.forwardNotifications()
 	}
 
 	// Send hello
-	err := si.enc.Encode(&common.HelloMessage{Capabilities: si.clientCapabilities()})
+	err := si.encode(ctx, &common.HelloMessage{Capabilities: si.clientCapabilities()})
 	if err != nil {
-		si.trace.Error("Failed to encode hello", si.target, err)
+		si.reportError("Failed to encode hello", err)
 		si.Close()
 		return nil, err
 	}
@@ -94,17 +195,23 @@ func NewSession(ctx context.Context, t Transport, cfg *Config) (Session, error)
 	// Launch goroutine to handle incoming messages from the server.
 	go si.handleIncomingMessages()
 
-	err = si.waitForServerHello()
+	err = si.waitForServerHello(ctx)
 	if err != nil {
-		si.trace.Error("Failed to receive hello", si.target, err)
+		si.reportError("Failed to receive hello", err)
 		si.Close()
 		return nil, err
 	}
+
+	if cfg.KeepaliveIntervalSecs > 0 && ti != nil {
+		ti.startKeepalive(time.Duration(cfg.KeepaliveIntervalSecs)*time.Second,
+			orDefault(cfg.KeepaliveMaxMissed, DefaultConfig.KeepaliveMaxMissed))
+	}
 	return si, nil
 }
 
 func (si *sesImpl) clientCapabilities() []string {
-	if si.cfg.DisableChunkedCodec {
+	// Framing defined by the configuration is not negotiated, so chunked framing is not advertised.
+	if si.cfg.DisableChunkedCodec || si.cfg.Framing != "" {
 		return common.NoChunkedCodecCapabilities
 	}
 	return common.DefaultCapabilities
@@ -122,16 +229,44 @@ func (si *sesImpl) Execute(req common.Request) (reply *common.RPCReply, err erro
 	defer si.relChan(rchan)
 
 	// Submit the request
-	err = si.execute(req, rchan)
+	err = si.execute(context.Background(), req, rchan)
 	if err != nil {
 		return nil, err
 	}
 
 	// Wait for the response.
 	reply = <-rchan
+	return si.mapReply(reply)
+}
+
+func (si *sesImpl) ExecuteContext(ctx context.Context, req common.Request) (reply *common.RPCReply, err error) {
+	si.trace.ExecuteStart(req, false)
 
-	err = mapError(reply)
-	return reply, err
+	defer func(begin time.Time) {
+		si.trace.ExecuteDone(req, false, reply, err, time.Since(begin))
+	}(time.Now())
+
+	// The response channel is not pooled, and is buffered so that a reply that arrives once the caller has
+	// abandoned the request is discarded without blocking.
+	rchan := make(chan *common.RPCReply, 1)
+	if err = si.execute(ctx, req, rchan); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply = <-rchan:
+		return si.mapReply(reply)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Delivers the reply to a synchronous request and the error it reports.
+func (si *sesImpl) mapReply(reply *common.RPCReply) (*common.RPCReply, error) {
+	if reply == nil && si.limitErr != nil {
+		return nil, si.limitErr
+	}
+	return reply, mapError(reply)
 }
 
 func (si *sesImpl) ExecuteAsync(req common.Request, rchan chan *common.RPCReply) (err error) {
@@ -140,10 +275,11 @@ func (si *sesImpl) ExecuteAsync(req common.Request, rchan chan *common.RPCReply)
 		si.trace.ExecuteDone(req, true, nil, err, time.Since(begin))
 	}(time.Now())
 
-	return si.execute(req, rchan)
+	return si.execute(context.Background(), req, rchan)
 }
 
-func (si *sesImpl) execute(req common.Request, rchan chan *common.RPCReply) (err error) {
+// Submits the request, with the deadline of ctx bounding its write.
+func (si *sesImpl) execute(ctx context.Context, req common.Request, rchan chan *common.RPCReply) (err error) {
 	// Build the request to be submitted.
 	msg := &common.RPCMessage{MessageID: uuid.New().String(), Union: common.GetUnion(req)}
 
@@ -151,15 +287,93 @@ func (si *sesImpl) execute(req common.Request, rchan chan *common.RPCReply) (err
 	si.reqLock.Lock()
 	defer si.reqLock.Unlock()
 
+	if max := si.cfg.MaxOutstandingRequests; max > 0 && si.outstanding()+len(si.pending) >= max {
+		return ErrTooManyRequests
+	}
+
+	// If requests are serialized and another is in progress, queue the request to be sent once it completes.
+	if si.cfg.SerializeRequests && (si.outstanding() > 0 || len(si.pending) > 0) {
+		si.pending = append(si.pending, pendingRequest{ctx: ctx, msg: msg, rchan: rchan})
+		return nil
+	}
+
 	// Add the response channel to the response queue, but take it off if the request was not
 	// submitted successfully.
 	si.pushRespChan(rchan)
-	if err = si.enc.Encode(msg); err != nil {
+	if err = si.encode(ctx, msg); err != nil {
+		si.lastErr.set(err)
 		si.popRespChan()
 	}
 	return
 }
 
+// Sends the request at the head of the pending queue, if there are no requests awaiting a reply, skipping requests
+// whose callers have abandoned them.
+// If the request cannot be sent, the session is closed, which will close the reply channels of all queued requests.
+func (si *sesImpl) sendPending() {
+	si.reqLock.Lock()
+	defer si.reqLock.Unlock()
+
+	if si.outstanding() > 0 {
+		return
+	}
+
+	var req pendingRequest
+	for {
+		if len(si.pending) == 0 {
+			return
+		}
+		req, si.pending = si.pending[0], si.pending[1:]
+		if req.ctx.Err() == nil {
+			break
+		}
+	}
+	si.pushRespChan(req.rchan)
+	if err := si.encode(req.ctx, req.msg); err != nil {
+		si.reportError("Failed to send queued request", err)
+		si.Close()
+	}
+}
+
+// Defines a transport that supports write deadlines.
+type deadlineWriter interface {
+	SetWriteDeadline(deadline time.Time) error
+}
+
+// Encodes the message, flushing any write buffer so the message is sent in its entirety.
+// The message must be written by the earlier of the deadline defined by the context and the configured
+// write timeout.
+func (si *sesImpl) encode(ctx context.Context, msg interface{}) error {
+	if dw, ok := si.t.(deadlineWriter); ok {
+		if deadline, ok := si.writeDeadline(ctx); ok {
+			_ = dw.SetWriteDeadline(deadline)
+			defer func() { _ = dw.SetWriteDeadline(time.Time{}) }()
+		}
+	}
+	encode := si.enc.Encode
+	if si.log != nil {
+		encode = si.encodeLogged
+	}
+	if err := encode(msg); err != nil {
+		return err
+	}
+	if si.wbuf != nil {
+		return si.wbuf.Flush()
+	}
+	return nil
+}
+
+// Delivers the deadline for writing a message, if any.
+func (si *sesImpl) writeDeadline(ctx context.Context) (deadline time.Time, ok bool) {
+	deadline, ok = ctx.Deadline()
+	if secs := si.cfg.WriteTimeoutSecs; secs > 0 {
+		if timeout := time.Now().Add(time.Duration(secs) * time.Second); !ok || timeout.Before(deadline) {
+			deadline, ok = timeout, true
+		}
+	}
+	return
+}
+
 func (si *sesImpl) Subscribe(req common.Request, nchan chan *common.Notification) (reply *common.RPCReply, err error) {
 	// Store the notification channel for the session.
 	si.subchan = nchan
@@ -169,7 +383,7 @@ func (si *sesImpl) Subscribe(req common.Request, nchan chan *common.Notification
 func (si *sesImpl) Close() {
 	err := si.t.Close()
 	if err != nil {
-		si.trace.Error("Session close failed", si.target, err)
+		si.reportError("Session close failed", err)
 	}
 }
 
@@ -177,18 +391,33 @@ func (si *sesImpl) ID() uint64 {
 	return si.hello.SessionID
 }
 
+// SSHClient delivers the ssh client connection of the session transport, or nil if the transport is not ssh.
+func (si *sesImpl) SSHClient() *ssh.Client {
+	return SSHClientOf(si.t)
+}
+
 func (si *sesImpl) ServerCapabilities() []string {
 	return si.hello.Capabilities
 }
 
-func (si *sesImpl) waitForServerHello() (err error) {
+// Waits for the server hello, for up to the configured setup timeout or until the context is done,
+// whichever is sooner.
+func (si *sesImpl) waitForServerHello(ctx context.Context) (err error) {
+	timer := time.NewTimer(time.Duration(si.cfg.SetupTimeoutSecs) * time.Second)
+	defer timer.Stop()
+
 	select {
 	case result := <-si.hellochan:
+		if !result && si.helloErr != nil {
+			return si.helloErr
+		}
 		if !result {
 			return errors.New("failed to get hello - remote closed connection?")
 		}
-	case <-time.After(time.Duration(si.cfg.SetupTimeoutSecs) * time.Second):
+	case <-timer.C:
 		err = errors.New("failed to get hello from server")
+	case <-ctx.Done():
+		err = ctx.Err()
 	}
 	return
 }
@@ -197,32 +426,47 @@ func (si *sesImpl) handleIncomingMessages() {
 	// When this goroutine finishes, make sure anytbody waiting for an async response or notification
 	// gets informed.
 	defer si.closeChannels()
+	defer atomic.StoreUint32(&si.closed, 1)
 
 	// Loop, looking for a start element type of hello, rpc-reply or notification.
 	for {
 		token, err := si.dec.Token()
-		if err != nil {
-			break
+		if err == nil {
+			err = si.handleToken(token)
 		}
-
-		if err = si.handleToken(token); err != nil {
+		if err != nil {
+			si.handleDecodeError(err)
 			return
 		}
 	}
 }
 
+// Closes the session if a message from the server exceeded one of the configured limits.
+func (si *sesImpl) handleDecodeError(err error) {
+	if err != io.EOF {
+		si.lastErr.set(err)
+	}
+	var lerr *codec.LimitError
+	if errors.As(err, &lerr) {
+		si.limitErr = lerr
+		si.trace.LimitExceeded(si.target, lerr)
+		si.Close()
+	}
+}
+
 func (si *sesImpl) handleToken(token xml.Token) (err error) {
 	switch token := token.(type) {
 	case xml.StartElement:
 		switch token.Name.Local {
 		case common.NameHello.Local: // <hello>
+			si.nextSeq(token)
 			err = si.handleHello(token)
 
 		case common.NameRPCReply.Local: // <rpc-reply>
-			err = si.handleRPCReply(token)
+			err = si.handleRPCReply(token, si.nextSeq(token))
 
 		case common.NameNotification.Local: // <notification>
-			err = si.handleNotification(token)
+			err = si.handleNotification(token, si.nextSeq(token))
 
 		default:
 		}
@@ -231,17 +475,38 @@ func (si *sesImpl) handleToken(token xml.Token) (err error) {
 	return
 }
 
+// Assigns the next sequence number to the message that starts with token.
+func (si *sesImpl) nextSeq(token xml.StartElement) uint64 {
+	si.seq++
+	si.trace.MessageReceived(si.target, token.Name.Local, si.seq)
+	return si.seq
+}
+
 func (si *sesImpl) handleHello(token xml.StartElement) (err error) {
 	// Decode the hello element and send it down the channel to trigger the rest of the session setup.
 
-	if err = si.decodeElement(&si.hello, &token); err != nil {
+	received := &serverHello{}
+	if err = si.decodeElement(received, &token); err != nil {
+		si.hellochan <- false
+		return
+	}
+	hello, anomaly, err := received.resolve(si.cfg.SessionIDPolicy)
+	if anomaly != nil {
+		si.trace.HelloAnomaly(si.target, anomaly)
+	}
+	if err != nil {
+		si.helloErr = err
 		si.hellochan <- false
 		return
 	}
+	si.hello, si.helloAnomaly = hello, anomaly
+	si.logReceived(si.hello)
 
-	if !si.cfg.DisableChunkedCodec && common.PeerSupportsChunkedFraming(si.hello.Capabilities) {
-		// Update the codec to use chunked framing from now.
-		codec.EnableChunkedFraming(si.dec, si.enc)
+	if si.cfg.Framing == "" {
+		if framing := codec.NegotiateFraming(si.clientCapabilities(), si.hello.Capabilities); framing != nil {
+			// Update the codec to use the negotiated framing from now.
+			codec.SetFraming(si.dec, si.enc, framing)
+		}
 	}
 
 	si.hellochan <- true
@@ -249,34 +514,94 @@ func (si *sesImpl) handleHello(token xml.StartElement) (err error) {
 	return
 }
 
-func (si *sesImpl) handleRPCReply(token xml.StartElement) (err error) {
+func (si *sesImpl) handleRPCReply(token xml.StartElement, seq uint64) (err error) {
 	reply := common.RPCReply{}
 	if err = si.decodeElement(&reply, &token); err != nil {
 		return
 	}
+	reply.Sequence = seq
+	si.logReceived(reply.RawXML)
 
-	// Pop the channel off the head of the queue and send the reply to it.
-	respch := si.popRespChan()
-	go func(ch chan *common.RPCReply, r *common.RPCReply) {
-		ch <- r
-	}(respch, &reply)
+	// Pop the channel off the head of the queue and send the reply to it, having sent the next queued request.
+	ch := si.popRespChan()
+	if si.cfg.SerializeRequests {
+		si.sendPending()
+	}
+	si.deliverReply(ch, &reply)
 	return
 }
 
-func (si *sesImpl) handleNotification(token xml.StartElement) (err error) {
+// deliverReply sends the reply to the channel. A synchronous caller is bound to read the reply, so it is always
+// delivered. If the reader of an asynchronous request is not ready, the reply is handled according to the configured
+// reply policy. By default, delivery is handed off to a goroutine that waits for up to the configured reply timeout,
+// after which the reply is dropped so that channels abandoned by asynchronous callers do not leak goroutines.
+func (si *sesImpl) deliverReply(ch chan *common.RPCReply, r *common.RPCReply) {
+	if ch == nil {
+		return
+	}
+	if si.isSyncChan(ch) {
+		ch <- r
+		return
+	}
+
+	switch si.cfg.ReplyPolicy {
+	case ReplyBlock:
+		ch <- r
+		return
+	case ReplyDrop:
+		select {
+		case ch <- r:
+		default:
+			atomic.AddUint64(&si.replyDropCount, 1)
+			si.trace.ReplyDropped(r)
+		}
+		return
+	case ReplyWait:
+	}
+
+	select {
+	case ch <- r:
+		return
+	default:
+	}
+
+	go func() {
+		timer := time.NewTimer(time.Duration(orDefault(si.cfg.ReplyTimeoutSecs, DefaultConfig.ReplyTimeoutSecs)) * time.Second)
+		defer timer.Stop()
+
+		select {
+		case ch <- r:
+		case <-timer.C:
+			atomic.AddUint64(&si.replyDropCount, 1)
+			si.trace.ReplyDropped(r)
+		}
+	}()
+}
+
+func (si *sesImpl) handleNotification(token xml.StartElement, seq uint64) (err error) {
 	result := &common.NotificationMessage{}
 	if err = si.decodeElement(&result, &token); err != nil {
 		return
 	}
+	if si.log != nil {
+		si.logReceived(fmt.Sprintf(`<%s xmlns=%q><eventTime>%s</eventTime>%s</%s>`, result.XMLName.Local,
+			result.XMLName.Space, result.EventTime, buildNotification(result).Event, result.XMLName.Local))
+	}
 
 	// Send notification to subscription channel, if it's defined and not full.
 	if si.subchan != nil {
 		notification := buildNotification(result)
+		notification.Sequence = seq
+		si.decodeEvent(notification)
 
 		si.trace.NotificationReceived(notification)
 
+		ch := si.subchan
+		if si.notifq != nil {
+			ch = si.notifq
+		}
 		select {
-		case si.subchan <- notification:
+		case ch <- notification:
 		default:
 			atomic.AddUint64(&si.notificationDropCount, 1)
 			si.trace.NotificationDropped(notification)
@@ -294,17 +619,40 @@ func buildNotification(nmsg *common.NotificationMessage) *common.Notification {
 
 func (si *sesImpl) decodeElement(v interface{}, start *xml.StartElement) (err error) {
 	if err = si.dec.DecodeElement(v, start); err != nil {
-		si.trace.Error(fmt.Sprintf("DecodeElement token:%s", start.Name.Local), si.target, err)
+		si.reportError(fmt.Sprintf("DecodeElement token:%s", start.Name.Local), err)
 	}
 	return
 }
 
+// Delivers buffered notifications to the subscriber. The subscription channel is closed once the buffer has been
+// closed and drained.
+func (si *sesImpl) forwardNotifications() {
+	for n := range si.notifq {
+		si.subchan <- n
+	}
+	if si.subchan != nil {
+		close(si.subchan)
+	}
+}
+
 func (si *sesImpl) closeChannels() {
 	close(si.hellochan)
-	if si.subchan != nil {
+	if si.notifq != nil {
+		close(si.notifq)
+	} else if si.subchan != nil {
 		close(si.subchan)
 	}
 	si.closeAllResponseChannels()
+	si.closePendingChannels()
+}
+
+func (si *sesImpl) closePendingChannels() {
+	si.reqLock.Lock()
+	defer si.reqLock.Unlock()
+	for _, req := range si.pending {
+		close(req.rchan)
+	}
+	si.pending = nil
 }
 
 func (si *sesImpl) closeAllResponseChannels() {
@@ -323,13 +671,25 @@ func (si *sesImpl) allocChan() (ch chan *common.RPCReply) {
 
 	l := len(si.pool)
 	if l == 0 {
-		return make(chan *common.RPCReply)
+		ch = make(chan *common.RPCReply)
+		if si.syncChans == nil {
+			si.syncChans = map[chan *common.RPCReply]bool{}
+		}
+		si.syncChans[ch] = true
+		return
 	}
 
 	si.pool, ch = si.pool[:l-1], si.pool[l-1]
 	return
 }
 
+// Reports whether the channel was allocated to a synchronous request.
+func (si *sesImpl) isSyncChan(ch chan *common.RPCReply) bool {
+	si.pchLock.Lock()
+	defer si.pchLock.Unlock()
+	return si.syncChans[ch]
+}
+
 func (si *sesImpl) relChan(ch chan *common.RPCReply) {
 	si.pchLock.Lock()
 	defer si.pchLock.Unlock()
@@ -342,6 +702,12 @@ func (si *sesImpl) pushRespChan(ch chan *common.RPCReply) {
 	si.responseq = append(si.responseq, ch)
 }
 
+func (si *sesImpl) outstanding() int {
+	si.rchLock.Lock()
+	defer si.rchLock.Unlock()
+	return len(si.responseq)
+}
+
 func (si *sesImpl) popRespChan() (ch chan *common.RPCReply) {
 	si.rchLock.Lock()
 	defer si.rchLock.Unlock()
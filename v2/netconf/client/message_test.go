@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/common/codec"
 	"github.com/damianoneill/net/v2/netconf/testserver"
 
 	assert "github.com/stretchr/testify/require"
@@ -71,6 +73,26 @@ func TestExecuteWithStruct(t *testing.T) {
 	assert.Equal(t, "<body></body>", sh.LastReq().Body, "Expected request body")
 }
 
+func TestExecuteWithReader(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSession(t, ts)
+	defer ncs.Close()
+
+	sh := ts.SessionHandler(ncs.ID())
+
+	// Body is large enough to span multiple chunks.
+	body := "<config>" + strings.Repeat("<item>value</item>", 1000) + "</config>"
+	reply, err := ncs.Execute(common.Request(strings.NewReader("<edit-config>" + body + "</edit-config>")))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, "<data>"+body+"</data>", reply.Data, "Reply should contain response data")
+	assert.Equal(t, "edit-config", sh.LastReq().XMLName.Local, "Expected EDIT-CONFIG request")
+	assert.Equal(t, body, sh.LastReq().Body, "Expected request body")
+
+	reply, err = ncs.Execute(common.Request([]byte(`<get><response/></get>`)))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><response/></data>`, reply.Data, "Reply should contain response data")
+}
+
 func TestExecuteWithFailingRequest(t *testing.T) {
 	ncs := newNCClientSession(t, testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.FailingRequestHandler))
 	defer ncs.Close()
@@ -125,6 +147,18 @@ func TestNewSessionWithNoChunkedCodec(t *testing.T) {
 	assert.Equal(t, "<response/>", sh.LastReq().Body, "Expected request body")
 }
 
+func TestNewSessionWithFraming(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{Framing: "eom"})
+	defer ncs.Close()
+
+	reply, err := ncs.Execute(common.Request(`<get><response/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><response/></data>`, reply.Data, "Reply should contain response data")
+	assert.Zero(t, ncs.Stats().Framing.Decoded.Chunks, "Expected end-of-message framing")
+	assert.NotContains(t, ts.SessionHandler(ncs.ID()).ClientHello.Capabilities, common.CapBase11)
+}
+
 func TestExecuteAsync(t *testing.T) {
 	ncs := newNCClientSession(t, testserver.NewTestNetconfServer(t))
 	defer ncs.Close()
@@ -158,6 +192,38 @@ func TestExecuteAsyncUnfulfilled(t *testing.T) {
 	assert.Nil(t, reply, "Reply should be nil")
 }
 
+func TestExecuteContext(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSession(t, ts)
+	defer ncs.Close()
+
+	reply, err := ncs.ExecuteContext(context.Background(), common.Request(`<get><response/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><response/></data>`, reply.Data, "Reply should contain response data")
+}
+
+func TestExecuteContextDone(t *testing.T) {
+	ncs := newNCClientSession(t, testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.IgnoreRequestHandler))
+	defer ncs.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reply, err := ncs.ExecuteContext(ctx, common.Request(`<get><test1/></get>`))
+	assert.Nil(t, reply)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestSendPendingSkipsAbandonedRequests(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	si := &sesImpl{pending: []pendingRequest{{ctx: ctx}, {ctx: ctx}}}
+
+	// The abandoned requests are not written.
+	si.sendPending()
+	assert.Empty(t, si.pending)
+	assert.Equal(t, 0, si.outstanding())
+}
+
 func TestExecuteAsyncInterrupted(t *testing.T) {
 	ncs := newNCClientSession(t, testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.IgnoreRequestHandler))
 	defer ncs.Close()
@@ -170,6 +236,73 @@ func TestExecuteAsyncInterrupted(t *testing.T) {
 	assert.Nil(t, reply, "Reply should be nil")
 }
 
+func TestExecuteAsyncAbandoned(t *testing.T) {
+	var dropped []*common.RPCReply
+	var mu sync.Mutex
+	trace := &ClientTrace{
+		ReplyDropped: func(res *common.RPCReply) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped = append(dropped, res)
+		},
+	}
+
+	ts := testserver.NewTestNetconfServer(t)
+	serverAddress := fmt.Sprintf("localhost:%d", ts.Port())
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	ncs, err := NewRPCSessionWithConfig(WithClientTrace(context.Background(), trace), sshConfig, serverAddress, &Config{ReplyTimeoutSecs: 1})
+	assert.NoError(t, err, "Failed to create session")
+	defer ncs.Close()
+
+	// Nobody reads from this channel, so the reply should be dropped after the timeout.
+	_ = ncs.ExecuteAsync(common.Request(`<get><test1/></get>`), make(chan *common.RPCReply))
+
+	time.Sleep(time.Millisecond * time.Duration(1500))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&(ncs.(*sesImpl).replyDropCount)), "Expected reply to have been dropped")
+	mu.Lock()
+	assert.Len(t, dropped, 1, "Expected dropped reply to be traced")
+	assert.Equal(t, `<data><test1/></data>`, dropped[0].Data, "Unexpected dropped reply")
+	mu.Unlock()
+
+	// The session should continue to deliver replies to active readers.
+	reply, err := ncs.Execute(common.Request(`<get><test2/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><test2/></data>`, reply.Data, "Reply should contain response data")
+}
+
+func TestExecuteAsyncDefaultReplyTimeout(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	serverAddress := fmt.Sprintf("localhost:%d", ts.Port())
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	tr, err := createTransport(context.Background(), sshConfig, serverAddress)
+	assert.NoError(t, err, "Failed to create transport")
+
+	// A configuration without a reply timeout should hold replies for the default timeout.
+	ncs, err := NewSession(context.Background(), tr, &Config{SetupTimeoutSecs: 5})
+	assert.NoError(t, err, "Failed to create session")
+	defer ncs.Close()
+
+	rch := make(chan *common.RPCReply)
+	_ = ncs.ExecuteAsync(common.Request(`<get><test1/></get>`), rch)
+
+	time.Sleep(time.Millisecond * time.Duration(200))
+	select {
+	case reply := <-rch:
+		assert.Equal(t, `<data><test1/></data>`, reply.Data, "Reply should contain response data")
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expected reply to be delivered")
+	}
+	assert.Equal(t, uint64(0), atomic.LoadUint64(&(ncs.(*sesImpl).replyDropCount)), "Expected reply to be held")
+}
+
 func TestSubscribe(t *testing.T) {
 	ts := testserver.NewTestNetconfServer(t)
 	ncs := newNCClientSession(t, ts)
@@ -212,6 +345,237 @@ func TestSubscribe(t *testing.T) {
 	assert.Nil(t, result, "No more notifications expected")
 }
 
+func TestSubscribeWithNotificationBuffer(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{NotificationBufferSize: 2})
+	sh := ts.SessionHandler(ncs.ID())
+
+	nch := make(chan *common.Notification)
+	reply, err := ncs.Subscribe(common.Request(`<ncEvent:create-subscription xmlns:ncEvent="urn:ietf:params:xml:ns:netconf:notification:1.0">`+
+		`</ncEvent:create-subscription>`), nch)
+	assert.NoError(t, err, "create-subscription failed")
+	assert.NotNil(t, reply, "create-subscription failed")
+
+	// Nobody is reading, so the first is held by the forwarder, the next two are buffered and the last dropped.
+	for i := 0; i < 4; i++ {
+		sh.SendNotification(notificationEvent())
+	}
+	time.Sleep(time.Millisecond * time.Duration(500))
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&(ncs.(*sesImpl).notificationDropCount)), "Expected notification to have been dropped")
+
+	ts.Close()
+	count := 0
+	for range nch {
+		count++
+	}
+	assert.Equal(t, 3, count, "Expected buffered notifications to be delivered before the channel is closed")
+}
+
+func TestMessageSequence(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	serverAddress := fmt.Sprintf("localhost:%d", ts.Port())
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	var mu sync.Mutex
+	var received []string
+	ctx := WithClientTrace(context.Background(), &ClientTrace{
+		MessageReceived: func(target, name string, seq uint64) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, fmt.Sprintf("%s:%d", name, seq))
+		},
+	})
+	ncs, err := NewRPCSession(ctx, sshConfig, serverAddress)
+	assert.NoError(t, err, "Failed to create session")
+	defer ncs.Close()
+	sh := ts.SessionHandler(ncs.ID())
+
+	nch := make(chan *common.Notification, 1)
+	reply, err := ncs.Subscribe(common.Request(`<ncEvent:create-subscription xmlns:ncEvent="urn:ietf:params:xml:ns:netconf:notification:1.0">`+
+		`</ncEvent:create-subscription>`), nch)
+	assert.NoError(t, err, "create-subscription failed")
+	assert.Equal(t, uint64(2), reply.Sequence, "Expecting reply to follow hello")
+
+	sh.SendNotification(notificationEvent())
+	reply, err = ncs.Execute(common.Request(`<get><response/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, uint64(4), reply.Sequence, "Expecting reply to follow notification")
+	assert.Equal(t, uint64(3), (<-nch).Sequence)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"hello:1", "rpc-reply:2", "notification:3", "rpc-reply:4"}, received)
+}
+
+func TestMaxOutstandingRequests(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.IgnoreRequestHandler)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{MaxOutstandingRequests: 1})
+	defer ncs.Close()
+
+	err := ncs.ExecuteAsync(common.Request(`<get><test1/></get>`), make(chan *common.RPCReply, 1))
+	assert.NoError(t, err, "Not expecting first request to fail")
+
+	err = ncs.ExecuteAsync(common.Request(`<get><test2/></get>`), make(chan *common.RPCReply, 1))
+	assert.Equal(t, ErrTooManyRequests, err, "Expecting second request to be rejected")
+}
+
+func TestReplyPolicyDrop(t *testing.T) {
+	var dropped int32
+	trace := &ClientTrace{ReplyDropped: func(res *common.RPCReply) { atomic.AddInt32(&dropped, 1) }}
+
+	ts := testserver.NewTestNetconfServer(t)
+	serverAddress := fmt.Sprintf("localhost:%d", ts.Port())
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	ncs, err := NewRPCSessionWithConfig(WithClientTrace(context.Background(), trace), sshConfig, serverAddress,
+		&Config{ReplyPolicy: ReplyDrop})
+	assert.NoError(t, err, "Failed to create session")
+	defer ncs.Close()
+
+	// Nobody reads from this channel, so the reply should be dropped immediately.
+	_ = ncs.ExecuteAsync(common.Request(`<get><test1/></get>`), make(chan *common.RPCReply))
+
+	reply, err := ncs.Execute(common.Request(`<get><test2/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><test2/></data>`, reply.Data, "Reply should contain response data")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dropped), "Expected reply to have been dropped")
+}
+
+func TestReplyPolicyDropDeliversToSyncCaller(t *testing.T) {
+	var dropped int32
+	si := &sesImpl{cfg: &Config{ReplyPolicy: ReplyDrop},
+		trace: &ClientTrace{ReplyDropped: func(res *common.RPCReply) { atomic.AddInt32(&dropped, 1) }}}
+
+	// The synchronous caller is late into its receive.
+	rchan := si.allocChan()
+	delivered := make(chan struct{})
+	go func() {
+		defer close(delivered)
+		si.deliverReply(rchan, &common.RPCReply{MessageID: "1"})
+	}()
+	time.Sleep(time.Millisecond * time.Duration(50))
+
+	reply := <-rchan
+	<-delivered
+	assert.Equal(t, "1", reply.MessageID, "Expected reply to be delivered to synchronous caller")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&dropped))
+
+	si.deliverReply(make(chan *common.RPCReply), &common.RPCReply{MessageID: "2"})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dropped), "Expected async reply to have been dropped")
+}
+
+func TestUnbufferedConfig(t *testing.T) {
+	ncs := newNCClientSessionWithConfig(t, testserver.NewTestNetconfServer(t),
+		NewConfigRegistry().Resolve("", WithBufferSizes(0, 0)))
+	defer ncs.Close()
+	assert.Nil(t, ncs.(*sesImpl).wbuf, "Expecting writes to be unbuffered")
+
+	reply, err := ncs.Execute(common.Request(`<get><test1/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><test1/></data>`, reply.Data)
+
+	ncs2 := newNCClientSessionWithConfig(t, testserver.NewTestNetconfServer(t), &Config{})
+	defer ncs2.Close()
+	assert.Equal(t, DefaultConfig.WriteBufferSize, ncs2.(*sesImpl).wbuf.Size(), "Expecting zero to mean default")
+}
+
+func TestReplyPolicyBlock(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{ReplyPolicy: ReplyBlock})
+	defer ncs.Close()
+
+	rch := make(chan *common.RPCReply)
+	_ = ncs.ExecuteAsync(common.Request(`<get><test1/></get>`), rch)
+	time.Sleep(time.Millisecond * time.Duration(100))
+
+	reply := <-rch
+	assert.Equal(t, `<data><test1/></data>`, reply.Data, "Expected reply to be held until read")
+}
+
+func TestSerializeRequestsQueues(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.IgnoreRequestHandler)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{SerializeRequests: true})
+	sh := ts.SessionHandler(ncs.ID())
+
+	rchans := []chan *common.RPCReply{make(chan *common.RPCReply), make(chan *common.RPCReply), make(chan *common.RPCReply)}
+	for _, rch := range rchans {
+		assert.NoError(t, ncs.ExecuteAsync(common.Request(`<get/>`), rch), "Not expecting request to fail")
+	}
+	time.Sleep(time.Millisecond * time.Duration(100))
+
+	assert.Equal(t, 1, sh.ReqCount(), "Expecting only the first request to have been sent")
+	assert.Equal(t, RequestStats{Queued: 2, Outstanding: 1}, ncs.Stats().RequestStats)
+
+	ts.Close()
+	for _, rch := range rchans {
+		assert.Nil(t, <-rch, "Expecting reply channel to be closed")
+	}
+}
+
+func TestSerializeRequestsConcurrentExecute(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{SerializeRequests: true, ReplyTimeoutSecs: 1})
+	defer ncs.Close()
+
+	var wg sync.WaitGroup
+	for r := 0; r < 10; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			req := fmt.Sprintf(`<get><Id>%d</Id></get>`, r)
+			reply, err := ncs.Execute(common.Request(req))
+			assert.NoError(t, err, "Not expecting exec to fail")
+			assert.Equal(t, fmt.Sprintf(`<data><Id>%d</Id></data>`, r), reply.Data, "Reply should contain response data")
+		}(r)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, ts.SessionHandler(ncs.ID()).ReqCount())
+	assert.Equal(t, RequestStats{}, ncs.Stats().RequestStats)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, cfg := range []*Config{
+		{NotificationBufferSize: -1},
+		{MaxOutstandingRequests: -1},
+		{ReplyPolicy: ReplyPolicy(99)},
+		{ReadBufferSize: -2},
+		{WriteBufferSize: -2},
+		{MaxReplySize: -1},
+		{MaxXMLDepth: -1},
+		{MaxTokenCount: -1},
+		{Framing: "unknown"},
+		{WriteTimeoutSecs: -1},
+	} {
+		_, err := NewSession(context.Background(), &tImpl{}, cfg)
+		assert.Error(t, err, "Expecting invalid configuration to be rejected")
+	}
+}
+
+func TestWriteDeadlineResolution(t *testing.T) {
+	si := &sesImpl{cfg: &Config{}}
+	_, ok := si.writeDeadline(context.Background())
+	assert.False(t, ok, "Expecting no deadline by default")
+
+	si.cfg.WriteTimeoutSecs = 10
+	deadline, ok := si.writeDeadline(context.Background())
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	expected, _ := ctx.Deadline()
+	deadline, _ = si.writeDeadline(ctx)
+	assert.Equal(t, expected, deadline, "Expecting earlier context deadline to apply")
+}
+
 func TestConcurrentExecute(t *testing.T) {
 	ts := testserver.NewTestNetconfServer(t)
 	ncs := newNCClientSession(t, ts)
@@ -312,6 +676,54 @@ func newNCClientSessionWithConfig(t assert.TestingT, ts *testserver.TestNCServer
 	return s
 }
 
+func TestReplyLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *Config
+		limit  string
+		within string
+	}{
+		{"Size", &Config{MaxReplySize: 400}, codec.LimitSize, `<get><a/></get>`},
+		{"Depth", &Config{MaxXMLDepth: 4}, codec.LimitDepth, `<get><a/></get>`},
+		{"Tokens", &Config{MaxTokenCount: 20}, codec.LimitTokens, `<get><a/><b/><c/></get>`},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := testserver.NewTestNetconfServer(t)
+			defer ts.Close()
+
+			var exceeded []*codec.LimitError
+			ctx := WithClientTrace(context.Background(), &ClientTrace{
+				LimitExceeded: func(target string, err *codec.LimitError) { exceeded = append(exceeded, err) },
+			})
+			sshConfig := &ssh.ClientConfig{
+				User:            testserver.TestUserName,
+				Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+				HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+			}
+			ncs, err := NewRPCSessionWithConfig(ctx, sshConfig, fmt.Sprintf("localhost:%d", ts.Port()), tt.cfg)
+			assert.NoError(t, err)
+			defer ncs.Close()
+
+			_, err = ncs.Execute(common.Request(tt.within))
+			assert.NoError(t, err, "Not expecting reply within limits to fail")
+
+			big := `<get>` + strings.Repeat(`<a><b><c>data</c></b></a>`, 20) + `</get>`
+			reply, err := ncs.Execute(common.Request(big))
+			assert.Nil(t, reply)
+			var lerr *codec.LimitError
+			assert.ErrorAs(t, err, &lerr)
+			assert.Equal(t, tt.limit, lerr.Limit)
+			assert.Equal(t, []*codec.LimitError{lerr}, exceeded)
+
+			_, err = ncs.Execute(common.Request(tt.within))
+			assert.Error(t, err, "Expecting session to be closed")
+		})
+	}
+}
+
 // Simple real NE access tests
 
 //nolint: lll,gocritic
@@ -382,3 +794,34 @@ func newNCClientSessionWithConfig(t assert.TestingT, ts *testserver.TestNCServer
 //	assert.NotNil(t, n, "Reply should be non-nil")
 //	fmt.Printf("%v\n", n)
 //}
+
+func TestFramingStats(t *testing.T) {
+	ncs := newNCClientSession(t, testserver.NewTestNetconfServer(t))
+	defer ncs.Close()
+
+	before := ncs.Stats().Framing
+	assert.Equal(t, uint64(1), before.Decoded.Messages, "Expected server hello to have been decoded")
+	assert.Equal(t, uint64(1), before.Encoded.Messages, "Expected client hello to have been encoded")
+
+	_, err := ncs.Execute(common.Request(`<get><response/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+
+	after := ncs.Stats().Framing
+	assert.Equal(t, uint64(2), after.Decoded.Messages, "Expected reply to have been decoded")
+	assert.Equal(t, uint64(2), after.Encoded.Messages, "Expected request to have been encoded")
+	assert.NotZero(t, after.Decoded.Chunks, "Expected chunked framing to be in use")
+	assert.Greater(t, after.Decoded.Bytes, before.Decoded.Bytes)
+	assert.Zero(t, after.Decoded.FramingErrors)
+}
+
+func TestStreamingDecoder(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{StreamingDecoder: true, ReadBufferSize: Unbuffered})
+	defer ncs.Close()
+
+	for _, name := range []string{"test1", "test2"} {
+		reply, err := ncs.Execute(common.Request(`<get><` + name + `/></get>`))
+		assert.NoError(t, err, "Not expecting exec to fail")
+		assert.Equal(t, `<data><`+name+`/></data>`, reply.Data, "Reply should contain response data")
+	}
+}
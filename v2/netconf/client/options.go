@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ConfigOption implements options for configuring session behaviour, as an alternative to defining a Config.
+type ConfigOption func(*Config)
+
+// FromConfig applies the values defined by cfg. Numeric, string and policy values that are zero in cfg are left
+// unchanged, as they select the default when used with NewRPCSessionWithConfig; boolean values are always applied,
+// so that a value set by an earlier option can be reset.
+func FromConfig(cfg *Config) ConfigOption {
+	return func(c *Config) {
+		for _, f := range []struct{ dst, src *int }{
+			{&c.SetupTimeoutSecs, &cfg.SetupTimeoutSecs},
+			{&c.ReplyTimeoutSecs, &cfg.ReplyTimeoutSecs},
+			{&c.KeepaliveIntervalSecs, &cfg.KeepaliveIntervalSecs},
+			{&c.KeepaliveMaxMissed, &cfg.KeepaliveMaxMissed},
+			{&c.NotificationBufferSize, &cfg.NotificationBufferSize},
+			{&c.MaxOutstandingRequests, &cfg.MaxOutstandingRequests},
+			{&c.ReadBufferSize, &cfg.ReadBufferSize},
+			{&c.WriteBufferSize, &cfg.WriteBufferSize},
+			{&c.MaxReplySize, &cfg.MaxReplySize},
+			{&c.MaxXMLDepth, &cfg.MaxXMLDepth},
+			{&c.MaxTokenCount, &cfg.MaxTokenCount},
+			{&c.WriteTimeoutSecs, &cfg.WriteTimeoutSecs},
+		} {
+			if *f.src != 0 {
+				*f.dst = *f.src
+			}
+		}
+		if cfg.ReplyPolicy != 0 {
+			c.ReplyPolicy = cfg.ReplyPolicy
+		}
+		if cfg.SessionIDPolicy != 0 {
+			c.SessionIDPolicy = cfg.SessionIDPolicy
+		}
+		if cfg.Framing != "" {
+			c.Framing = cfg.Framing
+		}
+		c.DisableChunkedCodec = cfg.DisableChunkedCodec
+		c.SerializeRequests = cfg.SerializeRequests
+		c.StreamingDecoder = cfg.StreamingDecoder
+	}
+}
+
+// WithSetupTimeout defines the time in seconds that the client will wait to receive a hello message from the server.
+func WithSetupTimeout(secs int) ConfigOption {
+	return func(c *Config) {
+		c.SetupTimeoutSecs = secs
+	}
+}
+
+// WithReplyTimeout defines the time in seconds that a reply to an asynchronous request will be held waiting for
+// the caller to read it.
+func WithReplyTimeout(secs int) ConfigOption {
+	return func(c *Config) {
+		c.ReplyTimeoutSecs = secs
+	}
+}
+
+// WithFramingMode defines the name of the framing used for all messages, in place of the framing negotiated with the
+// server, for example "raw" for transports that delimit messages themselves. An empty name restores negotiation.
+// See Config.Framing.
+func WithFramingMode(name string) ConfigOption {
+	return func(c *Config) {
+		c.Framing = name
+	}
+}
+
+// WithoutChunkedFraming prevents the client advertising the chunked framing capability, so that end-of-message
+// framing is used.
+func WithoutChunkedFraming() ConfigOption {
+	return func(c *Config) {
+		c.DisableChunkedCodec = true
+	}
+}
+
+// WithKeepalive enables SSH keepalive requests at the interval in seconds; the session is closed if maxMissed
+// consecutive requests go unanswered.
+func WithKeepalive(intervalSecs, maxMissed int) ConfigOption {
+	return func(c *Config) {
+		c.KeepaliveIntervalSecs = intervalSecs
+		c.KeepaliveMaxMissed = maxMissed
+	}
+}
+
+// WithNotificationBuffer defines the number of notifications buffered waiting for the subscriber.
+func WithNotificationBuffer(size int) ConfigOption {
+	return func(c *Config) {
+		c.NotificationBufferSize = size
+	}
+}
+
+// WithMaxOutstandingRequests defines the maximum number of requests that may be awaiting a reply.
+func WithMaxOutstandingRequests(max int) ConfigOption {
+	return func(c *Config) {
+		c.MaxOutstandingRequests = max
+	}
+}
+
+// WithReplyPolicy defines how replies are delivered to reply channels whose reader is not ready.
+func WithReplyPolicy(policy ReplyPolicy) ConfigOption {
+	return func(c *Config) {
+		c.ReplyPolicy = policy
+	}
+}
+
+// WithBufferSizes defines the sizes in bytes of the buffers used to read from and write to the transport. A size of
+// zero means unbuffered.
+func WithBufferSizes(read, write int) ConfigOption {
+	return func(c *Config) {
+		c.ReadBufferSize = bufferSize(read)
+		c.WriteBufferSize = bufferSize(write)
+	}
+}
+
+// Delivers the Config value of a buffer size, for which zero means unbuffered.
+func bufferSize(size int) int {
+	if size == 0 {
+		return Unbuffered
+	}
+	return size
+}
+
+// WithSerializedRequests indicates that only one request may be awaiting a reply at a time.
+func WithSerializedRequests() ConfigOption {
+	return func(c *Config) {
+		c.SerializeRequests = true
+	}
+}
+
+// WithStreamingDecoder indicates that incoming messages should be decoded by a streaming decoder.
+func WithStreamingDecoder() ConfigOption {
+	return func(c *Config) {
+		c.StreamingDecoder = true
+	}
+}
+
+// WithMessageLimits defines the maximum size in bytes, nesting depth and xml token count of messages received
+// from the server. Zero means no limit.
+func WithMessageLimits(size, depth, tokens int) ConfigOption {
+	return func(c *Config) {
+		c.MaxReplySize = size
+		c.MaxXMLDepth = depth
+		c.MaxTokenCount = tokens
+	}
+}
+
+// WithWriteTimeout defines the time in seconds allowed for each message to be written to the transport.
+func WithWriteTimeout(secs int) ConfigOption {
+	return func(c *Config) {
+		c.WriteTimeoutSecs = secs
+	}
+}
+
+// ConfigRegistry resolves the configuration of sessions to a target, by applying options registered for the
+// target over default options, which are themselves applied over DefaultConfig.
+// A ConfigRegistry is safe for concurrent use.
+type ConfigRegistry struct {
+	mu       sync.RWMutex
+	defaults []ConfigOption
+	targets  map[string][]ConfigOption
+}
+
+// DefaultRegistry is the registry used by NewRPCSessionWithOptions.
+var DefaultRegistry = NewConfigRegistry()
+
+// NewConfigRegistry delivers a registry with the default options.
+func NewConfigRegistry(defaults ...ConfigOption) *ConfigRegistry {
+	return &ConfigRegistry{defaults: defaults, targets: map[string][]ConfigOption{}}
+}
+
+// SetDefaults replaces the options applied to sessions to all targets.
+func (r *ConfigRegistry) SetDefaults(opts ...ConfigOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaults = opts
+}
+
+// SetTarget replaces the options applied to sessions to the target, over the default options.
+func (r *ConfigRegistry) SetTarget(target string, opts ...ConfigOption) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.targets[target] = opts
+}
+
+// RemoveTarget removes the options registered for the target.
+func (r *ConfigRegistry) RemoveTarget(target string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.targets, target)
+}
+
+// Resolve delivers the configuration of a session to the target, applying DefaultConfig, the default options, the
+// options registered for the target and finally opts, in that order.
+func (r *ConfigRegistry) Resolve(target string, opts ...ConfigOption) *Config {
+	cfg := *DefaultConfig
+
+	r.mu.RLock()
+	for _, opt := range r.defaults {
+		opt(&cfg)
+	}
+	for _, opt := range r.targets[target] {
+		opt(&cfg)
+	}
+	r.mu.RUnlock()
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &cfg
+}
+
+// NewRPCSessionWithOptions connects to the target using the ssh configuration, and establishes a netconf session
+// with the configuration resolved for the target by DefaultRegistry, to which opts are applied.
+func NewRPCSessionWithOptions(ctx context.Context, sshcfg *ssh.ClientConfig, target string, opts ...ConfigOption) (s Session, err error) {
+	var t Transport
+	if t, err = createTransport(ctx, sshcfg, target); err != nil {
+		return
+	}
+
+	if s, err = NewSession(ctx, t, DefaultRegistry.Resolve(target, opts...)); err != nil {
+		_ = t.Close()
+	}
+	return
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestConfigRegistryResolve(t *testing.T) {
+	r := NewConfigRegistry(WithSetupTimeout(10), WithNotificationBuffer(5))
+	r.SetTarget("router1:830", WithSetupTimeout(20), WithFramingMode("eom"))
+
+	cfg := r.Resolve("router2:830")
+	assert.Equal(t, 10, cfg.SetupTimeoutSecs)
+	assert.Equal(t, 5, cfg.NotificationBufferSize)
+	assert.Equal(t, DefaultConfig.ReplyTimeoutSecs, cfg.ReplyTimeoutSecs, "Expecting unspecified values to be defaulted")
+	assert.Equal(t, "", cfg.Framing)
+
+	cfg = r.Resolve("router1:830", WithNotificationBuffer(0))
+	assert.Equal(t, 20, cfg.SetupTimeoutSecs, "Expecting target options to override defaults")
+	assert.Equal(t, "eom", cfg.Framing)
+	assert.Equal(t, 0, cfg.NotificationBufferSize, "Expecting options to override target options")
+
+	r.RemoveTarget("router1:830")
+	assert.Equal(t, 10, r.Resolve("router1:830").SetupTimeoutSecs)
+
+	r.SetDefaults()
+	assert.Equal(t, DefaultConfig, r.Resolve("router1:830"))
+}
+
+func TestConfigOptions(t *testing.T) {
+	cfg := NewConfigRegistry().Resolve("",
+		WithReplyTimeout(30),
+		WithoutChunkedFraming(),
+		WithKeepalive(10, 2),
+		WithMaxOutstandingRequests(8),
+		WithReplyPolicy(ReplyBlock),
+		WithBufferSizes(0, 1024),
+		WithSerializedRequests(),
+		WithStreamingDecoder(),
+		WithMessageLimits(1<<20, 64, 10000),
+	)
+	assert.Equal(t, &Config{
+		SetupTimeoutSecs:       DefaultConfig.SetupTimeoutSecs,
+		DisableChunkedCodec:    true,
+		ReplyTimeoutSecs:       30,
+		KeepaliveIntervalSecs:  10,
+		KeepaliveMaxMissed:     2,
+		MaxOutstandingRequests: 8,
+		ReplyPolicy:            ReplyBlock,
+		ReadBufferSize:         Unbuffered,
+		WriteBufferSize:        1024,
+		SerializeRequests:      true,
+		StreamingDecoder:       true,
+		MaxReplySize:           1 << 20,
+		MaxXMLDepth:            64,
+		MaxTokenCount:          10000,
+	}, cfg)
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := NewConfigRegistry().Resolve("", WithBufferSizes(0, 0), FromConfig(&Config{SetupTimeoutSecs: 1, WriteBufferSize: 512}))
+	assert.Equal(t, 1, cfg.SetupTimeoutSecs)
+	assert.Equal(t, Unbuffered, cfg.ReadBufferSize, "Expecting zero values in the config to be ignored")
+	assert.Equal(t, 512, cfg.WriteBufferSize)
+	assert.Equal(t, DefaultConfig.ReplyTimeoutSecs, cfg.ReplyTimeoutSecs)
+
+	cfg = NewConfigRegistry().Resolve("", WithSerializedRequests(), WithoutChunkedFraming(),
+		FromConfig(&Config{StreamingDecoder: true, ReplyPolicy: ReplyDrop}))
+	assert.False(t, cfg.SerializeRequests, "Expecting boolean values in the config to be applied")
+	assert.False(t, cfg.DisableChunkedCodec)
+	assert.True(t, cfg.StreamingDecoder)
+	assert.Equal(t, ReplyDrop, cfg.ReplyPolicy)
+}
+
+func TestFromConfigAppliesEveryField(t *testing.T) {
+	// Every field of a config with no zero values is applied, so that fields added to Config are not overlooked.
+	cfg := &Config{}
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		switch f := v.Field(i); f.Kind() {
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Int:
+			f.SetInt(int64(i + 1))
+		case reflect.String:
+			f.SetString("eom")
+		default:
+			t.Fatalf("unexpected kind of field %s", v.Type().Field(i).Name)
+		}
+	}
+	c := &Config{}
+	FromConfig(cfg)(c)
+	assert.Equal(t, cfg, c)
+}
+
+func TestSessionWithOptions(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	target := fmt.Sprintf("localhost:%d", ts.Port())
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	DefaultRegistry.SetTarget(target, WithoutChunkedFraming())
+	defer DefaultRegistry.RemoveTarget(target)
+
+	s, err := NewRPCSessionWithOptions(context.Background(), sshConfig, target, WithSetupTimeout(2))
+	assert.NoError(t, err, "Expecting new session to succeed")
+	defer s.Close()
+
+	sh := ts.SessionHandler(s.ID())
+	sh.WaitStart()
+	assert.Equal(t, common.NoChunkedCodecCapabilities, sh.ClientHello.Capabilities, "Expecting target configuration to apply")
+	assert.Equal(t, 2, s.(*sesImpl).cfg.SetupTimeoutSecs)
+
+	_, err = NewRPCSessionWithOptions(context.Background(), sshConfig, "localhost:0")
+	assert.Error(t, err, "Expecting new session to fail")
+}
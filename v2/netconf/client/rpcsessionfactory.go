@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"net"
 	"time"
 
 	"github.com/imdario/mergo"
@@ -77,7 +78,47 @@ func (rd *RealDialer) Dial(ctx context.Context) (cli *ssh.Client, err error) {
 		tracer.DialDone(rd.config, rd.target, err, time.Since(begin))
 	}(time.Now())
 
-	return ssh.Dial("tcp", rd.target, rd.config)
+	return dialContext(ctx, "tcp", rd.target, rd.config)
+}
+
+// dialContext behaves as ssh.Dial, but abandons the connection and handshake if the context is
+// done before they complete, in which case the context error is returned.
+func dialContext(ctx context.Context, network, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	d := &net.Dialer{Timeout: config.Timeout}
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Interrupt the handshake if the context is done before it completes.
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	close(stop)
+	<-stopped
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if err == nil {
+			_ = c.Close()
+		} else {
+			_ = conn.Close()
+		}
+		return nil, ctxErr
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
 }
 
 func (rd *RealDialer) Close(cli *ssh.Client) (err error) {
@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"testing"
 	"time"
 
@@ -208,3 +209,54 @@ func exerciseSession(t *testing.T, hooks *ClientTrace) string {
 //	assert.NoError(t, err, "Not expecting exec to fail")
 //	assert.NotNil(t, reply, "Reply should be non-nil")
 //}
+
+func TestSessionSetupHandshakeDeadline(t *testing.T) {
+	// Listener that accepts connections, but never completes the SSH handshake.
+	l, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+		}()
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	s, err := NewRPCSession(ctx, &ssh.ClientConfig{HostKeyCallback: ssh.InsecureIgnoreHostKey()}, l.Addr().String()) //nolint: gosec
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Expecting new session to fail - handshake incomplete")
+	assert.Nil(t, s, "Session should be nil")
+	assert.Less(t, time.Since(begin), time.Second, "Expecting deadline to be honoured")
+}
+
+func TestSessionSetupHelloDeadline(t *testing.T) {
+	ts := testserver.NewSSHServer(t, testserver.TestUserName, testserver.TestPassword)
+	defer ts.Close()
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	s, err := NewRPCSessionWithConfig(ctx, sshConfig, fmt.Sprintf("localhost:%d", ts.Port()), &Config{SetupTimeoutSecs: 5})
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "Expecting new session to fail - no hello from server")
+	assert.Nil(t, s, "Session should be nil")
+	assert.Less(t, time.Since(begin), 2*time.Second, "Expecting deadline to be honoured")
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// unique type to prevent assignment.
+type sessionLogContextKey struct{}
+
+// WithSessionLog returns a new context based on the provided parent ctx. Netconf sessions created with the returned
+// context will record every message they send and receive to log, in a form that can be replayed by the test server.
+// Messages are recorded as they are encoded, so requests are not streamed to the transport while logging.
+func WithSessionLog(ctx context.Context, log *common.SessionLogWriter) context.Context {
+	return context.WithValue(ctx, sessionLogContextKey{}, log)
+}
+
+// ContextSessionLog returns the session log associated with the provided context. If none, it returns nil.
+func ContextSessionLog(ctx context.Context) *common.SessionLogWriter {
+	log, _ := ctx.Value(sessionLogContextKey{}).(*common.SessionLogWriter)
+	return log
+}
+
+// Encodes a message that is to be recorded in the session log.
+func (si *sesImpl) encodeLogged(msg interface{}) error {
+	b, err := xml.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	si.log.Record(true, b)
+	return si.enc.EncodeStream(func(w io.Writer) error {
+		_, err := w.Write(b)
+		return err
+	})
+}
+
+// Records a received message in the session log, if logging.
+func (si *sesImpl) logReceived(msg interface{}) {
+	if si.log == nil {
+		return
+	}
+	if s, ok := msg.(string); ok {
+		si.log.Record(false, []byte(s))
+		return
+	}
+	if b, err := xml.Marshal(msg); err == nil {
+		si.log.Record(false, b)
+	}
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec"
+)
+
+// SessionStats defines a snapshot of the state of a session, allowing long-lived sessions to be health-checked.
+type SessionStats struct {
+	// The request queue statistics of the session.
+	RequestStats
+	// The message framing statistics of the session.
+	Framing codec.Stats
+	// The number of notifications buffered waiting for the subscriber, when notifications are buffered.
+	NotificationsBuffered int
+	// The number of notifications and replies dropped because their receiver was not ready.
+	NotificationsDropped uint64
+	RepliesDropped       uint64
+	// The number of bytes received from and sent to the transport, including framing.
+	BytesIn  uint64
+	BytesOut uint64
+	// The time since the session was established.
+	Uptime time.Duration
+	// The last error encountered by the session, and when; nil if no error has occurred.
+	LastError     error
+	LastErrorTime time.Time
+	// Indicates that the session has stopped receiving messages, because it has been closed or the transport
+	// has failed, so can no longer be used.
+	Closed bool
+	// The anomaly in the server hello tolerated by the SessionIDPolicy, nil if the hello was well-formed.
+	HelloAnomaly *HelloAnomaly
+}
+
+// Records the last error encountered by a session.
+type lastError struct {
+	mu   sync.Mutex
+	err  error
+	when time.Time
+}
+
+func (l *lastError) set(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err, l.when = err, time.Now()
+}
+
+func (l *lastError) fill(stats *SessionStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats.LastError, stats.LastErrorTime = l.err, l.when
+}
+
+// Counts the bytes read from a reader.
+type countingReader struct {
+	r     io.Reader
+	count uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddUint64(&c.count, uint64(n))
+	return n, err
+}
+
+// Counts the bytes written to a writer.
+type countingWriter struct {
+	w     io.Writer
+	count uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddUint64(&c.count, uint64(n))
+	return n, err
+}
+
+func (si *sesImpl) Stats() SessionStats {
+	stats := SessionStats{
+		RequestStats:         si.requestStats(),
+		Framing:              codec.Stats{Decoded: si.dec.Stats(), Encoded: si.enc.Stats()},
+		NotificationsDropped: atomic.LoadUint64(&si.notificationDropCount),
+		RepliesDropped:       atomic.LoadUint64(&si.replyDropCount),
+		BytesIn:              atomic.LoadUint64(&si.in.count),
+		BytesOut:             atomic.LoadUint64(&si.out.count),
+		Uptime:               time.Since(si.started),
+		Closed:               atomic.LoadUint32(&si.closed) != 0,
+		HelloAnomaly:         si.helloAnomaly,
+	}
+	if si.notifq != nil {
+		stats.NotificationsBuffered = len(si.notifq)
+	}
+	si.lastErr.fill(&stats)
+	return stats
+}
+
+func (si *sesImpl) requestStats() RequestStats {
+	si.reqLock.Lock()
+	defer si.reqLock.Unlock()
+	return RequestStats{Queued: len(si.pending), Outstanding: si.outstanding()}
+}
+
+// Reports an error to the trace hooks, and records it as the last error of the session.
+func (si *sesImpl) reportError(context string, err error) {
+	si.lastErr.set(err)
+	si.trace.Error(context, si.target, err)
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/common/codec"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSessionStats(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSession(t, ts)
+
+	_, err := ncs.Execute(common.Request(`<get><response/></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+
+	stats := ncs.Stats()
+	assert.Zero(t, stats.Outstanding)
+	assert.Zero(t, stats.Queued)
+	assert.Greater(t, stats.BytesIn, uint64(0), "Expecting hello and reply to have been received")
+	assert.Greater(t, stats.BytesOut, uint64(0), "Expecting hello and request to have been sent")
+	assert.Greater(t, stats.Uptime, time.Duration(0))
+	assert.Nil(t, stats.LastError)
+	assert.False(t, stats.Closed)
+
+	ncs.Close()
+	assert.Eventually(t, func() bool { return ncs.Stats().Closed }, time.Second, 10*time.Millisecond,
+		"Expecting session to be reported closed")
+}
+
+func TestSessionStatsOutstanding(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.IgnoreRequestHandler)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{NotificationBufferSize: 4})
+	defer ncs.Close()
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, ncs.ExecuteAsync(common.Request(`<get/>`), make(chan *common.RPCReply, 1)))
+	}
+
+	stats := ncs.Stats()
+	assert.Equal(t, 2, stats.Outstanding)
+	assert.Zero(t, stats.NotificationsBuffered)
+}
+
+func TestSessionStatsLastError(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	ncs := newNCClientSessionWithConfig(t, ts, &Config{MaxReplySize: 400})
+
+	big := `<get>` + strings.Repeat(`<a><b><c>data</c></b></a>`, 20) + `</get>`
+	_, err := ncs.Execute(common.Request(big))
+	assert.Error(t, err)
+
+	stats := ncs.Stats()
+	var lerr *codec.LimitError
+	assert.True(t, errors.As(stats.LastError, &lerr), "Expecting limit error to be recorded")
+	assert.False(t, stats.LastErrorTime.IsZero())
+	assert.True(t, stats.Closed, "Expecting session to be closed")
+}
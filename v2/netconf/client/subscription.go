@@ -0,0 +1,84 @@
+package client
+
+import (
+	"encoding/xml"
+	"sync"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// Subscription defines the parameters of an event notification subscription, as described by RFC 5277, so that the
+// subscription can be re-established on a new session after the connection to the server has been lost.
+//
+// The subscription records the event time of the last notification received, and, where the stream supports
+// replay, requests replay of the notifications from that time when it is re-established, so that consumers see a
+// stream without gaps. The notification received at that time may be delivered again.
+type Subscription struct {
+	// The event stream; empty selects the default NETCONF stream.
+	Stream string
+	// An optional subtree filter, as xml.
+	Filter string
+	// Optional start and stop times, in RFC 3339 format, that request the replay of earlier notifications.
+	StartTime string
+	StopTime  string
+	// Indicates that the stream supports replay, so that notifications sent whilst the subscription was lost are
+	// requested when it is re-established. Replay is assumed if StartTime is defined.
+	Replay bool
+
+	mu            sync.Mutex
+	lastEventTime string
+}
+
+// Defines the create-subscription request.
+type createSubscription struct {
+	XMLName   xml.Name            `xml:"urn:ietf:params:xml:ns:netconf:notification:1.0 create-subscription"`
+	Stream    string              `xml:"stream,omitempty"`
+	Filter    *subscriptionFilter `xml:"filter,omitempty"`
+	StartTime string              `xml:"startTime,omitempty"`
+	StopTime  string              `xml:"stopTime,omitempty"`
+}
+
+type subscriptionFilter struct {
+	Type    string `xml:"type,attr"`
+	Content string `xml:",innerxml"`
+}
+
+// Subscribe issues the create-subscription request on the session, delivering the notifications received to nchan.
+// Subscribe may be called again, with the same channel, on a new session once the subscription has been lost; as
+// nchan is shared by successive sessions, it is not closed when the session ends.
+func (s *Subscription) Subscribe(session Session, nchan chan<- *common.Notification) (*common.RPCReply, error) {
+	in := make(chan *common.Notification, cap(nchan))
+	reply, err := session.Subscribe(s.request(), in)
+	if err != nil {
+		return reply, err
+	}
+
+	go func() {
+		for n := range in {
+			s.mu.Lock()
+			s.lastEventTime = n.EventTime
+			s.mu.Unlock()
+			nchan <- n
+		}
+	}()
+	return reply, nil
+}
+
+// LastEventTime delivers the event time of the last notification received, or empty if none have been received.
+func (s *Subscription) LastEventTime() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastEventTime
+}
+
+// Delivers the create-subscription request, starting from the last event time if the stream supports replay.
+func (s *Subscription) request() *createSubscription {
+	req := &createSubscription{Stream: s.Stream, StartTime: s.StartTime, StopTime: s.StopTime}
+	if s.Filter != "" {
+		req.Filter = &subscriptionFilter{Type: "subtree", Content: s.Filter}
+	}
+	if last := s.LastEventTime(); last != "" && (s.Replay || s.StartTime != "") {
+		req.StartTime = last
+	}
+	return req
+}
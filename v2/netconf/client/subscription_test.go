@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionReestablished(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	sub := &Subscription{Stream: "NETCONF", Filter: `<netconf-session-start/>`, Replay: true}
+	nch := make(chan *common.Notification)
+
+	ncs := newNCClientSession(t, ts)
+	_, err := sub.Subscribe(ncs, nch)
+	assert.NoError(t, err)
+	sh := ts.SessionHandler(ncs.ID())
+	assert.Equal(t, `<stream>NETCONF</stream><filter type="subtree"><netconf-session-start/></filter>`, sh.LastReq().Body)
+
+	sh.SendNotification(notificationEvent())
+	n := <-nch
+	assert.Equal(t, n.EventTime, sub.LastEventTime())
+
+	// The subscription is re-established on a new session, replaying from the last event received.
+	ncs.Close()
+	ncs = newNCClientSession(t, ts)
+	defer ncs.Close()
+	_, err = sub.Subscribe(ncs, nch)
+	assert.NoError(t, err)
+	sh = ts.SessionHandler(ncs.ID())
+	assert.Equal(t, `<stream>NETCONF</stream><filter type="subtree"><netconf-session-start/></filter>`+
+		`<startTime>`+n.EventTime+`</startTime>`, sh.LastReq().Body)
+
+	sh.SendNotification(notificationEvent())
+	n = <-nch
+	assert.Equal(t, "netconf-session-start", n.XMLName.Local, "Expecting notification on the same channel")
+}
+
+func TestSubscriptionWithoutReplay(t *testing.T) {
+	sub := &Subscription{StopTime: "2026-01-01T00:00:00Z"}
+	sub.lastEventTime = "2025-01-01T00:00:00Z"
+	assert.Equal(t, &createSubscription{StopTime: "2026-01-01T00:00:00Z"}, sub.request(),
+		"Expecting start time to be omitted where replay is not supported")
+
+	sub.StartTime = "2024-01-01T00:00:00Z"
+	assert.Equal(t, "2025-01-01T00:00:00Z", sub.request().StartTime,
+		"Expecting replay to resume from the last event")
+}
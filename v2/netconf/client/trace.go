@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/common/codec"
 
 	"github.com/imdario/mergo"
 	"golang.org/x/crypto/ssh"
@@ -54,6 +55,10 @@ type ClientTrace struct {
 	// HelloDone is called when the hello message has been received from the server.
 	HelloDone func(msg *common.HelloMessage)
 
+	// HelloAnomaly is called when the session-id of the hello message received from the server is missing or is
+	// not a number; see SessionIDPolicy.
+	HelloAnomaly func(target string, anomaly *HelloAnomaly)
+
 	// ConnectionClosed is called after a transport connection has been closed, with
 	// err indicating any error condition.
 	ConnectionClosed func(target string, err error)
@@ -73,12 +78,24 @@ type ClientTrace struct {
 	// Error is called after an error condition has been detected.
 	Error func(context, target string, err error)
 
+	// MessageReceived is called when the start of a hello, rpc-reply or notification message has been received,
+	// with the sequence number assigned to the message.
+	MessageReceived func(target, name string, seq uint64)
+
 	// NotificationReceived is called when a notification has been received.
 	NotificationReceived func(m *common.Notification)
 
 	// NotificationDropped is called when a notification is dropped because the reader is not ready.
 	NotificationDropped func(m *common.Notification)
 
+	// ReplyDropped is called when an rpc reply is dropped because nobody read it from the reply channel
+	// within the configured reply timeout.
+	ReplyDropped func(res *common.RPCReply)
+
+	// LimitExceeded is called when a message received from the server exceeds one of the limits defined by the
+	// session configuration, after which the session is closed.
+	LimitExceeded func(target string, err *codec.LimitError)
+
 	// ExecuteStart is called before the execution of an rpc request.
 	ExecuteStart func(req common.Request, async bool)
 
@@ -91,6 +108,12 @@ var DefaultLoggingHooks = &ClientTrace{
 	Error: func(context, target string, err error) {
 		log.Printf("NETCONF-Error context:%s target:%s err:%v\n", context, target, err)
 	},
+	LimitExceeded: func(target string, err *codec.LimitError) {
+		log.Printf("NETCONF-LimitExceeded target:%s err:%v\n", target, err)
+	},
+	HelloAnomaly: func(target string, anomaly *HelloAnomaly) {
+		log.Printf("NETCONF-HelloAnomaly target:%s err:%v assigned-id:%d\n", target, anomaly, anomaly.AssignedID)
+	},
 }
 
 // MetricLoggingHooks provides a set of hooks that will log network metrics.
@@ -139,12 +162,20 @@ var DiagnosticLoggingHooks = &ClientTrace{
 
 	Error: DefaultLoggingHooks.Error,
 
+	MessageReceived: func(target, name string, seq uint64) {
+		log.Printf("NETCONF-MessageReceived target:%s name:%s seq:%d\n", target, name, seq)
+	},
 	NotificationReceived: func(n *common.Notification) {
-		log.Printf("NETCONF-NotificationReceived %s\n", n.XMLName.Local)
+		log.Printf("NETCONF-NotificationReceived %s seq:%d\n", n.XMLName.Local, n.Sequence)
 	},
 	NotificationDropped: func(n *common.Notification) {
-		log.Printf("NETCONF-NotificationDropped %s\n", n.XMLName.Local)
+		log.Printf("NETCONF-NotificationDropped %s seq:%d\n", n.XMLName.Local, n.Sequence)
+	},
+	ReplyDropped: func(res *common.RPCReply) {
+		log.Printf("NETCONF-ReplyDropped message-id:%s seq:%d\n", res.MessageID, res.Sequence)
 	},
+	LimitExceeded: DefaultLoggingHooks.LimitExceeded,
+	HelloAnomaly:  DefaultLoggingHooks.HelloAnomaly,
 	ExecuteStart: func(req common.Request, async bool) {
 		log.Printf("NETCONF-ExecuteStart async:%v req:%s\n", async, req)
 	},
@@ -161,6 +192,7 @@ var NoOpLoggingHooks = &ClientTrace{
 	DialDone:         func(clientConfig *ssh.ClientConfig, target string, err error, d time.Duration) {},
 	ConnectionClosed: func(target string, err error) {},
 	HelloDone:        func(msg *common.HelloMessage) {},
+	HelloAnomaly:     func(target string, anomaly *HelloAnomaly) {},
 	ReadStart:        func(p []byte) {},
 	ReadDone:         func(p []byte, c int, err error, d time.Duration) {},
 
@@ -168,8 +200,11 @@ var NoOpLoggingHooks = &ClientTrace{
 	WriteDone:  func(p []byte, c int, err error, d time.Duration) {},
 
 	Error:                func(context, target string, err error) {},
+	MessageReceived:      func(target, name string, seq uint64) {},
 	NotificationReceived: func(n *common.Notification) {},
 	NotificationDropped:  func(n *common.Notification) {},
+	ReplyDropped:         func(res *common.RPCReply) {},
+	LimitExceeded:        func(target string, err *codec.LimitError) {},
 	ExecuteStart:         func(req common.Request, async bool) {},
 	ExecuteDone:          func(req common.Request, async bool, res *common.RPCReply, err error, d time.Duration) {},
 }
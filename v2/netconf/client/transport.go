@@ -2,12 +2,41 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/damianoneill/net/v2/internal/keepalive"
+
 	"golang.org/x/crypto/ssh"
 )
 
+// ErrPeerUnresponsive is reported to the ConnectionClosed trace hook when a session is closed because the
+// server failed to reply to keepalive requests.
+var ErrPeerUnresponsive = keepalive.ErrPeerUnresponsive
+
+// WriteTimeoutError is returned when a write to the transport does not complete before the write deadline, for
+// example because the server has stopped reading. The transport is closed, as the message is incomplete.
+type WriteTimeoutError struct {
+	// The target of the transport.
+	Target string
+	// The number of bytes written between the deadline being set and the start of the stalled write.
+	Written int
+	// The number of bytes in the stalled write.
+	Pending int
+}
+
+func (e *WriteTimeoutError) Error() string {
+	return fmt.Sprintf("write to %s timed out after %d bytes, with %d bytes pending", e.Target, e.Written, e.Pending)
+}
+
+// Timeout indicates that the error is a timeout, as for net.Error.
+func (e *WriteTimeoutError) Timeout() bool {
+	return true
+}
+
 // The Secure Transport layer provides a communication path between
 // the client and server.  NETCONF can be layered over any
 // transport protocol that provides a set of basic requirements.
@@ -26,6 +55,13 @@ type tImpl struct {
 	trace       *ClientTrace
 	target      string
 	dialer      SSHClientFactory
+	closeOnce   sync.Once
+	closeErr    error
+	// Closed when the transport is closed, to stop keepalive requests.
+	done chan struct{}
+	// The write deadline, and the number of bytes written since it was set; writes are serialised by the session.
+	writeDeadline time.Time
+	written       int
 }
 
 // SSHClientFactory defines a factory that provides an SSH client.
@@ -39,7 +75,7 @@ type SSHClientFactory interface {
 // NewSSHTransport creates a new SSH transport, connecting to the target with the supplied client configuration
 // and requesting the specified subsystem.
 func NewSSHTransport(ctx context.Context, dialer SSHClientFactory, target string) (rt Transport, err error) {
-	impl := tImpl{target: target, dialer: dialer}
+	impl := tImpl{target: target, dialer: dialer, done: make(chan struct{})}
 	impl.trace = ContextClientTrace(ctx)
 
 	impl.trace.ConnectStart(target)
@@ -62,6 +98,10 @@ func NewSSHTransport(ctx context.Context, dialer SSHClientFactory, target string
 		return
 	}
 
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
 	if impl.sshSession, err = impl.sshClient.NewSession(); err != nil {
 		return
 	}
@@ -85,12 +125,77 @@ func NewSSHTransport(ctx context.Context, dialer SSHClientFactory, target string
 	return rt, err
 }
 
+// SSHConnection is implemented by the sessions and transports established over ssh, to deliver the ssh client
+// connection, so that other channels, such as file transfers, can share the connection of a session.
+type SSHConnection interface {
+	SSHClient() *ssh.Client
+}
+
+// SSHClientOf delivers the ssh client connection of the session or transport v, or nil if v is not established over
+// ssh.
+func SSHClientOf(v interface{}) *ssh.Client {
+	if c, ok := v.(SSHConnection); ok {
+		return c.SSHClient()
+	}
+	return nil
+}
+
+// Delivers the target of the transport, or an empty string if t is nil.
+func (t *tImpl) targetName() string {
+	if t == nil {
+		return ""
+	}
+	return t.target
+}
+
+func (t *tImpl) SSHClient() *ssh.Client {
+	return t.sshClient
+}
+
 func (t *tImpl) Read(p []byte) (n int, err error) {
 	return t.reader.Read(p)
 }
 
 func (t *tImpl) Write(p []byte) (n int, err error) {
-	return t.writeCloser.Write(p)
+	if t.writeDeadline.IsZero() {
+		return t.writeCloser.Write(p)
+	}
+
+	terr := &WriteTimeoutError{Target: t.target, Written: t.written, Pending: len(p)}
+	wait := time.Until(t.writeDeadline)
+	if wait <= 0 {
+		return 0, t.writeTimedOut(terr)
+	}
+
+	// Closing the transport unblocks the stalled write.
+	var stalled uint32
+	timer := time.AfterFunc(wait, func() {
+		atomic.StoreUint32(&stalled, 1)
+		_ = t.closeWithCause(terr)
+	})
+	n, err = t.writeCloser.Write(p)
+	timer.Stop()
+	t.written += n
+
+	if atomic.LoadUint32(&stalled) == 1 {
+		err = t.writeTimedOut(terr)
+	}
+	return n, err
+}
+
+// SetWriteDeadline defines the time by which writes to the transport must complete, after which the transport is
+// closed and the write fails with a WriteTimeoutError. A zero value means writes do not time out.
+func (t *tImpl) SetWriteDeadline(deadline time.Time) error {
+	t.writeDeadline = deadline
+	t.written = 0
+	return nil
+}
+
+// Reports the write timeout, and closes the transport.
+func (t *tImpl) writeTimedOut(err *WriteTimeoutError) error {
+	t.trace.Error("Write timed out", t.target, err)
+	_ = t.closeWithCause(err)
+	return err
 }
 
 // Close closes all session resources in the following order:
@@ -100,9 +205,22 @@ func (t *tImpl) Write(p []byte) (n int, err error) {
 //  3. SSH client
 //
 // Errors are returned with priority matching the same order.
-func (t *tImpl) Close() (err error) {
-	defer t.trace.ConnectionClosed(t.target, err)
+// Subsequent calls return the error returned by the first.
+func (t *tImpl) Close() error {
+	return t.closeWithCause(nil)
+}
 
+// Closes the transport, reporting cause to the ConnectionClosed trace hook.
+func (t *tImpl) closeWithCause(cause error) error {
+	t.closeOnce.Do(func() {
+		close(t.done)
+		t.closeErr = t.close()
+		t.trace.ConnectionClosed(t.target, cause)
+	})
+	return t.closeErr
+}
+
+func (t *tImpl) close() (err error) {
 	var (
 		writeCloseErr      error
 		sshSessionCloseErr error
@@ -130,6 +248,14 @@ func (t *tImpl) Close() (err error) {
 	return err
 }
 
+// Starts sending keepalive requests to the server, closing the transport if the server becomes unresponsive.
+func (t *tImpl) startKeepalive(interval time.Duration, maxMissed int) {
+	go keepalive.Run(t.sshClient, interval, maxMissed, t.done, func(err error) {
+		t.trace.Error("Keepalive failed", t.target, err)
+		_ = t.closeWithCause(err)
+	})
+}
+
 type traceReader struct {
 	r     io.Reader
 	trace *ClientTrace
@@ -3,7 +3,9 @@ package client
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -128,6 +130,87 @@ func TestTrace(t *testing.T) {
 	assert.Contains(t, traces[8], "ConnectionClosed target:localhost:")
 }
 
+func TestKeepalive(t *testing.T) {
+	ts := testserver.NewSSHServer(t, "testUser", "testPassword")
+	defer ts.Close()
+
+	sshConfig := &ssh.ClientConfig{
+		User:            "testUser",
+		Auth:            []ssh.AuthMethod{ssh.Password("testPassword")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	var closedErrs []error
+	ctx := WithClientTrace(dftContext, &ClientTrace{
+		ConnectionClosed: func(target string, err error) {
+			closedErrs = append(closedErrs, err)
+		},
+	})
+	tr, err := newTransport(ctx, ts.Port(), sshConfig)
+	assert.NoError(t, err, "Not expecting new transport to fail")
+
+	tr.(*tImpl).startKeepalive(5*time.Millisecond, 2)
+	time.Sleep(50 * time.Millisecond)
+
+	rdr := bufio.NewReader(tr)
+	_, _ = tr.Write([]byte("Message\n"))
+	response, _ := rdr.ReadString('\n')
+	assert.Equal(t, "GOT:Message\n", response, "Expecting responsive transport to remain open")
+	assert.Empty(t, closedErrs)
+
+	_ = tr.(*tImpl).closeWithCause(ErrPeerUnresponsive)
+	_ = tr.Close()
+	assert.Equal(t, []error{ErrPeerUnresponsive}, closedErrs, "Expecting a single close with distinct error")
+}
+
+func TestWriteDeadline(t *testing.T) {
+	var errs []error
+	var closedErr error
+	trace := &ClientTrace{
+		Error: func(context, target string, err error) {
+			errs = append(errs, err)
+		},
+		ConnectionClosed: func(target string, err error) {
+			closedErr = err
+		},
+	}
+
+	// A pipe that is never read stalls writes, as for a server that has stopped reading.
+	_, w := io.Pipe()
+	tr := &tImpl{writeCloser: w, dialer: &RealDialer{}, trace: trace, target: "stalled", done: make(chan struct{})}
+
+	assert.NoError(t, tr.SetWriteDeadline(time.Now().Add(20*time.Millisecond)))
+	begin := time.Now()
+	_, err := tr.Write([]byte("Message"))
+	assert.WithinDuration(t, begin.Add(20*time.Millisecond), time.Now(), time.Second)
+
+	var terr *WriteTimeoutError
+	assert.True(t, errors.As(err, &terr), "Expecting write to time out")
+	assert.True(t, terr.Timeout())
+	assert.Equal(t, &WriteTimeoutError{Target: "stalled", Written: 0, Pending: 7}, terr)
+	assert.Equal(t, "write to stalled timed out after 0 bytes, with 7 bytes pending", err.Error())
+	assert.Equal(t, []error{err}, errs, "Expecting timeout to be reported")
+	assert.Equal(t, err, closedErr, "Expecting transport to be closed")
+}
+
+func TestWriteDeadlineExpired(t *testing.T) {
+	r, w := io.Pipe()
+	go func() { _, _ = io.Copy(io.Discard, r) }()
+	tr := &tImpl{writeCloser: w, dialer: &RealDialer{}, trace: NoOpLoggingHooks, target: "target", done: make(chan struct{})}
+
+	assert.NoError(t, tr.SetWriteDeadline(time.Now().Add(time.Second)))
+	n, err := tr.Write([]byte("Message"))
+	assert.NoError(t, err, "Expecting write within deadline to succeed")
+	assert.Equal(t, 7, n)
+
+	tr.writeDeadline = time.Now().Add(-time.Millisecond)
+	_, err = tr.Write([]byte("More"))
+	assert.Equal(t, &WriteTimeoutError{Target: "target", Written: 7, Pending: 4}, err)
+
+	_, err = tr.Write([]byte("After"))
+	assert.Error(t, err, "Expecting transport to be closed")
+}
+
 func newTransport(ctx context.Context, port int, cfg *ssh.ClientConfig) (Transport, error) {
 	target := fmt.Sprintf("localhost:%d", port)
 	return NewSSHTransport(ctx, NewDialer(target, cfg), target)
@@ -36,19 +36,53 @@ func (e *Encoder) Encode(msg interface{}) error {
 	return e.ncEncoder.EndOfMessage()
 }
 
-// NewDecoder delivers a new decoder.
-func NewDecoder(t io.Reader) *Decoder {
-	ncDecoder := rfc6242.NewDecoder(t)
+// EncodeStream encodes a netconf message whose content is written to the writer by fn, allowing a large message
+// to be written incrementally. The writer applies the message framing, so that with chunked framing each call to
+// Write produces one or more chunks.
+func (e *Encoder) EncodeStream(fn func(w io.Writer) error) error {
+	_, err := e.ncEncoder.Write([]byte(xml.Header))
+	if err != nil {
+		return err
+	}
+
+	err = fn(e.ncEncoder)
+	if err != nil {
+		return err
+	}
+	return e.ncEncoder.EndOfMessage()
+}
+
+// NewDecoder delivers a new decoder, configured with any framing options provided.
+func NewDecoder(t io.Reader, options ...rfc6242.DecoderOption) *Decoder {
+	ncDecoder := rfc6242.NewDecoder(t, options...)
 	return &Decoder{Decoder: xml.NewDecoder(ncDecoder), ncDecoder: ncDecoder}
 }
 
-// NewEncoder delivers a new encoder.
-func NewEncoder(t io.Writer) *Encoder {
-	ncEncoder := rfc6242.NewEncoder(t)
+// NewEncoder delivers a new encoder, configured with any framing options provided.
+func NewEncoder(t io.Writer, options ...rfc6242.EncoderOption) *Encoder {
+	ncEncoder := rfc6242.NewEncoder(t, options...)
 	return &Encoder{xmlEncoder: xml.NewEncoder(ncEncoder), ncEncoder: ncEncoder}
 }
 
+// Stats defines the framing statistics of a decoder/encoder pair.
+type Stats struct {
+	// Decoded holds statistics for received messages.
+	Decoded rfc6242.Stats
+	// Encoded holds statistics for sent messages.
+	Encoded rfc6242.Stats
+}
+
+// Stats delivers the framing statistics of the decoder.
+func (d *Decoder) Stats() rfc6242.Stats {
+	return d.ncDecoder.Stats()
+}
+
+// Stats delivers the framing statistics of the encoder.
+func (e *Encoder) Stats() rfc6242.Stats {
+	return e.ncEncoder.Stats()
+}
+
 // EnableChunkedFraming enables chunked framing on the specified decoder and encoder.
 func EnableChunkedFraming(d *Decoder, e *Encoder) {
-	rfc6242.SetChunkedFraming(d.ncDecoder, e.ncEncoder)
+	SetFraming(d, e, rfc6242.ChunkedFraming)
 }
@@ -1,10 +1,13 @@
 package codec
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"testing"
 
 	"github.com/damianoneill/net/netconf/mocks"
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
 	"github.com/stretchr/testify/mock"
 	assert "github.com/stretchr/testify/require"
 )
@@ -36,9 +39,29 @@ func TestEnableChunkedFraming(t *testing.T) {
 	enc := NewEncoder(nil)
 	dec := NewDecoder(nil)
 
-	assert.False(t, enc.ncEncoder.ChunkedFraming)
+	assert.Nil(t, enc.ncEncoder.Framing)
 
 	EnableChunkedFraming(dec, enc)
 
-	assert.True(t, enc.ncEncoder.ChunkedFraming)
+	assert.Equal(t, rfc6242.ChunkedFraming, enc.ncEncoder.Framing)
+}
+
+func TestEncodeStream(t *testing.T) {
+	out := &bytes.Buffer{}
+	enc := NewEncoder(out)
+	EnableChunkedFraming(NewDecoder(nil), enc)
+
+	err := enc.EncodeStream(func(w io.Writer) error {
+		for _, part := range []string{"<a>", "text", "</a>"} {
+			if _, err := w.Write([]byte(part)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "\n#39\n"+`<?xml version="1.0" encoding="UTF-8"?>`+"\n\n#3\n<a>\n#4\ntext\n#4\n</a>\n##\n", out.String())
+
+	err = enc.EncodeStream(func(w io.Writer) error { return errors.New("failed") })
+	assert.EqualError(t, err, "failed")
 }
@@ -0,0 +1,64 @@
+package codec
+
+import (
+	"sync"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
+)
+
+// Maps the capabilities that identify a framing to the framing, in order of preference.
+var (
+	capabilityFramingsMu sync.RWMutex
+	capabilityFramings   = []capabilityFraming{{capability: "urn:ietf:params:netconf:base:1.1", framing: rfc6242.ChunkedFraming}}
+)
+
+type capabilityFraming struct {
+	capability string
+	framing    rfc6242.Framing
+}
+
+// RegisterCapabilityFraming maps a capability to the framing that is used once both peers have advertised the
+// capability in their hello messages. Capabilities registered later are preferred to those registered earlier,
+// and registering a capability again replaces its framing.
+// The NETCONF 1.1 base capability is mapped to chunked framing by default.
+func RegisterCapabilityFraming(capability string, f rfc6242.Framing) {
+	capabilityFramingsMu.Lock()
+	defer capabilityFramingsMu.Unlock()
+
+	mapped := []capabilityFraming{{capability: capability, framing: f}}
+	for _, cf := range capabilityFramings {
+		if cf.capability != capability {
+			mapped = append(mapped, cf)
+		}
+	}
+	capabilityFramings = mapped
+}
+
+// NegotiateFraming delivers the preferred framing mapped to a capability advertised by both the local and remote
+// peer, or nil if there is none, in which case end-of-message framing remains in use.
+func NegotiateFraming(local, remote []string) rfc6242.Framing {
+	capabilityFramingsMu.RLock()
+	defer capabilityFramingsMu.RUnlock()
+
+	for _, cf := range capabilityFramings {
+		if hasCapability(local, cf.capability) && hasCapability(remote, cf.capability) {
+			return cf.framing
+		}
+	}
+	return nil
+}
+
+func hasCapability(caps []string, capability string) bool {
+	for _, c := range caps {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFraming selects the framing used by the specified decoder and encoder. The decoder adopts the framing once
+// the message it is decoding has ended.
+func SetFraming(d *Decoder, e *Encoder, f rfc6242.Framing) {
+	rfc6242.SetFraming(f, d.ncDecoder, e.ncEncoder)
+}
@@ -0,0 +1,42 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestNegotiateFraming(t *testing.T) {
+	base10 := "urn:ietf:params:netconf:base:1.0"
+	base11 := "urn:ietf:params:netconf:base:1.1"
+
+	assert.Equal(t, rfc6242.ChunkedFraming, NegotiateFraming([]string{base10, base11}, []string{base11}))
+	assert.Nil(t, NegotiateFraming([]string{base10, base11}, []string{base10}))
+	assert.Nil(t, NegotiateFraming([]string{base10}, []string{base10, base11}))
+
+	experimental := "urn:example:netconf:framing:raw"
+	RegisterCapabilityFraming(experimental, rfc6242.RawFraming)
+	defer func() {
+		capabilityFramings = capabilityFramings[1:]
+	}()
+
+	assert.Equal(t, rfc6242.RawFraming, NegotiateFraming([]string{base11, experimental}, []string{experimental, base11}))
+	assert.Equal(t, rfc6242.ChunkedFraming, NegotiateFraming([]string{base11, experimental}, []string{base11}))
+}
+
+func TestSetFraming(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewEncoder(buf, rfc6242.WithEncoderFraming(rfc6242.RawFraming))
+	assert.NoError(t, enc.Encode(&testStr{Field: "a"}))
+	assert.NotContains(t, buf.String(), "]]>]]>")
+
+	dec := NewDecoder(buf, rfc6242.WithFraming(rfc6242.RawFraming))
+	var msg testStr
+	assert.NoError(t, dec.Decode(&msg))
+	assert.Equal(t, "a", msg.Field)
+
+	SetFraming(dec, enc, rfc6242.EndOfMessageFraming)
+	assert.Equal(t, rfc6242.EndOfMessageFraming, enc.ncEncoder.Framing)
+}
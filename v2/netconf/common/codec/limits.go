@@ -0,0 +1,219 @@
+package codec
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
+)
+
+// Limits defines constraints on the messages accepted by a decoder, protecting against a peer that sends messages
+// that would otherwise be decoded without bound.
+type Limits struct {
+	// The maximum size of a message in bytes. Zero means no limit.
+	MaxSize int
+	// The maximum nesting depth of elements within a message. Zero means no limit.
+	MaxDepth int
+	// The maximum number of xml tokens within a message, counting each start element, end element, character
+	// data, comment, processing instruction and directive. Zero means no limit.
+	MaxTokens int
+}
+
+// Identifies the limit exceeded by a message.
+const (
+	LimitSize   = "size"
+	LimitDepth  = "depth"
+	LimitTokens = "tokens"
+)
+
+// LimitError is reported by a decoder when a message exceeds one of its Limits.
+// Once a limit has been exceeded, the decoder reports the error on all subsequent reads.
+type LimitError struct {
+	// Identifies the limit that was exceeded; one of LimitSize, LimitDepth or LimitTokens.
+	Limit string
+	// The value of the limit.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("message exceeds maximum %s of %d", e.Limit, e.Max)
+}
+
+// NewLimitedDecoder delivers a new decoder that rejects messages exceeding limits, configured with any framing
+// options provided.
+func NewLimitedDecoder(t io.Reader, limits Limits, options ...rfc6242.DecoderOption) *Decoder {
+	if limits.MaxSize > 0 {
+		options = append(options, rfc6242.WithMaximumMessageSize(limits.MaxSize))
+	}
+	ncDecoder := rfc6242.NewDecoder(t, options...)
+	lr := &limitReader{r: ncDecoder, limits: limits}
+	return &Decoder{Decoder: xml.NewDecoder(lr), ncDecoder: ncDecoder}
+}
+
+// States of the lexical scan applied by a limitReader.
+const (
+	lexText = iota
+	lexTagOpen
+	lexStartTag
+	lexAttrValue
+	lexEndTag
+	lexBang
+	lexComment
+	lexCDATA
+	lexDirective
+	lexProcInst
+)
+
+// limitReader applies a lexical scan to the decoded message stream, sufficient to track the element depth and
+// token count of each message without decoding it, before the data reaches the xml decoder.
+type limitReader struct {
+	r      io.Reader
+	limits Limits
+	err    error
+
+	state int
+	// Set whilst scanning character data, which is reported as a single token.
+	inText bool
+	// Set when the previous octet of a start tag is '/', indicating an empty element.
+	slash bool
+	// The quote character that terminates the current attribute value.
+	quote byte
+	// The number of consecutive octets seen that may terminate a comment, CDATA section or processing instruction.
+	closing int
+	// The nesting depth of '<' within a directive, such as a DOCTYPE with an internal subset.
+	nesting int
+
+	depth  int
+	tokens int
+}
+
+func (l *limitReader) Read(b []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.r.Read(b)
+	if err != nil && l.limits.MaxSize > 0 && errors.Is(err, rfc6242.ErrMessageSizeLimitExceeded) {
+		err = &LimitError{Limit: LimitSize, Max: l.limits.MaxSize}
+	}
+	for _, c := range b[:n] {
+		if l.err = l.scan(c); l.err != nil {
+			// The message is rejected, so none of the data is delivered.
+			return 0, l.err
+		}
+	}
+	if _, ok := err.(*LimitError); ok {
+		l.err = err
+	}
+	return n, err
+}
+
+// Advances the lexical scan by a single octet.
+//
+//nolint:gocyclo
+func (l *limitReader) scan(c byte) error {
+	switch l.state {
+	case lexText:
+		if c == '<' {
+			l.inText = false
+			l.state = lexTagOpen
+		} else if !l.inText {
+			l.inText = true
+			return l.token()
+		}
+	case lexTagOpen:
+		switch c {
+		case '/':
+			l.state = lexEndTag
+		case '!':
+			l.state = lexBang
+		case '?':
+			l.state, l.closing = lexProcInst, 0
+			return l.token()
+		default:
+			l.state, l.slash = lexStartTag, false
+			if l.depth++; l.limits.MaxDepth > 0 && l.depth > l.limits.MaxDepth {
+				return &LimitError{Limit: LimitDepth, Max: l.limits.MaxDepth}
+			}
+			return l.token()
+		}
+	case lexStartTag:
+		switch c {
+		case '"', '\'':
+			l.state, l.quote = lexAttrValue, c
+		case '>':
+			l.state = lexText
+			if l.slash {
+				// An empty element is reported as both a start and end element.
+				return l.endElement()
+			}
+		}
+		l.slash = c == '/'
+	case lexAttrValue:
+		if c == l.quote {
+			l.state = lexStartTag
+		}
+	case lexEndTag:
+		if c == '>' {
+			l.state = lexText
+			return l.endElement()
+		}
+	case lexBang:
+		switch c {
+		case '-':
+			l.state, l.closing = lexComment, 0
+		case '[':
+			l.state, l.closing = lexCDATA, 0
+		default:
+			l.state, l.nesting = lexDirective, 0
+		}
+		return l.token()
+	case lexComment:
+		l.state, l.closing = l.terminate(c, '-', 2, lexComment)
+	case lexCDATA:
+		l.state, l.closing = l.terminate(c, ']', 2, lexCDATA)
+	case lexProcInst:
+		l.state, l.closing = l.terminate(c, '?', 1, lexProcInst)
+	case lexDirective:
+		switch {
+		case c == '<':
+			l.nesting++
+		case c == '>' && l.nesting > 0:
+			l.nesting--
+		case c == '>':
+			l.state = lexText
+		}
+	}
+	return nil
+}
+
+// Tracks the octets terminating a comment, CDATA section or processing instruction, which end with at least
+// count occurrences of marker followed by '>'.
+func (l *limitReader) terminate(c, marker byte, count, state int) (int, int) {
+	switch {
+	case c == marker:
+		return state, l.closing + 1
+	case c == '>' && l.closing >= count:
+		return lexText, 0
+	default:
+		return state, 0
+	}
+}
+
+func (l *limitReader) endElement() error {
+	l.depth--
+	err := l.token()
+	if l.depth <= 0 {
+		// The end of the root element completes the message.
+		l.depth, l.tokens = 0, 0
+	}
+	return err
+}
+
+func (l *limitReader) token() error {
+	if l.tokens++; l.limits.MaxTokens > 0 && l.tokens > l.limits.MaxTokens {
+		return &LimitError{Limit: LimitTokens, Max: l.limits.MaxTokens}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const eom = "]]>]]>"
+
+func TestLimitedDecoder(t *testing.T) {
+	message := xml.Header + `<rpc-reply message-id="1"><data a="x/>y"><!-- <c> --><b/><![CDATA[<c>]]><b>text</b></data></rpc-reply>`
+
+	tests := []struct {
+		name   string
+		limits Limits
+		err    *LimitError
+	}{
+		{"NoLimits", Limits{}, nil},
+		{"WithinLimits", Limits{MaxSize: len(message), MaxDepth: 3, MaxTokens: 13}, nil},
+		{"SizeExceeded", Limits{MaxSize: len(message) - 1}, &LimitError{Limit: LimitSize, Max: len(message) - 1}},
+		{"DepthExceeded", Limits{MaxDepth: 2}, &LimitError{Limit: LimitDepth, Max: 2}},
+		{"TokensExceeded", Limits{MaxTokens: 12}, &LimitError{Limit: LimitTokens, Max: 12}},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The same message is sent twice, to check that limits apply to each message in turn.
+			input := strings.NewReader(message + eom + message + eom)
+			d := NewLimitedDecoder(input, tt.limits)
+
+			for i := 0; i < 2; i++ {
+				var reply struct {
+					Data string `xml:"data>b"`
+				}
+				err := d.Decode(&reply)
+				if tt.err != nil {
+					var lerr *LimitError
+					assert.True(t, errors.As(err, &lerr), "Expecting LimitError, got %v", err)
+					assert.Equal(t, tt.err, lerr)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, "text", reply.Data)
+			}
+			_, err := d.Token()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestLimitedDecoderTokenCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		tokens int
+	}{
+		{"EmptyElement", `<a/>`, 2},
+		{"Text", `<a>x y</a>`, 3},
+		{"Comment", `<a><!-- <b/> --></a>`, 3},
+		{"ProcInst", `<?xml version="1.0"?><a/>`, 3},
+		{"Directive", `<!DOCTYPE a [<!ELEMENT a ANY>]><a/>`, 3},
+		{"QuotedAttributes", `<a x='>' y="/"/>`, 2},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limitReader{r: strings.NewReader(tt.input), limits: Limits{MaxTokens: tt.tokens}}
+			_, err := io.ReadAll(l)
+			assert.NoError(t, err)
+
+			l = &limitReader{r: strings.NewReader(tt.input), limits: Limits{MaxTokens: tt.tokens - 1}}
+			_, err = io.ReadAll(l)
+			assert.Equal(t, &LimitError{Limit: LimitTokens, Max: tt.tokens - 1}, err)
+		})
+	}
+}
@@ -17,6 +17,9 @@ package rfc6242
 import (
 	"bufio"
 	"io"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
 )
 
 // FramerFn is the input tokenization function used by a Decoder.
@@ -45,12 +48,23 @@ type Decoder struct {
 	// Defines the number of bytes still to be read from the pipe reader.
 	pipedCount int
 
-	scanErr       error
-	chunkDataLeft uint64 // state
-	bufSize       int    // config
-	anySeen       bool
-	seenEOM       bool
-	eofOK         bool
+	scanErr         error
+	chunkDataLeft   uint64 // state
+	messageSize     int    // state
+	bufSize         int    // config
+	maxChunkSize    uint64 // config
+	maxMessageSize  int    // config
+	strictLineFeeds bool   // config
+	anySeen         bool
+	seenEOM         bool
+	eofOK           bool
+
+	// Streaming mode state; see WithStreaming.
+	streaming bool
+	ring      *ringBuffer
+	streamErr error
+
+	stats stats
 }
 
 // NewDecoder creates a new RFC6242 transport framing decoder reading from
@@ -67,6 +81,10 @@ func NewDecoder(input io.Reader, options ...DecoderOption) *Decoder {
 	for _, option := range options {
 		option(d)
 	}
+	if d.streaming {
+		d.ring = newRingBuffer(d.bufSize)
+		return d
+	}
 	d.pr, d.pw = io.Pipe()
 	if d.s == nil {
 		d.s = bufio.NewScanner(input)
@@ -80,6 +98,9 @@ func NewDecoder(input io.Reader, options ...DecoderOption) *Decoder {
 // Read reads from the Decoder's input and copies the data into b,
 // implementing io.Reader.
 func (d *Decoder) Read(b []byte) (n int, err error) {
+	if d.streaming {
+		return d.readStream(b)
+	}
 	// Deliver pending data from pipe, if there is any.
 	if d.pipedCount > 0 {
 		n, err = d.pr.Read(b)
@@ -113,7 +134,30 @@ func (d *Decoder) split(b []byte, eof bool) (a int, t []byte, err error) {
 		err = d.scanErr
 		return
 	}
-	return d.framer(d, b, eof)
+	a, t, err = d.framer(d, b, eof)
+	if err != nil {
+		atomic.AddUint64(&d.stats.framingErrors, 1)
+		return
+	}
+	atomic.AddUint64(&d.stats.bytes, uint64(len(t)))
+	if d.maxMessageSize > 0 {
+		// Framers deliver tokens that do not span messages, so the message size can be
+		// accumulated here and reset once the end of message has been seen.
+		if d.messageSize += len(t); d.messageSize > d.maxMessageSize {
+			atomic.AddUint64(&d.stats.framingErrors, 1)
+			return 0, nil, errors.WithStack(ErrMessageSizeLimitExceeded)
+		}
+		if d.eofOK {
+			d.messageSize = 0
+		}
+	}
+	return
+}
+
+// Stats delivers the framing statistics of the Decoder.
+// It is safe to call concurrently with Read.
+func (d *Decoder) Stats() Stats {
+	return d.stats.snapshot()
 }
 
 func (d *Decoder) setFramer(f FramerFn) {
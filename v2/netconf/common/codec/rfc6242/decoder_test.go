@@ -2,6 +2,7 @@
 package rfc6242
 
 import (
+	"errors"
 	"io"
 	"strings"
 	"testing"
@@ -244,6 +245,13 @@ func TestChunkedFramer(t *testing.T) {
 				{nil, "", "no valid chunk-size detected", false},
 			},
 		},
+		{
+			"InvalidEndOfChunks", 100,
+			[]decresp{
+				{[]string{"\n#6\n" + "<rpc/>" + "\n##X"}, "<rpc/>", "", false},
+				{nil, "", "invalid chunk header", false},
+			},
+		},
 		{
 			"InvalidChunkSize3", 100,
 			[]decresp{
@@ -280,6 +288,90 @@ func TestChunkedFramer(t *testing.T) {
 	}
 }
 
+func TestDecoderLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		chunked bool
+		options []DecoderOption
+		output  string
+		err     error
+	}{
+		{"ChunkWithinLimit", "\n#6\n<rpc/>\n##\n", true, []DecoderOption{WithMaximumDecodedChunkSize(6)}, "<rpc/>", nil},
+		{"ChunkExceedsLimit", "\n#6\n<rpc/>\n##\n", true, []DecoderOption{WithMaximumDecodedChunkSize(5)}, "", ErrChunkSizeLimitExceeded},
+		{
+			"ChunkedMessagesWithinLimit", "\n#3\n<rp\n#3\nc/>\n##\n\n#6\n<rpc/>\n##\n", true,
+			[]DecoderOption{WithMaximumMessageSize(6)}, "<rpc/><rpc/>", nil,
+		},
+		{
+			"ChunkedMessageExceedsLimit", "\n#3\n<rp\n#3\nc/>\n##\n", true,
+			[]DecoderOption{WithMaximumMessageSize(5)}, "", ErrMessageSizeLimitExceeded,
+		},
+		{"EOMMessagesWithinLimit", "<rpc/>" + EOM + "<rpc/>" + EOM, false, []DecoderOption{WithMaximumMessageSize(6)}, "<rpc/><rpc/>", nil},
+		{"EOMMessageExceedsLimit", "<rpc/>" + EOM, false, []DecoderOption{WithMaximumMessageSize(5)}, "", ErrMessageSizeLimitExceeded},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			if tt.chunked {
+				options = append(options, WithFramer(decoderChunked))
+			}
+			d := NewDecoder(strings.NewReader(tt.input), append(options, WithScannerBufferSize(0))...)
+
+			output, err := io.ReadAll(d)
+			if string(output) != tt.output {
+				t.Errorf("Decoder %s: output mismatch wanted >%s< got >%s<", tt.name, tt.output, output)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Decoder %s: error mismatch wanted %v got %v", tt.name, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestChunkedLineFeeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		strict bool
+		output string
+		err    string
+	}{
+		{"LineFeeds", "\n#6\n<rpc/>\n##\n", false, "<rpc/>", ""},
+		{"CarriageReturnsTolerated", "\n#6\r\n<rpc/>\n##\r\n", false, "<rpc/>", ""},
+		{"LongestChunkSizeTolerated", "\n#1000000000\r\n<rpc/>", false, "<rpc/>", io.ErrUnexpectedEOF.Error()},
+		{"StrictLineFeeds", "\n#6\n<rpc/>\n##\n", true, "<rpc/>", ""},
+		{"ChunkSizeCarriageReturnRejected", "\n#6\r\n<rpc/>\n##\n", true, "", "invalid syntax"},
+		{"EndOfChunksCarriageReturnRejected", "\n#6\n<rpc/>\n##\r\n", true, "<rpc/>", "invalid chunk header"},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, streaming := range []bool{false, true} {
+				options := []DecoderOption{WithFramer(decoderChunked), WithScannerBufferSize(0)}
+				if tt.strict {
+					options = append(options, WithStrictLineFeeds())
+				}
+				if streaming {
+					options = append(options, WithStreaming())
+				}
+				d := NewDecoder(strings.NewReader(tt.input), options...)
+
+				output, err := io.ReadAll(d)
+				if string(output) != tt.output {
+					t.Errorf("Decoder %s: output mismatch wanted >%s< got >%s<", tt.name, tt.output, output)
+				}
+				if err == nil && tt.err != "" || err != nil && (tt.err == "" || !strings.Contains(err.Error(), tt.err)) {
+					t.Errorf("Decoder %s: error mismatch wanted %s got %v", tt.name, tt.err, err)
+				}
+			}
+		})
+	}
+}
+
 func newTransport() *transport {
 	pr, pw := io.Pipe()
 	t := &transport{r: pr, w: pw, ch: make(chan string, 5)}
@@ -310,3 +402,20 @@ func (t *transport) Write(inputs []string, shouldClose bool) {
 		}
 	}
 }
+
+func TestDecoderStats(t *testing.T) {
+	d := NewDecoder(strings.NewReader("<hello/>"+EOM+"\n#3\n<rp\n#3\nc/>\n##\n\n#X"), WithScannerBufferSize(0))
+
+	buffer := make([]byte, 100)
+	_, _ = d.Read(buffer)
+	SetChunkedFraming(d)
+	_, err := io.ReadAll(d)
+	if err == nil {
+		t.Errorf("Decoder expecting framing error")
+	}
+
+	want := Stats{Messages: 2, Chunks: 2, Bytes: 14, MaxChunkSize: 3, FramingErrors: 1}
+	if got := d.Stats(); got != want {
+		t.Errorf("Decoder stats mismatch wanted %+v got %+v", want, got)
+	}
+}
@@ -17,6 +17,7 @@ package rfc6242
 import (
 	"io"
 	"strconv"
+	"sync/atomic"
 )
 
 // NewEncoder returns a new RFC6242 transport encoding writer with underlying
@@ -29,19 +30,26 @@ func NewEncoder(output io.Writer, opts ...EncoderOption) *Encoder {
 	return e
 }
 
-// Encoder is a filtering writer. By default it acts as a pass through writer.
-// If chunked mode is enabled (see SetChunkedFramingMode), input to Write calls
-// is chunked and the RFC6242 chunked encoding output written to the underlying
-// writer.
+// Encoder is a filtering writer. By default it acts as a pass through writer,
+// applying end-of-message framing. If another framing is selected (see SetFraming),
+// input to Write calls is encoded by that framing, for example chunked, and the
+// output written to the underlying writer.
 type Encoder struct {
 	// Output is the underlying Writer to receive encoded output
 	Output io.Writer
-	// ChunkedFraming sets whether the next call to Write should use
-	// chunked-message framing (true) or end-of-message framing (false)
+	// Framing defines the framing used by the next call to Write; nil selects
+	// the framing defined by ChunkedFraming.
+	Framing Framing
+	// ChunkedFraming selects chunked-message framing (true) or end-of-message
+	// framing (false) if Framing is nil.
+	//
+	// Deprecated: set Framing, for example with SetChunkedFraming.
 	ChunkedFraming bool
 	// MaxChunkSize is the maximum size of chunks the encoder will Encode. If
 	// zero, the Encoder places no artificial ceiling on the chunk size.
 	MaxChunkSize uint32
+
+	stats stats
 }
 
 // Write writes the framed output for b to the underlying writer
@@ -49,25 +57,36 @@ func (e *Encoder) Write(b []byte) (n int, err error) {
 	if len(b) == 0 {
 		return 0, nil
 	}
-	if e.ChunkedFraming {
-		return e.writeChunked(b)
-	}
-	return e.Output.Write(b)
+	return e.framing().Write(e, b)
 }
 
 // EndOfMessage must be called after each conceptual message (or XML document) is
-// written to the Encoder. It writes the appropriate NETCONF message ending,
-// either "]]>]]>" or if chunked framing is enabled, "\n##\n".
+// written to the Encoder. It writes the message ending defined by the framing,
+// for example "]]>]]>" or if chunked framing is enabled, "\n##\n".
 func (e *Encoder) EndOfMessage() error {
-	var err error
-	if e.ChunkedFraming {
-		_, err = e.Output.Write([]byte("\n##\n"))
-	} else {
-		_, err = e.Output.Write(tokenEOM)
+	err := e.framing().EndOfMessage(e)
+	if err == nil {
+		atomic.AddUint64(&e.stats.messages, 1)
 	}
 	return err
 }
 
+func (e *Encoder) framing() Framing {
+	if e.Framing == nil {
+		if e.ChunkedFraming {
+			return ChunkedFraming
+		}
+		return EndOfMessageFraming
+	}
+	return e.Framing
+}
+
+// Stats delivers the framing statistics of the Encoder.
+// It is safe to call concurrently with Write.
+func (e *Encoder) Stats() Stats {
+	return e.stats.snapshot()
+}
+
 // Close attempts to close the underlying writer.
 func (e *Encoder) Close() error {
 	// always be closing
@@ -101,6 +120,8 @@ func (e *Encoder) writeChunked(b []byte) (n int, err error) {
 			// io.Writer requires not returning nil error for short writes,
 			// so we do not check for them.
 			n += wn
+			atomic.AddUint64(&e.stats.bytes, uint64(wn))
+			e.stats.addChunk(uint64(chunksize))
 		}
 		if err != nil {
 			break
@@ -73,3 +73,29 @@ func TestChunkedEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestEncoderStats(t *testing.T) {
+	e := NewEncoder(bytes.NewBuffer([]byte{}), WithMaximumChunkSize(5))
+	_, _ = e.Write([]byte("<hello/>"))
+	_ = e.EndOfMessage()
+
+	SetChunkedFraming(e)
+	_, _ = e.Write([]byte("ABCDEFGH"))
+	_ = e.EndOfMessage()
+
+	want := Stats{Messages: 2, Chunks: 2, Bytes: 16, MaxChunkSize: 5}
+	if got := e.Stats(); got != want {
+		t.Errorf("Encoder stats mismatch wanted %+v got %+v", want, got)
+	}
+}
+
+func TestEncoderChunkedFramingField(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	e := &Encoder{Output: buf, MaxChunkSize: rfc6242maximumAllowedChunkSize, ChunkedFraming: true}
+	_, _ = e.Write([]byte("ABC"))
+	_ = e.EndOfMessage()
+
+	if want := "\n#3\nABC\n##\n"; buf.String() != want {
+		t.Errorf("Encoder buffer mismatch wanted >%s< got >%s<", want, buf.String())
+	}
+}
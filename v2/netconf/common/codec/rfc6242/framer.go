@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/pkg/errors"
 )
@@ -39,6 +40,12 @@ var (
 	// ErrChunkSizeTooLarge is a protocol error indicating that the
 	// chunk-size decoded exceeds the limit stated in RFC6242.
 	ErrChunkSizeTooLarge = errors.New("chunk size larger than maximum (4294967295)")
+	// ErrChunkSizeLimitExceeded is a protocol error indicating that the
+	// chunk-size decoded exceeds the limit configured on the Decoder.
+	ErrChunkSizeLimitExceeded = errors.New("chunk size larger than configured maximum")
+	// ErrMessageSizeLimitExceeded is a protocol error indicating that
+	// the size of a message exceeds the limit configured on the Decoder.
+	ErrMessageSizeLimitExceeded = errors.New("message size larger than configured maximum")
 )
 
 var tokenEOM = []byte("]]>]]>")
@@ -82,6 +89,7 @@ func decoderEndOfMessage(d *Decoder, b []byte, atEOF bool) (advance int, token [
 			// their peer has the appropriate capability (data
 			// contained within token).
 			if d.eofOK = i == len(tokenEOM); d.eofOK {
+				atomic.AddUint64(&d.stats.messages, 1)
 				// If there is a pending framer, it can now take effect (see comment in decoder setFramer())
 				if d.pendingFramer != nil {
 					d.framer = d.pendingFramer
@@ -121,15 +129,18 @@ func decoderChunked(d *Decoder, b []byte, atEOF bool) (advance int, token []byte
 
 		switch {
 		case d.chunkDataLeft == 0:
-			action, adv, chunksize, cherr := detectChunkHeader(cur)
+			action, adv, chunksize, cherr := detectChunkHeader(cur, d.strictLineFeeds)
 			switch {
 			case cherr != nil:
 				err = cherr
 			case action == chActionMoreData:
 				return
+			case action == chActionChunk && d.maxChunkSize > 0 && chunksize > d.maxChunkSize:
+				err = errors.WithStack(ErrChunkSizeLimitExceeded)
 			case action == chActionChunk:
 				advance += adv
 				d.chunkDataLeft = chunksize
+				d.stats.addChunk(chunksize)
 			case action == chActionEndOfChunks:
 				advance += adv
 				d.eofOK = true
@@ -137,8 +148,11 @@ func decoderChunked(d *Decoder, b []byte, atEOF bool) (advance int, token []byte
 				if !d.anySeen {
 					err = errors.WithStack(ErrZeroChunks)
 				} else {
-					// reset for the next message
+					// reset for the next message, and deliver the token so that it does
+					// not span messages.
 					d.anySeen = false
+					atomic.AddUint64(&d.stats.messages, 1)
+					return
 				}
 			default:
 				panic(errors.Errorf(
@@ -185,7 +199,9 @@ const (
 )
 
 //nolint:gocyclo
-func detectChunkHeader(b []byte) (action chunkHeaderAction, advance int, chunksize uint64, err error) {
+func detectChunkHeader(b []byte, strict bool) (action chunkHeaderAction, advance int, chunksize uint64, err error) {
+	// unless strict, a carriage return is tolerated before the line feed that terminates a chunk
+	// header or the end-of-chunks marker, as sent by some clients.
 	// special case short blocks to detect specific errors. we will
 	// never be called with an empty b.
 	if len(b) < 3 {
@@ -210,20 +226,25 @@ func detectChunkHeader(b []byte) (action chunkHeaderAction, advance int, chunksi
 			action = chActionChunk
 			bChunksize := b[2:]
 			lenChunksize := bytes.IndexByte(bChunksize, '\n')
+			lenToken := lenChunksize
+			if !strict && lenToken > 0 && bChunksize[lenToken-1] == '\r' {
+				lenToken--
+			}
 			switch {
 			case lenChunksize == -1:
-				if len(bChunksize) <= rfc6242maximumAllowedChunkSizeLength {
+				if len(bChunksize) <= rfc6242maximumAllowedChunkSizeLength ||
+					!strict && len(bChunksize) == rfc6242maximumAllowedChunkSizeLength+1 && bChunksize[len(bChunksize)-1] == '\r' {
 					// we might not have seen the whole chunk-size value
 					action = chActionMoreData
 				} else {
 					// we should have seen a chunk-size in bChunksize, but did not
 					err = errors.WithStack(ErrChunkSizeInvalid)
 				}
-			case lenChunksize > rfc6242maximumAllowedChunkSizeLength:
+			case lenToken > rfc6242maximumAllowedChunkSizeLength:
 				err = errors.WithStack(ErrChunkSizeTokenTooLong)
 			default:
 				// valid chunk-size token. decode chunk-size
-				chunksize, err = strconv.ParseUint(string(bChunksize[:lenChunksize]), 10, 64)
+				chunksize, err = strconv.ParseUint(string(bChunksize[:lenToken]), 10, 64)
 				if err == nil && chunksize > rfc6242maximumAllowedChunkSize {
 					err = errors.WithStack(ErrChunkSizeTooLarge)
 				}
@@ -237,6 +258,13 @@ func detectChunkHeader(b []byte) (action chunkHeaderAction, advance int, chunksi
 			case b[3] == '\n':
 				action = chActionEndOfChunks
 				advance = 4
+			case !strict && b[3] == '\r' && len(b) < 5:
+				action = chActionMoreData
+			case !strict && b[3] == '\r' && b[4] == '\n':
+				action = chActionEndOfChunks
+				advance = 5
+			default:
+				err = errors.WithStack(chunkHeaderLexError{got: b[3:4], want: []byte("\n")})
 			}
 		default:
 			err = chunkHeaderLexError{got: b[2:3], wexplicit: []byte("DIGIT1 or HASH")}
@@ -0,0 +1,106 @@
+package rfc6242
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Framing defines a message framing mechanism, used by a Decoder to extract messages from its input and by an
+// Encoder to delimit the messages it writes.
+type Framing interface {
+	// Name identifies the framing, for example in configuration.
+	Name() string
+	// Framer delivers the input tokenization function used by a Decoder.
+	Framer() FramerFn
+	// Write writes the framed output for b to the underlying writer of the Encoder.
+	Write(e *Encoder, b []byte) (int, error)
+	// EndOfMessage writes the message ending, if any, to the underlying writer of the Encoder.
+	EndOfMessage(e *Encoder) error
+}
+
+// The framings provided by the package.
+var (
+	// EndOfMessageFraming is the NETCONF 1.0 end-of-message framing, used by default.
+	EndOfMessageFraming Framing = eomFraming{}
+	// ChunkedFraming is the NETCONF 1.1 chunked framing.
+	ChunkedFraming Framing = chunkedFraming{}
+	// RawFraming applies no framing, for transports that delimit messages themselves.
+	// The Decoder delivers its input unchanged. As message boundaries are not known to the Decoder,
+	// messages are not counted by its statistics, and a maximum message size is applied to each block
+	// of input rather than to each message.
+	RawFraming Framing = rawFraming{}
+)
+
+// The registered framings, initially those provided by the package.
+var (
+	framingsMu sync.RWMutex
+	framings   = map[string]Framing{
+		EndOfMessageFraming.Name(): EndOfMessageFraming,
+		ChunkedFraming.Name():      ChunkedFraming,
+		RawFraming.Name():          RawFraming,
+	}
+)
+
+// RegisterFraming makes a framing available by name, replacing any framing registered with the same name.
+func RegisterFraming(f Framing) {
+	framingsMu.Lock()
+	defer framingsMu.Unlock()
+	framings[f.Name()] = f
+}
+
+// LookupFraming delivers the framing registered with the name, or nil if there is none.
+func LookupFraming(name string) Framing {
+	framingsMu.RLock()
+	defer framingsMu.RUnlock()
+	return framings[name]
+}
+
+type eomFraming struct{}
+
+func (eomFraming) Name() string { return "eom" }
+
+func (eomFraming) Framer() FramerFn { return decoderEndOfMessage }
+
+func (eomFraming) Write(e *Encoder, b []byte) (int, error) { return e.writeRaw(b) }
+
+func (eomFraming) EndOfMessage(e *Encoder) error {
+	_, err := e.Output.Write(tokenEOM)
+	return err
+}
+
+type chunkedFraming struct{}
+
+func (chunkedFraming) Name() string { return "chunked" }
+
+func (chunkedFraming) Framer() FramerFn { return decoderChunked }
+
+func (chunkedFraming) Write(e *Encoder, b []byte) (int, error) { return e.writeChunked(b) }
+
+func (chunkedFraming) EndOfMessage(e *Encoder) error {
+	_, err := e.Output.Write([]byte("\n##\n"))
+	return err
+}
+
+type rawFraming struct{}
+
+func (rawFraming) Name() string { return "raw" }
+
+func (rawFraming) Framer() FramerFn { return decoderRaw }
+
+func (rawFraming) Write(e *Encoder, b []byte) (int, error) { return e.writeRaw(b) }
+
+func (rawFraming) EndOfMessage(e *Encoder) error { return nil }
+
+// decoderRaw is the decoding function for raw framing, delivering all data available.
+func decoderRaw(d *Decoder, b []byte, atEOF bool) (advance int, token []byte, err error) {
+	d.eofOK = true
+	d.seenEOM = true
+	return len(b), b, nil
+}
+
+// Counts the bytes written by raw framings.
+func (e *Encoder) writeRaw(b []byte) (n int, err error) {
+	n, err = e.Output.Write(b)
+	atomic.AddUint64(&e.stats.bytes, uint64(n))
+	return
+}
@@ -0,0 +1,101 @@
+package rfc6242
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLookupFraming(t *testing.T) {
+	for name, want := range map[string]Framing{"eom": EndOfMessageFraming, "chunked": ChunkedFraming, "raw": RawFraming} {
+		if got := LookupFraming(name); got != want {
+			t.Errorf("LookupFraming(%q) wanted %v got %v", name, want, got)
+		}
+	}
+	if got := LookupFraming("unknown"); got != nil {
+		t.Errorf("LookupFraming of unknown framing wanted nil got %v", got)
+	}
+}
+
+type upperFraming struct{ rawFraming }
+
+func (upperFraming) Name() string { return "upper" }
+
+func (upperFraming) Write(e *Encoder, b []byte) (int, error) {
+	return e.writeRaw(bytes.ToUpper(b))
+}
+
+func TestRegisterFraming(t *testing.T) {
+	RegisterFraming(upperFraming{})
+
+	buf := &bytes.Buffer{}
+	e := NewEncoder(buf, WithEncoderFraming(LookupFraming("upper")))
+	_, _ = e.Write([]byte("<hello/>"))
+	_ = e.EndOfMessage()
+	if got := buf.String(); got != "<HELLO/>" {
+		t.Errorf("Encoder with registered framing wanted >%s< got >%s<", "<HELLO/>", got)
+	}
+}
+
+func TestEncoderFraming(t *testing.T) {
+	tests := []struct {
+		name    string
+		framing Framing
+		expect  string
+	}{
+		{"Default", nil, "ABC" + EOM},
+		{"EndOfMessage", EndOfMessageFraming, "ABC" + EOM},
+		{"Chunked", ChunkedFraming, "\n#3\nABC\n##\n"},
+		{"Raw", RawFraming, "ABC"},
+	}
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			e := NewEncoder(buf)
+			if tt.framing != nil {
+				SetFraming(tt.framing, e)
+			}
+			_, _ = e.Write([]byte("ABC"))
+			_ = e.EndOfMessage()
+			if got := buf.String(); got != tt.expect {
+				t.Errorf("Encoder %s: buffer mismatch wanted >%q< got >%q<", tt.name, tt.expect, got)
+			}
+			if got := e.Stats(); got.Messages != 1 || got.Bytes != 3 {
+				t.Errorf("Encoder %s: unexpected stats %+v", tt.name, got)
+			}
+		})
+	}
+}
+
+func TestDecoderRawFraming(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		options := []DecoderOption{WithScannerBufferSize(0)}
+		if streaming {
+			options = append(options, WithStreaming())
+		}
+
+		// Raw framing is adopted once the hello message has ended.
+		d := NewDecoder(strings.NewReader("<hello/>"+EOM+"<rpc/>]]><rpc/>"), options...)
+		buffer := make([]byte, 100)
+		n, err := d.Read(buffer)
+		if err != nil || string(buffer[:n]) != "<hello/>" {
+			t.Errorf("Decoder streaming:%v wanted hello got >%s< err %v", streaming, buffer[:n], err)
+		}
+		SetFraming(RawFraming, d)
+
+		data, err := io.ReadAll(d)
+		if err != nil || string(data) != "<rpc/>]]><rpc/>" {
+			t.Errorf("Decoder streaming:%v wanted raw input got >%s< err %v", streaming, data, err)
+		}
+	}
+}
+
+func TestDecoderWithFraming(t *testing.T) {
+	d := NewDecoder(strings.NewReader("\n#3\nABC\n##\n"), WithFraming(ChunkedFraming))
+	data, err := io.ReadAll(d)
+	if err != nil || string(data) != "ABC" {
+		t.Errorf("Decoder with chunked framing wanted >ABC< got >%s< err %v", data, err)
+	}
+}
@@ -41,6 +41,12 @@ func WithScannerBufferSize(bytes int) DecoderOption {
 // WithFramer sets the Decoder's initial Framer.
 func WithFramer(f FramerFn) DecoderOption { return func(d *Decoder) { d.framer = f } }
 
+// WithFraming sets the Decoder's initial Framer to that of the framing.
+func WithFraming(f Framing) DecoderOption { return WithFramer(f.Framer()) }
+
+// WithEncoderFraming sets the Encoder's initial framing.
+func WithEncoderFraming(f Framing) EncoderOption { return func(e *Encoder) { e.Framing = f } }
+
 // WithMaximumChunkSize sets an upper bound on the chunk size used
 // when writing data to an Encoder. If 0 is passed, the upper bound
 // reverts to the maximum chunk size permitted by RFC6242.
@@ -52,3 +58,28 @@ func WithMaximumChunkSize(size uint32) EncoderOption {
 		e.MaxChunkSize = size
 	}
 }
+
+// WithMaximumDecodedChunkSize sets an upper bound on the chunk size
+// accepted by a Decoder when chunked framing is in use. Chunks
+// declaring a larger size are rejected with ErrChunkSizeLimitExceeded.
+// If 0 is passed, the upper bound is the maximum chunk size
+// permitted by RFC6242.
+func WithMaximumDecodedChunkSize(size uint32) DecoderOption {
+	return func(d *Decoder) { d.maxChunkSize = uint64(size) }
+}
+
+// WithStrictLineFeeds configures a Decoder using chunked framing to
+// reject chunk headers and end-of-chunks markers that are not
+// terminated by a single line feed, as required by RFC6242. By
+// default, a carriage return preceding the line feed is tolerated.
+func WithStrictLineFeeds() DecoderOption {
+	return func(d *Decoder) { d.strictLineFeeds = true }
+}
+
+// WithMaximumMessageSize sets an upper bound on the size of a
+// message accepted by a Decoder. Messages exceeding the limit are
+// rejected with ErrMessageSizeLimitExceeded. If 0 is passed, message
+// size is not limited.
+func WithMaximumMessageSize(bytes int) DecoderOption {
+	return func(d *Decoder) { d.maxMessageSize = bytes }
+}
@@ -0,0 +1,45 @@
+package rfc6242
+
+import "sync/atomic"
+
+// Stats defines framing statistics maintained by a Decoder or Encoder.
+type Stats struct {
+	// Messages is the number of complete messages framed.
+	Messages uint64
+	// Chunks is the number of chunks framed, when chunked framing is in use.
+	Chunks uint64
+	// Bytes is the number of message bytes framed, excluding framing overhead.
+	Bytes uint64
+	// MaxChunkSize is the largest chunk size seen.
+	MaxChunkSize uint64
+	// FramingErrors is the number of framing errors detected; only maintained by a Decoder.
+	FramingErrors uint64
+}
+
+// Counters are updated by the goroutine driving the Decoder or Encoder, but may be read
+// concurrently, so are accessed atomically.
+type stats struct {
+	messages      uint64
+	chunks        uint64
+	bytes         uint64
+	maxChunkSize  uint64
+	framingErrors uint64
+}
+
+func (s *stats) snapshot() Stats {
+	return Stats{
+		Messages:      atomic.LoadUint64(&s.messages),
+		Chunks:        atomic.LoadUint64(&s.chunks),
+		Bytes:         atomic.LoadUint64(&s.bytes),
+		MaxChunkSize:  atomic.LoadUint64(&s.maxChunkSize),
+		FramingErrors: atomic.LoadUint64(&s.framingErrors),
+	}
+}
+
+func (s *stats) addChunk(size uint64) {
+	atomic.AddUint64(&s.chunks, 1)
+	if size > atomic.LoadUint64(&s.maxChunkSize) {
+		// Only the owning goroutine updates the value, so a plain store is sufficient.
+		atomic.StoreUint64(&s.maxChunkSize, size)
+	}
+}
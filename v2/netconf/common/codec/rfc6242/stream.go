@@ -0,0 +1,299 @@
+package rfc6242
+
+import (
+	"io"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Streaming mode decodes the input with a state machine operating on a ring buffer, in place of the bufio.Scanner
+// used by default. Message data is delivered incrementally, so no part of a message need be held in its entirety,
+// and chunk data is read directly into the caller's buffer when no input is buffered.
+
+// WithStreaming configures the Decoder to operate in streaming mode, in which input is decoded incrementally
+// by a state machine rather than a bufio.Scanner. Streaming mode is not subject to the maximum token size of the
+// scanner, so a message of any size can be decoded with a buffer (sized by WithScannerBufferSize) that need only
+// accommodate a chunk header.
+// In streaming mode, the data of a message may be delivered before a subsequent chunk of the message is rejected
+// because it exceeds the limit defined by WithMaximumMessageSize.
+// Streaming mode supports the end-of-message, chunked and raw framers only; a Framer defined by WithFramer
+// that is not the chunked or raw framer is treated as end-of-message framing.
+func WithStreaming() DecoderOption {
+	return func(d *Decoder) { d.streaming = true }
+}
+
+// Identifies the chunked and raw framers, so that streaming mode can follow changes to the Decoder framer.
+var (
+	chunkedFramer = reflect.ValueOf(FramerFn(decoderChunked)).Pointer()
+	rawFramer     = reflect.ValueOf(FramerFn(decoderRaw)).Pointer()
+)
+
+// Delivers the streaming step function corresponding to the Decoder framer.
+func (d *Decoder) streamStep() func([]byte) (int, bool, error) {
+	switch reflect.ValueOf(d.framer).Pointer() {
+	case chunkedFramer:
+		return d.streamChunked
+	case rawFramer:
+		return d.streamRaw
+	default:
+		return d.streamEndOfMessage
+	}
+}
+
+// Reads from the Decoder's input in streaming mode.
+func (d *Decoder) readStream(b []byte) (int, error) {
+	for d.streamErr == nil && len(b) > 0 {
+		n, more, err := d.streamStep()(b)
+		if err == io.EOF {
+			err = d.eofError()
+		}
+		switch {
+		case err != nil:
+			// Deliver any data obtained before the error, and report the error on the next call.
+			d.streamErr = err
+		case n == 0 && more:
+			d.streamErr = d.fill()
+			continue
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+	return 0, d.streamErr
+}
+
+// Reads more input into the ring buffer.
+func (d *Decoder) fill() error {
+	for {
+		n, err := d.ring.fill(d.Input)
+		switch {
+		case n > 0:
+			return nil
+		case err == io.EOF:
+			return d.eofError()
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// Delivers the error reported when the input is exhausted, which is io.EOF only at a message boundary.
+func (d *Decoder) eofError() error {
+	if d.eofOK && d.ring.Len() == 0 {
+		return io.EOF
+	}
+	return io.ErrUnexpectedEOF
+}
+
+// Delivers data from an end-of-message framed message, up to the end-of-message marker.
+func (d *Decoder) streamEndOfMessage(b []byte) (n int, more bool, err error) {
+	avail := d.ring.Len()
+	if avail == 0 {
+		return 0, true, nil
+	}
+	d.eofOK = false
+
+	eom := false
+scan:
+	for ; n < avail && n < len(b); n++ {
+		if d.ring.at(n) != tokenEOM[0] {
+			continue
+		}
+		switch matched := d.ring.match(n, tokenEOM); {
+		case matched == len(tokenEOM):
+			eom = true
+			break scan
+		case n+matched == avail:
+			// A partial marker ends the buffered input; it is held back until more input is available.
+			if n == 0 {
+				return 0, true, nil
+			}
+			break scan
+		}
+	}
+
+	if n, err = d.deliver(b[:n]); err != nil {
+		return
+	}
+	if eom {
+		d.ring.discard(len(tokenEOM))
+		d.endMessage()
+		d.seenEOM = true
+		if d.pendingFramer != nil {
+			d.framer, d.pendingFramer = d.pendingFramer, nil
+		}
+	}
+	return n, false, nil
+}
+
+// Delivers the data available, which is not framed.
+func (d *Decoder) streamRaw(b []byte) (n int, more bool, err error) {
+	d.eofOK = true
+	d.seenEOM = true
+	if d.ring.Len() == 0 {
+		return 0, true, nil
+	}
+	n, err = d.deliver(b[:min(d.ring.Len(), len(b))])
+	d.messageSize = 0
+	return n, false, err
+}
+
+// Delivers data from a chunked framed message, decoding chunk headers as they are encountered.
+func (d *Decoder) streamChunked(b []byte) (n int, more bool, err error) {
+	if d.chunkDataLeft == 0 {
+		// Sufficient to hold the longest valid chunk header, including a tolerated carriage return.
+		var header [rfc6242maximumAllowedChunkSizeLength + 4]byte
+		hlen := d.ring.peek(header[:])
+		if hlen == 0 {
+			return 0, true, nil
+		}
+		// The last byte is needed only to end the longest chunk header with a carriage return and line feed.
+		if hlen == len(header) && (d.strictLineFeeds || header[hlen-2] != '\r') {
+			hlen--
+		}
+		d.eofOK = false
+
+		action, advance, size, err := detectChunkHeader(header[:hlen], d.strictLineFeeds)
+		switch {
+		case err != nil:
+			return 0, false, d.framingError(err)
+		case action == chActionMoreData:
+			return 0, true, nil
+		case action == chActionEndOfChunks:
+			d.ring.discard(advance)
+			if !d.anySeen {
+				return 0, false, d.framingError(errors.WithStack(ErrZeroChunks))
+			}
+			d.anySeen = false
+			d.endMessage()
+			return 0, false, nil
+		case d.maxChunkSize > 0 && size > d.maxChunkSize:
+			return 0, false, d.framingError(errors.WithStack(ErrChunkSizeLimitExceeded))
+		case d.maxMessageSize > 0 && uint64(d.messageSize)+size > uint64(d.maxMessageSize):
+			return 0, false, d.framingError(errors.WithStack(ErrMessageSizeLimitExceeded))
+		}
+		d.ring.discard(advance)
+		d.chunkDataLeft = size
+		d.messageSize += int(size)
+		d.anySeen = true
+		d.stats.addChunk(size)
+	}
+
+	if uint64(len(b)) > d.chunkDataLeft {
+		b = b[:d.chunkDataLeft]
+	}
+	if d.ring.Len() > 0 {
+		n = d.ring.read(b)
+	} else if n, err = d.Input.Read(b); n > 0 {
+		// Read errors are reported on the next call.
+		err = nil
+	}
+	d.chunkDataLeft -= uint64(n)
+	atomic.AddUint64(&d.stats.bytes, uint64(n))
+	return n, false, err
+}
+
+// Copies message data from the ring buffer to b, enforcing the message size limit.
+func (d *Decoder) deliver(b []byte) (int, error) {
+	if d.maxMessageSize > 0 {
+		if d.messageSize += len(b); d.messageSize > d.maxMessageSize {
+			return 0, d.framingError(errors.WithStack(ErrMessageSizeLimitExceeded))
+		}
+	}
+	n := d.ring.read(b)
+	atomic.AddUint64(&d.stats.bytes, uint64(n))
+	return n, nil
+}
+
+func (d *Decoder) endMessage() {
+	atomic.AddUint64(&d.stats.messages, 1)
+	d.eofOK = true
+	d.messageSize = 0
+}
+
+func (d *Decoder) framingError(err error) error {
+	atomic.AddUint64(&d.stats.framingErrors, 1)
+	return err
+}
+
+// ringBuffer is a fixed capacity circular buffer of input octets.
+type ringBuffer struct {
+	buf   []byte
+	start int
+	size  int
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, capacity)}
+}
+
+// Len delivers the number of buffered octets.
+func (r *ringBuffer) Len() int {
+	return r.size
+}
+
+// Delivers the buffered octet at offset i.
+func (r *ringBuffer) at(i int) byte {
+	return r.buf[(r.start+i)%len(r.buf)]
+}
+
+// Delivers the number of leading octets of token that match the buffered octets at offset i.
+func (r *ringBuffer) match(i int, token []byte) (n int) {
+	for n < len(token) && i+n < r.size && r.at(i+n) == token[n] {
+		n++
+	}
+	return
+}
+
+// Copies buffered octets to b, without consuming them.
+func (r *ringBuffer) peek(b []byte) (n int) {
+	for ; n < len(b) && n < r.size; n++ {
+		b[n] = r.at(n)
+	}
+	return
+}
+
+// Copies buffered octets to b, consuming them.
+func (r *ringBuffer) read(b []byte) int {
+	n := copy(b, r.buf[r.start:min(r.start+r.size, len(r.buf))])
+	if n < len(b) && n < r.size {
+		n += copy(b[n:], r.buf[:r.size-n])
+	}
+	r.discard(n)
+	return n
+}
+
+// Consumes n buffered octets.
+func (r *ringBuffer) discard(n int) {
+	r.start = (r.start + n) % len(r.buf)
+	if r.size -= n; r.size == 0 {
+		// Maximise the contiguous space available to the next fill.
+		r.start = 0
+	}
+}
+
+// Reads from rd into the contiguous free space of the buffer.
+func (r *ringBuffer) fill(rd io.Reader) (int, error) {
+	end := r.start + r.size
+	var free []byte
+	if end < len(r.buf) {
+		free = r.buf[end:]
+	} else {
+		free = r.buf[end-len(r.buf) : r.start]
+	}
+	if len(free) == 0 {
+		return 0, errors.New("ring buffer full")
+	}
+	n, err := rd.Read(free)
+	r.size += n
+	return n, err
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,263 @@
+package rfc6242
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamingEOMDecoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		err    error
+	}{
+		{"Messages", "123456_abcde" + EOM + "XYZ1" + EOM, "123456_abcdeXYZ1", nil},
+		{"PartialEOM", "1234]]>]]XYZ" + EOM, "1234]]>]]XYZ", nil},
+		{"OverlappingEOM", "AB]" + EOM, "AB]", nil},
+		{"TrailingBrackets", "AB]]", "AB", io.ErrUnexpectedEOF},
+		{"MissingEOM", "ABCDEF", "ABCDEF", io.ErrUnexpectedEOF},
+		{"Empty", "", "", nil},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+				d := NewDecoder(r, WithStreaming(), WithScannerBufferSize(0))
+
+				output, err := io.ReadAll(iotest.OneByteReader(d))
+				if string(output) != tt.output {
+					t.Errorf("Decoder %s: output mismatch wanted >%s< got >%s<", tt.name, tt.output, output)
+				}
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Decoder %s: error mismatch wanted %v got %v", tt.name, tt.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamingReadsDoNotSpanMessages(t *testing.T) {
+	d := NewDecoder(strings.NewReader("<hello/>"+EOM+"<rpc/>"+EOM), WithStreaming())
+
+	buffer := make([]byte, 100)
+	for _, want := range []string{"<hello/>", "<rpc/>"} {
+		n, err := d.Read(buffer)
+		if err != nil || string(buffer[:n]) != want {
+			t.Errorf("Decoder read mismatch wanted >%s< got >%s< (%v)", want, buffer[:n], err)
+		}
+	}
+	if _, err := d.Read(buffer); err != io.EOF {
+		t.Errorf("Decoder expecting EOF got %v", err)
+	}
+}
+
+func TestStreamingFramerTransition(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs [][]string
+	}{
+		{"SimpleSwitch", [][]string{{"<hello/>" + EOM}, {"\n#6\n", "<rpc/>", "\n##\n"}}},
+		{"SwitchWithDanglingEOM", [][]string{{"<hello/>"}, {EOM + "\n#6\n" + "<rpc/>" + "\n##\n"}}},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newTransport()
+			d := NewDecoder(transport.r, WithStreaming())
+
+			buffer := make([]byte, 100)
+			transport.Write(tt.inputs[0], false)
+			n, err := d.Read(buffer)
+			if err != nil || string(buffer[:n]) != "<hello/>" {
+				t.Errorf("Decoder %s: hello mismatch got >%s< (%v)", tt.name, buffer[:n], err)
+			}
+			SetChunkedFraming(d)
+
+			transport.Write(tt.inputs[1], true)
+			n, err = d.Read(buffer)
+			if err != nil || string(buffer[:n]) != "<rpc/>" {
+				t.Errorf("Decoder %s: rpc mismatch got >%s< (%v)", tt.name, buffer[:n], err)
+			}
+			if _, err = d.Read(buffer); err != io.EOF {
+				t.Errorf("Decoder %s: expecting EOF got %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestStreamingChunkedDecoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		err    string
+	}{
+		{"SingleChunk", "\n#6\n<rpc/>\n##\n", "<rpc/>", ""},
+		{"MultipleChunks", "\n#3\n<rp\n#3\nc/>\n##\n\n#1\nX\n##\n", "<rpc/>X", ""},
+		{"ZeroChunks", "\n##\n", "", ErrZeroChunks.Error()},
+		{"InvalidHeader", "\n#6\n<rpc/>\nX", "<rpc/>", "invalid chunk header"},
+		{"InvalidChunkSize", "\n#12345678901\n", "", ErrChunkSizeInvalid.Error()},
+		{"ChunkSizeTooLarge", "\n#4294967296\n", "", ErrChunkSizeTooLarge.Error()},
+		{"TruncatedChunk", "\n#6\n<rp", "<rp", io.ErrUnexpectedEOF.Error()},
+		{"TruncatedHeader", "\n#6\n<rpc/>\n#", "<rpc/>", io.ErrUnexpectedEOF.Error()},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+				d := NewDecoder(r, WithStreaming(), WithFramer(decoderChunked), WithScannerBufferSize(0))
+
+				output, err := io.ReadAll(d)
+				if string(output) != tt.output {
+					t.Errorf("Decoder %s: output mismatch wanted >%s< got >%s<", tt.name, tt.output, output)
+				}
+				if err == nil && tt.err != "" || err != nil && !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Decoder %s: error mismatch wanted %s got %v", tt.name, tt.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamingLargeMessages(t *testing.T) {
+	// Messages far larger than the buffer, which would exceed the maximum token size of the scanner.
+	message := bytes.Repeat([]byte("<data>0123456789</data>"), 100000)
+
+	chunked := &bytes.Buffer{}
+	for rest := message; len(rest) > 0; {
+		size := len(rest)
+		if size > 1000000 {
+			size = 1000000
+		}
+		chunked.WriteString("\n#" + strconv.Itoa(size) + "\n")
+		chunked.Write(rest[:size])
+		rest = rest[size:]
+	}
+	chunked.WriteString("\n##\n")
+
+	tests := []struct {
+		name    string
+		input   []byte
+		options []DecoderOption
+	}{
+		{"EndOfMessage", append(append([]byte{}, message...), tokenEOM...), nil},
+		{"Chunked", chunked.Bytes(), []DecoderOption{WithFramer(decoderChunked)}},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(tt.input), append(tt.options, WithStreaming(), WithScannerBufferSize(64))...)
+
+			output, err := io.ReadAll(d)
+			if err != nil {
+				t.Errorf("Decoder %s: unexpected error %v", tt.name, err)
+			}
+			if !bytes.Equal(output, message) {
+				t.Errorf("Decoder %s: output mismatch, got %d bytes", tt.name, len(output))
+			}
+		})
+	}
+}
+
+func TestStreamingDecoderLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		chunked bool
+		options []DecoderOption
+		output  string
+		err     error
+	}{
+		{"ChunkWithinLimit", "\n#6\n<rpc/>\n##\n", true, []DecoderOption{WithMaximumDecodedChunkSize(6)}, "<rpc/>", nil},
+		{"ChunkExceedsLimit", "\n#6\n<rpc/>\n##\n", true, []DecoderOption{WithMaximumDecodedChunkSize(5)}, "", ErrChunkSizeLimitExceeded},
+		{
+			"ChunkedMessagesWithinLimit", "\n#3\n<rp\n#3\nc/>\n##\n\n#6\n<rpc/>\n##\n", true,
+			[]DecoderOption{WithMaximumMessageSize(6)}, "<rpc/><rpc/>", nil,
+		},
+		{
+			"ChunkedMessageExceedsLimit", "\n#3\n<rp\n#3\nc/>\n##\n", true,
+			[]DecoderOption{WithMaximumMessageSize(5)}, "<rp", ErrMessageSizeLimitExceeded,
+		},
+		{"EOMMessagesWithinLimit", "<rpc/>" + EOM + "<rpc/>" + EOM, false, []DecoderOption{WithMaximumMessageSize(6)}, "<rpc/><rpc/>", nil},
+		{"EOMMessageExceedsLimit", "<rpc/>" + EOM, false, []DecoderOption{WithMaximumMessageSize(5)}, "", ErrMessageSizeLimitExceeded},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append(tt.options, WithStreaming())
+			if tt.chunked {
+				options = append(options, WithFramer(decoderChunked))
+			}
+			d := NewDecoder(strings.NewReader(tt.input), options...)
+
+			output, err := io.ReadAll(d)
+			if string(output) != tt.output {
+				t.Errorf("Decoder %s: output mismatch wanted >%s< got >%s<", tt.name, tt.output, output)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Decoder %s: error mismatch wanted %v got %v", tt.name, tt.err, err)
+			}
+		})
+	}
+}
+
+func TestStreamingDecoderStats(t *testing.T) {
+	d := NewDecoder(strings.NewReader("<hello/>"+EOM+"\n#3\n<rp\n#3\nc/>\n##\n\n#X"), WithStreaming())
+
+	buffer := make([]byte, 100)
+	_, _ = d.Read(buffer)
+	SetChunkedFraming(d)
+	_, err := io.ReadAll(d)
+	if err == nil {
+		t.Errorf("Decoder expecting framing error")
+	}
+
+	want := Stats{Messages: 2, Chunks: 2, Bytes: 14, MaxChunkSize: 3, FramingErrors: 1}
+	if got := d.Stats(); got != want {
+		t.Errorf("Decoder stats mismatch wanted %+v got %+v", want, got)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	r := newRingBuffer(8)
+
+	if n, _ := r.fill(strings.NewReader("abcdef")); n != 6 {
+		t.Errorf("Ring buffer fill mismatch wanted 6 got %d", n)
+	}
+	b := make([]byte, 4)
+	if n := r.read(b); string(b[:n]) != "abcd" {
+		t.Errorf("Ring buffer read mismatch got >%s<", b[:n])
+	}
+
+	// Fill the space at the end, then wrap around to the start.
+	_, _ = r.fill(strings.NewReader("gh"))
+	_, _ = r.fill(strings.NewReader("ijklmn"))
+	if r.Len() != 8 {
+		t.Errorf("Ring buffer length mismatch wanted 8 got %d", r.Len())
+	}
+	if _, err := r.fill(strings.NewReader("o")); err == nil {
+		t.Errorf("Ring buffer expecting full error")
+	}
+	if r.match(2, []byte("ghiX")) != 3 {
+		t.Errorf("Ring buffer expecting match across wrap")
+	}
+
+	b = make([]byte, 10)
+	if n := r.read(b); string(b[:n]) != "efghijkl" {
+		t.Errorf("Ring buffer wrapped read mismatch got >%s<", b[:n])
+	}
+	if r.Len() != 0 || r.start != 0 {
+		t.Errorf("Ring buffer expecting reset when empty")
+	}
+}
@@ -17,15 +17,22 @@ package rfc6242
 // SetChunkedFraming enables chunked framing mode on any non-nil
 // *Decoder and *Encoder objects passed to it.
 func SetChunkedFraming(objects ...interface{}) {
+	SetFraming(ChunkedFraming, objects...)
+}
+
+// SetFraming selects the framing used by any non-nil *Decoder and
+// *Encoder objects passed to it. A Decoder that has not yet seen the
+// end of a message adopts the framing once the message ends.
+func SetFraming(f Framing, objects ...interface{}) {
 	for _, obj := range objects {
 		switch obj := obj.(type) {
 		case *Decoder:
 			if obj != nil {
-				obj.setFramer(decoderChunked)
+				obj.setFramer(f.Framer())
 			}
 		case *Encoder:
 			if obj != nil {
-				obj.ChunkedFraming = true
+				obj.Framing = f
 			}
 		}
 	}
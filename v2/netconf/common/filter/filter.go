@@ -0,0 +1,318 @@
+// Package filter implements NETCONF subtree filtering, as defined in RFC 6241 section 6.
+//
+// It can be used by servers to filter the data returned by get/get-config requests, or by clients to
+// post-filter datasets retrieved from devices that lack filtering support.
+package filter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Apply filters the XML document configXML using the subtree filter filterXML, delivering the selected subtrees.
+// filterXML defines the content of a subtree filter, i.e. the children of the <filter> element.
+// An empty filter selects no data.
+func Apply(configXML, filterXML string) (string, error) {
+	data, err := parse(configXML)
+	if err != nil {
+		return "", err
+	}
+	filter, err := parse(filterXML)
+	if err != nil {
+		return "", err
+	}
+	if len(filter) == 0 {
+		return "", nil
+	}
+
+	selected, ok := selectNodes(data, filter)
+	if !ok {
+		return "", nil
+	}
+
+	var buf bytes.Buffer
+	for _, n := range data {
+		if s, ok := selected[n]; ok {
+			n.write(&buf, s)
+		}
+	}
+	return buf.String(), nil
+}
+
+// node represents an element of a parsed document.
+type node struct {
+	// The element name and attributes, as they appeared in the document.
+	rawName  xml.Name
+	rawAttrs []xml.Attr
+	// The element name and attributes (excluding namespace declarations), with namespaces resolved.
+	name  xml.Name
+	attrs []xml.Attr
+	// Character data content of the element.
+	text     string
+	children []*node
+}
+
+// selection identifies the parts of a data node selected by a filter.
+type selection struct {
+	// Indicates that the node and all of its descendants are selected.
+	whole bool
+	// The selected children when the node is only partially selected.
+	children map[*node]*selection
+}
+
+// Merges another selection of the same node into s.
+func (s *selection) merge(o *selection) {
+	if o.whole {
+		s.whole = true
+	}
+	if s.whole {
+		s.children = nil
+		return
+	}
+	for n, os := range o.children {
+		if cs, ok := s.children[n]; ok {
+			cs.merge(os)
+		} else {
+			s.children[n] = os
+		}
+	}
+}
+
+// Applies the sibling filter nodes to the sibling data nodes, delivering the selected data nodes.
+// The boolean result is false if any of the content match nodes in the filter are not satisfied, in which case
+// none of the data nodes (nor their parent) are selected.
+func selectNodes(data, filter []*node) (map[*node]*selection, bool) {
+	var others []*node
+	for _, f := range filter {
+		if !f.isContentMatch() {
+			others = append(others, f)
+			continue
+		}
+		if !f.matchesAnyContent(data) {
+			return nil, false
+		}
+	}
+
+	selected := make(map[*node]*selection)
+	add := func(n *node, s *selection) {
+		if cs, ok := selected[n]; ok {
+			cs.merge(s)
+		} else {
+			selected[n] = s
+		}
+	}
+
+	// If the filter only contains content match nodes, all siblings are selected.
+	if len(others) == 0 {
+		for _, d := range data {
+			add(d, &selection{whole: true})
+		}
+		return selected, true
+	}
+
+	for _, f := range filter {
+		for _, d := range data {
+			if !f.matches(d) {
+				continue
+			}
+			switch {
+			case f.isContentMatch():
+				if f.matchesContent(d) {
+					add(d, &selection{whole: true})
+				}
+			case len(f.children) == 0:
+				// Selection node.
+				add(d, &selection{whole: true})
+			default:
+				// Containment node.
+				children, ok := selectNodes(d.children, f.children)
+				if ok && len(children) > 0 {
+					add(d, &selection{children: children})
+				}
+			}
+		}
+	}
+	return selected, true
+}
+
+// Determines whether the filter node is a content match node, i.e. a leaf with character data.
+func (f *node) isContentMatch() bool {
+	return len(f.children) == 0 && f.text != ""
+}
+
+// Determines whether any of the data nodes satisfy the content match node.
+func (f *node) matchesAnyContent(data []*node) bool {
+	for _, d := range data {
+		if f.matches(d) && f.matchesContent(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Determines whether the data node content equals that of the content match node.
+// Whitespace surrounding the character data is not significant.
+func (f *node) matchesContent(d *node) bool {
+	return len(d.children) == 0 && strings.TrimSpace(f.text) == strings.TrimSpace(d.text)
+}
+
+// Determines whether the data node matches the name, namespace and attributes of the filter node.
+// A filter node with no namespace matches any namespace.
+func (f *node) matches(d *node) bool {
+	if f.name.Local != d.name.Local {
+		return false
+	}
+	if f.name.Space != "" && f.name.Space != d.name.Space {
+		return false
+	}
+	for _, fa := range f.attrs {
+		if !hasAttr(d.attrs, fa) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAttr(attrs []xml.Attr, a xml.Attr) bool {
+	for _, da := range attrs {
+		if da.Name.Local == a.Name.Local && (a.Name.Space == "" || a.Name.Space == da.Name.Space) && da.Value == a.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes the selected part of the node to buf.
+func (n *node) write(buf *bytes.Buffer, s *selection) {
+	buf.WriteString("<")
+	buf.WriteString(qualifiedName(n.rawName))
+	for _, a := range n.rawAttrs {
+		buf.WriteString(" ")
+		buf.WriteString(qualifiedName(a.Name))
+		buf.WriteString(`="`)
+		_ = xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString(`"`)
+	}
+
+	if len(n.children) == 0 && n.text == "" {
+		buf.WriteString("/>")
+		return
+	}
+	buf.WriteString(">")
+	if len(n.children) == 0 {
+		_ = xml.EscapeText(buf, []byte(n.text))
+	}
+	for _, c := range n.children {
+		if s.whole {
+			c.write(buf, s)
+		} else if cs, ok := s.children[c]; ok {
+			c.write(buf, cs)
+		}
+	}
+	buf.WriteString("</")
+	buf.WriteString(qualifiedName(n.rawName))
+	buf.WriteString(">")
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Parses the XML document, which may contain multiple top level elements, delivering the top level nodes.
+// Namespaces are resolved explicitly, so that the original prefixes can be preserved in the filter output.
+func parse(doc string) ([]*node, error) {
+	root := &node{}
+	stack := []*node{root}
+	scopes := []map[string]string{{"xml": xmlNamespace}}
+
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		token, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			scope := newScope(scopes[len(scopes)-1], token.Attr)
+			scopes = append(scopes, scope)
+
+			n := &node{rawName: token.Name, rawAttrs: token.Copy().Attr}
+			n.name = xml.Name{Space: scope[token.Name.Space], Local: token.Name.Local}
+			for _, a := range token.Attr {
+				if isNamespaceDecl(a) {
+					continue
+				}
+				name := xml.Name{Local: a.Name.Local}
+				if a.Name.Space != "" {
+					name.Space = scope[a.Name.Space]
+				}
+				n.attrs = append(n.attrs, xml.Attr{Name: name, Value: a.Value})
+			}
+
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			if len(stack) == 1 || n.rawName != token.Name {
+				return nil, &xml.SyntaxError{Msg: "unexpected end element </" + qualifiedName(token.Name) + ">"}
+			}
+			// Character data is only significant for leaf elements.
+			if len(n.children) > 0 || strings.TrimSpace(n.text) == "" {
+				n.text = ""
+			}
+			stack = stack[:len(stack)-1]
+			scopes = scopes[:len(scopes)-1]
+
+		case xml.CharData:
+			stack[len(stack)-1].text += string(token)
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, &xml.SyntaxError{Msg: "unexpected EOF"}
+	}
+	return root.children, nil
+}
+
+// Delivers the namespace scope for an element, given the scope of its parent and the element attributes.
+// The default namespace is held against the empty prefix.
+func newScope(parent map[string]string, attrs []xml.Attr) map[string]string {
+	var scope map[string]string
+	for _, a := range attrs {
+		if !isNamespaceDecl(a) {
+			continue
+		}
+		if scope == nil {
+			scope = make(map[string]string, len(parent)+1)
+			for k, v := range parent {
+				scope[k] = v
+			}
+		}
+		if a.Name.Space == "xmlns" {
+			scope[a.Name.Local] = a.Value
+		} else {
+			scope[""] = a.Value
+		}
+	}
+	if scope == nil {
+		return parent
+	}
+	return scope
+}
+
+func isNamespaceDecl(a xml.Attr) bool {
+	return a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns")
+}
@@ -0,0 +1,165 @@
+package filter
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const users = `<top xmlns="http://example.com/schema/1.2/config">
+  <users>
+    <user>
+      <name>root</name>
+      <type>superuser</type>
+      <full-name>Charlie Root</full-name>
+      <company-info>
+        <dept>1</dept>
+        <id>1</id>
+      </company-info>
+    </user>
+    <user>
+      <name>fred</name>
+      <type>admin</type>
+      <full-name>Fred Flintstone</full-name>
+      <company-info>
+        <dept>2</dept>
+        <id>2</id>
+      </company-info>
+    </user>
+    <user>
+      <name>barney</name>
+      <type>admin</type>
+      <full-name>Barney Rubble</full-name>
+      <company-info>
+        <dept>2</dept>
+        <id>3</id>
+      </company-info>
+    </user>
+  </users>
+</top>`
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{
+			name:   "empty filter",
+			filter: ``,
+			want:   ``,
+		},
+		{
+			name:   "no match",
+			filter: `<top xmlns="http://example.com/schema/1.2/config"><groups/></top>`,
+			want:   ``,
+		},
+		{
+			name:   "namespace mismatch",
+			filter: `<top xmlns="http://example.com/schema/1.2/stats"/>`,
+			want:   ``,
+		},
+		{
+			name:   "selection node",
+			filter: `<top xmlns="http://example.com/schema/1.2/config"><users/></top>`,
+			want:   compact(users),
+		},
+		{
+			name:   "no namespace matches any namespace",
+			filter: `<top><users><user><name/></user></users></top>`,
+			want: `<top xmlns="http://example.com/schema/1.2/config"><users>` +
+				`<user><name>root</name></user>` +
+				`<user><name>fred</name></user>` +
+				`<user><name>barney</name></user>` +
+				`</users></top>`,
+		},
+		{
+			name:   "content match selects entire entry",
+			filter: `<top><users><user><name>fred</name></user></users></top>`,
+			want: `<top xmlns="http://example.com/schema/1.2/config"><users><user>` +
+				`<name>fred</name><type>admin</type><full-name>Fred Flintstone</full-name>` +
+				`<company-info><dept>2</dept><id>2</id></company-info>` +
+				`</user></users></top>`,
+		},
+		{
+			name:   "content match with selection nodes",
+			filter: `<top><users><user><name>fred</name><type/><full-name/></user></users></top>`,
+			want: `<top xmlns="http://example.com/schema/1.2/config"><users><user>` +
+				`<name>fred</name><type>admin</type><full-name>Fred Flintstone</full-name>` +
+				`</user></users></top>`,
+		},
+		{
+			name: "multiple subtrees",
+			filter: `<top><users>` +
+				`<user><name>root</name><company-info/></user>` +
+				`<user><name>fred</name><company-info><id/></company-info></user>` +
+				`<user><name>wilma</name><company-info/></user>` +
+				`</users></top>`,
+			want: `<top xmlns="http://example.com/schema/1.2/config"><users>` +
+				`<user><name>root</name><company-info><dept>1</dept><id>1</id></company-info></user>` +
+				`<user><name>fred</name><company-info><id>2</id></company-info></user>` +
+				`</users></top>`,
+		},
+		{
+			// Content match nodes only constrain their own sibling set.
+			name:   "nested content match",
+			filter: `<top><users><user><company-info><dept>2</dept></company-info><name/></user></users></top>`,
+			want: `<top xmlns="http://example.com/schema/1.2/config"><users>` +
+				`<user><name>root</name></user>` +
+				`<user><name>fred</name><company-info><dept>2</dept><id>2</id></company-info></user>` +
+				`<user><name>barney</name><company-info><dept>2</dept><id>3</id></company-info></user>` +
+				`</users></top>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(users, tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApplyAttributeMatch(t *testing.T) {
+	data := `<t:top xmlns:t="http://example.com/schema/1.2/config">` +
+		`<t:interfaces>` +
+		`<t:interface t:ifName="eth0"><t:ifInErrors>45621</t:ifInErrors></t:interface>` +
+		`<t:interface t:ifName="eth1"><t:ifInErrors>4</t:ifInErrors></t:interface>` +
+		`</t:interfaces>` +
+		`</t:top>`
+	filter := `<t:top xmlns:t="http://example.com/schema/1.2/config">` +
+		`<t:interfaces><t:interface t:ifName="eth0"/></t:interfaces>` +
+		`</t:top>`
+
+	got, err := Apply(data, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, `<t:top xmlns:t="http://example.com/schema/1.2/config">`+
+		`<t:interfaces><t:interface t:ifName="eth0"><t:ifInErrors>45621</t:ifInErrors></t:interface></t:interfaces>`+
+		`</t:top>`, got)
+}
+
+func TestApplyMultipleTopLevelElements(t *testing.T) {
+	data := `<top><a>1</a></top><other><b>2</b></other>`
+
+	got, err := Apply(data, `<other/>`)
+	assert.NoError(t, err)
+	assert.Equal(t, `<other><b>2</b></other>`, got)
+
+	got, err = Apply(data, `<top/><other/>`)
+	assert.NoError(t, err)
+	assert.Equal(t, data, got)
+}
+
+func TestApplyInvalidXML(t *testing.T) {
+	_, err := Apply(`<top><a></top>`, `<top/>`)
+	assert.Error(t, err)
+
+	_, err = Apply(`<top/>`, `<top>`)
+	assert.Error(t, err)
+}
+
+// Removes the indentation from the test data.
+func compact(s string) string {
+	got, _ := Apply(s, `<top/>`)
+	return got
+}
@@ -3,6 +3,8 @@ package common
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
+	"strings"
 )
 
 // Defines structs representing netconf messages and notifications.
@@ -26,12 +28,88 @@ type RPCMessage struct {
 
 // RPCReply defines the an rpc request message
 type RPCReply struct {
-	XMLName   xml.Name   `xml:"rpc-reply"`
-	Errors    []RPCError `xml:"rpc-error,omitempty"`
-	Data      string     `xml:",innerxml"`
-	Ok        bool       `xml:",omitempty"`
-	RawReply  string     `xml:"-"`
-	MessageID string     `xml:"message-id,attr"`
+	XMLName xml.Name   `xml:"rpc-reply"`
+	Errors  []RPCError `xml:"rpc-error,omitempty"`
+	// The content of the rpc-reply element.
+	Data string `xml:",innerxml"`
+	// Indicates that the reply holds an <ok/> element, acknowledging successful execution of the request.
+	Ok       bool   `xml:",omitempty"`
+	RawReply string `xml:"-"`
+	// The complete rpc-reply element, reconstructed from its start tag and verbatim content.
+	RawXML    string `xml:"-"`
+	MessageID string `xml:"message-id,attr"`
+	// The sequence number assigned to the reply on receipt; see Notification.Sequence.
+	Sequence uint64 `xml:"-"`
+	// Indicates that the reply holds a <data> element.
+	dataSeen bool
+}
+
+// HasData determines whether the reply holds a <data> element, which distinguishes a reply delivering empty data
+// from an <ok/> reply.
+func (r *RPCReply) HasData() bool {
+	return r.dataSeen
+}
+
+// UnmarshalXML decodes an rpc-reply element, recording the presence of the <ok/> and <data> elements.
+func (r *RPCReply) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var reply struct {
+		Errors    []RPCError `xml:"rpc-error"`
+		Content   string     `xml:",innerxml"`
+		MessageID string     `xml:"message-id,attr"`
+		Ok        *struct{}  `xml:"ok"`
+		Data      *struct{}  `xml:"data"`
+	}
+	if err := d.DecodeElement(&reply, &start); err != nil {
+		return err
+	}
+
+	r.XMLName = start.Name
+	r.Errors = reply.Errors
+	r.Data = reply.Content
+	r.MessageID = reply.MessageID
+	r.Ok = reply.Ok != nil
+	r.dataSeen = reply.Data != nil
+	r.RawXML = rawElement(start, reply.Content)
+	return nil
+}
+
+// Reconstructs the xml of an element from its start element and content. Namespaces are expressed using the
+// prefixes declared by the element where possible, otherwise as a default namespace declaration.
+func rawElement(start xml.StartElement, content string) string {
+	prefixes := map[string]string{}
+	hasDefault := false
+	for _, a := range start.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			prefixes[a.Value] = a.Name.Local
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			hasDefault = true
+		}
+	}
+	qualify := func(n xml.Name) string {
+		if prefix, ok := prefixes[n.Space]; ok && n.Space != "" {
+			return prefix + ":" + n.Local
+		}
+		return n.Local
+	}
+
+	sb := &strings.Builder{}
+	name := qualify(start.Name)
+	sb.WriteString("<" + name)
+	if _, ok := prefixes[start.Name.Space]; !ok && start.Name.Space != "" && !hasDefault {
+		fmt.Fprintf(sb, " xmlns=%q", start.Name.Space)
+	}
+	for _, a := range start.Attr {
+		attr := qualify(a.Name)
+		if a.Name.Space == "xmlns" {
+			attr = "xmlns:" + a.Name.Local
+		}
+		sb.WriteString(" " + attr + `="`)
+		_ = xml.EscapeText(sb, []byte(a.Value))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(">" + content + "</" + name + ">")
+	return sb.String()
 }
 
 // RPCError defines an error reply to a RPC request
@@ -54,6 +132,12 @@ type Notification struct {
 	XMLName   xml.Name
 	EventTime string
 	Event     string `xml:",innerxml"`
+	// The event unmarshalled into the value created by the factory registered for the event name with
+	// client.RegisterEvent; nil if the event is not registered, or cannot be unmarshalled.
+	Value interface{} `xml:"-"`
+	// The sequence number assigned to the notification on receipt. The messages received on a session are numbered
+	// consecutively from 1, in order of arrival, so that notifications and replies can be ordered.
+	Sequence uint64 `xml:"-"`
 }
 
 // NotificationMessage defines the notification message sent from the server.
@@ -63,20 +147,80 @@ type NotificationMessage struct {
 	Event     Notification `xml:",any"`
 }
 
+// Union defines the body of a request, which may be supplied in a number of forms.
 type Union struct {
-	ValueStr interface{}
-	ValueXML string `xml:",innerxml"`
+	ValueStr    interface{}
+	ValueXML    string       `xml:",innerxml"`
+	ValueBytes  []byte       `xml:",innerxml"`
+	ValueReader *readerValue `xml:",omitempty"`
 }
 
+// GetUnion delivers a Union for the request body s, which can be one of:
+// - an xml string or []byte, used verbatim;
+// - an io.Reader delivering xml, which will be streamed when the request is encoded;
+// - a struct with xml tags.
 func GetUnion(s interface{}) *Union {
 	switch request := s.(type) {
 	case string:
 		return &Union{ValueXML: request}
+	case []byte:
+		return &Union{ValueBytes: request}
+	case io.Reader:
+		return &Union{ValueReader: &readerValue{r: request}}
 	default:
 		return &Union{ValueStr: request}
 	}
 }
 
+// readerValue streams xml from a reader into an encoder.
+type readerValue struct {
+	r io.Reader
+}
+
+// MarshalXML re-encodes the xml tokens read from the reader, without wrapping them in an element.
+// Element and attribute names are encoded as they appear in the input, so namespace prefixes are preserved.
+func (rv *readerValue) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	d := xml.NewDecoder(rv.r)
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			t.Name = rawName(t.Name)
+			for i := range t.Attr {
+				t.Attr[i].Name = rawName(t.Attr[i].Name)
+			}
+			token = t
+		case xml.EndElement:
+			t.Name = rawName(t.Name)
+			token = t
+		case xml.ProcInst:
+			// An xml declaration in the input is not permitted within the request.
+			if t.Target == "xml" {
+				continue
+			}
+		}
+
+		if err = e.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+}
+
+// Delivers a name with the prefix folded into the local part, so that the encoder writes it verbatim.
+func rawName(n xml.Name) xml.Name {
+	if n.Space == "" {
+		return n
+	}
+	return xml.Name{Local: n.Space + ":" + n.Local}
+}
+
 // DefaultCapabilities sets the default capabilities of the client library
 var DefaultCapabilities = []string{
 	CapBase10,
@@ -1,6 +1,8 @@
 package common
 
 import (
+	"encoding/xml"
+	"strings"
 	"testing"
 
 	assert "github.com/stretchr/testify/require"
@@ -19,3 +21,91 @@ func TestPeerSupportsChunkedFraming(t *testing.T) {
 	assert.False(t, PeerSupportsChunkedFraming([]string{NetconfNS, NetconfNotifyNS, CapBase10}))
 	assert.True(t, PeerSupportsChunkedFraming([]string{NetconfNS, NetconfNotifyNS, CapBase11}))
 }
+
+func TestGetUnion(t *testing.T) {
+	tests := []struct {
+		name string
+		body interface{}
+		want string
+	}{
+		{"string", `<get/>`, `<get/>`},
+		{"bytes", []byte(`<get-config/>`), `<get-config/>`},
+		{"struct", &struct {
+			XMLName xml.Name `xml:"lock"`
+		}{}, `<lock></lock>`},
+		{
+			"reader",
+			strings.NewReader(`<?xml version="1.0"?><p:cfg xmlns:p="urn:x" p:a="1&amp;2"><item>a&lt;b</item><!-- note --></p:cfg>`),
+			`<p:cfg xmlns:p="urn:x" p:a="1&amp;2"><item>a&lt;b</item><!-- note --></p:cfg>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := xml.Marshal(&RPCMessage{MessageID: "1", Union: GetUnion(tt.body)})
+			assert.NoError(t, err)
+			assert.Equal(t, `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1">`+tt.want+`</rpc>`, string(b))
+		})
+	}
+}
+
+func TestGetUnionReaderInvalidXML(t *testing.T) {
+	_, err := xml.Marshal(&RPCMessage{MessageID: "1", Union: GetUnion(strings.NewReader(`<cfg><item></cfg>`))})
+	assert.Error(t, err)
+}
+
+func TestRPCReplyUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		ok      bool
+		hasData bool
+		data    string
+		raw     string
+	}{
+		{
+			"Ok", `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><ok/></rpc-reply>`,
+			true, false, `<ok/>`,
+			`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><ok/></rpc-reply>`,
+		},
+		{
+			"EmptyData", `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="2"><data/></rpc-reply>`,
+			false, true, `<data/>`,
+			`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="2"><data/></rpc-reply>`,
+		},
+		{
+			"Empty", `<rpc-reply message-id="3"></rpc-reply>`,
+			false, false, ``,
+			`<rpc-reply message-id="3"></rpc-reply>`,
+		},
+		{
+			"Prefixed", `<nc:rpc-reply xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0" nc:message-id="4"><nc:ok/></nc:rpc-reply>`,
+			true, false, `<nc:ok/>`,
+			`<nc:rpc-reply xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0" nc:message-id="4"><nc:ok/></nc:rpc-reply>`,
+		},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := &RPCReply{}
+			assert.NoError(t, xml.Unmarshal([]byte(tt.input), reply))
+			assert.Equal(t, tt.ok, reply.Ok, "Ok mismatch")
+			assert.Equal(t, tt.hasData, reply.HasData(), "HasData mismatch")
+			assert.Equal(t, tt.data, reply.Data, "Data mismatch")
+			assert.Equal(t, tt.raw, reply.RawXML, "RawXML mismatch")
+		})
+	}
+}
+
+func TestRPCReplyUnmarshalErrors(t *testing.T) {
+	input := `<rpc-reply message-id="5"><rpc-error><error-severity>error</error-severity>` +
+		`<error-message>oops</error-message></rpc-error></rpc-reply>`
+
+	reply := &RPCReply{}
+	assert.NoError(t, xml.Unmarshal([]byte(input), reply))
+	assert.Equal(t, "5", reply.MessageID)
+	assert.False(t, reply.Ok)
+	assert.False(t, reply.HasData())
+	assert.Len(t, reply.Errors, 1)
+	assert.Equal(t, "oops", reply.Errors[0].Message)
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A session log records the messages exchanged by a netconf session in a length-prefixed format, so that an
+// exchange captured from a production device can be replayed by a test server.
+//
+// Each message is written as a header line, holding a direction marker and the length of the message in bytes,
+// followed by the message and a newline:
+//
+//	> 123
+//	<rpc message-id="...">...</rpc>
+//
+// The marker is '>' for messages sent by the client and '<' for messages received by the client.
+
+// SessionLogEntry defines a message recorded in a session log.
+type SessionLogEntry struct {
+	// Indicates that the message was sent by the client.
+	Sent bool
+	// The xml of the message.
+	Message string
+}
+
+// SessionLogWriter writes messages to a session log; it is safe for concurrent use.
+type SessionLogWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewSessionLogWriter delivers a SessionLogWriter that writes to w.
+func NewSessionLogWriter(w io.Writer) *SessionLogWriter {
+	return &SessionLogWriter{w: w}
+}
+
+// Record writes a message to the log. Once a write has failed, subsequent messages are discarded and the error is
+// reported by Err.
+func (l *SessionLogWriter) Record(sent bool, message []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return
+	}
+	if _, l.err = fmt.Fprintf(l.w, "%c %d\n", direction(sent), len(message)); l.err != nil {
+		return
+	}
+	if _, l.err = l.w.Write(message); l.err != nil {
+		return
+	}
+	_, l.err = io.WriteString(l.w, "\n")
+}
+
+// Err delivers the error that caused messages to be discarded, if any.
+func (l *SessionLogWriter) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// ReadSessionLog reads the entries of a session log.
+func ReadSessionLog(r io.Reader) ([]SessionLogEntry, error) {
+	br := bufio.NewReader(r)
+	var entries []SessionLogEntry
+	for {
+		var dir byte
+		var length int
+		n, err := fmt.Fscanf(br, "%c %d\n", &dir, &length)
+		if err == io.EOF && n == 0 {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("session log entry %d: invalid header: %w", len(entries)+1, err)
+		}
+		if dir != direction(true) && dir != direction(false) {
+			return nil, fmt.Errorf("session log entry %d: invalid direction %q", len(entries)+1, dir)
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("session log entry %d: invalid length %d", len(entries)+1, length)
+		}
+		buf := make([]byte, length+1)
+		if _, err = io.ReadFull(br, buf); err != nil {
+			return nil, fmt.Errorf("session log entry %d: truncated message: %w", len(entries)+1, err)
+		}
+		if buf[length] != '\n' {
+			return nil, fmt.Errorf("session log entry %d: message not terminated", len(entries)+1)
+		}
+		entries = append(entries, SessionLogEntry{Sent: dir == direction(true), Message: string(buf[:length])})
+	}
+}
+
+func direction(sent bool) byte {
+	if sent {
+		return '>'
+	}
+	return '<'
+}
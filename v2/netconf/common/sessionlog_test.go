@@ -0,0 +1,55 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSessionLogRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSessionLogWriter(&buf)
+	l.Record(true, []byte(`<rpc message-id="1"><get/></rpc>`))
+	l.Record(false, []byte("<rpc-reply message-id=\"1\"><data>line1\nline2\n</data></rpc-reply>"))
+	l.Record(false, []byte{})
+	assert.NoError(t, l.Err())
+	assert.True(t, strings.HasPrefix(buf.String(), "> 32\n<rpc message-id=\"1\"><get/></rpc>\n< "))
+
+	entries, err := ReadSessionLog(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []SessionLogEntry{
+		{Sent: true, Message: `<rpc message-id="1"><get/></rpc>`},
+		{Message: "<rpc-reply message-id=\"1\"><data>line1\nline2\n</data></rpc-reply>"},
+		{Message: ""},
+	}, entries)
+}
+
+func TestReadInvalidSessionLog(t *testing.T) {
+	for _, tc := range []struct {
+		name, log, err string
+	}{
+		{"header", "> x\n<get/>\n", "session log entry 1: invalid header"},
+		{"direction", "? 6\n<get/>\n", "session log entry 1: invalid direction '?'"},
+		{"truncated", "> 6\n<get/>\n< 10\n<ok/>\n", "session log entry 2: truncated message"},
+		{"terminator", "> 5\n<get/>\n", "session log entry 1: message not terminated"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadSessionLog(strings.NewReader(tc.log))
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestSessionLogWriteError(t *testing.T) {
+	l := NewSessionLogWriter(failingWriter{})
+	l.Record(true, []byte(`<get/>`))
+	l.Record(true, []byte(`<get/>`))
+	assert.EqualError(t, l.Err(), "disk full")
+}
@@ -3,6 +3,9 @@
 package mocks
 
 import (
+	context "context"
+
+	client "github.com/damianoneill/net/v2/netconf/client"
 	common "github.com/damianoneill/net/v2/netconf/common"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -54,6 +57,29 @@ func (_m *OpSession) ExecuteAsync(req common.Request, rchan chan *common.RPCRepl
 	return r0
 }
 
+// ExecuteContext provides a mock function with given fields: ctx, req
+func (_m *OpSession) ExecuteContext(ctx context.Context, req common.Request) (*common.RPCReply, error) {
+	ret := _m.Called(ctx, req)
+
+	var r0 *common.RPCReply
+	if rf, ok := ret.Get(0).(func(context.Context, common.Request) *common.RPCReply); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*common.RPCReply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, common.Request) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ID provides a mock function with given fields:
 func (_m *OpSession) ID() uint64 {
 	ret := _m.Called()
@@ -84,6 +110,20 @@ func (_m *OpSession) ServerCapabilities() []string {
 	return r0
 }
 
+// Stats provides a mock function with given fields:
+func (_m *OpSession) Stats() client.SessionStats {
+	ret := _m.Called()
+
+	var r0 client.SessionStats
+	if rf, ok := ret.Get(0).(func() client.SessionStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(client.SessionStats)
+	}
+
+	return r0
+}
+
 // Subscribe provides a mock function with given fields: req, nchan
 func (_m *OpSession) Subscribe(req common.Request, nchan chan *common.Notification) (*common.RPCReply, error) {
 	ret := _m.Called(req, nchan)
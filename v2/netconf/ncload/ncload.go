@@ -0,0 +1,325 @@
+// Package ncload generates load against a NETCONF endpoint, issuing a configurable mix of RPCs from a number of
+// concurrent sessions and reporting latency percentiles and classified errors for each operation, for device
+// benchmarking and client soak tests.
+package ncload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// SessionFactory establishes a session with the endpoint under test.
+type SessionFactory func(ctx context.Context) (client.Session, error)
+
+// SSHSessionFactory delivers a factory that establishes sessions with target using the ssh and client configuration.
+func SSHSessionFactory(sshcfg *ssh.ClientConfig, target string, cfg *client.Config) SessionFactory {
+	return func(ctx context.Context) (client.Session, error) {
+		return client.NewRPCSessionWithConfig(ctx, sshcfg, target, cfg)
+	}
+}
+
+// Operation defines an RPC issued as part of the mix.
+type Operation struct {
+	// Identifies the operation in the report.
+	Name string
+	// The relative frequency with which the operation is issued.
+	Weight int
+	// Builds the request issued for the seq'th request of the run.
+	Request func(seq uint64) common.Request
+}
+
+// Get delivers an operation issuing a get request with the subtree filter, which may be empty.
+func Get(filter string) Operation {
+	req := "<get>" + subtreeFilter(filter) + "</get>"
+	return Operation{Name: "get", Weight: 1, Request: func(uint64) common.Request { return req }}
+}
+
+// GetConfig delivers an operation issuing a get-config request of the source datastore with the subtree filter,
+// which may be empty.
+func GetConfig(source, filter string) Operation {
+	req := "<get-config><source><" + source + "/></source>" + subtreeFilter(filter) + "</get-config>"
+	return Operation{Name: "get-config", Weight: 1, Request: func(uint64) common.Request { return req }}
+}
+
+// PayloadFunc delivers the content of the config element of the seq'th edit-config request, of approximately size
+// bytes.
+type PayloadFunc func(seq uint64, size int) string
+
+// EditConfig delivers an operation issuing an edit-config request of the target datastore, whose configuration is
+// delivered by payload. If payload is nil, DefaultPayload is used.
+func EditConfig(target string, size int, payload PayloadFunc) Operation {
+	if payload == nil {
+		payload = DefaultPayload
+	}
+	return Operation{Name: "edit-config", Weight: 1, Request: func(seq uint64) common.Request {
+		return "<edit-config><target><" + target + "/></target><config>" + payload(seq, size) + "</config></edit-config>"
+	}}
+}
+
+// DefaultPayload delivers configuration in the urn:ncload namespace, padded to size bytes. Devices will typically
+// reject it, so it is suited to test servers, or to measuring the cost of rejecting edits.
+func DefaultPayload(seq uint64, size int) string {
+	s := fmt.Sprintf(`<load xmlns="urn:ncload"><seq>%d</seq><pad></pad></load>`, seq)
+	if pad := size - len(s); pad > 0 {
+		s = strings.Replace(s, "<pad>", "<pad>"+strings.Repeat("x", pad), 1)
+	}
+	return s
+}
+
+// Weighted delivers the operation with the weight.
+func (o Operation) Weighted(weight int) Operation {
+	o.Weight = weight
+	return o
+}
+
+func subtreeFilter(filter string) string {
+	if filter == "" {
+		return ""
+	}
+	return `<filter type="subtree">` + filter + `</filter>`
+}
+
+// Config defines a load run.
+type Config struct {
+	// The number of sessions established with the endpoint. Default is 1.
+	Sessions int
+	// The number of requests issued concurrently on each session. Default is 1.
+	Concurrency int
+	// The duration of the run, once the sessions have been established. Zero means the run ends once Requests have
+	// been issued.
+	Duration time.Duration
+	// The number of requests issued by the run, across all sessions. Zero means the run ends after Duration.
+	Requests uint64
+	// The operations issued, selected at random in proportion to their weights.
+	Mix []Operation
+	// The fraction of requests issued with ExecuteAsync, rather than Execute.
+	AsyncRatio float64
+	// The notification stream subscribed to by each session before issuing requests; empty means no subscription.
+	Stream string
+	// Seeds the selection of operations, so that runs are repeatable.
+	Seed int64
+}
+
+// Run issues requests against the endpoint of the sessions delivered by factory, as defined by cfg, until the run
+// is complete or ctx is done, and reports the outcome. An error is returned if the configuration is invalid or no
+// session can be established; failures to establish some of the sessions are reported by Report.SessionErrors.
+func Run(ctx context.Context, factory SessionFactory, cfg *Config) (*Report, error) {
+	if len(cfg.Mix) == 0 {
+		return nil, errors.New("no operations defined")
+	}
+	if cfg.Duration <= 0 && cfg.Requests == 0 {
+		return nil, errors.New("either a duration or number of requests must be defined")
+	}
+	total := 0
+	for _, op := range cfg.Mix {
+		if op.Weight < 0 || op.Request == nil {
+			return nil, errors.Errorf("invalid operation %s", op.Name)
+		}
+		total += op.Weight
+	}
+	if total == 0 {
+		return nil, errors.New("operations have no weight")
+	}
+
+	r := &runner{cfg: cfg, totalWeight: total, report: newReport()}
+	return r.run(ctx, factory)
+}
+
+type runner struct {
+	cfg         *Config
+	totalWeight int
+	// The number of requests issued, which also numbers them.
+	issued uint64
+	// Set once the run has ended, after which outcomes are not recorded.
+	stopped int32
+
+	mu     sync.Mutex
+	report *Report
+}
+
+func (r *runner) run(ctx context.Context, factory SessionFactory) (*Report, error) {
+	sessions := r.cfg.Sessions
+	if sessions <= 0 {
+		sessions = 1
+	}
+	concurrency := r.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// Establish the sessions before issuing requests, so that set up is not measured under load.
+	var established, active []client.Session
+	var workers, listeners sync.WaitGroup
+	for i := 0; i < sessions; i++ {
+		s, err := factory(ctx)
+		if err != nil {
+			r.mu.Lock()
+			r.report.SessionErrors[ClassifyError(err)]++
+			r.mu.Unlock()
+			continue
+		}
+		established = append(established, s)
+		if r.cfg.Stream == "" || r.subscribe(s, &listeners) {
+			active = append(active, s)
+		}
+	}
+	if len(established) == 0 {
+		return nil, errors.New("failed to establish any session")
+	}
+
+	begin := time.Now()
+	if r.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Duration)
+		defer cancel()
+	}
+
+	for i, s := range active {
+		for j := 0; j < concurrency; j++ {
+			s, rnd := s, rand.New(rand.NewSource(r.cfg.Seed+int64(i*concurrency+j))) //nolint:gosec
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				r.work(ctx, s, rnd)
+			}()
+		}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-ctx.Done():
+	case <-finished:
+	}
+
+	// Closing the sessions releases any requests awaiting replies once the run has ended.
+	atomic.StoreInt32(&r.stopped, 1)
+	elapsed := time.Since(begin)
+	for _, s := range established {
+		s.Close()
+	}
+	workers.Wait()
+	listeners.Wait()
+
+	r.report.Elapsed = elapsed
+	for _, o := range r.report.Operations {
+		o.summarise()
+	}
+	return r.report, nil
+}
+
+// Subscribes to the notification stream on the session, counting the notifications received, and delivering true if
+// the subscription succeeds.
+func (r *runner) subscribe(s client.Session, listeners *sync.WaitGroup) bool {
+	nchan := make(chan *common.Notification, 100)
+	req := `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"><stream>` + r.cfg.Stream +
+		`</stream></create-subscription>`
+	begin := time.Now()
+	reply, err := s.Subscribe(req, nchan)
+	if err == nil {
+		err = replyError(reply)
+	}
+	r.record(subscriptionOperation, time.Since(begin), err)
+	if err != nil {
+		return false
+	}
+
+	listeners.Add(1)
+	go func() {
+		defer listeners.Done()
+		for range nchan {
+			r.mu.Lock()
+			r.report.Notifications++
+			r.mu.Unlock()
+		}
+	}()
+	return true
+}
+
+// Issues requests on the session until the run ends.
+func (r *runner) work(ctx context.Context, s client.Session, rnd *rand.Rand) {
+	for ctx.Err() == nil {
+		seq := atomic.AddUint64(&r.issued, 1)
+		if r.cfg.Requests > 0 && seq > r.cfg.Requests {
+			return
+		}
+		op := r.choose(rnd)
+		req := op.Request(seq)
+
+		begin := time.Now()
+		var err error
+		if rnd.Float64() < r.cfg.AsyncRatio {
+			err = executeAsync(ctx, s, req)
+		} else {
+			_, err = s.Execute(req)
+		}
+		// A request interrupted by the end of the run is not recorded.
+		if ctx.Err() != nil {
+			return
+		}
+		r.record(op.Name, time.Since(begin), err)
+	}
+}
+
+// Selects an operation from the mix in proportion to the weights.
+func (r *runner) choose(rnd *rand.Rand) Operation {
+	n := rnd.Intn(r.totalWeight)
+	for _, op := range r.cfg.Mix {
+		if n < op.Weight {
+			return op
+		}
+		n -= op.Weight
+	}
+	return r.cfg.Mix[len(r.cfg.Mix)-1]
+}
+
+// Records the outcome of a request, unless the run has ended.
+func (r *runner) record(name string, latency time.Duration, err error) {
+	if atomic.LoadInt32(&r.stopped) != 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.operation(name).record(latency, err)
+}
+
+func executeAsync(ctx context.Context, s client.Session, req common.Request) error {
+	rchan := make(chan *common.RPCReply, 1)
+	if err := s.ExecuteAsync(req, rchan); err != nil {
+		return err
+	}
+	select {
+	case reply := <-rchan:
+		return replyError(reply)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Maps a reply to an error, as Execute does, if the reply is nil or reports an error.
+func replyError(reply *common.RPCReply) error {
+	if reply == nil {
+		return io.ErrUnexpectedEOF
+	}
+	for i := range reply.Errors {
+		if reply.Errors[i].Severity == "error" {
+			return &reply.Errors[i]
+		}
+	}
+	return nil
+}
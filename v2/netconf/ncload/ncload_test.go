@@ -0,0 +1,172 @@
+package ncload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Delivers a factory establishing sessions on a single ssh connection, as the test server serves one connection at
+// a time.
+func testFactory(t *testing.T, ts *testserver.TestNCServer) SessionFactory {
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	sshClient, err := ssh.Dial("tcp", fmt.Sprintf("localhost:%d", ts.Port()), sshConfig)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = sshClient.Close() })
+	return func(ctx context.Context) (client.Session, error) {
+		return client.NewRPCSessionFromSSHClient(ctx, sshClient)
+	}
+}
+
+func TestRunRequests(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	report, err := Run(context.Background(), testFactory(t, ts), &Config{
+		Sessions:    2,
+		Concurrency: 3,
+		Requests:    60,
+		Mix:         []Operation{Get(`<top/>`).Weighted(2), GetConfig("running", ""), EditConfig("candidate", 512, nil)},
+		AsyncRatio:  0.5,
+		Stream:      "NETCONF",
+	})
+	assert.NoError(t, err)
+
+	var count uint64
+	for _, name := range []string{"get", "get-config", "edit-config"} {
+		o := report.Operations[name]
+		assert.NotNil(t, o, "Expecting %s to be issued", name)
+		assert.Empty(t, o.Errors)
+		assert.True(t, o.Latency.Min > 0 && o.Latency.Min <= o.Latency.P50, "Unexpected latency %+v", o.Latency)
+		assert.True(t, o.Latency.P50 <= o.Latency.P99 && o.Latency.P99 <= o.Latency.Max)
+		count += o.Count
+	}
+	assert.Equal(t, uint64(60), count)
+	assert.Equal(t, uint64(2), report.Operations["create-subscription"].Count)
+	assert.Equal(t, uint64(62), report.Requests())
+	assert.Empty(t, report.SessionErrors)
+	assert.Greater(t, report.Throughput(), 0.0)
+}
+
+func TestRunDuration(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	begin := time.Now()
+	report, err := Run(context.Background(), testFactory(t, ts), &Config{
+		Duration: 200 * time.Millisecond,
+		Mix:      []Operation{Get("")},
+	})
+	assert.NoError(t, err)
+	assert.True(t, time.Since(begin) < 2*time.Second)
+	assert.Greater(t, report.Operations["get"].Count, uint64(0))
+	assert.Empty(t, report.Operations["get"].Errors, "Expecting requests interrupted by the end of the run to be ignored")
+}
+
+func TestRunExcludesSetUp(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	factory := testFactory(t, ts)
+	report, err := Run(context.Background(), func(ctx context.Context) (client.Session, error) {
+		time.Sleep(300 * time.Millisecond)
+		return factory(ctx)
+	}, &Config{Duration: 100 * time.Millisecond, Mix: []Operation{Get("")}})
+	assert.NoError(t, err)
+	assert.Greater(t, report.Operations["get"].Count, uint64(0), "Expecting the duration to begin after set up")
+	assert.Less(t, report.Elapsed, 300*time.Millisecond)
+}
+
+func TestRunErrors(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).
+		WithRequestHandler(testserver.FailingRequestHandler).
+		WithRequestHandler(testserver.CloseRequestHandler)
+	defer ts.Close()
+
+	failing := func(ctx context.Context) (client.Session, error) {
+		return nil, io.EOF
+	}
+	factory := testFactory(t, ts)
+	var sessions int
+	report, err := Run(context.Background(), func(ctx context.Context) (client.Session, error) {
+		sessions++
+		if sessions == 1 {
+			return failing(ctx)
+		}
+		return factory(ctx)
+	}, &Config{Sessions: 2, Requests: 2, Mix: []Operation{Get("")}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint64{ErrorClassRPC: 1, ErrorClassSessionClosed: 1}, report.Operations["get"].Errors)
+	assert.Equal(t, map[string]uint64{ErrorClassSessionClosed: 1}, report.SessionErrors)
+
+	_, err = Run(context.Background(), failing, &Config{Requests: 1, Mix: []Operation{Get("")}})
+	assert.EqualError(t, err, "failed to establish any session")
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	_, err := Run(context.Background(), nil, &Config{Requests: 1})
+	assert.EqualError(t, err, "no operations defined")
+
+	_, err = Run(context.Background(), nil, &Config{Mix: []Operation{Get("")}})
+	assert.EqualError(t, err, "either a duration or number of requests must be defined")
+
+	_, err = Run(context.Background(), nil, &Config{Requests: 1, Mix: []Operation{Get("").Weighted(0)}})
+	assert.EqualError(t, err, "operations have no weight")
+}
+
+func TestClassifyError(t *testing.T) {
+	assert.Equal(t, "rpc-error:in-use", ClassifyError(&common.RPCError{Tag: "in-use"}))
+	assert.Equal(t, ErrorClassTimeout, ClassifyError(context.DeadlineExceeded))
+	assert.Equal(t, ErrorClassSessionClosed, ClassifyError(io.ErrUnexpectedEOF))
+	assert.Equal(t, ErrorClassTooManyRequests, ClassifyError(client.ErrTooManyRequests))
+	assert.Equal(t, ErrorClassOther, ClassifyError(fmt.Errorf("bad")))
+}
+
+func TestReport(t *testing.T) {
+	r := newReport()
+	for i := 1; i <= 100; i++ {
+		r.operation("get").record(time.Duration(i)*time.Millisecond, nil)
+	}
+	r.operation("get").record(0, &common.RPCError{Tag: "lock-denied"})
+	r.operation("get").summarise()
+	r.Elapsed = time.Second
+
+	l := r.Operations["get"].Latency
+	assert.Equal(t, LatencySummary{
+		Min: time.Millisecond, Mean: 50500 * time.Microsecond, P50: 50 * time.Millisecond,
+		P90: 90 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond,
+	}, l)
+	assert.Equal(t, 101.0, r.Throughput())
+
+	b := &bytes.Buffer{}
+	_, err := r.WriteTo(b)
+	assert.NoError(t, err)
+	assert.Equal(t, `elapsed 1s, 101 requests, 101.0 requests/s, 0 notifications
+operation  count  errors  min  mean    p50   p90   p99   max
+get        101    1       1ms  50.5ms  50ms  90ms  99ms  100ms
+get error rpc-error:lock-denied: 1
+`, b.String())
+	// Subscriptions made during set up are excluded from the throughput.
+	r.operation(subscriptionOperation).record(time.Millisecond, nil)
+	assert.Equal(t, 101.0, r.Throughput())
+}
+
+func TestDefaultPayload(t *testing.T) {
+	assert.Len(t, DefaultPayload(1, 200), 200)
+	assert.Equal(t, `<load xmlns="urn:ncload"><seq>7</seq><pad></pad></load>`, DefaultPayload(7, 0))
+}
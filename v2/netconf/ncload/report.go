@@ -0,0 +1,230 @@
+package ncload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// The number of latencies sampled for each operation; beyond this, latencies are sampled at random, so that long
+// soak tests run in bounded memory.
+const maxLatencySamples = 100000
+
+// Error classes delivered by ClassifyError.
+const (
+	// The prefix of the class of an rpc-error, which is followed by its error-tag, such as rpc-error:in-use.
+	ErrorClassRPC = "rpc-error"
+	// The request, or the session set up, timed out.
+	ErrorClassTimeout = "timeout"
+	// The session closed before the reply was received.
+	ErrorClassSessionClosed = "session-closed"
+	// The request exceeded the outstanding request limit of the session.
+	ErrorClassTooManyRequests = "too-many-requests"
+	// Any other error.
+	ErrorClassOther = "other"
+)
+
+// ClassifyError delivers the class of an error reported by a request, or by a session factory.
+func ClassifyError(err error) string {
+	var rpcErr *common.RPCError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &rpcErr):
+		if rpcErr.Tag == "" {
+			return ErrorClassRPC
+		}
+		return ErrorClassRPC + ":" + rpcErr.Tag
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassSessionClosed
+	case errors.Is(err, client.ErrTooManyRequests):
+		return ErrorClassTooManyRequests
+	}
+	return ErrorClassOther
+}
+
+// The name of the operation reporting subscriptions.
+const subscriptionOperation = "create-subscription"
+
+// Report defines the outcome of a load run.
+type Report struct {
+	// The duration of the run, excluding session set up.
+	Elapsed time.Duration
+	// The outcome of the requests issued for each operation, keyed by operation name. Subscriptions are reported as
+	// the create-subscription operation.
+	Operations map[string]*OperationStats
+	// The number of notifications received.
+	Notifications uint64
+	// The number of sessions that could not be established, keyed by error class.
+	SessionErrors map[string]uint64
+}
+
+// OperationStats defines the outcome of the requests issued for an operation.
+type OperationStats struct {
+	// The number of requests completed, successfully or not.
+	Count uint64
+	// The number of requests that failed, keyed by error class; see ClassifyError.
+	Errors map[string]uint64
+	// The latencies of successful requests.
+	Latency LatencySummary
+
+	samples []time.Duration
+	seen    uint64
+	sum     time.Duration
+	rnd     *rand.Rand
+}
+
+// LatencySummary summarises the latencies of requests. Percentiles are computed from a random sample of latencies if
+// more than 100000 requests completed.
+type LatencySummary struct {
+	Min, Mean, P50, P90, P99, Max time.Duration
+}
+
+func newReport() *Report {
+	return &Report{Operations: map[string]*OperationStats{}, SessionErrors: map[string]uint64{}}
+}
+
+func (r *Report) operation(name string) *OperationStats {
+	o, ok := r.Operations[name]
+	if !ok {
+		o = &OperationStats{Errors: map[string]uint64{}, rnd: rand.New(rand.NewSource(1))} //nolint:gosec
+		r.Operations[name] = o
+	}
+	return o
+}
+
+// Requests delivers the number of requests completed for all operations.
+func (r *Report) Requests() uint64 {
+	var n uint64
+	for _, o := range r.Operations {
+		n += o.Count
+	}
+	return n
+}
+
+// Throughput delivers the number of requests completed per second, excluding the subscriptions made during session
+// set up.
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	n := r.Requests()
+	if o, ok := r.Operations[subscriptionOperation]; ok {
+		n -= o.Count
+	}
+	return float64(n) / r.Elapsed.Seconds()
+}
+
+// WriteTo writes a textual summary of the report to w.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "elapsed %v, %d requests, %.1f requests/s, %d notifications\n", r.Elapsed.Round(time.Millisecond),
+		r.Requests(), r.Throughput(), r.Notifications)
+
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "operation\tcount\terrors\tmin\tmean\tp50\tp90\tp99\tmax")
+	for _, name := range sortedOperations(r.Operations) {
+		o := r.Operations[name]
+		l := o.Latency
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%v\t%v\t%v\t%v\t%v\t%v\n", name, o.Count, o.failed(),
+			l.Min, l.Mean, l.P50, l.P90, l.P99, l.Max)
+	}
+	_ = tw.Flush()
+
+	for _, name := range sortedOperations(r.Operations) {
+		for _, class := range sortedClasses(r.Operations[name].Errors) {
+			fmt.Fprintf(b, "%s error %s: %d\n", name, class, r.Operations[name].Errors[class])
+		}
+	}
+	for _, class := range sortedClasses(r.SessionErrors) {
+		fmt.Fprintf(b, "session error %s: %d\n", class, r.SessionErrors[class])
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func sortedOperations(m map[string]*OperationStats) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedClasses(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (o *OperationStats) failed() uint64 {
+	var n uint64
+	for _, c := range o.Errors {
+		n += c
+	}
+	return n
+}
+
+func (o *OperationStats) record(latency time.Duration, err error) {
+	o.Count++
+	if err != nil {
+		o.Errors[ClassifyError(err)]++
+		return
+	}
+
+	o.seen++
+	o.sum += latency
+	if len(o.samples) < maxLatencySamples {
+		o.samples = append(o.samples, latency)
+	} else if i := o.rnd.Int63n(int64(o.seen)); i < maxLatencySamples {
+		o.samples[i] = latency
+	}
+	if o.seen == 1 || latency < o.Latency.Min {
+		o.Latency.Min = latency
+	}
+	if latency > o.Latency.Max {
+		o.Latency.Max = latency
+	}
+}
+
+// Computes the mean and percentiles from the samples.
+func (o *OperationStats) summarise() {
+	if o.seen == 0 {
+		return
+	}
+	o.Latency.Mean = o.sum / time.Duration(o.seen)
+	sorted := append([]time.Duration(nil), o.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	o.Latency.P50 = percentile(sorted, 0.5)
+	o.Latency.P90 = percentile(sorted, 0.9)
+	o.Latency.P99 = percentile(sorted, 0.99)
+}
+
+// Delivers the latency below which the fraction q of the sorted latencies fall, using the nearest rank.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,389 @@
+package ops
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/pkg/errors"
+)
+
+// Auditing of configuration changes.
+// A session configured WithAudit records every edit-config, copy-config and commit request it executes, whether
+// synchronously or asynchronously, including those issued by SafeCommit, to the store of an AuditLog. Recorded
+// changes can be re-applied to a device by Replay, or reverted by Invert if the log captures the prior content of the
+// changed datastores.
+
+// Operations recorded by the audit log.
+const (
+	AuditEditConfig = "edit-config"
+	AuditCopyConfig = "copy-config"
+	AuditCommit     = "commit"
+)
+
+// ErrNotInvertible is returned by Invert when a record does not hold the prior content of the changed datastore.
+var ErrNotInvertible = errors.New("audit record cannot be inverted")
+
+// AuditRecord defines a configuration change executed by an audited session.
+type AuditRecord struct {
+	// Sequence number of the record, assigned by the audit log.
+	ID uint64 `json:"id"`
+	// The time at which the request was issued.
+	Time time.Time `json:"time"`
+	// The address of the device, and the user and session id used to access it.
+	Target    string `json:"target"`
+	User      string `json:"user"`
+	SessionID uint64 `json:"sessionId"`
+	// The operation, one of AuditEditConfig, AuditCopyConfig or AuditCommit.
+	Operation string `json:"operation"`
+	// The name of the datastore changed by the operation; empty if it is identified by url.
+	Datastore string `json:"datastore,omitempty"`
+	// The rpc request body, and the rpc-reply returned by the server.
+	Request string `json:"request"`
+	Reply   string `json:"reply,omitempty"`
+	// The error returned by the request; empty if it succeeded.
+	Err string `json:"error,omitempty"`
+	// The content of the datastore before the change, if captured (see AuditSnapshots).
+	Before *string `json:"before,omitempty"`
+}
+
+// AuditStore persists audit records.
+type AuditStore interface {
+	// Append adds the record to the store.
+	Append(rec *AuditRecord) error
+	// Records delivers the stored records, in the order they were appended.
+	Records() ([]AuditRecord, error)
+}
+
+// MemoryAuditStore is an AuditStore that holds records in memory.
+// It is safe for concurrent use.
+type MemoryAuditStore struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+// NewMemoryAuditStore delivers an empty in-memory store.
+func NewMemoryAuditStore() *MemoryAuditStore {
+	return &MemoryAuditStore{}
+}
+
+// Append implements AuditStore.
+func (m *MemoryAuditStore) Append(rec *AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, *rec)
+	return nil
+}
+
+// Records implements AuditStore.
+func (m *MemoryAuditStore) Records() ([]AuditRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AuditRecord(nil), m.records...), nil
+}
+
+// AuditLog records the configuration changes executed by audited sessions to a store.
+// An AuditLog may be shared by many sessions.
+type AuditLog struct {
+	store     AuditStore
+	snapshots bool
+	onError   func(rec *AuditRecord, err error)
+	nextID    uint64
+}
+
+// AuditOption implements options for configuring an audit log.
+type AuditOption func(*AuditLog)
+
+// AuditSnapshots captures the content of the changed datastore, by issuing a get-config request before each change,
+// so that recorded changes can be inverted.
+// Default is not to capture snapshots.
+func AuditSnapshots() AuditOption {
+	return func(l *AuditLog) {
+		l.snapshots = true
+	}
+}
+
+// AuditErrorHandler defines a function called when a record cannot be appended to the store. The change itself is
+// unaffected.
+// Default is to log the failure.
+func AuditErrorHandler(fn func(rec *AuditRecord, err error)) AuditOption {
+	return func(l *AuditLog) {
+		l.onError = fn
+	}
+}
+
+// NewAuditLog delivers an audit log recording changes to the store.
+func NewAuditLog(store AuditStore, opts ...AuditOption) *AuditLog {
+	l := &AuditLog{
+		store: store,
+		onError: func(rec *AuditRecord, err error) {
+			log.Printf("Audit target:%s operation:%s error:%v\n", rec.Target, rec.Operation, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Records delivers the records held by the store.
+func (l *AuditLog) Records() ([]AuditRecord, error) {
+	return l.store.Records()
+}
+
+// WithAudit records the configuration changes executed by the session to the audit log.
+func WithAudit(l *AuditLog) SessionOption {
+	return func(so *sessionOptions) {
+		so.audit = l
+	}
+}
+
+// Wraps the session, so that configuration changes are recorded.
+func (l *AuditLog) wrap(s client.Session, user, target string) client.Session {
+	return &auditedSession{Session: s, log: l, user: user, target: target}
+}
+
+type auditedSession struct {
+	client.Session
+	log    *AuditLog
+	user   string
+	target string
+}
+
+func (a *auditedSession) Execute(req common.Request) (*common.RPCReply, error) {
+	rec, err := a.begin(req)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return a.Session.Execute(req)
+	}
+
+	// The request is executed in its recorded form, as a request streaming from a reader can only be read once.
+	reply, err := a.Session.Execute(rec.Request)
+	a.end(rec, reply, err)
+	return reply, err
+}
+
+func (a *auditedSession) ExecuteContext(ctx context.Context, req common.Request) (*common.RPCReply, error) {
+	rec, err := a.begin(req)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return a.Session.ExecuteContext(ctx, req)
+	}
+
+	reply, err := a.Session.ExecuteContext(ctx, rec.Request)
+	a.end(rec, reply, err)
+	return reply, err
+}
+
+func (a *auditedSession) ExecuteAsync(req common.Request, rchan chan *common.RPCReply) error {
+	rec, err := a.begin(req)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return a.Session.ExecuteAsync(req, rchan)
+	}
+
+	replies := make(chan *common.RPCReply, 1)
+	if err = a.Session.ExecuteAsync(rec.Request, replies); err != nil {
+		a.end(rec, nil, err)
+		return err
+	}
+	go func() {
+		reply, ok := <-replies
+		a.end(rec, reply, replyError(reply))
+		if !ok {
+			// The session closed before the reply was received.
+			close(rchan)
+			return
+		}
+		rchan <- reply
+	}()
+	return nil
+}
+
+// Delivers the record of the request, with the prior content of the changed datastore if snapshots are captured,
+// or nil if the request is not audited.
+func (a *auditedSession) begin(req common.Request) (*AuditRecord, error) {
+	operation, datastore := auditedOperation(req)
+	if operation == "" {
+		return nil, nil
+	}
+
+	body, err := requestXML(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &AuditRecord{
+		Target: a.target, User: a.user, SessionID: a.ID(),
+		Operation: operation, Datastore: datastore, Request: body,
+	}
+	if a.log.snapshots && datastore != "" {
+		rec.Before = a.snapshot(datastore)
+	}
+	rec.ID, rec.Time = atomic.AddUint64(&a.log.nextID, 1), time.Now()
+	return rec, nil
+}
+
+// Completes the record with the outcome of the request, and appends it to the store.
+func (a *auditedSession) end(rec *AuditRecord, reply *common.RPCReply, err error) {
+	if reply != nil {
+		rec.Reply = reply.RawXML
+	}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	if serr := a.log.store.Append(rec); serr != nil {
+		a.log.onError(rec, serr)
+	}
+}
+
+// Delivers the error reported by an asynchronous reply, which is nil if the session closed before it was received.
+func replyError(reply *common.RPCReply) error {
+	if reply == nil {
+		return io.ErrUnexpectedEOF
+	}
+	for i := range reply.Errors {
+		if reply.Errors[i].Severity == "error" {
+			return &reply.Errors[i]
+		}
+	}
+	return nil
+}
+
+// Delivers the content of the datastore, or nil if it cannot be retrieved.
+func (a *auditedSession) snapshot(datastore string) *string {
+	reply, err := a.Session.Execute(createGetConfigSubtreeRequest(nil, datastore))
+	if err != nil {
+		return nil
+	}
+	data := &Data{}
+	if err = xml.Unmarshal([]byte(reply.Data), data); err != nil {
+		return nil
+	}
+	return &data.Content
+}
+
+// Delivers the audited operation defined by the request, and the datastore it changes, or an empty operation if the
+// request is not audited. Requests supplied as xml, such as those issued by Replay, are identified by their element
+// name.
+func auditedOperation(req common.Request) (operation, datastore string) {
+	switch r := req.(type) {
+	case *EditConfigReq:
+		return AuditEditConfig, datastoreName(r.Target)
+	case *CopyConfigReq:
+		return AuditCopyConfig, datastoreName(r.Target)
+	case *CommitReq, *JunosCommitReq:
+		return AuditCommit, RunningCfg
+	case string:
+		return auditedXMLOperation([]byte(r))
+	case []byte:
+		return auditedXMLOperation(r)
+	}
+	return "", ""
+}
+
+func auditedXMLOperation(b []byte) (operation, datastore string) {
+	req := &struct {
+		XMLName xml.Name
+		Target  *ConfigType `xml:"target"`
+	}{}
+	if xml.Unmarshal(b, req) != nil {
+		return "", ""
+	}
+	switch req.XMLName.Local {
+	case AuditEditConfig, AuditCopyConfig:
+		return req.XMLName.Local, datastoreName(req.Target)
+	case "commit", "commit-configuration":
+		return AuditCommit, RunningCfg
+	}
+	return "", ""
+}
+
+// Delivers the name of the datastore, for example "running" from "<running/>", or an empty string if the datastore
+// is identified by url.
+func datastoreName(ct *ConfigType) string {
+	if ct == nil || ct.URL != "" {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(ct.Type, "<"), "/>")
+}
+
+// Delivers the xml encoding of the request body.
+func requestXML(req common.Request) (string, error) {
+	switch r := req.(type) {
+	case string:
+		return r, nil
+	case []byte:
+		return string(r), nil
+	case io.Reader:
+		b, err := io.ReadAll(r)
+		return string(b), err
+	}
+	b, err := xml.Marshal(req)
+	return string(b), err
+}
+
+// Replay re-applies the recorded changes using the session, in the order they are supplied.
+// Records of failed requests are skipped. Replay stops at the first failure, returning an error identifying the
+// record.
+func Replay(s OpSession, records []AuditRecord) error {
+	for i := range records {
+		rec := &records[i]
+		if rec.Err != "" {
+			continue
+		}
+		if _, err := s.Execute(rec.Request); err != nil {
+			return errors.Wrapf(err, "failed to replay audit record %d", rec.ID)
+		}
+	}
+	return nil
+}
+
+// Invert reverts the recorded changes using the session, in the reverse of the order they are supplied, by
+// restoring the prior content of each changed datastore with a copy-config request, which replaces the datastore
+// content in full, so removing any nodes added by the change. A reverted commit is applied by copying the prior
+// running configuration to the candidate and committing it.
+// Records of failed requests are skipped. ErrNotInvertible is returned, before any change is made, if a record does
+// not hold the prior content of the datastore.
+func Invert(s OpSession, records []AuditRecord) error {
+	for i := range records {
+		rec := &records[i]
+		if rec.Err == "" && (rec.Before == nil || rec.Datastore == "") {
+			return errors.Wrapf(ErrNotInvertible, "record %d (%s)", rec.ID, rec.Operation)
+		}
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := &records[i]
+		if rec.Err != "" {
+			continue
+		}
+		if err := invertRecord(s, rec); err != nil {
+			return errors.Wrapf(err, "failed to invert audit record %d", rec.ID)
+		}
+	}
+	return nil
+}
+
+func invertRecord(s OpSession, rec *AuditRecord) error {
+	if rec.Operation != AuditCommit {
+		return s.CopyConfig(DsConfig(*rec.Before), DsName(rec.Datastore))
+	}
+	if err := s.CopyConfig(DsConfig(*rec.Before), DsName(CandidateCfg)); err != nil {
+		return err
+	}
+	return s.Commit()
+}
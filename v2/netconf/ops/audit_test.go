@@ -0,0 +1,281 @@
+package ops
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/mocks"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// datastoreSession is a client session that maintains datastore content in memory, applying edit-config (as a
+// replacement of the datastore content), copy-config, from a datastore or an inline configuration, and commit
+// requests.
+type datastoreSession struct {
+	mocks.OpSession
+	datastores map[string]string
+	fail       string
+}
+
+func newDatastoreSession() *datastoreSession {
+	return &datastoreSession{datastores: map[string]string{RunningCfg: "<a>1</a>", CandidateCfg: "<a>1</a>"}}
+}
+
+func (d *datastoreSession) ID() uint64 {
+	return 7
+}
+
+func (d *datastoreSession) ServerCapabilities() []string {
+	return nil
+}
+
+type datastoreRequest struct {
+	XMLName xml.Name
+	Target  struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"target"`
+	Source struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"source"`
+	Config struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"config"`
+}
+
+func (d *datastoreSession) Execute(req common.Request) (*common.RPCReply, error) {
+	if gc, ok := req.(*GetConfigReq); ok {
+		name := datastoreName(gc.Source)
+		return &common.RPCReply{Data: "<data>" + d.datastores[name] + "</data>"}, nil
+	}
+
+	body, err := requestXML(req)
+	if err != nil {
+		return nil, err
+	}
+	r := &datastoreRequest{}
+	if err = xml.Unmarshal([]byte(body), r); err != nil {
+		return nil, err
+	}
+	if r.XMLName.Local == d.fail {
+		return nil, &common.RPCError{Tag: "operation-failed", Severity: "error", Message: "failed " + d.fail}
+	}
+	switch r.XMLName.Local {
+	case "edit-config":
+		d.datastores[datastoreName(&ConfigType{Type: r.Target.Inner})] = r.Config.Inner
+	case "copy-config":
+		source := d.datastores[datastoreName(&ConfigType{Type: r.Source.Inner})]
+		if strings.HasPrefix(r.Source.Inner, "<config>") {
+			source = strings.TrimSuffix(strings.TrimPrefix(r.Source.Inner, "<config>"), "</config>")
+		}
+		d.datastores[datastoreName(&ConfigType{Type: r.Target.Inner})] = source
+	case "commit":
+		d.datastores[RunningCfg] = d.datastores[CandidateCfg]
+	}
+	return &common.RPCReply{RawXML: "<rpc-reply><ok/></rpc-reply>"}, nil
+}
+
+func (d *datastoreSession) ExecuteContext(ctx context.Context, req common.Request) (*common.RPCReply, error) {
+	return d.Execute(req)
+}
+
+func (d *datastoreSession) ExecuteAsync(req common.Request, rchan chan *common.RPCReply) error {
+	reply, err := d.Execute(req)
+	if err != nil {
+		return err
+	}
+	go func() { rchan <- reply }()
+	return nil
+}
+
+func (d *datastoreSession) Subscribe(req common.Request, nchan chan *common.Notification) (*common.RPCReply, error) {
+	return d.Execute(req)
+}
+
+func newAuditedSession(l *AuditLog) (OpSession, *datastoreSession) {
+	ds := newDatastoreSession()
+	return &sImpl{Session: l.wrap(ds, "admin", "router1:830")}, ds
+}
+
+func TestAuditRecords(t *testing.T) {
+	l := NewAuditLog(NewMemoryAuditStore(), AuditSnapshots())
+	ncs, ds := newAuditedSession(l)
+
+	assert.NoError(t, ncs.EditConfig(CandidateCfg, Cfg("<a>2</a>")))
+	assert.NoError(t, ncs.Lock(CandidateCfg), "Expecting lock to be executed")
+	assert.NoError(t, ncs.Commit())
+	assert.NoError(t, ncs.CopyConfig(DsName(RunningCfg), DsURL("file://backup.xml")))
+	assert.Equal(t, "<a>2</a>", ds.datastores[RunningCfg])
+
+	records, err := l.Records()
+	assert.NoError(t, err)
+	assert.Len(t, records, 3, "Expecting lock not to be recorded")
+
+	edit := records[0]
+	assert.Equal(t, uint64(1), edit.ID)
+	assert.Equal(t, "router1:830", edit.Target)
+	assert.Equal(t, "admin", edit.User)
+	assert.Equal(t, uint64(7), edit.SessionID)
+	assert.Equal(t, AuditEditConfig, edit.Operation)
+	assert.Equal(t, CandidateCfg, edit.Datastore)
+	assert.Equal(t, "<edit-config><target><candidate/></target><config><a>2</a></config></edit-config>", edit.Request)
+	assert.Equal(t, "<rpc-reply><ok/></rpc-reply>", edit.Reply)
+	assert.Equal(t, "<a>1</a>", *edit.Before)
+	assert.False(t, edit.Time.IsZero())
+
+	commit := records[1]
+	assert.Equal(t, AuditCommit, commit.Operation)
+	assert.Equal(t, RunningCfg, commit.Datastore)
+	assert.Equal(t, "<a>1</a>", *commit.Before)
+
+	cp := records[2]
+	assert.Equal(t, AuditCopyConfig, cp.Operation)
+	assert.Empty(t, cp.Datastore, "Expecting url target not to be named")
+	assert.Nil(t, cp.Before)
+}
+
+func TestAuditInvertAndReplay(t *testing.T) {
+	l := NewAuditLog(NewMemoryAuditStore(), AuditSnapshots())
+	ncs, ds := newAuditedSession(l)
+
+	assert.NoError(t, ncs.EditConfig(CandidateCfg, Cfg("<a>2</a>")))
+	assert.NoError(t, ncs.Commit())
+	changes, _ := l.Records()
+
+	assert.NoError(t, Invert(ncs, changes))
+	assert.Equal(t, "<a>1</a>", ds.datastores[RunningCfg])
+	assert.Equal(t, "<a>1</a>", ds.datastores[CandidateCfg])
+
+	assert.NoError(t, Replay(ncs, changes))
+	assert.Equal(t, "<a>2</a>", ds.datastores[RunningCfg])
+
+	records, _ := l.Records()
+	assert.Len(t, records, 7, "Expecting inverted and replayed changes to be recorded")
+	assert.Equal(t, "<copy-config><target><candidate/></target><source><config><a>1</a></config></source></copy-config>",
+		records[2].Request, "Expecting the candidate to be replaced by the prior running configuration")
+}
+
+func TestAuditInvertStartup(t *testing.T) {
+	l := NewAuditLog(NewMemoryAuditStore(), AuditSnapshots())
+	ncs, ds := newAuditedSession(l)
+	ds.datastores[StartupCfg] = "<a>0</a>"
+
+	assert.NoError(t, ncs.CopyConfig(DsName(RunningCfg), DsName(StartupCfg)))
+	assert.NoError(t, ncs.EditConfig(RunningCfg, Cfg("<a>1</a><b>2</b>")))
+	changes, _ := l.Records()
+
+	assert.NoError(t, Invert(ncs, changes))
+	assert.Equal(t, "<a>0</a>", ds.datastores[StartupCfg])
+	assert.Equal(t, "<a>1</a>", ds.datastores[RunningCfg], "Expecting the added node to be removed")
+}
+
+func TestAuditExecuteContext(t *testing.T) {
+	l := NewAuditLog(NewMemoryAuditStore())
+	ncs, ds := newAuditedSession(l)
+
+	_, err := ncs.ExecuteContext(context.Background(), createEditConfigRequest(RunningCfg, Cfg("<a>2</a>")))
+	assert.NoError(t, err)
+	assert.Equal(t, "<a>2</a>", ds.datastores[RunningCfg])
+
+	records, _ := l.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, AuditEditConfig, records[0].Operation)
+}
+
+func TestAuditExecuteAsync(t *testing.T) {
+	l := NewAuditLog(NewMemoryAuditStore(), AuditSnapshots())
+	ncs, ds := newAuditedSession(l)
+
+	rchan := make(chan *common.RPCReply)
+	assert.NoError(t, ncs.ExecuteAsync(createEditConfigRequest(RunningCfg, Cfg("<a>2</a>")), rchan))
+	reply := <-rchan
+	assert.Equal(t, "<rpc-reply><ok/></rpc-reply>", reply.RawXML)
+	assert.Equal(t, "<a>2</a>", ds.datastores[RunningCfg])
+
+	records, _ := l.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, AuditEditConfig, records[0].Operation)
+	assert.Equal(t, "<rpc-reply><ok/></rpc-reply>", records[0].Reply)
+	assert.Equal(t, "<a>1</a>", *records[0].Before)
+}
+
+func TestAuditFailedRequests(t *testing.T) {
+	l := NewAuditLog(NewMemoryAuditStore())
+	ncs, ds := newAuditedSession(l)
+
+	ds.fail = "commit"
+	assert.NoError(t, ncs.EditConfig(CandidateCfg, Cfg("<a>2</a>")))
+	assert.Error(t, ncs.Commit())
+
+	records, _ := l.Records()
+	assert.Len(t, records, 2)
+	assert.Equal(t, "netconf rpc [error] 'failed commit'", records[1].Err)
+	assert.Nil(t, records[0].Before, "Not expecting snapshots")
+
+	err := Invert(ncs, records)
+	assert.True(t, errors.Is(err, ErrNotInvertible))
+	assert.Equal(t, "<a>2</a>", ds.datastores[CandidateCfg], "Expecting no change to be made")
+
+	ds.fail = ""
+	ds.datastores[CandidateCfg] = "<a>3</a>"
+	assert.NoError(t, Replay(ncs, records), "Expecting failed commit to be skipped")
+	assert.Equal(t, "<a>2</a>", ds.datastores[CandidateCfg])
+	assert.Equal(t, "<a>1</a>", ds.datastores[RunningCfg])
+
+	ds.fail = "edit-config"
+	err = Replay(ncs, records)
+	assert.EqualError(t, err, "failed to replay audit record 1: netconf rpc [error] 'failed edit-config'")
+}
+
+type failingAuditStore struct {
+	MemoryAuditStore
+}
+
+func (f *failingAuditStore) Append(rec *AuditRecord) error {
+	return errors.New("store unavailable")
+}
+
+func TestAuditStoreFailure(t *testing.T) {
+	var failed []string
+	l := NewAuditLog(&failingAuditStore{}, AuditErrorHandler(func(rec *AuditRecord, err error) {
+		failed = append(failed, fmt.Sprintf("%s: %v", rec.Operation, err))
+	}))
+	ncs, ds := newAuditedSession(l)
+
+	assert.NoError(t, ncs.EditConfig(RunningCfg, Cfg("<a>2</a>")), "Expecting change to be unaffected")
+	assert.Equal(t, "<a>2</a>", ds.datastores[RunningCfg])
+	assert.Equal(t, []string{"edit-config: store unavailable"}, failed)
+}
+
+func TestSessionWithAudit(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	l := NewAuditLog(NewMemoryAuditStore())
+	target := fmt.Sprintf("localhost:%d", ts.Port())
+	s, err := NewSessionWithOptions(context.Background(), sshConfig, target, WithAudit(l))
+	assert.NoError(t, err, "Expecting new session to succeed")
+	defer s.Close()
+
+	assert.NoError(t, s.EditConfig(RunningCfg, Cfg(strings.NewReader("<top><a>1</a></top>"))))
+
+	records, _ := l.Records()
+	assert.Len(t, records, 1)
+	assert.Equal(t, testserver.TestUserName, records[0].User)
+	assert.Equal(t, target, records[0].Target)
+	assert.Equal(t, s.ID(), records[0].SessionID)
+	assert.Contains(t, records[0].Request, "<config><top><a>1</a></top></config>", "Expecting streamed config to be recorded")
+	assert.Contains(t, records[0].Reply, "<top><a>1</a></top>", "Expecting echoed request in reply")
+}
@@ -0,0 +1,218 @@
+package ops
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/pkg/errors"
+)
+
+// ErrBatchReplyMismatch is returned by ExecuteBatch when the reply elements cannot be matched to the operations.
+var ErrBatchReplyMismatch = errors.New("batch reply does not match operations")
+
+// BatchOperation defines an operation issued within a batch by ExecuteBatch.
+type BatchOperation struct {
+	// The operation request, which can be an xml string or []byte, used verbatim, or a struct with xml tags.
+	Request interface{}
+	// The address of the result that will hold the reply to the operation, which can be either:
+	// - a string, in which case it will hold the reply element, or the content of a <data> reply element, or
+	// - a JSONResult or YAMLResult, in which case it will hold the reply element converted to JSON or YAML, or
+	// - a map[string]interface{}, in which case it will hold the reply element converted by XMLToValue, or
+	// - a struct with xml tags, into which the reply element, or the content of a <data> reply element, is decoded.
+	// Nil if the reply is not required.
+	Result interface{}
+}
+
+// BatchOption configures a batch request.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	container string
+	namespace string
+}
+
+// BatchContainer wraps the operations of the batch in a vendor container element with the specified name and
+// namespace (if not empty). If the reply holds a single element of the same name, the replies to the operations
+// are extracted from it.
+func BatchContainer(name, namespace string) BatchOption {
+	return func(c *batchConfig) {
+		c.container, c.namespace = name, namespace
+	}
+}
+
+func (s *sImpl) ExecuteBatch(operations []BatchOperation, options ...BatchOption) error {
+	cfg := &batchConfig{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	req, err := createBatchRequest(operations, cfg)
+	if err != nil {
+		return err
+	}
+	reply, err := s.Session.Execute(req)
+	if err != nil {
+		return err
+	}
+
+	elements, err := batchReplyElements(reply.Data, cfg.container)
+	if err != nil {
+		return err
+	}
+	if len(elements) != len(operations) {
+		// Devices may acknowledge a batch of operations without data using a single <ok/>.
+		if !wantsBatchResults(operations) && allOk(elements) {
+			return nil
+		}
+		return errors.Wrapf(ErrBatchReplyMismatch, "%d operations, %d reply elements", len(operations), len(elements))
+	}
+	for i, op := range operations {
+		if op.Result == nil {
+			continue
+		}
+		if err := s.decodeBatchReply(elements[i], op.Result); err != nil {
+			return errors.Wrapf(err, "operation %d", i)
+		}
+	}
+	return nil
+}
+
+// Builds the batch request, wrapping the operation requests in the container, if any.
+func createBatchRequest(operations []BatchOperation, cfg *batchConfig) (common.Request, error) {
+	if len(operations) == 0 {
+		return nil, errors.New("batch has no operations")
+	}
+	sb := &strings.Builder{}
+	if cfg.container != "" {
+		sb.WriteString("<" + cfg.container)
+		if cfg.namespace != "" {
+			sb.WriteString(` xmlns="` + cfg.namespace + `"`)
+		}
+		sb.WriteString(">")
+	}
+	for i, op := range operations {
+		switch r := op.Request.(type) {
+		case string:
+			sb.WriteString(r)
+		case []byte:
+			sb.Write(r)
+		default:
+			b, err := xml.Marshal(r)
+			if err != nil {
+				return nil, errors.Wrapf(err, "operation %d", i)
+			}
+			sb.Write(b)
+		}
+	}
+	if cfg.container != "" {
+		sb.WriteString("</" + cfg.container + ">")
+	}
+	return common.Request(sb.String()), nil
+}
+
+// Delivers the top-level elements of the reply data, or the children of the container element if the data holds
+// a single container element.
+func batchReplyElements(data, container string) ([]string, error) {
+	elements, err := childElements(data)
+	if err != nil {
+		return nil, err
+	}
+	if container == "" || len(elements) != 1 || elementName(elements[0]) != container {
+		return elements, nil
+	}
+	start := strings.Index(elements[0], ">") + 1
+	end := strings.LastIndex(elements[0], "</")
+	if strings.HasSuffix(elements[0], "/>") || end < start {
+		return nil, nil
+	}
+	return childElements(elements[0][start:end])
+}
+
+// Delivers the top-level elements of the content, ignoring any text.
+func childElements(content string) ([]string, error) {
+	var elements []string
+	d := xml.NewDecoder(strings.NewReader(content))
+	var depth int
+	var start int64
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return elements, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				start = offset
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				elements = append(elements, content[start:d.InputOffset()])
+			}
+		}
+	}
+}
+
+// Delivers the local name of the element.
+func elementName(element string) string {
+	name := strings.TrimPrefix(element, "<")
+	if i := strings.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func wantsBatchResults(operations []BatchOperation) bool {
+	for _, op := range operations {
+		if op.Result != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func allOk(elements []string) bool {
+	for _, e := range elements {
+		if elementName(e) != "ok" {
+			return false
+		}
+	}
+	return true
+}
+
+// Decodes the reply to an operation of the batch into the result.
+func (s *sImpl) decodeBatchReply(element string, result interface{}) error {
+	if elementName(element) == "data" {
+		return s.handleGetReply(&common.RPCReply{Data: element}, result)
+	}
+
+	var err error
+	switch target := result.(type) {
+	case *string:
+		*target = element
+	case *JSONResult:
+		var out string
+		// Wrap the element so that it is delivered as a member.
+		out, err = toJSON("<reply>" + element + "</reply>")
+		*target = JSONResult(out)
+	case *YAMLResult:
+		var out string
+		out, err = toYAML("<reply>" + element + "</reply>")
+		*target = YAMLResult(out)
+	case *map[string]interface{}:
+		*target, err = XMLToValue("<reply>" + element + "</reply>")
+	default:
+		err = xml.Unmarshal([]byte(element), result)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package ops
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/mocks"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type routeInformation struct {
+	XMLName xml.Name `xml:"route-information"`
+	Tables  []string `xml:"route-table>table-name"`
+}
+
+func newOpsSessionWithBatchReply(request, data string) (OpSession, *mocks.OpSession) {
+	mockClient := &mocks.OpSession{}
+	mockClient.On("Execute", common.Request(request)).Return(&common.RPCReply{Data: data}, nil)
+	return &sImpl{Session: mockClient}, mockClient
+}
+
+func TestExecuteBatch(t *testing.T) {
+	ncs, mockClient := newOpsSessionWithBatchReply(
+		`<get-route-information/><get><filter type="subtree"><element/></filter></get><lock-database/>`,
+		`<route-information><route-table><table-name>inet.0</table-name></route-table></route-information>`+
+			`<data><element attr1="A"/></data><ok/>`)
+
+	var routes routeInformation
+	var element Element
+	err := ncs.ExecuteBatch([]BatchOperation{
+		{Request: `<get-route-information/>`, Result: &routes},
+		{Request: createGetSubtreeRequest(`<element/>`), Result: &element},
+		{Request: []byte(`<lock-database/>`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"inet.0"}, routes.Tables)
+	assert.Equal(t, "A", element.Attr1)
+	mockClient.AssertExpectations(t)
+}
+
+func TestExecuteBatchContainer(t *testing.T) {
+	ncs, _ := newOpsSessionWithBatchReply(
+		`<transaction xmlns="urn:example:batch"><first/><second/></transaction>`,
+		`<transaction xmlns="urn:example:batch"><first-reply>1</first-reply><second-reply>2</second-reply></transaction>`)
+
+	var first string
+	var second JSONResult
+	err := ncs.ExecuteBatch([]BatchOperation{
+		{Request: `<first/>`, Result: &first},
+		{Request: `<second/>`, Result: &second},
+	}, BatchContainer("transaction", "urn:example:batch"))
+	assert.NoError(t, err)
+	assert.Equal(t, `<first-reply>1</first-reply>`, first)
+	assert.JSONEq(t, `{"second-reply":"2"}`, string(second))
+}
+
+func TestExecuteBatchOk(t *testing.T) {
+	ncs, _ := newOpsSessionWithBatchReply(`<first/><second/>`, `<ok/>`)
+
+	assert.NoError(t, ncs.ExecuteBatch([]BatchOperation{{Request: `<first/>`}, {Request: `<second/>`}}),
+		"Expecting a single ok to acknowledge operations without results")
+
+	var first string
+	err := ncs.ExecuteBatch([]BatchOperation{{Request: `<first/>`, Result: &first}, {Request: `<second/>`}})
+	assert.True(t, errors.Is(err, ErrBatchReplyMismatch))
+	assert.EqualError(t, err, "2 operations, 1 reply elements: batch reply does not match operations")
+}
+
+func TestExecuteBatchFailures(t *testing.T) {
+	ncs := &sImpl{Session: &mocks.OpSession{}}
+	assert.EqualError(t, ncs.ExecuteBatch(nil), "batch has no operations")
+
+	mockClient := &mocks.OpSession{}
+	mockClient.On("Execute", common.Request(`<first/>`)).Return(nil, errors.New("failed"))
+	ncs = &sImpl{Session: mockClient}
+	assert.EqualError(t, ncs.ExecuteBatch([]BatchOperation{{Request: `<first/>`}}), "failed")
+
+	ncs2, _ := newOpsSessionWithBatchReply(`<first/>`, `<first-reply>x</first-reply>`)
+	var result struct {
+		Value int `xml:",chardata"`
+	}
+	err := ncs2.ExecuteBatch([]BatchOperation{{Request: `<first/>`, Result: &result}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "operation 0")
+}
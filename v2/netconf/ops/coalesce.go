@@ -0,0 +1,81 @@
+package ops
+
+import (
+	"encoding/xml"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// WithGetCoalescing enables the coalescing of identical concurrent GetSubtree and GetXpath requests on the session,
+// so that a request issued while an identical request is awaiting its reply is not sent to the server, but shares
+// the reply to the earlier request. This protects devices from redundant heavy gets, for example when many views
+// of a user interface refresh at once.
+// Each caller decodes the shared reply into its own result. Configuration requests are never coalesced.
+func WithGetCoalescing() SessionOption {
+	return func(so *sessionOptions) {
+		so.coalesceGets = true
+	}
+}
+
+// ErrCoalescedRequestAborted is returned to callers sharing the reply to a coalesced request whose execution did not
+// complete, because it panicked.
+var ErrCoalescedRequestAborted = errors.New("coalesced request aborted")
+
+// Coalesces concurrent executions of identical requests.
+type flightGroup struct {
+	group singleflight.Group
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{}
+}
+
+// Executes the request, unless an identical request is already in progress, in which case its outcome is delivered
+// once it completes. Callers waiting for a request whose execution panics are released with
+// ErrCoalescedRequestAborted, rather than panicking as singleflight would have them do; the panic is raised only in
+// the caller that executed the request.
+func (g *flightGroup) execute(s executor, req common.Request) (*common.RPCReply, error) {
+	key, ok := requestKey(req)
+	if !ok {
+		return s.Execute(req)
+	}
+
+	var recovered interface{}
+	executed := false
+	result, err, _ := g.group.Do(key, func() (_ interface{}, err error) {
+		executed = true
+		defer func() {
+			if recovered = recover(); recovered != nil {
+				err = ErrCoalescedRequestAborted
+			}
+		}()
+		return s.Execute(req)
+	})
+	if executed && recovered != nil {
+		panic(recovered)
+	}
+	reply, _ := result.(*common.RPCReply)
+	return reply, err
+}
+
+// Defines the method used to execute requests that are coalesced.
+type executor interface {
+	Execute(req common.Request) (*common.RPCReply, error)
+}
+
+// Delivers the key identifying the request, which is its xml encoding.
+func requestKey(req common.Request) (string, bool) {
+	switch r := req.(type) {
+	case string:
+		return r, true
+	case []byte:
+		return string(r), true
+	}
+	b, err := xml.Marshal(req)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
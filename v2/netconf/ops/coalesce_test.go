@@ -0,0 +1,142 @@
+package ops
+
+import (
+	"github.com/pkg/errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/mocks"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func newCoalescingSessionWithMockClient() (*sImpl, *mocks.OpSession) {
+	mockClient := &mocks.OpSession{}
+	return &sImpl{Session: mockClient, gets: newFlightGroup()}, mockClient
+}
+
+func TestGetCoalescing(t *testing.T) {
+	ncs, mcli := newCoalescingSessionWithMockClient()
+	mcli.On("Execute", createGetSubtreeRequest(`<subtree-element/>`)).After(100*time.Millisecond).
+		Return(&common.RPCReply{Data: `<data><element attr1="ABC"/></data>`}, nil).Once()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				var result string
+				assert.NoError(t, ncs.GetSubtree(`<subtree-element/>`, &result))
+				assert.Equal(t, `<element attr1="ABC"/>`, result)
+				return
+			}
+			result := &Element{}
+			assert.NoError(t, ncs.GetSubtree(`<subtree-element/>`, result))
+			assert.Equal(t, "ABC", result.Attr1)
+		}(i)
+	}
+	wg.Wait()
+	mcli.AssertNumberOfCalls(t, "Execute", 1)
+
+	// Once the reply has been delivered, an identical request is sent to the server.
+	mcli.On("Execute", createGetSubtreeRequest(`<subtree-element/>`)).
+		Return(&common.RPCReply{Data: `<data><element attr1="DEF"/></data>`}, nil).Once()
+	result := &Element{}
+	assert.NoError(t, ncs.GetSubtree(`<subtree-element/>`, result))
+	assert.Equal(t, "DEF", result.Attr1)
+	mcli.AssertNumberOfCalls(t, "Execute", 2)
+}
+
+func TestGetCoalescingDistinctRequests(t *testing.T) {
+	ncs, mcli := newCoalescingSessionWithMockClient()
+	mcli.On("Execute", createGetXpathRequest("/a", nil)).After(50*time.Millisecond).
+		Return(&common.RPCReply{Data: `<data><a/></data>`}, nil).Once()
+	mcli.On("Execute", createGetXpathRequest("/b", nil)).After(50*time.Millisecond).
+		Return(nil, errors.New("failed")).Once()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var result string
+			errs[i] = ncs.GetXpath([]string{"/a", "/b"}[i%2], nil, &result)
+		}(i)
+	}
+	wg.Wait()
+
+	mcli.AssertNumberOfCalls(t, "Execute", 2)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[2])
+	assert.EqualError(t, errs[1], "failed", "Expecting failure to be shared")
+	assert.EqualError(t, errs[3], "failed", "Expecting failure to be shared")
+}
+
+func TestGetConfigNotCoalesced(t *testing.T) {
+	ncs, mcli := newCoalescingSessionWithMockClient()
+	mcli.On("Execute", createGetConfigSubtreeRequest(`<subtree-element/>`, "running")).After(50*time.Millisecond).
+		Return(&common.RPCReply{Data: `<data><element attr1="ABC"/></data>`}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result string
+			assert.NoError(t, ncs.GetConfigSubtree(`<subtree-element/>`, "running", &result))
+		}()
+	}
+	wg.Wait()
+	mcli.AssertNumberOfCalls(t, "Execute", 2)
+}
+
+// Executor whose first execution panics once released.
+type panickingExecutor struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *panickingExecutor) Execute(req common.Request) (*common.RPCReply, error) {
+	first := false
+	p.once.Do(func() { first = true })
+	if !first {
+		return nil, errors.New("not coalesced")
+	}
+	close(p.started)
+	<-p.release
+	panic("execute failed")
+}
+
+func TestGetCoalescingPanic(t *testing.T) {
+	g := newFlightGroup()
+	ex := &panickingExecutor{started: make(chan struct{}), release: make(chan struct{})}
+
+	go func() {
+		defer func() { _ = recover() }()
+		_, _ = g.execute(ex, `<get/>`)
+	}()
+	<-ex.started
+
+	waiter := make(chan error)
+	go func() {
+		_, err := g.execute(ex, `<get/>`)
+		waiter <- err
+	}()
+	// Allow the second caller to join the flight.
+	time.Sleep(50 * time.Millisecond)
+	close(ex.release)
+
+	select {
+	case err := <-waiter:
+		assert.Equal(t, ErrCoalescedRequestAborted, err)
+	case <-time.After(time.Second):
+		t.Fatal("Expecting the waiting caller to be released")
+	}
+	_, err := g.execute(ex, `<get/>`)
+	assert.EqualError(t, err, "not coalesced", "Expecting the flight to be removed")
+}
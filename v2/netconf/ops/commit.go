@@ -0,0 +1,143 @@
+package ops
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Capabilities that determine the commit options supported by the server.
+const (
+	CapConfirmedCommit10 = "urn:ietf:params:netconf:capability:confirmed-commit:1.0"
+	CapConfirmedCommit11 = "urn:ietf:params:netconf:capability:confirmed-commit:1.1"
+	CapJunos             = "http://xml.juniper.net/netconf/junos/1.0"
+)
+
+// ErrUnsupportedCommitOption is returned by Commit when an option requires a capability the server does not
+// advertise.
+var ErrUnsupportedCommitOption = errors.New("unsupported commit option")
+
+// CommitOption configures a commit request.
+type CommitOption func(*commitConfig)
+
+type commitConfig struct {
+	confirmed      bool
+	confirmTimeout time.Duration
+	comment        string
+	synchronize    bool
+	atTime         string
+	extensions     []string
+}
+
+// Indicates that a Junos specific option has been defined, requiring a Junos commit-configuration request.
+func (c *commitConfig) isJunos() bool {
+	return c.comment != "" || c.synchronize || c.atTime != ""
+}
+
+// CommitConfirmed requests a confirmed commit, which the server will revert after timeout unless it is confirmed by a
+// subsequent commit. A zero timeout uses the server default.
+// Requires the :confirmed-commit capability, or the Junos capability.
+func CommitConfirmed(timeout time.Duration) CommitOption {
+	return func(c *commitConfig) {
+		c.confirmed, c.confirmTimeout = true, timeout
+	}
+}
+
+// CommitComment defines a comment recorded with the commit in the device commit history.
+// Requires the Junos capability.
+func CommitComment(comment string) CommitOption {
+	return func(c *commitConfig) {
+		c.comment = comment
+	}
+}
+
+// CommitSynchronize requests that the commit is synchronized to both routing engines of a dual routing engine
+// device. Requires the Junos capability.
+func CommitSynchronize() CommitOption {
+	return func(c *commitConfig) {
+		c.synchronize = true
+	}
+}
+
+// CommitAtTime schedules the commit, at a time of the form "yyyy-mm-dd hh:mm[:ss]" or "hh:mm[:ss]" in the device
+// local time zone, or "reboot" to commit when the device next reboots.
+// Requires the Junos capability.
+func CommitAtTime(at string) CommitOption {
+	return func(c *commitConfig) {
+		c.atTime = at
+	}
+}
+
+// CommitExtension appends vendor specific elements, defined as xml strings, to the commit request verbatim.
+// No capability check is applied.
+func CommitExtension(elements ...string) CommitOption {
+	return func(c *commitConfig) {
+		c.extensions = append(c.extensions, elements...)
+	}
+}
+
+func (s *sImpl) Commit(options ...CommitOption) error {
+	cfg := &commitConfig{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	req, err := createVendorCommitRequest(cfg, s.ServerCapabilities())
+	if err != nil {
+		return err
+	}
+	_, err = s.Session.Execute(req)
+	return err
+}
+
+// JunosCommitReq defines the Junos commit-configuration request, which supports the Junos commit options.
+type JunosCommitReq struct {
+	XMLName   xml.Name  `xml:"commit-configuration"`
+	Confirmed *struct{} `xml:"confirmed"`
+	// Junos expresses the confirm timeout in minutes.
+	ConfirmTimeout uint32    `xml:"confirm-timeout,omitempty"`
+	Synchronize    *struct{} `xml:"synchronize"`
+	AtTime         string    `xml:"at-time,omitempty"`
+	Log            string    `xml:"log,omitempty"`
+	Extensions     string    `xml:",innerxml"`
+}
+
+// Builds the commit request for the options, checking that the server capabilities support them.
+func createVendorCommitRequest(cfg *commitConfig, caps []string) (interface{}, error) {
+	if cfg.isJunos() {
+		if !hasCapability(caps, CapJunos) {
+			return nil, errors.Wrapf(ErrUnsupportedCommitOption, "comment, synchronize and at-time require %s", CapJunos)
+		}
+		req := &JunosCommitReq{Log: cfg.comment, AtTime: cfg.atTime, Extensions: strings.Join(cfg.extensions, "")}
+		if cfg.confirmed {
+			req.Confirmed = &struct{}{}
+			req.ConfirmTimeout = uint32((cfg.confirmTimeout + time.Minute - 1) / time.Minute)
+		}
+		if cfg.synchronize {
+			req.Synchronize = &struct{}{}
+		}
+		return req, nil
+	}
+
+	if cfg.confirmed && !hasCapability(caps, CapConfirmedCommit10, CapConfirmedCommit11, CapJunos) {
+		return nil, errors.Wrapf(ErrUnsupportedCommitOption, "confirmed commit requires %s", CapConfirmedCommit11)
+	}
+	req := createCommitRequest(cfg.confirmed, cfg.confirmTimeout)
+	req.Extensions = strings.Join(cfg.extensions, "")
+	return req, nil
+}
+
+// Determines whether any of the capabilities are present in caps, ignoring capability parameters.
+func hasCapability(caps []string, capabilities ...string) bool {
+	for _, c := range caps {
+		c = strings.SplitN(c, "?", 2)[0]
+		for _, capability := range capabilities {
+			if c == capability {
+				return true
+			}
+		}
+	}
+	return false
+}
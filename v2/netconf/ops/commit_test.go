@@ -0,0 +1,72 @@
+package ops
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestCommit(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase11})
+	mcli.On("Execute", &CommitReq{}).Return(&common.RPCReply{}, nil).Once()
+
+	assert.NoError(t, ncs.Commit())
+	mcli.AssertExpectations(t)
+}
+
+func TestCommitConfirmed(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase11, CapConfirmedCommit11 + "?module=ietf-netconf"})
+	mcli.On("Execute", &CommitReq{Confirmed: &struct{}{}, ConfirmTimeout: 120}).Return(&common.RPCReply{}, nil).Once()
+
+	assert.NoError(t, ncs.Commit(CommitConfirmed(2*time.Minute)))
+	mcli.AssertExpectations(t)
+}
+
+func TestCommitJunosOptions(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase10, CapJunos})
+
+	expected := &JunosCommitReq{Confirmed: &struct{}{}, ConfirmTimeout: 2, Synchronize: &struct{}{},
+		AtTime: "reboot", Log: "change 42", Extensions: `<force-synchronize/>`}
+	mcli.On("Execute", expected).Return(&common.RPCReply{}, nil).Once()
+
+	assert.NoError(t, ncs.Commit(CommitConfirmed(90*time.Second), CommitSynchronize(), CommitAtTime("reboot"),
+		CommitComment("change 42"), CommitExtension(`<force-synchronize/>`)))
+	mcli.AssertExpectations(t)
+
+	b, err := xml.Marshal(expected)
+	assert.NoError(t, err)
+	assert.Equal(t, `<commit-configuration><confirmed></confirmed><confirm-timeout>2</confirm-timeout>`+
+		`<synchronize></synchronize><at-time>reboot</at-time><log>change 42</log><force-synchronize/>`+
+		`</commit-configuration>`, string(b))
+}
+
+func TestCommitExtension(t *testing.T) {
+	req, err := createVendorCommitRequest(&commitConfig{extensions: []string{`<persist>id</persist>`}}, nil)
+	assert.NoError(t, err)
+
+	b, err := xml.Marshal(req)
+	assert.NoError(t, err)
+	assert.Equal(t, `<commit><persist>id</persist></commit>`, string(b))
+}
+
+func TestCommitUnsupportedOptions(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase11})
+
+	err := ncs.Commit(CommitComment("change 42"))
+	assert.True(t, errors.Is(err, ErrUnsupportedCommitOption), "Expecting unsupported option error")
+
+	err = ncs.Commit(CommitConfirmed(0))
+	assert.True(t, errors.Is(err, ErrUnsupportedCommitOption), "Expecting unsupported option error")
+
+	mcli.AssertNotCalled(t, "Execute", mock.Anything)
+}
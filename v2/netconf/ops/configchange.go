@@ -0,0 +1,175 @@
+package ops
+
+import (
+	"sync"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/pkg/errors"
+)
+
+// ErrNotModified is returned by ConfigChangeTracker.GetConfigIfChanged when the configuration has not changed since
+// it was last retrieved.
+var ErrNotModified = errors.New("configuration not modified")
+
+// ConfigVersion delivers a token identifying the version of the source configuration of a device, such as the time
+// of the last commit, where the device exposes one. An empty token indicates that the version is not known.
+type ConfigVersion func(s OpSession, source string) (string, error)
+
+// ConfigTrackerOption implements options for configuring a ConfigChangeTracker.
+type ConfigTrackerOption func(*ConfigChangeTracker)
+
+// WithConfigVersion defines the function used to determine the version of the configuration of a device before it
+// is retrieved, so that changes are detected without notifications.
+func WithConfigVersion(version ConfigVersion) ConfigTrackerOption {
+	return func(t *ConfigChangeTracker) {
+		t.version = version
+	}
+}
+
+// ConfigChangeTracker tracks changes to the configuration of devices, identified by target, so that reconciliation
+// loops can avoid repeatedly retrieving configuration that has not changed. Changes are detected from the
+// netconf-config-change events of RFC 6470 received on notification channels supplied to Watch, or by comparing the
+// versions delivered by WithConfigVersion. It is safe for concurrent use.
+type ConfigChangeTracker struct {
+	version ConfigVersion
+
+	mu      sync.Mutex
+	targets map[string]*trackedTarget
+}
+
+// Defines the changes observed to the configuration of a target.
+type trackedTarget struct {
+	// The number of channels being watched for the target.
+	watchers int
+	// Incremented whenever a channel ceases to be watched, after which changes may have been missed.
+	epoch uint64
+	// The number of changes observed to each datastore.
+	changes map[string]uint64
+	// The datastore change count, or version, at the last retrieval, keyed by source and filter.
+	retrieved map[string]retrieval
+}
+
+type retrieval struct {
+	changes uint64
+	watched bool
+	epoch   uint64
+	version string
+}
+
+// NewConfigChangeTracker delivers a new ConfigChangeTracker configured by the options.
+func NewConfigChangeTracker(opts ...ConfigTrackerOption) *ConfigChangeTracker {
+	t := &ConfigChangeTracker{targets: map[string]*trackedTarget{}}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Watch observes the notifications received from target on in, typically the channel supplied to Subscribe,
+// recording netconf-config-change events, and forwards them to the returned channel, which is closed once in is
+// closed. Changes are only detected from events while the channel is open, so configuration retrieved while no
+// channel is watched for the target is always considered modified.
+func (t *ConfigChangeTracker) Watch(target string, in <-chan *common.Notification) <-chan *common.Notification {
+	out := make(chan *common.Notification, cap(in))
+	t.mu.Lock()
+	t.target(target).watchers++
+	t.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		for n := range in {
+			if change, ok := n.Value.(*client.NetconfConfigChange); ok {
+				t.changed(target, change.Datastore)
+			}
+			out <- n
+		}
+		// Changes made while the target is not watched cannot be detected.
+		t.mu.Lock()
+		tt := t.target(target)
+		tt.watchers--
+		tt.epoch++
+		t.mu.Unlock()
+	}()
+	return out
+}
+
+// Invalidate records that the source configuration of target has changed, for example following an edit made
+// without a notification subscription.
+func (t *ConfigChangeTracker) Invalidate(target, source string) {
+	t.changed(target, source)
+}
+
+func (t *ConfigChangeTracker) changed(target, datastore string) {
+	if datastore == "" {
+		// The datastore defaults to running, see RFC 6470.
+		datastore = RunningCfg
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.target(target).changes[datastore]++
+}
+
+// Delivers the state of the target, which must be called with the lock held.
+func (t *ConfigChangeTracker) target(target string) *trackedTarget {
+	tt, ok := t.targets[target]
+	if !ok {
+		tt = &trackedTarget{changes: map[string]uint64{}, retrieved: map[string]retrieval{}}
+		t.targets[target] = tt
+	}
+	return tt
+}
+
+// GetConfigIfChanged issues a get-config request on s, the session with target, with the supplied subtree filter and
+// source, storing the response in the result as for OpSession.GetConfigSubtree, unless the configuration has not
+// changed since it was last retrieved with the same filter and source, in which case ErrNotModified is returned and
+// the result is not updated.
+// The configuration is considered unchanged if the version delivered by WithConfigVersion is unchanged, or, if no
+// version is delivered, if the target has been watched since the last retrieval and no change has been observed; as
+// events only report changes to the running and startup datastores, other sources are always retrieved.
+func (t *ConfigChangeTracker) GetConfigIfChanged(s OpSession, target string, filter interface{}, source string,
+	result interface{},
+) error {
+	key, ok := requestKey(filter)
+	if !ok {
+		return errors.New("unsupported filter")
+	}
+	key = source + "\x00" + key
+
+	var version string
+	if t.version != nil {
+		var err error
+		if version, err = t.version(s, source); err != nil {
+			return errors.Wrap(err, "failed to determine configuration version")
+		}
+	}
+
+	t.mu.Lock()
+	tt := t.target(target)
+	// Only changes to the running and startup datastores are reported by events.
+	watched := tt.watchers > 0 && (source == RunningCfg || source == StartupCfg)
+	current := retrieval{changes: tt.changes[source], watched: watched, epoch: tt.epoch, version: version}
+	last, found := tt.retrieved[key]
+	t.mu.Unlock()
+
+	if found && unchanged(last, current) {
+		return ErrNotModified
+	}
+
+	if err := s.GetConfigSubtree(filter, source, result); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tt.retrieved[key] = current
+	return nil
+}
+
+// Determines whether the configuration is unchanged since the last retrieval.
+func unchanged(last, current retrieval) bool {
+	if current.version != "" {
+		return current.version == last.version
+	}
+	return last.watched && current.watched && last.epoch == current.epoch && last.changes == current.changes
+}
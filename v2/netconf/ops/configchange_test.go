@@ -0,0 +1,93 @@
+package ops
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const trackedFilter = `<interfaces/>`
+
+func TestGetConfigIfChangedWatched(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	reply := &common.RPCReply{Data: `<data><interfaces/></data>`}
+	mcli.On("Execute", createGetConfigSubtreeRequest(trackedFilter, RunningCfg)).Return(reply, nil)
+	mcli.On("Execute", createGetConfigSubtreeRequest(trackedFilter, CandidateCfg)).Return(reply, nil)
+
+	tracker := NewConfigChangeTracker()
+	in := make(chan *common.Notification, 1)
+	out := tracker.Watch("device1", in)
+
+	var result string
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	assert.Equal(t, `<interfaces/>`, result)
+	assert.ErrorIs(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result), ErrNotModified)
+	mcli.AssertNumberOfCalls(t, "Execute", 1)
+
+	in <- &common.Notification{Value: &client.NetconfConfigChange{}}
+	assert.NotNil(t, <-out, "Expecting the notification to be forwarded")
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	assert.ErrorIs(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result), ErrNotModified)
+	mcli.AssertNumberOfCalls(t, "Execute", 2)
+
+	// Changes to the candidate are not reported by events.
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, CandidateCfg, &result))
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, CandidateCfg, &result))
+	mcli.AssertNumberOfCalls(t, "Execute", 4)
+
+	tracker.Invalidate("device1", RunningCfg)
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	mcli.AssertNumberOfCalls(t, "Execute", 5)
+
+	// Once the channel is closed, changes may be missed.
+	close(in)
+	_, open := <-out
+	assert.False(t, open)
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	mcli.AssertNumberOfCalls(t, "Execute", 7)
+}
+
+func TestGetConfigIfChangedUnwatched(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetConfigSubtreeRequest(trackedFilter, RunningCfg)).
+		Return(&common.RPCReply{Data: `<data><interfaces/></data>`}, nil)
+
+	tracker := NewConfigChangeTracker()
+	var result string
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	mcli.AssertNumberOfCalls(t, "Execute", 2)
+}
+
+func TestGetConfigIfChangedVersion(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetConfigSubtreeRequest(trackedFilter, RunningCfg)).
+		Return(&common.RPCReply{Data: `<data><interfaces/></data>`}, nil)
+
+	version := "2024-01-01 10:00:00"
+	var versionErr error
+	tracker := NewConfigChangeTracker(WithConfigVersion(func(s OpSession, source string) (string, error) {
+		assert.Equal(t, RunningCfg, source)
+		return version, versionErr
+	}))
+
+	var result string
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	assert.ErrorIs(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result), ErrNotModified)
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device2", trackedFilter, RunningCfg, &result),
+		"Expecting targets to be tracked independently")
+	mcli.AssertNumberOfCalls(t, "Execute", 2)
+
+	version = "2024-01-01 11:00:00"
+	assert.NoError(t, tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result))
+	mcli.AssertNumberOfCalls(t, "Execute", 3)
+
+	versionErr = errors.New("rpc failed")
+	err := tracker.GetConfigIfChanged(ncs, "device1", trackedFilter, RunningCfg, &result)
+	assert.EqualError(t, err, "failed to determine configuration version: rpc failed")
+}
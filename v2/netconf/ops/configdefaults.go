@@ -0,0 +1,147 @@
+package ops
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// ConfigDefaults defines the schema default values of configuration leaves, keyed by the path of local element names
+// from the top-level element of the configuration, for example "interfaces/interface/mtu". Namespaces are ignored.
+type ConfigDefaults map[string]string
+
+// DefaultsMode defines how leaves holding their schema default value are treated in get-config results.
+type DefaultsMode int
+
+const (
+	// DefaultsTrim removes leaves holding their default value, as for the with-defaults trim mode of RFC 6243.
+	DefaultsTrim DefaultsMode = iota
+	// DefaultsTag annotates leaves holding their default value with the default attribute of RFC 6243, as for the
+	// with-defaults report-all-tagged mode.
+	DefaultsTag
+)
+
+// WithDefaultsNamespace is the namespace of the default attribute added by DefaultsTag.
+const WithDefaultsNamespace = "urn:ietf:params:xml:ns:netconf:default:1.0"
+
+// WithConfigDefaults causes the results of the GetConfig... methods to be post-processed against the schema
+// defaults, trimming or tagging the leaves that hold their default value, so that configuration retrieved from
+// devices that lack with-defaults support can be compared consistently with configuration from those that have it.
+// A leaf holds its default value if its text, ignoring surrounding whitespace, equals the default.
+func WithConfigDefaults(defaults ConfigDefaults, mode DefaultsMode) SessionOption {
+	return func(so *sessionOptions) {
+		so.defaults = &configDefaults{values: defaults, mode: mode}
+	}
+}
+
+type configDefaults struct {
+	values ConfigDefaults
+	mode   DefaultsMode
+}
+
+// Issues the get-config request, applying any configured defaults processing to the reply.
+func (s *sImpl) handleGetConfigRequest(req common.Request, result interface{}) error {
+	reply, err := s.Session.Execute(req)
+	if err != nil {
+		return err
+	}
+	if s.defaults != nil && len(s.defaults.values) > 0 {
+		processed := *reply
+		if processed.Data, err = s.defaults.apply(reply.Data); err != nil {
+			return err
+		}
+		reply = &processed
+	}
+	return s.handleGetReply(reply, result)
+}
+
+// Trims or tags the leaves within the reply data that hold their default value.
+func (cd *configDefaults) apply(data string) (string, error) {
+	type element struct {
+		path string
+		// The offsets of the start of the element, and the end of its start tag.
+		start, tagEnd int64
+		children      bool
+		text          strings.Builder
+	}
+	type edit struct {
+		start, end int64
+		insert     string
+	}
+	var edits []edit
+	var stack []*element
+
+	d := xml.NewDecoder(strings.NewReader(data))
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &element{start: offset, tagEnd: d.InputOffset()}
+			switch {
+			case len(stack) > 0:
+				stack[len(stack)-1].children = true
+				e.path = joinPath(stack[len(stack)-1].path, t.Name.Local)
+			case t.Name.Local != "data":
+				// Tolerate configuration that is not wrapped in a <data> element.
+				e.path = t.Name.Local
+			}
+			stack = append(stack, e)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			e := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			def, ok := cd.values[e.path]
+			if e.children || !ok || strings.TrimSpace(e.text.String()) != def {
+				continue
+			}
+			if cd.mode == DefaultsTrim {
+				edits = append(edits, edit{start: e.start, end: d.InputOffset()})
+				continue
+			}
+			// Insert the attribute before the end of the start tag, which may be self-closing.
+			pos := e.tagEnd - 1
+			if strings.HasSuffix(data[:e.tagEnd], "/>") {
+				pos--
+			}
+			edits = append(edits, edit{start: pos, end: pos,
+				insert: ` xmlns:wd="` + WithDefaultsNamespace + `" wd:default="true"`})
+		}
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	sb := &strings.Builder{}
+	var prev int64
+	for _, e := range edits {
+		if e.start < prev {
+			continue
+		}
+		sb.WriteString(data[prev:e.start])
+		sb.WriteString(e.insert)
+		prev = e.end
+	}
+	sb.WriteString(data[prev:])
+	return sb.String(), nil
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "/" + name
+}
@@ -0,0 +1,66 @@
+package ops
+
+import (
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/mocks"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const interfacesConfig = `<data><interfaces xmlns="urn:example:if"><interface><name>eth0</name><mtu>1500</mtu>` +
+	`<enabled>true</enabled></interface><interface><name>eth1</name><mtu>9000</mtu><enabled/></interface>` +
+	`</interfaces></data>`
+
+var interfacesDefaults = ConfigDefaults{
+	"interfaces/interface/mtu":     "1500",
+	"interfaces/interface/enabled": "",
+	"interfaces/interface":         "",
+}
+
+func newOpsSessionWithConfig(data string, defaults *configDefaults) OpSession {
+	mockClient := &mocks.OpSession{}
+	mockClient.On("Execute", createGetConfigSubtreeRequest(`<interfaces/>`, "running")).
+		Return(&common.RPCReply{Data: data}, nil)
+	return &sImpl{Session: mockClient, defaults: defaults}
+}
+
+func TestGetConfigDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults *configDefaults
+		expected string
+	}{
+		{"no defaults", nil, interfacesConfig[len("<data>") : len(interfacesConfig)-len("</data>")]},
+		{"trim", &configDefaults{values: interfacesDefaults, mode: DefaultsTrim},
+			`<interfaces xmlns="urn:example:if"><interface><name>eth0</name>` +
+				`<enabled>true</enabled></interface><interface><name>eth1</name><mtu>9000</mtu></interface></interfaces>`},
+		{"tag", &configDefaults{values: interfacesDefaults, mode: DefaultsTag},
+			`<interfaces xmlns="urn:example:if"><interface><name>eth0</name>` +
+				`<mtu xmlns:wd="` + WithDefaultsNamespace + `" wd:default="true">1500</mtu><enabled>true</enabled>` +
+				`</interface><interface><name>eth1</name><mtu>9000</mtu>` +
+				`<enabled xmlns:wd="` + WithDefaultsNamespace + `" wd:default="true"/></interface></interfaces>`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ncs := newOpsSessionWithConfig(interfacesConfig, tc.defaults)
+			var result string
+			assert.NoError(t, ncs.GetConfigSubtree(`<interfaces/>`, "running", &result))
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestGetConfigDefaultsInvalidData(t *testing.T) {
+	ncs := newOpsSessionWithConfig(`<data><interfaces><mtu>1500</interfaces></data>`,
+		&configDefaults{values: interfacesDefaults, mode: DefaultsTrim})
+	var result string
+	assert.Error(t, ncs.GetConfigSubtree(`<interfaces/>`, "running", &result))
+}
+
+func TestWithConfigDefaults(t *testing.T) {
+	so := &sessionOptions{}
+	WithConfigDefaults(interfacesDefaults, DefaultsTag)(so)
+	assert.Equal(t, &configDefaults{values: interfacesDefaults, mode: DefaultsTag}, so.defaults)
+}
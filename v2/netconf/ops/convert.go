@@ -0,0 +1,133 @@
+package ops
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Conversion of reply data to JSON and YAML, for delivery to the caller of the Get... methods.
+
+// JSONResult may be supplied, by address, as the result of a Get... method, to receive the reply data as JSON.
+// The reply data is converted by the rules described for XMLToValue, and delivered with object members ordered by
+// name, so that equivalent replies deliver identical results.
+type JSONResult string
+
+// YAMLResult may be supplied, by address, as the result of a Get... method, to receive the reply data as YAML.
+// The reply data is converted by the rules described for XMLToValue, with mapping keys ordered by name.
+type YAMLResult string
+
+// XMLToValue converts the children of the root element of an xml document to a map, suitable for encoding as JSON or
+// YAML, applying the following rules:
+//   - each element is delivered as a member named by its local name; elements with the same name and parent are
+//     delivered as a list, in document order.
+//   - an element that contains text alone is delivered as a string, and an empty element as nil.
+//   - an element with child elements or attributes is delivered as a map, in which attributes are delivered as
+//     members named by the attribute's local name prefixed by '@', and any text that is not whitespace as a member
+//     named "#text". Namespace declarations are omitted.
+//
+// All values are delivered as strings, as the types of the values are not known without the schema.
+// The address of a map[string]interface{} may be supplied as the result of a Get... method, to receive the reply data
+// converted by these rules, so that tools can consume data without defining structs for each model.
+func XMLToValue(content string) (map[string]interface{}, error) {
+	d := xml.NewDecoder(strings.NewReader(content))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return map[string]interface{}{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := decodeValue(d, &start)
+			if err != nil {
+				return nil, err
+			}
+			if m, ok := value.(map[string]interface{}); ok {
+				return m, nil
+			}
+			return map[string]interface{}{}, nil
+		}
+	}
+}
+
+// Decodes the content of the element introduced by start, having consumed start.
+func decodeValue(d *xml.Decoder, start *xml.StartElement) (interface{}, error) {
+	members := map[string]interface{}{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		members["@"+attr.Name.Local] = attr.Value
+	}
+	attrs := len(members) > 0
+	children := false
+
+	var text strings.Builder
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := decodeValue(d, &t)
+			if err != nil {
+				return nil, err
+			}
+			addMember(members, t.Name.Local, value)
+			children = true
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			switch {
+			case !children && !attrs && text.Len() == 0:
+				return nil, nil
+			case !children && !attrs:
+				return text.String(), nil
+			}
+			if s := strings.TrimSpace(text.String()); s != "" {
+				members["#text"] = s
+			}
+			return members, nil
+		}
+	}
+}
+
+// Adds a member to the map, converting the member to a list if it is repeated.
+func addMember(members map[string]interface{}, name string, value interface{}) {
+	existing, ok := members[name]
+	if !ok {
+		members[name] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		members[name] = append(list, value)
+	} else {
+		members[name] = []interface{}{existing, value}
+	}
+}
+
+// Converts the reply data to JSON.
+func toJSON(content string) (string, error) {
+	value, err := XMLToValue(content)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+// Converts the reply data to YAML.
+func toYAML(content string) (string, error) {
+	value, err := XMLToValue(content)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(value)
+	return string(b), err
+}
@@ -0,0 +1,95 @@
+package ops
+
+import (
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	assert "github.com/stretchr/testify/require"
+)
+
+const interfacesData = `<data xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+  <interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces" xmlns:ianaift="urn:ietf:params:xml:ns:yang:iana-if-type">
+    <interface status="up">
+      <name>eth0</name>
+      <type>ianaift:ethernetCsmacd</type>
+      <enabled/>
+    </interface>
+    <interface>
+      <name>eth1</name>
+      <description> uplink </description>
+    </interface>
+  </interfaces>
+  <note lang="en">text<b>bold</b></note>
+</data>`
+
+func TestXMLToValue(t *testing.T) {
+	value, err := XMLToValue(interfacesData)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"interfaces": map[string]interface{}{
+			"interface": []interface{}{
+				map[string]interface{}{"@status": "up", "name": "eth0", "type": "ianaift:ethernetCsmacd", "enabled": nil},
+				map[string]interface{}{"name": "eth1", "description": " uplink "},
+			},
+		},
+		"note": map[string]interface{}{"@lang": "en", "#text": "text", "b": "bold"},
+	}, value)
+
+	value, err = XMLToValue(`<data/>`)
+	assert.NoError(t, err)
+	assert.Empty(t, value)
+
+	_, err = XMLToValue(`<data><unterminated></data>`)
+	assert.Error(t, err)
+}
+
+func TestGetSubtreeToJSON(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetSubtreeRequest(`<interfaces/>`)).
+		Return(&common.RPCReply{Data: interfacesData}, nil)
+
+	var result JSONResult
+	err := ncs.GetSubtree(`<interfaces/>`, &result)
+	assert.NoError(t, err, "Not expecting call to fail")
+	assert.Equal(t, JSONResult(`{"interfaces":{"interface":[`+
+		`{"@status":"up","enabled":null,"name":"eth0","type":"ianaift:ethernetCsmacd"},`+
+		`{"description":" uplink ","name":"eth1"}]},`+
+		`"note":{"#text":"text","@lang":"en","b":"bold"}}`), result)
+}
+
+func TestGetConfigXpathToYAML(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetConfigXpathRequest(`/interfaces`, "running", nil)).
+		Return(&common.RPCReply{Data: `<data><interfaces><interface><name>eth0</name><mtu>1500</mtu></interface></interfaces></data>`}, nil)
+
+	var result YAMLResult
+	err := ncs.GetConfigXpath(`/interfaces`, nil, "running", &result)
+	assert.NoError(t, err, "Not expecting call to fail")
+	assert.Equal(t, YAMLResult("interfaces:\n    interface:\n        mtu: \"1500\"\n        name: eth0\n"), result)
+}
+
+func TestGetSubtreeToJSONInvalidReply(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetSubtreeRequest(`<interfaces/>`)).
+		Return(&common.RPCReply{Data: `<data><interfaces></data>`}, nil)
+
+	var result JSONResult
+	err := ncs.GetSubtree(`<interfaces/>`, &result)
+	assert.Error(t, err, "Expecting invalid reply data to be rejected")
+}
+
+func TestGetXpathToMap(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetXpathRequest(`/interfaces`, nil)).
+		Return(&common.RPCReply{Data: interfacesData}, nil)
+
+	var result map[string]interface{}
+	err := ncs.GetXpath(`/interfaces`, nil, &result)
+	assert.NoError(t, err, "Not expecting call to fail")
+	interfaces := result["interfaces"].(map[string]interface{})["interface"].([]interface{})
+	assert.Len(t, interfaces, 2)
+	assert.Equal(t, map[string]interface{}{
+		"@status": "up", "name": "eth0", "type": "ianaift:ethernetCsmacd", "enabled": nil,
+	}, interfaces[0])
+	assert.Equal(t, "bold", result["note"].(map[string]interface{})["b"])
+}
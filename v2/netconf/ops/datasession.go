@@ -0,0 +1,507 @@
+package ops
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/target"
+	"github.com/pkg/errors"
+)
+
+// DataSession defines the data operations that can be carried out on a device over either netconf or restconf, so
+// that applications can manage fleets of devices with mixed capabilities with one code path.
+//
+// Data nodes are identified by a path in the form of a restconf data resource identifier, such as
+// "ietf-interfaces:interfaces/interface=eth0". The first segment, and any segment that is defined by a different
+// module from its parent, is qualified by the name of the module that defines it. A segment may only identify a list
+// entry by its key values if the keys of the list are defined by WithDataListKeys.
+type DataSession interface {
+	// Get retrieves the configuration and state data identified by path and stores it in the result, which should be
+	// the address of one of the types accepted by OpSession.GetSubtree.
+	Get(path string, result interface{}) error
+
+	// Put replaces the configuration data identified by path with config. config is an xml string or []byte, or a
+	// struct with xml tags, whose root element is the data node identified by path.
+	// If path identifies a list defined by WithDataListKeys, it must identify an entry of the list by its keys.
+	Put(path string, config interface{}) error
+
+	// Patch merges config, defined as for Put, into the configuration data identified by path.
+	Patch(path string, config interface{}) error
+
+	io.Closer
+}
+
+// ModuleNamespaces maps the names of yang modules to their xml namespaces, as reported by the yang library of a
+// device. The namespace of each module named by a path is required to build netconf requests, and to qualify the
+// configuration supplied to restconf.
+type ModuleNamespaces map[string]string
+
+// SchemaNamespaces delivers the namespaces of the modules described by schemas, as delivered by
+// OpSession.GetSchemas.
+func SchemaNamespaces(schemas []Schema) ModuleNamespaces {
+	mn := ModuleNamespaces{}
+	for _, s := range schemas {
+		if s.Namespace != "" {
+			mn[s.Identifier] = s.Namespace
+		}
+	}
+	return mn
+}
+
+// DataSessionOption implements options for configuring data sessions.
+type DataSessionOption func(*dataSessionOptions)
+
+type dataSessionOptions struct {
+	netconf []SessionOption
+	client  *http.Client
+	// Whether the client was created by the session, rather than supplied by WithHTTPClient.
+	ownsClient bool
+	listKeys   map[string][]string
+}
+
+// DefaultRestconfTimeout is the time limit of each restconf request issued by a data session, unless a client is
+// supplied by WithHTTPClient.
+const DefaultRestconfTimeout = 30 * time.Second
+
+// WithNetconfOptions defines the options used to establish a netconf session by NewDataSessionForTarget.
+func WithNetconfOptions(opts ...SessionOption) DataSessionOption {
+	return func(o *dataSessionOptions) {
+		o.netconf = opts
+	}
+}
+
+// WithHTTPClient defines the client used to issue restconf requests; its connections are not closed when the session
+// is closed.
+// Default is a client owned by the session, with a time limit of DefaultRestconfTimeout.
+func WithHTTPClient(c *http.Client) DataSessionOption {
+	return func(o *dataSessionOptions) {
+		o.client = c
+	}
+}
+
+// WithDataListKeys defines the key leaves of lists, keyed by the path of local element names from the top-level data
+// node, for example "interfaces/interface", as for ListKeys.
+// Default is no lists, so that no path segment may identify a list entry.
+func WithDataListKeys(keys map[string][]string) DataSessionOption {
+	return func(o *dataSessionOptions) {
+		o.listKeys = keys
+	}
+}
+
+// NewDataSessionForTarget establishes a data session with the target, using netconf if the target supports it, and
+// falling back to restconf if netconf is not supported or a netconf session cannot be established. The restconf API
+// root is expected at /restconf on the target's restconf endpoint, using https; it is requested to verify that
+// restconf is available.
+// Credentials for each protocol are resolved by r.
+func NewDataSessionForTarget(ctx context.Context, t *target.Target, r target.CredentialResolver,
+	namespaces ModuleNamespaces, opts ...DataSessionOption,
+) (DataSession, error) {
+	var ncErr error
+	if _, ok := t.Preferred(target.NETCONF); ok {
+		s, err := NewSessionForTarget(ctx, t, r, dataSessionConfig(opts).netconf...)
+		if err == nil {
+			return NewNetconfDataSession(s, namespaces, opts...), nil
+		}
+		ncErr = err
+	}
+	if _, ok := t.Preferred(target.RESTCONF); !ok {
+		if ncErr == nil {
+			ncErr = errors.New("target supports neither netconf nor restconf")
+		}
+		return nil, ncErr
+	}
+
+	creds, err := target.ResolveCredentials(ctx, r, t, target.RESTCONF)
+	if err == nil {
+		rs := newRestconfDataSession("https://"+t.Endpoint(target.RESTCONF)+"/restconf", creds, namespaces, opts)
+		if err = rs.probe(ctx); err == nil {
+			return rs, nil
+		}
+	}
+	if ncErr != nil {
+		return nil, errors.Wrapf(err, "restconf fallback failed (netconf: %v)", ncErr)
+	}
+	return nil, err
+}
+
+func dataSessionConfig(opts []DataSessionOption) *dataSessionOptions {
+	o := &dataSessionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.client == nil {
+		o.client, o.ownsClient = newRestconfClient(), true
+	}
+	return o
+}
+
+// Delivers a client with its own connections, so that they can be closed with the session.
+func newRestconfClient() *http.Client {
+	c := &http.Client{Timeout: DefaultRestconfTimeout}
+	if t, ok := http.DefaultTransport.(*http.Transport); ok {
+		c.Transport = t.Clone()
+	}
+	return c
+}
+
+// NewNetconfDataSession delivers a data session that issues requests on s. Get is issued as a get request with a
+// subtree filter, and Put and Patch as edit-config requests on the running datastore, with the replace and merge
+// operations respectively. Options that apply to restconf are ignored.
+func NewNetconfDataSession(s OpSession, namespaces ModuleNamespaces, opts ...DataSessionOption) DataSession {
+	o := &dataSessionOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &netconfDataSession{s: s, namespaces: namespaces, listKeys: o.listKeys}
+}
+
+type netconfDataSession struct {
+	s          OpSession
+	namespaces ModuleNamespaces
+	listKeys   map[string][]string
+}
+
+func (n *netconfDataSession) Get(path string, result interface{}) error {
+	segments, err := parseDataPath(path, n.namespaces, n.listKeys, true)
+	if err != nil {
+		return err
+	}
+	return n.s.GetSubtree(nestElements(segments, nil), result)
+}
+
+func (n *netconfDataSession) Put(path string, config interface{}) error {
+	return n.edit(path, config, "replace")
+}
+
+func (n *netconfDataSession) Patch(path string, config interface{}) error {
+	return n.edit(path, config, "")
+}
+
+func (n *netconfDataSession) edit(path string, config interface{}, operation string) error {
+	segments, err := parseDataPath(path, n.namespaces, n.listKeys, true)
+	if err != nil {
+		return err
+	}
+	if operation == "replace" {
+		if err = requireListEntry(path, segments); err != nil {
+			return err
+		}
+	}
+	cfg, err := dataConfig(config, segments, operation)
+	if err != nil {
+		return err
+	}
+	return n.s.EditConfigCfg(RunningCfg, nestElements(segments[:len(segments)-1], cfg))
+}
+
+func (n *netconfDataSession) Close() error {
+	n.s.Close()
+	return nil
+}
+
+// RestconfError is returned by a restconf data session when a request fails.
+type RestconfError struct {
+	// The http status code of the response.
+	StatusCode int
+	// The errors reported in the response, if any.
+	Errors []common.RPCError
+}
+
+func (e *RestconfError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("restconf request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("restconf request failed with status %d: %s", e.StatusCode, e.Errors[0].Message)
+}
+
+// The media type of restconf data encoded as xml.
+const yangDataXML = "application/yang-data+xml"
+
+// NewRestconfDataSession delivers a data session that issues restconf requests to the API root, such as
+// https://device/restconf, authenticated by the user name and password of creds, if defined. Data is exchanged
+// encoded as xml, and the configuration supplied to Put and Patch is qualified by the namespace of the data node if
+// the namespace of its module is defined by namespaces.
+func NewRestconfDataSession(root string, creds *target.Credentials, namespaces ModuleNamespaces,
+	opts ...DataSessionOption,
+) DataSession {
+	return newRestconfDataSession(root, creds, namespaces, opts)
+}
+
+func newRestconfDataSession(root string, creds *target.Credentials, namespaces ModuleNamespaces,
+	opts []DataSessionOption,
+) *restconfDataSession {
+	o := dataSessionConfig(opts)
+	return &restconfDataSession{
+		root: strings.TrimSuffix(root, "/"), creds: creds, namespaces: namespaces,
+		client: o.client, ownsClient: o.ownsClient, listKeys: o.listKeys,
+	}
+}
+
+type restconfDataSession struct {
+	root       string
+	creds      *target.Credentials
+	namespaces ModuleNamespaces
+	client     *http.Client
+	ownsClient bool
+	listKeys   map[string][]string
+}
+
+func (r *restconfDataSession) Get(path string, result interface{}) error {
+	if _, err := parseDataPath(path, r.namespaces, r.listKeys, false); err != nil {
+		return err
+	}
+	status, body, err := r.do(context.Background(), http.MethodGet, r.root+"/data/"+path, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound || status == http.StatusNoContent {
+		body = nil
+	} else if status != http.StatusOK {
+		return restconfError(status, body)
+	}
+	return decodeData("<data>"+string(body)+"</data>", result, false)
+}
+
+func (r *restconfDataSession) Put(path string, config interface{}) error {
+	return r.edit(http.MethodPut, path, config)
+}
+
+func (r *restconfDataSession) Patch(path string, config interface{}) error {
+	return r.edit(http.MethodPatch, path, config)
+}
+
+func (r *restconfDataSession) edit(method, path string, config interface{}) error {
+	segments, err := parseDataPath(path, r.namespaces, r.listKeys, false)
+	if err != nil {
+		return err
+	}
+	if method == http.MethodPut {
+		if err = requireListEntry(path, segments); err != nil {
+			return err
+		}
+	}
+	cfg, err := dataConfig(config, segments, "")
+	if err != nil {
+		return err
+	}
+	status, body, err := r.do(context.Background(), method, r.root+"/data/"+path, cfg)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		return restconfError(status, body)
+	}
+	return nil
+}
+
+// Requests the API root, to verify that restconf is available.
+func (r *restconfDataSession) probe(ctx context.Context) error {
+	status, body, err := r.do(ctx, http.MethodGet, r.root, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return restconfError(status, body)
+	}
+	return nil
+}
+
+func (r *restconfDataSession) Close() error {
+	if r.ownsClient {
+		r.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Issues a restconf request, delivering the status and body of the response.
+func (r *restconfDataSession) do(ctx context.Context, method, url string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", yangDataXML)
+	if body != nil {
+		req.Header.Set("Content-Type", yangDataXML)
+	}
+	if r.creds != nil && r.creds.Username != "" {
+		req.SetBasicAuth(r.creds.Username, r.creds.Password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "restconf request failed")
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to read restconf response")
+	}
+	return resp.StatusCode, b, nil
+}
+
+// Builds the error reported by a failed restconf request from the errors element of the response, if any.
+func restconfError(status int, body []byte) error {
+	rerrs := &struct {
+		Errors []common.RPCError `xml:"error"`
+	}{}
+	_ = xml.Unmarshal(body, rerrs)
+	return &RestconfError{StatusCode: status, Errors: rerrs.Errors}
+}
+
+// Defines a segment of a data path.
+type dataPathSegment struct {
+	name string
+	// The namespace of the module that qualifies the segment, if any.
+	namespace string
+	// The namespace of the data node identified by the segment, which is inherited from its parent if unqualified.
+	nodeNamespace string
+	// The key leaves of the list identified by the segment, if any, and the key values of the list entry, if
+	// identified.
+	keys   []string
+	values []string
+}
+
+// Parses a data path, resolving the namespaces of qualifying modules; unknown modules are rejected if required.
+// Segments may identify entries of the lists defined by listKeys.
+func parseDataPath(path string, namespaces ModuleNamespaces, listKeys map[string][]string, required bool,
+) ([]dataPathSegment, error) {
+	var segments []dataPathSegment
+	var inherited, schemaPath string
+	for i, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		segment := s
+		var values []string
+		if eq := strings.Index(s, "="); eq >= 0 {
+			for _, v := range strings.Split(s[eq+1:], ",") {
+				value, err := url.PathUnescape(v)
+				if err != nil {
+					return nil, errors.Errorf("data path %q: invalid key value %q", path, v)
+				}
+				values = append(values, value)
+			}
+			s = s[:eq]
+		}
+		if s == "" {
+			return nil, errors.Errorf("data path %q: invalid segment %q", path, segment)
+		}
+		seg := dataPathSegment{name: s, nodeNamespace: inherited}
+		if colon := strings.Index(s, ":"); colon >= 0 {
+			module := s[:colon]
+			seg.name = s[colon+1:]
+			seg.namespace = namespaces[module]
+			if seg.namespace == "" && required {
+				return nil, errors.Errorf("data path %q: unknown module %q", path, module)
+			}
+			seg.nodeNamespace = seg.namespace
+		} else if i == 0 {
+			return nil, errors.Errorf("data path %q: first segment is not qualified by a module", path)
+		}
+		if i > 0 {
+			schemaPath += "/"
+		}
+		schemaPath += seg.name
+		seg.keys = listKeys[schemaPath]
+		if values != nil && len(values) != len(seg.keys) {
+			return nil, errors.Errorf("data path %q: segment %q does not identify an entry of a list with %d keys",
+				path, segment, len(seg.keys))
+		}
+		seg.values = values
+		inherited = seg.nodeNamespace
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// Rejects a path that identifies a list, rather than one of its entries, as the target of a replacement.
+func requireListEntry(path string, segments []dataPathSegment) error {
+	if seg := segments[len(segments)-1]; len(seg.keys) > 0 && seg.values == nil {
+		return errors.Errorf("data path %q: list %s is replaced without identifying an entry by its keys", path,
+			seg.name)
+	}
+	return nil
+}
+
+// Nests content within the elements identified by segments, along with the key leaves of identified list entries.
+func nestElements(segments []dataPathSegment, content []byte) string {
+	var sb strings.Builder
+	for _, seg := range segments {
+		if seg.namespace != "" {
+			fmt.Fprintf(&sb, "<%s xmlns=%q>", seg.name, seg.namespace)
+		} else {
+			fmt.Fprintf(&sb, "<%s>", seg.name)
+		}
+		for i, value := range seg.values {
+			fmt.Fprintf(&sb, "<%s>", seg.keys[i])
+			_ = xml.EscapeText(&sb, []byte(value))
+			fmt.Fprintf(&sb, "</%s>", seg.keys[i])
+		}
+	}
+	sb.Write(content)
+	for i := len(segments) - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "</%s>", segments[i].name)
+	}
+	return sb.String()
+}
+
+// Delivers the xml of config, declaring the namespace of the data node identified by segments on the root element,
+// unless a default namespace is already declared, along with the netconf edit operation, if any.
+func dataConfig(config interface{}, segments []dataPathSegment, operation string) ([]byte, error) {
+	var b []byte
+	switch c := config.(type) {
+	case string:
+		b = []byte(c)
+	case []byte:
+		b = c
+	default:
+		var err error
+		if b, err = xml.Marshal(config); err != nil {
+			return nil, errors.Wrap(err, "failed to marshal configuration")
+		}
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if err != nil {
+			return nil, errors.Wrap(err, "configuration has no root element")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if name := segments[len(segments)-1].name; start.Name.Local != name {
+			return nil, errors.Errorf("configuration root element <%s> does not match data node %s", start.Name.Local,
+				name)
+		}
+
+		var attrs string
+		if ns := segments[len(segments)-1].nodeNamespace; ns != "" && !hasDefaultNamespace(start) {
+			attrs += fmt.Sprintf(" xmlns=%q", ns)
+		}
+		if operation != "" {
+			attrs += fmt.Sprintf(" xmlns:nc=%q nc:operation=%q", common.NetconfNS, operation)
+		}
+		// Insert the attributes after the element name.
+		at := int(offset) + len("<") + len(start.Name.Local)
+		if start.Name.Space != "" {
+			at += len(start.Name.Space) + len(":")
+		}
+		return append(append(append([]byte{}, b[:at]...), attrs...), b[at:]...), nil
+	}
+}
+
+func hasDefaultNamespace(start xml.StartElement) bool {
+	for _, a := range start.Attr {
+		if a.Name.Space == "" && a.Name.Local == "xmlns" {
+			return true
+		}
+	}
+	return start.Name.Space != ""
+}
@@ -0,0 +1,212 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/target"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const interfacesNS = "urn:ietf:params:xml:ns:yang:ietf-interfaces"
+
+var testNamespaces = ModuleNamespaces{"ietf-interfaces": interfacesNS, "ietf-ip": "urn:ietf:params:xml:ns:yang:ietf-ip"}
+
+var testListKeys = WithDataListKeys(map[string][]string{"interfaces/interface": {"name"}})
+
+func TestSchemaNamespaces(t *testing.T) {
+	mn := SchemaNamespaces([]Schema{
+		{Identifier: "ietf-interfaces", Namespace: interfacesNS},
+		{Identifier: "no-namespace"},
+	})
+	assert.Equal(t, ModuleNamespaces{"ietf-interfaces": interfacesNS}, mn)
+}
+
+func TestNetconfDataSessionGet(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetSubtreeRequest(
+		`<interfaces xmlns="`+interfacesNS+`"><interface><ipv4 xmlns="urn:ietf:params:xml:ns:yang:ietf-ip"></ipv4>`+
+			`</interface></interfaces>`)).
+		Return(&common.RPCReply{Data: `<data><interfaces xmlns="` + interfacesNS + `"/></data>`}, nil)
+
+	ds := NewNetconfDataSession(ncs, testNamespaces)
+	var result string
+	err := ds.Get("ietf-interfaces:interfaces/interface/ietf-ip:ipv4", &result)
+	assert.NoError(t, err)
+	assert.Equal(t, `<interfaces xmlns="`+interfacesNS+`"/>`, result)
+	mcli.AssertExpectations(t)
+}
+
+func TestNetconfDataSessionGetListEntry(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createGetSubtreeRequest(
+		`<interfaces xmlns="`+interfacesNS+`"><interface><name>eth0/1 &amp; 2</name>`+
+			`<ipv4 xmlns="urn:ietf:params:xml:ns:yang:ietf-ip"></ipv4></interface></interfaces>`)).
+		Return(&common.RPCReply{Data: `<data/>`}, nil)
+
+	ds := NewNetconfDataSession(ncs, testNamespaces, testListKeys)
+	var result string
+	assert.NoError(t, ds.Get("ietf-interfaces:interfaces/interface=eth0%2F1%20&%202/ietf-ip:ipv4", &result))
+	mcli.AssertExpectations(t)
+}
+
+func TestNetconfDataSessionPutAndPatch(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("Execute", createEditConfigRequest(RunningCfg, Cfg(`<interfaces xmlns="`+interfacesNS+`">`+
+		`<interface xmlns="`+interfacesNS+`" xmlns:nc="`+common.NetconfNS+`" nc:operation="replace">`+
+		`<name>eth0</name></interface></interfaces>`))).Return(&common.RPCReply{}, nil)
+	mcli.On("Execute", createEditConfigRequest(RunningCfg, Cfg(`<interfaces xmlns="`+interfacesNS+`">`+
+		`<interface xmlns="`+interfacesNS+`"><name>eth1</name></interface></interfaces>`))).Return(&common.RPCReply{}, nil)
+
+	ds := NewNetconfDataSession(ncs, testNamespaces, testListKeys)
+	assert.NoError(t, ds.Put("ietf-interfaces:interfaces/interface=eth0", `<interface><name>eth0</name></interface>`))
+	assert.NoError(t, ds.Patch("ietf-interfaces:interfaces/interface", []byte(`<interface><name>eth1</name></interface>`)))
+	assert.EqualError(t, ds.Put("ietf-interfaces:interfaces/interface", `<interface><name>eth0</name></interface>`),
+		`data path "ietf-interfaces:interfaces/interface": list interface is replaced without identifying an entry by its keys`)
+	mcli.AssertExpectations(t)
+}
+
+func TestNetconfDataSessionInvalidRequests(t *testing.T) {
+	ncs, _ := newOpsSessionWithMockClient(t)
+	ds := NewNetconfDataSession(ncs, testNamespaces)
+
+	var result string
+	assert.EqualError(t, ds.Get("unknown:top", &result), `data path "unknown:top": unknown module "unknown"`)
+	assert.EqualError(t, ds.Get("interfaces", &result),
+		`data path "interfaces": first segment is not qualified by a module`)
+	assert.EqualError(t, ds.Get("ietf-interfaces:interfaces/interface=eth0", &result),
+		`data path "ietf-interfaces:interfaces/interface=eth0": segment "interface=eth0" does not identify an entry of a list with 0 keys`)
+	assert.EqualError(t, ds.Get("ietf-interfaces:interfaces/=eth0", &result),
+		`data path "ietf-interfaces:interfaces/=eth0": invalid segment "=eth0"`)
+	assert.EqualError(t, ds.Put("ietf-interfaces:interfaces", `<interface/>`),
+		"configuration root element <interface> does not match data node interfaces")
+}
+
+// Defines a restconf server that records the last request, and replies with the status and body.
+type restconfServer struct {
+	status                      int
+	body                        string
+	method, path, accept, ctype string
+	user, password, requestBody string
+}
+
+func (rs *restconfServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rs.method, rs.path = r.Method, r.URL.Path
+	rs.accept, rs.ctype = r.Header.Get("Accept"), r.Header.Get("Content-Type")
+	rs.user, rs.password, _ = r.BasicAuth()
+	b, _ := io.ReadAll(r.Body)
+	rs.requestBody = string(b)
+	w.WriteHeader(rs.status)
+	_, _ = io.WriteString(w, rs.body)
+}
+
+func TestRestconfDataSession(t *testing.T) {
+	rs := &restconfServer{status: http.StatusOK, body: `<interfaces xmlns="` + interfacesNS + `"><interface/></interfaces>`}
+	server := httptest.NewServer(rs)
+	defer server.Close()
+
+	ds := NewRestconfDataSession(server.URL+"/restconf/", &target.Credentials{Username: "user", Password: "pass"},
+		testNamespaces, testListKeys)
+	defer ds.Close()
+	assert.Equal(t, DefaultRestconfTimeout, ds.(*restconfDataSession).client.Timeout)
+	assert.True(t, ds.(*restconfDataSession).ownsClient)
+
+	var result map[string]interface{}
+	err := ds.Get("ietf-interfaces:interfaces", &result)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"interfaces": map[string]interface{}{"interface": nil}}, result)
+	assert.Equal(t, http.MethodGet, rs.method)
+	assert.Equal(t, "/restconf/data/ietf-interfaces:interfaces", rs.path)
+	assert.Equal(t, yangDataXML, rs.accept)
+	assert.Equal(t, "user", rs.user)
+	assert.Equal(t, "pass", rs.password)
+
+	rs.status, rs.body = http.StatusNoContent, ""
+	err = ds.Put("ietf-interfaces:interfaces/interface=eth0", `<interface><name>eth0</name></interface>`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPut, rs.method)
+	assert.Equal(t, "/restconf/data/ietf-interfaces:interfaces/interface=eth0", rs.path)
+	assert.Equal(t, yangDataXML, rs.ctype)
+	assert.Equal(t, `<interface xmlns="`+interfacesNS+`"><name>eth0</name></interface>`, rs.requestBody)
+
+	rs.method = ""
+	err = ds.Put("ietf-interfaces:interfaces/interface", `<interface><name>eth0</name></interface>`)
+	assert.Error(t, err, "Expecting a keyless list to be rejected")
+	assert.Empty(t, rs.method)
+
+	err = ds.Patch("ietf-interfaces:interfaces", `<interfaces xmlns="urn:other"/>`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPatch, rs.method)
+	assert.Equal(t, `<interfaces xmlns="urn:other"/>`, rs.requestBody, "Expecting a declared namespace to be preserved")
+
+	rs.status, rs.body = http.StatusNotFound, ""
+	var s string
+	assert.NoError(t, ds.Get("ietf-interfaces:interfaces", &s))
+	assert.Empty(t, s)
+}
+
+func TestRestconfDataSessionError(t *testing.T) {
+	rs := &restconfServer{status: http.StatusBadRequest, body: `<errors xmlns="urn:ietf:params:xml:ns:yang:ietf-restconf">` +
+		`<error><error-type>protocol</error-type><error-tag>invalid-value</error-tag>` +
+		`<error-message>invalid interface name</error-message></error></errors>`}
+	server := httptest.NewServer(rs)
+	defer server.Close()
+
+	ds := NewRestconfDataSession(server.URL+"/restconf", nil, testNamespaces)
+	err := ds.Patch("ietf-interfaces:interfaces", `<interfaces/>`)
+	var rerr *RestconfError
+	assert.True(t, errors.As(err, &rerr))
+	assert.Equal(t, http.StatusBadRequest, rerr.StatusCode)
+	assert.Len(t, rerr.Errors, 1)
+	assert.Equal(t, "invalid-value", rerr.Errors[0].Tag)
+	assert.EqualError(t, err, "restconf request failed with status 400: invalid interface name")
+}
+
+func TestDataSessionForTargetFallback(t *testing.T) {
+	rs := &restconfServer{status: http.StatusOK}
+	server := httptest.NewTLSServer(rs)
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	restconfPort, _ := strconv.Atoi(u.Port())
+
+	// Acquire a port on which netconf connections are refused.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	netconfPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	tgt := &target.Target{
+		Address:         "127.0.0.1",
+		Ports:           map[target.Protocol]int{target.NETCONF: netconfPort, target.RESTCONF: restconfPort},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	creds := target.StaticCredentials(&target.Credentials{Username: "user", Password: "pass"})
+	ds, err := NewDataSessionForTarget(context.Background(), tgt, creds, testNamespaces,
+		WithHTTPClient(server.Client()), WithNetconfOptions(WithSetupTimeout(1)))
+	assert.NoError(t, err)
+	defer ds.Close()
+	assert.IsType(t, &restconfDataSession{}, ds)
+	assert.False(t, ds.(*restconfDataSession).ownsClient, "Expecting the supplied client not to be closed")
+	assert.Equal(t, "/restconf", rs.path, "Expecting the API root to be probed")
+
+	tgt.Protocols = []target.Protocol{target.NETCONF}
+	_, err = NewDataSessionForTarget(context.Background(), tgt, creds, testNamespaces,
+		WithHTTPClient(server.Client()))
+	assert.Error(t, err, "Expecting no fallback to a protocol the target does not support")
+
+	tgt.Protocols = []target.Protocol{target.RESTCONF}
+	rs.status = http.StatusUnauthorized
+	_, err = NewDataSessionForTarget(context.Background(), tgt, creds, testNamespaces,
+		WithHTTPClient(server.Client()))
+	assert.EqualError(t, err, "restconf request failed with status 401")
+}
@@ -0,0 +1,163 @@
+package ops
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Post-processing of reply data, applied before it is delivered to the caller of the Get... methods.
+
+// ReplyDecoder transforms the text content of an element in reply data, delivering the xml that will replace it.
+// Decoders are registered against an element name using WithReplyDecoder, and are only applied to elements that
+// contain text alone; empty elements are left unchanged.
+type ReplyDecoder func(text string) (string, error)
+
+// Base64Decoder is a ReplyDecoder that decodes base64 encoded content, for example <file-content>, to text.
+func Base64Decoder(text string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	if err != nil {
+		return "", err
+	}
+	return escapeText(b), nil
+}
+
+// JSONDecoder is a ReplyDecoder that converts JSON content to xml, so that it can be unmarshalled as part of the
+// reply. Object members are delivered as child elements named by the member name, array elements are delivered as
+// repeated elements named by the member name, and scalar values are delivered as text.
+// Members whose names are not valid xml element names, such as "rx bytes" or "1st", are omitted.
+// For example: {"rx":1,"peers":["a","b"]} is delivered as <peers>a</peers><peers>b</peers><rx>1</rx>.
+func JSONDecoder(text string) (string, error) {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(text))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return "", err
+	}
+	sb := &strings.Builder{}
+	writeJSONValue(sb, v)
+	return sb.String(), nil
+}
+
+func writeJSONValue(sb *strings.Builder, v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		// Order members by name, so that output is deterministic.
+		names := make([]string, 0, len(value))
+		for name := range value {
+			if isNCName(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if list, ok := value[name].([]interface{}); ok {
+				for _, item := range list {
+					writeJSONElement(sb, name, item)
+				}
+			} else {
+				writeJSONElement(sb, name, value[name])
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			writeJSONElement(sb, "item", item)
+		}
+	case nil:
+	default:
+		sb.WriteString(escapeText([]byte(fmt.Sprint(value))))
+	}
+}
+
+func writeJSONElement(sb *strings.Builder, name string, v interface{}) {
+	sb.WriteString("<" + name + ">")
+	writeJSONValue(sb, v)
+	sb.WriteString("</" + name + ">")
+}
+
+// Determines whether the name is a valid unqualified xml element name, as defined by the NCName production of
+// https://www.w3.org/TR/xml-names/#NT-NCName.
+func isNCName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' || unicode.Is(unicode.Mn, r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func escapeText(b []byte) string {
+	buf := &bytes.Buffer{}
+	_ = xml.EscapeText(buf, b)
+	return buf.String()
+}
+
+// Applies the decoders to the text content of the named elements within data.
+func decodeReplyData(data string, decoders map[string]ReplyDecoder) (string, error) {
+	if len(decoders) == 0 {
+		return data, nil
+	}
+
+	type span struct {
+		start, end int64
+		content    string
+	}
+	var spans []span
+
+	var decoder ReplyDecoder
+	var start, prev int64
+	var text strings.Builder
+	d := xml.NewDecoder(strings.NewReader(data))
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			// Nested elements mean the enclosing element is not a candidate for decoding.
+			decoder = decoders[t.Name.Local]
+			start = d.InputOffset()
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if decoder != nil && strings.TrimSpace(text.String()) != "" {
+				content, err := decoder(text.String())
+				if err != nil {
+					return "", errors.Wrapf(err, "failed to decode %s", t.Name.Local)
+				}
+				spans = append(spans, span{start: start, end: prev, content: content})
+			}
+			decoder = nil
+		}
+		prev = d.InputOffset()
+	}
+
+	var sb strings.Builder
+	var offset int64
+	for _, s := range spans {
+		sb.WriteString(data[offset:s.start])
+		sb.WriteString(s.content)
+		offset = s.end
+	}
+	sb.WriteString(data[offset:])
+	return sb.String(), nil
+}
@@ -0,0 +1,74 @@
+package ops
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestDecodeReplyData(t *testing.T) {
+	decoders := map[string]ReplyDecoder{"file-content": Base64Decoder, "stats": JSONDecoder}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"no match", `<data><file><name>a</name></file></data>`, `<data><file><name>a</name></file></data>`},
+		{"base64", `<data><file><file-content>PGEmYj4=</file-content></file></data>`,
+			`<data><file><file-content>&lt;a&amp;b&gt;</file-content></file></data>`},
+		{"base64 whitespace", "<data><file-content>\n  aGVs\n  bG8=\n</file-content></data>",
+			`<data><file-content>hello</file-content></data>`},
+		{"prefixed", `<data><x:file-content xmlns:x="urn:x">aGVsbG8=</x:file-content></data>`,
+			`<data><x:file-content xmlns:x="urn:x">hello</x:file-content></data>`},
+		{"json", `<data><stats>{"rx": 10, "peers": ["a", "b"], "if": {"name": "eth0"}}</stats></data>`,
+			`<data><stats><if><name>eth0</name></if><peers>a</peers><peers>b</peers><rx>10</rx></stats></data>`},
+		{"json escaped", `<data><stats>{&quot;descr&quot;: &quot;a&lt;b&quot;}</stats></data>`,
+			`<data><stats><descr>a&lt;b</descr></stats></data>`},
+		{"multiple", `<data><stats>{"a":1}</stats><stats>{"a":2}</stats></data>`,
+			`<data><stats><a>1</a></stats><stats><a>2</a></stats></data>`},
+		{"nested elements", `<data><stats><a>1</a></stats></data>`, `<data><stats><a>1</a></stats></data>`},
+		{"empty", `<data><stats/><stats> </stats><file-content></file-content></data>`,
+			`<data><stats/><stats> </stats><file-content></file-content></data>`},
+		{"json invalid names", `<data><stats>{"rx bytes": 1, "1st": 2, "a:b": 3, "&lt;x&gt;": 4, "tx-bytes": 5}</stats></data>`,
+			`<data><stats><tx-bytes>5</tx-bytes></stats></data>`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := decodeReplyData(test.data, decoders)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestDecodeReplyDataFailure(t *testing.T) {
+	_, err := decodeReplyData(`<data><file-content>!!!</file-content></data>`,
+		map[string]ReplyDecoder{"file-content": Base64Decoder})
+	assert.EqualError(t, err, "failed to decode file-content: illegal base64 data at input byte 0")
+}
+
+func TestGetSubtreeWithReplyDecoders(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	ncs.(*sImpl).decoders = map[string]ReplyDecoder{"file-content": Base64Decoder, "counters": JSONDecoder}
+	mcli.On("Execute", createGetSubtreeRequest(`<file/>`)).
+		Return(&common.RPCReply{Data: `<data><file><file-content>aGVsbG8=</file-content>` +
+			`<counters>{"in": 5, "out": 7}</counters></file></data>`}, nil)
+
+	result := &struct {
+		XMLName  xml.Name `xml:"file"`
+		Content  string   `xml:"file-content"`
+		Counters struct {
+			In  int `xml:"in"`
+			Out int `xml:"out"`
+		} `xml:"counters"`
+	}{}
+	err := ncs.GetSubtree(`<file/>`, result)
+	assert.NoError(t, err, "Not expecting call to fail")
+	assert.Equal(t, "hello", result.Content)
+	assert.Equal(t, 5, result.Counters.In)
+	assert.Equal(t, 7, result.Counters.Out)
+}
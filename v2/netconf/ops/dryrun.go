@@ -0,0 +1,148 @@
+package ops
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// DryRunRecorder is called with each write operation suppressed by a dry-run session, identified by its element
+// name, such as "edit-config", and the xml of its request.
+type DryRunRecorder func(operation, request string)
+
+// WithDryRun configures the session so that write operations (edit-config, copy-config, delete-config, commit,
+// cancel-commit and discard-changes) are passed to recorder, rather than being sent to the server, and succeed with
+// an <ok/> reply, whether executed synchronously, asynchronously or as a subscription. All other requests, including
+// gets and locks, are executed, so that automation can be validated against a device without changing it.
+// Suppressed operations are not recorded by WithAudit.
+// If recorder is nil, suppressed operations are logged.
+// Default is to send all operations.
+func WithDryRun(recorder DryRunRecorder) SessionOption {
+	return func(so *sessionOptions) {
+		if recorder == nil {
+			recorder = func(operation, request string) {
+				log.Printf("DryRun operation:%s request:%s\n", operation, request)
+			}
+		}
+		so.dryRun = recorder
+	}
+}
+
+// Wraps the session, so that write operations are suppressed.
+func (r DryRunRecorder) wrap(s client.Session) client.Session {
+	return &dryRunSession{Session: s, recorder: r}
+}
+
+type dryRunSession struct {
+	client.Session
+	recorder DryRunRecorder
+}
+
+func (d *dryRunSession) Execute(req common.Request) (*common.RPCReply, error) {
+	req, reply, err := d.suppress(req)
+	if reply != nil || err != nil {
+		return reply, err
+	}
+	return d.Session.Execute(req)
+}
+
+func (d *dryRunSession) ExecuteContext(ctx context.Context, req common.Request) (*common.RPCReply, error) {
+	req, reply, err := d.suppress(req)
+	if reply != nil || err != nil {
+		return reply, err
+	}
+	return d.Session.ExecuteContext(ctx, req)
+}
+
+func (d *dryRunSession) ExecuteAsync(req common.Request, rchan chan *common.RPCReply) error {
+	req, reply, err := d.suppress(req)
+	if err != nil {
+		return err
+	}
+	if reply != nil {
+		go func() { rchan <- reply }()
+		return nil
+	}
+	return d.Session.ExecuteAsync(req, rchan)
+}
+
+func (d *dryRunSession) Subscribe(req common.Request, nchan chan *common.Notification) (*common.RPCReply, error) {
+	req, reply, err := d.suppress(req)
+	if reply != nil || err != nil {
+		return reply, err
+	}
+	return d.Session.Subscribe(req, nchan)
+}
+
+// Records the request if it is a write operation, delivering the <ok/> reply with which it succeeds. Otherwise, the
+// request to be sent is delivered with a nil reply.
+func (d *dryRunSession) suppress(req common.Request) (common.Request, *common.RPCReply, error) {
+	body, err := requestXML(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	operation := writeOperation(body)
+	if operation == "" {
+		if _, ok := req.(io.Reader); ok {
+			// A request streaming from a reader can only be read once, so is sent in its encoded form.
+			return body, nil, nil
+		}
+		return req, nil, nil
+	}
+
+	d.recorder(operation, body)
+	return nil, &common.RPCReply{
+		Ok:     true,
+		Data:   "<ok/>",
+		RawXML: `<rpc-reply xmlns="` + common.NetconfNS + `"><ok/></rpc-reply>`,
+	}, nil
+}
+
+// Delivers the name of the first write operation defined by the request body, which may hold several operations
+// (see ExecuteBatch), or an empty string if it holds none.
+func writeOperation(body string) string {
+	elements, err := childElements(body)
+	if err != nil {
+		return ""
+	}
+	for _, e := range elements {
+		if name := elementName(e); isWriteOperation(name) {
+			return name
+		}
+		// Operations may be wrapped in a vendor container element.
+		children, err := childElements(innerXML(e))
+		if err != nil {
+			continue
+		}
+		for _, c := range children {
+			if name := elementName(c); isWriteOperation(name) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func isWriteOperation(name string) bool {
+	switch name {
+	case "edit-config", "copy-config", "delete-config", "commit", "commit-configuration", "cancel-commit",
+		"discard-changes":
+		return true
+	}
+	return false
+}
+
+// Delivers the content of the element.
+func innerXML(element string) string {
+	content := &struct {
+		Inner string `xml:",innerxml"`
+	}{}
+	if xml.Unmarshal([]byte(element), content) != nil {
+		return ""
+	}
+	return content.Inner
+}
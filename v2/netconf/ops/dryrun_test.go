@@ -0,0 +1,97 @@
+package ops
+
+import (
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type recordedOperation struct {
+	operation, request string
+}
+
+func newDryRunSession() (OpSession, *datastoreSession, *[]recordedOperation) {
+	ds := newDatastoreSession()
+	recorded := &[]recordedOperation{}
+	so := &sessionOptions{}
+	WithDryRun(func(operation, request string) {
+		*recorded = append(*recorded, recordedOperation{operation, request})
+	})(so)
+	return &sImpl{Session: so.dryRun.wrap(ds)}, ds, recorded
+}
+
+func TestDryRunSuppressesWrites(t *testing.T) {
+	ncs, ds, recorded := newDryRunSession()
+
+	assert.NoError(t, ncs.EditConfig(CandidateCfg, Cfg("<a>2</a>")))
+	assert.NoError(t, ncs.CopyConfig(DsName(CandidateCfg), DsName(RunningCfg)))
+	assert.NoError(t, ncs.DeleteConfig(DsName(StartupCfg)))
+	assert.NoError(t, ncs.Commit())
+	assert.NoError(t, ncs.Discard())
+	assert.Equal(t, "<a>1</a>", ds.datastores[CandidateCfg])
+	assert.Equal(t, "<a>1</a>", ds.datastores[RunningCfg])
+
+	assert.Len(t, *recorded, 5)
+	assert.Equal(t, recordedOperation{"edit-config",
+		"<edit-config><target><candidate/></target><config><a>2</a></config></edit-config>"}, (*recorded)[0])
+	var ops []string
+	for _, r := range *recorded {
+		ops = append(ops, r.operation)
+	}
+	assert.Equal(t, []string{"edit-config", "copy-config", "delete-config", "commit", "discard-changes"}, ops)
+}
+
+func TestDryRunExecutesReads(t *testing.T) {
+	ncs, ds, recorded := newDryRunSession()
+	ds.datastores[RunningCfg] = "<a>3</a>"
+
+	var result string
+	assert.NoError(t, ncs.GetConfigSubtree(nil, RunningCfg, &result))
+	assert.Equal(t, "<a>3</a>", result)
+	assert.NoError(t, ncs.Lock(CandidateCfg))
+	assert.Empty(t, *recorded)
+}
+
+func TestDryRunSuppressesXMLRequests(t *testing.T) {
+	ncs, ds, recorded := newDryRunSession()
+
+	_, err := ncs.Execute(`<edit-config><target><running/></target><config><a>4</a></config></edit-config>`)
+	assert.NoError(t, err)
+	// Write operations within a batch container are also suppressed.
+	_, err = ncs.Execute(`<batch xmlns="urn:vendor"><lock><target><candidate/></target></lock><commit/></batch>`)
+	assert.NoError(t, err)
+	assert.Equal(t, "<a>1</a>", ds.datastores[RunningCfg])
+	assert.Len(t, *recorded, 2)
+	assert.Equal(t, "commit", (*recorded)[1].operation)
+}
+
+func TestDryRunSuppressesAsyncAndSubscribe(t *testing.T) {
+	ncs, ds, recorded := newDryRunSession()
+
+	rch := make(chan *common.RPCReply)
+	assert.NoError(t, ncs.ExecuteAsync(`<edit-config><target><running/></target><config><a>5</a></config></edit-config>`,
+		rch))
+	reply := <-rch
+	assert.True(t, reply.Ok)
+	assert.Equal(t, "<a>1</a>", ds.datastores[RunningCfg])
+
+	reply, err := ncs.Subscribe(`<commit/>`, make(chan *common.Notification))
+	assert.NoError(t, err)
+	assert.True(t, reply.Ok)
+
+	// Other requests are sent.
+	assert.NoError(t, ncs.ExecuteAsync(`<lock><target><candidate/></target></lock>`, rch))
+	assert.NotNil(t, <-rch)
+
+	assert.Len(t, *recorded, 2)
+	assert.Equal(t, "edit-config", (*recorded)[0].operation)
+	assert.Equal(t, "commit", (*recorded)[1].operation)
+}
+
+func TestDryRunDefaultRecorder(t *testing.T) {
+	so := &sessionOptions{}
+	WithDryRun(nil)(so)
+	assert.NotNil(t, so.dryRun)
+}
@@ -0,0 +1,102 @@
+package ops
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// Default backoff applied by LockWithRetry.
+const (
+	defaultLockRetryInitial = 100 * time.Millisecond
+	defaultLockRetryMax     = 5 * time.Second
+)
+
+// LockDeniedError is returned by LockWithRetry when the lock could not be acquired before the context expired.
+type LockDeniedError struct {
+	// The target configuration.
+	Target string
+	// The id of the session holding the lock, as reported by the server; zero if not reported.
+	SessionID uint64
+	// The most recent lock-denied error returned by the server.
+	RPCError *common.RPCError
+	// The reason retries were abandoned.
+	Err error
+}
+
+// Error generates a string representation of the lock denied error.
+func (e *LockDeniedError) Error() string {
+	if e.SessionID != 0 {
+		return fmt.Sprintf("lock on %s denied, held by session %d: %v", e.Target, e.SessionID, e.Err)
+	}
+	return fmt.Sprintf("lock on %s denied: %v", e.Target, e.Err)
+}
+
+// Unwrap delivers the reason retries were abandoned.
+func (e *LockDeniedError) Unwrap() error {
+	return e.Err
+}
+
+// LockRetryOption configures the behaviour of LockWithRetry.
+type LockRetryOption func(*lockRetryConfig)
+
+type lockRetryConfig struct {
+	initial time.Duration
+	max     time.Duration
+}
+
+// LockBackoff defines the delay before the first retry, which doubles on each subsequent retry up to max.
+func LockBackoff(initial, max time.Duration) LockRetryOption {
+	return func(c *lockRetryConfig) {
+		c.initial = initial
+		c.max = max
+	}
+}
+
+func (s *sImpl) LockWithRetry(ctx context.Context, target string, options ...LockRetryOption) error {
+	cfg := &lockRetryConfig{initial: defaultLockRetryInitial, max: defaultLockRetryMax}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	delay := cfg.initial
+	for {
+		err := s.Lock(target)
+		rpcErr, sessionID, denied := lockDenied(err)
+		if !denied {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &LockDeniedError{Target: target, SessionID: sessionID, RPCError: rpcErr, Err: ctx.Err()}
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > cfg.max {
+			delay = cfg.max
+		}
+	}
+}
+
+// Determines whether err is a lock-denied rpc error, delivering the error and the id of the session holding the
+// lock, if reported.
+func lockDenied(err error) (rpcErr *common.RPCError, sessionID uint64, ok bool) {
+	if !errors.As(err, &rpcErr) || rpcErr.Tag != "lock-denied" {
+		return nil, 0, false
+	}
+
+	// The session id is held in the error-info element, see RFC 6241 Appendix A.
+	info := &struct {
+		SessionID uint64 `xml:"error-info>session-id"`
+	}{}
+	_ = xml.Unmarshal([]byte("<rpc-error>"+rpcErr.Info+"</rpc-error>"), info)
+	return rpcErr, info.SessionID, true
+}
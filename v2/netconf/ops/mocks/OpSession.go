@@ -3,6 +3,9 @@
 package mocks
 
 import (
+	context "context"
+
+	client "github.com/damianoneill/net/v2/netconf/client"
 	common "github.com/damianoneill/net/v2/netconf/common"
 	mock "github.com/stretchr/testify/mock"
 
@@ -33,6 +36,26 @@ func (_m *OpSession) CloseSession() error {
 	return r0
 }
 
+// Commit provides a mock function with given fields: options
+func (_m *OpSession) Commit(options ...ops.CommitOption) error {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(...ops.CommitOption) error); ok {
+		r0 = rf(options...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CopyConfig provides a mock function with given fields: source, target
 func (_m *OpSession) CopyConfig(source ops.CfgDsOpt, target ops.CfgDsOpt) error {
 	ret := _m.Called(source, target)
@@ -154,6 +177,50 @@ func (_m *OpSession) ExecuteAsync(req common.Request, rchan chan *common.RPCRepl
 	return r0
 }
 
+// ExecuteContext provides a mock function with given fields: ctx, req
+func (_m *OpSession) ExecuteContext(ctx context.Context, req common.Request) (*common.RPCReply, error) {
+	ret := _m.Called(ctx, req)
+
+	var r0 *common.RPCReply
+	if rf, ok := ret.Get(0).(func(context.Context, common.Request) *common.RPCReply); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*common.RPCReply)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, common.Request) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExecuteBatch provides a mock function with given fields: operations, options
+func (_m *OpSession) ExecuteBatch(operations []ops.BatchOperation, options ...ops.BatchOption) error {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, operations)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]ops.BatchOperation, ...ops.BatchOption) error); ok {
+		r0 = rf(operations, options...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetConfigSubtree provides a mock function with given fields: filter, source, result
 func (_m *OpSession) GetConfigSubtree(filter interface{}, source string, result interface{}) error {
 	ret := _m.Called(filter, source, result)
@@ -296,6 +363,59 @@ func (_m *OpSession) Lock(target string) error {
 	return r0
 }
 
+// LockWithRetry provides a mock function with given fields: ctx, target, options
+func (_m *OpSession) LockWithRetry(ctx context.Context, target string, options ...ops.LockRetryOption) error {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, target)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...ops.LockRetryOption) error); ok {
+		r0 = rf(ctx, target, options...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RegisterNamespaces provides a mock function with given fields: nslist
+func (_m *OpSession) RegisterNamespaces(nslist ...ops.Namespace) {
+	_va := make([]interface{}, len(nslist))
+	for _i := range nslist {
+		_va[_i] = nslist[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
+// SafeCommit provides a mock function with given fields: ctx, config, options
+func (_m *OpSession) SafeCommit(ctx context.Context, config ops.ConfigOption, options ...ops.SafeCommitOption) error {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, config)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, ops.ConfigOption, ...ops.SafeCommitOption) error); ok {
+		r0 = rf(ctx, config, options...)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ServerCapabilities provides a mock function with given fields:
 func (_m *OpSession) ServerCapabilities() []string {
 	ret := _m.Called()
@@ -312,6 +432,20 @@ func (_m *OpSession) ServerCapabilities() []string {
 	return r0
 }
 
+// Stats provides a mock function with given fields:
+func (_m *OpSession) Stats() client.SessionStats {
+	ret := _m.Called()
+
+	var r0 client.SessionStats
+	if rf, ok := ret.Get(0).(func() client.SessionStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(client.SessionStats)
+	}
+
+	return r0
+}
+
 // Subscribe provides a mock function with given fields: req, nchan
 func (_m *OpSession) Subscribe(req common.Request, nchan chan *common.Notification) (*common.RPCReply, error) {
 	ret := _m.Called(req, nchan)
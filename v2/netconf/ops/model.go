@@ -19,6 +19,8 @@ const (
 	MergeOp   = "merge"
 	ReplaceOp = "replace"
 	NoneOp    = "none"
+	CreateOp  = "create"
+	DeleteOp  = "delete"
 
 	// Edit Config Test Options
 	TestThenSetOpt = "test-then-set"
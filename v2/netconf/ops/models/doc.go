@@ -0,0 +1,11 @@
+// Package models provides Go structs, with xml tags, for commonly used YANG models.
+//
+// The structs can be supplied directly as the result of ops GetSubtree and GetConfigSubtree requests,
+// together with the matching filter, for example:
+//
+//	result := &models.InterfacesState{}
+//	err := s.GetSubtree(models.InterfacesStateFilter, result)
+//
+// Only the commonly used leaves of each model are defined; unrecognised elements in the reply are ignored.
+// Leaves of type yang:date-and-time are held as strings, to tolerate devices that report non-conformant values.
+package models
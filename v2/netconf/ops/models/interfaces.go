@@ -0,0 +1,63 @@
+package models
+
+import "encoding/xml"
+
+// Structs describing the ietf-interfaces model (RFC 8343).
+
+// IETFInterfacesNamespace is the namespace of the ietf-interfaces model.
+const IETFInterfacesNamespace = "urn:ietf:params:xml:ns:yang:ietf-interfaces"
+
+// Subtree filters selecting the ietf-interfaces containers.
+const (
+	InterfacesFilter      = `<interfaces xmlns="` + IETFInterfacesNamespace + `"/>`
+	InterfacesStateFilter = `<interfaces-state xmlns="` + IETFInterfacesNamespace + `"/>`
+)
+
+// Interfaces defines the ietf-interfaces interfaces container.
+type Interfaces struct {
+	XMLName   xml.Name    `xml:"urn:ietf:params:xml:ns:yang:ietf-interfaces interfaces"`
+	Interface []Interface `xml:"interface"`
+}
+
+// InterfacesState defines the deprecated ietf-interfaces interfaces-state container (RFC 7223), still
+// reported by many devices.
+type InterfacesState struct {
+	XMLName   xml.Name    `xml:"urn:ietf:params:xml:ns:yang:ietf-interfaces interfaces-state"`
+	Interface []Interface `xml:"interface"`
+}
+
+// Interface defines an entry in the interface list.
+type Interface struct {
+	Name                 string               `xml:"name"`
+	Description          string               `xml:"description,omitempty"`
+	Type                 string               `xml:"type,omitempty"`
+	Enabled              *bool                `xml:"enabled,omitempty"`
+	LinkUpDownTrapEnable string               `xml:"link-up-down-trap-enable,omitempty"`
+	AdminStatus          string               `xml:"admin-status,omitempty"`
+	OperStatus           string               `xml:"oper-status,omitempty"`
+	LastChange           string               `xml:"last-change,omitempty"`
+	IfIndex              int32                `xml:"if-index,omitempty"`
+	PhysAddress          string               `xml:"phys-address,omitempty"`
+	HigherLayerIf        []string             `xml:"higher-layer-if,omitempty"`
+	LowerLayerIf         []string             `xml:"lower-layer-if,omitempty"`
+	Speed                uint64               `xml:"speed,omitempty"`
+	Statistics           *InterfaceStatistics `xml:"statistics,omitempty"`
+}
+
+// InterfaceStatistics defines the statistics container of an interface.
+type InterfaceStatistics struct {
+	DiscontinuityTime string `xml:"discontinuity-time"`
+	InOctets          uint64 `xml:"in-octets"`
+	InUnicastPkts     uint64 `xml:"in-unicast-pkts"`
+	InBroadcastPkts   uint64 `xml:"in-broadcast-pkts"`
+	InMulticastPkts   uint64 `xml:"in-multicast-pkts"`
+	InDiscards        uint32 `xml:"in-discards"`
+	InErrors          uint32 `xml:"in-errors"`
+	InUnknownProtos   uint32 `xml:"in-unknown-protos"`
+	OutOctets         uint64 `xml:"out-octets"`
+	OutUnicastPkts    uint64 `xml:"out-unicast-pkts"`
+	OutBroadcastPkts  uint64 `xml:"out-broadcast-pkts"`
+	OutMulticastPkts  uint64 `xml:"out-multicast-pkts"`
+	OutDiscards       uint32 `xml:"out-discards"`
+	OutErrors         uint32 `xml:"out-errors"`
+}
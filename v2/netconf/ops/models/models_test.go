@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/ops"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Decode the reply data as a GetSubtree request would.
+func decode(t *testing.T, data string, result interface{}) {
+	err := xml.Unmarshal([]byte(data), &ops.Data{Body: result})
+	assert.NoError(t, err, "Not expecting decode to fail")
+}
+
+func TestInterfacesState(t *testing.T) {
+	result := &InterfacesState{}
+	decode(t, `<data><interfaces-state xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces">`+
+		`<interface><name>eth0</name><type xmlns:ianaift="urn:ietf:params:xml:ns:yang:iana-if-type">ianaift:ethernetCsmacd</type>`+
+		`<admin-status>up</admin-status><oper-status>down</oper-status><if-index>2</if-index>`+
+		`<phys-address>00:11:22:33:44:55</phys-address><speed>1000000000</speed>`+
+		`<statistics><discontinuity-time>2023-01-01T00:00:00Z</discontinuity-time>`+
+		`<in-octets>18446744073709551615</in-octets><out-errors>7</out-errors></statistics>`+
+		`</interface><interface><name>lo</name></interface>`+
+		`</interfaces-state></data>`, result)
+
+	assert.Len(t, result.Interface, 2)
+	eth0 := result.Interface[0]
+	assert.Equal(t, "eth0", eth0.Name)
+	assert.Equal(t, "ianaift:ethernetCsmacd", eth0.Type)
+	assert.Equal(t, "up", eth0.AdminStatus)
+	assert.Equal(t, "down", eth0.OperStatus)
+	assert.Equal(t, int32(2), eth0.IfIndex)
+	assert.Equal(t, uint64(1000000000), eth0.Speed)
+	assert.NotNil(t, eth0.Statistics)
+	assert.Equal(t, uint64(18446744073709551615), eth0.Statistics.InOctets)
+	assert.Equal(t, uint32(7), eth0.Statistics.OutErrors)
+	assert.Nil(t, result.Interface[1].Statistics)
+}
+
+func TestInterfaces(t *testing.T) {
+	result := &Interfaces{}
+	decode(t, `<data><interfaces xmlns="urn:ietf:params:xml:ns:yang:ietf-interfaces">`+
+		`<interface><name>eth0</name><description>uplink</description><enabled>true</enabled></interface>`+
+		`</interfaces></data>`, result)
+
+	assert.Len(t, result.Interface, 1)
+	assert.Equal(t, "uplink", result.Interface[0].Description)
+	assert.True(t, *result.Interface[0].Enabled)
+}
+
+func TestSystem(t *testing.T) {
+	result := &System{}
+	decode(t, `<data><system xmlns="urn:ietf:params:xml:ns:yang:ietf-system">`+
+		`<hostname>router1</hostname><clock><timezone-utc-offset>-300</timezone-utc-offset></clock>`+
+		`<ntp><enabled>true</enabled><server><name>ntp1</name><udp><address>10.0.0.1</address></udp><prefer>true</prefer></server></ntp>`+
+		`<dns-resolver><search>example.com</search>`+
+		`<server><name>dns1</name><udp-and-tcp><address>10.0.0.2</address><port>53</port></udp-and-tcp></server>`+
+		`<options><attempts>3</attempts></options></dns-resolver>`+
+		`</system></data>`, result)
+
+	assert.Equal(t, "router1", result.Hostname)
+	assert.Equal(t, int16(-300), *result.Clock.TimezoneUTCOffset)
+	assert.True(t, *result.NTP.Enabled)
+	assert.Equal(t, "10.0.0.1", result.NTP.Server[0].Address)
+	assert.True(t, *result.NTP.Server[0].Prefer)
+	assert.Equal(t, []string{"example.com"}, result.DNSResolver.Search)
+	assert.Equal(t, uint16(53), result.DNSResolver.Server[0].Port)
+	assert.Equal(t, uint8(3), result.DNSResolver.Options.Attempts)
+}
+
+func TestSystemState(t *testing.T) {
+	result := &SystemState{}
+	decode(t, `<data><system-state xmlns="urn:ietf:params:xml:ns:yang:ietf-system">`+
+		`<platform><os-name>Linux</os-name><machine>x86_64</machine></platform>`+
+		`<clock><boot-datetime>2023-01-01T00:00:00Z</boot-datetime></clock>`+
+		`</system-state></data>`, result)
+
+	assert.Equal(t, "Linux", result.Platform.OSName)
+	assert.Equal(t, "x86_64", result.Platform.Machine)
+	assert.Equal(t, "2023-01-01T00:00:00Z", result.Clock.BootDatetime)
+}
+
+func TestOpenConfigInterfaces(t *testing.T) {
+	result := &OpenConfigInterfaces{}
+	decode(t, `<data><interfaces xmlns="http://openconfig.net/yang/interfaces">`+
+		`<interface><name>Ethernet1</name>`+
+		`<config><name>Ethernet1</name><mtu>9000</mtu></config>`+
+		`<state><name>Ethernet1</name><mtu>9000</mtu><enabled>true</enabled><ifindex>5</ifindex><oper-status>UP</oper-status>`+
+		`<counters><in-pkts>100</in-pkts><carrier-transitions>2</carrier-transitions></counters></state>`+
+		`<subinterfaces><subinterface><index>0</index>`+
+		`<state><index>0</index><oper-status>UP</oper-status></state></subinterface></subinterfaces>`+
+		`</interface></interfaces></data>`, result)
+
+	assert.Len(t, result.Interface, 1)
+	intf := result.Interface[0]
+	assert.Equal(t, uint16(9000), intf.Config.MTU)
+	assert.Equal(t, uint16(9000), intf.State.MTU)
+	assert.True(t, *intf.State.Enabled)
+	assert.Equal(t, uint32(5), intf.State.IfIndex)
+	assert.Equal(t, "UP", intf.State.OperStatus)
+	assert.Equal(t, uint64(100), intf.State.Counters.InPkts)
+	assert.Equal(t, uint64(2), intf.State.Counters.CarrierTransitions)
+	assert.Len(t, intf.Subinterfaces, 1)
+	assert.Equal(t, "UP", intf.Subinterfaces[0].State.OperStatus)
+}
+
+func TestIETFInterfacesDoNotMatchOpenConfig(t *testing.T) {
+	result := &Interfaces{}
+	err := xml.Unmarshal([]byte(`<data><interfaces xmlns="http://openconfig.net/yang/interfaces">`+
+		`<interface><name>Ethernet1</name></interface></interfaces></data>`), &ops.Data{Body: result})
+	assert.Error(t, err, "Not expecting openconfig interfaces to decode as ietf interfaces")
+}
@@ -0,0 +1,88 @@
+package models
+
+import "encoding/xml"
+
+// Structs describing the openconfig-interfaces model.
+
+// OpenConfigInterfacesNamespace is the namespace of the openconfig-interfaces model.
+const OpenConfigInterfacesNamespace = "http://openconfig.net/yang/interfaces"
+
+// OpenConfigInterfacesFilter is a subtree filter selecting the openconfig-interfaces container.
+const OpenConfigInterfacesFilter = `<interfaces xmlns="` + OpenConfigInterfacesNamespace + `"/>`
+
+// OpenConfigInterfaces defines the openconfig-interfaces interfaces container.
+type OpenConfigInterfaces struct {
+	XMLName   xml.Name              `xml:"http://openconfig.net/yang/interfaces interfaces"`
+	Interface []OpenConfigInterface `xml:"interface"`
+}
+
+// OpenConfigInterface defines an entry in the interface list.
+type OpenConfigInterface struct {
+	Name          string                     `xml:"name"`
+	Config        *OpenConfigInterfaceConfig `xml:"config,omitempty"`
+	State         *OpenConfigInterfaceState  `xml:"state,omitempty"`
+	Subinterfaces []OpenConfigSubinterface   `xml:"subinterfaces>subinterface"`
+}
+
+// OpenConfigInterfaceConfig defines the configuration of an interface.
+type OpenConfigInterfaceConfig struct {
+	Name        string `xml:"name,omitempty"`
+	Type        string `xml:"type,omitempty"`
+	MTU         uint16 `xml:"mtu,omitempty"`
+	Description string `xml:"description,omitempty"`
+	Enabled     *bool  `xml:"enabled,omitempty"`
+}
+
+// OpenConfigInterfaceState defines the operational state of an interface.
+type OpenConfigInterfaceState struct {
+	OpenConfigInterfaceConfig
+	IfIndex     uint32                       `xml:"ifindex,omitempty"`
+	AdminStatus string                       `xml:"admin-status,omitempty"`
+	OperStatus  string                       `xml:"oper-status,omitempty"`
+	LastChange  uint64                       `xml:"last-change,omitempty"`
+	Logical     *bool                        `xml:"logical,omitempty"`
+	Counters    *OpenConfigInterfaceCounters `xml:"counters,omitempty"`
+}
+
+// OpenConfigInterfaceCounters defines the counters of an interface or subinterface.
+type OpenConfigInterfaceCounters struct {
+	InOctets           uint64 `xml:"in-octets"`
+	InPkts             uint64 `xml:"in-pkts"`
+	InUnicastPkts      uint64 `xml:"in-unicast-pkts"`
+	InBroadcastPkts    uint64 `xml:"in-broadcast-pkts"`
+	InMulticastPkts    uint64 `xml:"in-multicast-pkts"`
+	InDiscards         uint64 `xml:"in-discards"`
+	InErrors           uint64 `xml:"in-errors"`
+	InUnknownProtos    uint64 `xml:"in-unknown-protos"`
+	InFCSErrors        uint64 `xml:"in-fcs-errors"`
+	OutOctets          uint64 `xml:"out-octets"`
+	OutPkts            uint64 `xml:"out-pkts"`
+	OutUnicastPkts     uint64 `xml:"out-unicast-pkts"`
+	OutBroadcastPkts   uint64 `xml:"out-broadcast-pkts"`
+	OutMulticastPkts   uint64 `xml:"out-multicast-pkts"`
+	OutDiscards        uint64 `xml:"out-discards"`
+	OutErrors          uint64 `xml:"out-errors"`
+	CarrierTransitions uint64 `xml:"carrier-transitions"`
+	LastClear          uint64 `xml:"last-clear"`
+}
+
+// OpenConfigSubinterface defines an entry in the subinterface list of an interface.
+type OpenConfigSubinterface struct {
+	Index  uint32 `xml:"index"`
+	Config *struct {
+		Index       uint32 `xml:"index"`
+		Description string `xml:"description,omitempty"`
+		Enabled     *bool  `xml:"enabled,omitempty"`
+	} `xml:"config,omitempty"`
+	State *struct {
+		Index       uint32                       `xml:"index"`
+		Description string                       `xml:"description,omitempty"`
+		Enabled     *bool                        `xml:"enabled,omitempty"`
+		Name        string                       `xml:"name,omitempty"`
+		IfIndex     uint32                       `xml:"ifindex,omitempty"`
+		AdminStatus string                       `xml:"admin-status,omitempty"`
+		OperStatus  string                       `xml:"oper-status,omitempty"`
+		LastChange  uint64                       `xml:"last-change,omitempty"`
+		Counters    *OpenConfigInterfaceCounters `xml:"counters,omitempty"`
+	} `xml:"state,omitempty"`
+}
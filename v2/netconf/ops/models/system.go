@@ -0,0 +1,79 @@
+package models
+
+import "encoding/xml"
+
+// Structs describing the ietf-system model (RFC 7317).
+
+// IETFSystemNamespace is the namespace of the ietf-system model.
+const IETFSystemNamespace = "urn:ietf:params:xml:ns:yang:ietf-system"
+
+// Subtree filters selecting the ietf-system containers.
+const (
+	SystemFilter      = `<system xmlns="` + IETFSystemNamespace + `"/>`
+	SystemStateFilter = `<system-state xmlns="` + IETFSystemNamespace + `"/>`
+)
+
+// System defines the ietf-system system container.
+type System struct {
+	XMLName     xml.Name     `xml:"urn:ietf:params:xml:ns:yang:ietf-system system"`
+	Contact     string       `xml:"contact,omitempty"`
+	Hostname    string       `xml:"hostname,omitempty"`
+	Location    string       `xml:"location,omitempty"`
+	Clock       *SystemClock `xml:"clock,omitempty"`
+	NTP         *NTP         `xml:"ntp,omitempty"`
+	DNSResolver *DNSResolver `xml:"dns-resolver,omitempty"`
+}
+
+// SystemClock defines the system time zone configuration.
+type SystemClock struct {
+	TimezoneName      string `xml:"timezone-name,omitempty"`
+	TimezoneUTCOffset *int16 `xml:"timezone-utc-offset,omitempty"`
+}
+
+// NTP defines the NTP client configuration.
+type NTP struct {
+	Enabled *bool       `xml:"enabled,omitempty"`
+	Server  []NTPServer `xml:"server"`
+}
+
+// NTPServer defines an NTP server entry.
+type NTPServer struct {
+	Name            string `xml:"name"`
+	Address         string `xml:"udp>address"`
+	Port            uint16 `xml:"udp>port,omitempty"`
+	AssociationType string `xml:"association-type,omitempty"`
+	Iburst          *bool  `xml:"iburst,omitempty"`
+	Prefer          *bool  `xml:"prefer,omitempty"`
+}
+
+// DNSResolver defines the DNS resolver configuration.
+type DNSResolver struct {
+	Search  []string    `xml:"search"`
+	Server  []DNSServer `xml:"server"`
+	Options *struct {
+		Timeout  uint8 `xml:"timeout,omitempty"`
+		Attempts uint8 `xml:"attempts,omitempty"`
+	} `xml:"options,omitempty"`
+}
+
+// DNSServer defines a DNS server entry.
+type DNSServer struct {
+	Name    string `xml:"name"`
+	Address string `xml:"udp-and-tcp>address"`
+	Port    uint16 `xml:"udp-and-tcp>port,omitempty"`
+}
+
+// SystemState defines the ietf-system system-state container.
+type SystemState struct {
+	XMLName  xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-system system-state"`
+	Platform struct {
+		OSName    string `xml:"os-name"`
+		OSRelease string `xml:"os-release"`
+		OSVersion string `xml:"os-version"`
+		Machine   string `xml:"machine"`
+	} `xml:"platform"`
+	Clock struct {
+		CurrentDatetime string `xml:"current-datetime"`
+		BootDatetime    string `xml:"boot-datetime"`
+	} `xml:"clock"`
+}
@@ -0,0 +1,58 @@
+package ops
+
+import "encoding/xml"
+
+func (s *sImpl) RegisterNamespaces(nslist ...Namespace) {
+	s.nsMu.Lock()
+	defer s.nsMu.Unlock()
+
+	s.namespaces = mergeNamespaces(s.namespaces, append([]Namespace(nil), nslist...))
+}
+
+// Delivers the registered namespaces, merged with nslist.
+func (s *sImpl) mergeNamespaces(nslist []Namespace) []Namespace {
+	s.nsMu.RLock()
+	defer s.nsMu.RUnlock()
+
+	return mergeNamespaces(s.namespaces, nslist)
+}
+
+// Delivers the namespaces in base, with those in overrides replacing any with the same prefix, followed by the
+// remaining overrides.
+func mergeNamespaces(base, overrides []Namespace) []Namespace {
+	if len(base) == 0 {
+		return overrides
+	}
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make([]Namespace, 0, len(base)+len(overrides))
+	for _, ns := range base {
+		if !containsNamespace(overrides, ns.ID) {
+			merged = append(merged, ns)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+func containsNamespace(nslist []Namespace, id string) bool {
+	for _, ns := range nslist {
+		if ns.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivers the namespace declaration attributes for the namespaces.
+func namespaceAttrs(nslist []Namespace) []xml.Attr {
+	if len(nslist) == 0 {
+		return nil
+	}
+	attrs := make([]xml.Attr, len(nslist))
+	for i, ns := range nslist {
+		attrs[i] = xml.Attr{Name: xml.Name{Local: "xmlns:" + ns.ID}, Value: ns.Path}
+	}
+	return attrs
+}
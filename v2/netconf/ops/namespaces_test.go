@@ -0,0 +1,81 @@
+package ops
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+var (
+	ifNamespace  = Namespace{"if", "urn:ietf:params:xml:ns:yang:ietf-interfaces"}
+	sysNamespace = Namespace{"sys", "urn:ietf:params:xml:ns:yang:ietf-system"}
+)
+
+func TestRegisteredNamespacesSubtree(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	ncs.RegisterNamespaces(ifNamespace, sysNamespace)
+
+	mcli.On("Execute", createGetSubtreeRequest(`<if:interfaces/>`, ifNamespace, sysNamespace)).
+		Return(&common.RPCReply{Data: `<data><element attr1="ABC"/></data>`}, nil).Once()
+	mcli.On("Execute", createGetConfigSubtreeRequest(`<if:interfaces/>`, RunningCfg, ifNamespace, sysNamespace)).
+		Return(&common.RPCReply{Data: `<data><element attr1="ABC"/></data>`}, nil).Once()
+
+	var result string
+	assert.NoError(t, ncs.GetSubtree(`<if:interfaces/>`, &result))
+	assert.NoError(t, ncs.GetConfigSubtree(`<if:interfaces/>`, RunningCfg, &result))
+
+	mcli.AssertExpectations(t)
+}
+
+func TestRegisteredNamespacesXpath(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	ncs.RegisterNamespaces(ifNamespace, sysNamespace)
+	override := Namespace{"sys", "urn:example:system"}
+
+	mcli.On("Execute", createGetXpathRequest(`/if:interfaces`, []Namespace{ifNamespace, override})).
+		Return(&common.RPCReply{Data: `<data/>`}, nil).Once()
+	mcli.On("Execute", createGetConfigXpathRequest(`/if:interfaces`, RunningCfg, []Namespace{ifNamespace, sysNamespace})).
+		Return(&common.RPCReply{Data: `<data/>`}, nil).Once()
+
+	var result string
+	assert.NoError(t, ncs.GetXpath(`/if:interfaces`, []Namespace{override}, &result))
+	assert.NoError(t, ncs.GetConfigXpath(`/if:interfaces`, nil, RunningCfg, &result))
+
+	mcli.AssertExpectations(t)
+}
+
+func TestRegisteredNamespacesEditConfig(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	ncs.RegisterNamespaces(ifNamespace)
+
+	expected := createEditConfigRequest(CandidateCfg, Cfg(`<if:interfaces/>`))
+	expected.Config.Attrs = namespaceAttrs([]Namespace{ifNamespace})
+	mcli.On("Execute", expected).Return(&common.RPCReply{}, nil).Once()
+
+	assert.NoError(t, ncs.EditConfig(CandidateCfg, Cfg(`<if:interfaces/>`)))
+
+	b, err := xml.Marshal(expected)
+	assert.NoError(t, err)
+	assert.Equal(t, `<edit-config><target><candidate/></target>`+
+		`<config xmlns:if="urn:ietf:params:xml:ns:yang:ietf-interfaces"><if:interfaces/></config></edit-config>`, string(b))
+
+	mcli.AssertExpectations(t)
+}
+
+func TestRegisterNamespacesReplacesPrefix(t *testing.T) {
+	ncs, _ := newOpsSessionWithMockClient(t)
+	ncs.RegisterNamespaces(ifNamespace, sysNamespace)
+	ncs.RegisterNamespaces(Namespace{"if", "urn:example:interfaces"})
+
+	assert.Equal(t, []Namespace{sysNamespace, {"if", "urn:example:interfaces"}}, ncs.(*sImpl).mergeNamespaces(nil))
+}
+
+func TestSubtreeFilterNamespaceDeclarations(t *testing.T) {
+	b, err := xml.Marshal(createGetSubtreeRequest(`<if:interfaces/>`, ifNamespace))
+	assert.NoError(t, err)
+	assert.Equal(t, `<get><filter type="subtree" xmlns:if="urn:ietf:params:xml:ns:yang:ietf-interfaces">`+
+		`<if:interfaces/></filter></get>`, string(b))
+}
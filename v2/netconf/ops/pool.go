@@ -0,0 +1,372 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// Default configuration of a SessionPool.
+const (
+	defaultPoolMaxIdle      = 2
+	defaultPoolIdleTimeout  = 5 * time.Minute
+	defaultPoolPingAfter    = 30 * time.Second
+	defaultPoolPingTimeout  = 10 * time.Second
+	defaultPoolReapInterval = time.Minute
+)
+
+// ErrPoolClosed is returned by SessionPool.Borrow once the pool has been closed.
+var ErrPoolClosed = errors.New("session pool closed")
+
+// Request used to check the health of an idle session; an empty subtree filter selects no data.
+const pingRequest = `<get><filter type="subtree"/></get>`
+
+// SessionFactory creates a new session with the target, for use by a SessionPool.
+// For example:
+//
+//	func(ctx context.Context, target string) (OpSession, error) {
+//	    return NewSession(ctx, sshConfig, target)
+//	}
+type SessionFactory func(ctx context.Context, target string) (OpSession, error)
+
+// SessionPool maintains idle sessions keyed by target, so that sessions can be reused by clients that issue
+// occasional requests to many targets without incurring the cost of session setup for every request.
+//
+// A session is obtained by Borrow and must be handed back by either Return, if it remains usable, or Discard, if it
+// has failed. Idle sessions are closed by a background reaper once they exceed the idle timeout or maximum lifetime,
+// and are checked with a <get> request that selects no data before being lent if they have been idle for a while.
+//
+// A SessionPool is safe for concurrent use.
+type SessionPool struct {
+	factory SessionFactory
+	cfg     poolConfig
+
+	mu       sync.Mutex
+	idle     map[string][]*pooledSession
+	borrowed map[OpSession]*pooledSession
+	closed   bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// PoolStats defines the statistics of a session pool.
+type PoolStats struct {
+	// The number of idle sessions held by the pool.
+	Idle int
+	// The number of sessions currently borrowed from the pool.
+	InUse int
+}
+
+type pooledSession struct {
+	OpSession
+	target   string
+	created  time.Time
+	lastUsed time.Time
+}
+
+// PoolOption configures the behaviour of a SessionPool.
+type PoolOption func(*poolConfig)
+
+type poolConfig struct {
+	maxIdle      int
+	idleTimeout  time.Duration
+	maxLifetime  time.Duration
+	pingAfter    time.Duration
+	pingTimeout  time.Duration
+	reapInterval time.Duration
+}
+
+// PoolMaxIdle defines the maximum number of idle sessions retained for each target; sessions returned when the
+// limit has been reached are closed.
+// Default is 2.
+func PoolMaxIdle(n int) PoolOption {
+	return func(c *poolConfig) {
+		c.maxIdle = n
+	}
+}
+
+// PoolIdleTimeout defines the period after which an idle session is closed. Zero means idle sessions are retained
+// indefinitely.
+// Default is 5 minutes.
+func PoolIdleTimeout(timeout time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.idleTimeout = timeout
+	}
+}
+
+// PoolMaxLifetime defines the period after which a session is closed, measured from its creation. A borrowed
+// session that exceeds its lifetime is closed when it is returned. Zero means sessions have no maximum lifetime.
+// Default is zero.
+func PoolMaxLifetime(lifetime time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.maxLifetime = lifetime
+	}
+}
+
+// PoolPingAfter defines the period of idleness after which a session is checked with a <get> request before it is
+// lent; sessions that fail the check are closed. Zero means sessions are always checked, and a negative value
+// means they are never checked.
+// Default is 30 seconds.
+func PoolPingAfter(idle time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.pingAfter = idle
+	}
+}
+
+// PoolPingTimeout defines the period within which the reply to the <get> request checking an idle session must be
+// received; a session that does not reply in time, or before the context passed to Borrow is done, is closed.
+// Default is 10 seconds.
+func PoolPingTimeout(timeout time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.pingTimeout = timeout
+	}
+}
+
+// PoolReapInterval defines the interval at which idle sessions are checked against the idle timeout and maximum
+// lifetime. Zero or a negative value disables the background reaper, so expired idle sessions are only closed when
+// the pool is next asked for a session with their target.
+// Default is 1 minute.
+func PoolReapInterval(interval time.Duration) PoolOption {
+	return func(c *poolConfig) {
+		c.reapInterval = interval
+	}
+}
+
+// NewSessionPool creates a session pool that uses factory to create sessions.
+// The pool should be closed when no longer required, to release its idle sessions.
+func NewSessionPool(factory SessionFactory, options ...PoolOption) *SessionPool {
+	cfg := poolConfig{
+		maxIdle:      defaultPoolMaxIdle,
+		idleTimeout:  defaultPoolIdleTimeout,
+		pingAfter:    defaultPoolPingAfter,
+		pingTimeout:  defaultPoolPingTimeout,
+		reapInterval: defaultPoolReapInterval,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	p := &SessionPool{
+		factory:  factory,
+		cfg:      cfg,
+		idle:     map[string][]*pooledSession{},
+		borrowed: map[OpSession]*pooledSession{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.reap()
+	return p
+}
+
+// Borrow delivers a session with the target, reusing an idle session if one is available, or creating a new
+// session otherwise.
+func (p *SessionPool) Borrow(ctx context.Context, target string) (OpSession, error) {
+	for {
+		ps, err := p.takeIdle(target)
+		if err != nil {
+			return nil, err
+		}
+		if ps == nil {
+			break
+		}
+		if p.healthy(ctx, ps) {
+			p.lend(ps)
+			return ps.OpSession, nil
+		}
+		ps.Close()
+	}
+
+	s, err := p.factory(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	p.lend(&pooledSession{OpSession: s, target: target, created: now, lastUsed: now})
+	return s, nil
+}
+
+// Return hands a borrowed session back to the pool, for reuse by subsequent callers.
+// The session is closed if the pool is closed, the session has exceeded its lifetime, or the pool already holds
+// the maximum number of idle sessions for the target.
+// Sessions that were not borrowed from the pool are ignored.
+func (p *SessionPool) Return(s OpSession) {
+	p.mu.Lock()
+	ps, ok := p.borrowed[s]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.borrowed, s)
+
+	ps.lastUsed = time.Now()
+	if p.closed || p.expired(ps, ps.lastUsed) || len(p.idle[ps.target]) >= p.cfg.maxIdle {
+		p.mu.Unlock()
+		s.Close()
+		return
+	}
+	p.idle[ps.target] = append(p.idle[ps.target], ps)
+	p.mu.Unlock()
+}
+
+// Discard closes a borrowed session that is no longer usable, for example following a transport failure, and
+// removes it from the pool.
+// Sessions that were not borrowed from the pool are ignored.
+func (p *SessionPool) Discard(s OpSession) {
+	p.mu.Lock()
+	_, ok := p.borrowed[s]
+	delete(p.borrowed, s)
+	p.mu.Unlock()
+
+	if ok {
+		s.Close()
+	}
+}
+
+// Stats delivers the statistics of the pool.
+func (p *SessionPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := PoolStats{InUse: len(p.borrowed)}
+	for _, sessions := range p.idle {
+		stats.Idle += len(sessions)
+	}
+	return stats
+}
+
+// Close closes all idle sessions and stops the reaper. Sessions that are currently borrowed are closed when they
+// are returned.
+func (p *SessionPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = map[string][]*pooledSession{}
+	p.mu.Unlock()
+
+	close(p.stop)
+	<-p.done
+	for _, sessions := range idle {
+		closeSessions(sessions)
+	}
+}
+
+// Removes the most recently used idle session for the target, closing any that have expired.
+func (p *SessionPool) takeIdle(target string) (*pooledSession, error) {
+	var expired []*pooledSession
+	defer func() {
+		closeSessions(expired)
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	now := time.Now()
+	for sessions := p.idle[target]; len(sessions) > 0; sessions = p.idle[target] {
+		ps := sessions[len(sessions)-1]
+		p.idle[target] = sessions[:len(sessions)-1]
+		if !p.expired(ps, now) {
+			return ps, nil
+		}
+		expired = append(expired, ps)
+	}
+	return nil, nil
+}
+
+func (p *SessionPool) lend(ps *pooledSession) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.borrowed[ps.OpSession] = ps
+}
+
+// Checks an idle session with a ping request, if it has been idle beyond the ping threshold. The session is unhealthy
+// if the reply is not received within the ping timeout, or before ctx is done.
+func (p *SessionPool) healthy(ctx context.Context, ps *pooledSession) bool {
+	if p.cfg.pingAfter < 0 || time.Since(ps.lastUsed) < p.cfg.pingAfter {
+		return true
+	}
+	if p.cfg.pingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.cfg.pingTimeout)
+		defer cancel()
+	}
+
+	// The reply channel is buffered, so that a late reply to an abandoned ping does not block the session.
+	rchan := make(chan *common.RPCReply, 1)
+	if err := ps.ExecuteAsync(pingRequest, rchan); err != nil {
+		return false
+	}
+	select {
+	case reply := <-rchan:
+		return replyError(reply) == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Determines whether a session has exceeded its idle timeout or lifetime at time now.
+func (p *SessionPool) expired(ps *pooledSession, now time.Time) bool {
+	if p.cfg.maxLifetime > 0 && now.Sub(ps.created) >= p.cfg.maxLifetime {
+		return true
+	}
+	return p.cfg.idleTimeout > 0 && now.Sub(ps.lastUsed) >= p.cfg.idleTimeout
+}
+
+// Periodically closes idle sessions that have expired, until the pool is closed.
+func (p *SessionPool) reap() {
+	defer close(p.done)
+
+	if p.cfg.reapInterval <= 0 {
+		<-p.stop
+		return
+	}
+	ticker := time.NewTicker(p.cfg.reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			closeSessions(p.removeExpired())
+		}
+	}
+}
+
+func (p *SessionPool) removeExpired() (expired []*pooledSession) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for target, sessions := range p.idle {
+		retained := sessions[:0]
+		for _, ps := range sessions {
+			if p.expired(ps, now) {
+				expired = append(expired, ps)
+			} else {
+				retained = append(retained, ps)
+			}
+		}
+		if len(retained) == 0 {
+			delete(p.idle, target)
+		} else {
+			p.idle[target] = retained
+		}
+	}
+	return
+}
+
+func closeSessions(sessions []*pooledSession) {
+	for _, ps := range sessions {
+		ps.Close()
+	}
+}
@@ -0,0 +1,285 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// Minimal session used to observe the behaviour of the pool.
+type poolTestSession struct {
+	OpSession
+	id      uint64
+	target  string
+	pingErr error
+	// Set to hold the replies to asynchronous requests.
+	hang bool
+
+	mu     sync.Mutex
+	pings  int
+	closed bool
+}
+
+func (s *poolTestSession) Execute(req common.Request) (*common.RPCReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pings++
+	return &common.RPCReply{}, s.pingErr
+}
+
+func (s *poolTestSession) ExecuteAsync(req common.Request, rchan chan *common.RPCReply) error {
+	reply, err := s.Execute(req)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hang {
+		rchan <- reply
+	}
+	return nil
+}
+
+func (s *poolTestSession) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *poolTestSession) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+type poolTestFactory struct {
+	mu       sync.Mutex
+	sessions []*poolTestSession
+	err      error
+}
+
+func (f *poolTestFactory) create(ctx context.Context, target string) (OpSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	s := &poolTestSession{id: uint64(len(f.sessions) + 1), target: target}
+	f.sessions = append(f.sessions, s)
+	return s, nil
+}
+
+func (f *poolTestFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sessions)
+}
+
+func TestPoolReusesSessions(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolPingAfter(-1))
+	defer p.Close()
+
+	s1, err := p.Borrow(context.Background(), "a")
+	assert.NoError(t, err)
+	s2, err := p.Borrow(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.NotEqual(t, s1, s2, "Expecting distinct sessions whilst both borrowed")
+	assert.Equal(t, PoolStats{InUse: 2}, p.Stats())
+
+	p.Return(s1)
+	assert.Equal(t, PoolStats{Idle: 1, InUse: 1}, p.Stats())
+
+	s3, err := p.Borrow(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, s1, s3, "Expecting idle session to be reused")
+
+	s4, err := p.Borrow(context.Background(), "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", s4.(*poolTestSession).target, "Expecting sessions to be keyed by target")
+	assert.Equal(t, 3, f.count())
+}
+
+func TestPoolMaxIdle(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolMaxIdle(1))
+	defer p.Close()
+
+	s1, _ := p.Borrow(context.Background(), "a")
+	s2, _ := p.Borrow(context.Background(), "a")
+	p.Return(s1)
+	p.Return(s2)
+
+	assert.Equal(t, PoolStats{Idle: 1}, p.Stats())
+	assert.False(t, s1.(*poolTestSession).isClosed())
+	assert.True(t, s2.(*poolTestSession).isClosed(), "Expecting session beyond idle limit to be closed")
+}
+
+func TestPoolDiscard(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create)
+	defer p.Close()
+
+	s, _ := p.Borrow(context.Background(), "a")
+	p.Discard(s)
+	assert.True(t, s.(*poolTestSession).isClosed())
+	assert.Equal(t, PoolStats{}, p.Stats())
+
+	// Sessions that were not borrowed are ignored.
+	other := &poolTestSession{}
+	p.Return(other)
+	p.Discard(other)
+	assert.False(t, other.isClosed())
+	assert.Equal(t, PoolStats{}, p.Stats())
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolPingAfter(0))
+	defer p.Close()
+
+	s1, _ := p.Borrow(context.Background(), "a")
+	p.Return(s1)
+	s2, _ := p.Borrow(context.Background(), "a")
+	assert.Equal(t, s1, s2, "Expecting healthy session to be reused")
+	assert.Equal(t, 1, s1.(*poolTestSession).pings, "Expecting idle session to be pinged")
+
+	s1.(*poolTestSession).pingErr = errors.New("failed")
+	p.Return(s1)
+	s3, err := p.Borrow(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.NotEqual(t, s1, s3, "Expecting unhealthy session to be replaced")
+	assert.True(t, s1.(*poolTestSession).isClosed(), "Expecting unhealthy session to be closed")
+}
+
+func TestPoolHealthCheckTimeout(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolPingAfter(0), PoolPingTimeout(10*time.Millisecond))
+	defer p.Close()
+
+	s1, _ := p.Borrow(context.Background(), "a")
+	s1.(*poolTestSession).hang = true
+	p.Return(s1)
+	s2, err := p.Borrow(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.NotEqual(t, s1, s2, "Expecting session that does not reply to be replaced")
+	assert.True(t, s1.(*poolTestSession).isClosed(), "Expecting session that does not reply to be closed")
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolIdleTimeout(50*time.Millisecond), PoolReapInterval(10*time.Millisecond))
+	defer p.Close()
+
+	s, _ := p.Borrow(context.Background(), "a")
+	p.Return(s)
+	assert.Equal(t, PoolStats{Idle: 1}, p.Stats())
+
+	assert.Eventually(t, s.(*poolTestSession).isClosed, time.Second, 10*time.Millisecond,
+		"Expecting idle session to be reaped")
+	assert.Equal(t, PoolStats{}, p.Stats())
+}
+
+func TestPoolReaperDisabled(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolIdleTimeout(time.Millisecond), PoolReapInterval(0))
+
+	s1, _ := p.Borrow(context.Background(), "a")
+	p.Return(s1)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, PoolStats{Idle: 1}, p.Stats(), "Not expecting idle session to be reaped")
+
+	s2, err := p.Borrow(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.NotEqual(t, s1, s2, "Expecting expired session to be replaced")
+	assert.True(t, s1.(*poolTestSession).isClosed())
+	p.Close()
+}
+
+func TestPoolMaxLifetime(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create, PoolMaxLifetime(20*time.Millisecond), PoolReapInterval(time.Hour))
+	defer p.Close()
+
+	s1, _ := p.Borrow(context.Background(), "a")
+	p.Return(s1)
+	time.Sleep(30 * time.Millisecond)
+
+	s2, _ := p.Borrow(context.Background(), "a")
+	assert.NotEqual(t, s1, s2, "Expecting expired session to be replaced")
+	assert.True(t, s1.(*poolTestSession).isClosed(), "Expecting expired session to be closed")
+
+	time.Sleep(30 * time.Millisecond)
+	p.Return(s2)
+	assert.True(t, s2.(*poolTestSession).isClosed(), "Expecting expired session to be closed on return")
+	assert.Equal(t, PoolStats{}, p.Stats())
+}
+
+func TestPoolClose(t *testing.T) {
+	f := &poolTestFactory{}
+	p := NewSessionPool(f.create)
+
+	s1, _ := p.Borrow(context.Background(), "a")
+	s2, _ := p.Borrow(context.Background(), "a")
+	p.Return(s1)
+
+	p.Close()
+	p.Close()
+	assert.True(t, s1.(*poolTestSession).isClosed(), "Expecting idle session to be closed")
+	assert.False(t, s2.(*poolTestSession).isClosed(), "Expecting borrowed session to remain open")
+
+	p.Return(s2)
+	assert.True(t, s2.(*poolTestSession).isClosed(), "Expecting session returned to closed pool to be closed")
+
+	_, err := p.Borrow(context.Background(), "a")
+	assert.ErrorIs(t, err, ErrPoolClosed)
+}
+
+func TestPoolFactoryFailure(t *testing.T) {
+	f := &poolTestFactory{err: errors.New("connection refused")}
+	p := NewSessionPool(f.create)
+	defer p.Close()
+
+	s, err := p.Borrow(context.Background(), "a")
+	assert.EqualError(t, err, "connection refused")
+	assert.Nil(t, s)
+	assert.Equal(t, PoolStats{}, p.Stats())
+}
+
+func TestPoolWithNetconfSessions(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	p := NewSessionPool(func(ctx context.Context, target string) (OpSession, error) {
+		return NewSession(ctx, sshConfig, target)
+	}, PoolPingAfter(0))
+	defer p.Close()
+
+	target := fmt.Sprintf("localhost:%d", ts.Port())
+	s1, err := p.Borrow(context.Background(), target)
+	assert.NoError(t, err)
+	p.Return(s1)
+
+	s2, err := p.Borrow(context.Background(), target)
+	assert.NoError(t, err)
+	assert.Equal(t, s1.ID(), s2.ID(), "Expecting session to be reused")
+
+	sh := ts.SessionHandler(s2.ID())
+	assert.Equal(t, 1, sh.ReqCount(), "Expecting health check request")
+	assert.Contains(t, sh.LastReq().Body, `<filter type="subtree"/>`)
+	p.Return(s2)
+}
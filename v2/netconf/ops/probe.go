@@ -0,0 +1,119 @@
+package ops
+
+import (
+	"context"
+	"time"
+
+	"github.com/damianoneill/net/v2/internal/keepalive"
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// Capabilities reported by Probe.
+const (
+	CapCandidate      = "urn:ietf:params:netconf:capability:candidate:1.0"
+	CapNotification   = "urn:ietf:params:netconf:capability:notification:1.0"
+	CapYangLibrary10  = "urn:ietf:params:netconf:capability:yang-library:1.0"
+	CapYangLibrary11  = "urn:ietf:params:netconf:capability:yang-library:1.1"
+	YangLibraryModule = "urn:ietf:params:xml:ns:yang:ietf-yang-library"
+)
+
+// ProbeReport describes the reachability and capabilities of a device, as determined by Probe.
+type ProbeReport struct {
+	Target string
+	// Indicates that an ssh connection was established and authenticated.
+	SSHReachable bool
+	// The round trip time of an ssh request on the established connection.
+	RTT time.Duration
+	// Indicates that the device accepted a request for the netconf subsystem.
+	SubsystemAvailable bool
+	// Indicates that a netconf session was established, in which case the remaining fields describe the hello
+	// message received from the device.
+	NetconfAvailable bool
+	SessionID        uint64
+	Capabilities     []string
+	// The netconf base protocol versions supported by the device.
+	Base10 bool
+	Base11 bool
+	// Indicates support for the candidate datastore, xpath filters and event notifications.
+	Candidate     bool
+	XPath         bool
+	Notifications bool
+	// Indicates support for the yang library, either by the yang-library capability, or by advertising the
+	// ietf-yang-library module.
+	YangLibrary bool
+}
+
+// Probe connects to the target using the ssh configuration and establishes a netconf session, reporting how far
+// the device could be reached and the capabilities it advertises, so that devices can be classified before they are
+// used. The session is closed before Probe returns.
+// The report is always delivered; if any stage fails, the error describes the failure, and the report describes the
+// stages that succeeded. Options that configure session establishment, such as WithSetupTimeout and WithTrace,
+// are honoured.
+func Probe(ctx context.Context, sshcfg *ssh.ClientConfig, target string, opts ...SessionOption) (*ProbeReport, error) {
+	so := &sessionOptions{cfg: *client.DefaultConfig}
+	for _, opt := range opts {
+		opt(so)
+	}
+	if so.trace != nil {
+		ctx = client.WithClientTrace(ctx, so.trace)
+	}
+
+	report := &ProbeReport{Target: target}
+
+	sshClient, err := client.NewDialer(target, sshcfg).Dial(ctx)
+	if err != nil {
+		return report, errors.Wrap(err, "ssh connection failed")
+	}
+	defer sshClient.Close()
+	report.SSHReachable = true
+
+	begin := time.Now()
+	if _, _, err = sshClient.SendRequest(keepalive.RequestType, true, nil); err != nil {
+		return report, errors.Wrap(err, "ssh request failed")
+	}
+	report.RTT = time.Since(begin)
+
+	t, err := client.NewSSHTransport(ctx, &probeDialer{client: sshClient}, target)
+	if err != nil {
+		return report, errors.Wrap(err, "netconf subsystem unavailable")
+	}
+	report.SubsystemAvailable = true
+
+	s, err := client.NewSession(ctx, t, &so.cfg)
+	if err != nil {
+		_ = t.Close()
+		return report, errors.Wrap(err, "netconf session failed")
+	}
+	defer s.Close()
+
+	report.setCapabilities(s.ID(), s.ServerCapabilities())
+	return report, nil
+}
+
+func (r *ProbeReport) setCapabilities(sid uint64, caps []string) {
+	r.NetconfAvailable = true
+	r.SessionID = sid
+	r.Capabilities = caps
+	r.Base10 = hasCapability(caps, common.CapBase10)
+	r.Base11 = hasCapability(caps, common.CapBase11)
+	r.Candidate = hasCapability(caps, CapCandidate)
+	r.XPath = hasCapability(caps, common.CapXpath)
+	r.Notifications = hasCapability(caps, CapNotification)
+	r.YangLibrary = hasCapability(caps, CapYangLibrary10, CapYangLibrary11, YangLibraryModule)
+}
+
+// Delivers the ssh client established by Probe to the netconf transport, leaving Probe to close it.
+type probeDialer struct {
+	client *ssh.Client
+}
+
+func (d *probeDialer) Dial(ctx context.Context) (*ssh.Client, error) {
+	return d.client, nil
+}
+
+func (d *probeDialer) Close(*ssh.Client) error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func probeSSHConfig() *ssh.ClientConfig {
+	return &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+}
+
+func TestProbe(t *testing.T) {
+	caps := []string{
+		common.CapBase10,
+		common.CapBase11,
+		CapCandidate,
+		CapNotification,
+		YangLibraryModule + "?module=ietf-yang-library&revision=2016-06-21",
+	}
+	ts := testserver.NewTestNetconfServer(t).WithCapabilities(caps)
+	defer ts.Close()
+
+	report, err := Probe(context.Background(), probeSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	assert.True(t, report.SSHReachable)
+	assert.True(t, report.RTT > 0, "Expecting round trip time to be measured")
+	assert.True(t, report.SubsystemAvailable)
+	assert.True(t, report.NetconfAvailable)
+	assert.NotZero(t, report.SessionID)
+	assert.Equal(t, caps, report.Capabilities)
+	assert.True(t, report.Base10)
+	assert.True(t, report.Base11)
+	assert.True(t, report.Candidate)
+	assert.False(t, report.XPath)
+	assert.True(t, report.Notifications)
+	assert.True(t, report.YangLibrary)
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	report, err := Probe(context.Background(), probeSSHConfig(), "localhost:0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ssh connection failed")
+	assert.Equal(t, &ProbeReport{Target: "localhost:0"}, report)
+}
+
+func TestProbeSubsystemUnavailable(t *testing.T) {
+	ts := testserver.NewSSHServerHandler(t, testserver.TestUserName, testserver.TestPassword,
+		func(t assert.TestingT) testserver.SSHHandler { return &closingHandler{} }, testserver.RequestTypes(nil))
+	defer ts.Close()
+
+	report, err := Probe(context.Background(), probeSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "netconf subsystem unavailable")
+	assert.True(t, report.SSHReachable)
+	assert.False(t, report.SubsystemAvailable)
+	assert.False(t, report.NetconfAvailable)
+}
+
+// An ssh channel handler that closes the channel immediately.
+type closingHandler struct{}
+
+func (h *closingHandler) Handle(t assert.TestingT, ch ssh.Channel) {}
+
+func TestProbeNoHello(t *testing.T) {
+	ts := testserver.NewSSHServer(t, testserver.TestUserName, testserver.TestPassword)
+	defer ts.Close()
+
+	report, err := Probe(context.Background(), probeSSHConfig(), fmt.Sprintf("localhost:%d", ts.Port()), WithSetupTimeout(1))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "netconf session failed")
+	assert.True(t, report.SSHReachable)
+	assert.True(t, report.SubsystemAvailable)
+	assert.False(t, report.NetconfAvailable)
+	assert.Nil(t, report.Capabilities)
+}
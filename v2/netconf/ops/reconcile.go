@@ -0,0 +1,442 @@
+package ops
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/pkg/errors"
+)
+
+// Reconciliation of configuration.
+// Reconcile compares intended and actual configuration, as XML documents such as get-config results, and delivers
+// the edit operations that move a device from the actual to the intended configuration. Apply executes the
+// operations against the candidate datastore as a single transaction.
+//
+// No schema is used, so list entries are identified by their keys, which must be defined by ListKeys. An element
+// without children that occurs more than once among its siblings is treated as a leaf-list entry, identified by its
+// value. Any other element that is not defined by ListKeys is treated as a container, whose leaves are replaced when
+// their values differ, so a list that is not defined by ListKeys may hold at most one entry. Attributes other than
+// namespace declarations are ignored.
+
+// ErrCandidateUnsupported is returned by Apply when the server does not support the candidate datastore.
+var ErrCandidateUnsupported = errors.New("candidate datastore not supported")
+
+// EditOperation defines an edit operation that is part of the reconciliation of configuration.
+type EditOperation struct {
+	// The edit-config operation, which is CreateOp, DeleteOp or ReplaceOp.
+	Operation string
+	// Identifies the element to which the operation applies by the local names of its ancestors, qualified by the
+	// keys of list entries, for example "/interfaces/interface[name='eth0']/mtu".
+	Path string
+	// The configuration of an edit-config request that performs the operation, nesting the element within its
+	// ancestors, which are identified by their keys. It is applied with a default operation of none.
+	Config string
+}
+
+// ReconcileOption implements options for configuring Reconcile behaviour.
+type ReconcileOption func(*reconcileConfig)
+
+// ListKeys defines the key leaves of lists, keyed by the path of local element names from the top-level element of
+// the configuration, for example "interfaces/interface", as for ConfigDefaults.
+func ListKeys(keys map[string][]string) ReconcileOption {
+	return func(c *reconcileConfig) {
+		c.keys = keys
+	}
+}
+
+type reconcileConfig struct {
+	keys map[string][]string
+}
+
+// Reconcile delivers the edit operations that move configuration from actual to intended. For the children of each
+// element, the deletion of elements missing from intended precedes the creation of elements missing from actual and
+// the replacement of leaves whose values differ, which follow the order of intended.
+// The configuration may be wrapped in a <data> or <config> element.
+func Reconcile(intended, actual string, options ...ReconcileOption) ([]EditOperation, error) {
+	cfg := &reconcileConfig{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	in, err := parseConfigTree(intended)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse intended configuration")
+	}
+	act, err := parseConfigTree(actual)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse actual configuration")
+	}
+
+	r := &reconciler{cfg: cfg}
+	if err = r.diff(nil, "", "", act, in); err != nil {
+		return nil, err
+	}
+	return r.operations, nil
+}
+
+// Apply executes the operations against the candidate datastore, which is locked for the duration, by a single
+// edit-config request merging their configuration, and commits the result. If the edit or the commit fails, the
+// changes to the candidate are discarded and an error identifying the failure is returned.
+// ErrCandidateUnsupported is returned if the server does not advertise the candidate capability.
+func Apply(s OpSession, operations []EditOperation) (err error) {
+	if len(operations) == 0 {
+		return nil
+	}
+	if !hasCapability(s.ServerCapabilities(), CapCandidate) {
+		return ErrCandidateUnsupported
+	}
+	config, err := mergeOperations(operations)
+	if err != nil {
+		return err
+	}
+	if err = s.Lock(CandidateCfg); err != nil {
+		return errors.Wrap(err, "failed to lock candidate")
+	}
+	defer func() {
+		_ = s.Unlock(CandidateCfg)
+	}()
+
+	if err = s.EditConfig(CandidateCfg, Cfg(config), DefaultOperation(NoneOp)); err != nil {
+		_ = s.Discard()
+		return errors.Wrapf(err, "failed to apply %d reconciliation operations", len(operations))
+	}
+	if err = s.Commit(); err != nil {
+		_ = s.Discard()
+		return errors.Wrap(err, "failed to commit reconciled configuration")
+	}
+	return nil
+}
+
+// Defines an element of configuration.
+type configNode struct {
+	name     xml.Name
+	text     string
+	children []*configNode
+	// The edit-config operation of an element parsed from the configuration of an EditOperation.
+	operation string
+}
+
+func (n *configNode) isLeaf() bool {
+	return len(n.children) == 0
+}
+
+// Delivers the leaf child with the local name, or nil.
+func (n *configNode) leaf(name string) *configNode {
+	for _, c := range n.children {
+		if c.name.Local == name && c.isLeaf() {
+			return c
+		}
+	}
+	return nil
+}
+
+// Delivers the top-level elements of the configuration, removing any <data> or <config> wrapper.
+func parseConfigTree(content string) ([]*configNode, error) {
+	root := &configNode{}
+	stack := []*configNode{root}
+	var text strings.Builder
+
+	d := xml.NewDecoder(strings.NewReader(content))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := &configNode{name: t.Name}
+			for _, a := range t.Attr {
+				if a.Name.Space == common.NetconfNS && a.Name.Local == "operation" {
+					n.operation = a.Value
+				}
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if n.isLeaf() {
+				n.text = strings.TrimSpace(text.String())
+			}
+			text.Reset()
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, errors.Errorf("element <%s> is not closed", stack[len(stack)-1].name.Local)
+	}
+
+	top := root.children
+	if len(top) == 1 && !top[0].isLeaf() && (top[0].name.Local == "data" || top[0].name.Local == "config") {
+		top = top[0].children
+	}
+	return top, nil
+}
+
+// Identifies an ancestor of the element to which an operation applies.
+type ancestor struct {
+	node *configNode
+	// The key leaves of a list entry.
+	keys []*configNode
+}
+
+// Identifies an element among its siblings.
+type configEntry struct {
+	node *configNode
+	id   string
+	// The key leaves of a list entry.
+	keys []*configNode
+	// The predicate appended to the element's path, for example [name='eth0'].
+	predicate string
+}
+
+type reconciler struct {
+	cfg        *reconcileConfig
+	operations []EditOperation
+}
+
+// Adds the operations that reconcile the children of an element, whose path and schema path are supplied.
+func (r *reconciler) diff(ancestors []ancestor, path, schemaPath string, actual, intended []*configNode) error {
+	lists := repeatedNames(actual, intended)
+	act, err := r.entries(schemaPath, actual, lists)
+	if err != nil {
+		return err
+	}
+	in, err := r.entries(schemaPath, intended, lists)
+	if err != nil {
+		return err
+	}
+
+	intendedIDs := make(map[string]bool, len(in))
+	for _, e := range in {
+		intendedIDs[e.id] = true
+	}
+	actualByID := make(map[string]*configEntry, len(act))
+	for _, e := range act {
+		actualByID[e.id] = e
+		if !intendedIDs[e.id] {
+			r.add(DeleteOp, ancestors, path, e)
+		}
+	}
+
+	for _, e := range in {
+		a, ok := actualByID[e.id]
+		switch {
+		case !ok:
+			r.add(CreateOp, ancestors, path, e)
+		case e.node.isLeaf() != a.node.isLeaf():
+			r.add(ReplaceOp, ancestors, path, e)
+		case e.node.isLeaf():
+			if e.node.text != a.node.text {
+				r.add(ReplaceOp, ancestors, path, e)
+			}
+		default:
+			err = r.diff(append(ancestors[:len(ancestors):len(ancestors)], ancestor{node: e.node, keys: e.keys}),
+				path+"/"+e.node.name.Local+e.predicate, joinPath(schemaPath, e.node.name.Local),
+				a.node.children, e.node.children)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Delivers the local names that occur more than once among the siblings of either configuration.
+func repeatedNames(actual, intended []*configNode) map[string]bool {
+	repeated := map[string]bool{}
+	for _, nodes := range [][]*configNode{actual, intended} {
+		seen := map[string]bool{}
+		for _, n := range nodes {
+			if seen[n.name.Local] {
+				repeated[n.name.Local] = true
+			}
+			seen[n.name.Local] = true
+		}
+	}
+	return repeated
+}
+
+// Identifies the elements among their siblings, which must be unique.
+func (r *reconciler) entries(schemaPath string, nodes []*configNode, lists map[string]bool) ([]*configEntry, error) {
+	entries := make([]*configEntry, len(nodes))
+	ids := make(map[string]bool, len(nodes))
+	for i, n := range nodes {
+		e, err := r.entry(joinPath(schemaPath, n.name.Local), n, lists[n.name.Local])
+		if err != nil {
+			return nil, err
+		}
+		if ids[e.id] {
+			return nil, errors.Errorf("%s: duplicate element %s%s", schemaPath, n.name.Local, e.predicate)
+		}
+		ids[e.id] = true
+		entries[i] = e
+	}
+	return entries, nil
+}
+
+func (r *reconciler) entry(schemaPath string, n *configNode, repeated bool) (*configEntry, error) {
+	e := &configEntry{node: n}
+	keys, defined := r.cfg.keys[schemaPath]
+	switch {
+	case defined:
+		for _, k := range keys {
+			leaf := n.leaf(k)
+			if leaf == nil {
+				return nil, errors.Errorf("%s: list entry has no key %s", schemaPath, k)
+			}
+			e.keys = append(e.keys, leaf)
+		}
+	case repeated && n.isLeaf():
+		e.predicate = fmt.Sprintf("[.=%s]", quotePredicate(n.text))
+	case repeated:
+		return nil, errors.Errorf("%s: list entries are not identified by ListKeys", schemaPath)
+	}
+
+	preds := make([]string, len(e.keys))
+	for i, k := range e.keys {
+		preds[i] = fmt.Sprintf("[%s=%s]", k.name.Local, quotePredicate(k.text))
+	}
+	e.predicate += strings.Join(preds, "")
+	e.id = n.name.Space + " " + n.name.Local + e.predicate
+	return e, nil
+}
+
+func quotePredicate(value string) string {
+	if strings.Contains(value, "'") {
+		return `"` + value + `"`
+	}
+	return "'" + value + "'"
+}
+
+// Adds the operation on the element identified by e.
+func (r *reconciler) add(operation string, ancestors []ancestor, path string, e *configEntry) {
+	sb := &strings.Builder{}
+	var ns string
+	for _, a := range ancestors {
+		writeStartElement(sb, a.node.name, ns, "")
+		ns = a.node.name.Space
+		for _, k := range a.keys {
+			writeConfigNode(sb, k, ns)
+		}
+	}
+
+	writeStartElement(sb, e.node.name, ns,
+		fmt.Sprintf(` xmlns:nc=%q nc:operation=%q`, common.NetconfNS, operation))
+	switch {
+	case e.node.isLeaf():
+		_ = xml.EscapeText(sb, []byte(e.node.text))
+	case operation == DeleteOp:
+		for _, k := range e.keys {
+			writeConfigNode(sb, k, e.node.name.Space)
+		}
+	default:
+		for _, c := range e.node.children {
+			writeConfigNode(sb, c, e.node.name.Space)
+		}
+	}
+	fmt.Fprintf(sb, "</%s>", e.node.name.Local)
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		fmt.Fprintf(sb, "</%s>", ancestors[i].node.name.Local)
+	}
+	r.operations = append(r.operations, EditOperation{
+		Operation: operation, Path: path + "/" + e.node.name.Local + e.predicate, Config: sb.String(),
+	})
+}
+
+// Writes the start tag of an element, declaring its namespace if it differs from that of its parent.
+func writeStartElement(sb *strings.Builder, name xml.Name, parentNamespace, attrs string) {
+	sb.WriteString("<" + name.Local)
+	if name.Space != parentNamespace {
+		fmt.Fprintf(sb, " xmlns=%q", name.Space)
+	}
+	sb.WriteString(attrs + ">")
+}
+
+func writeConfigNode(sb *strings.Builder, n *configNode, parentNamespace string) {
+	var attrs string
+	if n.operation != "" {
+		attrs = fmt.Sprintf(` xmlns:nc=%q nc:operation=%q`, common.NetconfNS, n.operation)
+	}
+	writeStartElement(sb, n.name, parentNamespace, attrs)
+	if n.isLeaf() {
+		_ = xml.EscapeText(sb, []byte(n.text))
+	}
+	for _, c := range n.children {
+		writeConfigNode(sb, c, n.name.Space)
+	}
+	fmt.Fprintf(sb, "</%s>", n.name.Local)
+}
+
+// Delivers the configuration of a single edit-config request that performs the operations, merging the ancestors
+// they have in common.
+func mergeOperations(operations []EditOperation) (string, error) {
+	var merged []*configNode
+	for i := range operations {
+		nodes, err := parseConfigTree(operations[i].Config)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse configuration of %s %s", operations[i].Operation,
+				operations[i].Path)
+		}
+		merged = mergeConfigNodes(merged, nodes)
+	}
+
+	sb := &strings.Builder{}
+	for _, n := range merged {
+		writeConfigNode(sb, n, "")
+	}
+	return sb.String(), nil
+}
+
+// Merges the src elements into their siblings in dst. An element without an operation is an ancestor of an operation,
+// which is merged with the matching ancestor of an earlier operation; a leaf without an operation is the key of such
+// an ancestor.
+func mergeConfigNodes(dst, src []*configNode) []*configNode {
+	for _, s := range src {
+		if s.operation == "" {
+			if d := matchingAncestor(dst, s); d != nil {
+				if !s.isLeaf() {
+					d.children = mergeConfigNodes(d.children, s.children)
+				}
+				continue
+			}
+		}
+		dst = append(dst, s)
+	}
+	return dst
+}
+
+// Delivers the element in nodes, other than the target of an operation, that has the name and keys of n, or nil.
+func matchingAncestor(nodes []*configNode, n *configNode) *configNode {
+	for _, d := range nodes {
+		if d.operation != "" || d.name != n.name || d.isLeaf() != n.isLeaf() {
+			continue
+		}
+		if n.isLeaf() && d.text == n.text || !n.isLeaf() && ancestorKeys(d) == ancestorKeys(n) {
+			return d
+		}
+	}
+	return nil
+}
+
+// Delivers the key leaves of an ancestor, which are its leaves without an operation.
+func ancestorKeys(n *configNode) string {
+	sb := &strings.Builder{}
+	for _, c := range n.children {
+		if c.isLeaf() && c.operation == "" {
+			fmt.Fprintf(sb, "[%s %s=%s]", c.name.Space, c.name.Local, quotePredicate(c.text))
+		}
+	}
+	return sb.String()
+}
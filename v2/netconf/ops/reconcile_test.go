@@ -0,0 +1,181 @@
+package ops
+
+import (
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+const (
+	reconcileActual = `<data><interfaces xmlns="urn:if">
+<interface><name>eth0</name><mtu>1500</mtu><enabled>true</enabled></interface>
+<interface><name>eth1</name><mtu>1500</mtu></interface>
+</interfaces>
+<dns xmlns="urn:dns"><server>1.1.1.1</server><server>8.8.8.8</server></dns></data>`
+
+	reconcileIntended = `<interfaces xmlns="urn:if">
+<interface><name>eth0</name><mtu>9000</mtu><enabled>true</enabled></interface>
+<interface><name>eth2</name><mtu>1500</mtu></interface>
+</interfaces>
+<dns xmlns="urn:dns"><server>8.8.8.8</server><server>9.9.9.9</server></dns>`
+)
+
+const reconcileOperation = ` xmlns:nc="` + common.NetconfNS + `" nc:operation=`
+
+var reconcileKeys = ListKeys(map[string][]string{"interfaces/interface": {"name"}})
+
+func TestReconcile(t *testing.T) {
+	ops, err := Reconcile(reconcileIntended, reconcileActual, reconcileKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, []EditOperation{
+		{
+			Operation: DeleteOp, Path: "/interfaces/interface[name='eth1']",
+			Config: `<interfaces xmlns="urn:if"><interface` + reconcileOperation + `"delete"><name>eth1</name>` +
+				`</interface></interfaces>`,
+		},
+		{
+			Operation: ReplaceOp, Path: "/interfaces/interface[name='eth0']/mtu",
+			Config: `<interfaces xmlns="urn:if"><interface><name>eth0</name><mtu` + reconcileOperation +
+				`"replace">9000</mtu></interface></interfaces>`,
+		},
+		{
+			Operation: CreateOp, Path: "/interfaces/interface[name='eth2']",
+			Config: `<interfaces xmlns="urn:if"><interface` + reconcileOperation + `"create"><name>eth2</name>` +
+				`<mtu>1500</mtu></interface></interfaces>`,
+		},
+		{
+			Operation: DeleteOp, Path: "/dns/server[.='1.1.1.1']",
+			Config: `<dns xmlns="urn:dns"><server` + reconcileOperation + `"delete">1.1.1.1</server></dns>`,
+		},
+		{
+			Operation: CreateOp, Path: "/dns/server[.='9.9.9.9']",
+			Config: `<dns xmlns="urn:dns"><server` + reconcileOperation + `"create">9.9.9.9</server></dns>`,
+		},
+	}, ops)
+
+	ops, err = Reconcile(reconcileActual, reconcileActual, reconcileKeys)
+	assert.NoError(t, err)
+	assert.Empty(t, ops, "Expecting identical configuration to need no operations")
+
+	_, err = Reconcile(reconcileIntended, reconcileActual)
+	assert.EqualError(t, err, "interfaces/interface: list entries are not identified by ListKeys")
+}
+
+func TestReconcileListKeys(t *testing.T) {
+	actual := `<routes><route><vrf>a</vrf><prefix>10.0.0.0/8</prefix><next-hop>x</next-hop></route></routes>`
+	intended := `<routes><route><vrf>a</vrf><prefix>10.0.0.0/8</prefix><next-hop>y</next-hop></route></routes>`
+
+	// Without keys, a single entry is treated as a container.
+	ops, err := Reconcile(intended, actual)
+	assert.NoError(t, err)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, "/routes/route/next-hop", ops[0].Path)
+
+	ops, err = Reconcile(intended, actual, ListKeys(map[string][]string{"routes/route": {"vrf", "prefix"}}))
+	assert.NoError(t, err)
+	assert.Len(t, ops, 1)
+	assert.Equal(t, "/routes/route[vrf='a'][prefix='10.0.0.0/8']/next-hop", ops[0].Path)
+	assert.Equal(t, `<routes><route><vrf>a</vrf><prefix>10.0.0.0/8</prefix><next-hop`+reconcileOperation+
+		`"replace">y</next-hop></route></routes>`, ops[0].Config)
+
+	_, err = Reconcile(`<routes><route><vrf>a</vrf></route></routes>`, actual,
+		ListKeys(map[string][]string{"routes/route": {"vrf", "prefix"}}))
+	assert.EqualError(t, err, "routes/route: list entry has no key prefix")
+}
+
+func TestReconcileSingleListEntry(t *testing.T) {
+	actual := `<interfaces xmlns="urn:if"><interface><name>eth0</name><mtu>1500</mtu></interface></interfaces>`
+	intended := `<interfaces xmlns="urn:if"><interface><name>eth1</name><mtu>1500</mtu></interface></interfaces>`
+
+	// Without keys, the entry is treated as a container, so its key leaf is replaced.
+	ops, err := Reconcile(intended, actual)
+	assert.NoError(t, err)
+	assert.Equal(t, []EditOperation{
+		{
+			Operation: ReplaceOp, Path: "/interfaces/interface/name",
+			Config: `<interfaces xmlns="urn:if"><interface><name` + reconcileOperation + `"replace">eth1</name>` +
+				`</interface></interfaces>`,
+		},
+	}, ops)
+
+	ops, err = Reconcile(intended, actual, reconcileKeys)
+	assert.NoError(t, err)
+	assert.Equal(t, []EditOperation{
+		{
+			Operation: DeleteOp, Path: "/interfaces/interface[name='eth0']",
+			Config: `<interfaces xmlns="urn:if"><interface` + reconcileOperation + `"delete"><name>eth0</name>` +
+				`</interface></interfaces>`,
+		},
+		{
+			Operation: CreateOp, Path: "/interfaces/interface[name='eth1']",
+			Config: `<interfaces xmlns="urn:if"><interface` + reconcileOperation + `"create"><name>eth1</name>` +
+				`<mtu>1500</mtu></interface></interfaces>`,
+		},
+	}, ops)
+}
+
+func TestReconcileErrors(t *testing.T) {
+	_, err := Reconcile(`<top>`, `<top/>`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse intended configuration")
+
+	_, err = Reconcile(`<top><a>1</a></top>`, `<top><a>1</a><a>1</a></top>`)
+	assert.EqualError(t, err, "top: duplicate element a[.='1']")
+}
+
+func TestApply(t *testing.T) {
+	ops, err := Reconcile(reconcileIntended, reconcileActual, reconcileKeys)
+	assert.NoError(t, err)
+
+	// The operations are merged into a single edit.
+	config := `<interfaces xmlns="urn:if">` +
+		`<interface` + reconcileOperation + `"delete"><name>eth1</name></interface>` +
+		`<interface><name>eth0</name><mtu` + reconcileOperation + `"replace">9000</mtu></interface>` +
+		`<interface` + reconcileOperation + `"create"><name>eth2</name><mtu>1500</mtu></interface>` +
+		`</interfaces>` +
+		`<dns xmlns="urn:dns">` +
+		`<server` + reconcileOperation + `"delete">1.1.1.1</server>` +
+		`<server` + reconcileOperation + `"create">9.9.9.9</server>` +
+		`</dns>`
+
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase11, CapCandidate})
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createEditConfigRequest(CandidateCfg, Cfg(config), DefaultOperation(NoneOp))).
+		Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", &CommitReq{}).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createUnlockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+
+	assert.NoError(t, Apply(ncs, ops))
+	mcli.AssertExpectations(t)
+}
+
+func TestApplyFailure(t *testing.T) {
+	ops, err := Reconcile(reconcileIntended, reconcileActual, reconcileKeys)
+	assert.NoError(t, err)
+
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase11, CapCandidate})
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", mock.AnythingOfType("*ops.EditConfigReq")).
+		Return(nil, &common.RPCError{Tag: "data-missing", Severity: "error"}).Once()
+	mcli.On("Execute", createDiscardRequest()).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createUnlockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+
+	err = Apply(ncs, ops)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply 5 reconciliation operations")
+	mcli.AssertExpectations(t)
+}
+
+func TestApplyWithoutCandidate(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	mcli.On("ServerCapabilities").Return([]string{common.CapBase11})
+
+	err := Apply(ncs, []EditOperation{{Operation: CreateOp, Path: "/a", Config: "<a/>"}})
+	assert.Equal(t, ErrCandidateUnsupported, err)
+	mcli.AssertExpectations(t)
+}
@@ -0,0 +1,113 @@
+package ops
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnexpectedReplyData is returned by the Get... methods of a session configured by WithStrictReplyData when the
+// reply data does not have the expected shape.
+var ErrUnexpectedReplyData = errors.New("unexpected reply data")
+
+// WithStrictReplyData causes the Get... methods to fail with ErrUnexpectedReplyData, rather than deliver what can be
+// salvaged, when the reply holds anything other than a single <data> element, when the <data> element holds text
+// alongside its child elements, or when it holds more than one child element and the result is not a slice.
+func WithStrictReplyData() SessionOption {
+	return func(so *sessionOptions) {
+		so.strictData = true
+	}
+}
+
+// Defines the <data> elements found in a reply.
+type replyData struct {
+	// The encoding of each <data> element, including its start and end tags.
+	elements []string
+	// The content of each <data> element.
+	contents []string
+	// The number of child elements of all <data> elements.
+	children int
+}
+
+// Delivers the content of the <data> elements in the reply, which some devices deliver as multiple elements.
+// In strict mode, anything other than a single <data> element, or text that is not whitespace alongside its
+// children, is rejected.
+func parseReplyData(content string, strict bool) (*replyData, error) {
+	rd := &replyData{}
+	d := xml.NewDecoder(strings.NewReader(content))
+	var depth int
+	var inData bool
+	var elementStart, contentStart int64
+	for {
+		offset := d.InputOffset()
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return rd, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case depth == 0 && t.Name.Local == "data":
+				if strict && len(rd.elements) > 0 {
+					return nil, errors.Wrap(ErrUnexpectedReplyData, "multiple <data> elements")
+				}
+				inData, elementStart, contentStart = true, offset, d.InputOffset()
+			case depth == 0 && strict:
+				return nil, errors.Wrapf(ErrUnexpectedReplyData, "<%s> element outside <data>", t.Name.Local)
+			case depth == 1 && inData:
+				rd.children++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 && inData {
+				rd.elements = append(rd.elements, content[elementStart:d.InputOffset()])
+				rd.contents = append(rd.contents, content[contentStart:offset])
+				inData = false
+			}
+		case xml.CharData:
+			if strict && depth == 1 && inData && len(strings.TrimSpace(string(t))) > 0 {
+				return nil, errors.Wrapf(ErrUnexpectedReplyData, "text %q within <data>", strings.TrimSpace(string(t)))
+			}
+		}
+	}
+}
+
+// Delivers the content of all the <data> elements.
+func (rd *replyData) content() string {
+	return strings.Join(rd.contents, "")
+}
+
+// Delivers a single <data> element holding the content of all the <data> elements.
+func (rd *replyData) merged() string {
+	if len(rd.elements) == 1 {
+		return rd.elements[0]
+	}
+	return "<data>" + rd.content() + "</data>"
+}
+
+// Decodes the children of each <data> element into result, so that a slice result collects all the children.
+// In strict mode, more than one child is rejected unless result is a slice.
+func (rd *replyData) decode(result interface{}, strict bool) error {
+	if strict && rd.children > 1 && !isSlicePointer(result) {
+		return errors.Wrapf(ErrUnexpectedReplyData, "%d elements within <data> for a single result", rd.children)
+	}
+	for _, element := range rd.elements {
+		if err := xml.Unmarshal([]byte(element), &Data{Body: result}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isSlicePointer(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice
+}
@@ -0,0 +1,82 @@
+package ops
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/mocks"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const (
+	multipleDataReply = `<data><element attr1="A"/></data><data><element attr1="B"/></data>`
+	mixedContentReply = `<data>
+  <element attr1="A"/> interleaved text <element attr1="B"/>
+</data>`
+)
+
+func newOpsSessionWithReply(data string, strict bool) OpSession {
+	mockClient := &mocks.OpSession{}
+	mockClient.On("Execute", createGetSubtreeRequest(`<element/>`)).Return(&common.RPCReply{Data: data}, nil)
+	return &sImpl{Session: mockClient, strictData: strict}
+}
+
+func TestGetMultipleDataElements(t *testing.T) {
+	ncs := newOpsSessionWithReply(multipleDataReply, false)
+
+	var result string
+	assert.NoError(t, ncs.GetSubtree(`<element/>`, &result))
+	assert.Equal(t, `<element attr1="A"/><element attr1="B"/>`, result, "Expecting content of all data elements")
+
+	var elements []Element
+	assert.NoError(t, ncs.GetSubtree(`<element/>`, &elements))
+	assert.Len(t, elements, 2)
+	assert.Equal(t, "A", elements[0].Attr1)
+	assert.Equal(t, "B", elements[1].Attr1)
+
+	var json JSONResult
+	assert.NoError(t, ncs.GetSubtree(`<element/>`, &json))
+	assert.JSONEq(t, `{"element":[{"@attr1":"A"},{"@attr1":"B"}]}`, string(json))
+}
+
+func TestGetMixedContent(t *testing.T) {
+	ncs := newOpsSessionWithReply(mixedContentReply, false)
+
+	var result string
+	assert.NoError(t, ncs.GetSubtree(`<element/>`, &result))
+	assert.Contains(t, result, "interleaved text", "Expecting text to be preserved")
+
+	var elements []Element
+	assert.NoError(t, ncs.GetSubtree(`<element/>`, &elements))
+	assert.Len(t, elements, 2)
+}
+
+func TestGetStrictReplyData(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		result interface{}
+		err    string
+	}{
+		{"MultipleData", multipleDataReply, &[]Element{}, "multiple <data> elements"},
+		{"OutsideData", `<data/><other/>`, new(string), "<other> element outside <data>"},
+		{"MixedContent", mixedContentReply, &[]Element{}, `text "interleaved text" within <data>`},
+		{"SingleResult", `<data><element attr1="A"/><element attr1="B"/></data>`, &Element{}, "2 elements within <data> for a single result"},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newOpsSessionWithReply(tt.data, true).GetSubtree(`<element/>`, tt.result)
+			assert.True(t, errors.Is(err, ErrUnexpectedReplyData), "Expecting unexpected reply data error")
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	var elements []Element
+	err := newOpsSessionWithReply(`<data><element attr1="A"/><element attr1="B"/></data>`, true).GetSubtree(`<element/>`, &elements)
+	assert.NoError(t, err, "Expecting slice result to accept multiple elements")
+	assert.Len(t, elements, 2)
+}
@@ -0,0 +1,200 @@
+package ops
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Stages of the SafeCommit procedure, as reported by SafeCommitError.
+const (
+	SafeCommitLock        = "lock"
+	SafeCommitSnapshot    = "snapshot"
+	SafeCommitEdit        = "edit-config"
+	SafeCommitValidate    = "validate"
+	SafeCommitConfirmed   = "confirmed-commit"
+	SafeCommitHealthCheck = "health-check"
+	SafeCommitConfirm     = "confirm"
+)
+
+// HealthCheckFunc is called by SafeCommit after the confirmed commit has been applied to the running configuration.
+// A nil return confirms the commit; an error causes the commit to be cancelled.
+type HealthCheckFunc func(ctx context.Context) error
+
+// SafeCommitError is returned by SafeCommit when the procedure did not complete.
+type SafeCommitError struct {
+	// The stage at which the procedure failed.
+	Stage string
+	// The error reported by the failing stage.
+	Err error
+	// The error, if any, encountered whilst restoring the previous configuration.
+	RollbackErr error
+}
+
+// Error generates a string representation of the safe commit error.
+func (e *SafeCommitError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("safe commit failed at %s: %v (rollback failed: %v)", e.Stage, e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("safe commit failed at %s: %v", e.Stage, e.Err)
+}
+
+// Unwrap delivers the error reported by the failing stage.
+func (e *SafeCommitError) Unwrap() error {
+	return e.Err
+}
+
+// SafeCommitOption configures the behaviour of SafeCommit.
+type SafeCommitOption func(*safeCommitConfig)
+
+type safeCommitConfig struct {
+	confirmTimeout time.Duration
+	healthCheck    HealthCheckFunc
+	editOptions    []EditOption
+}
+
+// ConfirmTimeout defines the period after which the server will revert the confirmed commit if it has not been
+// confirmed. It must exceed the time taken by the health check.
+// Default is the server default, which is 600 seconds.
+func ConfirmTimeout(timeout time.Duration) SafeCommitOption {
+	return func(c *safeCommitConfig) {
+		c.confirmTimeout = timeout
+	}
+}
+
+// HealthCheck defines the function used to determine whether the confirmed commit should be confirmed.
+// Default is to confirm the commit unconditionally.
+func HealthCheck(check HealthCheckFunc) SafeCommitOption {
+	return func(c *safeCommitConfig) {
+		c.healthCheck = check
+	}
+}
+
+// CommitEditOptions defines the options applied to the edit-config request issued against the candidate.
+func CommitEditOptions(options ...EditOption) SafeCommitOption {
+	return func(c *safeCommitConfig) {
+		c.editOptions = options
+	}
+}
+
+func (s *sImpl) SafeCommit(ctx context.Context, config ConfigOption, options ...SafeCommitOption) (err error) {
+	cfg := &safeCommitConfig{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	// Both datastores are locked, so that the running configuration cannot be changed by another session between the
+	// confirmed commit and its confirmation, or its cancellation.
+	if err = s.Lock(CandidateCfg); err != nil {
+		return &SafeCommitError{Stage: SafeCommitLock, Err: err}
+	}
+	defer func() {
+		_ = s.Unlock(CandidateCfg)
+	}()
+	if err = s.Lock(RunningCfg); err != nil {
+		return &SafeCommitError{Stage: SafeCommitLock, Err: err}
+	}
+	defer func() {
+		_ = s.Unlock(RunningCfg)
+	}()
+
+	if err = ctx.Err(); err != nil {
+		return &SafeCommitError{Stage: SafeCommitSnapshot, Err: err}
+	}
+	var snapshot string
+	if err = s.GetConfigSubtree(nil, CandidateCfg, &snapshot); err != nil {
+		return &SafeCommitError{Stage: SafeCommitSnapshot, Err: err}
+	}
+
+	// Prior to the confirmed commit, failures are recovered by restoring the candidate from the snapshot.
+	restore := func(stage string, err error) error {
+		return &SafeCommitError{Stage: stage, Err: err, RollbackErr: s.restoreCandidate(snapshot)}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return restore(SafeCommitEdit, err)
+	}
+	if err = s.EditConfig(CandidateCfg, config, cfg.editOptions...); err != nil {
+		return restore(SafeCommitEdit, err)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return restore(SafeCommitValidate, err)
+	}
+	if _, err = s.Session.Execute(createValidateRequest(CandidateCfg)); err != nil {
+		return restore(SafeCommitValidate, err)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return restore(SafeCommitConfirmed, err)
+	}
+	if _, err = s.Session.Execute(createCommitRequest(true, cfg.confirmTimeout)); err != nil {
+		return restore(SafeCommitConfirmed, err)
+	}
+
+	// Once the confirmed commit has been applied, failures are recovered by cancelling the commit, which reverts the
+	// running configuration; the candidate is then restored separately.
+	cancel := func(stage string, err error) error {
+		_, rbErr := s.Session.Execute(createCancelCommitRequest())
+		if rbErr == nil {
+			rbErr = s.restoreCandidate(snapshot)
+		}
+		return &SafeCommitError{Stage: stage, Err: err, RollbackErr: rbErr}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return cancel(SafeCommitHealthCheck, err)
+	}
+	if cfg.healthCheck != nil {
+		if err = cfg.healthCheck(ctx); err != nil {
+			return cancel(SafeCommitHealthCheck, err)
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return cancel(SafeCommitConfirm, err)
+	}
+	if _, err = s.Session.Execute(createCommitRequest(false, 0)); err != nil {
+		// The server will revert the confirmed commit when the confirm timeout expires.
+		return &SafeCommitError{Stage: SafeCommitConfirm, Err: err}
+	}
+	return nil
+}
+
+func (s *sImpl) restoreCandidate(snapshot string) error {
+	return s.EditConfig(CandidateCfg, Cfg(snapshot), DefaultOperation(ReplaceOp))
+}
+
+type ValidateReq struct {
+	XMLName xml.Name    `xml:"validate"`
+	Source  *ConfigType `xml:"source"`
+}
+
+type CommitReq struct {
+	XMLName        xml.Name  `xml:"commit"`
+	Confirmed      *struct{} `xml:"confirmed"`
+	ConfirmTimeout uint32    `xml:"confirm-timeout,omitempty"`
+	Extensions     string    `xml:",innerxml"`
+}
+
+type CancelCommitReq struct {
+	XMLName xml.Name `xml:"cancel-commit"`
+}
+
+func createValidateRequest(source string) *ValidateReq {
+	return &ValidateReq{Source: &ConfigType{Type: "<" + source + "/>"}}
+}
+
+func createCommitRequest(confirmed bool, timeout time.Duration) *CommitReq {
+	req := &CommitReq{}
+	if confirmed {
+		req.Confirmed = &struct{}{}
+		req.ConfirmTimeout = uint32(timeout / time.Second)
+	}
+	return req
+}
+
+func createCancelCommitRequest() *CancelCommitReq {
+	return &CancelCommitReq{}
+}
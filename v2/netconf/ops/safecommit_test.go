@@ -0,0 +1,158 @@
+package ops
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/mocks"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const (
+	safeCommitSnapshot = `<top><a>1</a></top>`
+	safeCommitChange   = `<top><a>2</a></top>`
+)
+
+func TestSafeCommit(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	expectSafeCommitPreamble(mcli)
+	mcli.On("Execute", createValidateRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createCommitRequest(true, 30*time.Second)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createCommitRequest(false, 0)).Return(&common.RPCReply{}, nil).Once()
+	expectSafeCommitUnlock(mcli)
+
+	checked := false
+	err := ncs.SafeCommit(context.Background(), Cfg(safeCommitChange),
+		ConfirmTimeout(30*time.Second),
+		HealthCheck(func(ctx context.Context) error {
+			checked = true
+			return nil
+		}))
+	assert.NoError(t, err, "Not expecting safe commit to fail")
+	assert.True(t, checked, "Expecting health check to be called")
+
+	mcli.AssertExpectations(t)
+}
+
+func TestSafeCommitValidateFailure(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	expectSafeCommitPreamble(mcli)
+	invalid := &common.RPCError{Tag: "operation-failed", Severity: "error", Message: "invalid"}
+	mcli.On("Execute", createValidateRequest(CandidateCfg)).Return(nil, invalid).Once()
+	mcli.On("Execute", createEditConfigRequest(CandidateCfg, Cfg(safeCommitSnapshot), DefaultOperation(ReplaceOp))).
+		Return(&common.RPCReply{}, nil).Once()
+	expectSafeCommitUnlock(mcli)
+
+	err := ncs.SafeCommit(context.Background(), Cfg(safeCommitChange))
+
+	var sce *SafeCommitError
+	assert.True(t, errors.As(err, &sce))
+	assert.Equal(t, SafeCommitValidate, sce.Stage)
+	assert.NoError(t, sce.RollbackErr)
+	assert.True(t, errors.Is(err, invalid))
+
+	mcli.AssertExpectations(t)
+}
+
+func TestSafeCommitHealthCheckFailure(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	expectSafeCommitPreamble(mcli)
+	mcli.On("Execute", createValidateRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createCommitRequest(true, 0)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createCancelCommitRequest()).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createEditConfigRequest(CandidateCfg, Cfg(safeCommitSnapshot), DefaultOperation(ReplaceOp))).
+		Return(&common.RPCReply{}, nil).Once()
+	expectSafeCommitUnlock(mcli)
+
+	unhealthy := errors.New("peer down")
+	err := ncs.SafeCommit(context.Background(), Cfg(safeCommitChange),
+		HealthCheck(func(ctx context.Context) error { return unhealthy }))
+
+	assert.EqualError(t, err, "safe commit failed at health-check: peer down")
+	assert.True(t, errors.Is(err, unhealthy))
+
+	mcli.AssertExpectations(t)
+}
+
+func TestSafeCommitCancelled(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	expectSafeCommitPreamble(mcli)
+	mcli.On("Execute", createValidateRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createCommitRequest(true, 0)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createCancelCommitRequest()).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createEditConfigRequest(CandidateCfg, Cfg(safeCommitSnapshot), DefaultOperation(ReplaceOp))).
+		Return(&common.RPCReply{}, nil).Once()
+	expectSafeCommitUnlock(mcli)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := ncs.SafeCommit(ctx, Cfg(safeCommitChange),
+		HealthCheck(func(ctx context.Context) error {
+			cancel()
+			return nil
+		}))
+
+	var sce *SafeCommitError
+	assert.True(t, errors.As(err, &sce))
+	assert.Equal(t, SafeCommitConfirm, sce.Stage, "Expecting the commit not to be confirmed once ctx is done")
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.NoError(t, sce.RollbackErr)
+
+	mcli.AssertExpectations(t)
+}
+
+func TestSafeCommitLockFailure(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	denied := &common.RPCError{Tag: "lock-denied", Severity: "error"}
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createLockRequest(RunningCfg)).Return(nil, denied).Once()
+	mcli.On("Execute", createUnlockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+
+	err := ncs.SafeCommit(context.Background(), Cfg(safeCommitChange))
+
+	var sce *SafeCommitError
+	assert.True(t, errors.As(err, &sce))
+	assert.Equal(t, SafeCommitLock, sce.Stage)
+
+	mcli.AssertExpectations(t)
+}
+
+func TestCommitRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      interface{}
+		expected string
+	}{
+		{"validate", createValidateRequest(CandidateCfg), `<validate><source><candidate/></source></validate>`},
+		{"commit", createCommitRequest(false, 0), `<commit></commit>`},
+		{"confirmed", createCommitRequest(true, 0), `<commit><confirmed></confirmed></commit>`},
+		{"confirmed timeout", createCommitRequest(true, 2*time.Minute),
+			`<commit><confirmed></confirmed><confirm-timeout>120</confirm-timeout></commit>`},
+		{"cancel", createCancelCommitRequest(), `<cancel-commit></cancel-commit>`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := xml.Marshal(test.req)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(b))
+		})
+	}
+}
+
+func expectSafeCommitPreamble(mcli *mocks.OpSession) {
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createLockRequest(RunningCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createGetConfigSubtreeRequest(nil, CandidateCfg)).
+		Return(&common.RPCReply{Data: "<data>" + safeCommitSnapshot + "</data>"}, nil).Once()
+	mcli.On("Execute", createEditConfigRequest(CandidateCfg, Cfg(safeCommitChange))).
+		Return(&common.RPCReply{}, nil).Once()
+}
+
+func expectSafeCommitUnlock(mcli *mocks.OpSession) {
+	mcli.On("Execute", createUnlockRequest(RunningCfg)).Return(&common.RPCReply{}, nil).Once()
+	mcli.On("Execute", createUnlockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+}
@@ -1,15 +1,19 @@
 package ops
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/damianoneill/net/v2/netconf/client"
 
 	"github.com/damianoneill/net/v2/netconf/common"
+	"golang.org/x/crypto/ssh"
 )
 
+// Namespace defines an xml namespace prefix (ID) and the namespace name (Path) that it identifies.
 type Namespace struct {
 	ID   string
 	Path string
@@ -22,24 +26,32 @@ type OpSession interface {
 	// GetSubtree issues a GET request, with the supplied subtree filter and stores the response in the result, which
 	// should be the address of either:
 	// - a string, in which case it will hold the response body, or
+	// - a JSONResult or YAMLResult, in which case it will hold the response body converted to JSON or YAML, or
+	// - a map[string]interface{}, in which case it will hold the response body converted by XMLToValue, or
 	// - a struct with xml tags.
 	GetSubtree(filter interface{}, result interface{}) error
 
 	// GetXpath issues a GET request, with the supplied xpath filter and namespace list and stores the response in the result, which
 	// should be the address of either:
 	// - a string, in which case it will hold the response body, or
+	// - a JSONResult or YAMLResult, in which case it will hold the response body converted to JSON or YAML, or
+	// - a map[string]interface{}, in which case it will hold the response body converted by XMLToValue, or
 	// - a struct with xml tags.
 	GetXpath(xpath string, nslist []Namespace, result interface{}) error
 
 	// GetConfigSubtree issues a GET-CONFIG request, with the supplied subtree filter and source, and stores the
 	// response in the result, which should be the address of either:
 	// - a string, in which case it will hold the response body, or
+	// - a JSONResult or YAMLResult, in which case it will hold the response body converted to JSON or YAML, or
+	// - a map[string]interface{}, in which case it will hold the response body converted by XMLToValue, or
 	// - a struct with xml tags.
 	GetConfigSubtree(filter interface{}, source string, result interface{}) error
 
 	// GetConfigXpath issues a GET-CONFIG request, with the supplied xpath filter, source and namespace list and stores the
 	// response in the result, which should be the address of either:
 	// - a string, in which case it will hold the response body, or
+	// - a JSONResult or YAMLResult, in which case it will hold the response body converted to JSON or YAML, or
+	// - a map[string]interface{}, in which case it will hold the response body converted by XMLToValue, or
 	// - a struct with xml tags.
 	GetConfigXpath(xpath string, nslist []Namespace, source string, result interface{}) error
 
@@ -53,7 +65,8 @@ type OpSession interface {
 	// EditOptions can be added to qualify the operation.
 	// config will be defined by a ConfigOption, which can be one of:
 	// - Cfg(cfg), where cfg is
-	//   o   an xml string, in which case it will be used verbatim as the content of the <config> element.
+	//   o   an xml string or []byte, in which case it will be used verbatim as the content of the <config> element.
+	//   o   an io.Reader delivering xml, which will be streamed as the content of the <config> element.
 	//   o   a struct with xml tags that will be marshalled as the child of the <config> element.
 	// - CfgURL(url), in which case the configuration is defined by a <url> element.
 	EditConfig(target string, config ConfigOption, options ...EditOption) error
@@ -67,6 +80,7 @@ type OpSession interface {
 	// source and target are defined by a CfgDsOpt, which can be one of:
 	// - DsName(name) where name defines the configuration data store name (Running, Candidate ...)
 	// - DsURL(url) where url defines the url of the datastore
+	// - DsConfig(cfg) where cfg defines the content of an inline configuration, which may only be a source
 	CopyConfig(source, target CfgDsOpt) error
 
 	// DeleteConfig issues a delete-config request.
@@ -78,21 +92,72 @@ type OpSession interface {
 	// Lock issues a lock request on the target configuration.
 	Lock(target string) error
 
+	// LockWithRetry issues a lock request on the target configuration, retrying with backoff while the lock is
+	// denied because it is held by another session.
+	// If the context expires before the lock is acquired, a *LockDeniedError identifying the session holding the
+	// lock is returned.
+	LockWithRetry(ctx context.Context, target string, options ...LockRetryOption) error
+
 	// Unlock issues an unlock request on the target configuration.
 	Unlock(target string) error
 
 	// Discard issues a discard changes request.
 	Discard() error
 
+	// Commit issues a commit request, committing the candidate configuration to the running configuration.
+	// CommitOptions can be added to request a confirmed commit, vendor specific behaviour such as a Junos commit
+	// comment, or to append arbitrary vendor elements to the request.
+	// ErrUnsupportedCommitOption is returned, without issuing the request, if an option requires a capability
+	// that the server does not advertise.
+	Commit(options ...CommitOption) error
+
+	// SafeCommit applies config to the running configuration using the candidate datastore and a confirmed commit.
+	// The candidate and running datastores are locked, the candidate is snapshotted, config is applied by edit-config
+	// and validated, and a confirmed commit is issued. The commit is confirmed once the health check (if any)
+	// succeeds, otherwise it is cancelled. ctx is checked before each step, so that the procedure stops if ctx is
+	// done; the health check and confirmation are then abandoned and the commit cancelled.
+	// Failures before the confirmed commit restore the candidate from the snapshot.
+	// The server must support the :candidate, :validate and :confirmed-commit:1.1 capabilities.
+	// A *SafeCommitError identifying the failing stage is returned if the procedure does not complete.
+	SafeCommit(ctx context.Context, config ConfigOption, options ...SafeCommitOption) error
+
+	// ExecuteBatch issues the operations within a single rpc, for devices that support multiple operations in one
+	// rpc, optionally wrapped in a vendor container element defined by BatchContainer.
+	// The reply is expected to hold an element for each operation, in order, which is delivered to the operation
+	// result, if any. A single <ok/> is accepted as the reply if no operation has a result.
+	ExecuteBatch(operations []BatchOperation, options ...BatchOption) error
+
 	// CloseSession issues a close session request.
 	CloseSession() error
 
 	// KillSession issues a kill session request for the specified session id.
 	KillSession(id uint64) error
+
+	// RegisterNamespaces registers namespace prefixes that will be declared on the filter element of every
+	// subsequent get/get-config request, and on the config element of every subsequent edit-config request, so
+	// that they can be used without repeated declaration.
+	// A registration replaces any earlier registration of the same prefix. A namespace list supplied to an xpath
+	// request takes precedence over registered namespaces with the same prefix.
+	RegisterNamespaces(nslist ...Namespace)
 }
 
 type sImpl struct {
 	client.Session
+	// Decoders applied to reply data, keyed by element name.
+	decoders map[string]ReplyDecoder
+
+	// Guards the registered namespaces.
+	nsMu       sync.RWMutex
+	namespaces []Namespace
+
+	// Coalesces identical concurrent get requests, if enabled by WithGetCoalescing.
+	gets *flightGroup
+	// See WithStrictReplyData.
+	strictData bool
+	// See WithConfigDefaults.
+	defaults *configDefaults
+	// The ssh connection that carries the session, if any, see NewFileTransfer.
+	sshClient *ssh.Client
 }
 
 func (s *sImpl) Close() {
@@ -100,23 +165,27 @@ func (s *sImpl) Close() {
 }
 
 func (s *sImpl) GetSubtree(filter, result interface{}) error {
-	return s.handleGetRequest(createGetSubtreeRequest(filter), result)
+	return s.handleSharedGetRequest(createGetSubtreeRequest(filter, s.mergeNamespaces(nil)...), result)
 }
 
 func (s *sImpl) GetXpath(xpath string, nslist []Namespace, result interface{}) error {
-	return s.handleGetRequest(createGetXpathRequest(xpath, nslist), result)
+	return s.handleSharedGetRequest(createGetXpathRequest(xpath, s.mergeNamespaces(nslist)), result)
 }
 
 func (s *sImpl) GetConfigSubtree(filter interface{}, source string, result interface{}) error {
-	return s.handleGetRequest(createGetConfigSubtreeRequest(filter, source), result)
+	return s.handleGetConfigRequest(createGetConfigSubtreeRequest(filter, source, s.mergeNamespaces(nil)...), result)
 }
 
 func (s *sImpl) GetConfigXpath(xpath string, nslist []Namespace, source string, result interface{}) error {
-	return s.handleGetRequest(createGetConfigXpathRequest(xpath, source, nslist), result)
+	return s.handleGetConfigRequest(createGetConfigXpathRequest(xpath, source, s.mergeNamespaces(nslist)), result)
 }
 
 func (s *sImpl) EditConfig(target string, config ConfigOption, options ...EditOption) error {
-	_, err := s.Session.Execute(createEditConfigRequest(target, config, options...))
+	req := createEditConfigRequest(target, config, options...)
+	if req.Config != nil {
+		req.Config.Attrs = namespaceAttrs(s.mergeNamespaces(nil))
+	}
+	_, err := s.Session.Execute(req)
 	return err
 }
 
@@ -182,14 +251,16 @@ func (s *sImpl) GetSchema(id, version, format string) (string, error) {
 // Request structs.
 
 type Filter struct {
-	XMLName xml.Name `xml:"filter"`
-	Type    string   `xml:"type,attr"`
-	Select  string   `xml:"select,attr,omitempty"`
+	XMLName xml.Name   `xml:"filter"`
+	Type    string     `xml:"type,attr"`
+	Select  string     `xml:"select,attr,omitempty"`
+	Attrs   []xml.Attr `xml:",any,attr"`
 	*common.Union
 }
 
 type Config struct {
-	XMLName xml.Name `xml:"config"`
+	XMLName xml.Name   `xml:"config"`
+	Attrs   []xml.Attr `xml:",any,attr"`
 	*common.Union
 }
 
@@ -291,6 +362,14 @@ func DsURL(url string) CfgDsOpt {
 	}
 }
 
+// DsConfig defines an inline configuration, the xml content of a <config> element, as the source of a copy-config
+// request.
+func DsConfig(cfg string) CfgDsOpt {
+	return func(t *ConfigType) {
+		t.Type = "<config>" + cfg + "</config>"
+	}
+}
+
 // EditOption configures an edit config operation.
 type EditOption func(*EditConfigReq)
 
@@ -318,10 +397,10 @@ func (r *EditConfigReq) applyOpts(options ...EditOption) {
 	}
 }
 
-func createGetSubtreeRequest(s interface{}) common.Request {
+func createGetSubtreeRequest(s interface{}, nslist ...Namespace) common.Request {
 	req := &GetReq{}
 	if s != nil {
-		req.Filter = &Filter{Type: "subtree", Union: common.GetUnion(s)}
+		req.Filter = &Filter{Type: "subtree", Attrs: namespaceAttrs(nslist), Union: common.GetUnion(s)}
 	}
 	return req
 }
@@ -338,11 +417,11 @@ func getNamespaceAttributes(nslist []Namespace) string {
 	return strings.TrimSpace(attrs)
 }
 
-func createGetConfigSubtreeRequest(s interface{}, source string) common.Request {
+func createGetConfigSubtreeRequest(s interface{}, source string, nslist ...Namespace) common.Request {
 	// xml Marshaller will not create self-closing tags (and some devices require it)...
 	req := &GetConfigReq{Source: &ConfigType{Type: "<" + source + "/>"}}
 	if s != nil {
-		req.Filter = &Filter{Type: "subtree", Union: common.GetUnion(s)}
+		req.Filter = &Filter{Type: "subtree", Attrs: namespaceAttrs(nslist), Union: common.GetUnion(s)}
 	}
 	return req
 }
@@ -413,15 +492,56 @@ func (s *sImpl) handleGetRequest(req common.Request, result interface{}) error {
 	if err != nil {
 		return err
 	}
+	return s.handleGetReply(reply, result)
+}
+
+// Issues the get request, coalescing it with any identical request in progress if enabled by WithGetCoalescing.
+func (s *sImpl) handleSharedGetRequest(req common.Request, result interface{}) error {
+	if s.gets == nil {
+		return s.handleGetRequest(req, result)
+	}
+	reply, err := s.gets.execute(s.Session, req)
+	if err != nil {
+		return err
+	}
+	return s.handleGetReply(reply, result)
+}
+
+// Decodes the data returned by a get request into the result.
+func (s *sImpl) handleGetReply(reply *common.RPCReply, result interface{}) error {
+	content, err := decodeReplyData(reply.Data, s.decoders)
+	if err != nil {
+		return err
+	}
+	return decodeData(content, result, s.strictData)
+}
+
+// Decodes the content of the <data> elements of a reply into the result.
+func decodeData(content string, result interface{}, strict bool) error {
+	rd, err := parseReplyData(content, strict)
+	if err != nil {
+		return err
+	}
+	if len(rd.elements) == 0 {
+		// Preserve the error reported by the decoder for replies without data.
+		return xml.Unmarshal([]byte(content), &Data{})
+	}
 
 	switch target := result.(type) {
 	case *string:
-		data := &Data{}
-		err = xml.Unmarshal([]byte(reply.Data), data)
-		*target = data.Content
+		*target = rd.content()
+	case *JSONResult:
+		var out string
+		out, err = toJSON(rd.merged())
+		*target = JSONResult(out)
+	case *YAMLResult:
+		var out string
+		out, err = toYAML(rd.merged())
+		*target = YAMLResult(out)
+	case *map[string]interface{}:
+		*target, err = XMLToValue(rd.merged())
 	default:
-		data := &Data{Body: result}
-		err = xml.Unmarshal([]byte(reply.Data), data)
+		err = rd.decode(result, strict)
 	}
 	return err
 }
@@ -1,9 +1,11 @@
 package ops
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/damianoneill/net/v2/netconf/common"
 
@@ -232,6 +234,49 @@ func TestLock(t *testing.T) {
 	mcli.AssertExpectations(t)
 }
 
+func TestLockWithRetry(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	denied := &common.RPCError{Tag: "lock-denied", Severity: "error",
+		Info: `<error-tag>lock-denied</error-tag><error-info><session-id>454</session-id></error-info>`}
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(nil, denied).Twice()
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil).Once()
+
+	err := ncs.LockWithRetry(context.Background(), CandidateCfg, LockBackoff(time.Millisecond, 2*time.Millisecond))
+	assert.NoError(t, err, "Not expecting call to fail")
+
+	mcli.AssertExpectations(t)
+}
+
+func TestLockWithRetryTimeout(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	denied := &common.RPCError{Tag: "lock-denied", Severity: "error",
+		Info: `<error-info><session-id>454</session-id></error-info>`}
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(nil, denied)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := ncs.LockWithRetry(ctx, CandidateCfg, LockBackoff(time.Millisecond, 5*time.Millisecond))
+	assert.Error(t, err, "Expecting lock to fail")
+
+	var lde *LockDeniedError
+	assert.True(t, errors.As(err, &lde))
+	assert.Equal(t, uint64(454), lde.SessionID)
+	assert.Equal(t, denied, lde.RPCError)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, "lock on candidate denied, held by session 454: context deadline exceeded", err.Error())
+}
+
+func TestLockWithRetryOtherError(t *testing.T) {
+	ncs, mcli := newOpsSessionWithMockClient(t)
+	failure := &common.RPCError{Tag: "operation-failed", Severity: "error"}
+	mcli.On("Execute", createLockRequest(CandidateCfg)).Return(nil, failure).Once()
+
+	err := ncs.LockWithRetry(context.Background(), CandidateCfg)
+	assert.Equal(t, failure, err, "Expecting error to be returned without retry")
+
+	mcli.AssertExpectations(t)
+}
+
 func TestUnlock(t *testing.T) {
 	ncs, mcli := newOpsSessionWithMockClient(t)
 	mcli.On("Execute", createUnlockRequest(CandidateCfg)).Return(&common.RPCReply{}, nil)
@@ -336,7 +381,7 @@ func TestGetSchemaExecuteError(t *testing.T) {
 
 func newOpsSessionWithMockClient(_ assert.TestingT) (OpSession, *mocks.OpSession) { //nolint: gocritic
 	mockClient := &mocks.OpSession{}
-	return &sImpl{mockClient}, mockClient
+	return &sImpl{Session: mockClient}, mockClient
 }
 
 type Element struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/target"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -19,11 +20,134 @@ func NewSession(ctx context.Context, sshcfg *ssh.ClientConfig, target string) (s
 // NewSessionWithConfig connects to the  target using the ssh configuration, and establishes
 // a netconf session with the client configuration.
 func NewSessionWithConfig(ctx context.Context, sshcfg *ssh.ClientConfig, target string, cfg *client.Config) (s OpSession, err error) {
+	return newSession(ctx, sshcfg, target, cfg, &sessionOptions{})
+}
+
+// NewSessionWithOptions connects to the target using the ssh configuration, and establishes
+// a netconf session configured by the options, applied over the configuration resolved for the target by
+// client.DefaultRegistry.
+func NewSessionWithOptions(ctx context.Context, sshcfg *ssh.ClientConfig, target string, opts ...SessionOption) (s OpSession, err error) {
+	so := &sessionOptions{cfg: *client.DefaultRegistry.Resolve(target)}
+	for _, opt := range opts {
+		opt(so)
+	}
+	if so.trace != nil {
+		ctx = client.WithClientTrace(ctx, so.trace)
+	}
+	return newSession(ctx, sshcfg, target, &so.cfg, so)
+}
+
+// NewSessionForTarget connects to the target's netconf endpoint, using the ssh configuration built from the
+// credentials resolved by r, and establishes a netconf session configured by the options, as for
+// NewSessionWithOptions.
+func NewSessionForTarget(ctx context.Context, t *target.Target, r target.CredentialResolver,
+	opts ...SessionOption) (s OpSession, err error) {
+	var sshcfg *ssh.ClientConfig
+	if sshcfg, err = target.SSHClientConfig(ctx, r, t, target.NETCONF); err != nil {
+		return
+	}
+	return NewSessionWithOptions(ctx, sshcfg, t.Endpoint(target.NETCONF), opts...)
+}
+
+func newSession(ctx context.Context, sshcfg *ssh.ClientConfig, target string, cfg *client.Config,
+	so *sessionOptions) (s OpSession, err error) {
 	var cs client.Session
 	if cs, err = client.NewRPCSessionWithConfig(ctx, sshcfg, target, cfg); err != nil {
 		return
 	}
+	// Retained before the session is wrapped, so that file transfers can share the connection.
+	sshClient := client.SSHClientOf(cs)
+	if so.audit != nil {
+		cs = so.audit.wrap(cs, sshcfg.User, target)
+	}
+	if so.dryRun != nil {
+		cs = so.dryRun.wrap(cs)
+	}
 
-	s = &sImpl{Session: cs}
+	si := &sImpl{Session: cs, decoders: so.decoders, namespaces: so.namespaces, strictData: so.strictData,
+		defaults: so.defaults, sshClient: sshClient}
+	if so.coalesceGets {
+		si.gets = newFlightGroup()
+	}
+	s = si
 	return
 }
+
+// SessionOption implements options for configuring session behaviour.
+type SessionOption func(*sessionOptions)
+
+type sessionOptions struct {
+	cfg          client.Config
+	trace        *client.ClientTrace
+	decoders     map[string]ReplyDecoder
+	namespaces   []Namespace
+	audit        *AuditLog
+	coalesceGets bool
+	strictData   bool
+	defaults     *configDefaults
+	dryRun       DryRunRecorder
+}
+
+// WithConfig defines the client configuration used by the session; options that follow it
+// override the corresponding configuration values.
+// Default value is client.DefaultConfig.
+func WithConfig(cfg *client.Config) SessionOption {
+	return func(so *sessionOptions) {
+		so.cfg = *cfg
+	}
+}
+
+// WithConfigOptions applies client configuration options to the session configuration.
+func WithConfigOptions(opts ...client.ConfigOption) SessionOption {
+	return func(so *sessionOptions) {
+		for _, opt := range opts {
+			opt(&so.cfg)
+		}
+	}
+}
+
+// WithSetupTimeout applies client.WithSetupTimeout to the session configuration.
+func WithSetupTimeout(secs int) SessionOption {
+	return WithConfigOptions(client.WithSetupTimeout(secs))
+}
+
+// WithoutChunkedFraming applies client.WithoutChunkedFraming to the session configuration.
+func WithoutChunkedFraming() SessionOption {
+	return WithConfigOptions(client.WithoutChunkedFraming())
+}
+
+// WithFraming applies client.WithFramingMode to the session configuration.
+func WithFraming(name string) SessionOption {
+	return WithConfigOptions(client.WithFramingMode(name))
+}
+
+// WithKeepalive applies client.WithKeepalive to the session configuration.
+func WithKeepalive(intervalSecs, maxMissed int) SessionOption {
+	return WithConfigOptions(client.WithKeepalive(intervalSecs, maxMissed))
+}
+
+// WithTrace defines the trace hooks used by the session, in place of any defined by the context.
+func WithTrace(trace *client.ClientTrace) SessionOption {
+	return func(so *sessionOptions) {
+		so.trace = trace
+	}
+}
+
+// WithReplyDecoder registers a decoder to be applied to the text content of elements with the name (ignoring
+// namespace) in the data returned by the Get... methods, before it is delivered to the caller.
+// For example: WithReplyDecoder("file-content", Base64Decoder).
+func WithReplyDecoder(element string, decoder ReplyDecoder) SessionOption {
+	return func(so *sessionOptions) {
+		if so.decoders == nil {
+			so.decoders = map[string]ReplyDecoder{}
+		}
+		so.decoders[element] = decoder
+	}
+}
+
+// WithNamespaces registers namespace prefixes with the session, as described by OpSession.RegisterNamespaces.
+func WithNamespaces(nslist ...Namespace) SessionOption {
+	return func(so *sessionOptions) {
+		so.namespaces = mergeNamespaces(so.namespaces, append([]Namespace(nil), nslist...))
+	}
+}
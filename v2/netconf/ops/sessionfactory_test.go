@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
 
 	"github.com/damianoneill/net/v2/netconf/testserver"
+	nettarget "github.com/damianoneill/net/v2/target"
 
 	assert "github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
@@ -50,6 +53,61 @@ func TestSessionSetupSuccess(t *testing.T) {
 	assert.NotNil(t, s, "OpSession should not be nil")
 }
 
+func TestSessionWithOptions(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	helloReceived := make(chan struct{})
+	s, err := NewSessionWithOptions(context.Background(), sshConfig, fmt.Sprintf("localhost:%d", ts.Port()),
+		WithConfig(&client.Config{SetupTimeoutSecs: 2}),
+		WithSetupTimeout(1),
+		WithoutChunkedFraming(),
+		WithFraming("eom"),
+		WithKeepalive(10, 2),
+		WithTrace(&client.ClientTrace{HelloDone: func(msg *common.HelloMessage) { close(helloReceived) }}),
+		WithReplyDecoder("file-content", Base64Decoder),
+		WithNamespaces(Namespace{"if", "urn:ietf:params:xml:ns:yang:ietf-interfaces"}),
+		WithGetCoalescing(),
+		WithStrictReplyData(),
+	)
+	assert.NoError(t, err, "Expecting new session to succeed")
+	assert.NotNil(t, s, "OpSession should not be nil")
+	defer s.Close()
+
+	select {
+	case <-helloReceived:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expecting trace hook to have been called")
+	}
+	sh := ts.SessionHandler(s.ID())
+	sh.WaitStart()
+	assert.Equal(t, common.NoChunkedCodecCapabilities, sh.ClientHello.Capabilities, "Expecting chunked framing not to be advertised")
+	assert.Contains(t, s.(*sImpl).decoders, "file-content", "Expecting reply decoder to be registered")
+	assert.Len(t, s.(*sImpl).namespaces, 1, "Expecting namespace to be registered")
+	assert.NotNil(t, s.(*sImpl).gets, "Expecting get coalescing to be enabled")
+	assert.True(t, s.(*sImpl).strictData, "Expecting strict reply data to be enabled")
+}
+
+func TestSessionWithOptionsSetupFailure(t *testing.T) {
+	ts := testserver.NewSSHServer(t, testserver.TestUserName, testserver.TestPassword)
+	defer ts.Close()
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	s, err := NewSessionWithOptions(context.Background(), sshConfig, fmt.Sprintf("localhost:%d", ts.Port()), WithSetupTimeout(1))
+	assert.Error(t, err, "Expecting new session to fail - no hello from server")
+	assert.Nil(t, s, "OpSession should be nil")
+}
+
 // Simple real NE access test
 
 // func TestRealNewSession(t *testing.T) {
@@ -70,3 +128,56 @@ func TestSessionSetupSuccess(t *testing.T) {
 // 	assert.NoError(t, err, "Not expecting exec to fail")
 // 	assert.NotNil(t, reply, "Reply should be non-nil")
 // }
+
+func TestSessionWithOptionsRegistry(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	target := fmt.Sprintf("localhost:%d", ts.Port())
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	client.DefaultRegistry.SetTarget(target, client.WithoutChunkedFraming())
+	defer client.DefaultRegistry.RemoveTarget(target)
+
+	s, err := NewSessionWithOptions(context.Background(), sshConfig, target,
+		WithConfigOptions(client.WithSetupTimeout(2)))
+	assert.NoError(t, err, "Expecting new session to succeed")
+	defer s.Close()
+
+	sh := ts.SessionHandler(s.ID())
+	sh.WaitStart()
+	assert.Equal(t, common.NoChunkedCodecCapabilities, sh.ClientHello.Capabilities, "Expecting target configuration to apply")
+}
+
+func TestSessionForTarget(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+
+	resolved := false
+	resolver := nettarget.CredentialResolverFunc(func(ctx context.Context, tgt *nettarget.Target,
+		p nettarget.Protocol) (*nettarget.Credentials, error) {
+		resolved = true
+		assert.Equal(t, nettarget.NETCONF, p)
+		assert.Equal(t, "device-1", tgt.CredentialRef)
+		return &nettarget.Credentials{Username: testserver.TestUserName, Password: testserver.TestPassword}, nil
+	})
+	tgt := &nettarget.Target{
+		Address:         "localhost",
+		Ports:           map[nettarget.Protocol]int{nettarget.NETCONF: ts.Port()},
+		CredentialRef:   "device-1",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+
+	s, err := NewSessionForTarget(context.Background(), tgt, resolver, WithSetupTimeout(1))
+	assert.NoError(t, err, "Expecting new session to succeed")
+	defer s.Close()
+	assert.True(t, resolved, "Expecting credentials to be resolved")
+}
+
+func TestSessionForTargetResolveFailure(t *testing.T) {
+	s, err := NewSessionForTarget(context.Background(), &nettarget.Target{Address: "localhost"}, nettarget.StaticCredentials(nil))
+	assert.EqualError(t, err, "no netconf credentials for localhost")
+	assert.Nil(t, s, "OpSession should be nil")
+}
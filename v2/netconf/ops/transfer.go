@@ -0,0 +1,329 @@
+package ops
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// File transfer.
+// FileTransfer copies files to and from the device of a netconf session using the scp protocol, over channels of
+// the ssh connection that carries the session, so that no further connection or authentication is required. The
+// device must allow the scp command to be executed by the session user.
+// Each transfer copies a single regular file; the recursive copy of directories is not supported.
+// SFTP is not implemented: devices that permit file transfer only over the sftp subsystem cannot be used, as the
+// transfers require the scp command.
+
+// ErrNoSSHConnection is returned by NewFileTransfer if the session is not established over ssh.
+var ErrNoSSHConnection = errors.New("session is not established over ssh")
+
+// ChecksumError is returned if the SHA-256 digest of the content transferred differs from that defined by
+// VerifySHA256.
+type ChecksumError struct {
+	// The remote path of the file.
+	Path string
+	// The expected and actual digests, as lower-case hex.
+	Expected, Actual string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.Path, e.Expected, e.Actual)
+}
+
+// TransferResult describes a completed transfer.
+type TransferResult struct {
+	// The number of bytes of file content transferred.
+	Bytes int64
+	// The SHA-256 digest of the content, as lower-case hex.
+	SHA256 string
+}
+
+// TransferOption implements options for configuring file transfers.
+type TransferOption func(*transferConfig)
+
+// TransferProgress defines a function that is called as file content is transferred, with the number of bytes
+// transferred so far and the size of the file.
+func TransferProgress(progress func(transferred, total int64)) TransferOption {
+	return func(c *transferConfig) {
+		c.progress = progress
+	}
+}
+
+// VerifySHA256 defines the expected SHA-256 digest of the file content, as hex. If the reader of an upload is an
+// io.ReadSeeker, the content is read twice, being verified before the transfer starts so that the remote file is not
+// changed if it differs. Otherwise the content is verified as it is sent, and the transfer is aborted with an error
+// in place of the final acknowledgement if it differs, which devices that write the file as it is received may not
+// honour, leaving it changed. A download fails once the content has been written, so the caller must discard it.
+// In each case a *ChecksumError is returned.
+func VerifySHA256(digest string) TransferOption {
+	return func(c *transferConfig) {
+		c.sha256 = strings.ToLower(digest)
+	}
+}
+
+// UploadMode defines the permissions of an uploaded file.
+// Default value is 0644.
+func UploadMode(mode os.FileMode) TransferOption {
+	return func(c *transferConfig) {
+		c.mode = mode
+	}
+}
+
+type transferConfig struct {
+	progress func(transferred, total int64)
+	sha256   string
+	mode     os.FileMode
+}
+
+// FileTransfer transfers files using the ssh connection of a session.
+type FileTransfer struct {
+	client *ssh.Client
+}
+
+// NewFileTransfer delivers a FileTransfer that uses the ssh connection of the session, which must remain open for the
+// duration of the transfers.
+func NewFileTransfer(s OpSession) (*FileTransfer, error) {
+	c := client.SSHClientOf(s)
+	if c == nil {
+		return nil, ErrNoSSHConnection
+	}
+	return &FileTransfer{client: c}, nil
+}
+
+// Upload copies size bytes read from r to the file with the remote path, which is created or replaced.
+// The transfer is abandoned if the context is done, which may leave the remote file incomplete.
+func (ft *FileTransfer) Upload(ctx context.Context, r io.Reader, size int64, remotePath string,
+	opts ...TransferOption) (*TransferResult, error) {
+	cfg := newTransferConfig(opts)
+	if rs, ok := r.(io.ReadSeeker); ok && cfg.sha256 != "" {
+		if err := cfg.verifyContent(remotePath, rs, size); err != nil {
+			return nil, errors.Wrapf(err, "failed to upload %s", remotePath)
+		}
+	}
+	result, err := ft.run(ctx, "scp -t "+shellQuote(remotePath), func(in io.Writer, out *bufio.Reader) (
+		*TransferResult, error) {
+		if err := readSCPAck(out); err != nil {
+			return nil, err
+		}
+		if _, err := fmt.Fprintf(in, "C%04o %d %s\n", cfg.mode.Perm(), size, path.Base(remotePath)); err != nil {
+			return nil, err
+		}
+		if err := readSCPAck(out); err != nil {
+			return nil, err
+		}
+
+		h := sha256.New()
+		if _, err := io.CopyN(io.MultiWriter(in, h, cfg.counter(size)), r, size); err != nil {
+			return nil, err
+		}
+		result := &TransferResult{Bytes: size, SHA256: hex.EncodeToString(h.Sum(nil))}
+		if err := cfg.verify(remotePath, result); err != nil {
+			// The content differs, or changed after it was verified. The error is reported to the device, but the
+			// remote file may already have been written.
+			_, _ = in.Write([]byte("\x01checksum mismatch\n"))
+			return nil, err
+		}
+		if _, err := in.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+		return result, readSCPAck(out)
+	})
+	return result, errors.Wrapf(err, "failed to upload %s", remotePath)
+}
+
+// Download copies the content of the file with the remote path to w.
+// The transfer is abandoned if the context is done.
+func (ft *FileTransfer) Download(ctx context.Context, remotePath string, w io.Writer,
+	opts ...TransferOption) (*TransferResult, error) {
+	cfg := newTransferConfig(opts)
+	result, err := ft.run(ctx, "scp -f "+shellQuote(remotePath), func(in io.Writer, out *bufio.Reader) (
+		*TransferResult, error) {
+		if _, err := in.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+		size, err := readSCPFileHeader(out)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = in.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+
+		h := sha256.New()
+		if _, err = io.CopyN(io.MultiWriter(w, h, cfg.counter(size)), out, size); err != nil {
+			return nil, err
+		}
+		if err = readSCPAck(out); err != nil {
+			return nil, err
+		}
+		if _, err = in.Write([]byte{0}); err != nil {
+			return nil, err
+		}
+		result := &TransferResult{Bytes: size, SHA256: hex.EncodeToString(h.Sum(nil))}
+		return result, cfg.verify(remotePath, result)
+	})
+	return result, errors.Wrapf(err, "failed to download %s", remotePath)
+}
+
+// Executes the scp command on a new channel, and performs the transfer with its input and output.
+func (ft *FileTransfer) run(ctx context.Context, command string,
+	transfer func(in io.Writer, out *bufio.Reader) (*TransferResult, error)) (*TransferResult, error) {
+	ss, err := ft.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer ss.Close()
+
+	in, err := ss.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := ss.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = ss.Close()
+		case <-done:
+		}
+	}()
+
+	var result *TransferResult
+	if err = ss.Start(command); err == nil {
+		if result, err = transfer(in, bufio.NewReader(out)); err == nil {
+			_ = in.Close()
+			err = ss.Wait()
+		}
+	}
+	if ctx.Err() != nil {
+		// The channel was closed as the context is done.
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func newTransferConfig(opts []TransferOption) *transferConfig {
+	cfg := &transferConfig{mode: 0o644}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Delivers a writer that reports the progress of a transfer of total bytes.
+func (c *transferConfig) counter(total int64) io.Writer {
+	return &progressCounter{progress: c.progress, total: total}
+}
+
+func (c *transferConfig) verify(remotePath string, result *TransferResult) error {
+	if c.sha256 != "" && c.sha256 != result.SHA256 {
+		return &ChecksumError{Path: remotePath, Expected: c.sha256, Actual: result.SHA256}
+	}
+	return nil
+}
+
+// Verifies the digest of the size bytes of content read from rs, returning it to the start of the content.
+func (c *transferConfig) verifyContent(remotePath string, rs io.ReadSeeker, size int64) error {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err = io.CopyN(h, rs, size); err != nil {
+		return err
+	}
+	if _, err = rs.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	return c.verify(remotePath, &TransferResult{Bytes: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+}
+
+type progressCounter struct {
+	progress           func(transferred, total int64)
+	transferred, total int64
+}
+
+func (p *progressCounter) Write(b []byte) (int, error) {
+	if p.progress != nil {
+		p.transferred += int64(len(b))
+		p.progress(p.transferred, p.total)
+	}
+	return len(b), nil
+}
+
+// Reads the response to an scp protocol message, which is a 0 byte, or a warning or error followed by a message.
+func readSCPAck(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b == 0 {
+		return nil
+	}
+	msg, _ := r.ReadString('\n')
+	return errors.Errorf("scp: %s", strings.TrimSpace(strings.TrimPrefix(msg, "scp: ")))
+}
+
+// Reads the file header sent by an scp source, delivering the size of the file.
+func readSCPFileHeader(r *bufio.Reader) (int64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 'C' {
+		if err = r.UnreadByte(); err != nil {
+			return 0, err
+		}
+		if err = readSCPAck(r); err != nil {
+			return 0, err
+		}
+		return 0, errors.Errorf("scp: unexpected response %q", b)
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	// The header holds the mode, size and name, which may contain spaces.
+	fields := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 3)
+	if len(fields) != 3 {
+		return 0, errors.Errorf("scp: invalid file header %q", strings.TrimSpace(line))
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || size < 0 {
+		return 0, errors.Errorf("scp: invalid file size in header %q", strings.TrimSpace(line))
+	}
+	return size, nil
+}
+
+// Quotes s for interpretation by a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// SSHClient delivers the ssh client connection that carries the session, or nil if the session is not established
+// over ssh.
+func (s *sImpl) SSHClient() *ssh.Client {
+	if s.sshClient != nil {
+		return s.sshClient
+	}
+	return client.SSHClientOf(s.Session)
+}
@@ -0,0 +1,133 @@
+package ops
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newFileTransfer(t *testing.T) (*FileTransfer, *testserver.SCPStore, OpSession) {
+	store := testserver.NewSCPStore()
+	ts := testserver.NewTestNetconfServer(t).WithExecChannelHandler(store.Handle)
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint: gosec
+	}
+	s, err := NewSessionWithOptions(context.Background(), sshConfig, fmt.Sprintf("localhost:%d", ts.Port()),
+		WithAudit(NewAuditLog(NewMemoryAuditStore())))
+	assert.NoError(t, err, "Expecting new session to succeed")
+
+	ft, err := NewFileTransfer(s)
+	assert.NoError(t, err, "Expecting the ssh connection of the wrapped session to be available")
+	return ft, store, s
+}
+
+func digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFileTransfer(t *testing.T) {
+	ft, store, s := newFileTransfer(t)
+	defer s.Close()
+
+	content := []byte(strings.Repeat("firmware image\n", 1000))
+	var progress []int64
+	result, err := ft.Upload(context.Background(), bytes.NewReader(content), int64(len(content)), "/tmp/it's.bin",
+		VerifySHA256(digest(content)), TransferProgress(func(transferred, total int64) {
+			assert.Equal(t, int64(len(content)), total)
+			progress = append(progress, transferred)
+		}))
+	assert.NoError(t, err)
+	assert.Equal(t, &TransferResult{Bytes: int64(len(content)), SHA256: digest(content)}, result)
+	assert.NotEmpty(t, progress)
+	assert.Equal(t, int64(len(content)), progress[len(progress)-1])
+
+	stored, ok := store.Get("/tmp/it's.bin")
+	assert.True(t, ok, "Expecting the quoted path to be interpreted")
+	assert.Equal(t, content, stored)
+
+	var buf bytes.Buffer
+	result, err = ft.Download(context.Background(), "/tmp/it's.bin", &buf, VerifySHA256(digest(content)))
+	assert.NoError(t, err)
+	assert.Equal(t, content, buf.Bytes())
+	assert.Equal(t, digest(content), result.SHA256)
+
+	assert.NoError(t, s.Lock(RunningCfg), "Expecting the netconf session to be unaffected by the transfers")
+}
+
+func TestFileTransferChecksumMismatch(t *testing.T) {
+	ft, store, s := newFileTransfer(t)
+	defer s.Close()
+
+	_, err := ft.Upload(context.Background(), strings.NewReader("corrupt"), 7, "/tmp/a.bin",
+		VerifySHA256(digest([]byte("content"))))
+	var cerr *ChecksumError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, "/tmp/a.bin", cerr.Path)
+	assert.Equal(t, digest([]byte("corrupt")), cerr.Actual)
+	_, ok := store.Get("/tmp/a.bin")
+	assert.False(t, ok, "Expecting an aborted upload not to be stored")
+
+	// Content that cannot be read twice is verified as it is sent, and the upload aborted before the final ack.
+	_, err = ft.Upload(context.Background(), io.MultiReader(strings.NewReader("corrupt")), 7, "/tmp/a.bin",
+		VerifySHA256(digest([]byte("content"))))
+	assert.True(t, errors.As(err, &cerr))
+	_, ok = store.Get("/tmp/a.bin")
+	assert.False(t, ok, "Expecting an aborted upload not to be stored")
+	_, err = ft.Upload(context.Background(), io.MultiReader(strings.NewReader("content")), 7, "/tmp/a.bin",
+		VerifySHA256(digest([]byte("content"))))
+	assert.NoError(t, err)
+	stored, _ := store.Get("/tmp/a.bin")
+	assert.Equal(t, []byte("content"), stored)
+
+	store.Put("/tmp/b.bin", []byte("corrupt"))
+	var buf bytes.Buffer
+	_, err = ft.Download(context.Background(), "/tmp/b.bin", &buf, VerifySHA256(digest([]byte("content"))))
+	assert.True(t, errors.As(err, &cerr))
+	assert.Contains(t, err.Error(), "failed to download /tmp/b.bin: checksum mismatch")
+}
+
+func TestFileTransferErrors(t *testing.T) {
+	ft, _, s := newFileTransfer(t)
+	defer s.Close()
+
+	_, err := ft.Download(context.Background(), "/tmp/missing", &bytes.Buffer{})
+	assert.EqualError(t, err, "failed to download /tmp/missing: scp: /tmp/missing: No such file or directory")
+
+	_, err = ft.Upload(context.Background(), strings.NewReader("short"), 10, "/tmp/c.bin")
+	assert.Error(t, err, "Expecting a short read to fail")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ft.Upload(ctx, strings.NewReader("content"), 7, "/tmp/c.bin")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = NewFileTransfer(&sImpl{})
+	assert.Equal(t, ErrNoSSHConnection, err)
+}
+
+func TestReadSCPFileHeader(t *testing.T) {
+	size, err := readSCPFileHeader(bufio.NewReader(strings.NewReader("C0644 42 file name with spaces.bin\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), size)
+
+	for _, header := range []string{"C0644 -1 a.bin\n", "C0644 x a.bin\n", "C0644 42\n"} {
+		_, err = readSCPFileHeader(bufio.NewReader(strings.NewReader(header)))
+		assert.Error(t, err, "Expecting header %q to be rejected", header)
+	}
+}
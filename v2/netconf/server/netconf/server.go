@@ -3,12 +3,14 @@ package netconf
 import (
 	"context"
 	"encoding/xml"
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/damianoneill/net/v2/netconf/common"
 	"github.com/damianoneill/net/v2/netconf/common/codec"
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
 
 	"github.com/damianoneill/net/v2/netconf/server/ssh"
 
@@ -24,6 +26,52 @@ type Server struct {
 	sessionHandlers map[uint64]*SessionHandler
 	nextSid         uint64
 	trace           *Trace
+	decoderOptions  []rfc6242.DecoderOption
+	authorize       AuthorizeFunc
+	pacing          pacing
+}
+
+// ServerOption implements options for configuring server behaviour.
+type ServerOption func(*Server)
+
+// MaxChunkSize defines the maximum chunk size that will be accepted from a client using chunked framing.
+// Clients sending larger chunks are rejected.
+// Default is the maximum chunk size permitted by RFC6242.
+func MaxChunkSize(size uint32) ServerOption {
+	return func(s *Server) {
+		s.decoderOptions = append(s.decoderOptions, rfc6242.WithMaximumDecodedChunkSize(size))
+	}
+}
+
+// MaxMessageSize defines the maximum size in bytes of a message that will be accepted from a client.
+// Clients sending larger messages are rejected.
+// Default is no limit.
+func MaxMessageSize(size int) ServerOption {
+	return func(s *Server) {
+		s.decoderOptions = append(s.decoderOptions, rfc6242.WithMaximumMessageSize(size))
+	}
+}
+
+// StrictLineFeeds rejects clients using chunked framing whose chunk headers are not terminated by a single line feed,
+// as required by RFC6242.
+// Default is to tolerate a carriage return preceding the line feed.
+func StrictLineFeeds() ServerOption {
+	return func(s *Server) {
+		s.decoderOptions = append(s.decoderOptions, rfc6242.WithStrictLineFeeds())
+	}
+}
+
+// AuthorizeFunc is called to determine whether the session may execute an RPC request, identified by the
+// operation name (the local name of the request element, for example "edit-config").
+type AuthorizeFunc func(s *SessionHandler, operation string) bool
+
+// Authorize defines a function used to authorize each RPC request before it is passed to the session callback.
+// Requests that are not authorized are rejected with an access-denied rpc-error.
+// Default is to authorize all requests.
+func Authorize(fn AuthorizeFunc) ServerOption {
+	return func(s *Server) {
+		s.authorize = fn
+	}
 }
 
 // SessionCallback defines the caller supplied callback functions.
@@ -51,6 +99,8 @@ type SessionHandler struct {
 	// The codecs used to handle client i/o
 	enc *codec.Encoder
 	dec *codec.Decoder
+	// The input read by the decoder from the transport channel.
+	input *transportReader
 
 	// Serialises access to encoder (avoiding contention between sending notifications and request responses).
 	encLock sync.Mutex
@@ -95,6 +145,9 @@ type RPCReplyMessage struct {
 	Ok        bool              `xml:",omitempty"`
 	RawReply  string            `xml:"-"`
 	MessageID string            `xml:"message-id,attr"`
+	// Stream, if defined, writes the content of the data element in place of Data, allowing the reply to be
+	// delivered incrementally. Errors and Ok are ignored.
+	Stream ReplyStreamer `xml:"-"`
 }
 
 type ReplyData struct {
@@ -108,13 +161,18 @@ type RequestHandler func(h *SessionHandler, req *RPCRequestMessage)
 
 // NewServer creates a new Server that will accept Netconf localhost connections on an ephemeral port (available
 // via Port()), with credentials defined by the sshcfg configuration.
-func NewServer(ctx context.Context, address string, port int, sshcfg *xssh.ServerConfig, sf SessionFactory) (ncs *Server, err error) {
+func NewServer(ctx context.Context, address string, port int, sshcfg *xssh.ServerConfig, sf SessionFactory,
+	opts ...ServerOption,
+) (ncs *Server, err error) {
 	trace := ContextNetconfTrace(ctx)
 	if trace.Trace != nil && ssh.ContextSSHTrace(ctx) == nil {
 		ctx = ssh.WithSSHTrace(ctx, trace.Trace)
 	}
 
 	ncs = &Server{sessionHandlers: make(map[uint64]*SessionHandler), sf: sf, trace: trace}
+	for _, opt := range opts {
+		opt(ncs)
+	}
 
 	ncs.Server, err = ssh.NewServer(ctx, address, port, sshcfg, ncs.handlerFactory())
 	if err != nil {
@@ -165,7 +223,8 @@ func (ncs *Server) newSessionHandler(svrcon *xssh.ServerConn, sid uint64) *Sessi
 // Handle establishes a Netconf server session on a newly-connected SSH channel.
 func (h *SessionHandler) Handle(ch xssh.Channel) {
 	h.ch = ch
-	h.dec = codec.NewDecoder(ch)
+	h.input = &transportReader{r: ch}
+	h.dec = codec.NewDecoder(h.input, h.server.decoderOptions...)
 	h.enc = codec.NewEncoder(ch)
 
 	wg := &sync.WaitGroup{}
@@ -184,6 +243,16 @@ func (h *SessionHandler) Handle(ch xssh.Channel) {
 	h.server.trace.EndSession(h, err)
 }
 
+// User delivers the name of the user that authenticated the session.
+func (h *SessionHandler) User() string {
+	return h.svrcon.User()
+}
+
+// Permissions delivers the permissions, if any, granted to the session when it was authenticated.
+func (h *SessionHandler) Permissions() *xssh.Permissions {
+	return h.svrcon.Permissions
+}
+
 // Close initiates session tear-down by closing the underlying transport channel.
 func (h *SessionHandler) Close() {
 	_ = h.ch.Close()
@@ -207,6 +276,16 @@ func (h *SessionHandler) handleIncomingMessages(wg *sync.WaitGroup) {
 	for {
 		token, err := h.dec.Token()
 		if err != nil {
+			switch {
+			case err == io.EOF:
+			case h.input.err != nil:
+				// The transport has failed, or the client disconnected part way through a message.
+				h.server.trace.Disconnected(h, err)
+			default:
+				// Input from the client is non-compliant, so reject it by closing the transport.
+				h.server.trace.Rejected(h, err)
+				h.Close()
+			}
 			break
 		}
 		h.handleToken(token)
@@ -230,9 +309,9 @@ func (h *SessionHandler) handleHello(token xml.StartElement) {
 
 	err := h.decodeElement(&h.ClientHello, &token)
 	if err == nil {
-		if common.PeerSupportsChunkedFraming(h.ClientHello.Capabilities) && common.PeerSupportsChunkedFraming(h.capabilities) {
-			// Update the codec to use chunked framing from now.
-			codec.EnableChunkedFraming(h.dec, h.enc)
+		if framing := codec.NegotiateFraming(h.capabilities, h.ClientHello.Capabilities); framing != nil {
+			// Update the codec to use the negotiated framing from now.
+			codec.SetFraming(h.dec, h.enc, framing)
 		}
 	}
 
@@ -246,9 +325,43 @@ func (h *SessionHandler) handleRPC(token xml.StartElement) {
 		return
 	}
 
-	reply := h.cb.HandleRequest(request)
+	var reply *RPCReplyMessage
+	if operation := request.Request.XMLName.Local; h.server.authorize != nil && !h.server.authorize(h, operation) {
+		h.server.trace.Denied(h, operation)
+		reply = accessDeniedReply(request, operation)
+	} else {
+		reply = h.cb.HandleRequest(request)
+	}
 	if reply != nil {
-		_ = h.encode(reply)
+		_ = h.encodeReply(reply)
+	}
+}
+
+func (h *SessionHandler) encodeReply(reply *RPCReplyMessage) error {
+	pacing := h.server.pacing
+	if pacing.delay > 0 {
+		time.Sleep(pacing.delay)
+	}
+	switch {
+	case reply.Stream != nil:
+		return h.encodeStream(func(w io.Writer) error {
+			return writeStreamedReply(newReplyWriter(w, pacing), reply)
+		})
+	case pacing.enabled():
+		return h.encodeStream(func(w io.Writer) error {
+			return writePacedReply(newReplyWriter(w, pacing), reply)
+		})
+	default:
+		return h.encode(reply)
+	}
+}
+
+func accessDeniedReply(req *RPCRequestMessage, operation string) *RPCReplyMessage {
+	return &RPCReplyMessage{
+		MessageID: req.MessageID,
+		Errors: []common.RPCError{
+			{Type: "protocol", Tag: "access-denied", Severity: "error", Message: "access denied to operation " + operation},
+		},
 	}
 }
 
@@ -265,3 +378,30 @@ func (h *SessionHandler) encode(m interface{}) error {
 	h.server.trace.Encoded(h, err)
 	return err
 }
+
+func (h *SessionHandler) encodeStream(fn func(w io.Writer) error) error {
+	h.encLock.Lock()
+	defer h.encLock.Unlock()
+	err := h.enc.EncodeStream(fn)
+	h.server.trace.Encoded(h, err)
+	if err != nil {
+		// The message cannot be completed, so the client cannot decode any further messages.
+		h.Close()
+	}
+	return err
+}
+
+// Records the error, if any, that ended reading from the transport, so that the failure of the transport can be
+// distinguished from non-compliant input.
+type transportReader struct {
+	r   io.Reader
+	err error
+}
+
+func (t *transportReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	return n, err
+}
@@ -3,11 +3,14 @@ package netconf
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/damianoneill/net/v2/netconf/ops"
 
 	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/common/codec/rfc6242"
 	"github.com/damianoneill/net/v2/netconf/server/ssh"
 	xssh "golang.org/x/crypto/ssh"
 
@@ -88,3 +91,126 @@ func TestServer(t *testing.T) {
 	assert.NotEmpty(t, result, "Reply should be non-nil")
 	assert.Equal(t, `<top><sub attr="cfgval1"><child1>cfgval2</child1></sub></top>`, result)
 }
+
+func TestServerRejectsOversizedMessage(t *testing.T) {
+	sshcfg, err := ssh.PasswordConfig(TestUserName, TestPassword)
+	assert.NoError(t, err)
+
+	rejected := make(chan error, 1)
+	ctx := WithTrace(context.Background(), &Trace{Rejected: func(s *SessionHandler, e error) { rejected <- e }})
+	server, err := NewServer(ctx, "localhost", 0, sshcfg, sessionFactory, MaxChunkSize(4096), MaxMessageSize(1024))
+	assert.NoError(t, err)
+	defer server.Close()
+
+	sshConfig := &xssh.ClientConfig{
+		User:            TestUserName,
+		Auth:            []xssh.AuthMethod{xssh.Password(TestPassword)},
+		HostKeyCallback: xssh.InsecureIgnoreHostKey(),
+	}
+
+	ncs, err := ops.NewSession(context.Background(), sshConfig, fmt.Sprintf("%s:%d", "localhost", server.Port()))
+	assert.NoError(t, err, "Not expecting new session to fail")
+	defer ncs.Close()
+
+	var result string
+	err = ncs.GetSubtree("/", &result)
+	assert.NoError(t, err, "Not expecting get within limits to fail")
+
+	err = ncs.GetSubtree(strings.Repeat("<filter/>", 200), &result)
+	assert.Error(t, err, "Expecting oversized get to fail")
+	assert.ErrorIs(t, <-rejected, rfc6242.ErrMessageSizeLimitExceeded)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	sshcfg, err := ssh.PasswordConfig(TestUserName, TestPassword,
+		ssh.WithAuthCallback(func(username string, password []byte, key xssh.PublicKey) (*xssh.Permissions, error) {
+			if username == "reader" && string(password) == "readerPassword" {
+				return &xssh.Permissions{Extensions: map[string]string{"role": "read-only"}}, nil
+			}
+			return nil, fmt.Errorf("rejected %s", username)
+		}))
+	assert.NoError(t, err)
+
+	denied := make(chan string, 1)
+	ctx := WithTrace(context.Background(), &Trace{Denied: func(s *SessionHandler, operation string) { denied <- operation }})
+	server, err := NewServer(ctx, "localhost", 0, sshcfg, sessionFactory,
+		Authorize(func(s *SessionHandler, operation string) bool {
+			return s.Permissions() == nil || s.Permissions().Extensions["role"] != "read-only" || operation == "get"
+		}))
+	assert.NoError(t, err)
+	defer server.Close()
+
+	target := fmt.Sprintf("%s:%d", "localhost", server.Port())
+	newSession := func(user, password string) ops.OpSession {
+		sshConfig := &xssh.ClientConfig{
+			User:            user,
+			Auth:            []xssh.AuthMethod{xssh.Password(password)},
+			HostKeyCallback: xssh.InsecureIgnoreHostKey(),
+		}
+		ncs, err := ops.NewSession(context.Background(), sshConfig, target)
+		assert.NoError(t, err, "Not expecting new session to fail")
+		return ncs
+	}
+
+	reader := newSession("reader", "readerPassword")
+
+	var result string
+	assert.NoError(t, reader.GetSubtree("/", &result), "Expecting get to be authorized")
+
+	err = reader.GetConfigSubtree("/", ops.CandidateCfg, &result)
+	assert.Error(t, err, "Expecting get-config to be denied")
+	assert.Contains(t, err.Error(), "access denied to operation get-config")
+	assert.Equal(t, "get-config", <-denied)
+	reader.Close()
+
+	admin := newSession(TestUserName, TestPassword)
+	assert.NoError(t, admin.GetConfigSubtree("/", ops.CandidateCfg, &result), "Expecting get-config to be authorized")
+	admin.Close()
+
+	_, err = ops.NewSession(context.Background(), &xssh.ClientConfig{
+		User:            "reader",
+		Auth:            []xssh.AuthMethod{xssh.Password("wrong")},
+		HostKeyCallback: xssh.InsecureIgnoreHostKey(),
+	}, target)
+	assert.Error(t, err, "Expecting invalid credentials to be rejected")
+}
+
+func TestServerClientDisconnect(t *testing.T) {
+	sshcfg, err := ssh.PasswordConfig(TestUserName, TestPassword)
+	assert.NoError(t, err)
+
+	disconnected := make(chan error, 1)
+	ctx := WithTrace(context.Background(), &Trace{
+		Rejected:     func(s *SessionHandler, e error) { t.Errorf("Not expecting disconnect to be rejected: %v", e) },
+		Disconnected: func(s *SessionHandler, e error) { disconnected <- e },
+	})
+	server, err := NewServer(ctx, "localhost", 0, sshcfg, sessionFactory)
+	assert.NoError(t, err)
+	defer server.Close()
+
+	client, err := xssh.Dial("tcp", fmt.Sprintf("%s:%d", "localhost", server.Port()), &xssh.ClientConfig{
+		User:            TestUserName,
+		Auth:            []xssh.AuthMethod{xssh.Password(TestPassword)},
+		HostKeyCallback: xssh.InsecureIgnoreHostKey(),
+	})
+	assert.NoError(t, err)
+	session, err := client.NewSession()
+	assert.NoError(t, err)
+	stdin, err := session.StdinPipe()
+	assert.NoError(t, err)
+	assert.NoError(t, session.RequestSubsystem("netconf"))
+
+	// Disconnect part way through a request.
+	_, err = stdin.Write([]byte(`<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>` +
+		`<capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>]]>]]>` +
+		`<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><get>`))
+	assert.NoError(t, err)
+	_ = client.Close()
+
+	select {
+	case err = <-disconnected:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expecting disconnect to be reported")
+	}
+}
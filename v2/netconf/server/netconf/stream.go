@@ -0,0 +1,109 @@
+package netconf
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+// ReplyStreamer is a function that writes the content of the data element of an rpc-reply to a ReplyWriter,
+// allowing large or slowly generated replies to be delivered to the client incrementally.
+// If the function returns an error the reply cannot be completed, so the session is closed.
+type ReplyStreamer func(w *ReplyWriter) error
+
+// ReplyPacing defines the pacing of rpc replies sent to clients: replies are sent in pieces of at most chunkSize
+// bytes (each a separate chunk when chunked framing is used), with a delay before each piece.
+// The delay before the first piece elapses before the session's encoder is locked, so that other messages, such as
+// notifications, are not held up by it; the delays between the pieces of a reply necessarily hold the encoder, as
+// the pieces of a message cannot be interleaved with other messages.
+// Pacing applies to streamed replies (see RPCReplyMessage.Stream) and to replies encoded by the server.
+// It is intended to allow the incremental decoding and timeout behaviour of clients to be tested.
+// Default is no pacing.
+func ReplyPacing(chunkSize int, delay time.Duration) ServerOption {
+	return func(s *Server) {
+		s.pacing = pacing{chunkSize: chunkSize, delay: delay}
+	}
+}
+
+type pacing struct {
+	chunkSize int
+	delay     time.Duration
+}
+
+func (p pacing) enabled() bool {
+	return p.chunkSize > 0 || p.delay > 0
+}
+
+// ReplyWriter buffers the content of a streamed reply, and sends it to the client when it is flushed, or when
+// the buffered content reaches the chunk size defined by ReplyPacing.
+type ReplyWriter struct {
+	out    io.Writer
+	pacing pacing
+	buf    []byte
+	sent   bool
+}
+
+func newReplyWriter(out io.Writer, p pacing) *ReplyWriter {
+	return &ReplyWriter{out: out, pacing: p}
+}
+
+// Write buffers b, sending the buffered content if it reaches the paced chunk size.
+func (w *ReplyWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for w.pacing.chunkSize > 0 && len(w.buf) >= w.pacing.chunkSize {
+		if err := w.send(w.buf[:w.pacing.chunkSize]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[w.pacing.chunkSize:]
+	}
+	return len(b), nil
+}
+
+// WriteString buffers s, as for Write.
+func (w *ReplyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends the buffered content to the client.
+func (w *ReplyWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.send(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+func (w *ReplyWriter) send(b []byte) error {
+	if w.sent && w.pacing.delay > 0 {
+		time.Sleep(w.pacing.delay)
+	}
+	w.sent = true
+	_, err := w.out.Write(b)
+	return err
+}
+
+// Writes a streamed reply, enclosing the content written by the streamer in the rpc-reply and data elements.
+func writeStreamedReply(w *ReplyWriter, reply *RPCReplyMessage) error {
+	sb := &strings.Builder{}
+	sb.WriteString(`<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="`)
+	_ = xml.EscapeText(sb, []byte(reply.MessageID))
+	sb.WriteString(`"><data>`)
+	_, _ = w.WriteString(sb.String())
+
+	if err := reply.Stream(w); err != nil {
+		return err
+	}
+
+	_, _ = w.WriteString(`</data></rpc-reply>`)
+	return w.Flush()
+}
+
+// Writes a reply encoded by the server, paced as defined by ReplyPacing.
+func writePacedReply(w *ReplyWriter, reply *RPCReplyMessage) error {
+	if err := xml.NewEncoder(w).Encode(reply); err != nil {
+		return err
+	}
+	return w.Flush()
+}
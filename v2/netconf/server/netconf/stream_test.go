@@ -0,0 +1,157 @@
+package netconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/common/codec"
+	"github.com/damianoneill/net/v2/netconf/ops"
+	"github.com/damianoneill/net/v2/netconf/server/ssh"
+	xssh "golang.org/x/crypto/ssh"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type streamCallback struct {
+	stream ReplyStreamer
+}
+
+func (cb *streamCallback) Capabilities() []string {
+	return common.DefaultCapabilities
+}
+
+func (cb *streamCallback) HandleRequest(req *RPCRequestMessage) *RPCReplyMessage {
+	if req.Request.XMLName.Local == "get" {
+		return &RPCReplyMessage{MessageID: req.MessageID, Stream: cb.stream}
+	}
+	return &RPCReplyMessage{MessageID: req.MessageID, Data: ReplyData{Data: responseFor(req)}}
+}
+
+func newStreamSession(t *testing.T, stream ReplyStreamer, opts []ServerOption) ops.OpSession {
+	sshcfg, err := ssh.PasswordConfig(TestUserName, TestPassword)
+	assert.NoError(t, err)
+
+	server, err := NewServer(context.Background(), "localhost", 0, sshcfg,
+		func(sh *SessionHandler) SessionCallback { return &streamCallback{stream: stream} }, opts...)
+	assert.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	sshConfig := &xssh.ClientConfig{
+		User:            TestUserName,
+		Auth:            []xssh.AuthMethod{xssh.Password(TestPassword)},
+		HostKeyCallback: xssh.InsecureIgnoreHostKey(),
+	}
+	ncs, err := ops.NewSessionWithOptions(context.Background(), sshConfig, fmt.Sprintf("localhost:%d", server.Port()))
+	assert.NoError(t, err, "Not expecting new session to fail")
+	t.Cleanup(ncs.Close)
+	return ncs
+}
+
+func TestStreamedReply(t *testing.T) {
+	const items = 100
+	ncs := newStreamSession(t, func(w *ReplyWriter) error {
+		_, _ = w.WriteString("<top>")
+		for i := 0; i < items; i++ {
+			_, _ = fmt.Fprintf(w, "<item><id>%d</id><value>%s</value></item>", i, strings.Repeat("x", 100))
+			if i%10 == 9 {
+				if err := w.Flush(); err != nil {
+					return err
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		_, _ = w.WriteString("</top>")
+		return nil
+	}, nil)
+
+	var result string
+	assert.NoError(t, ncs.GetSubtree("/", &result), "Not expecting get to fail")
+	assert.True(t, strings.HasPrefix(result, "<top><item><id>0</id>"))
+	assert.True(t, strings.HasSuffix(result, "</item></top>"))
+	assert.Equal(t, items, strings.Count(result, "<item>"))
+
+	err := ncs.GetConfigSubtree("/", ops.CandidateCfg, &result)
+	assert.NoError(t, err, "Expecting session to remain usable after a streamed reply")
+	assert.Equal(t, `<top><sub attr="cfgval1"><child1>cfgval2</child1></sub></top>`, result)
+}
+
+func TestReplyPacing(t *testing.T) {
+	ncs := newStreamSession(t, func(w *ReplyWriter) error {
+		_, err := w.WriteString("<top>" + strings.Repeat("<a/>", 50) + "</top>")
+		return err
+	}, []ServerOption{ReplyPacing(64, 10*time.Millisecond)})
+
+	var result string
+	start := time.Now()
+	assert.NoError(t, ncs.GetSubtree("/", &result), "Not expecting get to fail")
+	assert.Equal(t, 50, strings.Count(result, "<a/>"))
+	// The streamed reply exceeds 250 bytes, so is sent in at least 4 paced pieces.
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	start = time.Now()
+	assert.NoError(t, ncs.GetConfigSubtree("/", ops.CandidateCfg, &result), "Not expecting get-config to fail")
+	assert.Equal(t, `<top><sub attr="cfgval1"><child1>cfgval2</child1></sub></top>`, result)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "Expecting encoded replies to be paced")
+}
+
+func TestStreamedReplyFailure(t *testing.T) {
+	ncs := newStreamSession(t, func(w *ReplyWriter) error {
+		_, _ = w.WriteString("<top>")
+		_ = w.Flush()
+		return errors.New("generation failed")
+	}, nil)
+
+	var result string
+	assert.Error(t, ncs.GetSubtree("/", &result), "Expecting get to fail when the reply cannot be completed")
+}
+
+func TestStreamedReplyDelay(t *testing.T) {
+	release := make(chan struct{})
+	ncs := newStreamSession(t, func(w *ReplyWriter) error {
+		_, _ = w.WriteString("<top>")
+		_ = w.Flush()
+		<-release
+		_, _ = w.WriteString("</top>")
+		return nil
+	}, nil)
+
+	rchan := make(chan *common.RPCReply, 1)
+	assert.NoError(t, ncs.ExecuteAsync(common.Request(`<get/>`), rchan))
+	select {
+	case <-rchan:
+		assert.Fail(t, "Not expecting reply before it is complete")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case reply := <-rchan:
+		assert.NotNil(t, reply, "Expecting reply once complete")
+		assert.Equal(t, "<data><top></top></data>", reply.Data)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Expecting reply once complete")
+	}
+}
+
+func TestReplyPacingDelayNotHoldingEncoder(t *testing.T) {
+	out := &bytes.Buffer{}
+	h := &SessionHandler{server: &Server{pacing: pacing{delay: 100 * time.Millisecond}, trace: NoOpLoggingHooks},
+		enc: codec.NewEncoder(out)}
+
+	done := make(chan error)
+	go func() {
+		done <- h.encodeReply(&RPCReplyMessage{MessageID: "1"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, h.encLock.TryLock(), "Expecting the encoder to be available during the first delay")
+	h.encLock.Unlock()
+
+	assert.NoError(t, <-done)
+	assert.Contains(t, out.String(), `message-id="1"`)
+}
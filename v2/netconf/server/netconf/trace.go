@@ -40,6 +40,14 @@ type Trace struct {
 	ClientHello  func(s *SessionHandler)
 	Encoded      func(s *SessionHandler, e error)
 	Decoded      func(s *SessionHandler, e error)
+	// Rejected is called when a session is closed because the client input is not compliant, for example
+	// when it exceeds the configured framing limits.
+	Rejected func(s *SessionHandler, e error)
+	// Disconnected is called when a session ends because the transport fails, or the client disconnects, part way
+	// through a message.
+	Disconnected func(s *SessionHandler, e error)
+	// Denied is called when an RPC request is rejected because the session is not authorized to execute it.
+	Denied func(s *SessionHandler, operation string)
 }
 
 // DefaultLoggingHooks provides a default logging hook to report errors.
@@ -64,6 +72,15 @@ var DefaultLoggingHooks = &Trace{
 			log.Printf("Decoded id:%d error:%v\n", s.sid, e)
 		}
 	},
+	Rejected: func(s *SessionHandler, e error) {
+		log.Printf("Rejected id:%d error:%v\n", s.sid, e)
+	},
+	Disconnected: func(s *SessionHandler, e error) {
+		log.Printf("Disconnected id:%d error:%v\n", s.sid, e)
+	},
+	Denied: func(s *SessionHandler, operation string) {
+		log.Printf("Denied id:%d operation:%s\n", s.sid, operation)
+	},
 }
 
 // DiagnosticLoggingHooks provides a set of default diagnostic hooks
@@ -77,6 +94,11 @@ var DiagnosticLoggingHooks = &Trace{
 	EndSession: func(s *SessionHandler, e error) {
 		log.Printf("EndSession id:%d error:%v\n", s.sid, e)
 	},
+	Rejected:     DefaultLoggingHooks.Rejected,
+	Disconnected: DefaultLoggingHooks.Disconnected,
+	Denied: func(s *SessionHandler, operation string) {
+		log.Printf("Denied id:%d user:%s operation:%s\n", s.sid, s.User(), operation)
+	},
 }
 
 // NoOpLoggingHooks provides set of hooks that do nothing.
@@ -86,4 +108,7 @@ var NoOpLoggingHooks = &Trace{
 	EndSession:   func(s *SessionHandler, e error) {},
 	Encoded:      func(s *SessionHandler, e error) {},
 	Decoded:      func(s *SessionHandler, e error) {},
+	Rejected:     func(s *SessionHandler, e error) {},
+	Disconnected: func(s *SessionHandler, e error) {},
+	Denied:       func(s *SessionHandler, operation string) {},
 }
@@ -12,6 +12,9 @@ func TestDefaultHooksForUntestableExceptions(t *testing.T) {
 	hooks.EndSession(session, errors.New("failed"))
 	hooks.Encoded(session, errors.New("failed"))
 	hooks.Decoded(session, errors.New("failed"))
+	hooks.Rejected(session, errors.New("failed"))
+	hooks.Disconnected(session, errors.New("failed"))
+	hooks.Denied(session, "edit-config")
 }
 
 func TestNoLoggingHooks(t *testing.T) {
@@ -21,4 +24,7 @@ func TestNoLoggingHooks(t *testing.T) {
 	hooks.EndSession(session, errors.New("failed"))
 	hooks.Encoded(session, errors.New("failed"))
 	hooks.Decoded(session, errors.New("failed"))
+	hooks.Rejected(session, errors.New("failed"))
+	hooks.Disconnected(session, errors.New("failed"))
+	hooks.Denied(session, "edit-config")
 }
@@ -1,63 +1,126 @@
 package ssh
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 
 	"golang.org/x/crypto/ssh"
 )
 
-func PasswordConfig(uname, password string) (*ssh.ServerConfig, error) {
+// AuthCallback is called to authenticate a client that does not present the credentials defined by
+// PasswordConfig. key is nil when the client uses password authentication, and password is nil when it uses public
+// key authentication. A nil error accepts the client, with the permissions (which may be nil) made available to the
+// connection handler by ssh.ServerConn.Permissions.
+type AuthCallback func(username string, password []byte, key ssh.PublicKey) (*ssh.Permissions, error)
+
+// ConfigOption implements options for configuring server authentication.
+type ConfigOption func(*authConfig)
+
+type authConfig struct {
+	callback     AuthCallback
+	hostKeys     []ssh.Signer
+	hostKeyFile  string
+	keyAlgorithm KeyAlgorithm
+	algorithms   *ssh.Config
+}
+
+// WithAuthCallback defines a callback used to authenticate clients that do not present the credentials defined by
+// PasswordConfig, enabling public key authentication in addition to password authentication.
+func WithAuthCallback(cb AuthCallback) ConfigOption {
+	return func(c *authConfig) {
+		c.callback = cb
+	}
+}
+
+// WithHostKey defines the host keys presented by the server, in place of a generated key.
+func WithHostKey(keys ...ssh.Signer) ConfigOption {
+	return func(c *authConfig) {
+		c.hostKeys = append(c.hostKeys, keys...)
+	}
+}
+
+// WithHostKeyFile defines a file from which the host key is loaded, as described by LoadHostKey, so that strict
+// host key checking by clients survives a restart of the server.
+func WithHostKeyFile(path string) ConfigOption {
+	return func(c *authConfig) {
+		c.hostKeyFile = path
+	}
+}
+
+// WithHostKeyAlgorithm defines the algorithm of the host key generated by the server, or written to the file
+// defined by WithHostKeyFile. Default is RSA.
+func WithHostKeyAlgorithm(alg KeyAlgorithm) ConfigOption {
+	return func(c *authConfig) {
+		c.keyAlgorithm = alg
+	}
+}
+
+// WithAlgorithms defines the key exchange, cipher and MAC algorithms accepted by the server.
+// Default is the golang.org/x/crypto/ssh default set.
+func WithAlgorithms(cfg ssh.Config) ConfigOption {
+	return func(c *authConfig) {
+		c.algorithms = &cfg
+	}
+}
+
+// PasswordConfig delivers a server configuration that accepts clients presenting the username and password, and
+// any others accepted by the options.
+func PasswordConfig(uname, password string, opts ...ConfigOption) (*ssh.ServerConfig, error) {
+	ac := &authConfig{}
+	for _, opt := range opts {
+		opt(ac)
+	}
+
 	config := &ssh.ServerConfig{
 		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
-			return checkCredentials(uname, password, c, pass)
+			perms, err := checkCredentials(uname, password, c, pass)
+			if err != nil && ac.callback != nil {
+				return ac.callback(c.User(), pass, nil)
+			}
+			return perms, err
 		},
 	}
+	if ac.callback != nil {
+		config.PublicKeyCallback = func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return ac.callback(c.User(), nil, key)
+		}
+	}
 
-	hostKey, err := generateHostKey()
+	if ac.algorithms != nil {
+		config.Config = *ac.algorithms
+	}
+
+	hostKeys, err := ac.resolveHostKeys()
 	if err != nil {
 		return nil, err
 	}
-	config.AddHostKey(hostKey)
+	for _, key := range hostKeys {
+		config.AddHostKey(key)
+	}
 	return config, nil
 }
 
-func checkCredentials(uname, password string, c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
-	if c.User() == uname && string(pass) == password {
-		return nil, nil
+// Delivers the host keys defined by the options, generating a key if none are defined.
+func (c *authConfig) resolveHostKeys() ([]ssh.Signer, error) {
+	if len(c.hostKeys) > 0 {
+		return c.hostKeys, nil
 	}
-	return nil, fmt.Errorf("password rejected for %q", c.User())
-}
 
-func generateHostKey() (hostkey ssh.Signer, err error) {
-	reader := rand.Reader
-	bitSize := 2048
-	var key *rsa.PrivateKey
-	if key, err = rsa.GenerateKey(reader, bitSize); err == nil {
-		privateBytes := encodePrivateKeyToPEM(key)
-		if hostkey, err = ssh.ParsePrivateKey(privateBytes); err == nil {
-			return
-		}
+	var key ssh.Signer
+	var err error
+	if c.hostKeyFile != "" {
+		key, err = LoadHostKey(c.hostKeyFile, c.keyAlgorithm)
+	} else {
+		key, err = GenerateHostKey(c.keyAlgorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host key: %w", err)
 	}
-	return
+	return []ssh.Signer{key}, nil
 }
 
-func encodePrivateKeyToPEM(privateKey *rsa.PrivateKey) []byte {
-	// Get ASN.1 DER format
-	privDER := x509.MarshalPKCS1PrivateKey(privateKey)
-
-	// pem.Block
-	privBlock := pem.Block{
-		Type:    "RSA PRIVATE KEY",
-		Headers: nil,
-		Bytes:   privDER,
+func checkCredentials(uname, password string, c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+	if c.User() == uname && string(pass) == password {
+		return nil, nil
 	}
-
-	// Private key in PEM format
-	privatePEM := pem.EncodeToMemory(&privBlock)
-
-	return privatePEM
+	return nil, fmt.Errorf("password rejected for %q", c.User())
 }
@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyAlgorithm identifies the algorithm of a generated host key.
+type KeyAlgorithm string
+
+// Supported host key algorithms.
+const (
+	RSA     KeyAlgorithm = "rsa"
+	ECDSA   KeyAlgorithm = "ecdsa"
+	ED25519 KeyAlgorithm = "ed25519"
+)
+
+const rsaKeyBits = 2048
+
+// GenerateHostKey delivers a new host key of the algorithm; RSA keys are 2048 bits, and ECDSA keys use the P-256
+// curve.
+func GenerateHostKey(alg KeyAlgorithm) (ssh.Signer, error) {
+	key, err := generatePrivateKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(key)
+}
+
+// LoadHostKey delivers the host key held in PEM format by the file at path. If the file does not exist, a new key of
+// the algorithm is generated and written to the file, so that the server presents the same host key each time it
+// starts.
+func LoadHostKey(path string, alg KeyAlgorithm) (ssh.Signer, error) {
+	b, err := os.ReadFile(path)
+	if err == nil {
+		return ssh.ParsePrivateKey(b)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := generatePrivateKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	const ownerReadWrite = 0o600
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), ownerReadWrite); err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(key)
+}
+
+// Fingerprint delivers the SHA256 fingerprint of the host key, in the form displayed by OpenSSH, for example
+// "SHA256:6dOp1lQ4vS2mGu1mZRZ7K7t2xR1Wb4wDvTkgRszc3yA".
+func Fingerprint(key ssh.PublicKey) string {
+	return ssh.FingerprintSHA256(key)
+}
+
+func generatePrivateKey(alg KeyAlgorithm) (crypto.Signer, error) {
+	switch alg {
+	case RSA, "":
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case ECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	return nil, fmt.Errorf("unsupported host key algorithm %q", alg)
+}
@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+
+	xssh "golang.org/x/crypto/ssh"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Connects to the server, verifying that it presents the host key.
+func connectWithHostKey(server *Server, key xssh.PublicKey, algorithms xssh.Config) error {
+	sshConfig := &xssh.ClientConfig{
+		Config:          algorithms,
+		User:            TestUserName,
+		Auth:            []xssh.AuthMethod{xssh.Password(TestPassword)},
+		HostKeyCallback: xssh.FixedHostKey(key),
+	}
+	target := fmt.Sprintf("localhost:%d", server.Port())
+	tr, err := client.NewSSHTransport(context.Background(), client.NewDialer(target, sshConfig), target)
+	if err == nil {
+		tr.Close()
+	}
+	return err
+}
+
+func newHostKeyServer(t *testing.T, opts ...ConfigOption) *Server {
+	sshcfg, err := PasswordConfig(TestUserName, TestPassword, opts...)
+	assert.NoError(t, err)
+	server, err := NewServer(context.Background(), "localhost", 0, sshcfg, handlerFactory())
+	assert.NoError(t, err)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGenerateHostKey(t *testing.T) {
+	for alg, keyType := range map[KeyAlgorithm]string{
+		RSA:     xssh.KeyAlgoRSA,
+		ECDSA:   xssh.KeyAlgoECDSA256,
+		ED25519: xssh.KeyAlgoED25519,
+	} {
+		key, err := GenerateHostKey(alg)
+		assert.NoError(t, err)
+		assert.Equal(t, keyType, key.PublicKey().Type())
+
+		server := newHostKeyServer(t, WithHostKey(key))
+		assert.NoError(t, connectWithHostKey(server, key.PublicKey(), xssh.Config{}), "Expecting %s host key", alg)
+	}
+
+	_, err := GenerateHostKey("dsa")
+	assert.EqualError(t, err, `unsupported host key algorithm "dsa"`)
+	_, err = PasswordConfig(TestUserName, TestPassword, WithHostKeyAlgorithm("dsa"))
+	assert.EqualError(t, err, `failed to create host key: unsupported host key algorithm "dsa"`)
+}
+
+func TestHostKeyFilePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "host_key")
+
+	first, err := LoadHostKey(path, ED25519)
+	assert.NoError(t, err)
+	info, err := os.Stat(path)
+	assert.NoError(t, err, "Expecting host key to be written")
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	second, err := LoadHostKey(path, RSA)
+	assert.NoError(t, err)
+	assert.Equal(t, Fingerprint(first.PublicKey()), Fingerprint(second.PublicKey()), "Expecting persisted key to be loaded")
+	assert.Contains(t, Fingerprint(first.PublicKey()), "SHA256:")
+
+	// Successive servers present the same host key.
+	for i := 0; i < 2; i++ {
+		server := newHostKeyServer(t, WithHostKeyFile(path))
+		assert.NoError(t, connectWithHostKey(server, first.PublicKey(), xssh.Config{}))
+	}
+
+	assert.NoError(t, os.WriteFile(path, []byte("not a key"), 0o600))
+	_, err = PasswordConfig(TestUserName, TestPassword, WithHostKeyFile(path))
+	assert.Error(t, err, "Expecting invalid host key file to be rejected")
+}
+
+func TestServerAlgorithms(t *testing.T) {
+	key, err := GenerateHostKey(ECDSA)
+	assert.NoError(t, err)
+	server := newHostKeyServer(t, WithHostKey(key), WithAlgorithms(xssh.Config{Ciphers: []string{"aes256-ctr"}}))
+
+	assert.NoError(t, connectWithHostKey(server, key.PublicKey(), xssh.Config{Ciphers: []string{"aes256-ctr"}}))
+	err = connectWithHostKey(server, key.PublicKey(), xssh.Config{Ciphers: []string{"aes128-ctr"}})
+	assert.Error(t, err, "Expecting cipher negotiation to fail")
+}
@@ -2,7 +2,10 @@
 package ssh
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"fmt"
 	"testing"
 
@@ -158,3 +161,38 @@ func TestServerNoOpTraceHooks(t *testing.T) {
 	_, _ = tr.Read(buffer)
 	assert.Equal(t, ">hello<", string(buffer))
 }
+
+func TestServerAuthCallback(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := xssh.NewSignerFromKey(private)
+	assert.NoError(t, err)
+
+	sshcfg, err := PasswordConfig(TestUserName, TestPassword,
+		WithAuthCallback(func(username string, password []byte, key xssh.PublicKey) (*xssh.Permissions, error) {
+			if username == "keyUser" && key != nil && bytes.Equal(key.Marshal(), signer.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("rejected %s", username)
+		}))
+	assert.NoError(t, err)
+
+	server, err := NewServer(context.Background(), "localhost", 0, sshcfg, handlerFactory())
+	assert.NoError(t, err)
+	defer server.Close()
+
+	target := fmt.Sprintf("localhost:%d", server.Port())
+	connect := func(user string, auth xssh.AuthMethod) error {
+		sshConfig := &xssh.ClientConfig{User: user, Auth: []xssh.AuthMethod{auth}, HostKeyCallback: xssh.InsecureIgnoreHostKey()}
+		tr, err := client.NewSSHTransport(context.Background(), client.NewDialer(target, sshConfig), target)
+		if err == nil {
+			tr.Close()
+		}
+		return err
+	}
+
+	assert.NoError(t, connect("keyUser", xssh.PublicKeys(signer)), "Expecting public key to be accepted")
+	assert.NoError(t, connect(TestUserName, xssh.Password(TestPassword)), "Expecting password to be accepted")
+	assert.Error(t, connect("otherUser", xssh.PublicKeys(signer)), "Expecting unknown user to be rejected")
+	assert.Error(t, connect(TestUserName, xssh.Password("wrong")), "Expecting wrong password to be rejected")
+}
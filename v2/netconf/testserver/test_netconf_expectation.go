@@ -0,0 +1,130 @@
+package testserver
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Expectation describes an RPC request that the test server expects to receive, and the reply that it will send.
+// Expectations are defined using TestNCServer.ExpectRPC, and are matched in the order in which they are defined.
+type Expectation struct {
+	name     string
+	contains []string
+	data     string
+	hasData  bool
+	errors   []common.RPCError
+}
+
+// WithBodyContaining qualifies the expectation, so that it only matches requests whose body contains s.
+func (e *Expectation) WithBodyContaining(s string) *Expectation {
+	e.contains = append(e.contains, s)
+	return e
+}
+
+// Reply defines the content of the data element of the reply sent when the expectation is matched.
+// By default, the reply is that of the SmartRequesttHandler.
+func (e *Expectation) Reply(data string) *Expectation {
+	e.data, e.hasData = data, true
+	return e
+}
+
+// ReplyError defines the errors that will be returned when the expectation is matched.
+func (e *Expectation) ReplyError(errs ...common.RPCError) *Expectation {
+	e.errors = errs
+	return e
+}
+
+func (e *Expectation) matches(req *RPCRequest) bool {
+	if req.XMLName.Local != e.name {
+		return false
+	}
+	for _, s := range e.contains {
+		if !strings.Contains(req.Body, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) String() string {
+	if len(e.contains) == 0 {
+		return e.name
+	}
+	return fmt.Sprintf("%s containing %q", e.name, e.contains)
+}
+
+// expectations holds the expectations defined for a server, shared by all of its sessions.
+type expectations struct {
+	mu         sync.Mutex
+	list       []*Expectation
+	next       int
+	unexpected []RPCRequest
+}
+
+// Delivers the next expectation if it is matched by the request, otherwise records the request as unexpected.
+func (x *expectations) match(req *RPCRequest) *Expectation {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if len(x.list) == 0 {
+		return nil
+	}
+	if x.next < len(x.list) && x.list[x.next].matches(req) {
+		x.next++
+		return x.list[x.next-1]
+	}
+	x.unexpected = append(x.unexpected, *req)
+	return nil
+}
+
+// ExpectRPC defines an expectation that the server will receive an RPC request whose body element has the name,
+// for example "edit-config". Expectations are matched in the order in which they are defined, across all sessions,
+// and take precedence over any request handlers.
+// Requests received once expectations are defined that do not match the next expectation are recorded as
+// unexpected, and handled by the request handlers.
+func (ncs *TestNCServer) ExpectRPC(name string) *Expectation {
+	ncs.expect.mu.Lock()
+	defer ncs.expect.mu.Unlock()
+
+	e := &Expectation{name: name}
+	ncs.expect.list = append(ncs.expect.list, e)
+	return e
+}
+
+// VerifyAll asserts that every expectation has been matched, in order, and that no unexpected requests have been
+// received.
+func (ncs *TestNCServer) VerifyAll(t assert.TestingT) {
+	ncs.expect.mu.Lock()
+	defer ncs.expect.mu.Unlock()
+
+	var failures []string
+	for _, e := range ncs.expect.list[ncs.expect.next:] {
+		failures = append(failures, fmt.Sprintf("expected request not received: %s", e))
+	}
+	for _, r := range ncs.expect.unexpected {
+		failures = append(failures, fmt.Sprintf("unexpected request received: <%s>%s</%s>", r.XMLName.Local, r.Body, r.XMLName.Local))
+	}
+	if len(failures) > 0 {
+		t.Errorf("%s", strings.Join(failures, "\n"))
+		t.FailNow()
+	}
+}
+
+// Sends the reply defined by the expectation.
+func (h *SessionHandler) replyTo(e *Expectation, req *rpcRequestMessage) {
+	reply := &RPCReplyMessage{MessageID: req.MessageID, Errors: e.errors}
+	if len(e.errors) == 0 {
+		data := e.data
+		if !e.hasData {
+			data = responseFor(req)
+		}
+		reply.Data = replyData{Data: data}
+	}
+	err := h.encode(reply)
+	assert.NoError(h.t, err, "Failed to encode response")
+}
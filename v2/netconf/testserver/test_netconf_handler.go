@@ -3,12 +3,15 @@ package testserver
 
 import (
 	"encoding/xml"
+	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/damianoneill/net/v2/netconf/common"
 
 	"github.com/damianoneill/net/v2/netconf/common/codec"
+	"github.com/damianoneill/net/v2/netconf/common/filter"
 
 	assert "github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
@@ -19,8 +22,10 @@ type SessionHandler struct {
 	// t is the testing context used for handling unexpected errors.
 	t assert.TestingT
 
-	// ch is the underlying transport connection.
-	ch ssh.Channel
+	// ch is the underlying transport connection, guarded by chLock as the server may be closed while the
+	// session is being established.
+	ch     ssh.Channel
+	chLock sync.Mutex
 
 	// The codecs used to handle client i/o
 	enc *codec.Encoder
@@ -33,9 +38,15 @@ type SessionHandler struct {
 	capabilities []string
 	// The session id to be reported to the client.
 	sid uint64
+	// See WithHelloVariation.
+	helloVariation HelloVariation
+	// The session log being replayed, nil if not replaying; see WithSessionLog.
+	replay *sessionReplay
 
 	// Channel used to signal successful receipt of client capabilities.
 	hellochan chan bool
+	// Delivers the outcome of sending the server hello; see HelloError.
+	helloSent chan error
 
 	// The HelloMessage sent by the connecting client.
 	ClientHello *common.HelloMessage
@@ -48,6 +59,9 @@ type SessionHandler struct {
 	// If the queue is empty, a request is processed by the EchoRequestHandler
 	reqHandlers []RequestHandler
 
+	// The expectations defined for the server, which take precedence over the request handlers.
+	expect *expectations
+
 	// Records executed requests.
 	reqMutex sync.Mutex
 	Reqs     []RPCRequest
@@ -136,9 +150,9 @@ var SmartRequesttHandler = func(h *SessionHandler, req *rpcRequestMessage) {
 func responseFor(req *rpcRequestMessage) string {
 	switch req.Request.XMLName.Local {
 	case "get":
-		return `<top><sub attr="avalue"><child1>cvalue</child1><child2/></sub></top>`
+		return applyFilter(req, `<top><sub attr="avalue"><child1>cvalue</child1><child2/></sub></top>`)
 	case "get-config":
-		return `<top><sub attr="cfgval1"><child1>cfgval2</child1></sub></top>`
+		return applyFilter(req, `<top><sub attr="cfgval1"><child1>cfgval2</child1></sub></top>`)
 	case "edit-config":
 		return `<ok/>`
 	case "get-schema":
@@ -154,6 +168,29 @@ func responseFor(req *rpcRequestMessage) string {
 	}
 }
 
+// requestFilter represents the filter element of a get/get-config request.
+type requestFilter struct {
+	Filter *struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",innerxml"`
+	} `xml:"filter"`
+}
+
+// Applies the subtree filter defined by the request, if any, to the data.
+// Other filter types are not supported, so the data is returned unfiltered.
+func applyFilter(req *rpcRequestMessage, data string) string {
+	rf := &requestFilter{}
+	err := xml.Unmarshal([]byte("<request>"+req.Request.Body+"</request>"), rf)
+	if err != nil || rf.Filter == nil || (rf.Filter.Type != "" && rf.Filter.Type != "subtree") {
+		return data
+	}
+	filtered, err := filter.Apply(data, rf.Filter.Body)
+	if err != nil {
+		return data
+	}
+	return filtered
+}
+
 func newSessionHandler(t assert.TestingT, sid uint64) *SessionHandler {
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
@@ -161,6 +198,7 @@ func newSessionHandler(t assert.TestingT, sid uint64) *SessionHandler {
 		t:            t,
 		sid:          sid,
 		hellochan:    make(chan bool),
+		helloSent:    make(chan error, 1),
 		startwg:      wg,
 		capabilities: common.DefaultCapabilities,
 	}
@@ -168,16 +206,22 @@ func newSessionHandler(t assert.TestingT, sid uint64) *SessionHandler {
 
 // Handle establishes a Netconf server session on a newly-connected SSH channel.
 func (h *SessionHandler) Handle(t assert.TestingT, ch ssh.Channel) {
+	h.chLock.Lock()
 	h.ch = ch
+	h.chLock.Unlock()
 	h.dec = codec.NewDecoder(ch)
 	h.enc = codec.NewEncoder(ch)
 
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 
-	// Send server hello to client.
-	err := h.encode(&common.HelloMessage{Capabilities: h.capabilities, SessionID: h.sid})
-	assert.NoError(h.t, err, "Failed to send server hello")
+	// Send server hello to client. The client may have given up waiting for it, so a failure is reported by
+	// HelloError, rather than failing the test from this goroutine.
+	err := h.sendHello()
+	h.helloSent <- err
+	if err != nil {
+		return
+	}
 
 	go h.handleIncomingMessages(wg)
 
@@ -190,6 +234,69 @@ func (h *SessionHandler) Handle(t assert.TestingT, ch ssh.Channel) {
 	wg.Wait()
 }
 
+// Sends the server hello, applying any variation defined by WithHelloVariation.
+func (h *SessionHandler) sendHello() error {
+	if h.replay != nil && h.replay.hello != "" {
+		return h.sendLoggedHello()
+	}
+	v := h.helloVariation
+	time.Sleep(v.Delay)
+	if v.Hold != nil {
+		<-v.Hold
+	}
+	hello := &common.HelloMessage{Capabilities: h.capabilities, SessionID: h.sid}
+	if v.OmitSessionID {
+		hello.SessionID = 0
+	}
+	if v.SessionID != "" {
+		return h.sendHelloWithSessionID(hello.Capabilities, v.SessionID)
+	}
+	if !v.MalformedCapabilities {
+		return h.encode(hello)
+	}
+
+	h.encLock.Lock()
+	defer h.encLock.Unlock()
+	return h.enc.EncodeStream(func(w io.Writer) error {
+		// The capability elements are not terminated.
+		_, err := fmt.Fprintf(w, `<hello xmlns=%q><capabilities>`, common.NetconfNS)
+		for _, c := range hello.Capabilities {
+			if err == nil {
+				_, err = fmt.Fprintf(w, `<capability>%s`, c)
+			}
+		}
+		if err == nil {
+			_, err = io.WriteString(w, `</capabilities></hello>`)
+		}
+		return err
+	})
+}
+
+// Sends a server hello whose session-id element has the content id.
+func (h *SessionHandler) sendHelloWithSessionID(capabilities []string, id string) error {
+	h.encLock.Lock()
+	defer h.encLock.Unlock()
+	return h.enc.EncodeStream(func(w io.Writer) error {
+		if _, err := fmt.Fprintf(w, `<hello xmlns=%q><capabilities>`, common.NetconfNS); err != nil {
+			return err
+		}
+		for _, c := range capabilities {
+			if _, err := fmt.Fprintf(w, `<capability>%s</capability>`, c); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, `</capabilities><session-id>%s</session-id></hello>`, id)
+		return err
+	})
+}
+
+// HelloError waits until the server hello has been sent, delivering the error that prevented it being sent, if any.
+func (h *SessionHandler) HelloError() error {
+	err := <-h.helloSent
+	h.helloSent <- err
+	return err
+}
+
 // WaitStart waits until the session handler is ready.
 func (h *SessionHandler) WaitStart() {
 	h.startwg.Wait()
@@ -205,7 +312,18 @@ func (h *SessionHandler) SendNotification(body string) *SessionHandler {
 
 // Close initiates session tear-down by closing the underlying transport channel.
 func (h *SessionHandler) Close() {
+	h.closeChannel()
+}
+
+// Closes the transport channel, reporting whether the session had been connected.
+func (h *SessionHandler) closeChannel() bool {
+	h.chLock.Lock()
+	defer h.chLock.Unlock()
+	if h.ch == nil {
+		return false
+	}
 	_ = h.ch.Close()
+	return true
 }
 
 func (h *SessionHandler) waitForClientHello() {
@@ -252,9 +370,9 @@ func (h *SessionHandler) handleHello(token xml.StartElement) {
 
 	h.decodeElement(&h.ClientHello, &token)
 
-	if common.PeerSupportsChunkedFraming(h.ClientHello.Capabilities) && common.PeerSupportsChunkedFraming(h.capabilities) {
-		// Update the codec to use chunked framing from now.
-		codec.EnableChunkedFraming(h.dec, h.enc)
+	if framing := codec.NegotiateFraming(h.capabilities, h.ClientHello.Capabilities); framing != nil {
+		// Update the codec to use the negotiated framing from now.
+		codec.SetFraming(h.dec, h.enc, framing)
 	}
 
 	h.hellochan <- true
@@ -265,6 +383,16 @@ func (h *SessionHandler) handleRPC(token xml.StartElement) {
 	h.decodeElement(&request, &token)
 
 	h.reqLogger(request.Request)
+	if h.replay != nil {
+		h.replayRPC(request)
+		return
+	}
+	if h.expect != nil {
+		if e := h.expect.match(&request.Request); e != nil {
+			h.replyTo(e, request)
+			return
+		}
+	}
 	reqh := h.nextReqHandler()
 	reqh(h, request)
 }
@@ -299,11 +427,15 @@ func (h *SessionHandler) reqLogger(r RPCRequest) {
 
 // ReqCount delivers the number of requests received by the handler.
 func (h *SessionHandler) ReqCount() int {
+	h.reqMutex.Lock()
+	defer h.reqMutex.Unlock()
 	return len(h.Reqs)
 }
 
 // LastReq delivers the last request received by the handler, or nil if no requests have been received.
 func (h *SessionHandler) LastReq() *RPCRequest {
+	h.reqMutex.Lock()
+	defer h.reqMutex.Unlock()
 	count := len(h.Reqs)
 	if count > 0 {
 		return &h.Reqs[count-1]
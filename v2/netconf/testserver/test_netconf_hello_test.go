@@ -0,0 +1,67 @@
+package testserver_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func helloVariation(v testserver.HelloVariation) func(uint64) testserver.HelloVariation {
+	return func(uint64) testserver.HelloVariation { return v }
+}
+
+func TestDelayedHello(t *testing.T) {
+	hold := make(chan struct{})
+	ts := testserver.NewTestNetconfServer(t).
+		WithHelloVariation(helloVariation(testserver.HelloVariation{Hold: hold}))
+
+	cfg := *client.DefaultConfig
+	cfg.SetupTimeoutSecs = 1
+	_, err := client.NewRPCSessionWithConfig(context.Background(), sshConfig(), fmt.Sprintf("localhost:%d", ts.Port()), &cfg)
+	assert.EqualError(t, err, "failed to get hello from server")
+
+	// The hello is released once the client has given up, and the server has been closed.
+	sh := ts.LastHandler()
+	ts.Close()
+	close(hold)
+	assert.Error(t, sh.HelloError(), "Expecting the late hello not to be sent")
+}
+
+func TestHelloWithoutSessionID(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).
+		WithHelloVariation(helloVariation(testserver.HelloVariation{OmitSessionID: true}))
+	defer ts.Close()
+
+	s := newNCClientSession(t, ts)
+	defer s.Close()
+	assert.Zero(t, s.ID())
+}
+
+func TestMalformedHello(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).
+		WithHelloVariation(helloVariation(testserver.HelloVariation{MalformedCapabilities: true}))
+	defer ts.Close()
+
+	_, err := client.NewRPCSession(context.Background(), sshConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.Error(t, err)
+}
+
+func TestHelloVariationPerConnection(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).WithHelloVariation(func(sid uint64) testserver.HelloVariation {
+		return testserver.HelloVariation{OmitSessionID: sid == 1}
+	})
+	defer ts.Close()
+
+	first := newNCClientSession(t, ts)
+	assert.Zero(t, first.ID())
+	first.Close()
+
+	second := newNCClientSession(t, ts)
+	defer second.Close()
+	assert.Equal(t, uint64(2), second.ID())
+}
@@ -0,0 +1,127 @@
+package testserver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// WithSessionLog configures the server to reply to each session from a session log, recorded by a client session
+// created with client.WithSessionLog, so that an exchange captured from a device can be replayed as a deterministic
+// test.
+// The logged server hello is sent verbatim, and its capabilities are used to negotiate framing. Each request is
+// matched, by the name of its operation, against the next request in the log, and the messages that the client
+// received after sending the logged request, up to the next logged request, are sent verbatim. A request that does
+// not match the log, or that is received once the log is exhausted, is answered with an rpc-error.
+// The session log takes precedence over any expectations and request handlers.
+func (ncs *TestNCServer) WithSessionLog(entries []common.SessionLogEntry) *TestNCServer {
+	ncs.sessionLog = entries
+	return ncs
+}
+
+// Replays a session log on a single session.
+type sessionReplay struct {
+	// The logged server hello, if any.
+	hello string
+	// The remaining entries, excluding hello messages.
+	entries []common.SessionLogEntry
+}
+
+func newSessionReplay(log []common.SessionLogEntry) *sessionReplay {
+	r := &sessionReplay{}
+	for _, e := range log {
+		if rootName(e.Message) != common.NameHello.Local {
+			r.entries = append(r.entries, e)
+		} else if !e.Sent && r.hello == "" {
+			r.hello = e.Message
+		}
+	}
+	return r
+}
+
+// Delivers the received messages at the head of the log.
+func (r *sessionReplay) nextReceived() (msgs []string) {
+	for len(r.entries) > 0 && !r.entries[0].Sent {
+		msgs = append(msgs, r.entries[0].Message)
+		r.entries = r.entries[1:]
+	}
+	return
+}
+
+// Delivers the messages to be sent in response to a request, or an error if the request does not match the log.
+func (r *sessionReplay) replyTo(req *rpcRequestMessage) ([]string, error) {
+	if len(r.entries) == 0 {
+		return nil, fmt.Errorf("session log exhausted, received %s", req.Request.XMLName.Local)
+	}
+	logged := &rpcRequestMessage{}
+	if err := xml.Unmarshal([]byte(r.entries[0].Message), logged); err != nil {
+		return nil, fmt.Errorf("invalid logged request: %w", err)
+	}
+	if logged.Request.XMLName.Local != req.Request.XMLName.Local {
+		return nil, fmt.Errorf("session log expected %s, received %s", logged.Request.XMLName.Local,
+			req.Request.XMLName.Local)
+	}
+	r.entries = r.entries[1:]
+	return r.nextReceived(), nil
+}
+
+// Delivers the local name of the root element of a message.
+func rootName(msg string) string {
+	dec := xml.NewDecoder(strings.NewReader(msg))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// Sends the logged server hello, followed by any messages logged before the first request, adopting the logged
+// capabilities.
+func (h *SessionHandler) sendLoggedHello() error {
+	hello := &common.HelloMessage{}
+	if err := xml.Unmarshal([]byte(h.replay.hello), hello); err != nil {
+		return fmt.Errorf("invalid logged hello: %w", err)
+	}
+	h.capabilities = hello.Capabilities
+	return h.sendRaw(append([]string{h.replay.hello}, h.replay.nextReceived()...))
+}
+
+// Replies to a request from the session log.
+func (h *SessionHandler) replayRPC(req *rpcRequestMessage) {
+	msgs, err := h.replay.replyTo(req)
+	if err != nil {
+		reply := &RPCReplyMessage{
+			MessageID: req.MessageID,
+			Errors:    []common.RPCError{{Severity: "error", Message: err.Error()}},
+		}
+		err = h.encode(reply)
+		assert.NoError(h.t, err, "Failed to encode response")
+		return
+	}
+	assert.NoError(h.t, h.sendRaw(msgs), "Failed to send logged messages")
+}
+
+// Sends messages verbatim.
+func (h *SessionHandler) sendRaw(msgs []string) error {
+	h.encLock.Lock()
+	defer h.encLock.Unlock()
+	for _, msg := range msgs {
+		msg := msg
+		if err := h.enc.EncodeStream(func(w io.Writer) error {
+			_, err := io.WriteString(w, msg)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
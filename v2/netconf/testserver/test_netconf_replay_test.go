@@ -0,0 +1,93 @@
+package testserver_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const subscribeRequest = `<create-subscription xmlns="urn:ietf:params:xml:ns:netconf:notification:1.0"/>`
+
+// Runs a subscription and a get request against the server, delivering the notification and reply received.
+func runLoggedExchange(t *testing.T, ts *testserver.TestNCServer, ctx context.Context,
+	notify func(s client.Session)) (*common.Notification, *common.RPCReply) {
+	s, err := client.NewRPCSession(ctx, sshConfig(), fmt.Sprintf("localhost:%d", ts.Port()))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	nch := make(chan *common.Notification, 1)
+	_, err = s.Subscribe(common.Request(subscribeRequest), nch)
+	assert.NoError(t, err)
+	notify(s)
+
+	var n *common.Notification
+	select {
+	case n = <-nch:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Failed to receive notification")
+	}
+
+	reply, err := s.Execute(common.Request(`<get/>`))
+	assert.NoError(t, err)
+	return n, reply
+}
+
+func TestSessionLogReplay(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).WithRequestHandler(testserver.SmartRequesttHandler).
+		WithRequestHandler(testserver.SmartRequesttHandler)
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	log := common.NewSessionLogWriter(&buf)
+	n, reply := runLoggedExchange(t, ts, client.WithSessionLog(context.Background(), log), func(s client.Session) {
+		ts.SessionHandler(s.ID()).SendNotification(`<event xmlns="urn:test"><name>link-down</name></event>`)
+	})
+	assert.NoError(t, log.Err())
+
+	entries, err := common.ReadSessionLog(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 7, "Expecting hellos, two requests, two replies and a notification")
+
+	replay := testserver.NewTestNetconfServer(t).WithSessionLog(entries)
+	defer replay.Close()
+
+	rn, rreply := runLoggedExchange(t, replay, context.Background(), func(client.Session) {})
+	assert.Equal(t, n.XMLName, rn.XMLName)
+	assert.Equal(t, n.EventTime, rn.EventTime)
+	assert.Equal(t, n.Event, rn.Event)
+	assert.Equal(t, reply.Data, rreply.Data)
+	assert.Equal(t, reply.MessageID, rreply.MessageID)
+}
+
+func TestSessionLogReplayMismatch(t *testing.T) {
+	entries := []common.SessionLogEntry{
+		{Message: `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>` +
+			`<capability>urn:ietf:params:netconf:base:1.0</capability></capabilities><session-id>42</session-id></hello>`},
+		{Sent: true, Message: `<rpc xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><get/></rpc>`},
+		{Message: `<rpc-reply xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" message-id="1"><data><top/></data></rpc-reply>`},
+	}
+	ts := testserver.NewTestNetconfServer(t).WithSessionLog(entries)
+	defer ts.Close()
+
+	s := newNCClientSession(t, ts)
+	defer s.Close()
+	assert.Equal(t, uint64(42), s.ID())
+
+	_, err := s.Execute(common.Request(`<get-config/>`))
+	assert.ErrorContains(t, err, "session log expected get, received get-config")
+
+	reply, err := s.Execute(common.Request(`<get/>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "<data><top/></data>", reply.Data)
+
+	_, err = s.Execute(common.Request(`<get/>`))
+	assert.ErrorContains(t, err, "session log exhausted, received get")
+}
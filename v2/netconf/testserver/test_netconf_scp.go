@@ -0,0 +1,129 @@
+package testserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SCPStore is an in-memory file store that serves the sink (scp -t) and source (scp -f) modes of the scp protocol,
+// for single files, as an ExecChannelFunc, for example:
+//
+//	store := NewSCPStore()
+//	ncs := NewTestNetconfServer(t).WithExecChannelHandler(store.Handle)
+type SCPStore struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewSCPStore delivers an empty store.
+func NewSCPStore() *SCPStore {
+	return &SCPStore{files: map[string][]byte{}}
+}
+
+// Put stores the content of the file with the path.
+func (s *SCPStore) Put(path string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = content
+}
+
+// Get delivers the content of the file with the path, reporting whether it exists.
+func (s *SCPStore) Get(path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.files[path]
+	return content, ok
+}
+
+// Handle serves an scp command, as an ExecChannelFunc.
+func (s *SCPStore) Handle(command string, ch io.ReadWriter) int {
+	fields := strings.SplitN(command, " ", 3)
+	if len(fields) != 3 || fields[0] != "scp" {
+		_, _ = fmt.Fprintf(ch, "unsupported command %s\n", command)
+		return 127
+	}
+	target := unquote(fields[2])
+	r := bufio.NewReader(ch)
+	switch fields[1] {
+	case "-t":
+		return s.sink(target, r, ch)
+	case "-f":
+		return s.source(target, r, ch)
+	}
+	_, _ = fmt.Fprintf(ch, "unsupported scp mode %s\n", fields[1])
+	return 1
+}
+
+// Receives a file from the client.
+func (s *SCPStore) sink(target string, r *bufio.Reader, w io.Writer) int {
+	_, _ = w.Write([]byte{0})
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 1
+	}
+	// The header holds the mode, size and name, which may contain spaces.
+	fields := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 3)
+	size := int64(-1)
+	if len(fields) == 3 && strings.HasPrefix(fields[0], "C") {
+		if n, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			size = n
+		}
+	}
+	if size < 0 {
+		_, _ = fmt.Fprintf(w, "\x01scp: protocol error: %s\n", strings.TrimSpace(line))
+		return 1
+	}
+	_, _ = w.Write([]byte{0})
+
+	content := make([]byte, size)
+	if _, err = io.ReadFull(r, content); err != nil {
+		return 1
+	}
+	// The client confirms the transfer by sending a 0 byte, or aborts it.
+	if ack, err := r.ReadByte(); err != nil || ack != 0 {
+		return 1
+	}
+	s.Put(target, content)
+	_, _ = w.Write([]byte{0})
+	return 0
+}
+
+// Sends a file to the client.
+func (s *SCPStore) source(target string, r *bufio.Reader, w io.Writer) int {
+	if !readAck(r) {
+		return 1
+	}
+	content, ok := s.Get(target)
+	if !ok {
+		_, _ = fmt.Fprintf(w, "\x01scp: %s: No such file or directory\n", target)
+		return 1
+	}
+	_, _ = fmt.Fprintf(w, "C0644 %d %s\n", len(content), path.Base(target))
+	if !readAck(r) {
+		return 1
+	}
+	_, _ = w.Write(content)
+	_, _ = w.Write([]byte{0})
+	if !readAck(r) {
+		return 1
+	}
+	return 0
+}
+
+func readAck(r *bufio.Reader) bool {
+	ack, err := r.ReadByte()
+	return err == nil && ack == 0
+}
+
+// Removes shell single quoting from a path.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return s
+	}
+	return strings.ReplaceAll(s[1:len(s)-1], `'\''`, `'`)
+}
@@ -1,9 +1,14 @@
 package testserver
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
 	"runtime"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/damianoneill/net/v2/netconf/common"
 
@@ -21,20 +26,31 @@ const (
 // be invoked to handle netconf messages.
 type TestNCServer struct {
 	*SSHServer
+	// The handlers of the sessions established with the server, guarded by mu, as they are added as connections
+	// are served.
 	sessionHandlers map[uint64]*SessionHandler
+	mu              sync.Mutex
 	reqHandlers     []RequestHandler
 	caps            []string
+	helloVariation  func(sid uint64) HelloVariation
+	sessionLog      []common.SessionLogEntry
 	nextSid         uint64
 	tctx            assert.TestingT
+	expect          *expectations
+	// The TLS listener started by WithTLS, its configuration, and the roots that trust a generated certificate.
+	tlsListener net.Listener
+	tlsConfig   *tls.Config
+	tlsRoots    *x509.CertPool
 }
 
 // NewTestNetconfServer creates a new TestNCServer that will accept Netconf localhost connections on an ephemeral port (available
 // via Port(), with credentials defined by TestUserName and TestPassword.
 // tctx will be used for handling failures; if the supplied value is nil, a default test context will be used.
 // The behaviour of the Netconf session handler can be conifgured using the WithCapabilities and
-// WithRequestHandler methods.
+// WithRequestHandler methods; WithTLS additionally exposes a NETCONF over TLS listener.
 func NewTestNetconfServer(tctx assert.TestingT) *TestNCServer {
-	ncs := &TestNCServer{sessionHandlers: make(map[uint64]*SessionHandler), caps: common.DefaultCapabilities}
+	ncs := &TestNCServer{sessionHandlers: make(map[uint64]*SessionHandler), caps: common.DefaultCapabilities,
+		expect: &expectations{}}
 
 	if tctx == nil {
 		// Default test context to built-in implementation.
@@ -51,16 +67,34 @@ func (ncs *TestNCServer) newFactory() HandlerFactory {
 	return func(t assert.TestingT) SSHHandler {
 		sid := atomic.AddUint64(&ncs.nextSid, 1)
 		sess := newSessionHandler(ncs, sid)
+		ncs.mu.Lock()
 		ncs.sessionHandlers[sid] = sess
+		ncs.mu.Unlock()
 		sess.capabilities = ncs.caps
+		if ncs.helloVariation != nil {
+			sess.helloVariation = ncs.helloVariation(sid)
+		}
+		if ncs.sessionLog != nil {
+			sess.replay = newSessionReplay(ncs.sessionLog)
+		}
 		sess.reqHandlers = ncs.reqHandlers
+		sess.expect = ncs.expect
 		return sess
 	}
 }
 
 // LastHandler delivers the most recently instantiated session handler.
 func (ncs *TestNCServer) LastHandler() *SessionHandler {
-	return ncs.sessionHandlers[ncs.nextSid]
+	ncs.mu.Lock()
+	defer ncs.mu.Unlock()
+	return ncs.sessionHandlers[atomic.LoadUint64(&ncs.nextSid)]
+}
+
+// WithExecChannelHandler causes exec requests on the ssh connections of netconf sessions to be served by h, as
+// described by ExecChannelHandler, for example by an SCPStore.
+func (ncs *TestNCServer) WithExecChannelHandler(h ExecChannelFunc) *TestNCServer {
+	ncs.options.execChannel = h
+	return ncs
 }
 
 // WithRequestHandler adds a request handler to the netconf session.
@@ -75,15 +109,42 @@ func (ncs *TestNCServer) WithCapabilities(caps []string) *TestNCServer {
 	return ncs
 }
 
+// HelloVariation defines deviations from a well-formed, timely server hello, used to exercise the handling of
+// session setup failures by clients.
+type HelloVariation struct {
+	// The period for which the server hello is delayed.
+	Delay time.Duration
+	// Omits the session-id element from the server hello.
+	OmitSessionID bool
+	// Sends the session-id element with the content, in place of the allocated session id; for example, a
+	// non-numeric id.
+	SessionID string
+	// Sends the server hello with malformed xml, in which the capability elements are not terminated.
+	MalformedCapabilities bool
+	// Holds the server hello, after any Delay, until the channel is closed, so that tests can control its timing.
+	Hold <-chan struct{}
+}
+
+// WithHelloVariation defines a function that delivers the variation applied to the server hello of each connection,
+// identified by the session id that would be allocated to it, so that connections can vary.
+func (ncs *TestNCServer) WithHelloVariation(fn func(sid uint64) HelloVariation) *TestNCServer {
+	ncs.helloVariation = fn
+	return ncs
+}
+
 // Close closes any active transport to the test server and prevents subsequent connections.
 func (ncs *TestNCServer) Close() {
+	ncs.mu.Lock()
 	for k, v := range ncs.sessionHandlers {
-		if v.ch != nil {
-			v.Close()
+		if v != nil && v.closeChannel() {
 			ncs.sessionHandlers[k] = nil
 		}
 	}
+	ncs.mu.Unlock()
 	ncs.SSHServer.Close()
+	if ncs.tlsListener != nil {
+		_ = ncs.tlsListener.Close()
+	}
 }
 
 // Errorf provides testing.T compatibility if a test context is not provided when the test server is
@@ -100,7 +161,9 @@ func (ncs *TestNCServer) FailNow() {
 
 // SessionHandler delivers the netconf session handler associated with the specified session id.
 func (ncs *TestNCServer) SessionHandler(id uint64) *SessionHandler {
+	ncs.mu.Lock()
 	sh, ok := ncs.sessionHandlers[id]
+	ncs.mu.Unlock()
 	if !ok {
 		ncs.tctx.Errorf("Failed to get handler for session %d", id)
 		ncs.tctx.FailNow()
@@ -9,6 +9,7 @@ import (
 
 	"github.com/damianoneill/net/v2/netconf/client"
 	"github.com/damianoneill/net/v2/netconf/common"
+	sshserver "github.com/damianoneill/net/v2/netconf/server/ssh"
 	"github.com/damianoneill/net/v2/netconf/testserver"
 
 	assert "github.com/stretchr/testify/require"
@@ -94,6 +95,83 @@ func TestMultipleSessions(t *testing.T) {
 	assert.NotNil(t, reply, "Reply should be non-nil")
 }
 
+func TestSmartRequestHandlerSubtreeFilter(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t).
+		WithRequestHandler(testserver.SmartRequesttHandler).
+		WithRequestHandler(testserver.SmartRequesttHandler)
+	defer ts.Close()
+
+	ncs := newNCClientSession(t, ts)
+	defer ncs.Close()
+
+	reply, err := ncs.Execute(common.Request(`<get><filter type="subtree"><top><sub><child1/></sub></top></filter></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data><top><sub attr="avalue"><child1>cvalue</child1></sub></top></data>`, reply.Data)
+
+	reply, err = ncs.Execute(common.Request(`<get><filter type="subtree"><other/></filter></get>`))
+	assert.NoError(t, err, "Not expecting exec to fail")
+	assert.Equal(t, `<data></data>`, reply.Data)
+}
+
+func TestExpectations(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	ts.ExpectRPC("lock")
+	ts.ExpectRPC("edit-config").WithBodyContaining("<candidate/>").WithBodyContaining("<mtu>9000</mtu>").Reply("")
+	ts.ExpectRPC("commit").ReplyError(common.RPCError{Severity: "error", Tag: "operation-failed", Message: "commit failed"})
+
+	ncs := newNCClientSession(t, ts)
+	defer ncs.Close()
+
+	_, err := ncs.Execute(common.Request(`<lock><target><candidate/></target></lock>`))
+	assert.NoError(t, err, "Not expecting lock to fail")
+
+	reply, err := ncs.Execute(common.Request(`<edit-config><target><candidate/></target><config><mtu>9000</mtu></config></edit-config>`))
+	assert.NoError(t, err, "Not expecting edit-config to fail")
+	assert.Equal(t, `<data></data>`, reply.Data)
+
+	_, err = ncs.Execute(common.Request(`<commit/>`))
+	assert.EqualError(t, err, "netconf rpc [error] 'commit failed'")
+
+	ts.VerifyAll(t)
+}
+
+func TestExpectationsNotMet(t *testing.T) {
+	ts := testserver.NewTestNetconfServer(t)
+	defer ts.Close()
+
+	ts.ExpectRPC("edit-config").WithBodyContaining("<mtu>9000</mtu>")
+	ts.ExpectRPC("commit")
+
+	ncs := newNCClientSession(t, ts)
+	defer ncs.Close()
+
+	reply, err := ncs.Execute(common.Request(`<edit-config><config><mtu>1500</mtu></config></edit-config>`))
+	assert.NoError(t, err, "Not expecting unexpected request to fail")
+	assert.NotNil(t, reply, "Expecting unexpected request to be handled by request handlers")
+
+	mt := &mockT{}
+	ts.VerifyAll(mt)
+	assert.True(t, mt.failed, "Expecting verification to fail")
+	assert.Contains(t, mt.msg, `expected request not received: edit-config containing ["<mtu>9000</mtu>"]`)
+	assert.Contains(t, mt.msg, "expected request not received: commit")
+	assert.Contains(t, mt.msg, "unexpected request received: <edit-config><config><mtu>1500</mtu></config></edit-config>")
+}
+
+type mockT struct {
+	msg    string
+	failed bool
+}
+
+func (m *mockT) Errorf(format string, args ...interface{}) {
+	m.msg = fmt.Sprintf(format, args...)
+}
+
+func (m *mockT) FailNow() {
+	m.failed = true
+}
+
 func exSession(t *testing.T, s client.Session, wg *sync.WaitGroup, reqCount int) {
 	defer wg.Done()
 	defer s.Close()
@@ -153,3 +231,23 @@ func newNCClientSession(t assert.TestingT, ts *testserver.TestNCServer) client.S
 	assert.NoError(t, err, "Failed to create session")
 	return s
 }
+
+func TestSSHServerHostKey(t *testing.T) {
+	key, err := sshserver.GenerateHostKey(sshserver.ED25519)
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		ts := testserver.NewSSHServerHandler(t, testserver.TestUserName, testserver.TestPassword,
+			func(t assert.TestingT) testserver.SSHHandler { return nil }, testserver.HostKey(key))
+		assert.Equal(t, key.PublicKey(), ts.HostKey())
+
+		conn, err := ssh.Dial("tcp", fmt.Sprintf("localhost:%d", ts.Port()), &ssh.ClientConfig{
+			User:            testserver.TestUserName,
+			Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+			HostKeyCallback: ssh.FixedHostKey(key.PublicKey()),
+		})
+		assert.NoError(t, err, "Expecting host key to be accepted")
+		conn.Close()
+		ts.Close()
+	}
+}
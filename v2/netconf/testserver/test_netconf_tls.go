@@ -0,0 +1,129 @@
+package testserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// WithTLS starts a NETCONF over TLS listener, as described by RFC 7589, on an ephemeral localhost port (available via
+// TLSPort), whose sessions are handled as for SSH sessions. The listener uses config, or if nil, a configuration
+// with a generated self-signed certificate for localhost, which is trusted by the configuration delivered by
+// TLSClientConfig.
+func (ncs *TestNCServer) WithTLS(config *tls.Config) *TestNCServer {
+	if config == nil {
+		config = ncs.generateTLSConfig()
+	}
+	ncs.tlsConfig = config
+
+	listener, err := tls.Listen("tcp", "localhost:0", config)
+	assert.NoError(ncs.tctx, err, "TLS listen failed")
+	ncs.tlsListener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go ncs.serveTLS(conn.(*tls.Conn))
+		}
+	}()
+	return ncs
+}
+
+// TLSPort delivers the tcp port number on which the TLS listener started by WithTLS is listening.
+func (ncs *TestNCServer) TLSPort() int {
+	return ncs.tlsListener.Addr().(*net.TCPAddr).Port
+}
+
+// TLSClientConfig delivers a client configuration that trusts the certificate generated by WithTLS, or nil if
+// WithTLS was called with a configuration.
+func (ncs *TestNCServer) TLSClientConfig() *tls.Config {
+	if ncs.tlsRoots == nil {
+		return nil
+	}
+	return &tls.Config{RootCAs: ncs.tlsRoots, ServerName: "localhost", MinVersion: tls.VersionTLS12}
+}
+
+// CallHomeTLS initiates a NETCONF over TLS call home connection to the client listening at address, as described by
+// RFC 8071, and then acts as the TLS server on the connection, using the configuration defined by WithTLS.
+func (ncs *TestNCServer) CallHomeTLS(address string) error {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	go ncs.serveTLS(tls.Server(conn, ncs.tlsConfig))
+	return nil
+}
+
+// Serves a netconf session on the TLS connection, once the handshake has completed.
+func (ncs *TestNCServer) serveTLS(conn *tls.Conn) {
+	defer conn.Close()
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	ncs.newFactory()(ncs.tctx).Handle(ncs.tctx, &tlsChannel{Conn: conn})
+}
+
+// Generates a server configuration with a self-signed certificate for localhost.
+func (ncs *TestNCServer) generateTLSConfig() *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(ncs.tctx, err, "Failed to generate TLS key")
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(ncs.tctx, err, "Failed to generate TLS certificate")
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(ncs.tctx, err, "Failed to parse TLS certificate")
+
+	ncs.tlsRoots = x509.NewCertPool()
+	ncs.tlsRoots.AddCert(cert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// Adapts a TLS connection to the ssh.Channel interface used by session handlers.
+type tlsChannel struct {
+	*tls.Conn
+}
+
+func (c *tlsChannel) SendRequest(name string, wantReply bool, payload []byte) (bool, error) {
+	return false, nil
+}
+
+func (c *tlsChannel) Stderr() io.ReadWriter {
+	return discard{}
+}
+
+type discard struct{}
+
+func (discard) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (discard) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,124 @@
+package testserver_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/damianoneill/net/v2/netconf/client"
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/netconf/common/codec"
+	"github.com/damianoneill/net/v2/netconf/testserver"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// Exchanges hellos over the connection, using end-of-message framing, then executes a get request.
+func tlsExchange(t *testing.T, conn io.ReadWriter) *common.RPCReply {
+	enc := codec.NewEncoder(conn)
+	dec := codec.NewDecoder(conn)
+
+	assert.NoError(t, enc.Encode(&common.HelloMessage{Capabilities: common.NoChunkedCodecCapabilities}))
+	hello := &common.HelloMessage{}
+	assert.NoError(t, dec.Decode(hello))
+	assert.NotZero(t, hello.SessionID)
+
+	assert.NoError(t, enc.Encode(&common.RPCMessage{MessageID: "1", Union: common.GetUnion("<get><ping/></get>")}))
+	reply := &common.RPCReply{}
+	assert.NoError(t, dec.Decode(reply))
+	return reply
+}
+
+func TestTLSEndpoint(t *testing.T) {
+	ncs := testserver.NewTestNetconfServer(t).WithTLS(nil)
+	defer ncs.Close()
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("localhost:%d", ncs.TLSPort()), ncs.TLSClientConfig())
+	assert.NoError(t, err, "Expecting client to trust the generated certificate")
+	defer conn.Close()
+
+	reply := tlsExchange(t, conn)
+	assert.Equal(t, "1", reply.MessageID)
+	assert.Contains(t, reply.Data, "<ping/>")
+	assert.Equal(t, 1, ncs.LastHandler().ReqCount())
+}
+
+func TestTLSCallHome(t *testing.T) {
+	ncs := testserver.NewTestNetconfServer(t).WithTLS(nil)
+	defer ncs.Close()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.NoError(t, ncs.CallHomeTLS(listener.Addr().String()))
+	conn, err := listener.Accept()
+	assert.NoError(t, err)
+	tconn := tls.Client(conn, ncs.TLSClientConfig())
+	defer tconn.Close()
+
+	reply := tlsExchange(t, tconn)
+	assert.Equal(t, "1", reply.MessageID)
+}
+
+// Delivers an ssh client established over a call home connection.
+type callHomeFactory struct {
+	client *ssh.Client
+}
+
+func (f *callHomeFactory) Dial(ctx context.Context) (*ssh.Client, error) {
+	return f.client, nil
+}
+
+func (f *callHomeFactory) Close(c *ssh.Client) error {
+	return c.Close()
+}
+
+func TestSSHCallHome(t *testing.T) {
+	ncs := testserver.NewTestNetconfServer(t)
+	defer ncs.Close()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.NoError(t, ncs.CallHome(listener.Addr().String()))
+	conn, err := listener.Accept()
+	assert.NoError(t, err)
+
+	sshConfig := &ssh.ClientConfig{
+		User:            testserver.TestUserName,
+		Auth:            []ssh.AuthMethod{ssh.Password(testserver.TestPassword)},
+		HostKeyCallback: ssh.FixedHostKey(ncs.HostKey()),
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, conn.RemoteAddr().String(), sshConfig)
+	assert.NoError(t, err, "Expecting ssh handshake over the call home connection")
+
+	ctx := context.Background()
+	tr, err := client.NewSSHTransport(ctx, &callHomeFactory{client: ssh.NewClient(c, chans, reqs)}, "callhome")
+	assert.NoError(t, err)
+	s, err := client.NewSession(ctx, tr, client.DefaultConfig)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	reply, err := s.Execute(common.Request("<get><ping/></get>"))
+	assert.NoError(t, err)
+	assert.Contains(t, reply.Data, "<ping/>")
+	assert.Equal(t, 1, ncs.SessionHandler(s.ID()).ReqCount())
+}
+
+func TestCallHomeFailure(t *testing.T) {
+	ncs := testserver.NewTestNetconfServer(t)
+	defer ncs.Close()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	assert.Error(t, ncs.CallHome(addr), "Expecting call home to fail without a listening client")
+}
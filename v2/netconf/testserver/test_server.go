@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 
 	assert "github.com/stretchr/testify/require"
@@ -16,6 +17,12 @@ import (
 // SSHServer represents a test SSH Server
 type SSHServer struct {
 	listener net.Listener
+	hostKey  ssh.Signer
+	// Used to serve call home connections.
+	t       assert.TestingT
+	config  *ssh.ServerConfig
+	factory HandlerFactory
+	options *serverOptions
 }
 
 // SSHHandler is the interface that is implemented to handle an SSH channel.
@@ -44,9 +51,14 @@ func NewSSHServerHandler(t assert.TestingT, uname, password string, factory Hand
 	listener, err := net.Listen("tcp", "localhost:0")
 	assert.NoError(t, err, "Listen failed")
 
-	go acceptConnections(t, listener, newSSHServerConfig(t, uname, password), factory, serverOptions)
+	hostKey := serverOptions.hostKey
+	if hostKey == nil {
+		hostKey = generateHostKey(t)
+	}
+	config := newSSHServerConfig(uname, password, hostKey)
+	go acceptConnections(t, listener, config, factory, serverOptions)
 
-	return &SSHServer{listener: listener}
+	return &SSHServer{listener: listener, hostKey: hostKey, t: t, config: config, factory: factory, options: serverOptions}
 }
 
 // ServerOption implements options for configuring test server behaviour.
@@ -55,8 +67,19 @@ type ServerOption func(*serverOptions)
 // serverOptions defines properties controlling test server behaviour.
 type serverOptions struct {
 	requestTypes []string
+	execHandler  ExecFunc
+	execChannel  ExecChannelFunc
+	hostKey      ssh.Signer
+	observer     func(req *ssh.Request)
 }
 
+// ExecFunc serves an exec request for the command, delivering the command output and exit status.
+type ExecFunc func(command string) (output string, status int)
+
+// ExecChannelFunc serves an exec request for the command, reading the command input from, and writing its output
+// to, ch, and delivering the exit status.
+type ExecChannelFunc func(command string, ch io.ReadWriter) (status int)
+
 // RequestTypes defines the request types that will be 'accepted' - i.e. the request response will be 'ok' (true).
 // Defaults to {"subsystem"}
 func RequestTypes(types []string) ServerOption {
@@ -65,11 +88,65 @@ func RequestTypes(types []string) ServerOption {
 	}
 }
 
+// ExecHandler defines the function that serves exec requests, which are otherwise rejected.
+// When defined, the channel handler is only invoked once a shell or subsystem request has been accepted, rather
+// than as soon as a channel is opened, so that it is not invoked for the channels on which commands are executed.
+func ExecHandler(h ExecFunc) ServerOption {
+	return func(c *serverOptions) {
+		c.execHandler = h
+	}
+}
+
+// ExecChannelHandler defines the function that serves exec requests for commands that read input, such as scp, in
+// place of any ExecHandler. Channel handlers are invoked as described by ExecHandler.
+func ExecChannelHandler(h ExecChannelFunc) ServerOption {
+	return func(c *serverOptions) {
+		c.execChannel = h
+	}
+}
+
+// Reports whether exec requests are served.
+func (c *serverOptions) servesExec() bool {
+	return c.execHandler != nil || c.execChannel != nil
+}
+
+// HostKey defines the host key presented by the server, in place of a generated key, so that clients can apply
+// strict host key checking across servers.
+func HostKey(key ssh.Signer) ServerOption {
+	return func(c *serverOptions) {
+		c.hostKey = key
+	}
+}
+
+// RequestObserver defines a function that is called with each channel request received by the server, such as
+// env or pty-req requests, before it is replied to.
+func RequestObserver(f func(req *ssh.Request)) ServerOption {
+	return func(c *serverOptions) {
+		c.observer = f
+	}
+}
+
+// HostKey delivers the public host key presented by the server, for use with ssh.FixedHostKey.
+func (ts *SSHServer) HostKey() ssh.PublicKey {
+	return ts.hostKey.PublicKey()
+}
+
 // Port delivers the tcp port number on which the server is listening.
 func (ts *SSHServer) Port() int {
 	return ts.listener.Addr().(*net.TCPAddr).Port
 }
 
+// CallHome initiates an SSH call home connection to the client listening at address, as described by RFC 8071, and
+// then acts as the SSH server on the connection, as for connections accepted by the server.
+func (ts *SSHServer) CallHome(address string) error {
+	nConn, err := net.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	go serveConnection(ts.t, nConn, ts.config, ts.factory, ts.options)
+	return nil
+}
+
 // Close closes any resources used by the server.
 func (ts *SSHServer) Close() {
 	_ = ts.listener.Close()
@@ -81,43 +158,80 @@ func acceptConnections(t assert.TestingT, listener net.Listener, config *ssh.Ser
 		if err != nil {
 			return
 		}
+		serveConnection(t, nConn, config, factory, options)
+	}
+}
 
-		_, chch, reqch, err := ssh.NewServerConn(nConn, config)
-		if err != nil {
-			return
+// Serves the ssh connection until it is closed.
+func serveConnection(t assert.TestingT, nConn net.Conn, config *ssh.ServerConfig, factory HandlerFactory, options *serverOptions) {
+	_, chch, reqch, err := ssh.NewServerConn(nConn, config)
+	if err != nil {
+		return
+	}
+
+	go ssh.DiscardRequests(reqch)
+
+	// Service the incoming Channel channel.
+	for newChannel := range chch {
+		dataChan, requests, err := newChannel.Accept()
+		assert.NoError(t, err, "Failed to accept new channel")
+
+		handle := func() {
+			defer dataChan.Close()
+			factory(t).Handle(t, dataChan)
 		}
 
-		go ssh.DiscardRequests(reqch)
-
-		// Service the incoming Channel channel.
-		for newChannel := range chch {
-			dataChan, requests, err := newChannel.Accept()
-			assert.NoError(t, err, "Failed to accept new channel")
-
-			// Handle requests - subsystem, pty-req, shell etc.
-			go func(in <-chan *ssh.Request) {
-				for req := range in {
-					typeOk := false
-					for _, ty := range options.requestTypes {
-						if req.Type == ty {
-							typeOk = true
-							break
-						}
+		// Handle requests - subsystem, pty-req, shell etc.
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				if options.observer != nil {
+					options.observer(req)
+				}
+				if req.Type == "exec" && options.servesExec() {
+					_ = req.Reply(true, nil)
+					go serveExec(dataChan, req.Payload, options)
+					continue
+				}
+
+				typeOk := false
+				for _, ty := range options.requestTypes {
+					if req.Type == ty {
+						typeOk = true
+						break
 					}
+				}
 
-					_ = req.Reply(typeOk, nil)
+				_ = req.Reply(typeOk, nil)
+				if typeOk && options.servesExec() && (req.Type == "shell" || req.Type == "subsystem") {
+					go handle()
 				}
-			}(requests)
+			}
+		}(requests)
 
-			go func() {
-				defer dataChan.Close()
-				factory(t).Handle(t, dataChan)
-			}()
+		if !options.servesExec() {
+			go handle()
 		}
 	}
 }
 
-func newSSHServerConfig(t assert.TestingT, uname, password string) *ssh.ServerConfig {
+// Serves an exec request with the payload, writing the command output and exit status to the channel.
+func serveExec(ch ssh.Channel, payload []byte, options *serverOptions) {
+	defer ch.Close()
+
+	var req struct{ Command string }
+	_ = ssh.Unmarshal(payload, &req)
+	var status int
+	if options.execChannel != nil {
+		status = options.execChannel(req.Command, ch)
+	} else {
+		var output string
+		output, status = options.execHandler(req.Command)
+		_, _ = ch.Write([]byte(output))
+	}
+	_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+}
+
+func newSSHServerConfig(uname, password string, hostKey ssh.Signer) *ssh.ServerConfig {
 	config := &ssh.ServerConfig{
 		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
 			if c.User() == uname && string(pass) == password {
@@ -127,7 +241,7 @@ func newSSHServerConfig(t assert.TestingT, uname, password string) *ssh.ServerCo
 		},
 	}
 
-	config.AddHostKey(generateHostKey(t))
+	config.AddHostKey(hostKey)
 	return config
 }
 
@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"context"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+)
+
+// NotificationPayload defines the payload of a published netconf notification.
+type NotificationPayload struct {
+	// The namespace of the notification event element.
+	Namespace string `json:"namespace"`
+	// The event time reported by the server.
+	EventTime string `json:"eventTime"`
+	// The notification event element, as xml.
+	Event string `json:"event"`
+}
+
+// ForwardNotifications publishes the notifications received on nchan, which will typically be the channel
+// supplied to Session.Subscribe, until the channel is closed or ctx is done.
+// The target identifies the server from which the notifications originate.
+func ForwardNotifications(ctx context.Context, p Publisher, target string, nchan <-chan *common.Notification,
+	opts ...Option,
+) error {
+	c := newConfig(DefaultNotificationTopic, opts)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n, ok := <-nchan:
+			if !ok {
+				return nil
+			}
+			c.publish(ctx, p, notificationEnvelope(target, n))
+		}
+	}
+}
+
+func notificationEnvelope(target string, n *common.Notification) *Envelope {
+	return &Envelope{
+		Source:    "netconf",
+		Target:    target,
+		Timestamp: time.Now(),
+		Type:      n.XMLName.Local,
+		Payload:   &NotificationPayload{Namespace: n.XMLName.Space, EventTime: n.EventTime, Event: n.Event},
+	}
+}
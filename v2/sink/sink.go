@@ -0,0 +1,152 @@
+// Package sink publishes NETCONF notifications and SNMP traps to a message broker, such as Kafka or NATS, so that
+// they can be fed into telemetry collection pipelines.
+//
+// The package does not depend on any broker client library; events are delivered to a Publisher, which is readily
+// implemented with the client of choice. For example, with NATS:
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	publisher := sink.NATS(nc.Publish)
+//
+// and with Kafka, using github.com/segmentio/kafka-go:
+//
+//	w := &kafka.Writer{Addr: kafka.TCP("localhost:9092")}
+//	publisher := sink.PublisherFunc(func(ctx context.Context, topic string, key, payload []byte) error {
+//	    return w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: payload})
+//	})
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Publisher delivers an encoded event to a broker topic (Kafka) or subject (NATS).
+// The key identifies the target from which the event originated, and may be used by the broker to partition events.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, key, payload []byte) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, topic string, key, payload []byte) error
+
+// Publish calls f(ctx, topic, key, payload).
+func (f PublisherFunc) Publish(ctx context.Context, topic string, key, payload []byte) error {
+	return f(ctx, topic, key, payload)
+}
+
+// NATS adapts a NATS style publish function, such as the Publish method of a nats.Conn, to the Publisher interface.
+// The key is not used.
+func NATS(publish func(subject string, data []byte) error) Publisher {
+	return PublisherFunc(func(ctx context.Context, subject string, key, payload []byte) error {
+		return publish(subject, payload)
+	})
+}
+
+// Envelope defines the content of a published event.
+type Envelope struct {
+	// The protocol that delivered the event; either "netconf" or "snmp".
+	Source string `json:"source"`
+	// The target from which the event originated; the netconf server address, or snmp agent address.
+	Target string `json:"target"`
+	// The time at which the event was received.
+	Timestamp time.Time `json:"timestamp"`
+	// Identifies the event; the notification element name, or the trap OID.
+	Type string `json:"type"`
+	// The content of the event; either a *NotificationPayload or a *TrapPayload.
+	Payload interface{} `json:"payload"`
+}
+
+// Serializer encodes an envelope for publication.
+type Serializer func(e *Envelope) ([]byte, error)
+
+// JSONSerializer encodes the envelope as a JSON object.
+func JSONSerializer(e *Envelope) ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// ErrorHandler is called when an event cannot be encoded or published.
+type ErrorHandler func(e *Envelope, err error)
+
+// Default configuration of a sink.
+const (
+	DefaultNotificationTopic = "netconf.notifications"
+	DefaultTrapTopic         = "snmp.traps"
+	defaultPublishTimeout    = 10 * time.Second
+)
+
+// Option implements options for configuring sink behaviour.
+type Option func(*config)
+
+type config struct {
+	topic          string
+	serializer     Serializer
+	onError        ErrorHandler
+	publishTimeout time.Duration
+}
+
+// Topic defines the topic, or subject, to which events are published.
+// Default is DefaultNotificationTopic for notifications, and DefaultTrapTopic for traps.
+func Topic(topic string) Option {
+	return func(c *config) {
+		c.topic = topic
+	}
+}
+
+// WithSerializer defines the encoding of published events.
+// Default is JSONSerializer.
+func WithSerializer(s Serializer) Option {
+	return func(c *config) {
+		c.serializer = s
+	}
+}
+
+// OnError defines the handler called when an event cannot be encoded or published; the event is dropped.
+// Default is to log the error.
+func OnError(h ErrorHandler) Option {
+	return func(c *config) {
+		c.onError = h
+	}
+}
+
+// PublishTimeout defines the period allowed for an event to be published. Zero means no timeout.
+// Default is 10 seconds.
+func PublishTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.publishTimeout = timeout
+	}
+}
+
+func newConfig(topic string, opts []Option) *config {
+	c := &config{
+		topic:          topic,
+		serializer:     JSONSerializer,
+		onError:        logError,
+		publishTimeout: defaultPublishTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func logError(e *Envelope, err error) {
+	log.Printf("SINK-Error source:%s target:%s type:%s err:%v\n", e.Source, e.Target, e.Type, err)
+}
+
+// Encodes and publishes the envelope, reporting any failure to the error handler.
+func (c *config) publish(ctx context.Context, p Publisher, e *Envelope) {
+	payload, err := c.serializer(e)
+	if err == nil {
+		if c.publishTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.publishTimeout)
+			defer cancel()
+		}
+		err = p.Publish(ctx, c.topic, []byte(e.Target), payload)
+	}
+	if err != nil {
+		c.onError(e, err)
+	}
+}
@@ -0,0 +1,174 @@
+package sink
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/netconf/common"
+	"github.com/damianoneill/net/v2/snmp"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type published struct {
+	topic   string
+	key     string
+	payload map[string]interface{}
+}
+
+type testPublisher struct {
+	mu     sync.Mutex
+	events []published
+	err    error
+}
+
+func (p *testPublisher) Publish(ctx context.Context, topic string, key, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	e := published{topic: topic, key: string(key)}
+	if err := json.Unmarshal(payload, &e.payload); err != nil {
+		return err
+	}
+	p.events = append(p.events, e)
+	return nil
+}
+
+func TestForwardNotifications(t *testing.T) {
+	p := &testPublisher{}
+	nchan := make(chan *common.Notification, 2)
+	nchan <- &common.Notification{
+		XMLName:   xml.Name{Space: "urn:test", Local: "event1"},
+		EventTime: "2020-01-01T00:00:00Z",
+		Event:     `<event1 xmlns="urn:test"><a>1</a></event1>`,
+	}
+	nchan <- &common.Notification{XMLName: xml.Name{Local: "event2"}}
+	close(nchan)
+
+	err := ForwardNotifications(context.Background(), p, "host:830", nchan, Topic("notifications"))
+	assert.NoError(t, err)
+	assert.Len(t, p.events, 2)
+
+	e := p.events[0]
+	assert.Equal(t, "notifications", e.topic)
+	assert.Equal(t, "host:830", e.key)
+	assert.Equal(t, "netconf", e.payload["source"])
+	assert.Equal(t, "host:830", e.payload["target"])
+	assert.Equal(t, "event1", e.payload["type"])
+	assert.NotEmpty(t, e.payload["timestamp"])
+	assert.Equal(t, map[string]interface{}{
+		"namespace": "urn:test",
+		"eventTime": "2020-01-01T00:00:00Z",
+		"event":     `<event1 xmlns="urn:test"><a>1</a></event1>`,
+	}, e.payload["payload"])
+	assert.Equal(t, "event2", p.events[1].payload["type"])
+}
+
+func TestForwardNotificationsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ForwardNotifications(ctx, &testPublisher{}, "host:830", make(chan *common.Notification))
+	}()
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestPublishFailure(t *testing.T) {
+	p := &testPublisher{err: errors.New("broker unavailable")}
+	var failed []*Envelope
+	s := NewTrapSink(p, OnError(func(e *Envelope, err error) {
+		assert.EqualError(t, err, "broker unavailable")
+		failed = append(failed, e)
+	}))
+
+	s.NewTrap(&snmp.TrapData{SnmpTrapOID: asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 1}})
+	assert.Len(t, failed, 1)
+	assert.Equal(t, ".1.3.6.1.6.3.1.1.5.1", failed[0].Type)
+}
+
+func TestTrapSink(t *testing.T) {
+	p := &testPublisher{}
+	s := NewTrapSink(p)
+
+	s.NewTrap(&snmp.TrapData{
+		Version:       snmp.SNMPV1,
+		Community:     "public",
+		SourceAddress: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 162},
+		SysUpTime:     1234,
+		SnmpTrapOID:   asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 3},
+		Enterprise:    asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 9},
+		AgentAddress:  net.IPv4(10, 0, 0, 2),
+		Varbinds: []snmp.Varbind{
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 1, 1}, TypedValue: &snmp.TypedValue{Type: snmp.Integer, Value: int64(1)}},
+		},
+	})
+
+	assert.Len(t, p.events, 1)
+	e := p.events[0]
+	assert.Equal(t, DefaultTrapTopic, e.topic)
+	assert.Equal(t, "10.0.0.1:162", e.key)
+	assert.Equal(t, "snmp", e.payload["source"])
+	assert.Equal(t, ".1.3.6.1.6.3.1.1.5.3", e.payload["type"])
+	assert.Equal(t, map[string]interface{}{
+		"version":      "v1",
+		"community":    "public",
+		"isInform":     false,
+		"sysUpTime":    float64(1234),
+		"snmpTrapOID":  ".1.3.6.1.6.3.1.1.5.3",
+		"enterprise":   ".1.3.6.1.4.1.9",
+		"agentAddress": "10.0.0.2",
+		"varbinds": []interface{}{
+			map[string]interface{}{"oid": ".1.3.6.1.2.1.2.2.1.1.1", "value": "INTEGER: 1"},
+		},
+	}, e.payload["payload"])
+}
+
+func TestNATS(t *testing.T) {
+	var subject string
+	var data []byte
+	p := NATS(func(s string, d []byte) error {
+		subject, data = s, d
+		return nil
+	})
+
+	err := p.Publish(context.Background(), "traps", []byte("key"), []byte("payload"))
+	assert.NoError(t, err)
+	assert.Equal(t, "traps", subject)
+	assert.Equal(t, []byte("payload"), data)
+}
+
+func TestCustomSerializer(t *testing.T) {
+	var payload []byte
+	p := PublisherFunc(func(ctx context.Context, topic string, key, b []byte) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok, "Expecting no publish timeout")
+		payload = b
+		return nil
+	})
+	s := NewTrapSink(p, PublishTimeout(0), WithSerializer(func(e *Envelope) ([]byte, error) {
+		return []byte(e.Source + ":" + e.Type), nil
+	}))
+
+	s.NewTrap(&snmp.TrapData{SnmpTrapOID: asn1.ObjectIdentifier{1, 3}, Varbinds: []snmp.Varbind{}})
+	assert.Equal(t, "snmp:.1.3", string(payload))
+}
+
+func TestPublishTimeout(t *testing.T) {
+	p := PublisherFunc(func(ctx context.Context, topic string, key, b []byte) error {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok, "Expecting publish timeout")
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		return nil
+	})
+	NewTrapSink(p, PublishTimeout(time.Minute)).NewTrap(&snmp.TrapData{})
+}
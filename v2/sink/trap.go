@@ -0,0 +1,91 @@
+package sink
+
+import (
+	"context"
+	"encoding/asn1"
+	"net"
+	"time"
+
+	"github.com/damianoneill/net/v2/snmp"
+)
+
+// TrapPayload defines the payload of a published snmp trap or inform.
+type TrapPayload struct {
+	// The SNMP version of the message; "v1" or "v2c".
+	Version   string `json:"version"`
+	Community string `json:"community"`
+	IsInform  bool   `json:"isInform"`
+	// The value of sysUpTime.0, in hundredths of a second.
+	SysUpTime    uint32 `json:"sysUpTime"`
+	SnmpTrapOID  string `json:"snmpTrapOID"`
+	Enterprise   string `json:"enterprise,omitempty"`
+	AgentAddress string `json:"agentAddress,omitempty"`
+	// The remaining variable bindings.
+	Varbinds []VarbindPayload `json:"varbinds"`
+}
+
+// VarbindPayload defines a variable binding of a published trap.
+type VarbindPayload struct {
+	OID string `json:"oid"`
+	// The value, formatted as by snmp.Format, for example "INTEGER: 5".
+	Value string `json:"value"`
+}
+
+// TrapSink is an snmp server Handler that publishes the traps and informs received by the server.
+// As the server does not process further messages whilst the handler is publishing, a Publisher that
+// buffers events is preferred.
+type TrapSink struct {
+	p Publisher
+	c *config
+}
+
+// NewTrapSink creates a handler that publishes traps with p.
+func NewTrapSink(p Publisher, opts ...Option) *TrapSink {
+	return &TrapSink{p: p, c: newConfig(DefaultTrapTopic, opts)}
+}
+
+// NewMessage implements snmp.Handler; it is superseded by NewTrap, which the server invokes in its place.
+func (s *TrapSink) NewMessage(pdu *snmp.PDU, isInform bool, sourceAddr net.Addr) {
+	s.NewTrap(snmp.NewTrapData(pdu, isInform, sourceAddr))
+}
+
+// NewTrap implements snmp.TrapHandler.
+func (s *TrapSink) NewTrap(trap *snmp.TrapData) {
+	s.c.publish(context.Background(), s.p, trapEnvelope(trap))
+}
+
+func trapEnvelope(trap *snmp.TrapData) *Envelope {
+	payload := &TrapPayload{
+		Version:     "v2c",
+		Community:   trap.Community,
+		IsInform:    trap.IsInform,
+		SysUpTime:   trap.SysUpTime,
+		SnmpTrapOID: oidString(trap.SnmpTrapOID),
+		Enterprise:  oidString(trap.Enterprise),
+		Varbinds:    make([]VarbindPayload, len(trap.Varbinds)),
+	}
+	if trap.Version == snmp.SNMPV1 {
+		payload.Version = "v1"
+	}
+	if trap.AgentAddress != nil {
+		payload.AgentAddress = trap.AgentAddress.String()
+	}
+	for i := range trap.Varbinds {
+		vb := &trap.Varbinds[i]
+		payload.Varbinds[i] = VarbindPayload{OID: oidString(vb.OID), Value: snmp.Format(vb.TypedValue)}
+	}
+
+	e := &Envelope{Source: "snmp", Timestamp: time.Now(), Type: payload.SnmpTrapOID, Payload: payload}
+	if trap.SourceAddress != nil {
+		e.Target = trap.SourceAddress.String()
+	}
+	return e
+}
+
+// Delivers the OID in dotted form with a leading period, as output by snmp.FormatVarbind, or empty if undefined.
+func oidString(oid asn1.ObjectIdentifier) string {
+	if len(oid) == 0 {
+		return ""
+	}
+	return "." + oid.String()
+}
@@ -0,0 +1,614 @@
+package snmp
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"net"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/geoffgarside/ber"
+)
+
+// Agent-side handling of Get, GetNext, GetBulk and Set requests, allowing the server to act as a minimal SNMP agent.
+// Variables are served by providers registered against scalar objects and conceptual table entries; requests for
+// any other oid are answered with the appropriate exception. Set requests are accepted only for scalar objects
+// registered with an updater.
+// Providers are invoked on the goroutine that receives messages, so should return in a timely fashion.
+
+// VariableProvider delivers the value of the variable instance identified by oid.
+// A nil value (with nil error) indicates that no such instance exists; an error is reported to the manager as a
+// genErr.
+type VariableProvider func(oid asn1.ObjectIdentifier) (*TypedValue, error)
+
+// VariableUpdater assigns the value of the variable instance identified by oid, in response to a Set request.
+// An error is reported to the manager as a commitFailed.
+type VariableUpdater func(oid asn1.ObjectIdentifier, value *TypedValue) error
+
+// TableIndexer delivers the index part of the oid of each row currently present in a table, in any order.
+type TableIndexer func() []asn1.ObjectIdentifier
+
+// ServeScalar registers a provider for the scalar object oid, whose single instance is oid.0.
+func ServeScalar(oid string, provider VariableProvider) ServerOption {
+	return func(c *serverConfig) {
+		c.registerObject(oid, &agentObject{provider: provider})
+	}
+}
+
+// ServeWritableScalar registers a provider for the scalar object oid, as ServeScalar, and an updater that assigns
+// the value of its instance in response to Set requests.
+func ServeWritableScalar(oid string, provider VariableProvider, updater VariableUpdater) ServerOption {
+	return func(c *serverConfig) {
+		c.registerObject(oid, &agentObject{provider: provider, updater: updater})
+	}
+}
+
+// ServeTable registers a provider for the columns of the conceptual table entry oid, for example ifEntry
+// 1.3.6.1.2.1.2.2.1. Instances are identified as entry.column.index, with indexer delivering the indexes of the rows
+// that are traversed by GetNext and GetBulk requests.
+func ServeTable(entry string, columns []int, indexer TableIndexer, provider VariableProvider) ServerOption {
+	return func(c *serverConfig) {
+		cols := append([]int{}, columns...)
+		sort.Ints(cols)
+		c.registerObject(entry, &agentObject{provider: provider, columns: cols, indexer: indexer})
+	}
+}
+
+// AgentMaxMessageSize defines the maximum size in bytes of the responses sent by the agent, which must be between
+// 484, the minimum that managers are required to accept, and 65507, the maximum udp payload. A GetBulk response
+// that would exceed the size is truncated to fewer variable bindings, and any other response is replaced by a tooBig
+// error, as described at https://tools.ietf.org/html/rfc3416#section-4.2.
+// Default value is 65507.
+func AgentMaxMessageSize(size int) ServerOption {
+	return func(c *serverConfig) {
+		if size < minMessageSize || size > maxMessageSize {
+			c.err = errors.Errorf("invalid max message size %d, must be between %d and %d", size, minMessageSize,
+				maxMessageSize)
+			return
+		}
+		c.maxMessageSize = size
+	}
+}
+
+func (c *serverConfig) registerObject(oid string, obj *agentObject) {
+	o, err := parseOID(oid)
+	if err != nil {
+		c.err = err
+		return
+	}
+	if c.objects == nil {
+		c.objects = NewOIDTree()
+	}
+	c.objects.Insert(o, obj)
+}
+
+// Defines a registered scalar object or table entry.
+type agentObject struct {
+	provider VariableProvider
+	// Nil if the object is not writable.
+	updater VariableUpdater
+	// Table columns in ascending order; nil for a scalar object.
+	columns []int
+	indexer TableIndexer
+}
+
+func (o *agentObject) isTable() bool {
+	return o.indexer != nil
+}
+
+func (o *agentObject) hasColumn(col int) bool {
+	i := sort.SearchInts(o.columns, col)
+	return i < len(o.columns) && o.columns[i] == col
+}
+
+// Delivers the indexes of the rows of a table, in lexicographic order.
+func (o *agentObject) sortedIndexes() []asn1.ObjectIdentifier {
+	indexes := o.indexer()
+	sort.Slice(indexes, func(i, j int) bool { return compareOIDs(indexes[i], indexes[j]) < 0 })
+	return indexes
+}
+
+// Delivers the instances of the object whose oids follow oid, in lexicographic order, with indexes holding the sorted
+// indexes of a table.
+func (o *agentObject) instancesAfter(base, oid asn1.ObjectIdentifier, indexes []asn1.ObjectIdentifier) []asn1.ObjectIdentifier {
+	if !o.isTable() {
+		instance := appendOID(base, 0)
+		if compareOIDs(instance, oid) > 0 {
+			return []asn1.ObjectIdentifier{instance}
+		}
+		return nil
+	}
+
+	var instances []asn1.ObjectIdentifier
+	for _, col := range o.columns {
+		for _, index := range indexes {
+			instance := appendOID(appendOID(base, col), index...)
+			if compareOIDs(instance, oid) > 0 {
+				instances = append(instances, instance)
+			}
+		}
+	}
+	return instances
+}
+
+// SNMP error-status values used in responses.
+const (
+	noError      = int(NoError)
+	noSuchName   = int(NoSuchName)
+	badValue     = int(BadValue)
+	genErr       = int(GenErr)
+	wrongType    = int(WrongType)
+	noCreation   = int(NoCreation)
+	commitFailed = int(CommitFailed)
+	notWritable  = int(NotWritable)
+	tooBig       = int(TooBig)
+)
+
+// Used to terminate a walk of the registered objects once the next instance has been found.
+var errInstanceFound = errors.New("instance found")
+
+// Resolves the variable bindings requested by Get, GetNext and GetBulk requests; implemented by the server for
+// registered objects, and by proxyResolver for proxied requests.
+type instanceResolver interface {
+	getInstance(oid asn1.ObjectIdentifier) (rawVarbind, error)
+	getNextInstance(oid asn1.ObjectIdentifier) (rawVarbind, error)
+}
+
+// Resolves the instances of the registered objects for a single request, so that the indexes of each table are
+// retrieved and sorted once per request, rather than for each variable binding and repetition.
+type agentRequest struct {
+	*serverImpl
+	indexes map[*agentObject][]asn1.ObjectIdentifier
+}
+
+// Delivers the sorted indexes of the rows of a table, retrieving them on first use.
+func (r *agentRequest) tableIndexes(obj *agentObject) []asn1.ObjectIdentifier {
+	if !obj.isTable() {
+		return nil
+	}
+	indexes, ok := r.indexes[obj]
+	if !ok {
+		indexes = obj.sortedIndexes()
+		r.indexes[obj] = indexes
+	}
+	return indexes
+}
+
+// Delivers the resolver for a Get, GetNext or GetBulk request, or nil if the server does not answer the request.
+func (s *serverImpl) resolverFor(pkt *packet) instanceResolver {
+	if p := s.config.proxyResolver(string(pkt.Community)); p != nil {
+		return p
+	}
+	if s.config.objects != nil {
+		return &agentRequest{serverImpl: s, indexes: map[*agentObject][]asn1.ObjectIdentifier{}}
+	}
+	return nil
+}
+
+// Responds to a Get, GetNext, GetBulk or Set request.
+func (s *serverImpl) respond(pkt *packet, mType byte, addr net.Addr, r instanceResolver, loc *decodeLocator) error {
+	raw := &rawPDU{}
+	pkt.RawPdu.FullBytes[0] = 0x30
+	if _, err := ber.Unmarshal(pkt.RawPdu.FullBytes, raw); err != nil {
+		pkt.RawPdu.FullBytes[0] = mType
+		return errors.Wrap(loc.fail("pdu", pkt.RawPdu.FullBytes, err), "failed to unmarshal request pdu")
+	}
+
+	response := rawPDU{RequestID: raw.RequestID}
+	var err error
+	switch mType {
+	case getMessage:
+		response.VarbindList, response.Error, response.ErrorIndex, err = s.get(raw.VarbindList, r.getInstance)
+	case getNextMessage:
+		response.VarbindList, response.Error, response.ErrorIndex, err = s.get(raw.VarbindList, r.getNextInstance)
+	case setRequestMessage:
+		response.VarbindList, response.Error, response.ErrorIndex, err = s.set(raw.VarbindList)
+	default:
+		response.VarbindList, response.Error, response.ErrorIndex, err = s.getBulk(raw.VarbindList, raw.Error, raw.ErrorIndex,
+			r.getNextInstance)
+	}
+	if err != nil {
+		s.config.trace.Error(s.config, err)
+	}
+
+	// SNMPv1 has no exception values, so these are reported as a noSuchName error, and has fewer error-status
+	// values, to which those of SNMPv2 are mapped.
+	if pkt.Version == SNMPV1 {
+		response.Error = v1ErrorStatus(response.Error)
+	}
+	if pkt.Version == SNMPV1 && response.Error == noError {
+		for i := range response.VarbindList {
+			if response.VarbindList[i].Value.Class == asn1.ClassContextSpecific {
+				response.Error, response.ErrorIndex = noSuchName, i+1
+				break
+			}
+		}
+	}
+	if response.Error != noError {
+		response.VarbindList = raw.VarbindList
+	}
+
+	resp, err := s.marshalResponse(pkt, &response, raw.VarbindList, mType == getBulkMessage)
+	if err != nil {
+		return err
+	}
+	return s.writeMessage(resp, addr)
+}
+
+// Marshals the response message, limiting it to the maximum message size, as described at
+// https://tools.ietf.org/html/rfc3416#section-4.2. A GetBulk response is truncated to the variable bindings that fit,
+// and any other response that is too big is replaced by a tooBig error, which holds no variable bindings, or for
+// SNMPv1 those of the request.
+func (s *serverImpl) marshalResponse(pkt *packet, response *rawPDU, requested []rawVarbind, truncate bool) ([]byte, error) {
+	limit := s.config.maxMessageSize
+	if limit == 0 {
+		limit = maxMessageSize
+	}
+	resp, err := marshalResponse(pkt, response)
+	if err != nil || len(resp) <= limit {
+		return resp, err
+	}
+
+	if truncate && response.Error == noError {
+		// The largest number of variable bindings that fit is found by a binary search.
+		vbl := response.VarbindList
+		n := sort.Search(len(vbl), func(n int) bool {
+			response.VarbindList = vbl[:n+1]
+			b, merr := marshalResponse(pkt, response)
+			return merr != nil || len(b) > limit
+		})
+		if n > 0 {
+			response.VarbindList = vbl[:n]
+			return marshalResponse(pkt, response)
+		}
+	}
+	response.Error, response.ErrorIndex, response.VarbindList = tooBig, 0, []rawVarbind{}
+	if pkt.Version == SNMPV1 {
+		response.VarbindList = requested
+	}
+	return marshalResponse(pkt, response)
+}
+
+func marshalResponse(pkt *packet, response *rawPDU) ([]byte, error) {
+	b, err := ber.Marshal(*response)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal response pdu")
+	}
+	b[0] = getResponse
+	message := *pkt
+	message.RawPdu = asn1.RawValue{FullBytes: b}
+	resp, err := ber.Marshal(message)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal response")
+	}
+	return resp, nil
+}
+
+// Resolves each of the requested variable bindings with the resolve function, returning the error status and
+// index if any provider fails.
+func (s *serverImpl) get(vbl []rawVarbind, resolve func(oid asn1.ObjectIdentifier) (rawVarbind, error)) (
+	result []rawVarbind, errStatus, errIndex int, err error) {
+	result = make([]rawVarbind, len(vbl))
+	for i := range vbl {
+		if result[i], err = resolve(vbl[i].OID); err != nil {
+			return nil, genErr, i + 1, err
+		}
+	}
+	return result, noError, 0, nil
+}
+
+// Resolves a Set request, as described at https://tools.ietf.org/html/rfc1905#section-4.2.5. Each variable binding
+// is checked before any value is assigned, so that the request is rejected as a whole if a variable is not writable,
+// or its value is not of the expected type.
+func (s *serverImpl) set(vbl []rawVarbind) (result []rawVarbind, errStatus, errIndex int, err error) {
+	updaters := make([]VariableUpdater, len(vbl))
+	values := make([]*TypedValue, len(vbl))
+	for i := range vbl {
+		if updaters[i], errStatus = s.updaterFor(vbl[i].OID); errStatus != noError {
+			return nil, errStatus, i + 1, nil
+		}
+		if values[i], err = unmarshalVariable(&vbl[i].Value); err != nil || values[i].isException() ||
+			values[i].Type == Null {
+			return nil, wrongType, i + 1, nil
+		}
+	}
+	for i := range vbl {
+		if err = updaters[i](vbl[i].OID, values[i]); err != nil {
+			return nil, commitFailed, i + 1, err
+		}
+	}
+	return vbl, noError, 0, nil
+}
+
+// Delivers the updater for the instance oid, or the error-status with which a Set of the instance is rejected.
+func (s *serverImpl) updaterFor(oid asn1.ObjectIdentifier) (VariableUpdater, int) {
+	prefix, value, ok := s.config.objects.LongestPrefix(oid)
+	if !ok {
+		return nil, noCreation
+	}
+	obj := value.(*agentObject)
+	suffix := oid[len(prefix):]
+	if obj.isTable() || len(suffix) != 1 || suffix[0] != 0 {
+		return nil, noCreation
+	}
+	if obj.updater == nil {
+		return nil, notWritable
+	}
+	return obj.updater, noError
+}
+
+// Maps an SNMPv2 error-status to its SNMPv1 equivalent, as described at
+// https://tools.ietf.org/html/rfc2576#section-4.3.
+func v1ErrorStatus(status int) int {
+	switch status {
+	case wrongType, int(WrongLength), int(WrongEncoding), int(WrongValue), int(InconsistentValue):
+		return badValue
+	case int(NoAccess), notWritable, noCreation, int(InconsistentName), int(AuthorizationError):
+		return noSuchName
+	case int(ResourceUnavailable), commitFailed, int(UndoFailed):
+		return genErr
+	}
+	return status
+}
+
+// Resolves a GetBulk request, as described at https://tools.ietf.org/html/rfc1905#section-4.2.3, with next
+// delivering the instance that follows an oid.
+func (s *serverImpl) getBulk(vbl []rawVarbind, nonRepeaters, maxRepetitions int,
+	next func(oid asn1.ObjectIdentifier) (rawVarbind, error)) (result []rawVarbind, errStatus, errIndex int, err error) {
+	if nonRepeaters < 0 {
+		nonRepeaters = 0
+	}
+	if nonRepeaters > len(vbl) {
+		nonRepeaters = len(vbl)
+	}
+	if maxRepetitions < 0 {
+		maxRepetitions = 0
+	}
+
+	result, errStatus, errIndex, err = s.get(vbl[:nonRepeaters], next)
+	if err != nil {
+		return nil, errStatus, errIndex, err
+	}
+
+	repeaters := append([]rawVarbind{}, vbl[nonRepeaters:]...)
+	for r := 0; r < maxRepetitions && len(repeaters) > 0; r++ {
+		endOfMib := true
+		for i := range repeaters {
+			if repeaters[i], err = next(repeaters[i].OID); err != nil {
+				return nil, genErr, nonRepeaters + i + 1, err
+			}
+			endOfMib = endOfMib && isEndOfMib(&repeaters[i])
+		}
+		result = append(result, repeaters...)
+		if endOfMib {
+			break
+		}
+	}
+	return result, noError, 0, nil
+}
+
+// Delivers the variable binding for the instance oid.
+func (s *serverImpl) getInstance(oid asn1.ObjectIdentifier) (rawVarbind, error) {
+	prefix, value, ok := s.config.objects.LongestPrefix(oid)
+	if !ok {
+		return exceptionVarbind(oid, noSuchObjectTag), nil
+	}
+	obj := value.(*agentObject)
+	suffix := oid[len(prefix):]
+	if !obj.isTable() && (len(suffix) != 1 || suffix[0] != 0) {
+		return exceptionVarbind(oid, noSuchInstanceTag), nil
+	}
+	if obj.isTable() {
+		if len(suffix) == 0 || !obj.hasColumn(suffix[0]) {
+			return exceptionVarbind(oid, noSuchObjectTag), nil
+		}
+		if len(suffix) == 1 {
+			return exceptionVarbind(oid, noSuchInstanceTag), nil
+		}
+	}
+
+	tv, err := obj.provider(oid)
+	if err != nil || tv == nil {
+		return exceptionVarbind(oid, noSuchInstanceTag), err
+	}
+	return valueVarbind(oid, tv)
+}
+
+// Delivers the variable binding for the first instance that follows oid, with a provided value.
+func (r *agentRequest) getNextInstance(oid asn1.ObjectIdentifier) (rawVarbind, error) {
+	var vb rawVarbind
+	err := r.config.objects.Walk(nil, func(base asn1.ObjectIdentifier, value interface{}) error {
+		obj := value.(*agentObject)
+		for _, instance := range obj.instancesAfter(base, oid, r.tableIndexes(obj)) {
+			tv, err := obj.provider(instance)
+			if err != nil {
+				vb = exceptionVarbind(oid, noSuchInstanceTag)
+				return err
+			}
+			if tv != nil {
+				if vb, err = valueVarbind(instance, tv); err != nil {
+					return err
+				}
+				return errInstanceFound
+			}
+		}
+		return nil
+	})
+	switch {
+	case err == errInstanceFound:
+		return vb, nil
+	case err != nil:
+		return vb, err
+	}
+	return exceptionVarbind(oid, endOfMibTag), nil
+}
+
+func exceptionVarbind(oid asn1.ObjectIdentifier, tag byte) rawVarbind {
+	return rawVarbind{OID: oid, Value: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: int(tag & tagMask),
+		FullBytes: []byte{tag, 0}}}
+}
+
+func isEndOfMib(vb *rawVarbind) bool {
+	return vb.Value.Class == asn1.ClassContextSpecific && vb.Value.Tag == resolvedEndOfMibTag
+}
+
+func valueVarbind(oid asn1.ObjectIdentifier, tv *TypedValue) (rawVarbind, error) {
+	value, err := marshalVariable(tv)
+	if err != nil {
+		return rawVarbind{}, errors.Wrapf(err, "oid %s", oid)
+	}
+	return rawVarbind{OID: oid, Value: value}, nil
+}
+
+// Marshals a TypedValue into an asn1 RawValue with the tag corresponding to its SNMP data type.
+//
+//nolint:gocyclo
+func marshalVariable(tv *TypedValue) (asn1.RawValue, error) {
+	var tag byte
+	var content []byte
+	switch tv.Type {
+	case Integer:
+		v, ok := signedValue(tv.Value)
+		if !ok {
+			return asn1.RawValue{}, errors.Errorf("unsupported Integer value %T", tv.Value)
+		}
+		tag, content = asn1.TagInteger, signedOctets(v)
+	case Counter32, Gauge32, Time, Counter64:
+		v, ok := unsignedValue(tv.Value)
+		if !ok {
+			return asn1.RawValue{}, errors.Errorf("unsupported unsigned value %T", tv.Value)
+		}
+		tag, content = map[DataType]byte{Counter32: counter32Tag, Gauge32: gauge32Tag, Time: timeTag,
+			Counter64: counter64Tag}[tv.Type], unsignedOctets(v)
+	case OctetString, Opaque:
+		tag = map[DataType]byte{OctetString: asn1.TagOctetString, Opaque: opaqueTag}[tv.Type]
+		switch v := tv.Value.(type) {
+		case []byte:
+			content = v
+		case string:
+			content = []byte(v)
+		default:
+			return asn1.RawValue{}, errors.Errorf("unsupported OctetString value %T", tv.Value)
+		}
+	case Float, Double, Integer64, Unsigned64:
+		var err error
+		if content, err = marshalOpaque(tv); err != nil {
+			return asn1.RawValue{}, err
+		}
+		tag = opaqueTag
+	case IPAdddress:
+		tag = ipTag
+		switch v := tv.Value.(type) {
+		case net.IP:
+			content = v.To4()
+		case []byte:
+			content = v
+		}
+		if len(content) != net.IPv4len {
+			return asn1.RawValue{}, errors.Errorf("unsupported IpAddress value %v", tv.Value)
+		}
+	case OID:
+		oid, ok := tv.Value.(asn1.ObjectIdentifier)
+		if !ok {
+			return asn1.RawValue{}, errors.Errorf("unsupported OID value %T", tv.Value)
+		}
+		b, err := asn1.Marshal(oid)
+		if err != nil {
+			return asn1.RawValue{}, err
+		}
+		return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagOID, FullBytes: b}, nil
+	case EndOfMib:
+		tag = endOfMibTag
+	case NoSuchObject:
+		tag = noSuchObjectTag
+	case NoSuchInstance:
+		tag = noSuchInstanceTag
+	case Null:
+		tag = asn1.TagNull
+	default:
+		return asn1.RawValue{}, errors.Errorf("unsupported data type %d", tv.Type)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte(tag)
+	writeLength(buf, len(content))
+	buf.Write(content)
+	return asn1.RawValue{Class: int(tag >> 6), Tag: int(tag & tagMask), FullBytes: buf.Bytes()}, nil
+}
+
+func signedValue(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+func unsignedValue(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Delivers the minimal two's complement encoding of v.
+func signedOctets(v int64) []byte {
+	n := 1
+	for x := v; x > 127 || x < -128; x >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// Delivers the minimal encoding of v as a non-negative integer.
+func unsignedOctets(v uint64) []byte {
+	n := 1
+	for x := v; x > 127; x >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return b
+}
+
+// Delivers a new oid with the arcs appended to oid.
+func appendOID(oid asn1.ObjectIdentifier, arcs ...int) asn1.ObjectIdentifier {
+	return append(append(make(asn1.ObjectIdentifier, 0, len(oid)+len(arcs)), oid...), arcs...)
+}
+
+// Compares oids in lexicographic order, returning -1, 0 or +1.
+func compareOIDs(a, b asn1.ObjectIdentifier) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,382 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/geoffgarside/ber"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const (
+	sysDescr    = "1.3.6.1.2.1.1.1"
+	sysContact  = "1.3.6.1.2.1.1.4"
+	sysName     = "1.3.6.1.2.1.1.5"
+	sysLocation = "1.3.6.1.2.1.1.6"
+	ifEntry     = "1.3.6.1.2.1.2.2.1"
+)
+
+func newTestAgentServer(t *testing.T, opts ...ServerOption) Server {
+	opts = append([]ServerOption{Address("127.0.0.1"), Port(0), Hooks(NoOpServerHooks)}, opts...)
+	s, err := NewServerFactory().NewServer(context.Background(), nil, opts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+var testAgentObjects = []ServerOption{
+	ServeScalar(sysDescr, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		return &TypedValue{Type: OctetString, Value: []byte("test agent")}, nil
+	}),
+	ServeScalar(sysName, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		return nil, errors.New("provider failed")
+	}),
+	ServeTable(ifEntry, []int{2, 1}, func() []asn1.ObjectIdentifier {
+		return []asn1.ObjectIdentifier{{2}, {1}}
+	}, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		index := oid[len(oid)-1]
+		if oid[len(oid)-2] == 1 {
+			return &TypedValue{Type: Integer, Value: int64(index)}, nil
+		}
+		return &TypedValue{Type: OctetString, Value: []byte(fmt.Sprintf("eth%d", index))}, nil
+	}),
+}
+
+func newTestAgent(t *testing.T) Session {
+	return newTestSession(t, newTestAgentServer(t, testAgentObjects...))
+}
+
+func TestAgentGet(t *testing.T) {
+	ses := newTestAgent(t)
+
+	pdu, err := ses.Get(context.Background(), []string{sysDescr + ".0", sysDescr + ".1", "1.3.6.1.2.1.99.0",
+		ifEntry + ".2.1", ifEntry + ".3.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pdu.Error)
+	assert.Equal(t, "test agent", string(pdu.VarbindList[0].TypedValue.Value.([]byte)))
+	assert.Equal(t, NoSuchInstance, pdu.VarbindList[1].TypedValue.Type)
+	assert.Equal(t, NoSuchObject, pdu.VarbindList[2].TypedValue.Type)
+	assert.Equal(t, "eth1", string(pdu.VarbindList[3].TypedValue.Value.([]byte)))
+	assert.Equal(t, NoSuchObject, pdu.VarbindList[4].TypedValue.Type)
+}
+
+func TestAgentGetProviderFailure(t *testing.T) {
+	s := newTestAgentServer(t, testAgentObjects...)
+
+	pdu := exchange(t, s, SNMPV2C, []string{sysDescr + ".0", sysName + ".0"})
+	assert.Equal(t, genErr, pdu.Error)
+	assert.Equal(t, 2, pdu.ErrorIndex)
+}
+
+func TestAgentWalk(t *testing.T) {
+	ses := newTestAgent(t)
+
+	var oids []string
+	var values []string
+	err := ses.Walk(context.Background(), ifEntry, func(vb *Varbind) error {
+		if vb.TypedValue.Type == EndOfMib {
+			return nil
+		}
+		oids = append(oids, vb.OID.String())
+		values = append(values, vb.TypedValue.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ifEntry + ".1.1", ifEntry + ".1.2", ifEntry + ".2.1", ifEntry + ".2.2"}, oids)
+	assert.Equal(t, []string{"1", "2", "eth1", "eth2"}, values)
+}
+
+func TestAgentSharedSession(t *testing.T) {
+	ses := newTestAgent(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if i%2 == 0 {
+					pdu, err := ses.Get(context.Background(), []string{sysDescr + ".0"})
+					assert.NoError(t, err)
+					assert.Equal(t, "test agent", pdu.VarbindList[0].TypedValue.String())
+					continue
+				}
+				var values []string
+				err := ses.BulkWalk(context.Background(), ifEntry, 2, func(vb *Varbind) error {
+					if vb.TypedValue.Type != EndOfMib {
+						values = append(values, vb.TypedValue.String())
+					}
+					return nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"1", "2", "eth1", "eth2"}, values)
+				_ = ses.Health()
+				_ = ses.Discards()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Zero(t, ses.Discards(), "Expecting every response to answer its request")
+}
+
+func TestAgentGetNextEndOfMib(t *testing.T) {
+	ses := newTestAgent(t)
+
+	pdu, err := ses.GetNext(context.Background(), []string{sysDescr, ifEntry + ".2.2"})
+	assert.NoError(t, err)
+	assert.Equal(t, sysDescr+".0", pdu.VarbindList[0].OID.String())
+	assert.Equal(t, EndOfMib, pdu.VarbindList[1].TypedValue.Type)
+}
+
+func TestAgentGetBulk(t *testing.T) {
+	ses := newTestAgent(t)
+
+	pdu, err := ses.GetBulk(context.Background(), []string{"1.3.6.1.2.1.1", ifEntry + ".1"}, 1, 10)
+	assert.NoError(t, err)
+	var oids []string
+	for _, vb := range pdu.VarbindList {
+		oids = append(oids, vb.OID.String())
+	}
+	// The repeater terminates once it reaches the end of the mib.
+	assert.Equal(t, []string{sysDescr + ".0", ifEntry + ".1.1", ifEntry + ".1.2", ifEntry + ".2.1", ifEntry + ".2.2",
+		ifEntry + ".2.2"}, oids)
+	assert.Equal(t, EndOfMib, pdu.VarbindList[5].TypedValue.Type)
+}
+
+// Delivers the options registering a table with the number of rows, whose values are 32 byte strings, counting the
+// calls to its indexer.
+func largeTable(rows int, indexed *int32) ServerOption {
+	return ServeTable(ifEntry, []int{2}, func() []asn1.ObjectIdentifier {
+		atomic.AddInt32(indexed, 1)
+		indexes := make([]asn1.ObjectIdentifier, rows)
+		for i := range indexes {
+			indexes[i] = asn1.ObjectIdentifier{rows - i}
+		}
+		return indexes
+	}, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		return &TypedValue{Type: OctetString, Value: bytes.Repeat([]byte("x"), 32)}, nil
+	})
+}
+
+func TestAgentGetBulkTruncated(t *testing.T) {
+	var indexed int32
+	ses := newTestSession(t, newTestAgentServer(t, AgentMaxMessageSize(minMessageSize), largeTable(50, &indexed)))
+
+	pdu, err := ses.GetBulk(context.Background(), []string{ifEntry}, 0, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pdu.Error)
+	assert.Less(t, len(pdu.VarbindList), 50, "Expecting the repetitions to be truncated")
+	assert.Greater(t, len(pdu.VarbindList), 0)
+	for i, vb := range pdu.VarbindList {
+		assert.Equal(t, fmt.Sprintf("%s.2.%d", ifEntry, i+1), vb.OID.String())
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&indexed), "Expecting the table to be indexed once per request")
+}
+
+func TestAgentGetTooBig(t *testing.T) {
+	var indexed int32
+	s := newTestAgentServer(t, AgentMaxMessageSize(minMessageSize), largeTable(50, &indexed))
+
+	oids := make([]string, 20)
+	for i := range oids {
+		oids[i] = fmt.Sprintf("%s.2.%d", ifEntry, i+1)
+	}
+	pdu := exchange(t, s, SNMPV2C, oids)
+	assert.Equal(t, tooBig, pdu.Error)
+	assert.Equal(t, 0, pdu.ErrorIndex)
+	assert.Empty(t, pdu.VarbindList)
+}
+
+func TestAgentInvalidMaxMessageSize(t *testing.T) {
+	_, err := NewServerFactory().NewServer(context.Background(), nil, Port(0), AgentMaxMessageSize(100))
+	assert.EqualError(t, err, "invalid max message size 100, must be between 484 and 65507")
+}
+
+func TestAgentV1NoSuchName(t *testing.T) {
+	s := newTestAgentServer(t, ServeScalar(sysDescr, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		return &TypedValue{Type: OctetString, Value: "test agent"}, nil
+	}))
+
+	pdu := exchange(t, s, SNMPV1, []string{sysDescr + ".0", sysName + ".0"})
+	assert.Equal(t, noSuchName, pdu.Error)
+	assert.Equal(t, 2, pdu.ErrorIndex)
+	assert.Len(t, pdu.VarbindList, 2)
+}
+
+// Delivers a server with writable sysContact and sysLocation scalars, holding their values in values. Sets of
+// sysLocation to "fail" are rejected by its updater.
+func newWritableTestAgent(t *testing.T, values map[string]string) Server {
+	var mu sync.Mutex
+	provider := func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return &TypedValue{Type: OctetString, Value: []byte(values[oid.String()])}, nil
+	}
+	updater := func(oid asn1.ObjectIdentifier, value *TypedValue) error {
+		if value.Type != OctetString {
+			return fmt.Errorf("unexpected type %d", value.Type)
+		}
+		if value.String() == "fail" {
+			return errors.New("updater failed")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		values[oid.String()] = value.String()
+		return nil
+	}
+	opts := append([]ServerOption{}, testAgentObjects...)
+	return newTestAgentServer(t, append(opts,
+		ServeWritableScalar(sysContact, provider, updater),
+		ServeWritableScalar(sysLocation, provider, updater))...)
+}
+
+func TestAgentSet(t *testing.T) {
+	values := map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}
+	ses := newTestSession(t, newWritableTestAgent(t, values))
+
+	pdu, err := ses.Set(context.Background(), []Varbind{stringVarbind(sysContact+".0", "noc"),
+		stringVarbind(sysLocation+".0", "dc1")})
+	assert.NoError(t, err)
+	assert.Equal(t, noError, pdu.Error)
+	assert.Equal(t, "dc1", pdu.VarbindList[1].TypedValue.String())
+	assert.Equal(t, map[string]string{sysContact + ".0": "noc", sysLocation + ".0": "dc1"}, values)
+
+	pdu, err = ses.Get(context.Background(), []string{sysContact + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "noc", pdu.VarbindList[0].TypedValue.String())
+}
+
+func TestAgentSetRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		varbinds []Varbind
+		status   ErrorStatus
+		index    int
+	}{
+		{
+			name:     "NotWritable",
+			varbinds: []Varbind{stringVarbind(sysContact+".0", "noc"), stringVarbind(sysDescr+".0", "router")},
+			status:   NotWritable,
+			index:    2,
+		},
+		{
+			name:     "NoCreation",
+			varbinds: []Varbind{stringVarbind(sysContact+".1", "noc")},
+			status:   NoCreation,
+			index:    1,
+		},
+		{
+			name: "WrongType",
+			varbinds: []Varbind{stringVarbind(sysLocation+".0", "dc1"),
+				{OID: mustParseOID(sysContact + ".0"), TypedValue: &TypedValue{Type: Null}}},
+			status: WrongType,
+			index:  2,
+		},
+		{
+			name:     "CommitFailed",
+			varbinds: []Varbind{stringVarbind(sysLocation+".0", "fail")},
+			status:   CommitFailed,
+			index:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}
+			ses := newTestSession(t, newWritableTestAgent(t, values))
+
+			pdu, err := ses.Set(context.Background(), tt.varbinds)
+			assert.NoError(t, err)
+			assert.Equal(t, int(tt.status), pdu.Error)
+			assert.Equal(t, tt.index, pdu.ErrorIndex)
+			assert.Equal(t, map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}, values,
+				"Expecting no values to be assigned")
+		})
+	}
+}
+
+func TestAgentSetV1(t *testing.T) {
+	values := map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}
+	ses := newTestSession(t, newWritableTestAgent(t, values), WithVersion(SNMPV1))
+
+	pdu, err := ses.Set(context.Background(), []Varbind{stringVarbind(sysDescr+".0", "router")})
+	assert.NoError(t, err)
+	assert.Equal(t, noSuchName, pdu.Error)
+	assert.Equal(t, 1, pdu.ErrorIndex)
+}
+
+// Issues a get request directly, as error responses echo the request varbinds with Null values, which the session
+// cannot decode.
+func exchange(t *testing.T, s Server, version Version, oids []string) *rawPDU {
+	conn, err := net.Dial("udp", s.(*serverImpl).conn.LocalAddr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	client := &sessionImpl{config: &SessionConfig{version: version, community: "public"}}
+	request, err := client.buildPacket(&bytes.Buffer{}, client.nextID(), oids, nil, getMessage, 0, 0)
+	assert.NoError(t, err)
+	_, err = conn.Write(request)
+	assert.NoError(t, err)
+
+	input := make([]byte, maxInputBufferSize)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(input)
+	assert.NoError(t, err)
+
+	pkt := &packet{}
+	_, err = ber.Unmarshal(input[:n], pkt)
+	assert.NoError(t, err)
+	assert.Equal(t, version, pkt.Version)
+	assert.Equal(t, byte(getResponse), pkt.RawPdu.FullBytes[0])
+	pkt.RawPdu.FullBytes[0] = 0x30
+	pdu := &rawPDU{}
+	_, err = ber.Unmarshal(pkt.RawPdu.FullBytes, pdu)
+	assert.NoError(t, err)
+	return pdu
+}
+
+func TestServeInvalidOID(t *testing.T) {
+	_, err := NewServerFactory().NewServer(context.Background(), nil, Port(0), ServeScalar("1.3.x", nil))
+	assert.Error(t, err)
+}
+
+func TestMarshalVariable(t *testing.T) {
+	tests := []struct {
+		input    *TypedValue
+		expected []byte
+	}{
+		{&TypedValue{Type: Integer, Value: int64(-129)}, []byte{0x02, 0x02, 0xff, 0x7f}},
+		{&TypedValue{Type: Integer, Value: int64(127)}, []byte{0x02, 0x01, 0x7f}},
+		{&TypedValue{Type: Counter32, Value: uint32(0xffffffff)}, []byte{counter32Tag, 0x05, 0x00, 0xff, 0xff, 0xff, 0xff}},
+		{&TypedValue{Type: Counter64, Value: uint64(128)}, []byte{counter64Tag, 0x02, 0x00, 0x80}},
+		{&TypedValue{Type: Time, Value: uint32(0)}, []byte{timeTag, 0x01, 0x00}},
+		{&TypedValue{Type: IPAdddress, Value: []byte{10, 0, 0, 1}}, []byte{ipTag, 0x04, 10, 0, 0, 1}},
+		{&TypedValue{Type: OID, Value: asn1.ObjectIdentifier{1, 3, 6}}, []byte{0x06, 0x02, 0x2b, 0x06}},
+		{&TypedValue{Type: Float, Value: 1.5}, []byte{opaqueTag, 0x07, 0x9f, 0x78, 0x04, 0x3f, 0xc0, 0x00, 0x00}},
+		{&TypedValue{Type: Double, Value: 1.5}, []byte{opaqueTag, 0x0b, 0x9f, 0x79, 0x08, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{&TypedValue{Type: Integer64, Value: int64(-200)}, []byte{opaqueTag, 0x05, 0x9f, 0x7a, 0x02, 0xff, 0x38}},
+		{&TypedValue{Type: Unsigned64, Value: uint64(200)}, []byte{opaqueTag, 0x05, 0x9f, 0x7b, 0x02, 0x00, 0xc8}},
+	}
+	for _, test := range tests {
+		raw, err := marshalVariable(test.input)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, raw.FullBytes)
+
+		tv, err := unmarshalVariable(&raw)
+		assert.NoError(t, err)
+		assert.Equal(t, test.input.String(), tv.String())
+	}
+
+	_, err := marshalVariable(&TypedValue{Type: Integer, Value: "1"})
+	assert.Error(t, err)
+	_, err = marshalVariable(&TypedValue{Type: Float, Value: 1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// benchConn is a minimal net.Conn that responds to every write with a canned response.
+type benchConn struct {
+	net.Conn
+	response []byte
+}
+
+func (c *benchConn) Write(b []byte) (int, error)   { return len(b), nil }
+func (c *benchConn) Read(b []byte) (int, error)    { return copy(b, c.response), nil }
+func (c *benchConn) SetDeadline(t time.Time) error { return nil }
+func (c *benchConn) Close() error                  { return nil }
+
+var benchResponse = []byte{
+	0x30, 0x82, 0x00, 0x36,
+	0x02, 0x01, 0x01,
+	0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
+	0xa2, 0x82, 0x00, 0x27,
+	0x02, 0x01, 0x01,
+	0x02, 0x01, 0x00,
+	0x02, 0x01, 0x00,
+	0x30, 0x82, 0x00, 0x1a,
+	0x30, 0x82, 0x00, 0x16,
+	0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00,
+	0x04, 0x0a, 0x63, 0x69, 0x73, 0x63, 0x6f, 0x2d, 0x37, 0x35, 0x31, 0x33,
+}
+
+func newBenchSession() *sessionImpl {
+	config := DefaultConfig
+	config.address = localhost161
+	config.trace = NoOpLoggingHooks
+	return &sessionImpl{config: &config, conn: &benchConn{response: benchResponse}, nextRequestID: 1}
+}
+
+func BenchmarkBuildPacket(b *testing.B) {
+	m := newBenchSession()
+	oids := []string{"1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.1.3.0", "1.3.6.1.2.1.2.2.1.10.1"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := getMarshalBuffer()
+		if _, err := m.buildPacket(buf, 1, oids, nil, getMessage, 0, 0); err != nil {
+			b.Fatal(err)
+		}
+		putMarshalBuffer(buf)
+	}
+}
+
+func BenchmarkParseResponse(b *testing.B) {
+	m := newBenchSession()
+	input := make([]byte, len(benchResponse))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		copy(input, benchResponse)
+		if _, err := m.parseResponse(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	m := newBenchSession()
+	oids := []string{"1.3.6.1.2.1.1.5.0"}
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// The canned response answers request-id 1.
+		m.nextRequestID = 1
+		if _, err := m.Get(ctx, oids); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Verifies that the response delivered by a session does not share memory with the pooled read buffer.
+func TestGetResponseDoesNotAliasReadBuffer(t *testing.T) {
+	m := newBenchSession()
+	pdu, err := m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.NoError(t, err)
+
+	// Reuse the pooled buffers, overwriting their content.
+	m.conn = &benchConn{response: make([]byte, len(benchResponse))}
+	for i := 0; i < 10; i++ {
+		buf := getReadBuffer()
+		for j := range *buf {
+			(*buf)[j] = 0xff
+		}
+		putReadBuffer(buf)
+	}
+
+	assert.Equal(t, "cisco-7513", pdu.VarbindList[0].TypedValue.String())
+}
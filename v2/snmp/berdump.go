@@ -0,0 +1,156 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Pretty printing of BER encoded SNMP messages, used by the diagnostic hooks to log messages as a readable tree
+// of tags, lengths and decoded values.
+
+// Names of the tags found in SNMP messages.
+var berTagNames = map[byte]string{
+	asn1.TagInteger:                "INTEGER",
+	asn1.TagOctetString:            "OCTET STRING",
+	asn1.TagNull:                   "NULL",
+	asn1.TagOID:                    "OBJECT IDENTIFIER",
+	asn1.TagSequence | compoundTag: "SEQUENCE",
+	ipTag:                          "IpAddress",
+	counter32Tag:                   "Counter32",
+	gauge32Tag:                     "Gauge32",
+	timeTag:                        "TimeTicks",
+	opaqueTag:                      "Opaque",
+	counter64Tag:                   "Counter64",
+	noSuchObjectTag:                "noSuchObject",
+	noSuchInstanceTag:              "noSuchInstance",
+	endOfMibTag:                    "endOfMibView",
+	getMessage:                     "GetRequest-PDU",
+	getNextMessage:                 "GetNextRequest-PDU",
+	getResponse:                    "Response-PDU",
+	setRequestMessage:              "SetRequest-PDU",
+	v1Trap:                         "Trap-PDU",
+	getBulkMessage:                 "GetBulkRequest-PDU",
+	inform:                         "InformRequest-PDU",
+	v2Trap:                         "SNMPv2-Trap-PDU",
+	reportMessage:                  "Report-PDU",
+}
+
+// SNMP message type that is only named when pretty printing.
+const reportMessage = 0xA8
+
+// FormatBER delivers a multi-line representation of the BER encoded message b, with each element on a line
+// showing its tag, content length and, for primitive elements, its decoded value. Constructed elements are
+// followed by their content, indented.
+// If b is malformed, the representation ends with the undecodable octets in hex.
+func FormatBER(b []byte) string {
+	sb := &strings.Builder{}
+	formatBER(sb, b, 0)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func formatBER(sb *strings.Builder, b []byte, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for len(b) > 0 {
+		tag := b[0]
+		length, hdr, ok := berLength(b[1:])
+		if !ok || 1+hdr+length > len(b) {
+			fmt.Fprintf(sb, "%smalformed: %s\n", indent, hex.EncodeToString(b))
+			return
+		}
+		content := b[1+hdr : 1+hdr+length]
+		b = b[1+hdr+length:]
+
+		name, known := berTagNames[tag]
+		if !known {
+			name = fmt.Sprintf("[tag 0x%02x]", tag)
+		}
+		if tag&compoundTag != 0 {
+			fmt.Fprintf(sb, "%s%s (%d)\n", indent, name, length)
+			formatBER(sb, content, depth+1)
+			continue
+		}
+		fmt.Fprintf(sb, "%s%s (%d): %s\n", indent, name, length, berValue(tag, content))
+	}
+}
+
+// Decodes a BER length, returning the length, the number of octets it occupies, and whether it is valid.
+func berLength(b []byte) (length, size int, ok bool) {
+	const longForm = 0x80
+	const maxLengthOctets = 4
+	if len(b) == 0 {
+		return 0, 0, false
+	}
+	if b[0]&longForm == 0 {
+		return int(b[0]), 1, true
+	}
+	n := int(b[0] &^ longForm)
+	if n == 0 || n > maxLengthOctets || n >= len(b) {
+		return 0, 0, false
+	}
+	for _, octet := range b[1 : n+1] {
+		length = length<<8 | int(octet)
+	}
+	return length, n + 1, true
+}
+
+// Delivers a readable representation of the content of a primitive element.
+func berValue(tag byte, content []byte) string {
+	switch tag {
+	case asn1.TagInteger:
+		v := new(big.Int).SetBytes(content)
+		if len(content) > 0 && content[0]&0x80 != 0 {
+			// Negative two's complement value.
+			v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(content))))
+		}
+		return v.String()
+	case counter32Tag, gauge32Tag, timeTag, counter64Tag:
+		return new(big.Int).SetBytes(content).String()
+	case asn1.TagOID:
+		if oid, ok := berOID(content); ok {
+			return oid.String()
+		}
+	case ipTag:
+		if len(content) == 4 {
+			return fmt.Sprintf("%d.%d.%d.%d", content[0], content[1], content[2], content[3])
+		}
+	case asn1.TagOctetString:
+		if isPrintable(content) {
+			return fmt.Sprintf("%q", content)
+		}
+	case asn1.TagNull, noSuchObjectTag, noSuchInstanceTag, endOfMibTag:
+		if len(content) == 0 {
+			return "-"
+		}
+	}
+	return hex.EncodeToString(content)
+}
+
+// Decodes the content of an object identifier.
+func berOID(content []byte) (asn1.ObjectIdentifier, bool) {
+	var arcs []int
+	v := 0
+	for i, octet := range content {
+		v = v<<7 | int(octet&0x7f)
+		if octet&0x80 != 0 {
+			if i == len(content)-1 {
+				return nil, false
+			}
+			continue
+		}
+		if len(arcs) == 0 {
+			// The first subidentifier encodes the first two arcs.
+			first := v / 40
+			if first > 2 {
+				first = 2
+			}
+			arcs = append(arcs, first, v-first*40)
+		} else {
+			arcs = append(arcs, v)
+		}
+		v = 0
+	}
+	return arcs, len(arcs) > 0
+}
@@ -0,0 +1,49 @@
+package snmp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestFormatBER(t *testing.T) {
+	// SNMPv2c GetRequest for sysDescr.0.
+	msg, _ := hex.DecodeString("302902010104067075626c6963a01c0204000000010201000201003" +
+		"00e300c06082b060102010101000500")
+	assert.Equal(t, `SEQUENCE (41)
+  INTEGER (1): 1
+  OCTET STRING (6): "public"
+  GetRequest-PDU (28)
+    INTEGER (4): 1
+    INTEGER (1): 0
+    INTEGER (1): 0
+    SEQUENCE (14)
+      SEQUENCE (12)
+        OBJECT IDENTIFIER (8): 1.3.6.1.2.1.1.1.0
+        NULL (0): -`, FormatBER(msg))
+}
+
+func TestFormatBERValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"negative integer", "0201ff", "INTEGER (1): -1"},
+		{"counter", "4104ffffffff", "Counter32 (4): 4294967295"},
+		{"ip address", "40040a000001", "IpAddress (4): 10.0.0.1"},
+		{"binary octets", "04020001", "OCTET STRING (2): 0001"},
+		{"end of mib", "8200", "endOfMibView (0): -"},
+		{"unknown tag", "1f0101", "[tag 0x1f] (1): 01"},
+		{"long form length", "04810161", `OCTET STRING (1): "a"`},
+		{"truncated", "3005020101", "malformed: 3005020101"},
+		{"invalid length", "0480", "malformed: 0480"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(test.input)
+			assert.Equal(t, test.expect, FormatBER(b))
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ResultCache holds the results of requests for semi-static objects, such as sysDescr, ifDescr or the entity MIB,
+// so that frequent pollers do not fetch them repeatedly. Only Get requests whose oids are all within the
+// designated prefixes, and walks whose root oid is within a designated prefix, are served from the cache; all
+// other requests, such as those for counters, bypass it.
+// Results are held for the cache TTL, or until invalidated. Get responses reporting an error-status, or truncated
+// by the agent, and walks that are terminated by an error or deliver an error-status are not cached.
+// A cache holds the results of each session target and community separately, so may be shared by sessions, although
+// typically each session is given its own cache; the results obtained before credentials are rotated are not served
+// to requests made with the new credentials.
+type ResultCache struct {
+	ttl      time.Duration
+	prefixes *OIDTree
+
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	hits    uint64
+	misses  uint64
+	// Delivers the current time; replaced by tests.
+	now func() time.Time
+}
+
+// CacheStats defines the number of requests served by a ResultCache, and the number of results it holds.
+type CacheStats struct {
+	// The number of requests served from the cache.
+	Hits uint64
+	// The number of cacheable requests that were sent to the agent, as no current result was held.
+	Misses uint64
+	// The number of results held, including any that have expired but have not yet been discarded.
+	Entries int
+}
+
+type cacheKey struct {
+	target    string
+	community string
+	// Distinguishes walk results from the results of a Get request.
+	walk bool
+	oid  string
+}
+
+type cacheEntry struct {
+	oid      asn1.ObjectIdentifier
+	expires  time.Time
+	varbinds []Varbind
+	address  string
+}
+
+// NewResultCache delivers a cache that holds the results of requests for the oids within the prefixes, for example
+// "1.3.6.1.2.1.1.1" for sysDescr, for the period ttl.
+func NewResultCache(ttl time.Duration, prefixes ...string) (*ResultCache, error) {
+	if ttl <= 0 {
+		return nil, errors.Errorf("invalid cache ttl %s", ttl)
+	}
+	c := &ResultCache{ttl: ttl, prefixes: NewOIDTree(), entries: map[cacheKey]*cacheEntry{}, now: time.Now}
+	for _, prefix := range prefixes {
+		oid, err := parseOID(prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cache oid prefix %q", prefix)
+		}
+		c.prefixes.Insert(oid, true)
+	}
+	return c, nil
+}
+
+// ResultCaching defines the cache used to serve requests for semi-static objects, as described by ResultCache.
+// Default is no cache.
+func ResultCaching(cache *ResultCache) SessionOption {
+	return func(c *SessionConfig) {
+		c.cache = cache
+	}
+}
+
+// Invalidate discards the results held for oids within any of the prefixes, including walks whose root oid is
+// within a prefix, or is an ancestor of a prefix. If no prefixes are specified, all results are discarded.
+func (c *ResultCache) Invalidate(prefixes ...string) error {
+	oids := make([]asn1.ObjectIdentifier, len(prefixes))
+	for i, prefix := range prefixes {
+		oid, err := parseOID(prefix)
+		if err != nil {
+			return errors.Wrapf(err, "invalid oid prefix %q", prefix)
+		}
+		oids[i] = oid
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(oids) == 0 {
+		c.entries = map[cacheKey]*cacheEntry{}
+		return nil
+	}
+	for key, e := range c.entries {
+		for _, oid := range oids {
+			if hasOIDPrefix(e.oid, oid) || key.walk && hasOIDPrefix(oid, e.oid) {
+				delete(c.entries, key)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Stats delivers the cache statistics.
+func (c *ResultCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
+
+// Delivers the parsed oid if it is within one of the designated prefixes.
+func (c *ResultCache) cacheable(oid string) (asn1.ObjectIdentifier, bool) {
+	parsed, err := parseOID(oid)
+	if err != nil {
+		return nil, false
+	}
+	_, _, ok := c.prefixes.LongestPrefix(parsed)
+	return parsed, ok
+}
+
+// Delivers the variable bindings held for the keys, if all are current, recording a hit or miss.
+func (c *ResultCache) lookup(keys []cacheKey) ([]Varbind, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	var varbinds []Varbind
+	var address string
+	for _, key := range keys {
+		e, ok := c.entries[key]
+		if ok && !now.Before(e.expires) {
+			delete(c.entries, key)
+			ok = false
+		}
+		if !ok {
+			c.misses++
+			return nil, "", false
+		}
+		varbinds = append(varbinds, copyVarbinds(e.varbinds)...)
+		address = e.address
+	}
+	c.hits++
+	return varbinds, address, true
+}
+
+func (c *ResultCache) store(key cacheKey, oid asn1.ObjectIdentifier, varbinds []Varbind, address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &cacheEntry{
+		oid: oid, expires: c.now().Add(c.ttl), varbinds: copyVarbinds(varbinds), address: address,
+	}
+}
+
+func copyVarbinds(varbinds []Varbind) []Varbind {
+	out := make([]Varbind, len(varbinds))
+	for i, vb := range varbinds {
+		out[i] = Varbind{OID: append(asn1.ObjectIdentifier{}, vb.OID...)}
+		if vb.TypedValue != nil {
+			tv := *vb.TypedValue
+			out[i].TypedValue = &tv
+		}
+	}
+	return out
+}
+
+// Delivers the cache keys of the Get request oids, if all are cacheable.
+func (m *sessionImpl) getCacheKeys(oids []string) ([]cacheKey, []asn1.ObjectIdentifier, bool) {
+	if len(oids) == 0 {
+		return nil, nil, false
+	}
+	community := m.community()
+	keys := make([]cacheKey, len(oids))
+	parsed := make([]asn1.ObjectIdentifier, len(oids))
+	for i, oid := range oids {
+		p, ok := m.config.cache.cacheable(oid)
+		if !ok {
+			return nil, nil, false
+		}
+		keys[i], parsed[i] = cacheKey{target: m.addresses[0], community: community, oid: p.String()}, p
+	}
+	return keys, parsed, true
+}
+
+// Serves the Get request from the cache, if all its oids are cacheable and current, otherwise executes the request,
+// caching the results if appropriate.
+func (m *sessionImpl) cachedGet(ctx context.Context, oids []string) (*PDU, error) {
+	keys, parsed, ok := m.getCacheKeys(oids)
+	if !ok {
+		return m.executeGet(ctx, getMessage, oids, 0, 0)
+	}
+	cache := m.config.cache
+	if varbinds, address, hit := cache.lookup(keys); hit {
+		return &PDU{VarbindList: varbinds, Address: address}, nil
+	}
+
+	pdu, err := m.executeGet(ctx, getMessage, oids, 0, 0)
+	if err != nil || pdu.Error != noError || pdu.Truncated || len(pdu.VarbindList) != len(oids) {
+		return pdu, err
+	}
+	for i := range keys {
+		cache.store(keys[i], parsed[i], pdu.VarbindList[i:i+1], pdu.Address)
+	}
+	return pdu, nil
+}
+
+// Serves the walk from the cache, if its root oid is cacheable and the walk is current, otherwise executes the walk,
+// caching the variables delivered to the walker if the walk completes successfully.
+func (m *sessionImpl) cachedWalk(ctx context.Context, mType messageType, maxRepetitions int, rootOid string,
+	walker StatusWalker) error {
+	root, ok := m.config.cache.cacheable(rootOid)
+	if !ok {
+		return m.runWalk(ctx, mType, maxRepetitions, rootOid, walker)
+	}
+	cache := m.config.cache
+	key := cacheKey{target: m.addresses[0], community: m.community(), walk: true, oid: root.String()}
+	if varbinds, _, hit := cache.lookup([]cacheKey{key}); hit {
+		for i := range varbinds {
+			if err := walker(&varbinds[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var delivered []Varbind
+	complete := true
+	err := m.runWalk(ctx, mType, maxRepetitions, rootOid, func(vb *Varbind, status error) error {
+		if status != nil {
+			complete = false
+		} else if vb != nil {
+			delivered = append(delivered, *vb)
+		}
+		return walker(vb, status)
+	})
+	if err == nil && complete {
+		cache.store(key, root, delivered, "")
+	}
+	return err
+}
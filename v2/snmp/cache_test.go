@@ -0,0 +1,139 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const ifInOctets = "1.3.6.1.2.1.2.2.1.10"
+
+// Delivers a session to an agent serving sysDescr, ifDescr and ifInOctets, with a cache for sysDescr and ifDescr,
+// and the number of times the agent has served each object.
+func newCachingTestAgent(t *testing.T) (Session, *ResultCache, map[string]*int32) {
+	counts := map[string]*int32{sysDescr: new(int32), ifEntry + ".2": new(int32), ifInOctets: new(int32)}
+	s := newTestAgentServer(t,
+		ServeScalar(sysDescr, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+			atomic.AddInt32(counts[sysDescr], 1)
+			return &TypedValue{Type: OctetString, Value: []byte("test agent")}, nil
+		}),
+		ServeTable(ifEntry, []int{2, 10}, func() []asn1.ObjectIdentifier {
+			return []asn1.ObjectIdentifier{{1}, {2}}
+		}, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+			if oid[len(oid)-2] == 10 {
+				atomic.AddInt32(counts[ifInOctets], 1)
+				return &TypedValue{Type: Counter32, Value: uint32(100)}, nil
+			}
+			atomic.AddInt32(counts[ifEntry+".2"], 1)
+			return &TypedValue{Type: OctetString, Value: []byte("eth")}, nil
+		}))
+
+	cache, err := NewResultCache(time.Minute, sysDescr, ifEntry+".2")
+	assert.NoError(t, err)
+	return newTestSession(t, s, ResultCaching(cache)), cache, counts
+}
+
+func TestResultCacheGet(t *testing.T) {
+	ses, cache, counts := newCachingTestAgent(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		pdu, err := ses.Get(ctx, []string{sysDescr + ".0"})
+		assert.NoError(t, err)
+		assert.Equal(t, "test agent", pdu.VarbindList[0].TypedValue.String())
+		assert.NotEmpty(t, pdu.Address)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(counts[sysDescr]))
+
+	// Counters bypass the cache, as do requests that include them.
+	for i := 0; i < 2; i++ {
+		_, err := ses.Get(ctx, []string{sysDescr + ".0", ifInOctets + ".1"})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(counts[sysDescr]))
+	assert.Equal(t, int32(2), atomic.LoadInt32(counts[ifInOctets]))
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Entries: 1}, cache.Stats())
+
+	assert.NoError(t, cache.Invalidate(sysDescr))
+	_, err := ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(counts[sysDescr]))
+}
+
+func TestResultCacheExpiry(t *testing.T) {
+	ses, cache, counts := newCachingTestAgent(t)
+	ctx := context.Background()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, err := ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	now = now.Add(59 * time.Second)
+	_, err = ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(counts[sysDescr]))
+
+	now = now.Add(time.Second)
+	_, err = ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(counts[sysDescr]), "Expecting expired result to be fetched")
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, Entries: 1}, cache.Stats())
+}
+
+func TestResultCacheWalk(t *testing.T) {
+	ses, cache, counts := newCachingTestAgent(t)
+	ctx := context.Background()
+
+	walk := func(root string) []string {
+		var values []string
+		assert.NoError(t, ses.BulkWalk(ctx, root, 10, func(vb *Varbind) error {
+			values = append(values, vb.OID.String()+"="+vb.TypedValue.String())
+			return nil
+		}))
+		return values
+	}
+	first := walk(ifEntry + ".2")
+	assert.Equal(t, []string{ifEntry + ".2.1=eth", ifEntry + ".2.2=eth"}, first)
+	served := atomic.LoadInt32(counts[ifEntry+".2"])
+	assert.Equal(t, first, walk(ifEntry+".2"))
+	assert.Equal(t, served, atomic.LoadInt32(counts[ifEntry+".2"]), "Expecting walk to be served from the cache")
+
+	before := atomic.LoadInt32(counts[ifInOctets])
+	walk(ifInOctets)
+	served = atomic.LoadInt32(counts[ifInOctets]) - before
+	walk(ifInOctets)
+	assert.Equal(t, before+2*served, atomic.LoadInt32(counts[ifInOctets]), "Expecting counters to bypass the cache")
+	assert.Equal(t, uint64(1), cache.Stats().Hits)
+
+	// Invalidating an ancestor of the root discards the walk.
+	assert.NoError(t, cache.Invalidate(ifEntry))
+	assert.Equal(t, 0, cache.Stats().Entries)
+	assert.NoError(t, cache.Invalidate())
+	assert.Error(t, cache.Invalidate("1.3.x"))
+}
+
+func TestResultCacheCredentials(t *testing.T) {
+	ses, cache, counts := newCachingTestAgent(t)
+	ctx := context.Background()
+
+	_, err := ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.NoError(t, ses.UpdateCredentials(ctx, WithCommunity("rotated")))
+	for i := 0; i < 2; i++ {
+		_, err = ses.Get(ctx, []string{sysDescr + ".0"})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(counts[sysDescr]), "Expecting the new community to bypass the result of the previous")
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, Entries: 2}, cache.Stats())
+}
+
+func TestInvalidResultCache(t *testing.T) {
+	_, err := NewResultCache(0, sysDescr)
+	assert.EqualError(t, err, "invalid cache ttl 0s")
+	_, err = NewResultCache(time.Minute, "1.3.x")
+	assert.Error(t, err)
+}
@@ -0,0 +1,521 @@
+package snmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Capture of received datagrams in pcapng format (https://datatracker.ietf.org/doc/draft-ietf-opsawg-pcapng/), so
+// that captures can be examined with tools such as Wireshark, and replayed into a handler by ReplayCapture.
+// Each datagram is recorded with the time it was received, and IP and UDP headers synthesised from its source
+// address and the server's local address, using the raw IP link type.
+
+// pcapng block types, and the constants used to define the capture.
+const (
+	pcapngSectionHeader   = 0x0A0D0D0A
+	pcapngInterface       = 0x00000001
+	pcapngEnhancedPacket  = 0x00000006
+	pcapngByteOrderMagic  = 0x1A2B3C4D
+	pcapngLinkTypeRaw     = 101
+	pcapngSnapLen         = maxInputBufferSize
+	pcapngBlockHeaderSize = 8
+	pcapngEPBFixedSize    = 20
+	pcapngIDBFixedSize    = 8
+	// Blocks longer than this are rejected rather than read, as for libpcap.
+	pcapngMaxBlockLen = 16 << 20
+	// The end of options marker, the if_tsresol interface option, the default timestamp resolution of microseconds,
+	// and the finest decimal and binary resolutions supported.
+	pcapngOptEnd          = 0
+	pcapngOptTSResol      = 9
+	pcapngDefaultResol    = 6
+	pcapngMaxDecResol     = 19
+	pcapngMaxBinResol     = 63
+	pcapngBinaryResolFlag = 0x80
+)
+
+// CaptureWriter writes the datagrams received by a server, defined by CapturePackets, to a capture file, rotating the
+// file when it reaches a maximum size.
+// It is safe for concurrent use, so may be shared by several servers.
+type CaptureWriter struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewCaptureWriter delivers a writer that records datagrams to the file at path. Once the file would exceed maxBytes,
+// it is renamed path.1, any existing path.1 is renamed path.2 and so on, retaining at most maxFiles earlier files,
+// and a new file is started; a maxBytes of zero or less disables rotation. An existing file at path is rotated
+// rather than overwritten.
+func NewCaptureWriter(path string, maxBytes int64, maxFiles int) (*CaptureWriter, error) {
+	w := &CaptureWriter{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if fi, err := os.Stat(path); err == nil && fi.Size() > 0 {
+		if err = w.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// CapturePackets records every datagram received by the server, including those subsequently dropped by filters or
+// that cannot be decoded, using w. A failure to record a datagram is reported to the Error hook, and the datagram is
+// processed as usual.
+// Default is no capture.
+func CapturePackets(w *CaptureWriter) ServerOption {
+	return func(c *serverConfig) {
+		c.capture = w
+	}
+}
+
+// WritePacket records the datagram, received at time t from src by the server listening on dst.
+func (w *CaptureWriter) WritePacket(t time.Time, src, dst net.Addr, datagram []byte) error {
+	block := enhancedPacketBlock(t, synthesiseIPPacket(src, dst, datagram))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if w.maxBytes > 0 && w.size > 0 && w.size+int64(len(block)) > w.maxBytes {
+		if err := w.f.Close(); err != nil {
+			return err
+		}
+		w.f = nil
+		if err := w.rotate(); err != nil {
+			return err
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	n, err := w.f.Write(block)
+	w.size += int64(n)
+	return err
+}
+
+// Close closes the capture file.
+func (w *CaptureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+// Creates the capture file, writing the section header and interface description.
+func (w *CaptureWriter) open() error {
+	f, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	header := append(sectionHeaderBlock(), interfaceDescriptionBlock()...)
+	if _, err = f.Write(header); err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f, w.size = f, int64(len(header))
+	return nil
+}
+
+// Renames the capture file, and the earlier files, discarding the oldest.
+func (w *CaptureWriter) rotate() error {
+	if w.maxFiles <= 0 {
+		return os.Remove(w.path)
+	}
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", w.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(w.path, w.path+".1")
+}
+
+func sectionHeaderBlock() []byte {
+	b := pcapngBlock(pcapngSectionHeader, 16)
+	binary.LittleEndian.PutUint32(b[8:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(b[12:], 1)
+	binary.LittleEndian.PutUint16(b[14:], 0)
+	// The section length is not specified.
+	binary.LittleEndian.PutUint64(b[16:], ^uint64(0))
+	return b
+}
+
+func interfaceDescriptionBlock() []byte {
+	b := pcapngBlock(pcapngInterface, 8)
+	binary.LittleEndian.PutUint16(b[8:], pcapngLinkTypeRaw)
+	binary.LittleEndian.PutUint32(b[12:], pcapngSnapLen)
+	return b
+}
+
+func enhancedPacketBlock(t time.Time, packet []byte) []byte {
+	padded := (len(packet) + 3) &^ 3
+	b := pcapngBlock(pcapngEnhancedPacket, pcapngEPBFixedSize+padded)
+	// Timestamps are recorded in microseconds, the default resolution.
+	ts := uint64(t.UnixNano() / int64(time.Microsecond))
+	binary.LittleEndian.PutUint32(b[12:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(b[16:], uint32(ts))
+	binary.LittleEndian.PutUint32(b[20:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(b[24:], uint32(len(packet)))
+	copy(b[28:], packet)
+	return b
+}
+
+// Delivers a block of the type, with the body length, a multiple of 4, filled by the caller.
+func pcapngBlock(blockType uint32, bodyLen int) []byte {
+	total := pcapngBlockHeaderSize + bodyLen + 4
+	b := make([]byte, total)
+	binary.LittleEndian.PutUint32(b, blockType)
+	binary.LittleEndian.PutUint32(b[4:], uint32(total))
+	binary.LittleEndian.PutUint32(b[total-4:], uint32(total))
+	return b
+}
+
+// Delivers the datagram encapsulated in IP and UDP headers. The UDP checksum is omitted.
+func synthesiseIPPacket(src, dst net.Addr, datagram []byte) []byte {
+	srcIP, srcPort := udpEndpoint(src)
+	dstIP, dstPort := udpEndpoint(dst)
+	udpLen := 8 + len(datagram)
+
+	buf := &bytes.Buffer{}
+	if src4 := srcIP.To4(); src4 != nil || srcIP == nil {
+		dst4 := dstIP.To4()
+		if dst4 == nil {
+			dst4 = net.IPv4zero.To4()
+		}
+		if src4 == nil {
+			src4 = net.IPv4zero.To4()
+		}
+		hdr := make([]byte, 20)
+		hdr[0] = 0x45
+		binary.BigEndian.PutUint16(hdr[2:], uint16(20+udpLen))
+		hdr[8] = 64
+		hdr[9] = 17
+		copy(hdr[12:], src4)
+		copy(hdr[16:], dst4)
+		binary.BigEndian.PutUint16(hdr[10:], ipv4Checksum(hdr))
+		buf.Write(hdr)
+	} else {
+		dst6 := dstIP.To16()
+		if dst6 == nil {
+			dst6 = net.IPv6unspecified
+		}
+		hdr := make([]byte, 40)
+		hdr[0] = 0x60
+		binary.BigEndian.PutUint16(hdr[4:], uint16(udpLen))
+		hdr[6] = 17
+		hdr[7] = 64
+		copy(hdr[8:], srcIP.To16())
+		copy(hdr[24:], dst6)
+		buf.Write(hdr)
+	}
+
+	udp := make([]byte, 8)
+	binary.BigEndian.PutUint16(udp, uint16(srcPort))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dstPort))
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpLen))
+	buf.Write(udp)
+	buf.Write(datagram)
+	return buf.Bytes()
+}
+
+func udpEndpoint(addr net.Addr) (net.IP, int) {
+	if ua, ok := addr.(*net.UDPAddr); ok && ua != nil {
+		return ua.IP, ua.Port
+	}
+	return nil, 0
+}
+
+func ipv4Checksum(hdr []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(hdr); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(hdr[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// CapturedPacket defines a datagram read from a capture by a CaptureReader.
+type CapturedPacket struct {
+	// The time at which the datagram was received.
+	Time time.Time
+	// The address from which the datagram was sent, and the address of the server that received it.
+	Source      *net.UDPAddr
+	Destination *net.UDPAddr
+	// The content of the datagram.
+	Data []byte
+}
+
+// CaptureReader reads the datagrams recorded in a pcapng capture, such as one written by a CaptureWriter.
+// Only captures with the raw IP link type, holding UDP datagrams, are supported; other packets are skipped.
+type CaptureReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	// The interfaces of the current section.
+	interfaces []captureInterface
+}
+
+// Defines an interface described by a capture.
+type captureInterface struct {
+	// Indicates whether the interface records raw IP packets.
+	rawIP bool
+	// The resolution of the packet timestamps, as defined by the if_tsresol option.
+	tsresol byte
+}
+
+// NewCaptureReader delivers a reader of the capture read from r.
+func NewCaptureReader(r io.Reader) *CaptureReader {
+	return &CaptureReader{r: r}
+}
+
+// Next delivers the next datagram in the capture, or io.EOF at the end of the capture.
+func (cr *CaptureReader) Next() (*CapturedPacket, error) {
+	for {
+		blockType, body, err := cr.readBlock()
+		if err != nil {
+			return nil, err
+		}
+		switch blockType {
+		case pcapngInterface:
+			ci, err := cr.parseInterface(body)
+			if err != nil {
+				return nil, err
+			}
+			cr.interfaces = append(cr.interfaces, ci)
+		case pcapngEnhancedPacket:
+			if len(body) < pcapngEPBFixedSize {
+				return nil, errors.New("invalid pcapng enhanced packet block")
+			}
+			id := int(cr.order.Uint32(body))
+			if id >= len(cr.interfaces) || !cr.interfaces[id].rawIP {
+				continue
+			}
+			capLen := int(cr.order.Uint32(body[12:]))
+			if pcapngEPBFixedSize+capLen > len(body) {
+				return nil, errors.New("invalid pcapng packet length")
+			}
+			ts := uint64(cr.order.Uint32(body[4:]))<<32 | uint64(cr.order.Uint32(body[8:]))
+			pkt, ok := parseUDPPacket(body[pcapngEPBFixedSize : pcapngEPBFixedSize+capLen])
+			if !ok {
+				continue
+			}
+			pkt.Time = timestamp(ts, cr.interfaces[id].tsresol)
+			return pkt, nil
+		}
+	}
+}
+
+// Decodes the body of an interface description block.
+func (cr *CaptureReader) parseInterface(body []byte) (captureInterface, error) {
+	if len(body) < pcapngIDBFixedSize {
+		return captureInterface{}, errors.New("invalid pcapng interface description block")
+	}
+	ci := captureInterface{rawIP: cr.order.Uint16(body) == pcapngLinkTypeRaw, tsresol: pcapngDefaultResol}
+	for opts := body[pcapngIDBFixedSize:]; len(opts) >= 4; {
+		code, length := cr.order.Uint16(opts), int(cr.order.Uint16(opts[2:]))
+		if code == pcapngOptEnd {
+			break
+		}
+		padded := (length + 3) &^ 3
+		if 4+padded > len(opts) {
+			return captureInterface{}, errors.New("invalid pcapng interface option length")
+		}
+		if code == pcapngOptTSResol && length == 1 {
+			ci.tsresol = opts[4]
+			if base2 := ci.tsresol&pcapngBinaryResolFlag != 0; (!base2 && ci.tsresol > pcapngMaxDecResol) ||
+				(base2 && ci.tsresol&^pcapngBinaryResolFlag > pcapngMaxBinResol) {
+				return captureInterface{}, errors.Errorf("unsupported pcapng timestamp resolution %#x", ci.tsresol)
+			}
+		}
+		opts = opts[4+padded:]
+	}
+	return ci, nil
+}
+
+// Delivers the time of the timestamp, recorded in units of the resolution: a negative power of 10, or a negative
+// power of 2 if the most significant bit is set.
+func timestamp(ts uint64, tsresol byte) time.Time {
+	if tsresol&pcapngBinaryResolFlag != 0 {
+		exp := uint(tsresol &^ pcapngBinaryResolFlag)
+		frac := ts & (1<<exp - 1)
+		hi, lo := bits.Mul64(frac, uint64(time.Second))
+		nanos, _ := bits.Div64(hi, lo, 1<<exp)
+		return time.Unix(int64(ts>>exp), int64(nanos))
+	}
+	unit := uint64(1)
+	for i := byte(0); i < tsresol; i++ {
+		unit *= 10
+	}
+	frac := ts % unit
+	if unit <= uint64(time.Second) {
+		frac *= uint64(time.Second) / unit
+	} else {
+		frac /= unit / uint64(time.Second)
+	}
+	return time.Unix(int64(ts/unit), int64(frac))
+}
+
+// Reads the next block, delivering its type and body.
+func (cr *CaptureReader) readBlock() (uint32, []byte, error) {
+	var hdr [pcapngBlockHeaderSize]byte
+	if _, err := io.ReadFull(cr.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated pcapng block")
+		}
+		return 0, nil, err
+	}
+
+	order := cr.order
+	if binary.LittleEndian.Uint32(hdr[:]) == pcapngSectionHeader {
+		// The byte order of a section is defined by its header, so is determined before its length is decoded.
+		var magic [4]byte
+		if _, err := io.ReadFull(cr.r, magic[:]); err != nil {
+			return 0, nil, errors.New("truncated pcapng section header")
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic[:]) == pcapngByteOrderMagic:
+			order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic[:]) == pcapngByteOrderMagic:
+			order = binary.BigEndian
+		default:
+			return 0, nil, errors.New("invalid pcapng byte order magic")
+		}
+		cr.order, cr.interfaces = order, nil
+		body, err := cr.readBody(order.Uint32(hdr[4:]), 4)
+		return pcapngSectionHeader, append(magic[:], body...), err
+	}
+	if order == nil {
+		return 0, nil, errors.New("capture is not in pcapng format")
+	}
+	body, err := cr.readBody(order.Uint32(hdr[4:]), 0)
+	return order.Uint32(hdr[:]), body, err
+}
+
+// Reads the remainder of a block of the total length, of which consumed bytes of the body have been read, checking
+// the trailing length.
+func (cr *CaptureReader) readBody(total uint32, consumed int) ([]byte, error) {
+	if total%4 != 0 || total < pcapngBlockHeaderSize+4+uint32(consumed) {
+		return nil, errors.Errorf("invalid pcapng block length %d", total)
+	}
+	if total > pcapngMaxBlockLen {
+		return nil, errors.Errorf("pcapng block length %d exceeds the maximum of %d", total, pcapngMaxBlockLen)
+	}
+	rest := make([]byte, int(total)-pcapngBlockHeaderSize-consumed)
+	if _, err := io.ReadFull(cr.r, rest); err != nil {
+		return nil, errors.New("truncated pcapng block")
+	}
+	if cr.order.Uint32(rest[len(rest)-4:]) != total {
+		return nil, errors.New("pcapng block length mismatch")
+	}
+	return rest[:len(rest)-4], nil
+}
+
+// Decodes the IP and UDP headers of the packet, delivering the addresses and datagram, or false if the packet is not
+// a UDP datagram.
+func parseUDPPacket(b []byte) (*CapturedPacket, bool) {
+	if len(b) == 0 {
+		return nil, false
+	}
+	var src, dst net.IP
+	var udp []byte
+	switch b[0] >> 4 {
+	case 4:
+		ihl := int(b[0]&0x0f) * 4
+		if len(b) < ihl+8 || ihl < 20 || b[9] != 17 {
+			return nil, false
+		}
+		src, dst, udp = net.IP(b[12:16]), net.IP(b[16:20]), b[ihl:]
+	case 6:
+		if len(b) < 48 || b[6] != 17 {
+			return nil, false
+		}
+		src, dst, udp = net.IP(b[8:24]), net.IP(b[24:40]), b[40:]
+	default:
+		return nil, false
+	}
+	udpLen := int(binary.BigEndian.Uint16(udp[4:]))
+	if udpLen < 8 || udpLen > len(udp) {
+		return nil, false
+	}
+	return &CapturedPacket{
+		Source:      &net.UDPAddr{IP: append(net.IP{}, src...), Port: int(binary.BigEndian.Uint16(udp))},
+		Destination: &net.UDPAddr{IP: append(net.IP{}, dst...), Port: int(binary.BigEndian.Uint16(udp[2:]))},
+		Data:        append([]byte{}, udp[8:udpLen]...),
+	}, true
+}
+
+// ReplayCapture delivers the traps and informs recorded in the pcapng capture read from r to the handler, as if they
+// had been received by a server configured by opts, applying its filters, trap enrichment and duplicate suppression.
+// Datagrams are delivered without delay, in the order they were captured, and are reported to the server hooks;
+// no responses, such as inform acknowledgements, are sent. The network, address and port options are ignored.
+// The windows opened by SuppressDuplicateTraps are closed, and the repeated traps delivered, before returning.
+func ReplayCapture(r io.Reader, handler Handler, opts ...ServerOption) error {
+	config, err := newServerConfig(opts)
+	if err != nil {
+		return err
+	}
+	s := &serverImpl{config: config, handler: handler, conn: &replayConn{reader: NewCaptureReader(r)}}
+	if err = s.serve(nil); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// A connection that delivers the datagrams read from a capture, discarding any messages written.
+type replayConn struct {
+	reader *CaptureReader
+	local  net.Addr
+}
+
+func (c *replayConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	pkt, err := c.reader.Next()
+	if err != nil {
+		return 0, nil, err
+	}
+	c.local = pkt.Destination
+	return copy(p, pkt.Data), pkt.Source, nil
+}
+
+func (c *replayConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return len(p), nil
+}
+
+func (c *replayConn) Close() error {
+	return nil
+}
+
+func (c *replayConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *replayConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *replayConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
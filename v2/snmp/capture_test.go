@@ -0,0 +1,209 @@
+package snmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func (h *collectingTrapHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.traps) + len(h.pdus)
+}
+
+func readCapture(t *testing.T, path string) []*CapturedPacket {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var packets []*CapturedPacket
+	cr := NewCaptureReader(f)
+	for {
+		pkt, err := cr.Next()
+		if err == io.EOF {
+			return packets
+		}
+		assert.NoError(t, err)
+		packets = append(packets, pkt)
+	}
+}
+
+func TestCaptureAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traps.pcapng")
+	w, err := NewCaptureWriter(path, 0, 0)
+	assert.NoError(t, err)
+
+	h := &collectingTrapHandler{}
+	s, err := NewServerFactory().NewServer(context.Background(), h, Address("127.0.0.1"), Port(0), CapturePackets(w))
+	assert.NoError(t, err)
+	serverAddr := s.(*serverImpl).conn.LocalAddr().(*net.UDPAddr)
+
+	client, err := net.DialUDP("udp", nil, serverAddr)
+	assert.NoError(t, err)
+	defer client.Close()
+	before := time.Now()
+	_, err = client.Write(messageWithType(v2Trap))
+	assert.NoError(t, err)
+	_, err = client.Write(v1TrapMessage(0, 0))
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return h.count() == 2 }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, s.Close())
+	assert.NoError(t, w.Close())
+
+	packets := readCapture(t, path)
+	assert.Len(t, packets, 2)
+	assert.Equal(t, messageWithType(v2Trap), packets[0].Data)
+	assert.Equal(t, v1TrapMessage(0, 0), packets[1].Data)
+	clientAddr := client.LocalAddr().(*net.UDPAddr)
+	assert.True(t, clientAddr.IP.Equal(packets[0].Source.IP))
+	assert.Equal(t, clientAddr.Port, packets[0].Source.Port)
+	assert.Equal(t, serverAddr.Port, packets[0].Destination.Port)
+	assert.False(t, packets[0].Time.Before(before.Truncate(time.Microsecond)))
+
+	// Replay the capture into a new handler.
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	replayed := &collectingTrapHandler{}
+	assert.NoError(t, ReplayCapture(f, replayed))
+	assert.Len(t, replayed.traps, 2)
+	assert.Equal(t, clientAddr.Port, replayed.traps[0].SourceAddress.(*net.UDPAddr).Port)
+	assert.Equal(t, h.traps[0].Varbinds, replayed.traps[0].Varbinds)
+}
+
+func TestCaptureRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traps.pcapng")
+	// Holds the headers and two packets.
+	w, err := NewCaptureWriter(path, 360, 2)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	src := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1024}
+	dst := &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 162}
+	message := messageWithType(v2Trap)
+	for i := 0; i < 7; i++ {
+		message[len(message)-1] = byte(i)
+		assert.NoError(t, w.WritePacket(time.Now(), src, dst, message))
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Len(t, readCapture(t, path), 1)
+	assert.Len(t, readCapture(t, path+".1"), 2)
+	older := readCapture(t, path+".2")
+	assert.Len(t, older, 2)
+	assert.Equal(t, byte(2), older[0].Data[len(message)-1])
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	// An existing capture is rotated when the writer is created.
+	w, err = NewCaptureWriter(path, 360, 2)
+	assert.NoError(t, err)
+	assert.Len(t, readCapture(t, path+".1"), 1)
+	assert.Empty(t, readCapture(t, path))
+}
+
+func TestCaptureIPv6(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.Write(sectionHeaderBlock())
+	buf.Write(interfaceDescriptionBlock())
+	src := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 5000}
+	dst := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 162}
+	at := time.Unix(1700000000, 123456000)
+	buf.Write(enhancedPacketBlock(at, synthesiseIPPacket(src, dst, []byte{1, 2, 3})))
+
+	pkt, err := NewCaptureReader(buf).Next()
+	assert.NoError(t, err)
+	assert.Equal(t, src.String(), pkt.Source.String())
+	assert.Equal(t, dst.String(), pkt.Destination.String())
+	assert.Equal(t, []byte{1, 2, 3}, pkt.Data)
+	assert.True(t, at.Equal(pkt.Time))
+}
+
+func TestCaptureReaderInvalid(t *testing.T) {
+	_, err := NewCaptureReader(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})).Next()
+	assert.EqualError(t, err, "capture is not in pcapng format")
+
+	truncated := append(sectionHeaderBlock(), interfaceDescriptionBlock()[:10]...)
+	_, err = NewCaptureReader(bytes.NewReader(truncated)).Next()
+	assert.EqualError(t, err, "truncated pcapng block")
+}
+
+// Delivers an interface description block for raw IP packets, with the if_tsresol option.
+func interfaceDescriptionBlockWithResolution(tsresol byte) []byte {
+	b := pcapngBlock(pcapngInterface, pcapngIDBFixedSize+12)
+	copy(b[pcapngBlockHeaderSize:], interfaceDescriptionBlock()[pcapngBlockHeaderSize:pcapngBlockHeaderSize+pcapngIDBFixedSize])
+	opts := b[pcapngBlockHeaderSize+pcapngIDBFixedSize:]
+	binary.LittleEndian.PutUint16(opts, pcapngOptTSResol)
+	binary.LittleEndian.PutUint16(opts[2:], 1)
+	opts[4] = tsresol
+	return b
+}
+
+// Delivers an enhanced packet block recording the timestamp, in units of the interface resolution.
+func enhancedPacketBlockWithTimestamp(ts uint64, packet []byte) []byte {
+	b := enhancedPacketBlock(time.Time{}, packet)
+	binary.LittleEndian.PutUint32(b[12:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(b[16:], uint32(ts))
+	return b
+}
+
+func TestCaptureTimestampResolution(t *testing.T) {
+	packet := synthesiseIPPacket(&net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1024},
+		&net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 162}, []byte{1})
+	for _, tc := range []struct {
+		tsresol  byte
+		ts       uint64
+		expected time.Time
+	}{
+		{9, 1700000000123456789, time.Unix(1700000000, 123456789)},
+		{3, 1700000000123, time.Unix(1700000000, 123000000)},
+		{12, 1700000000123456789, time.Unix(1700000, 123456)},
+		{0x80 | 10, 5<<10 | 512, time.Unix(5, 500000000)},
+	} {
+		buf := &bytes.Buffer{}
+		buf.Write(sectionHeaderBlock())
+		buf.Write(interfaceDescriptionBlockWithResolution(tc.tsresol))
+		buf.Write(enhancedPacketBlockWithTimestamp(tc.ts, packet))
+
+		pkt, err := NewCaptureReader(buf).Next()
+		assert.NoError(t, err)
+		assert.True(t, tc.expected.Equal(pkt.Time), "resolution %#x: expecting %s, found %s", tc.tsresol, tc.expected, pkt.Time)
+	}
+
+	invalid := append(sectionHeaderBlock(), interfaceDescriptionBlockWithResolution(20)...)
+	_, err := NewCaptureReader(bytes.NewReader(invalid)).Next()
+	assert.EqualError(t, err, "unsupported pcapng timestamp resolution 0x14")
+}
+
+func TestCaptureReaderBlockLimit(t *testing.T) {
+	hdr := make([]byte, pcapngBlockHeaderSize)
+	binary.LittleEndian.PutUint32(hdr, pcapngEnhancedPacket)
+	binary.LittleEndian.PutUint32(hdr[4:], pcapngMaxBlockLen+4)
+	_, err := NewCaptureReader(bytes.NewReader(append(sectionHeaderBlock(), hdr...))).Next()
+	assert.EqualError(t, err, "pcapng block length 16777220 exceeds the maximum of 16777216")
+}
+
+func TestReplayCaptureFlushesDuplicates(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.Write(sectionHeaderBlock())
+	buf.Write(interfaceDescriptionBlock())
+	src := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1024}
+	dst := &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 162}
+	for i := 0; i < 3; i++ {
+		buf.Write(enhancedPacketBlock(time.Now(), synthesiseIPPacket(src, dst, messageWithType(v2Trap))))
+	}
+
+	h := &collectingTrapHandler{}
+	assert.NoError(t, ReplayCapture(buf, h, SuppressDuplicateTraps(time.Hour)))
+	assert.Len(t, h.traps, 2)
+	assert.Equal(t, 2, h.traps[1].Repeats)
+}
@@ -0,0 +1,154 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Setter defines the SNMP requests used by a ConfigSetter, which are implemented by Session.
+type Setter interface {
+	// Issues an SNMP GET request for the specified oids.
+	Get(ctx context.Context, oids []string) (*PDU, error)
+	// Issues an SNMP SET request assigning the values of the variable bindings.
+	Set(ctx context.Context, varbinds []Varbind) (*PDU, error)
+}
+
+// ConfigSetter applies an ordered list of Set operations, each holding the variable bindings assigned by a single
+// SET request, and rolls back the operations already applied if a later operation fails, in the manner of a
+// NETCONF transaction.
+// Before each operation is applied, the current values of its variables are retrieved by a GET request and recorded
+// in a journal; on failure, the recorded values are restored by SET requests, in the reverse order of the operations,
+// including those of the failed operation unless the agent rejected it with an error-status.
+// Variables that did not exist before an operation, reported by a noSuchObject or noSuchInstance exception, cannot
+// be restored, and are omitted from the rollback.
+type ConfigSetter struct {
+	s          Setter
+	operations [][]Varbind
+	journal    [][]Varbind
+}
+
+// ConfigSetError is returned by ConfigSetter.Apply when an operation fails.
+type ConfigSetError struct {
+	// The index of the operation that failed.
+	Operation int
+	// The cause of the failure; a *VarbindError if the agent reported an error-status.
+	Err error
+	// The error encountered restoring the prior values of the operations already applied, or nil if they were
+	// restored.
+	RollbackErr error
+}
+
+func (e *ConfigSetError) Error() string {
+	msg := fmt.Sprintf("set operation %d failed: %s", e.Operation, e.Err)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf("; rollback failed: %s", e.RollbackErr)
+	}
+	return msg
+}
+
+func (e *ConfigSetError) Unwrap() error {
+	return e.Err
+}
+
+// NewConfigSetter delivers a ConfigSetter that issues requests using s.
+func NewConfigSetter(s Setter) *ConfigSetter {
+	return &ConfigSetter{s: s}
+}
+
+// Add appends an operation assigning the values of the variable bindings in a single SET request.
+func (c *ConfigSetter) Add(varbinds ...Varbind) *ConfigSetter {
+	c.operations = append(c.operations, varbinds)
+	return c
+}
+
+// Apply applies the operations in order, returning a *ConfigSetError if an operation fails, once the operations
+// already applied have been rolled back.
+func (c *ConfigSetter) Apply(ctx context.Context) error {
+	c.journal = nil
+	for i, op := range c.operations {
+		prior, err := c.record(ctx, op)
+		if err != nil {
+			return &ConfigSetError{Operation: i, Err: err, RollbackErr: c.rollback(ctx)}
+		}
+		// The prior values are journalled before the operation is applied, so that it is rolled back if it fails
+		// after the agent may have applied it, such as when the response is lost.
+		c.journal = append(c.journal, prior)
+		if err := c.set(ctx, op); err != nil {
+			// An agent that reports an error-status has assigned none of the variables.
+			var verr *VarbindError
+			if errors.As(err, &verr) {
+				c.journal = c.journal[:i]
+			}
+			return &ConfigSetError{Operation: i, Err: err, RollbackErr: c.rollback(ctx)}
+		}
+	}
+	return nil
+}
+
+// Journal delivers the prior values recorded for each of the operations applied by the last call to Apply, in order.
+// Operations that were rolled back are not included.
+func (c *ConfigSetter) Journal() [][]Varbind {
+	return c.journal
+}
+
+// Retrieves the current values of the variables assigned by the operation.
+func (c *ConfigSetter) record(ctx context.Context, op []Varbind) ([]Varbind, error) {
+	oids := make([]string, len(op))
+	for i := range op {
+		oids[i] = op[i].OID.String()
+	}
+	pdu, err := c.s.Get(ctx, oids)
+	if err != nil {
+		return nil, err
+	}
+	if err := statusError(pdu); err != nil {
+		return nil, err
+	}
+	if len(pdu.VarbindList) != len(op) {
+		return nil, fmt.Errorf("get of prior values returned %d of %d variables", len(pdu.VarbindList), len(op))
+	}
+	return pdu.VarbindList, nil
+}
+
+func (c *ConfigSetter) set(ctx context.Context, varbinds []Varbind) error {
+	pdu, err := c.s.Set(ctx, varbinds)
+	if err != nil {
+		return err
+	}
+	return statusError(pdu)
+}
+
+// Restores the prior values recorded in the journal, in the reverse order of the operations, stopping at the first
+// failure.
+func (c *ConfigSetter) rollback(ctx context.Context) error {
+	for i := len(c.journal) - 1; i >= 0; i-- {
+		var restore []Varbind
+		for _, vb := range c.journal[i] {
+			if vb.TypedValue != nil && !vb.TypedValue.isException() {
+				restore = append(restore, vb)
+			}
+		}
+		if len(restore) == 0 {
+			c.journal = c.journal[:i]
+			continue
+		}
+		if err := c.set(ctx, restore); err != nil {
+			return fmt.Errorf("restoring operation %d: %w", i, err)
+		}
+		c.journal = c.journal[:i]
+	}
+	return nil
+}
+
+// Delivers a *VarbindError if the response reports an error-status.
+func statusError(pdu *PDU) error {
+	if pdu.Error == int(NoError) {
+		return nil
+	}
+	verr := &VarbindError{Status: ErrorStatus(pdu.Error), Index: pdu.ErrorIndex}
+	if i := pdu.ErrorIndex - 1; i >= 0 && i < len(pdu.VarbindList) {
+		verr.OID = pdu.VarbindList[i].OID
+	}
+	return verr
+}
@@ -0,0 +1,206 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Holds variable values, rejecting sets of the variables in fail, keyed by oid, or by oid and value, and failing
+// sets of the variables in lost, keyed by oid, once they are assigned.
+type fakeSetter struct {
+	values map[string]*TypedValue
+	fail   map[string]ErrorStatus
+	lost   map[string]bool
+	sets   [][]Varbind
+}
+
+func newFakeSetter() *fakeSetter {
+	return &fakeSetter{
+		values: map[string]*TypedValue{
+			"1.3.6.1.2.1.1.5.0": {Type: OctetString, Value: []byte("host")},
+			"1.3.6.1.2.1.1.6.0": {Type: OctetString, Value: []byte("lab")},
+			"1.3.6.1.2.1.1.4.0": {Type: OctetString, Value: []byte("ops")},
+		},
+		fail: map[string]ErrorStatus{},
+		lost: map[string]bool{},
+	}
+}
+
+func (f *fakeSetter) Get(ctx context.Context, oids []string) (*PDU, error) {
+	pdu := &PDU{}
+	for _, oid := range oids {
+		tv, ok := f.values[oid]
+		if !ok {
+			tv = &TypedValue{Type: NoSuchInstance}
+		}
+		pdu.VarbindList = append(pdu.VarbindList, Varbind{OID: mustParseOID(oid), TypedValue: tv})
+	}
+	return pdu, nil
+}
+
+func (f *fakeSetter) Set(ctx context.Context, varbinds []Varbind) (*PDU, error) {
+	f.sets = append(f.sets, varbinds)
+	for i, vb := range varbinds {
+		status, ok := f.fail[vb.OID.String()]
+		if !ok {
+			status, ok = f.fail[vb.OID.String()+"="+vb.TypedValue.String()]
+		}
+		if ok {
+			return &PDU{Error: int(status), ErrorIndex: i + 1, VarbindList: varbinds}, nil
+		}
+	}
+	lost := false
+	for _, vb := range varbinds {
+		f.values[vb.OID.String()] = vb.TypedValue
+		lost = lost || f.lost[vb.OID.String()]
+	}
+	if lost {
+		delete(f.lost, varbinds[0].OID.String())
+		return nil, errors.New("request timeout")
+	}
+	return &PDU{VarbindList: varbinds}, nil
+}
+
+func mustParseOID(oid string) []int {
+	parsed, err := parseOID(oid)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func stringVarbind(oid, value string) Varbind {
+	return Varbind{OID: mustParseOID(oid), TypedValue: &TypedValue{Type: OctetString, Value: []byte(value)}}
+}
+
+func TestConfigSetterApply(t *testing.T) {
+	f := newFakeSetter()
+	cs := NewConfigSetter(f).
+		Add(stringVarbind("1.3.6.1.2.1.1.5.0", "router")).
+		Add(stringVarbind("1.3.6.1.2.1.1.6.0", "dc1"), stringVarbind("1.3.6.1.2.1.1.4.0", "noc"))
+
+	assert.NoError(t, cs.Apply(context.Background()))
+	assert.Equal(t, "router", f.values["1.3.6.1.2.1.1.5.0"].String())
+	assert.Equal(t, "dc1", f.values["1.3.6.1.2.1.1.6.0"].String())
+	assert.Equal(t, "noc", f.values["1.3.6.1.2.1.1.4.0"].String())
+
+	journal := cs.Journal()
+	assert.Len(t, journal, 2)
+	assert.Equal(t, "host", journal[0][0].TypedValue.String())
+	assert.Equal(t, "lab", journal[1][0].TypedValue.String())
+	assert.Equal(t, "ops", journal[1][1].TypedValue.String())
+}
+
+func TestConfigSetterRollback(t *testing.T) {
+	f := newFakeSetter()
+	f.fail["1.3.6.1.2.1.1.4.0"] = NotWritable
+	cs := NewConfigSetter(f).
+		Add(stringVarbind("1.3.6.1.2.1.1.5.0", "router")).
+		Add(stringVarbind("1.3.6.1.2.1.1.6.0", "dc1")).
+		Add(stringVarbind("1.3.6.1.2.1.1.4.0", "noc"))
+
+	err := cs.Apply(context.Background())
+	var cerr *ConfigSetError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, 2, cerr.Operation)
+	assert.NoError(t, cerr.RollbackErr)
+	var verr *VarbindError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, NotWritable, verr.Status)
+	assert.Equal(t, "1.3.6.1.2.1.1.4.0", verr.OID.String())
+
+	// The applied operations are restored in reverse order.
+	assert.Equal(t, "host", f.values["1.3.6.1.2.1.1.5.0"].String())
+	assert.Equal(t, "lab", f.values["1.3.6.1.2.1.1.6.0"].String())
+	assert.Len(t, f.sets, 5)
+	assert.Equal(t, "1.3.6.1.2.1.1.6.0", f.sets[3][0].OID.String())
+	assert.Equal(t, "1.3.6.1.2.1.1.5.0", f.sets[4][0].OID.String())
+	assert.Empty(t, cs.Journal())
+}
+
+func TestConfigSetterRollbackLostResponse(t *testing.T) {
+	f := newFakeSetter()
+	f.lost["1.3.6.1.2.1.1.6.0"] = true
+	cs := NewConfigSetter(f).
+		Add(stringVarbind("1.3.6.1.2.1.1.5.0", "router")).
+		Add(stringVarbind("1.3.6.1.2.1.1.6.0", "dc1"))
+
+	err := cs.Apply(context.Background())
+	var cerr *ConfigSetError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, 1, cerr.Operation)
+	assert.NoError(t, cerr.RollbackErr)
+
+	// The operation that failed may have been applied, so is restored along with the operations before it.
+	assert.Equal(t, "host", f.values["1.3.6.1.2.1.1.5.0"].String())
+	assert.Equal(t, "lab", f.values["1.3.6.1.2.1.1.6.0"].String())
+	assert.Len(t, f.sets, 4)
+	assert.Equal(t, "1.3.6.1.2.1.1.6.0", f.sets[2][0].OID.String())
+	assert.Empty(t, cs.Journal())
+}
+
+func TestConfigSetterRollbackSkipsMissingVariables(t *testing.T) {
+	f := newFakeSetter()
+	f.fail["1.3.6.1.2.1.1.4.0"] = CommitFailed
+	cs := NewConfigSetter(f).
+		Add(stringVarbind("1.3.6.1.2.1.1.5.0", "router"), stringVarbind("1.3.6.1.4.1.1.1.0", "new")).
+		Add(stringVarbind("1.3.6.1.2.1.1.4.0", "noc"))
+
+	err := cs.Apply(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, "host", f.values["1.3.6.1.2.1.1.5.0"].String())
+	// The variable created by the first operation is not restored.
+	assert.Equal(t, "new", f.values["1.3.6.1.4.1.1.1.0"].String())
+	assert.Len(t, f.sets[len(f.sets)-1], 1)
+}
+
+func TestConfigSetterRollbackFailure(t *testing.T) {
+	f := newFakeSetter()
+	f.fail["1.3.6.1.2.1.1.4.0"] = NotWritable
+	f.fail["1.3.6.1.2.1.1.5.0=host"] = ResourceUnavailable
+	cs := NewConfigSetter(f).
+		Add(stringVarbind("1.3.6.1.2.1.1.5.0", "router")).
+		Add(stringVarbind("1.3.6.1.2.1.1.6.0", "dc1")).
+		Add(stringVarbind("1.3.6.1.2.1.1.4.0", "noc"))
+
+	err := cs.Apply(context.Background())
+	var cerr *ConfigSetError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, 2, cerr.Operation)
+	var verr *VarbindError
+	assert.True(t, errors.As(cerr.RollbackErr, &verr))
+	assert.Equal(t, ResourceUnavailable, verr.Status)
+	assert.Contains(t, err.Error(), "rollback failed: restoring operation 0")
+
+	// The operation that could not be restored remains in the journal.
+	assert.Equal(t, "lab", f.values["1.3.6.1.2.1.1.6.0"].String())
+	assert.Len(t, cs.Journal(), 1)
+	assert.Equal(t, "host", cs.Journal()[0][0].TypedValue.String())
+}
+
+func TestConfigSetterSession(t *testing.T) {
+	values := map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}
+	ses := newTestSession(t, newWritableTestAgent(t, values))
+
+	cs := NewConfigSetter(ses).
+		Add(stringVarbind(sysContact+".0", "noc")).
+		Add(stringVarbind(sysLocation+".0", "fail"))
+
+	err := cs.Apply(context.Background())
+	var cerr *ConfigSetError
+	assert.True(t, errors.As(err, &cerr))
+	assert.Equal(t, 1, cerr.Operation)
+	assert.NoError(t, cerr.RollbackErr)
+	var verr *VarbindError
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, CommitFailed, verr.Status)
+	assert.Equal(t, map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}, values,
+		"Expecting the first operation to have been rolled back")
+
+	cs = NewConfigSetter(ses).Add(stringVarbind(sysContact+".0", "noc"))
+	assert.NoError(t, cs.Apply(context.Background()))
+	assert.Equal(t, "noc", values[sysContact+".0"])
+}
@@ -0,0 +1,85 @@
+package snmp
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// CredentialOption implements options for UpdateCredentials.
+type CredentialOption func(*credentialUpdate)
+
+// WithCommunity defines the community string used for subsequent requests.
+func WithCommunity(value string) CredentialOption {
+	return func(u *credentialUpdate) {
+		u.community = &value
+	}
+}
+
+// VerifyCredentials issues a Get for the oids with the new credentials before they are adopted, restoring the
+// previous credentials if the Get fails or the agent reports an error-status. If no oids are specified, sysUpTime.0
+// is retrieved.
+func VerifyCredentials(oids ...string) CredentialOption {
+	return func(u *credentialUpdate) {
+		if len(oids) == 0 {
+			oids = []string{sysUpTimeInstance}
+		}
+		u.verify = oids
+	}
+}
+
+// The oid of the sysUpTime instance, retrieved by default to verify credentials.
+const sysUpTimeInstance = "1.3.6.1.2.1.1.3.0"
+
+type credentialUpdate struct {
+	// The new community, nil if unchanged.
+	community *string
+	// The oids retrieved to verify the credentials, nil if not verified.
+	verify []string
+}
+
+// UpdateCredentials replaces the credentials used for subsequent requests on the session, so that credentials may
+// be rotated without re-establishing the session, and losing its health, latency and cache state.
+// Requests in progress complete with the previous credentials. If VerifyCredentials is specified, other requests
+// wait for the verification to complete, and are then issued with the credentials that are in effect; if the
+// verification fails, the previous credentials are restored and the error is returned.
+func (m *sessionImpl) UpdateCredentials(ctx context.Context, opts ...CredentialOption) error {
+	u := &credentialUpdate{}
+	for _, opt := range opts {
+		opt(u)
+	}
+	if u.community == nil {
+		return errors.New("no credentials to update")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous := m.community()
+	m.setCommunity(*u.community)
+	if u.verify == nil {
+		return nil
+	}
+
+	pdu, err := m.execute(ctx, getMessage, u.verify, nil, 0, 0)
+	if err == nil && pdu.Error != noError {
+		_, err = responseStatus(pdu, "")
+	}
+	if err != nil {
+		m.setCommunity(previous)
+		return errors.Wrap(err, "failed to verify credentials")
+	}
+	return nil
+}
+
+// Delivers the community used for requests; unlike the requests, the result cache reads it without holding mu.
+func (m *sessionImpl) community() string {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	return m.config.community
+}
+
+func (m *sessionImpl) setCommunity(community string) {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	m.config.community = community
+}
@@ -0,0 +1,51 @@
+package snmp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func newCommunityTestSession(t *testing.T, community string) Session {
+	s := newTestAgentServer(t, append([]ServerOption{AllowCommunities([]string{"rotated"})}, testAgentObjects...)...)
+	return newTestSession(t, s, Timeout(200*time.Millisecond), Community(community))
+}
+
+func TestUpdateCredentials(t *testing.T) {
+	ses := newCommunityTestSession(t, "public")
+	ctx := context.Background()
+
+	_, err := ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.Error(t, err, "Expecting the agent to ignore the initial community")
+
+	assert.NoError(t, ses.UpdateCredentials(ctx, WithCommunity("rotated")))
+	pdu, err := ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test agent", string(pdu.VarbindList[0].TypedValue.Value.([]byte)))
+}
+
+func TestUpdateCredentialsVerified(t *testing.T) {
+	ses := newCommunityTestSession(t, "rotated")
+	ctx := context.Background()
+
+	err := ses.UpdateCredentials(ctx, WithCommunity("wrong"), VerifyCredentials())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to verify credentials")
+	_, err = ses.Get(ctx, []string{sysDescr + ".0"})
+	assert.NoError(t, err, "Expecting the previous community to be restored")
+
+	// The provider of sysName fails, so the agent reports a genErr.
+	err = ses.UpdateCredentials(ctx, WithCommunity("rotated"), VerifyCredentials(sysName+".0"))
+	var verr *VarbindError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, GenErr, verr.Status)
+
+	assert.NoError(t, ses.UpdateCredentials(ctx, WithCommunity("rotated"), VerifyCredentials(sysDescr+".0")))
+}
+
+func TestUpdateCredentialsNone(t *testing.T) {
+	ses := newCommunityTestSession(t, "rotated")
+	assert.EqualError(t, ses.UpdateCredentials(context.Background(), VerifyCredentials()), "no credentials to update")
+}
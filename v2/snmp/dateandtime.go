@@ -0,0 +1,75 @@
+package snmp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Decoding and encoding of the DateAndTime textual convention, defined by RFC 2579 as an OctetString of
+// 8 or 11 octets:
+//
+//	octets  contents            range
+//	1-2     year                0..65536 (network byte order)
+//	3       month               1..12
+//	4       day                 1..31
+//	5       hour                0..23
+//	6       minutes             0..59
+//	7       seconds             0..60 (60 for leap-second)
+//	8       deci-seconds        0..9
+//	9       direction from UTC  '+' / '-'
+//	10      hours from UTC      0..13
+//	11      minutes from UTC    0..59
+
+const (
+	dateAndTimeLocalSize = 8
+	dateAndTimeSize      = 11
+)
+
+// ParseDateAndTime decodes an OctetString value defined by the DateAndTime textual convention.
+// If the value includes the offset from UTC, the time is delivered in a fixed zone with that offset, otherwise the
+// zone of the agent is unknown and the time is delivered in UTC.
+func ParseDateAndTime(b []byte) (time.Time, error) {
+	if len(b) != dateAndTimeLocalSize && len(b) != dateAndTimeSize {
+		return time.Time{}, fmt.Errorf("invalid DateAndTime length %d", len(b))
+	}
+
+	year := int(binary.BigEndian.Uint16(b[0:2]))
+	month, day, hour, min, sec, decis := int(b[2]), int(b[3]), int(b[4]), int(b[5]), int(b[6]), int(b[7])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || min > 59 || sec > 60 || decis > 9 {
+		return time.Time{}, fmt.Errorf("invalid DateAndTime value % x", b)
+	}
+
+	loc := time.UTC
+	if len(b) == dateAndTimeSize {
+		direction, hours, mins := b[8], int(b[9]), int(b[10])
+		if direction != '+' && direction != '-' || hours > 13 || mins > 59 {
+			return time.Time{}, fmt.Errorf("invalid DateAndTime offset from UTC % x", b[8:])
+		}
+		offset := (hours*60 + mins) * 60
+		if direction == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	const nanosPerDecisecond = int(100 * time.Millisecond)
+	return time.Date(year, time.Month(month), day, hour, min, sec, decis*nanosPerDecisecond, loc), nil
+}
+
+// EncodeDateAndTime encodes the time as an OctetString value defined by the DateAndTime textual convention,
+// including the offset from UTC of the time's zone.
+func EncodeDateAndTime(t time.Time) []byte {
+	b := make([]byte, dateAndTimeSize)
+	binary.BigEndian.PutUint16(b[0:2], uint16(t.Year()))
+	b[2], b[3], b[4], b[5], b[6] = byte(t.Month()), byte(t.Day()), byte(t.Hour()), byte(t.Minute()), byte(t.Second())
+	b[7] = byte(t.Nanosecond() / int(100*time.Millisecond))
+
+	_, offset := t.Zone()
+	b[8] = '+'
+	if offset < 0 {
+		b[8], offset = '-', -offset
+	}
+	b[9], b[10] = byte(offset/3600), byte(offset%3600/60)
+	return b
+}
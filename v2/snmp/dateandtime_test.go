@@ -0,0 +1,63 @@
+package snmp
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestParseDateAndTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"utc offset", []byte{0x07, 0xe6, 5, 26, 13, 30, 15, 0, '+', 0, 0}, "2022-05-26T13:30:15Z"},
+		{"positive offset", []byte{0x07, 0xe6, 5, 26, 13, 30, 15, 0, '+', 5, 30}, "2022-05-26T13:30:15+05:30"},
+		{"negative offset", []byte{0x07, 0xd0, 12, 31, 23, 59, 59, 0, '-', 8, 0}, "2000-12-31T23:59:59-08:00"},
+		{"deciseconds", []byte{0x07, 0xe6, 5, 26, 13, 30, 15, 7, '+', 1, 0}, "2022-05-26T13:30:15.7+01:00"},
+		{"no offset", []byte{0x07, 0xe6, 5, 26, 13, 30, 15, 0}, "2022-05-26T13:30:15Z"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseDateAndTime(test.input)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result.Format(time.RFC3339Nano))
+		})
+	}
+}
+
+func TestParseDateAndTimeFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		err   string
+	}{
+		{"length", []byte{0x07, 0xe6, 5, 26}, "invalid DateAndTime length 4"},
+		{"month", []byte{0x07, 0xe6, 13, 26, 13, 30, 15, 0}, "invalid DateAndTime value 07 e6 0d 1a 0d 1e 0f 00"},
+		{"direction", []byte{0x07, 0xe6, 5, 26, 13, 30, 15, 0, 'x', 0, 0}, "invalid DateAndTime offset from UTC 78 00 00"},
+		{"offset", []byte{0x07, 0xe6, 5, 26, 13, 30, 15, 0, '+', 14, 0}, "invalid DateAndTime offset from UTC 2b 0e 00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseDateAndTime(test.input)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestEncodeDateAndTime(t *testing.T) {
+	input := time.Date(2000, 12, 31, 23, 59, 59, 700*int(time.Millisecond), time.FixedZone("PST", -8*3600))
+	b := EncodeDateAndTime(input)
+	assert.Equal(t, []byte{0x07, 0xd0, 12, 31, 23, 59, 59, 7, '-', 8, 0}, b)
+
+	result, err := (&TypedValue{Type: OctetString, Value: b}).DateAndTime()
+	assert.NoError(t, err)
+	assert.True(t, input.Equal(result))
+}
+
+func TestTimeTicksDuration(t *testing.T) {
+	tv := &TypedValue{Type: Time, Value: uint32(18532)}
+	assert.Equal(t, 3*time.Minute+5*time.Second+320*time.Millisecond, tv.Duration())
+}
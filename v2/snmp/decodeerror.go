@@ -0,0 +1,134 @@
+package snmp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The number of octets either side of the failure shown by DecodeError.Context.
+const decodeContextOctets = 8
+
+// DecodeError is returned when a received message cannot be decoded, locating the failure within the message so
+// that interoperability issues with agents can be diagnosed.
+type DecodeError struct {
+	// The stage of decoding that failed: "envelope", "pdu" or "varbind N", where N counts variable bindings from 1.
+	Stage string
+	// The offset within the message of the element that could not be decoded. Where the encoding of the element, or
+	// of an element it contains, is malformed, the offset locates the malformed element.
+	Offset int
+	// The octets of the message around the offset in hex, with the octet at the offset bracketed, such as
+	// "... 30 0e 06 0a [04] 81 ff ...".
+	Context string
+	// The error reported by the decoder.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s at offset %d (%s): %v", e.Stage, e.Offset, e.Context, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Locates the elements of a received message, to report decode errors.
+// Decoding may replace the tags of elements with generic tags, so a decoder that fails must restore the original
+// tags before reporting the error.
+type decodeLocator struct {
+	msg []byte
+	// A copy of the PDU of the message, if it was copied before decoding, and its offset within the message.
+	pdu       []byte
+	pduOffset int
+}
+
+// Delivers the offset within the message of element, which is a slice of the message or of the PDU copy.
+func (l *decodeLocator) offset(element []byte) int {
+	if off, ok := sliceOffset(l.msg, element); ok {
+		return off
+	}
+	if off, ok := sliceOffset(l.pdu, element); ok {
+		return l.pduOffset + off
+	}
+	return 0
+}
+
+// Delivers a DecodeError reporting that decoding element failed at the stage.
+func (l *decodeLocator) fail(stage string, element []byte, err error) error {
+	if l == nil {
+		return err
+	}
+	offset := l.offset(element)
+	if fault, ok := berFault(element); ok {
+		offset += fault
+	}
+	return &DecodeError{Stage: stage, Offset: offset, Context: hexContext(l.msg, offset), Err: err}
+}
+
+// Delivers the offset of sub within b, if sub is a slice of b.
+func sliceOffset(b, sub []byte) (int, bool) {
+	if len(sub) == 0 {
+		return 0, false
+	}
+	off := cap(b) - cap(sub)
+	if off < 0 || off >= len(b) || &b[off] != &sub[0] {
+		return 0, false
+	}
+	return off, true
+}
+
+// Delivers the offset of the first malformed element in the BER encoding b, whose header is invalid, or whose length
+// exceeds the octets available. Where a constructed element is truncated, the fault is located within its content.
+func berFault(b []byte) (int, bool) {
+	for off := 0; off < len(b); {
+		length, hdr, ok := berLength(b[off+1:])
+		if !ok {
+			return off, true
+		}
+		end := off + 1 + hdr + length
+		overrun := end > len(b)
+		if b[off]&compoundTag != 0 {
+			// Locate the fault within the content, or within the octets available if the element is truncated.
+			content := b[off+1+hdr:]
+			if !overrun {
+				content = content[:length]
+			}
+			if inner, bad := berFault(content); bad {
+				return off + 1 + hdr + inner, true
+			}
+		}
+		if overrun {
+			return off, true
+		}
+		off = end
+	}
+	return 0, false
+}
+
+// Delivers the octets of msg around the offset in hex, with the octet at the offset bracketed.
+func hexContext(msg []byte, offset int) string {
+	from, to := offset-decodeContextOctets, offset+decodeContextOctets+1
+	var parts []string
+	if from <= 0 {
+		from = 0
+	} else {
+		parts = append(parts, "...")
+	}
+	truncated := to < len(msg)
+	if !truncated {
+		to = len(msg)
+	}
+	for i := from; i < to; i++ {
+		if i == offset {
+			parts = append(parts, fmt.Sprintf("[%02x]", msg[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%02x", msg[i]))
+		}
+	}
+	if offset >= len(msg) {
+		parts = append(parts, "[]")
+	}
+	if truncated {
+		parts = append(parts, "...")
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,91 @@
+package snmp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestParseResponseDecodeErrors(t *testing.T) {
+	m := &sessionImpl{config: &SessionConfig{trace: NoOpLoggingHooks}}
+
+	// A value whose tag identifies an integer type, but whose content is too long for an integer.
+	response := testResponse(t, 7, "1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0")
+	at := bytes.Index(response, []byte("value of 1.3.6.1.2.1.1.5.0")) - 2
+	response[at] = counter32Tag
+	_, err := m.parseResponse(response)
+	var derr *DecodeError
+	assert.True(t, errors.As(err, &derr))
+	assert.Equal(t, "varbind 2", derr.Stage)
+	assert.Equal(t, at, derr.Offset)
+	assert.Contains(t, derr.Context, "00 [41] 1a 76 61", "Expecting the original tag in the context")
+	assert.Error(t, derr.Err)
+
+	// A request id that is not an integer.
+	response = testResponse(t, 7, "1.3.6.1.2.1.1.4.0")
+	pduAt := bytes.IndexByte(response, getResponse)
+	response[pduAt+2] = 0x04
+	_, err = m.parseResponse(response)
+	assert.True(t, errors.As(err, &derr))
+	assert.Equal(t, "pdu", derr.Stage)
+	assert.Equal(t, pduAt, derr.Offset)
+	assert.Contains(t, derr.Context, "[a2]", "Expecting the original pdu tag in the context")
+
+	// An envelope whose version is truncated.
+	_, err = m.parseResponse([]byte{0x30, 0x05, 0x02, 0x01})
+	assert.True(t, errors.As(err, &derr))
+	assert.Equal(t, &DecodeError{Stage: "envelope", Offset: 2, Context: "30 05 [02] 01", Err: derr.Err}, derr)
+	assert.Contains(t, err.Error(), "failed to decode envelope at offset 2 (30 05 [02] 01): ")
+}
+
+func TestServerDecodeError(t *testing.T) {
+	config := defaultServerConfig
+	config.trace = NoOpServerHooks
+	s := &serverImpl{config: &config, handler: newHandler()}
+
+	message := messageWithType(v2Trap)
+	message[79] = 0x85
+	err := s.processMessage(message, nil, time.Now())
+	var derr *DecodeError
+	assert.True(t, errors.As(err, &derr))
+	assert.Equal(t, "varbind 3", derr.Stage)
+	assert.Equal(t, 79, derr.Offset, "Expecting the offset within the message, rather than the pdu")
+	assert.Equal(t, "... 06 06 2b 06 01 07 08 09 [85] 03 01 e2 40", derr.Context)
+	assert.EqualError(t, derr.Err, "unsupported class 2 tag 5")
+}
+
+func TestBERFault(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  []byte
+		offset int
+		fault  bool
+	}{
+		{"valid", []byte{0x30, 0x03, 0x02, 0x01, 0x01, 0x05, 0x00}, 0, false},
+		{"invalid length", []byte{0x30, 0x03, 0x02, 0x01, 0x01, 0x04, 0x85}, 5, true},
+		{"overrun", []byte{0x30, 0x03, 0x02, 0x01, 0x01, 0x04, 0x02, 0x01}, 5, true},
+		{"truncated sequence", []byte{0x30, 0x08, 0x02, 0x01, 0x01, 0x04, 0x03, 0x01}, 5, true},
+		{"truncated primitive", []byte{0x30, 0x03, 0x04, 0x08, 0x01}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, fault := berFault(tt.input)
+			assert.Equal(t, tt.fault, fault)
+			assert.Equal(t, tt.offset, offset)
+		})
+	}
+}
+
+func TestHexContext(t *testing.T) {
+	msg := make([]byte, 40)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	assert.Equal(t, "00 01 [02] 03 04 05 06 07 08 09 0a ...", hexContext(msg, 2))
+	assert.Equal(t, "... 0c 0d 0e 0f 10 11 12 13 [14] 15 16 17 18 19 1a 1b 1c ...", hexContext(msg, 20))
+	assert.Equal(t, "... 1f 20 21 22 23 24 25 26 [27]", hexContext(msg, 39))
+	assert.Equal(t, "... 20 21 22 23 24 25 26 27 []", hexContext(msg, 40))
+}
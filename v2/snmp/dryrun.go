@@ -0,0 +1,47 @@
+package snmp
+
+import (
+	"context"
+	"log"
+)
+
+// DryRun configures the session so that the variable bindings of each Set request are passed to recorder, rather
+// than being sent to the agent, and the request succeeds, while all other requests are issued, so that automation can
+// be validated against an agent without changing it.
+// If recorder is nil, suppressed requests are logged.
+// Default is to issue Set requests.
+func DryRun(recorder func(varbinds []Varbind)) SessionOption {
+	return func(c *SessionConfig) {
+		c.dryRun = dryRunRecorder(recorder)
+	}
+}
+
+// Delivers recorder, or a recorder that logs the variable bindings if recorder is nil.
+func dryRunRecorder(recorder func(varbinds []Varbind)) func(varbinds []Varbind) {
+	if recorder != nil {
+		return recorder
+	}
+	return func(varbinds []Varbind) {
+		for i := range varbinds {
+			log.Printf("DryRun set oid:%s value:%s\n", varbinds[i].OID, varbinds[i].TypedValue)
+		}
+	}
+}
+
+// DryRunSetter delivers a Setter, typically supplied to a ConfigSetter, that passes the variable bindings of each Set
+// request to recorder, rather than issuing the request, and reports success, while Get requests are issued by s, so
+// that automation can be validated against an agent without changing it.
+// If recorder is nil, suppressed requests are logged.
+func DryRunSetter(s Setter, recorder func(varbinds []Varbind)) Setter {
+	return &dryRunSetter{Setter: s, recorder: dryRunRecorder(recorder)}
+}
+
+type dryRunSetter struct {
+	Setter
+	recorder func(varbinds []Varbind)
+}
+
+func (d *dryRunSetter) Set(ctx context.Context, varbinds []Varbind) (*PDU, error) {
+	d.recorder(varbinds)
+	return &PDU{VarbindList: copyVarbinds(varbinds)}, nil
+}
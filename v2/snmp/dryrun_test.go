@@ -0,0 +1,61 @@
+package snmp
+
+import (
+	"context"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestDryRunSetter(t *testing.T) {
+	f := newFakeSetter()
+	var recorded [][]Varbind
+	cs := NewConfigSetter(DryRunSetter(f, func(varbinds []Varbind) { recorded = append(recorded, varbinds) })).
+		Add(stringVarbind("1.3.6.1.2.1.1.5.0", "router")).
+		Add(stringVarbind("1.3.6.1.2.1.1.6.0", "dc1"))
+
+	assert.NoError(t, cs.Apply(context.Background()))
+	assert.Empty(t, f.sets, "Expecting no set requests to be issued")
+	assert.Equal(t, "host", f.values["1.3.6.1.2.1.1.5.0"].String())
+	assert.Len(t, recorded, 2)
+	assert.Equal(t, "router", recorded[0][0].TypedValue.String())
+
+	// The prior values are retrieved from the agent.
+	assert.Equal(t, "lab", cs.Journal()[1][0].TypedValue.String())
+}
+
+func TestDryRunSession(t *testing.T) {
+	values := map[string]string{sysContact + ".0": "ops"}
+	var recorded [][]Varbind
+	s := newTestSession(t, newWritableTestAgent(t, values), DryRun(func(varbinds []Varbind) {
+		recorded = append(recorded, varbinds)
+	}))
+
+	pdu, err := s.Set(context.Background(), []Varbind{stringVarbind(sysContact+".0", "noc")})
+	assert.NoError(t, err)
+	assert.Equal(t, "noc", pdu.VarbindList[0].TypedValue.String())
+	assert.Len(t, recorded, 1)
+	assert.Equal(t, "ops", values[sysContact+".0"], "Expecting no set request to be issued")
+
+	// Get requests are issued.
+	pdu, err = s.Get(context.Background(), []string{sysContact + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ops", pdu.VarbindList[0].TypedValue.String())
+
+	// Invalid variable bindings are rejected.
+	_, err = s.Set(context.Background(), []Varbind{{OID: mustParseOID(sysContact + ".0")}})
+	assert.Error(t, err)
+	assert.Len(t, recorded, 1)
+}
+
+func TestDryRunSetterSession(t *testing.T) {
+	values := map[string]string{sysContact + ".0": "ops", sysLocation + ".0": "lab"}
+	var recorded [][]Varbind
+	s := DryRunSetter(newTestSession(t, newWritableTestAgent(t, values)), func(varbinds []Varbind) {
+		recorded = append(recorded, varbinds)
+	})
+
+	assert.NoError(t, NewConfigSetter(s).Add(stringVarbind(sysContact+".0", "noc")).Apply(context.Background()))
+	assert.Len(t, recorded, 1)
+	assert.Equal(t, "ops", values[sysContact+".0"], "Expecting no set request to be issued")
+}
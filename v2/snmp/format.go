@@ -0,0 +1,179 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Formatting of typed values in the style of the net-snmp command line tools, for example snmpwalk.
+
+// FormatOption implements options for configuring formatted output.
+type FormatOption func(*formatConfig)
+
+type octetStringMode int
+
+const (
+	// Render octet strings as STRING if printable, otherwise as Hex-STRING.
+	octetStringAuto octetStringMode = iota
+	octetStringHex
+	octetStringDisplay
+)
+
+type formatConfig struct {
+	octetStrings octetStringMode
+	omitType     bool
+}
+
+// HexStrings renders all octet strings as Hex-STRING, regardless of whether they are printable.
+// Default is to render printable octet strings as STRING.
+func HexStrings() FormatOption {
+	return func(c *formatConfig) {
+		c.octetStrings = octetStringHex
+	}
+}
+
+// DisplayStrings renders all octet strings as STRING (DisplayString), regardless of whether they are printable.
+// Default is to render non-printable octet strings as Hex-STRING.
+func DisplayStrings() FormatOption {
+	return func(c *formatConfig) {
+		c.octetStrings = octetStringDisplay
+	}
+}
+
+// OmitType omits the type prefix, for example "Counter32: ", from the output, as with snmpwalk -Oq.
+func OmitType() FormatOption {
+	return func(c *formatConfig) {
+		c.omitType = true
+	}
+}
+
+// Format delivers the value in the form output by snmpwalk, for example:
+//
+//	INTEGER: 5
+//	STRING: "Linux router1"
+//	Hex-STRING: 00 1A 2B 3C 4D 5E
+//	Timeticks: (2322054929) 268 days, 18:09:09.29
+func Format(tv *TypedValue, opts ...FormatOption) string {
+	c := &formatConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	typ, value := formatValue(tv, c)
+	if typ == "" || c.omitType {
+		return value
+	}
+	return typ + ": " + value
+}
+
+// FormatVarbind delivers the variable binding in the form output by snmpwalk -On, for example:
+//
+//	.1.3.6.1.2.1.1.3.0 = Timeticks: (2322054929) 268 days, 18:09:09.29
+func FormatVarbind(vb *Varbind, opts ...FormatOption) string {
+	return "." + vb.OID.String() + " = " + Format(vb.TypedValue, opts...)
+}
+
+// Delivers the net-snmp type name and formatted value. The type name is empty for exception values.
+func formatValue(tv *TypedValue, c *formatConfig) (typ, value string) {
+	const base10 = 10
+	switch tv.Type {
+	case Integer:
+		return "INTEGER", strconv.FormatInt(tv.Value.(int64), base10)
+	case OctetString:
+		return formatOctetString(tv.Value.([]byte), c.octetStrings)
+	case OID:
+		return "OID", "." + tv.Value.(asn1.ObjectIdentifier).String()
+	case Time:
+		return "Timeticks", FormatTimeticks(tv.Value.(uint32))
+	case Counter32:
+		return "Counter32", strconv.FormatUint(uint64(tv.Value.(uint32)), base10)
+	case Gauge32:
+		return "Gauge32", strconv.FormatUint(uint64(tv.Value.(uint32)), base10)
+	case Counter64:
+		return "Counter64", strconv.FormatUint(tv.Value.(uint64), base10)
+	case IPAdddress:
+		return "IpAddress", tv.String()
+	case Opaque:
+		return "OPAQUE", hexString(tv.Value.([]byte))
+	case Float:
+		return "OPAQUE", "Float: " + strconv.FormatFloat(tv.Value.(float64), 'f', 6, 32)
+	case Double:
+		return "OPAQUE", "Double: " + strconv.FormatFloat(tv.Value.(float64), 'f', 6, 64)
+	case Integer64:
+		return "OPAQUE", "I64: " + strconv.FormatInt(tv.Value.(int64), base10)
+	case Unsigned64:
+		return "OPAQUE", "U64: " + strconv.FormatUint(tv.Value.(uint64), base10)
+	case EndOfMib:
+		return "", "No more variables left in this MIB View (It is past the end of the MIB tree)"
+	case NoSuchObject:
+		return "", "No Such Object available on this agent at this OID"
+	case NoSuchInstance:
+		return "", "No Such Instance currently exists at this OID"
+	case Null:
+		return "", "NULL"
+	}
+	return "", fmt.Sprintf("unrecognised data type %d", tv.Type)
+}
+
+func formatOctetString(b []byte, mode octetStringMode) (typ, value string) {
+	if mode == octetStringHex || mode == octetStringAuto && !isPrintable(b) {
+		return "Hex-STRING", hexString(b)
+	}
+	return "STRING", strconv.Quote(string(b))
+}
+
+// FormatTimeticks delivers the time ticks (hundredths of a second) in the form output by net-snmp, for example
+// "(2322054929) 268 days, 18:09:09.29".
+func FormatTimeticks(ticks uint32) string {
+	return fmt.Sprintf("(%d) %s", ticks, formatTicks(ticks))
+}
+
+// Delivers the time ticks as days, hours, minutes and seconds, for example "268 days, 18:09:09.29" or "0:03:05.32".
+func formatTicks(ticks uint32) string {
+	const (
+		ticksPerSecond = 100
+		ticksPerMinute = 60 * ticksPerSecond
+		ticksPerHour   = 60 * ticksPerMinute
+		ticksPerDay    = 24 * ticksPerHour
+	)
+
+	clock := fmt.Sprintf("%d:%02d:%02d.%02d", ticks%ticksPerDay/ticksPerHour, ticks%ticksPerHour/ticksPerMinute,
+		ticks%ticksPerMinute/ticksPerSecond, ticks%ticksPerSecond)
+	switch days := ticks / ticksPerDay; days {
+	case 0:
+		return clock
+	case 1:
+		return "1 day, " + clock
+	default:
+		return fmt.Sprintf("%d days, %s", days, clock)
+	}
+}
+
+// Delivers the octets as upper case hex pairs, separated by spaces.
+func hexString(b []byte) string {
+	var sb strings.Builder
+	for i, octet := range b {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02X", octet)
+	}
+	return sb.String()
+}
+
+// Determines whether the octets are printable text.
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
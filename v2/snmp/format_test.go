@@ -0,0 +1,52 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *TypedValue
+		opts  []FormatOption
+		want  string
+	}{
+		{"Integer", &TypedValue{Type: Integer, Value: int64(-5)}, nil, "INTEGER: -5"},
+		{"String", &TypedValue{Type: OctetString, Value: []byte("Linux router1")}, nil, `STRING: "Linux router1"`},
+		{"EmptyString", &TypedValue{Type: OctetString, Value: []byte{}}, nil, `STRING: ""`},
+		{"NonPrintableString", &TypedValue{Type: OctetString, Value: []byte{0x00, 0x1a, 0x2b}}, nil, "Hex-STRING: 00 1A 2B"},
+		{"HexStrings", &TypedValue{Type: OctetString, Value: []byte("AB")}, []FormatOption{HexStrings()}, "Hex-STRING: 41 42"},
+		{"DisplayStrings", &TypedValue{Type: OctetString, Value: []byte{0x01}}, []FormatOption{DisplayStrings()}, `STRING: "\x01"`},
+		{"OID", &TypedValue{Type: OID, Value: asn1.ObjectIdentifier{1, 3, 6, 1}}, nil, "OID: .1.3.6.1"},
+		{"Timeticks", &TypedValue{Type: Time, Value: uint32(2322054929)}, nil, "Timeticks: (2322054929) 268 days, 18:09:09.29"},
+		{"TimeticksOneDay", &TypedValue{Type: Time, Value: uint32(8640001)}, nil, "Timeticks: (8640001) 1 day, 0:00:00.01"},
+		{"Counter32", &TypedValue{Type: Counter32, Value: uint32(4294967295)}, nil, "Counter32: 4294967295"},
+		{"Gauge32", &TypedValue{Type: Gauge32, Value: uint32(1000)}, nil, "Gauge32: 1000"},
+		{"Counter64", &TypedValue{Type: Counter64, Value: uint64(18446744073709551615)}, nil, "Counter64: 18446744073709551615"},
+		{"IpAddress", &TypedValue{Type: IPAdddress, Value: []byte{10, 0, 0, 1}}, nil, "IpAddress: 10.0.0.1"},
+		{"Opaque", &TypedValue{Type: Opaque, Value: []byte{0x9f, 0x78}}, nil, "OPAQUE: 9F 78"},
+		{"Float", &TypedValue{Type: Float, Value: 1.5}, nil, "OPAQUE: Float: 1.500000"},
+		{"Double", &TypedValue{Type: Double, Value: -0.25}, nil, "OPAQUE: Double: -0.250000"},
+		{"Integer64", &TypedValue{Type: Integer64, Value: int64(-200)}, nil, "OPAQUE: I64: -200"},
+		{"Unsigned64", &TypedValue{Type: Unsigned64, Value: uint64(200)}, nil, "OPAQUE: U64: 200"},
+		{"OmitType", &TypedValue{Type: Counter32, Value: uint32(7)}, []FormatOption{OmitType()}, "7"},
+		{"EndOfMib", &TypedValue{Type: EndOfMib}, nil, "No more variables left in this MIB View (It is past the end of the MIB tree)"},
+		{"NoSuchObject", &TypedValue{Type: NoSuchObject}, nil, "No Such Object available on this agent at this OID"},
+		{"NoSuchInstance", &TypedValue{Type: NoSuchInstance}, nil, "No Such Instance currently exists at this OID"},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Format(tt.input, tt.opts...))
+		})
+	}
+}
+
+func TestFormatVarbind(t *testing.T) {
+	vb := &Varbind{OID: SysUpTimeOID, TypedValue: &TypedValue{Type: Time, Value: uint32(1234)}}
+	assert.Equal(t, ".1.3.6.1.2.1.1.3.0 = Timeticks: (1234) 0:00:12.34", FormatVarbind(vb))
+}
@@ -0,0 +1,175 @@
+package snmp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by a session when the circuit breaker considers the target to be down, and the
+// request has not been sent.
+var ErrCircuitOpen = errors.New("circuit open: target deemed unreachable")
+
+// HealthStats reports the recent health of a target, as observed by the sessions sharing a HealthTracker.
+type HealthStats struct {
+	// Number of requests included in the rolling window.
+	Requests int
+	// Proportion of requests in the rolling window that received a response, in the range 0..1.
+	SuccessRate float64
+	// Average latency of the successful requests in the rolling window.
+	AverageLatency time.Duration
+	// Number of timeouts observed since the last successful request.
+	ConsecutiveTimeouts int
+	// Time of the most recent request outcome.
+	LastSeen time.Time
+}
+
+// HealthTracker maintains rolling health statistics for a set of targets.
+// A single tracker may be shared by many sessions, so that statistics persist beyond the lifetime of an
+// individual session.
+type HealthTracker struct {
+	mu      sync.Mutex
+	window  int
+	targets map[string]*targetHealth
+}
+
+// Defines the recorded outcome of a single request.
+type outcome struct {
+	success bool
+	latency time.Duration
+}
+
+// Defines the health state of a single target.
+type targetHealth struct {
+	outcomes            []outcome
+	next                int
+	consecutiveTimeouts int
+	lastSeen            time.Time
+	lastTimeout         time.Time
+	// The end of the cooldown of an open circuit; zero until the circuit is opened.
+	openUntil time.Time
+}
+
+const defaultHealthWindow = 100
+
+// NewHealthTracker delivers a tracker that computes statistics over the last window requests issued to each
+// target; a window of zero or less selects the default of 100.
+func NewHealthTracker(window int) *HealthTracker {
+	if window <= 0 {
+		window = defaultHealthWindow
+	}
+	return &HealthTracker{window: window, targets: make(map[string]*targetHealth)}
+}
+
+// Stats delivers the current health statistics for the target.
+func (h *HealthTracker) Stats(target string) HealthStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	th, ok := h.targets[target]
+	if !ok {
+		return HealthStats{}
+	}
+	return th.stats()
+}
+
+// Reset discards any statistics held for the target.
+func (h *HealthTracker) Reset(target string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.targets, target)
+}
+
+// Records the outcome of a request to the target.
+func (h *HealthTracker) record(target string, err error, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	th := h.target(target)
+	o := outcome{success: err == nil, latency: d}
+	if len(th.outcomes) < h.window {
+		th.outcomes = append(th.outcomes, o)
+	} else {
+		th.outcomes[th.next] = o
+	}
+	th.next = (th.next + 1) % h.window
+	th.lastSeen = time.Now()
+
+	switch {
+	case err == nil:
+		th.consecutiveTimeouts = 0
+		th.openUntil = time.Time{}
+	case isTimeout(err):
+		th.consecutiveTimeouts++
+		th.lastTimeout = th.lastSeen
+	}
+}
+
+// Determines whether a request to the target should be allowed, given the circuit breaker configuration.
+// When the target has reached the timeout threshold, the circuit is opened for the cooldown period, from the timeout
+// that reached the threshold; once the cooldown expires a single trial request is allowed before the circuit is
+// re-opened.
+func (h *HealthTracker) allow(target string, cb *circuitBreaker) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	th := h.target(target)
+	if th.consecutiveTimeouts < cb.threshold {
+		return true
+	}
+	if th.openUntil.IsZero() {
+		th.openUntil = th.lastTimeout.Add(cb.cooldown)
+	}
+	now := time.Now()
+	if now.Before(th.openUntil) {
+		return false
+	}
+	th.openUntil = now.Add(cb.cooldown)
+	return true
+}
+
+func (h *HealthTracker) target(target string) *targetHealth {
+	th, ok := h.targets[target]
+	if !ok {
+		th = &targetHealth{}
+		h.targets[target] = th
+	}
+	return th
+}
+
+func (th *targetHealth) stats() HealthStats {
+	stats := HealthStats{
+		Requests:            len(th.outcomes),
+		ConsecutiveTimeouts: th.consecutiveTimeouts,
+		LastSeen:            th.lastSeen,
+	}
+	var successes int
+	var total time.Duration
+	for _, o := range th.outcomes {
+		if o.success {
+			successes++
+			total += o.latency
+		}
+	}
+	if stats.Requests > 0 {
+		stats.SuccessRate = float64(successes) / float64(stats.Requests)
+	}
+	if successes > 0 {
+		stats.AverageLatency = total / time.Duration(successes)
+	}
+	return stats
+}
+
+// Defines the circuit breaker configuration.
+type circuitBreaker struct {
+	// Number of consecutive timeouts after which the target is deemed down.
+	threshold int
+	// Period during which requests fail fast once the target is deemed down.
+	cooldown time.Duration
+}
+
+func isTimeout(err error) bool {
+	e, ok := err.(net.Error)
+	return ok && e.Timeout()
+}
@@ -0,0 +1,129 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/golang/mock/gomock"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestHealthTrackerStats(t *testing.T) {
+	h := NewHealthTracker(4)
+
+	assert.Equal(t, HealthStats{}, h.Stats(localhost161))
+
+	h.record(localhost161, nil, 10*time.Millisecond)
+	h.record(localhost161, nil, 30*time.Millisecond)
+	h.record(localhost161, &timeoutError{}, time.Second)
+	h.record(localhost161, &timeoutError{}, time.Second)
+
+	stats := h.Stats(localhost161)
+	assert.Equal(t, 4, stats.Requests)
+	assert.Equal(t, 0.5, stats.SuccessRate)
+	assert.Equal(t, 20*time.Millisecond, stats.AverageLatency)
+	assert.Equal(t, 2, stats.ConsecutiveTimeouts)
+	assert.False(t, stats.LastSeen.IsZero())
+
+	// Window is rolling, so the oldest outcomes are discarded.
+	h.record(localhost161, nil, 40*time.Millisecond)
+	h.record(localhost161, errors.New("snmp failure"), 0)
+	stats = h.Stats(localhost161)
+	assert.Equal(t, 4, stats.Requests)
+	assert.Equal(t, 0.25, stats.SuccessRate)
+	assert.Equal(t, 40*time.Millisecond, stats.AverageLatency)
+	assert.Equal(t, 0, stats.ConsecutiveTimeouts, "success should reset consecutive timeouts")
+
+	h.Reset(localhost161)
+	assert.Equal(t, HealthStats{}, h.Stats(localhost161))
+}
+
+func TestHealthTrackerCircuitBreaker(t *testing.T) {
+	h := NewHealthTracker(0)
+	cb := &circuitBreaker{threshold: 2, cooldown: time.Hour}
+
+	assert.True(t, h.allow(localhost161, cb))
+	h.record(localhost161, &timeoutError{}, time.Second)
+	assert.True(t, h.allow(localhost161, cb))
+	h.record(localhost161, &timeoutError{}, time.Second)
+
+	// Threshold reached; the circuit is open for the cooldown.
+	assert.False(t, h.allow(localhost161, cb))
+	assert.False(t, h.allow(localhost161, cb))
+	assert.True(t, h.allow("otherhost:161", cb), "other targets should be unaffected")
+
+	h.record(localhost161, nil, time.Millisecond)
+	assert.True(t, h.allow(localhost161, cb))
+}
+
+func TestHealthTrackerCircuitBreakerTrial(t *testing.T) {
+	h := NewHealthTracker(0)
+	cb := &circuitBreaker{threshold: 1, cooldown: 20 * time.Millisecond}
+
+	h.record(localhost161, &timeoutError{}, time.Second)
+	assert.False(t, h.allow(localhost161, cb))
+
+	// Once the cooldown expires, a single trial is allowed before the circuit is re-opened.
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, h.allow(localhost161, cb))
+	assert.False(t, h.allow(localhost161, cb))
+
+	h.record(localhost161, &timeoutError{}, time.Second)
+	assert.False(t, h.allow(localhost161, cb), "Expecting a failed trial to leave the circuit open")
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, h.allow(localhost161, cb))
+}
+
+func TestSessionCircuitBreaker(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockConn(mockCtrl)
+
+	gomock.InOrder(
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).Return(0, &timeoutError{}),
+	)
+
+	config := DefaultConfig
+	config.address = localhost161
+	config.community = public
+	config.trace = NoOpLoggingHooks
+	config.retries = 3
+	config.health = NewHealthTracker(0)
+	config.breaker = &circuitBreaker{threshold: 1, cooldown: time.Hour}
+	m := &sessionImpl{config: &config, conn: mockConn, nextRequestID: 1}
+
+	_, err := m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.Equal(t, ErrCircuitOpen, err)
+
+	_, err = m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.Equal(t, ErrCircuitOpen, err)
+
+	stats := m.Health()
+	assert.Equal(t, 1, stats.Requests, "Expecting the circuit to open at the threshold")
+	assert.Equal(t, 0.0, stats.SuccessRate)
+	assert.Equal(t, 1, stats.ConsecutiveTimeouts)
+}
+
+func TestNewSessionHealthOptions(t *testing.T) {
+	tracker := NewHealthTracker(10)
+	f := NewFactory()
+	m, err := f.NewSession(context.Background(), localhost161,
+		HealthTracking(tracker),
+		CircuitBreaker(3, time.Minute),
+	)
+	assert.NoError(t, err)
+	impl := m.(*sessionImpl)
+	assert.Equal(t, tracker, impl.config.health)
+	assert.Equal(t, &circuitBreaker{threshold: 3, cooldown: time.Minute}, impl.config.breaker)
+
+	m, err = f.NewSession(context.Background(), localhost161)
+	assert.NoError(t, err)
+	assert.NotNil(t, m.(*sessionImpl).config.health, "session should have a private tracker by default")
+	assert.Equal(t, HealthStats{}, m.Health())
+}
@@ -0,0 +1,19 @@
+package snmp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Delivers a session with the server, configured with opts.
+func newTestSession(t *testing.T, s Server, opts ...SessionOption) Session {
+	addr := s.(*serverImpl).conn.LocalAddr().String()
+	opts = append([]SessionOption{Timeout(time.Second), Retries(0), LoggingHooks(NoOpLoggingHooks)}, opts...)
+	ses, err := NewFactory().NewSession(context.Background(), addr, opts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { ses.Close() })
+	return ses
+}
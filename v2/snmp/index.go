@@ -0,0 +1,174 @@
+package snmp
+
+import (
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+)
+
+// Helpers that decode the index part of the OID of a table column instance into its typed components, according to
+// the encoding rules of https://tools.ietf.org/html/rfc2578#section-7.7.
+
+// IndexType defines the type of a component of a table index, which determines how it is encoded in the index part
+// of an instance OID.
+type IndexType int
+
+const (
+	// IndexInteger is an integer-valued component, such as ifIndex, encoded as a single arc; delivered as an Integer.
+	IndexInteger IndexType = iota
+	// IndexIPAddress is an IpAddress component, encoded as four arcs; delivered as an IPAdddress.
+	IndexIPAddress
+	// IndexOctetString is an OCTET STRING component, encoded as a length followed by an arc for each octet, unless
+	// fixed-size or implied; delivered as an OctetString.
+	IndexOctetString
+	// IndexOID is an OBJECT IDENTIFIER component, encoded as a length followed by its arcs, unless implied; delivered
+	// as an OID.
+	IndexOID
+)
+
+const ipAddressArcs = 4
+
+// IndexComponent defines a component of a table index.
+type IndexComponent struct {
+	// The name of the index object, for example "ipNetToMediaNetAddress".
+	Name string
+	Type IndexType
+	// The size of a fixed-size OCTET STRING, such as a MacAddress, which is encoded without a length. Zero means the
+	// string is variable-sized.
+	Size int
+	// Indicates that the component is IMPLIED, so is encoded without a length; only the last component may be implied.
+	Implied bool
+}
+
+// IndexSpec defines the components of a table index, in the order of the table's INDEX clause.
+// For example, the index of ipNetToMediaTable is
+//
+//	IndexSpec{{Name: "ipNetToMediaIfIndex", Type: IndexInteger}, {Name: "ipNetToMediaNetAddress", Type: IndexIPAddress}}
+type IndexSpec []IndexComponent
+
+// IndexValue defines the decoded value of a component of a table index.
+type IndexValue struct {
+	Name  string
+	Value *TypedValue
+}
+
+// IndexValues defines the decoded components of a table index.
+type IndexValues []IndexValue
+
+// Get delivers the value of the named index component, and whether it was found.
+func (v IndexValues) Get(name string) (*TypedValue, bool) {
+	for i := range v {
+		if v[i].Name == name {
+			return v[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// Decode decodes the index part of an instance OID into the values of its components. An error is returned if the
+// index does not conform to the specification, or has arcs that follow the last component.
+func (s IndexSpec) Decode(index asn1.ObjectIdentifier) (IndexValues, error) {
+	values := make(IndexValues, 0, len(s))
+	rest := index
+	for i, c := range s {
+		if c.Implied && i != len(s)-1 {
+			return nil, errors.Errorf("index component %s cannot be implied, as it is not the last", c.Name)
+		}
+		tv, n, err := c.decode(rest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid index %s: component %s", index, c.Name)
+		}
+		values = append(values, IndexValue{Name: c.Name, Value: tv})
+		rest = rest[n:]
+	}
+	if len(rest) > 0 {
+		return nil, errors.Errorf("invalid index %s: %d arcs follow the last component", index, len(rest))
+	}
+	return values, nil
+}
+
+// DecodeInstance decodes the OID of a column instance of the table entry, for example "1.3.6.1.2.1.4.22.1" for
+// ipNetToMediaEntry, delivering the column number and the index values. An error is returned if the OID is not an
+// instance of a column of the entry.
+func (s IndexSpec) DecodeInstance(entry string, oid asn1.ObjectIdentifier) (column int, values IndexValues, err error) {
+	prefix, err := parseOID(entry)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(oid) <= len(prefix)+1 || !hasOIDPrefix(oid, prefix) {
+		return 0, nil, errors.Errorf("%s is not an instance of a column of %s", oid, entry)
+	}
+	values, err = s.Decode(oid[len(prefix)+1:])
+	return oid[len(prefix)], values, err
+}
+
+// Decodes the component from the start of the arcs, delivering its value and the number of arcs consumed.
+func (c *IndexComponent) decode(arcs asn1.ObjectIdentifier) (*TypedValue, int, error) {
+	switch c.Type {
+	case IndexInteger:
+		if len(arcs) < 1 {
+			return nil, 0, errors.New("missing integer")
+		}
+		return &TypedValue{Type: Integer, Value: int64(arcs[0])}, 1, nil
+
+	case IndexIPAddress:
+		b, err := octets(arcs, ipAddressArcs)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &TypedValue{Type: IPAdddress, Value: b}, ipAddressArcs, nil
+
+	case IndexOctetString:
+		start, length, err := c.extent(arcs)
+		if err != nil {
+			return nil, 0, err
+		}
+		b, err := octets(arcs[start:], length)
+		if err != nil {
+			return nil, 0, err
+		}
+		return &TypedValue{Type: OctetString, Value: b}, start + length, nil
+
+	case IndexOID:
+		start, length, err := c.extent(arcs)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(arcs) < start+length {
+			return nil, 0, errors.Errorf("expecting %d arcs, found %d", length, len(arcs)-start)
+		}
+		oid := append(asn1.ObjectIdentifier{}, arcs[start:start+length]...)
+		return &TypedValue{Type: OID, Value: oid}, start + length, nil
+	}
+	return nil, 0, errors.Errorf("unsupported index type %d", c.Type)
+}
+
+// Delivers the position and length of the arcs holding the value of a variable-length component.
+func (c *IndexComponent) extent(arcs asn1.ObjectIdentifier) (start, length int, err error) {
+	switch {
+	case c.Size > 0:
+		return 0, c.Size, nil
+	case c.Implied:
+		return 0, len(arcs), nil
+	case len(arcs) < 1:
+		return 0, 0, errors.New("missing length")
+	case arcs[0] < 0:
+		return 0, 0, errors.Errorf("invalid length %d", arcs[0])
+	}
+	return 1, arcs[0], nil
+}
+
+// Delivers the first n arcs as octets.
+func octets(arcs asn1.ObjectIdentifier, n int) ([]byte, error) {
+	if n < 0 || len(arcs) < n {
+		return nil, errors.Errorf("expecting %d octets, found %d", n, len(arcs))
+	}
+	b := make([]byte, n)
+	for i, arc := range arcs[:n] {
+		if arc < 0 || arc > 255 {
+			return nil, errors.Errorf("arc %d is not an octet", arc)
+		}
+		b[i] = byte(arc)
+	}
+	return b, nil
+}
@@ -0,0 +1,116 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const ipNetToMediaEntry = "1.3.6.1.2.1.4.22.1"
+
+var ipNetToMediaIndex = IndexSpec{
+	{Name: "ipNetToMediaIfIndex", Type: IndexInteger},
+	{Name: "ipNetToMediaNetAddress", Type: IndexIPAddress},
+}
+
+func TestDecodeInstance(t *testing.T) {
+	oid, err := parseOID(ipNetToMediaEntry + ".2.3.192.168.1.254")
+	assert.NoError(t, err)
+
+	column, values, err := ipNetToMediaIndex.DecodeInstance(ipNetToMediaEntry, oid)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, column)
+	ifIndex, ok := values.Get("ipNetToMediaIfIndex")
+	assert.True(t, ok)
+	assert.Equal(t, "3", ifIndex.String())
+	address, ok := values.Get("ipNetToMediaNetAddress")
+	assert.True(t, ok)
+	assert.Equal(t, "192.168.1.254", address.String())
+	_, ok = values.Get("unknown")
+	assert.False(t, ok)
+
+	other, err := parseOID("1.3.6.1.2.1.4.21.1.2.3.192.168.1.254")
+	assert.NoError(t, err)
+	_, _, err = ipNetToMediaIndex.DecodeInstance(ipNetToMediaEntry, other)
+	assert.EqualError(t, err, "1.3.6.1.2.1.4.21.1.2.3.192.168.1.254 is not an instance of a column of "+ipNetToMediaEntry)
+}
+
+func TestDecodeIndex(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		spec     IndexSpec
+		index    asn1.ObjectIdentifier
+		expected []*TypedValue
+	}{
+		{
+			name:  "length-prefixed string and oid",
+			spec:  IndexSpec{{Name: "s", Type: IndexOctetString}, {Name: "o", Type: IndexOID}},
+			index: asn1.ObjectIdentifier{3, 'a', 'b', 'c', 2, 1, 3},
+			expected: []*TypedValue{
+				{Type: OctetString, Value: []byte("abc")},
+				{Type: OID, Value: asn1.ObjectIdentifier{1, 3}},
+			},
+		},
+		{
+			name:  "fixed-size string",
+			spec:  IndexSpec{{Name: "mac", Type: IndexOctetString, Size: 6}, {Name: "i", Type: IndexInteger}},
+			index: asn1.ObjectIdentifier{0, 17, 34, 51, 68, 85, 7},
+			expected: []*TypedValue{
+				{Type: OctetString, Value: []byte{0, 17, 34, 51, 68, 85}},
+				{Type: Integer, Value: int64(7)},
+			},
+		},
+		{
+			name:     "implied string",
+			spec:     IndexSpec{{Name: "i", Type: IndexInteger}, {Name: "name", Type: IndexOctetString, Implied: true}},
+			index:    asn1.ObjectIdentifier{1, 'e', 't', 'h'},
+			expected: []*TypedValue{{Type: Integer, Value: int64(1)}, {Type: OctetString, Value: []byte("eth")}},
+		},
+		{
+			name:     "implied oid",
+			spec:     IndexSpec{{Name: "o", Type: IndexOID, Implied: true}},
+			index:    asn1.ObjectIdentifier{1, 3, 6},
+			expected: []*TypedValue{{Type: OID, Value: asn1.ObjectIdentifier{1, 3, 6}}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := tc.spec.Decode(tc.index)
+			assert.NoError(t, err)
+			assert.Len(t, values, len(tc.expected))
+			for i, v := range values {
+				assert.Equal(t, tc.spec[i].Name, v.Name)
+				assert.Equal(t, tc.expected[i], v.Value)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalidIndex(t *testing.T) {
+	for _, tc := range []struct {
+		spec     IndexSpec
+		index    asn1.ObjectIdentifier
+		expected string
+	}{
+		{ipNetToMediaIndex, asn1.ObjectIdentifier{3, 192, 168},
+			"invalid index 3.192.168: component ipNetToMediaNetAddress: expecting 4 octets, found 2"},
+		{ipNetToMediaIndex, asn1.ObjectIdentifier{3, 192, 168, 1, 256},
+			"invalid index 3.192.168.1.256: component ipNetToMediaNetAddress: arc 256 is not an octet"},
+		{ipNetToMediaIndex, asn1.ObjectIdentifier{3, 192, 168, 1, 1, 9},
+			"invalid index 3.192.168.1.1.9: 1 arcs follow the last component"},
+		{ipNetToMediaIndex, asn1.ObjectIdentifier{}, "invalid index : component ipNetToMediaIfIndex: missing integer"},
+		{IndexSpec{{Name: "s", Type: IndexOctetString}}, asn1.ObjectIdentifier{4, 1, 2},
+			"invalid index 4.1.2: component s: expecting 4 octets, found 2"},
+		{IndexSpec{{Name: "o", Type: IndexOID}}, asn1.ObjectIdentifier{}, "invalid index : component o: missing length"},
+		{IndexSpec{{Name: "o", Type: IndexOID}}, asn1.ObjectIdentifier{-1, 1},
+			"invalid index -1.1: component o: invalid length -1"},
+		{IndexSpec{{Name: "s", Type: IndexOctetString}}, asn1.ObjectIdentifier{-2},
+			"invalid index -2: component s: invalid length -2"},
+		{IndexSpec{{Name: "s", Type: IndexOctetString, Implied: true}, {Name: "i", Type: IndexInteger}},
+			asn1.ObjectIdentifier{1, 1},
+			"index component s cannot be implied, as it is not the last"},
+	} {
+		_, err := tc.spec.Decode(tc.index)
+		assert.EqualError(t, err, tc.expected)
+	}
+}
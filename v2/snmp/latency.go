@@ -0,0 +1,121 @@
+package snmp
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets defines the bucket upper bounds used by a LatencyHistogram when none are specified.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// LatencyHistogram accumulates the latencies of successful requests in buckets, for SLA reporting.
+// A single histogram may be shared by many sessions, to aggregate the latencies of a set of targets.
+type LatencyHistogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// LatencySnapshot defines the content of a LatencyHistogram at a point in time.
+type LatencySnapshot struct {
+	// The upper bounds of the buckets, in ascending order.
+	Bounds []time.Duration
+	// The number of latencies in each bucket; Counts[i] is the number of latencies greater than Bounds[i-1] and no
+	// greater than Bounds[i]. The final element counts the latencies greater than the last bound.
+	Counts []uint64
+	// The number and sum of all latencies.
+	Count uint64
+	Sum   time.Duration
+	// The greatest latency observed.
+	Max time.Duration
+}
+
+// NewLatencyHistogram delivers a histogram with buckets defined by the upper bounds; if none are specified,
+// DefaultLatencyBuckets is used.
+func NewLatencyHistogram(bounds ...time.Duration) *LatencyHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	return &LatencyHistogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+// Observe records a latency.
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Snapshot delivers the current content of the histogram.
+func (h *LatencyHistogram) Snapshot() LatencySnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := LatencySnapshot{Bounds: h.bounds, Counts: append([]uint64(nil), h.counts...), Sum: h.sum, Max: h.max}
+	for _, c := range h.counts {
+		s.Count += c
+	}
+	return s
+}
+
+// Reset discards all recorded latencies.
+func (h *LatencyHistogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts = make([]uint64, len(h.bounds)+1)
+	h.sum, h.max = 0, 0
+}
+
+// Mean delivers the mean latency, or zero if none have been recorded.
+func (s LatencySnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile delivers an upper estimate of the latency below which the fraction q of latencies fall, being the upper
+// bound of the bucket holding the quantile, or the maximum latency if it falls beyond the last bound.
+// Zero is delivered if no latencies have been recorded.
+func (s LatencySnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i, c := range s.Counts {
+		cumulative += c
+		if cumulative >= rank && i < len(s.Bounds) {
+			return s.Bounds[i]
+		}
+	}
+	return s.Max
+}
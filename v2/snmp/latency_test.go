@@ -0,0 +1,115 @@
+package snmp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/golang/mock/gomock"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	h := NewLatencyHistogram(10*time.Millisecond, time.Millisecond, 100*time.Millisecond)
+	assert.Equal(t, LatencySnapshot{}.Quantile(0.5), time.Duration(0))
+
+	for _, d := range []time.Duration{500 * time.Microsecond, time.Millisecond, 5 * time.Millisecond,
+		50 * time.Millisecond, 200 * time.Millisecond} {
+		h.Observe(d)
+	}
+
+	s := h.Snapshot()
+	assert.Equal(t, []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond}, s.Bounds)
+	assert.Equal(t, []uint64{2, 1, 1, 1}, s.Counts)
+	assert.Equal(t, uint64(5), s.Count)
+	assert.Equal(t, 256500*time.Microsecond, s.Sum)
+	assert.Equal(t, 200*time.Millisecond, s.Max)
+	assert.Equal(t, 51300*time.Microsecond, s.Mean())
+
+	assert.Equal(t, time.Millisecond, s.Quantile(0))
+	assert.Equal(t, time.Millisecond, s.Quantile(0.4))
+	assert.Equal(t, 10*time.Millisecond, s.Quantile(0.5))
+	assert.Equal(t, 100*time.Millisecond, s.Quantile(0.8))
+	assert.Equal(t, 200*time.Millisecond, s.Quantile(0.99))
+
+	h.Reset()
+	s = h.Snapshot()
+	assert.Equal(t, uint64(0), s.Count)
+	assert.Equal(t, []uint64{0, 0, 0, 0}, s.Counts)
+	assert.Equal(t, time.Duration(0), s.Mean())
+
+	assert.Equal(t, DefaultLatencyBuckets, NewLatencyHistogram().Snapshot().Bounds)
+}
+
+func TestSessionLatency(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockConn(mockCtrl)
+
+	getResponse := []byte{
+		0x30, 0x82, 0x00, 0x36,
+		0x02, 0x01, 0x01,
+		0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
+		0xa2, 0x82, 0x00, 0x27,
+		0x02, 0x01, 0x02,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x82, 0x00, 0x1a,
+		0x30, 0x82, 0x00, 0x16,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00,
+		0x04, 0x0a, 0x63, 0x69, 0x73, 0x63, 0x6f, 0x2d, 0x37, 0x35, 0x31, 0x33,
+	}
+
+	gomock.InOrder(
+		// The first request succeeds after a retry, the second fails.
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).Return(0, &timeoutError{}),
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(func(input []byte) (int, error) {
+			return copy(input, getResponse), nil
+		}),
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).Return(0, &timeoutError{}),
+	)
+
+	type done struct {
+		start time.Time
+		d     time.Duration
+		err   error
+	}
+	var requests []done
+	config := DefaultConfig
+	config.address = localhost161
+	config.community = public
+	config.retries = 1
+	config.trace = &SessionTrace{}
+	*config.trace = *NoOpLoggingHooks
+	config.trace.RequestDone = func(config *SessionConfig, pdu *PDU, err error, start time.Time, d time.Duration) {
+		requests = append(requests, done{start: start, d: d, err: err})
+	}
+	config.latency = NewLatencyHistogram()
+	m := &sessionImpl{config: &config, conn: mockConn, nextRequestID: 1}
+
+	begin := time.Now()
+	_, err := m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.NoError(t, err)
+	config.retries = 0
+	_, err = m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.Error(t, err)
+
+	assert.Len(t, requests, 2)
+	assert.NoError(t, requests[0].err)
+	assert.Error(t, requests[1].err)
+	assert.False(t, requests[0].start.Before(begin))
+	assert.False(t, requests[1].start.Before(requests[0].start))
+
+	// Only the successful request is recorded.
+	s := m.Latency()
+	assert.Equal(t, uint64(1), s.Count)
+	assert.Equal(t, requests[0].d, s.Sum)
+}
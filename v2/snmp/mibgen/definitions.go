@@ -0,0 +1,36 @@
+package mibgen
+
+import (
+	"encoding/asn1"
+
+	"github.com/damianoneill/net/v2/snmp"
+)
+
+// Definitions parses the MIB module files, as supplied to Generate, and delivers a resolver holding the definition
+// of each object, notification and other definition of the modules, with the qualified name and enumerated value
+// labels described by the generated source, for example to enrich received traps using snmp.EnrichTraps.
+// As severities are not defined by MIB modules, the Severity of each definition is empty; it may be annotated by
+// adding a replacement definition.
+func Definitions(moduleFiles []string) (*snmp.MIBDefinitions, error) {
+	g, err := loadGenerator(moduleFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var defs []snmp.ObjectDefinition
+	for _, mod := range g.modules {
+		for _, def := range mod.definitions {
+			oid := g.oids[def.name]
+			od := snmp.ObjectDefinition{OID: make(asn1.ObjectIdentifier, len(oid)), Name: qualified(def)}
+			copy(od.OID, oid)
+			if enums := g.enums(def); len(enums) > 0 {
+				od.Enums = make(map[int]string, len(enums))
+				for _, e := range enums {
+					od.Enums[e.value] = e.label
+				}
+			}
+			defs = append(defs, od)
+		}
+	}
+	return snmp.NewMIBDefinitions(defs...), nil
+}
@@ -0,0 +1,35 @@
+package mibgen
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/damianoneill/net/v2/snmp"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestDefinitions(t *testing.T) {
+	defs, err := Definitions([]string{"testdata/TEST-MIB.txt", "testdata/TEST-V1-MIB.txt"})
+	assert.NoError(t, err)
+
+	def, index, ok := defs.Lookup(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 2, 1, 3, 3})
+	assert.True(t, ok)
+	assert.Equal(t, "TEST-MIB::testPortStatus", def.Name)
+	assert.Equal(t, asn1.ObjectIdentifier{3}, index)
+	assert.Equal(t, "lowerLayerDown", def.Enums[7], "Expecting the enumeration of the textual convention")
+
+	td := &snmp.TrapData{
+		SnmpTrapOID: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 0, 1},
+		Varbinds: []snmp.Varbind{{
+			OID:        asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 2, 1, 3, 3},
+			TypedValue: &snmp.TypedValue{Type: snmp.Integer, Value: int64(2)},
+		}},
+	}
+	e := td.Enrich(defs)
+	assert.Equal(t, "TEST-MIB::testPortDown", e.Name)
+	assert.Equal(t, snmp.EnrichedVarbind{Name: "TEST-MIB::testPortStatus.3", Value: "down(2)"}, e.Varbinds[0])
+
+	_, err = Definitions([]string{"testdata/missing.txt"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,337 @@
+// Package mibgen generates Go source from MIB modules, defining constants for the object identifiers and enumerated
+// values of the modules, and metadata describing their tables, so that applications can refer to objects by
+// compile-time checked names rather than OID strings.
+//
+// Generate is intended to be called from a program run by go:generate, for example:
+//
+//	//go:generate go run ./gen
+//
+// where gen/main.go calls mibgen.Generate([]string{"mibs/IF-MIB.txt"}, "ifmib", out).
+package mibgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Table defines the structure of a conceptual table, as generated for each table defined by the modules.
+type Table struct {
+	// The qualified name of the table, for example "IF-MIB::ifTable".
+	Name string
+	// The object identifiers of the table and its entry.
+	OID   string
+	Entry string
+	// The names of the objects that index the table entries.
+	Index []string
+	// The columns of the table, in object identifier order.
+	Columns []Column
+}
+
+// Column defines a column of a table.
+type Column struct {
+	// The qualified name of the column, for example "IF-MIB::ifDescr".
+	Name string
+	// The object identifier of the column.
+	OID string
+	// The syntax of the column, for example "DisplayString".
+	Syntax string
+	// The maximum access of the column, for example "read-only".
+	Access string
+}
+
+// Object identifiers defined by SNMPv2-SMI and the root arcs, available to all modules.
+var builtinOIDs = map[string][]int{
+	"ccitt":           {0},
+	"zeroDotZero":     {0, 0},
+	"iso":             {1},
+	"joint-iso-ccitt": {2},
+	"org":             {1, 3},
+	"dod":             {1, 3, 6},
+	"internet":        {1, 3, 6, 1},
+	"directory":       {1, 3, 6, 1, 1},
+	"mgmt":            {1, 3, 6, 1, 2},
+	"mib-2":           {1, 3, 6, 1, 2, 1},
+	"transmission":    {1, 3, 6, 1, 2, 1, 10},
+	"experimental":    {1, 3, 6, 1, 3},
+	"private":         {1, 3, 6, 1, 4},
+	"enterprises":     {1, 3, 6, 1, 4, 1},
+	"security":        {1, 3, 6, 1, 5},
+	"snmpV2":          {1, 3, 6, 1, 6},
+	"snmpDomains":     {1, 3, 6, 1, 6, 1},
+	"snmpProxys":      {1, 3, 6, 1, 6, 2},
+	"snmpModules":     {1, 3, 6, 1, 6, 3},
+}
+
+// Generate parses the MIB module files and writes Go source for package pkgName to w, defining:
+//   - a string constant holding the dotted object identifier of each object, notification and other definition,
+//     named after the definition, for example IfDescr = "1.3.6.1.2.1.2.2.1.2";
+//   - an integer constant for each enumerated value of an object, for example IfOperStatusDown = 2;
+//   - a Table variable describing each table, named after the table with the suffix Info, for example IfTableInfo.
+//
+// Definitions imported by a module must be defined by one of the files, or by SNMPv2-SMI.
+func Generate(moduleFiles []string, pkgName string, w io.Writer) error {
+	g, err := loadGenerator(moduleFiles)
+	if err != nil {
+		return err
+	}
+	src, err := format.Source(g.generate(pkgName))
+	if err != nil {
+		return errors.Wrap(err, "failed to format generated source")
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// Parses the MIB module files, and delivers a generator for the definitions of the modules.
+func loadGenerator(moduleFiles []string) (*generator, error) {
+	var modules []*module
+	for _, file := range moduleFiles {
+		mod, err := parseFile(file)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, mod)
+	}
+	return newGenerator(modules)
+}
+
+func parseFile(file string) (*module, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mod, err := parseModule(f)
+	return mod, errors.Wrapf(err, "failed to parse %s", file)
+}
+
+type generator struct {
+	modules     []*module
+	definitions map[string]*definition
+	conventions map[string]*textualConvention
+	oids        map[string][]int
+}
+
+func newGenerator(modules []*module) (*generator, error) {
+	g := &generator{
+		modules:     modules,
+		definitions: map[string]*definition{},
+		conventions: map[string]*textualConvention{},
+		oids:        map[string][]int{},
+	}
+	for _, mod := range modules {
+		for name, tc := range mod.conventions {
+			g.conventions[name] = tc
+		}
+		for _, def := range mod.definitions {
+			if existing, ok := g.definitions[def.name]; ok && existing.module != def.module {
+				return nil, errors.Errorf("%s defined by both %s and %s", def.name, existing.module, def.module)
+			}
+			g.definitions[def.name] = def
+		}
+	}
+	for _, mod := range modules {
+		for _, def := range mod.definitions {
+			if _, err := g.resolve(def.name, nil); err != nil {
+				return nil, errors.Wrap(err, mod.name)
+			}
+		}
+	}
+	return g, nil
+}
+
+// Resolves the object identifier of the named definition. pending holds the definitions being resolved, to
+// detect cycles.
+func (g *generator) resolve(name string, pending map[string]bool) ([]int, error) {
+	if oid, ok := g.oids[name]; ok {
+		return oid, nil
+	}
+	def, ok := g.definitions[name]
+	if !ok {
+		if oid, ok := builtinOIDs[name]; ok {
+			return oid, nil
+		}
+		return nil, errors.Errorf("unresolved object identifier %s", name)
+	}
+	if pending[name] {
+		return nil, errors.Errorf("cyclic object identifier %s", name)
+	}
+	if pending == nil {
+		pending = map[string]bool{}
+	}
+	pending[name] = true
+
+	parent, err := g.resolve(def.parent, pending)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parent of %s", name)
+	}
+	oid := append(append([]int{}, parent...), def.arcs...)
+	g.oids[name] = oid
+	return oid, nil
+}
+
+func (g *generator) generate(pkgName string) []byte {
+	tables := g.tables()
+
+	b := &bytes.Buffer{}
+	names := make([]string, len(g.modules))
+	for i, mod := range g.modules {
+		names[i] = mod.name
+	}
+	fmt.Fprintf(b, "// Code generated by mibgen from %s. DO NOT EDIT.\n\n", strings.Join(names, ", "))
+	fmt.Fprintf(b, "package %s\n\n", pkgName)
+	if len(tables) > 0 {
+		b.WriteString("import \"github.com/damianoneill/net/v2/snmp/mibgen\"\n\n")
+	}
+
+	for _, mod := range g.modules {
+		fmt.Fprintf(b, "// Object identifiers defined by %s.\nconst (\n", mod.name)
+		for _, def := range mod.definitions {
+			fmt.Fprintf(b, "\t// %s is %s.\n", identifier(def.name), describe(def))
+			fmt.Fprintf(b, "\t%s = %q\n", identifier(def.name), formatOID(g.oids[def.name]))
+		}
+		b.WriteString(")\n\n")
+
+		var enums bytes.Buffer
+		for _, def := range mod.definitions {
+			for _, e := range g.enums(def) {
+				fmt.Fprintf(&enums, "\t%s%s = %d\n", identifier(def.name), identifier(e.label), e.value)
+			}
+		}
+		if enums.Len() > 0 {
+			fmt.Fprintf(b, "// Enumerated values of objects defined by %s.\nconst (\n%s)\n\n", mod.name, enums.String())
+		}
+	}
+
+	for _, t := range tables {
+		fmt.Fprintf(b, "// %sInfo describes %s.\n", identifier(t.def.name), qualified(t.def))
+		fmt.Fprintf(b, "var %sInfo = mibgen.Table{\n", identifier(t.def.name))
+		fmt.Fprintf(b, "\tName: %q,\n\tOID: %s,\n\tEntry: %s,\n", qualified(t.def), identifier(t.def.name), identifier(t.entry.name))
+		fmt.Fprintf(b, "\tIndex: %#v,\n\tColumns: []mibgen.Column{\n", t.index)
+		for _, c := range t.columns {
+			fmt.Fprintf(b, "\t\t{Name: %q, OID: %s, Syntax: %q, Access: %q},\n", qualified(c), identifier(c.name), c.syntax, c.access)
+		}
+		b.WriteString("\t},\n}\n\n")
+	}
+	return b.Bytes()
+}
+
+// Delivers the enumerated values of the definition, defined either by its syntax or by its textual convention.
+func (g *generator) enums(def *definition) []enum {
+	if len(def.enums) > 0 || def.macro != "OBJECT-TYPE" {
+		return def.enums
+	}
+	if tc, ok := g.conventions[def.syntax]; ok {
+		return tc.enums
+	}
+	return nil
+}
+
+type table struct {
+	def     *definition
+	entry   *definition
+	index   []string
+	columns []*definition
+}
+
+// Delivers the tables defined by the modules, in definition order.
+func (g *generator) tables() []*table {
+	children := map[string][]*definition{}
+	for _, mod := range g.modules {
+		for _, def := range mod.definitions {
+			children[def.parent] = append(children[def.parent], def)
+		}
+	}
+
+	var tables []*table
+	for _, mod := range g.modules {
+		for _, def := range mod.definitions {
+			if !strings.HasPrefix(def.syntax, "SEQUENCE OF ") {
+				continue
+			}
+			for _, entry := range children[def.name] {
+				if entry.macro != "OBJECT-TYPE" {
+					continue
+				}
+				t := &table{def: def, entry: entry, index: g.index(entry)}
+				t.columns = append(t.columns, children[entry.name]...)
+				sort.SliceStable(t.columns, func(i, j int) bool {
+					return lessOID(g.oids[t.columns[i].name], g.oids[t.columns[j].name])
+				})
+				tables = append(tables, t)
+				break
+			}
+		}
+	}
+	return tables
+}
+
+// Delivers the index of a table entry, which may be defined by the entry that it augments.
+func (g *generator) index(entry *definition) []string {
+	for i := 0; entry.augments != "" && i < len(g.definitions); i++ {
+		augmented, ok := g.definitions[entry.augments]
+		if !ok {
+			break
+		}
+		entry = augmented
+	}
+	if entry.index == nil {
+		return []string{}
+	}
+	return entry.index
+}
+
+func lessOID(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func qualified(def *definition) string {
+	return def.module + "::" + def.name
+}
+
+func describe(def *definition) string {
+	switch {
+	case def.syntax != "" && def.access != "":
+		return fmt.Sprintf("%s (%s, %s)", qualified(def), def.syntax, def.access)
+	case def.syntax != "":
+		return fmt.Sprintf("%s (%s)", qualified(def), def.syntax)
+	}
+	return fmt.Sprintf("%s (%s)", qualified(def), def.macro)
+}
+
+// Delivers an exported Go identifier for a MIB name, for example IfDescr for ifDescr, or LowerLayerDown for
+// lowerLayerDown.
+func identifier(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			upper = true
+		case upper:
+			sb.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	id := sb.String()
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "X" + id
+	}
+	return id
+}
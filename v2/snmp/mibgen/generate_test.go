@@ -0,0 +1,88 @@
+package mibgen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := Generate([]string{"testdata/TEST-MIB.txt", "testdata/TEST-V1-MIB.txt"}, "testmib", out)
+	assert.NoError(t, err)
+	src := out.String()
+
+	fset := token.NewFileSet()
+	file, err := goparser.ParseFile(fset, "testmib.go", src, goparser.AllErrors)
+	assert.NoError(t, err, "Expecting valid Go source")
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("testmib", fset, []*ast.File{file}, nil)
+	assert.NoError(t, err, "Expecting generated source to type-check")
+
+	for _, expected := range []string{
+		"// Code generated by mibgen from TEST-MIB, TEST-V1-MIB. DO NOT EDIT.",
+		"package testmib",
+		`TestMIB = "1.3.6.1.4.1.99999"`,
+		"// TestPortCount is TEST-MIB::testPortCount (Integer32, read-only).",
+		`TestPortCount = "1.3.6.1.4.1.99999.1.1"`,
+		`TestPortName = "1.3.6.1.4.1.99999.1.2.1.2"`,
+		"// TestPortDown is TEST-MIB::testPortDown (NOTIFICATION-TYPE).",
+		`TestPortDown = "1.3.6.1.4.1.99999.0.1"`,
+		"TestPortStatusLowerLayerDown = 7",
+		`TestV1 = "1.3.6.1.4.1.99998"`,
+		`TestV1Alias = "1.3.6.1.4.1.99999.9"`,
+		`TestV1Trap = "1.3.6.1.4.1.99998.0.3"`,
+		"var TestPortTableInfo = mibgen.Table{",
+		`Index: []string{"testPortIndex"},`,
+		`{Name: "TEST-MIB::testPortStatus", OID: TestPortStatus, Syntax: "PortStatus", Access: "read-only"},`,
+	} {
+		assert.Contains(t, src, expected)
+	}
+
+	assert.Regexp(t, `TestPortAdminDisabled\s+= 2`, src)
+
+	// Columns are ordered by object identifier, and an augmenting table takes the index of the augmented entry.
+	assert.Less(t, bytes.Index(out.Bytes(), []byte("OID: TestPortStatus,")), bytes.Index(out.Bytes(), []byte("OID: TestPortErrors,")))
+	stats := src[bytes.Index(out.Bytes(), []byte("var TestPortStatsTableInfo")):]
+	assert.Contains(t, stats, `Index: []string{"testPortIndex"},`)
+}
+
+func TestGenerateFailures(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+		return file
+	}
+
+	err := Generate([]string{filepath.Join(dir, "missing.txt")}, "p", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	err = Generate([]string{write("bad.txt", "not a module")}, "p", &bytes.Buffer{})
+	assert.EqualError(t, err, "failed to parse "+filepath.Join(dir, "bad.txt")+": missing module header")
+
+	unresolved := write("A-MIB.txt", "A-MIB DEFINITIONS ::= BEGIN\n a OBJECT IDENTIFIER ::= { unknown 1 }\nEND\n")
+	err = Generate([]string{unresolved}, "p", &bytes.Buffer{})
+	assert.EqualError(t, err, "A-MIB: parent of a: unresolved object identifier unknown")
+
+	cyclic := write("C-MIB.txt", "C-MIB DEFINITIONS ::= BEGIN\n a OBJECT IDENTIFIER ::= { b 1 }\n b OBJECT IDENTIFIER ::= { a 1 }\nEND\n")
+	err = Generate([]string{cyclic}, "p", &bytes.Buffer{})
+	assert.Contains(t, err.Error(), "cyclic object identifier")
+
+	dup := write("B-MIB.txt", "B-MIB DEFINITIONS ::= BEGIN\n a OBJECT IDENTIFIER ::= { iso 1 }\nEND\n")
+	dup2 := write("D-MIB.txt", "D-MIB DEFINITIONS ::= BEGIN\n a OBJECT IDENTIFIER ::= { iso 2 }\nEND\n")
+	err = Generate([]string{dup, dup2}, "p", &bytes.Buffer{})
+	assert.EqualError(t, err, "a defined by both B-MIB and D-MIB")
+
+	unterminated := write("E-MIB.txt", "E-MIB DEFINITIONS ::= BEGIN\n a OBJECT IDENTIFIER ::= { iso 1 }\n")
+	err = Generate([]string{unterminated}, "p", &bytes.Buffer{})
+	assert.Contains(t, err.Error(), "E-MIB: missing END")
+}
@@ -0,0 +1,388 @@
+package mibgen
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Parsing of SMIv1 and SMIv2 MIB modules, sufficient to determine the object identifiers, syntaxes, enumerations
+// and table structure that are generated. Clauses that do not contribute to the generated source are skipped.
+
+// Macros that define object identifiers.
+var oidMacros = map[string]bool{
+	"OBJECT-TYPE":        true,
+	"MODULE-IDENTITY":    true,
+	"OBJECT-IDENTITY":    true,
+	"NOTIFICATION-TYPE":  true,
+	"OBJECT-GROUP":       true,
+	"NOTIFICATION-GROUP": true,
+	"MODULE-COMPLIANCE":  true,
+	"AGENT-CAPABILITIES": true,
+	"TRAP-TYPE":          true,
+}
+
+// A definition of an object identifier.
+type definition struct {
+	module string
+	name   string
+	macro  string
+	// The definition's object identifier is the identifier of parent followed by arcs.
+	parent string
+	arcs   []int
+	// OBJECT-TYPE clauses.
+	syntax   string
+	access   string
+	enums    []enum
+	index    []string
+	augments string
+}
+
+type enum struct {
+	label string
+	value int
+}
+
+// A textual convention, or type assignment.
+type textualConvention struct {
+	syntax string
+	enums  []enum
+}
+
+type module struct {
+	name        string
+	definitions []*definition
+	conventions map[string]*textualConvention
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+	mod    *module
+}
+
+func parseModule(r io.Reader) (*module, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokenize(string(b))}
+	if err = p.parse(); err != nil {
+		return nil, err
+	}
+	return p.mod, nil
+}
+
+func (p *parser) parse() error {
+	if len(p.tokens) < 4 || p.tokens[1] != "DEFINITIONS" || p.tokens[2] != "::=" || p.tokens[3] != "BEGIN" {
+		return errors.New("missing module header")
+	}
+	p.mod = &module{name: p.tokens[0], conventions: map[string]*textualConvention{}}
+	p.pos = 4
+
+	for p.pos < len(p.tokens) {
+		tok := p.tokens[p.pos]
+		var err error
+		switch {
+		case tok == "END":
+			return nil
+		case tok == "IMPORTS":
+			p.skipTo(";")
+		case isUpper(tok) && p.peek(1) == "MACRO":
+			// Macro definitions, as found in SNMPv2-SMI, end with an END that does not end the module.
+			p.skipTo("END")
+		case isLower(tok) && p.peek(1) == "OBJECT" && p.peek(2) == "IDENTIFIER" && p.peek(3) == "::=":
+			p.pos += 4
+			err = p.parseAssignment(&definition{name: tok, macro: "OBJECT IDENTIFIER"})
+		case isLower(tok) && oidMacros[p.peek(1)]:
+			p.pos += 2
+			err = p.parseMacro(&definition{name: tok, macro: p.peek(-1)})
+		case isUpper(tok) && p.peek(1) == "::=":
+			p.pos += 2
+			p.parseType(tok)
+		default:
+			p.pos++
+		}
+		if err != nil {
+			return errors.Wrapf(err, "%s: %s", p.mod.name, tok)
+		}
+	}
+	return errors.Errorf("%s: missing END", p.mod.name)
+}
+
+// Parses the clauses of a macro up to its value assignment.
+func (p *parser) parseMacro(def *definition) error {
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case "::=":
+			if def.macro == "TRAP-TYPE" {
+				return p.parseTrapValue(def)
+			}
+			return p.parseAssignment(def)
+		case "SYNTAX":
+			def.syntax, def.enums = p.parseSyntax()
+		case "MAX-ACCESS", "ACCESS":
+			def.access = p.next()
+		case "INDEX":
+			for _, name := range p.braceList() {
+				def.index = append(def.index, strings.TrimPrefix(name, "IMPLIED "))
+			}
+		case "AUGMENTS":
+			if names := p.braceList(); len(names) > 0 {
+				def.augments = names[0]
+			}
+		case "ENTERPRISE":
+			def.parent = p.next()
+		}
+	}
+	return errors.New("missing value assignment")
+}
+
+// Parses an object identifier value, for example { ifEntry 2 } or { iso org(3) dod(6) 1 }.
+func (p *parser) parseAssignment(def *definition) error {
+	if p.next() != "{" {
+		return errors.New("expected object identifier value")
+	}
+	for i := 0; p.pos < len(p.tokens); i++ {
+		tok := p.next()
+		if tok == "}" {
+			if def.parent == "" {
+				return errors.New("empty object identifier value")
+			}
+			def.module = p.mod.name
+			p.mod.definitions = append(p.mod.definitions, def)
+			return nil
+		}
+		arc, err := strconv.Atoi(tok)
+		switch {
+		case i == 0 && err != nil:
+			def.parent = tok
+			p.skipNamedNumber()
+		case i == 0:
+			return errors.New("object identifier value must start with a name")
+		case err == nil:
+			def.arcs = append(def.arcs, arc)
+		default:
+			// A named number, for example dod(6).
+			if p.peek(0) != "(" {
+				return errors.Errorf("unexpected %q in object identifier value", tok)
+			}
+			p.pos++
+			if arc, err = strconv.Atoi(p.next()); err != nil || p.next() != ")" {
+				return errors.Errorf("invalid named number %q", tok)
+			}
+			def.arcs = append(def.arcs, arc)
+		}
+	}
+	return errors.New("unterminated object identifier value")
+}
+
+// Parses the value of an SMIv1 TRAP-TYPE, whose object identifier is formed from the enterprise, as described in
+// https://tools.ietf.org/html/rfc3584#section-3.
+func (p *parser) parseTrapValue(def *definition) error {
+	specific, err := strconv.Atoi(p.next())
+	if err != nil || def.parent == "" {
+		return errors.New("invalid trap definition")
+	}
+	def.arcs = []int{0, specific}
+	def.module = p.mod.name
+	p.mod.definitions = append(p.mod.definitions, def)
+	return nil
+}
+
+// Parses a type assignment, recording textual conventions and other named types.
+func (p *parser) parseType(name string) {
+	if p.peek(0) == "TEXTUAL-CONVENTION" {
+		for p.pos < len(p.tokens) && p.peek(0) != "SYNTAX" {
+			p.pos++
+		}
+		p.pos++
+	}
+	syntax, enums := p.parseSyntax()
+	p.mod.conventions[name] = &textualConvention{syntax: syntax, enums: enums}
+}
+
+// Parses a syntax, returning its type name and any enumerated values. Constraints are skipped.
+func (p *parser) parseSyntax() (syntax string, enums []enum) {
+	syntax = p.next()
+	switch {
+	case syntax == "OCTET" && p.peek(0) == "STRING", syntax == "OBJECT" && p.peek(0) == "IDENTIFIER":
+		syntax += " " + p.next()
+	case syntax == "SEQUENCE" && p.peek(0) == "OF":
+		p.pos++
+		syntax += " OF " + p.next()
+	}
+
+	switch p.peek(0) {
+	case "{":
+		end := p.matching()
+		if syntax == "SEQUENCE" {
+			p.pos = end + 1
+			return syntax, nil
+		}
+		for i := p.pos; i+3 < end; i++ {
+			if p.tokens[i+1] == "(" && p.tokens[i+3] == ")" {
+				if value, err := strconv.Atoi(p.tokens[i+2]); err == nil {
+					enums = append(enums, enum{label: p.tokens[i], value: value})
+				}
+			}
+		}
+		p.pos = end + 1
+	case "(":
+		p.pos = p.matching() + 1
+	}
+	return syntax, enums
+}
+
+// Parses a list of names within braces, for example the INDEX clause { IMPLIED ifName, ifIndex }.
+func (p *parser) braceList() []string {
+	if p.peek(0) != "{" {
+		return nil
+	}
+	end := p.matching()
+	var names []string
+	implied := false
+	for _, tok := range p.tokens[p.pos+1 : end] {
+		switch tok {
+		case ",":
+		case "IMPLIED":
+			implied = true
+		default:
+			if implied {
+				tok = "IMPLIED " + tok
+			}
+			names = append(names, tok)
+			implied = false
+		}
+	}
+	p.pos = end + 1
+	return names
+}
+
+// Delivers the position of the token closing the bracket at the current position.
+func (p *parser) matching() int {
+	open := p.tokens[p.pos]
+	closing := map[string]string{"{": "}", "(": ")"}[open]
+	depth := 0
+	for i := p.pos; i < len(p.tokens); i++ {
+		switch p.tokens[i] {
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(p.tokens) - 1
+}
+
+func (p *parser) skipNamedNumber() {
+	if p.peek(0) == "(" {
+		p.pos = p.matching() + 1
+	}
+}
+
+func (p *parser) skipTo(tok string) {
+	for p.pos < len(p.tokens) && p.next() != tok {
+	}
+}
+
+func (p *parser) next() string {
+	if p.pos >= len(p.tokens) {
+		p.pos++
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *parser) peek(offset int) string {
+	if i := p.pos + offset; i >= 0 && i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return ""
+}
+
+// Splits MIB source into tokens, discarding comments. Quoted strings are delivered as single tokens, including
+// their quotes.
+func tokenize(src string) []string {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			i = skipComment(src, i+2)
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				end = len(src) - i - 1
+			}
+			tokens = append(tokens, src[i:i+end+2])
+			i += end + 2
+		case strings.HasPrefix(src[i:], "::="):
+			tokens = append(tokens, "::=")
+			i += 3
+		case strings.HasPrefix(src[i:], ".."):
+			tokens = append(tokens, "..")
+			i += 2
+		case strings.ContainsRune("{}(),;|", rune(c)):
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			start := i
+			for i < len(src) && isWordChar(src[i]) && !strings.HasPrefix(src[i:], "--") &&
+				!strings.HasPrefix(src[i:], "..") {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			tokens = append(tokens, src[start:i])
+		}
+	}
+	return tokens
+}
+
+// Delivers the position following a comment starting at i, which ends at the end of the line or at the next "--".
+func skipComment(src string, i int) int {
+	for i < len(src) {
+		switch {
+		case src[i] == '\n':
+			return i + 1
+		case strings.HasPrefix(src[i:], "--"):
+			return i + 2
+		}
+		i++
+	}
+	return i
+}
+
+func isWordChar(c byte) bool {
+	return c == '-' || c == '_' || c == '\'' || c < unicode.MaxASCII && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)))
+}
+
+func isLower(tok string) bool {
+	return tok != "" && unicode.IsLower(rune(tok[0]))
+}
+
+func isUpper(tok string) bool {
+	return tok != "" && unicode.IsUpper(rune(tok[0]))
+}
+
+// Formats an object identifier.
+func formatOID(oid []int) string {
+	parts := make([]string, len(oid))
+	for i, arc := range oid {
+		parts[i] = fmt.Sprint(arc)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,160 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"sort"
+)
+
+// OIDTree is a prefix tree of values keyed by object identifier.
+// It supports exact and longest-prefix lookup in time proportional to the depth of the OID, so is suited to
+// dispatching varbinds or traps to handlers registered against OID prefixes.
+// Children of each node are held in a slice ordered by arc, so that iteration is in lexicographic OID order.
+// An OIDTree is not safe for concurrent modification.
+type OIDTree struct {
+	root oidNode
+	size int
+}
+
+type oidNode struct {
+	children []oidEdge
+	value    interface{}
+	isSet    bool
+}
+
+type oidEdge struct {
+	arc  int
+	node *oidNode
+}
+
+// OIDWalker is a function type that will be called for each entry visited by OIDTree.Walk.
+// If it returns an error, the walk terminates and the error is returned.
+type OIDWalker func(oid asn1.ObjectIdentifier, value interface{}) error
+
+// NewOIDTree delivers an empty OIDTree.
+func NewOIDTree() *OIDTree {
+	return &OIDTree{}
+}
+
+// Len returns the number of entries in the tree.
+func (t *OIDTree) Len() int {
+	return t.size
+}
+
+// Insert stores the value against the oid, replacing any existing value.
+func (t *OIDTree) Insert(oid asn1.ObjectIdentifier, value interface{}) {
+	n := &t.root
+	for _, arc := range oid {
+		n = n.child(arc, true)
+	}
+	if !n.isSet {
+		t.size++
+	}
+	n.value, n.isSet = value, true
+}
+
+// Get returns the value stored against the oid, and whether it was found.
+func (t *OIDTree) Get(oid asn1.ObjectIdentifier) (interface{}, bool) {
+	n := t.find(oid)
+	if n == nil || !n.isSet {
+		return nil, false
+	}
+	return n.value, true
+}
+
+// Delete removes the value stored against the oid, returning whether it was found.
+func (t *OIDTree) Delete(oid asn1.ObjectIdentifier) bool {
+	n := t.find(oid)
+	if n == nil || !n.isSet {
+		return false
+	}
+	n.value, n.isSet = nil, false
+	t.size--
+	t.root.prune(oid)
+	return true
+}
+
+// LongestPrefix returns the entry whose oid is the longest prefix of (or equal to) the supplied oid, and
+// whether such an entry was found.
+func (t *OIDTree) LongestPrefix(oid asn1.ObjectIdentifier) (prefix asn1.ObjectIdentifier, value interface{}, ok bool) {
+	n := &t.root
+	depth := -1
+	if n.isSet {
+		depth, value = 0, n.value
+	}
+	for i, arc := range oid {
+		if n = n.child(arc, false); n == nil {
+			break
+		}
+		if n.isSet {
+			depth, value = i+1, n.value
+		}
+	}
+	if depth < 0 {
+		return nil, nil, false
+	}
+	return oid[:depth:depth], value, true
+}
+
+// Walk calls walker for each entry whose oid equals or is a descendant of root, in lexicographic oid order.
+func (t *OIDTree) Walk(root asn1.ObjectIdentifier, walker OIDWalker) error {
+	n := t.find(root)
+	if n == nil {
+		return nil
+	}
+	oid := make(asn1.ObjectIdentifier, len(root), len(root)+8) //nolint: gomnd
+	copy(oid, root)
+	return n.walk(oid, walker)
+}
+
+func (t *OIDTree) find(oid asn1.ObjectIdentifier) *oidNode {
+	n := &t.root
+	for _, arc := range oid {
+		if n = n.child(arc, false); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Delivers the child node for the arc, optionally creating it if it does not exist.
+func (n *oidNode) child(arc int, create bool) *oidNode {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].arc >= arc })
+	if i < len(n.children) && n.children[i].arc == arc {
+		return n.children[i].node
+	}
+	if !create {
+		return nil
+	}
+	c := &oidNode{}
+	n.children = append(n.children, oidEdge{})
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = oidEdge{arc: arc, node: c}
+	return c
+}
+
+// Removes nodes along the oid path that no longer hold a value or have children, returning whether n
+// itself is now empty.
+func (n *oidNode) prune(oid asn1.ObjectIdentifier) bool {
+	if len(oid) > 0 {
+		i := sort.Search(len(n.children), func(i int) bool { return n.children[i].arc >= oid[0] })
+		if i < len(n.children) && n.children[i].arc == oid[0] && n.children[i].node.prune(oid[1:]) {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+		}
+	}
+	return !n.isSet && len(n.children) == 0
+}
+
+func (n *oidNode) walk(oid asn1.ObjectIdentifier, walker OIDWalker) error {
+	if n.isSet {
+		// Deliver a copy, so the walker may retain the oid.
+		if err := walker(append(asn1.ObjectIdentifier(nil), oid...), n.value); err != nil {
+			return err
+		}
+	}
+	for _, e := range n.children {
+		if err := e.node.walk(append(oid, e.arc), walker); err != nil {
+			return err
+		}
+	}
+	return nil
+}
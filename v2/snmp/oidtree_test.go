@@ -0,0 +1,123 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestOIDTreeInsertGetDelete(t *testing.T) {
+	tree := NewOIDTree()
+	assert.Equal(t, 0, tree.Len())
+
+	tree.Insert(asn1.ObjectIdentifier{1, 3, 6, 1}, "internet")
+	tree.Insert(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1}, "mib-2")
+	tree.Insert(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1}, "mib-2 replaced")
+	assert.Equal(t, 2, tree.Len())
+
+	value, ok := tree.Get(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1})
+	assert.True(t, ok)
+	assert.Equal(t, "mib-2 replaced", value)
+
+	_, ok = tree.Get(asn1.ObjectIdentifier{1, 3, 6})
+	assert.False(t, ok, "Intermediate node should not hold a value")
+	_, ok = tree.Get(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1})
+	assert.False(t, ok, "Unknown oid should not be found")
+
+	assert.True(t, tree.Delete(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1}))
+	assert.False(t, tree.Delete(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1}))
+	assert.Equal(t, 1, tree.Len())
+	assert.Empty(t, tree.find(asn1.ObjectIdentifier{1, 3, 6, 1}).children, "Expected empty nodes to be pruned")
+
+	value, ok = tree.Get(asn1.ObjectIdentifier{1, 3, 6, 1})
+	assert.True(t, ok)
+	assert.Equal(t, "internet", value)
+}
+
+func TestOIDTreeLongestPrefix(t *testing.T) {
+	tree := NewOIDTree()
+	tree.Insert(LinkDownOID, "linkDown")
+	tree.Insert(asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5}, "snmpTraps")
+	tree.Insert(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1}, "enterprises")
+
+	prefix, value, ok := tree.LongestPrefix(LinkDownOID)
+	assert.True(t, ok)
+	assert.Equal(t, LinkDownOID, prefix)
+	assert.Equal(t, "linkDown", value)
+
+	prefix, value, ok = tree.LongestPrefix(LinkUpOID)
+	assert.True(t, ok)
+	assert.Equal(t, asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5}, prefix)
+	assert.Equal(t, "snmpTraps", value)
+
+	prefix, value, ok = tree.LongestPrefix(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 9, 9, 41, 2})
+	assert.True(t, ok)
+	assert.Equal(t, asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1}, prefix)
+	assert.Equal(t, "enterprises", value)
+
+	_, _, ok = tree.LongestPrefix(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1})
+	assert.False(t, ok, "Not expecting a match")
+
+	tree.Insert(asn1.ObjectIdentifier{}, "default")
+	prefix, value, ok = tree.LongestPrefix(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1})
+	assert.True(t, ok)
+	assert.Empty(t, prefix)
+	assert.Equal(t, "default", value)
+}
+
+func TestOIDTreeWalk(t *testing.T) {
+	tree := NewOIDTree()
+	for _, oid := range []asn1.ObjectIdentifier{
+		{1, 3, 6, 1, 2, 1, 2, 2, 1, 10, 1},
+		{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 1},
+		{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 10},
+		{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 2},
+		{1, 3, 6, 1, 2, 1, 1, 5, 0},
+	} {
+		tree.Insert(oid, oid.String())
+	}
+
+	var visited []string
+	err := tree.Walk(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2}, func(oid asn1.ObjectIdentifier, value interface{}) error {
+		assert.Equal(t, oid.String(), value)
+		visited = append(visited, oid.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"1.3.6.1.2.1.2.2.1.2.1",
+		"1.3.6.1.2.1.2.2.1.2.2",
+		"1.3.6.1.2.1.2.2.1.2.10",
+		"1.3.6.1.2.1.2.2.1.10.1",
+	}, visited, "Expected lexicographic order")
+
+	stop := errors.New("stop")
+	count := 0
+	err = tree.Walk(nil, func(oid asn1.ObjectIdentifier, value interface{}) error {
+		count++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, count)
+
+	err = tree.Walk(asn1.ObjectIdentifier{1, 3, 6, 1, 4}, func(oid asn1.ObjectIdentifier, value interface{}) error {
+		return fmt.Errorf("not expecting %s", oid)
+	})
+	assert.NoError(t, err, "Expecting unknown root to visit nothing")
+}
+
+func BenchmarkOIDTreeLongestPrefix(b *testing.B) {
+	tree := NewOIDTree()
+	for i := 0; i < 1000; i++ {
+		tree.Insert(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, i}, i)
+	}
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 999, 1, 2, 3}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, _, _ = tree.LongestPrefix(oid)
+	}
+}
@@ -0,0 +1,118 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// Decoding and encoding of the types embedded within Opaque values by agents such as net-snmp, following
+// draft-perkins-opaque-01. An embedded value is encoded with an extension tag, followed by a sub-type tag, a length
+// and the value content.
+
+const (
+	opaqueExtensionTag  = 0x9f
+	opaqueFloatTag      = 0x78
+	opaqueDoubleTag     = 0x79
+	opaqueInteger64Tag  = 0x7a
+	opaqueUnsigned64Tag = 0x7b
+
+	float32Length = 4
+	float64Length = 8
+	int64Length   = 8
+)
+
+// Unmarshals an SNMP Opaque variable into a TypedValue, decoding any embedded Float, Double, Integer64 or Unsigned64
+// value.
+// Other Opaque values are delivered as bytes.
+func unmarshalOpaque(raw *asn1.RawValue) (*TypedValue, error) {
+	tv, err := unmarshalOctetString(raw, Opaque)
+	if err != nil {
+		return nil, err
+	}
+	if embedded := decodeOpaque(tv.Value.([]byte)); embedded != nil {
+		return embedded, nil
+	}
+	return tv, nil
+}
+
+// Decodes the value embedded within Opaque content, or delivers nil if the content does not hold a supported value.
+func decodeOpaque(b []byte) *TypedValue {
+	const headerLength = 3
+	if len(b) < headerLength || b[0] != opaqueExtensionTag || int(b[2]) != len(b)-headerLength {
+		return nil
+	}
+	content := b[headerLength:]
+
+	switch b[1] {
+	case opaqueFloatTag:
+		if len(content) == float32Length {
+			return &TypedValue{Type: Float, Value: float64(math.Float32frombits(binary.BigEndian.Uint32(content)))}
+		}
+	case opaqueDoubleTag:
+		if len(content) == float64Length {
+			return &TypedValue{Type: Double, Value: math.Float64frombits(binary.BigEndian.Uint64(content))}
+		}
+	case opaqueInteger64Tag:
+		if len(content) > 0 && len(content) <= int64Length {
+			// Sign extend the two's complement content.
+			v := int64(int8(content[0]))
+			for _, octet := range content[1:] {
+				v = v<<8 | int64(octet)
+			}
+			return &TypedValue{Type: Integer64, Value: v}
+		}
+	case opaqueUnsigned64Tag:
+		// A leading zero octet is present when the most significant bit of the value is set.
+		if len(content) > 0 && (len(content) <= int64Length || len(content) == int64Length+1 && content[0] == 0) {
+			var v uint64
+			for _, octet := range content {
+				v = v<<8 | uint64(octet)
+			}
+			return &TypedValue{Type: Unsigned64, Value: v}
+		}
+	}
+	return nil
+}
+
+// Marshals a Float, Double, Integer64 or Unsigned64 value as Opaque content.
+func marshalOpaque(tv *TypedValue) ([]byte, error) {
+	var subType byte
+	var content []byte
+	switch tv.Type { //nolint:exhaustive
+	case Float, Double:
+		var v float64
+		switch value := tv.Value.(type) {
+		case float32:
+			v = float64(value)
+		case float64:
+			v = value
+		default:
+			return nil, errors.Errorf("unsupported floating point value %T", tv.Value)
+		}
+		if tv.Type == Float {
+			subType, content = opaqueFloatTag, make([]byte, float32Length)
+			binary.BigEndian.PutUint32(content, math.Float32bits(float32(v)))
+		} else {
+			subType, content = opaqueDoubleTag, make([]byte, float64Length)
+			binary.BigEndian.PutUint64(content, math.Float64bits(v))
+		}
+	case Integer64:
+		v, ok := signedValue(tv.Value)
+		if !ok {
+			return nil, errors.Errorf("unsupported Integer64 value %T", tv.Value)
+		}
+		subType, content = opaqueInteger64Tag, signedOctets(v)
+	case Unsigned64:
+		v, ok := unsignedValue(tv.Value)
+		if !ok {
+			return nil, errors.Errorf("unsupported Unsigned64 value %T", tv.Value)
+		}
+		subType, content = opaqueUnsigned64Tag, unsignedOctets(v)
+	default:
+		return nil, errors.Errorf("unsupported opaque data type %d", tv.Type)
+	}
+	return append([]byte{opaqueExtensionTag, subType, byte(len(content))}, content...), nil
+}
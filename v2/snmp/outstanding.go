@@ -0,0 +1,96 @@
+package snmp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DiscardReason identifies why a response was discarded by a session.
+type DiscardReason string
+
+// Reasons for discarding a response.
+const (
+	// The response answers an earlier request that has been retried or abandoned.
+	DiscardLate DiscardReason = "late"
+	// The response answers a request that has already been answered.
+	DiscardDuplicate DiscardReason = "duplicate"
+	// The response has a request-id that the session has not issued recently.
+	DiscardUnknown DiscardReason = "unknown"
+)
+
+// DiscardStats reports the number of responses discarded by a session because they did not answer the request
+// being processed, or could not be parsed.
+type DiscardStats struct {
+	Late      uint64
+	Duplicate uint64
+	Unknown   uint64
+	Malformed uint64
+}
+
+// The number of request timeouts for which the request-id of a request is remembered, so that late and duplicate
+// responses can be distinguished from those with an unknown request-id.
+const requestHistoryTimeouts = 10
+
+// Tracks the request-ids recently issued by a session, recording when each was sent and whether it has been
+// answered.
+type requestTracker struct {
+	requests map[int32]*trackedRequest
+
+	late      uint64
+	duplicate uint64
+	unknown   uint64
+	malform   uint64
+}
+
+type trackedRequest struct {
+	sent     time.Time
+	answered bool
+}
+
+// Records that a request with the id has been sent, forgetting requests sent before the retention period.
+func (rt *requestTracker) sent(id int32, retention time.Duration) {
+	now := time.Now()
+	if rt.requests == nil {
+		rt.requests = map[int32]*trackedRequest{}
+	}
+	for rid, r := range rt.requests {
+		if now.Sub(r.sent) > retention {
+			delete(rt.requests, rid)
+		}
+	}
+	rt.requests[id] = &trackedRequest{sent: now}
+}
+
+// Determines whether a response with the id answers the current request; otherwise, the reason the response
+// should be discarded is returned and counted.
+func (rt *requestTracker) match(id, current int32) (DiscardReason, bool) {
+	r, ok := rt.requests[id]
+	switch {
+	case !ok:
+		atomic.AddUint64(&rt.unknown, 1)
+		return DiscardUnknown, false
+	case r.answered:
+		atomic.AddUint64(&rt.duplicate, 1)
+		return DiscardDuplicate, false
+	}
+	r.answered = true
+	if id != current {
+		atomic.AddUint64(&rt.late, 1)
+		return DiscardLate, false
+	}
+	return "", true
+}
+
+// Counts a response that was discarded because it could not be parsed.
+func (rt *requestTracker) malformed() {
+	atomic.AddUint64(&rt.malform, 1)
+}
+
+func (rt *requestTracker) stats() DiscardStats {
+	return DiscardStats{
+		Late:      atomic.LoadUint64(&rt.late),
+		Duplicate: atomic.LoadUint64(&rt.duplicate),
+		Unknown:   atomic.LoadUint64(&rt.unknown),
+		Malformed: atomic.LoadUint64(&rt.malform),
+	}
+}
@@ -0,0 +1,86 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"fmt"
+)
+
+// Convenience accessors for the variable bindings of a PDU, which locate variables by OID rather than by their
+// position in the variable binding list, since agents may reorder or omit variables.
+
+// ValueOf delivers the value of the variable identified by oid, a dotted OID string with optional leading period.
+// The boolean result is false if the PDU holds no such variable, or if the agent reported an exception
+// (NoSuchObject, NoSuchInstance or EndOfMib) in place of its value.
+func (p *PDU) ValueOf(oid string) (*TypedValue, bool) {
+	target, err := parseOID(oid)
+	if err != nil {
+		return nil, false
+	}
+	for i := range p.VarbindList {
+		vb := &p.VarbindList[i]
+		if vb.OID.Equal(target) && vb.TypedValue != nil && !vb.TypedValue.isException() {
+			return vb.TypedValue, true
+		}
+	}
+	return nil, false
+}
+
+// MustString delivers the value of the variable identified by oid as a string, as described by TypedValue.String.
+// It panics if the PDU holds no value for the variable.
+func (p *PDU) MustString(oid string) string {
+	tv, ok := p.ValueOf(oid)
+	if !ok {
+		panic(fmt.Errorf("no value for oid %s", oid))
+	}
+	return tv.String()
+}
+
+// Uint64 delivers the value of the variable identified by oid as a uint64.
+// The boolean result is false if the PDU holds no value for the variable, the value is not integer-based, or the
+// value is a negative Integer.
+func (p *PDU) Uint64(oid string) (uint64, bool) {
+	tv, ok := p.ValueOf(oid)
+	if !ok {
+		return 0, false
+	}
+	switch tv.Type { //nolint:exhaustive
+	case Integer:
+		if v := tv.Value.(int64); v >= 0 {
+			return uint64(v), true
+		}
+	case Counter64:
+		return tv.Value.(uint64), true
+	case Counter32, Gauge32, Time:
+		return uint64(tv.Value.(uint32)), true
+	}
+	return 0, false
+}
+
+// ForEach calls walker for each variable binding whose OID is prefix, or a descendant of prefix, in the order they
+// appear in the variable binding list. If walker returns an error, iteration stops and the error is returned.
+func (p *PDU) ForEach(prefix string, walker Walker) error {
+	root, err := parseOID(prefix)
+	if err != nil {
+		return err
+	}
+	for i := range p.VarbindList {
+		vb := &p.VarbindList[i]
+		if !hasOIDPrefix(vb.OID, root) {
+			continue
+		}
+		if err = walker(vb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Determines whether the value is an exception reported by the agent in place of a value.
+func (tv *TypedValue) isException() bool {
+	return tv.Type == NoSuchObject || tv.Type == NoSuchInstance || tv.Type == EndOfMib
+}
+
+// Determines whether oid is prefix, or a descendant of prefix.
+func hasOIDPrefix(oid, prefix asn1.ObjectIdentifier) bool {
+	return len(oid) >= len(prefix) && oid[:len(prefix)].Equal(prefix)
+}
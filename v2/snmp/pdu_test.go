@@ -0,0 +1,96 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"errors"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func testPDU() *PDU {
+	return &PDU{VarbindList: []Varbind{
+		{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 5, 0}, TypedValue: &TypedValue{Type: OctetString, Value: []byte("router1")}},
+		{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 3, 0}, TypedValue: &TypedValue{Type: Time, Value: uint32(12345)}},
+		{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 10, 1}, TypedValue: &TypedValue{Type: Counter64, Value: uint64(1) << 40}},
+		{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 10, 2}, TypedValue: &TypedValue{Type: Counter32, Value: uint32(7)}},
+		{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 7, 0}, TypedValue: &TypedValue{Type: Integer, Value: int64(-1)}},
+		{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 6, 0}, TypedValue: &TypedValue{Type: NoSuchObject}},
+	}}
+}
+
+func TestPDUValueOf(t *testing.T) {
+	pdu := testPDU()
+
+	tv, ok := pdu.ValueOf("1.3.6.1.2.1.1.5.0")
+	assert.True(t, ok)
+	assert.Equal(t, "router1", tv.String())
+
+	_, ok = pdu.ValueOf(".1.3.6.1.2.1.1.3.0")
+	assert.True(t, ok, "Expecting leading period to be accepted")
+
+	_, ok = pdu.ValueOf("1.3.6.1.2.1.1.6.0")
+	assert.False(t, ok, "Expecting exception to be treated as absent")
+	_, ok = pdu.ValueOf("1.3.6.1.2.1.1.4.0")
+	assert.False(t, ok)
+	_, ok = pdu.ValueOf("1.3.x")
+	assert.False(t, ok)
+}
+
+func TestPDUMustString(t *testing.T) {
+	pdu := testPDU()
+	assert.Equal(t, "router1", pdu.MustString("1.3.6.1.2.1.1.5.0"))
+	assert.PanicsWithError(t, "no value for oid 1.3.6.1.2.1.1.6.0", func() { pdu.MustString("1.3.6.1.2.1.1.6.0") })
+}
+
+func TestPDUUint64(t *testing.T) {
+	pdu := testPDU()
+
+	tests := []struct {
+		oid   string
+		value uint64
+		ok    bool
+	}{
+		{"1.3.6.1.2.1.1.3.0", 12345, true},
+		{"1.3.6.1.2.1.2.2.1.10.1", 1 << 40, true},
+		{"1.3.6.1.2.1.2.2.1.10.2", 7, true},
+		{"1.3.6.1.2.1.1.7.0", 0, false},
+		{"1.3.6.1.2.1.1.5.0", 0, false},
+		{"1.3.6.1.2.1.1.6.0", 0, false},
+	}
+	for _, test := range tests {
+		value, ok := pdu.Uint64(test.oid)
+		assert.Equal(t, test.value, value, test.oid)
+		assert.Equal(t, test.ok, ok, test.oid)
+	}
+}
+
+func TestPDUForEach(t *testing.T) {
+	pdu := testPDU()
+
+	var oids []string
+	err := pdu.ForEach("1.3.6.1.2.1.2.2.1.10", func(vb *Varbind) error {
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.3.6.1.2.1.2.2.1.10.1", "1.3.6.1.2.1.2.2.1.10.2"}, oids)
+
+	oids = nil
+	_ = pdu.ForEach("1.3.6.1.2.1.1.5.0", func(vb *Varbind) error {
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	assert.Equal(t, []string{"1.3.6.1.2.1.1.5.0"}, oids, "Expecting prefix itself to be included")
+
+	stop := errors.New("stop")
+	count := 0
+	err = pdu.ForEach("1.3.6.1.2.1", func(vb *Varbind) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+
+	assert.Error(t, pdu.ForEach("1.x", func(vb *Varbind) error { return nil }))
+}
@@ -0,0 +1,214 @@
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Default maximum number of targets polled concurrently.
+const defaultPollParallelism = 16
+
+// Poller issues SNMP GET requests for a set of oids to many targets concurrently, the common fan-out primitive for
+// monitoring systems.
+// A session is established for each target on first use, and retained for subsequent polls, so that the socket
+// for each target is shared across polls. Sessions are closed by RemoveTarget or Close.
+// A Poller is safe for concurrent use.
+type Poller struct {
+	factory     SessionFactory
+	parallelism int
+
+	mu      sync.Mutex
+	targets map[string]*pollTarget
+}
+
+type pollTarget struct {
+	opts    []SessionOption
+	session Session
+}
+
+// PollerOption implements options for configuring poller behaviour.
+type PollerOption func(*Poller)
+
+// Parallelism defines the maximum number of targets that will be polled concurrently.
+// Default value is 16.
+func Parallelism(value int) PollerOption {
+	return func(p *Poller) {
+		p.parallelism = value
+	}
+}
+
+// PollerSessionFactory defines the factory used to establish target sessions.
+// Default value is the factory returned by NewFactory.
+func PollerSessionFactory(factory SessionFactory) PollerOption {
+	return func(p *Poller) {
+		p.factory = factory
+	}
+}
+
+// PollError is returned by Poll when one or more targets could not be polled.
+type PollError struct {
+	// The error returned for each failed target, keyed by target address.
+	Errors map[string]error
+}
+
+// Error generates a string representation of the poll error, listing the failed targets in address order.
+func (e *PollError) Error() string {
+	targets := make([]string, 0, len(e.Errors))
+	for target := range e.Errors {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	failures := make([]string, len(targets))
+	for i, target := range targets {
+		failures[i] = fmt.Sprintf("%s: %v", target, e.Errors[target])
+	}
+	return fmt.Sprintf("poll failed for %d target(s): %s", len(targets), strings.Join(failures, "; "))
+}
+
+// NewPoller delivers a new poller with no targets.
+func NewPoller(opts ...PollerOption) *Poller {
+	p := &Poller{factory: NewFactory(), parallelism: defaultPollParallelism, targets: map[string]*pollTarget{}}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.parallelism < 1 {
+		p.parallelism = 1
+	}
+	return p
+}
+
+// AddTarget adds the target, for example 10.48.24.234:161, to the set of targets polled.
+// The options configure the target session, for example the community, timeout and number of retries.
+// Adding a target that already exists replaces its options, and closes any established session.
+func (p *Poller) AddTarget(address string, opts ...SessionOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.targets[address]; ok && t.session != nil {
+		_ = t.session.Close()
+	}
+	p.targets[address] = &pollTarget{opts: opts}
+}
+
+// RemoveTarget removes the target from the set of targets polled, closing any established session.
+func (p *Poller) RemoveTarget(address string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.targets[address]; ok {
+		if t.session != nil {
+			_ = t.session.Close()
+		}
+		delete(p.targets, address)
+	}
+}
+
+// Targets delivers the addresses of the targets polled, in address order.
+func (p *Poller) Targets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	targets := make([]string, 0, len(p.targets))
+	for target := range p.targets {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Poll issues a GET request for the oids to each target, delivering the responses keyed by target address.
+// If any target fails, the responses of the successful targets are delivered together with a *PollError
+// identifying the failures.
+func (p *Poller) Poll(ctx context.Context, oids []string) (map[string]*PDU, error) {
+	p.mu.Lock()
+	targets := make(map[string]*pollTarget, len(p.targets))
+	for address, t := range p.targets {
+		targets[address] = t
+	}
+	p.mu.Unlock()
+
+	var mu sync.Mutex
+	results := make(map[string]*PDU, len(targets))
+	failures := map[string]error{}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, p.parallelism)
+	for address, t := range targets {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			failures[address] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(address string, t *pollTarget) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			pdu, err := p.get(ctx, address, t, oids)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures[address] = err
+			} else {
+				results[address] = pdu
+			}
+		}(address, t)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return results, &PollError{Errors: failures}
+	}
+	return results, nil
+}
+
+// Closes the sessions established for all targets.
+func (p *Poller) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, t := range p.targets {
+		if t.session != nil {
+			_ = t.session.Close()
+			t.session = nil
+		}
+	}
+	return nil
+}
+
+// Issues the GET request to the target, establishing the target session if required.
+func (p *Poller) get(ctx context.Context, address string, t *pollTarget, oids []string) (*PDU, error) {
+	p.mu.Lock()
+	session := t.session
+	p.mu.Unlock()
+
+	if session == nil {
+		s, err := p.factory.NewSession(ctx, address, t.opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		if p.targets[address] != t || t.session != nil {
+			// The target was replaced or removed, or a concurrent poll established a session, during setup.
+			p.mu.Unlock()
+			defer s.Close()
+			return s.Get(ctx, oids)
+		}
+		t.session = s
+		p.mu.Unlock()
+		session = s
+	}
+	return session.Get(ctx, oids)
+}
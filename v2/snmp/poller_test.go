@@ -0,0 +1,139 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestPoll(t *testing.T) {
+	f := newPollFactory()
+	f.failures["10.0.0.2:161"] = errors.New("request timeout")
+
+	p := NewPoller(PollerSessionFactory(f), Parallelism(2))
+	defer p.Close()
+	p.AddTarget("10.0.0.1:161", Community("private"))
+	p.AddTarget("10.0.0.2:161")
+	p.AddTarget("10.0.0.3:161", Retries(1))
+	assert.Equal(t, []string{"10.0.0.1:161", "10.0.0.2:161", "10.0.0.3:161"}, p.Targets())
+
+	results, err := p.Poll(context.Background(), []string{"1.3.6.1.2.1.1.3.0"})
+	assert.EqualError(t, err, "poll failed for 1 target(s): 10.0.0.2:161: request timeout")
+	var pe *PollError
+	assert.True(t, errors.As(err, &pe))
+	assert.Len(t, pe.Errors, 1)
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, "10.0.0.1:161", results["10.0.0.1:161"].VarbindList[0].TypedValue.Value)
+	assert.Equal(t, "10.0.0.3:161", results["10.0.0.3:161"].VarbindList[0].TypedValue.Value)
+	assert.Len(t, f.sessionOpts["10.0.0.1:161"], 1, "Expecting target options to be applied")
+}
+
+func TestPollReusesSessions(t *testing.T) {
+	f := newPollFactory()
+	p := NewPoller(PollerSessionFactory(f))
+	p.AddTarget("10.0.0.1:161")
+
+	for i := 0; i < 3; i++ {
+		_, err := p.Poll(context.Background(), []string{"1.3.6.1.2.1.1.3.0"})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&f.created))
+
+	p.AddTarget("10.0.0.1:161", Community("private"))
+	_, err := p.Poll(context.Background(), []string{"1.3.6.1.2.1.1.3.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&f.created), "Expecting replaced target to use a new session")
+
+	p.RemoveTarget("10.0.0.1:161")
+	assert.Empty(t, p.Targets())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&f.closed))
+}
+
+func TestPollParallelism(t *testing.T) {
+	f := newPollFactory()
+	f.delay = 10 * time.Millisecond
+	p := NewPoller(PollerSessionFactory(f), Parallelism(3))
+	defer p.Close()
+	for _, target := range []string{"a:161", "b:161", "c:161", "d:161", "e:161", "f:161", "g:161"} {
+		p.AddTarget(target)
+	}
+
+	results, err := p.Poll(context.Background(), []string{"1.3.6.1.2.1.1.3.0"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 7)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&f.maxActive))
+}
+
+func TestPollCancelled(t *testing.T) {
+	f := newPollFactory()
+	p := NewPoller(PollerSessionFactory(f))
+	defer p.Close()
+	p.AddTarget("10.0.0.1:161")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := p.Poll(ctx, []string{"1.3.6.1.2.1.1.3.0"})
+	assert.Error(t, err)
+	assert.Empty(t, results)
+}
+
+// Session factory delivering sessions that respond to Get with the target address.
+type pollFactory struct {
+	mu          sync.Mutex
+	failures    map[string]error
+	sessionOpts map[string][]SessionOption
+	delay       time.Duration
+	created     int32
+	closed      int32
+	active      int32
+	maxActive   int32
+}
+
+func newPollFactory() *pollFactory {
+	return &pollFactory{failures: map[string]error{}, sessionOpts: map[string][]SessionOption{}}
+}
+
+func (f *pollFactory) NewSession(ctx context.Context, target string, opts ...SessionOption) (Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&f.created, 1)
+	f.mu.Lock()
+	f.sessionOpts[target] = opts
+	f.mu.Unlock()
+	return &pollSession{f: f, target: target}, nil
+}
+
+type pollSession struct {
+	Session
+	f      *pollFactory
+	target string
+}
+
+func (s *pollSession) Get(ctx context.Context, oids []string) (*PDU, error) {
+	active := atomic.AddInt32(&s.f.active, 1)
+	defer atomic.AddInt32(&s.f.active, -1)
+	for {
+		max := atomic.LoadInt32(&s.f.maxActive)
+		if active <= max || atomic.CompareAndSwapInt32(&s.f.maxActive, max, active) {
+			break
+		}
+	}
+	time.Sleep(s.f.delay)
+
+	if err := s.f.failures[s.target]; err != nil {
+		return nil, err
+	}
+	return &PDU{VarbindList: []Varbind{{TypedValue: &TypedValue{Type: OctetString, Value: s.target}}}}, nil
+}
+
+func (s *pollSession) Close() error {
+	atomic.AddInt32(&s.f.closed, 1)
+	return nil
+}
@@ -0,0 +1,88 @@
+package snmp
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"sync"
+)
+
+// Pools used to reduce allocations on the request/response path, which matters for high-rate pollers.
+
+// Pool of read buffers, each large enough to hold any response.
+var readBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, maxInputBufferSize)
+		return &b
+	},
+}
+
+// Pool of scratch buffers used when marshaling requests.
+var marshalBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getReadBuffer() *[]byte {
+	return readBufferPool.Get().(*[]byte)
+}
+
+func putReadBuffer(b *[]byte) {
+	readBufferPool.Put(b)
+}
+
+func getMarshalBuffer() *bytes.Buffer {
+	buf := marshalBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putMarshalBuffer(buf *bytes.Buffer) {
+	marshalBufferPool.Put(buf)
+}
+
+// Writes a BER definite length to buf.
+func writeLength(buf *bytes.Buffer, length int) {
+	const shortFormLimit = 0x80
+	if length < shortFormLimit {
+		buf.WriteByte(byte(length))
+		return
+	}
+	var octets [8]byte
+	n := 0
+	for l := length; l > 0; l >>= 8 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		octets[i] = byte(length)
+		length >>= 8
+	}
+	buf.WriteByte(shortFormLimit | byte(n))
+	buf.Write(octets[:n])
+}
+
+// Writes a BER encoded small non-negative integer to buf.
+func writeInteger(buf *bytes.Buffer, value int) {
+	var octets [8]byte
+	n := 0
+	for v := value; ; v >>= 8 {
+		n++
+		if v < 0x80 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		octets[i] = byte(value)
+		value >>= 8
+	}
+	buf.WriteByte(asn1.TagInteger)
+	writeLength(buf, n)
+	buf.Write(octets[:n])
+}
+
+const (
+	// ASN1 constructed encoding flag.
+	compoundTag = 0x20
+	// Maximum size of a sequence header: tag, plus long form length of up to 4 octets.
+	maxHeaderSize = 6
+)
@@ -0,0 +1,120 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+
+	"github.com/pkg/errors"
+)
+
+// Proxy forwarding of Get, GetNext and GetBulk requests to backend agents, allowing the server to act as an SNMP
+// proxy for managers that cannot reach the agents directly, as in jump-host architectures.
+// Requests are routed by community and oid prefix, and are forwarded using the session registered for the route,
+// so the community used to reach the backend agent is that of the session, rather than that of the request.
+// Each variable binding is forwarded in a separate request, on the goroutine that receives messages; a GetBulk
+// request is resolved as a sequence of GetNext requests.
+
+// ProxyTo forwards requests received with community, for variables within the oid prefix, to the agent reached by
+// session. An empty community matches requests whose community has no routes registered.
+// Requests for variables outside the prefixes registered for a community are answered with the appropriate
+// exception, and GetNext and GetBulk requests traverse the routes in prefix order. The prefixes registered for a
+// community should not overlap.
+// Requests whose community has no routes are answered by any objects registered with ServeScalar or ServeTable.
+// The server does not close the session.
+func ProxyTo(community, prefix string, session Session) ServerOption {
+	return func(c *serverConfig) {
+		oid, err := parseOID(prefix)
+		if err != nil {
+			c.err = errors.Wrapf(err, "invalid proxy prefix %q", prefix)
+			return
+		}
+		if c.proxies == nil {
+			c.proxies = map[string]*OIDTree{}
+		}
+		routes := c.proxies[community]
+		if routes == nil {
+			routes = NewOIDTree()
+			c.proxies[community] = routes
+		}
+		routes.Insert(oid, session)
+	}
+}
+
+// Delivers the resolver for proxied requests received with community, or nil if the community has no routes.
+func (c *serverConfig) proxyResolver(community string) *proxyResolver {
+	routes, ok := c.proxies[community]
+	if !ok {
+		routes, ok = c.proxies[""]
+	}
+	if !ok {
+		return nil
+	}
+	return &proxyResolver{routes: routes}
+}
+
+// Resolves variable bindings by forwarding requests to the sessions registered against oid prefixes.
+type proxyResolver struct {
+	routes *OIDTree
+}
+
+func (p *proxyResolver) getInstance(oid asn1.ObjectIdentifier) (rawVarbind, error) {
+	_, value, ok := p.routes.LongestPrefix(oid)
+	if !ok {
+		return exceptionVarbind(oid, noSuchObjectTag), nil
+	}
+	vb, err := forward(value.(Session).Get, oid)
+	if err != nil {
+		return exceptionVarbind(oid, noSuchInstanceTag), err
+	}
+	return valueVarbind(oid, vb.TypedValue)
+}
+
+func (p *proxyResolver) getNextInstance(oid asn1.ObjectIdentifier) (rawVarbind, error) {
+	var result rawVarbind
+	err := p.routes.Walk(nil, func(prefix asn1.ObjectIdentifier, value interface{}) error {
+		from := oid
+		switch {
+		case hasOIDPrefix(oid, prefix):
+		case compareOIDs(oid, prefix) < 0:
+			from = prefix
+		default:
+			// The oid follows the subtree of the route.
+			return nil
+		}
+
+		vb, err := forward(value.(Session).GetNext, from)
+		switch {
+		case err != nil:
+			result = exceptionVarbind(oid, noSuchInstanceTag)
+			return err
+		case vb.TypedValue.Type == EndOfMib || !hasOIDPrefix(vb.OID, prefix):
+			// The subtree of the route is exhausted, so the next route is consulted.
+			return nil
+		}
+		if result, err = valueVarbind(vb.OID, vb.TypedValue); err != nil {
+			return err
+		}
+		return errInstanceFound
+	})
+	switch {
+	case err == errInstanceFound:
+		return result, nil
+	case err != nil:
+		return result, err
+	}
+	return exceptionVarbind(oid, endOfMibTag), nil
+}
+
+// Forwards a request for the oid with the session operation, delivering the single variable binding of the response.
+func forward(op func(ctx context.Context, oids []string) (*PDU, error), oid asn1.ObjectIdentifier) (*Varbind, error) {
+	pdu, err := op(context.Background(), []string{oid.String()})
+	switch {
+	case err != nil:
+		return nil, errors.Wrapf(err, "proxy request for oid %s failed", oid)
+	case pdu.Error != noError:
+		return nil, errors.Errorf("proxy request for oid %s failed with error-status %d", oid, pdu.Error)
+	case len(pdu.VarbindList) != 1:
+		return nil, errors.Errorf("proxy request for oid %s delivered %d variable bindings", oid, len(pdu.VarbindList))
+	}
+	return &pdu.VarbindList[0], nil
+}
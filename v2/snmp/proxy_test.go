@@ -0,0 +1,108 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Delivers a session with a proxy that forwards requests with the public community to two backend agents, one
+// serving the system group and accepting only the private community, the other serving the interfaces table.
+func newTestProxy(t *testing.T) Session {
+	system := newTestAgentServer(t, AllowCommunities([]string{"private"}), testAgentObjects[0])
+	interfaces := newTestAgentServer(t, testAgentObjects[2])
+
+	proxy := newTestAgentServer(t,
+		ProxyTo("public", "1.3.6.1.2.1.1", newTestSession(t, system, Community("private"))),
+		ProxyTo("public", "1.3.6.1.2.1.2", newTestSession(t, interfaces)),
+	)
+	return newTestSession(t, proxy)
+}
+
+func TestProxyGet(t *testing.T) {
+	ses := newTestProxy(t)
+
+	pdu, err := ses.Get(context.Background(), []string{sysDescr + ".0", ifEntry + ".2.1", sysDescr + ".1",
+		"1.3.6.1.2.1.99.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pdu.Error)
+	assert.Equal(t, "test agent", pdu.VarbindList[0].TypedValue.String())
+	assert.Equal(t, "eth1", pdu.VarbindList[1].TypedValue.String())
+	assert.Equal(t, NoSuchInstance, pdu.VarbindList[2].TypedValue.Type)
+	assert.Equal(t, NoSuchObject, pdu.VarbindList[3].TypedValue.Type)
+}
+
+func TestProxyWalk(t *testing.T) {
+	ses := newTestProxy(t)
+
+	var oids []string
+	err := ses.Walk(context.Background(), "1.3.6.1.2.1", func(vb *Varbind) error {
+		if vb.TypedValue.Type != EndOfMib {
+			oids = append(oids, vb.OID.String())
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{sysDescr + ".0", ifEntry + ".1.1", ifEntry + ".1.2", ifEntry + ".2.1", ifEntry + ".2.2"},
+		oids, "Expecting walk to traverse both routes")
+}
+
+func TestProxyGetBulk(t *testing.T) {
+	ses := newTestProxy(t)
+
+	pdu, err := ses.GetBulk(context.Background(), []string{"1.3.6.1", ifEntry + ".2.1"}, 1, 3)
+	assert.NoError(t, err)
+	var oids []string
+	for _, vb := range pdu.VarbindList {
+		oids = append(oids, vb.OID.String())
+	}
+	assert.Equal(t, []string{sysDescr + ".0", ifEntry + ".2.2", ifEntry + ".2.2"}, oids)
+	assert.Equal(t, EndOfMib, pdu.VarbindList[2].TypedValue.Type)
+}
+
+func TestProxyCommunityRouting(t *testing.T) {
+	backend := newTestAgentServer(t, testAgentObjects[0])
+	proxy := newTestAgentServer(t,
+		ProxyTo("", "1.3.6.1.2.1.1", newTestSession(t, backend)),
+		ProxyTo("restricted", "1.3.6.1.2.1.2", newTestSession(t, backend)),
+	)
+
+	// Communities without routes use the default routes.
+	pdu, err := newTestSession(t, proxy, Community("any")).Get(context.Background(), []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test agent", pdu.VarbindList[0].TypedValue.String())
+
+	pdu, err = newTestSession(t, proxy, Community("restricted")).Get(context.Background(), []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, NoSuchObject, pdu.VarbindList[0].TypedValue.Type)
+}
+
+func TestProxyBackendFailure(t *testing.T) {
+	backend := newTestAgentServer(t, testAgentObjects[0])
+	upstream := newTestSession(t, backend, Timeout(100*time.Millisecond))
+	backend.Close()
+
+	proxy := newTestAgentServer(t, ProxyTo("public", "1.3.6.1.2.1.1", upstream))
+	pdu := exchange(t, proxy, SNMPV2C, []string{sysDescr + ".0"})
+	assert.Equal(t, genErr, pdu.Error)
+	assert.Equal(t, 1, pdu.ErrorIndex)
+}
+
+func TestProxyInvalidPrefix(t *testing.T) {
+	_, err := NewServerFactory().NewServer(context.Background(), nil, Port(0), ProxyTo("public", "1.x", nil))
+	assert.EqualError(t, err, `invalid proxy prefix "1.x": invalid oid component "x"`)
+}
+
+func TestProxyResolverRouteOrder(t *testing.T) {
+	routes := NewOIDTree()
+	routes.Insert(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2}, nil)
+	p := &proxyResolver{routes: routes}
+
+	// Oids that follow all routes are at the end of the mib, without consulting any session.
+	vb, err := p.getNextInstance(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 3})
+	assert.NoError(t, err)
+	assert.True(t, isEndOfMib(&vb))
+}
@@ -1,8 +1,11 @@
 package snmp
 
 import (
+	"context"
 	"io"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -22,29 +25,143 @@ type Handler interface {
 	// sourceAddr is the address which originated the message
 	// Note that a NewMessage invocation will block the receipt of other messages.
 	// In the case of an inform message, it will also block the transmission of the acknowledgement message.
-	// It is the responsibility of the Handler implementation to return in a timely fashion.
+	// It is the responsibility of the Handler implementation to return in a timely fashion, unless the server is
+	// configured with a HandlerTimeout, after which the invocation is abandoned.
+	// SNMPv1 traps are delivered with the variable bindings converted to the SNMPv2 format.
+	// Handlers that also implement TrapHandler will have NewTrap invoked instead, and those that implement
+	// ContextHandler or ContextTrapHandler receive a context with each message.
 	NewMessage(pdu *PDU, isInform bool, sourceAddr net.Addr)
 }
 
+// ErrServerClosed is returned by PacketServer.Serve after a call to Shutdown.
+var ErrServerClosed = errors.New("snmp server closed")
+
+// PacketServer receives Trap and Inform messages, and responds to agent requests, on a net.PacketConn supplied by
+// the caller. It allows message handling to be integrated with listener management that is owned by the caller,
+// such as systemd socket activation or a test harness, rather than the server factory.
+//
+// The connection remains owned by the caller; it is not closed by Shutdown.
+type PacketServer struct {
+	impl *serverImpl
+
+	mu       sync.Mutex
+	shutdown bool
+	// Closed when Serve returns.
+	done chan struct{}
+}
+
+// NewPacketServer creates a server that handles messages with handler, configured by opts.
+// The network, address and port options are ignored, as the connection is supplied to Serve.
+func NewPacketServer(handler Handler, opts ...ServerOption) (*PacketServer, error) {
+	config, err := newServerConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PacketServer{impl: (&serverImpl{config: config, handler: handler}).withContext(context.Background())}, nil
+}
+
+// Serve processes messages received on conn, blocking until reading from conn fails or Shutdown is called.
+// Serve always returns a non-nil error; following Shutdown, the error is ErrServerClosed.
+// A PacketServer can only serve one connection at a time.
+func (s *PacketServer) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	switch {
+	case s.shutdown:
+		s.mu.Unlock()
+		return ErrServerClosed
+	case s.done != nil:
+		s.mu.Unlock()
+		return errors.New("snmp server already serving")
+	}
+	s.impl.conn = conn
+	s.done = make(chan struct{})
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		close(s.done)
+		s.impl.conn, s.done = nil, nil
+	}()
+	return s.impl.serve(s.isShutdown)
+}
+
+// Shutdown stops the server, interrupting any read in progress on the connection, cancelling the contexts of
+// handlers in progress, and waiting for Serve to return. The connection is left open.
+func (s *PacketServer) Shutdown() error {
+	s.impl.cancelHandlers()
+	s.mu.Lock()
+	s.shutdown = true
+	conn, done := s.impl.conn, s.done
+	s.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	// Unblock the pending read; the deadline is cleared once Serve has returned.
+	if err := conn.SetReadDeadline(time.Now()); err != nil {
+		return err
+	}
+	<-done
+	return conn.SetReadDeadline(time.Time{})
+}
+
+// FilterStats delivers the counts of messages dropped by the configured filters.
+func (s *PacketServer) FilterStats() FilterStats {
+	return s.impl.FilterStats()
+}
+
+func (s *PacketServer) isShutdown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdown
+}
+
 type serverImpl struct {
 	conn    net.PacketConn
 	config  *serverConfig
 	handler Handler
+	// Counts of messages dropped by the configured filters.
+	filtered filterCounts
+	// Collapses identical traps, if enabled by SuppressDuplicateTraps; created on first use.
+	dedup *trapDeduplicator
+	// The parent of the contexts supplied to handlers, cancelled when the server is closed; nil means
+	// context.Background().
+	ctx    context.Context
+	cancel context.CancelFunc
+	// The number of handlers abandoned by HandlerTimeout that are still running.
+	abandoned int32
 }
 
 func (s *serverImpl) Close() error {
+	s.cancelHandlers()
 	return s.conn.Close()
 }
 
 // Launches a goroutine to process incoming messages.
 func (s *serverImpl) handleMessages() {
 	go func() {
-		s.config.trace.StartListening(s.conn.LocalAddr())
-		err := s.listen()
-		s.config.trace.StopListening(s.conn.LocalAddr(), err)
+		_ = s.serve(nil)
 	}()
 }
 
+// Processes incoming messages until reading from the connection fails, reporting the listening lifecycle to the
+// trace hooks. If shutdown is not nil, it determines whether the failure was caused by an orderly shutdown, in which
+// case ErrServerClosed is reported.
+func (s *serverImpl) serve(shutdown func() bool) error {
+	addr := s.conn.LocalAddr()
+	s.config.trace.StartListening(addr)
+	err := s.listen()
+	if s.dedup != nil {
+		s.dedup.flush()
+	}
+	if shutdown != nil && shutdown() {
+		err = ErrServerClosed
+	}
+	s.config.trace.StopListening(addr, err)
+	return err
+}
+
 // Processes incoming messages.
 func (s *serverImpl) listen() error {
 	for {
@@ -52,22 +169,50 @@ func (s *serverImpl) listen() error {
 		if err != nil {
 			return err
 		}
+		received := time.Now()
+		if s.config.capture != nil {
+			if err = s.config.capture.WritePacket(received, addr, s.conn.LocalAddr(), input); err != nil {
+				s.config.trace.Error(s.config, errors.Wrap(err, "failed to capture message"))
+			}
+		}
 
-		err = s.processMessage(input, addr)
+		err = s.processMessage(input, addr, received)
 		if err != nil {
 			s.config.trace.Error(s.config, err)
 		}
 	}
 }
 
-func (s *serverImpl) processMessage(input []byte, addr net.Addr) error {
+func (s *serverImpl) processMessage(input []byte, addr net.Addr, received time.Time) error {
+	if !s.config.filter.allowSource(addr) {
+		s.recordFiltered(addr, FilteredSource)
+		return nil
+	}
+
+	loc := &decodeLocator{msg: input}
 	pkt := &packet{}
 	if _, err := ber.Unmarshal(input, pkt); err != nil {
-		return errors.Wrap(err, "failed to unmarshal packet")
+		return errors.Wrap(loc.fail("envelope", input, err), "failed to unmarshal packet")
+	}
+
+	if !s.config.filter.allowCommunity(string(pkt.Community)) {
+		s.recordFiltered(addr, FilteredCommunity)
+		return nil
 	}
 
 	mType := pkt.RawPdu.FullBytes[0]
-	if mType != inform && mType != v2Trap {
+	if mType == getMessage || mType == getNextMessage || mType == getBulkMessage {
+		if r := s.resolverFor(pkt); r != nil {
+			return s.respond(pkt, mType, addr, r, loc)
+		}
+	}
+	// Set requests are only answered for registered objects, and are not proxied.
+	if mType == setRequestMessage {
+		if r, ok := s.resolverFor(pkt).(*agentRequest); ok {
+			return s.respond(pkt, mType, addr, r, loc)
+		}
+	}
+	if mType != inform && mType != v2Trap && mType != v1Trap {
 		return errors.Errorf("unrecognised message type %d", mType)
 	}
 
@@ -75,18 +220,34 @@ func (s *serverImpl) processMessage(input []byte, addr net.Addr) error {
 	copy(rawResponsePDU, pkt.RawPdu.FullBytes)
 	// Replace SNMP PDU Type with ASN1 sequence tag.
 	rawResponsePDU[0] = 0x30
+	loc.pdu, loc.pduOffset = rawResponsePDU, loc.offset(pkt.RawPdu.FullBytes)
+
+	var pdu *PDU
+	var v1 *rawV1TrapPDU
+	var err error
+	if mType == v1Trap {
+		pdu, v1, err = unmarshalV1Trap(rawResponsePDU, loc)
+		if err != nil {
+			return err
+		}
+	} else {
+		rawPDU := &rawPDU{}
+		if _, err = ber.Unmarshal(rawResponsePDU, rawPDU); err != nil {
+			return errors.Wrap(loc.fail("pdu", pkt.RawPdu.FullBytes, err), "failed to unmarshal pdu")
+		}
 
-	rawPDU := &rawPDU{}
-	if _, err := ber.Unmarshal(rawResponsePDU, rawPDU); err != nil {
-		return errors.Wrap(err, "failed to unmarshal pdu")
+		pdu, err = unmarshalValues(rawPDU, loc)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal values")
+		}
 	}
 
-	pdu, err := unmarshalValues(rawPDU)
-	if err != nil {
-		return errors.Wrap(err, "failed to unmarshal values")
+	if !s.config.filter.allowTrapOID(pdu) {
+		s.recordFiltered(addr, FilteredTrapOID)
+		return nil
 	}
 
-	s.handler.NewMessage(pdu, mType == inform, addr)
+	s.deliver(&MessageInfo{ReceivedAt: received, SourceAddress: addr}, pkt, pdu, v1, mType == inform)
 
 	if mType == inform {
 		err = s.acknowledgeInform(pkt, addr)
@@ -94,6 +255,74 @@ func (s *serverImpl) processMessage(input []byte, addr net.Addr) error {
 	return err
 }
 
+// Delivers the message to the handler, as decoded trap data if the handler implements TrapHandler or
+// ContextTrapHandler. Identical traps are collapsed, if enabled by SuppressDuplicateTraps.
+func (s *serverImpl) deliver(info *MessageInfo, pkt *packet, pdu *PDU, v1 *rawV1TrapPDU, isInform bool) {
+	if s.handler == nil {
+		return
+	}
+	_, ok := s.handler.(TrapHandler)
+	if _, cok := s.handler.(ContextTrapHandler); cok {
+		ok = true
+	}
+	if !ok && s.config.dedupWindow == 0 {
+		s.deliverMessage(info, pdu, isInform)
+		return
+	}
+
+	td := NewTrapData(pdu, isInform, info.SourceAddress)
+	td.Version = pkt.Version
+	td.Community = string(pkt.Community)
+	if v1 != nil {
+		td.AgentAddress = net.IP(append([]byte{}, v1.AgentAddr.Bytes...))
+		td.GenericTrap = v1.GenericTrap
+		td.SpecificTrap = v1.SpecificTrap
+	}
+	if s.config.dedupWindow > 0 {
+		if s.dedup == nil {
+			s.dedup = newTrapDeduplicator(s.config.dedupWindow)
+		}
+		first := *td
+		if !s.dedup.add(trapKey(td), func(repeats int) {
+			if ok {
+				summary := first
+				summary.Repeats = repeats
+				s.deliverTrap(info, &summary)
+			}
+		}) {
+			return
+		}
+		if !ok {
+			s.deliverMessage(info, pdu, isInform)
+			return
+		}
+	}
+	s.deliverTrap(info, td)
+}
+
+func (s *serverImpl) deliverMessage(info *MessageInfo, pdu *PDU, isInform bool) {
+	s.invoke(info, func(ctx context.Context) {
+		if ch, ok := s.handler.(ContextHandler); ok {
+			ch.NewMessageContext(ctx, pdu, isInform, info.SourceAddress)
+			return
+		}
+		s.handler.NewMessage(pdu, isInform, info.SourceAddress)
+	})
+}
+
+func (s *serverImpl) deliverTrap(info *MessageInfo, td *TrapData) {
+	if s.config.mibs != nil {
+		td.Enrichment = td.Enrich(s.config.mibs)
+	}
+	s.invoke(info, func(ctx context.Context) {
+		if ch, ok := s.handler.(ContextTrapHandler); ok {
+			ch.NewTrapContext(ctx, td)
+			return
+		}
+		s.handler.(TrapHandler).NewTrap(td)
+	})
+}
+
 func (s *serverImpl) acknowledgeInform(pkt *packet, addr net.Addr) error {
 	pkt.RawPdu.FullBytes[0] = getResponse
 	resp, err := ber.Marshal(*pkt)
@@ -202,6 +202,66 @@ func TestMessageParseFailure(t *testing.T) {
 	assert.Nil(t, h.pdu)
 }
 
+func TestPacketServer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	var stopErr error
+	hooks := &ServerHooks{StopListening: func(addr net.Addr, err error) { stopErr = err }}
+	h := newHandler()
+	h.wg.Add(1)
+	s, err := NewPacketServer(h, Hooks(hooks))
+	assert.NoError(t, err)
+
+	served := make(chan error)
+	go func() {
+		served <- s.Serve(conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	assert.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write(messageWithType(v2Trap))
+	assert.NoError(t, err)
+
+	h.wg.Wait()
+	assert.Equal(t, "123456", h.pdu.VarbindList[2].TypedValue.String())
+
+	assert.NoError(t, s.Shutdown())
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+	assert.ErrorIs(t, stopErr, ErrServerClosed)
+
+	// The connection remains usable by its owner, but the server cannot serve again.
+	_, err = conn.WriteTo([]byte("x"), client.LocalAddr())
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.Serve(conn), ErrServerClosed)
+	assert.NoError(t, s.Shutdown())
+}
+
+func TestPacketServerConnectionFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockPacketConn(mockCtrl)
+
+	mockConn.EXPECT().LocalAddr().Return(nil).AnyTimes()
+	mockConn.EXPECT().ReadFrom(gomock.Any()).Return(0, nil, errors.New("read failed"))
+
+	s, err := NewPacketServer(newHandler(), Hooks(NoOpServerHooks))
+	assert.NoError(t, err)
+	assert.EqualError(t, s.Serve(mockConn), "read failed")
+
+	// Following failure, the server may serve another connection.
+	mockConn.EXPECT().ReadFrom(gomock.Any()).Return(0, nil, errors.New("read failed again"))
+	assert.EqualError(t, s.Serve(mockConn), "read failed again")
+}
+
+func TestPacketServerInvalidOptions(t *testing.T) {
+	s, err := NewPacketServer(nil, AllowTrapOIDs([]string{"1.x"}))
+	assert.Error(t, err)
+	assert.Nil(t, s)
+}
+
 func messageWithType(mType byte) []byte {
 	trap := []byte{
 		// Message Type = Sequence, Length = 82
@@ -0,0 +1,121 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ContextHandler may be implemented by a server Handler that wishes to receive a context with each message. If the
+// handler supplied to the server implements ContextHandler, NewMessageContext is invoked in place of NewMessage.
+type ContextHandler interface {
+	// NewMessageContext is called when a trap/inform message has been received, as for Handler.NewMessage.
+	// ctx carries the MessageInfo of the message, and is done when the server is closed or, if HandlerTimeout is
+	// configured, once the timeout has elapsed.
+	NewMessageContext(ctx context.Context, pdu *PDU, isInform bool, sourceAddr net.Addr)
+}
+
+// ContextTrapHandler may be implemented by a server Handler that wishes to receive a context with decoded trap data.
+// If the handler supplied to the server implements ContextTrapHandler, NewTrapContext is invoked in place of NewTrap
+// and NewMessage.
+type ContextTrapHandler interface {
+	// NewTrapContext is called when a trap/inform message has been received, as for TrapHandler.NewTrap, with ctx as
+	// described by ContextHandler.
+	NewTrapContext(ctx context.Context, trap *TrapData)
+}
+
+// MessageInfo describes the receipt of a message delivered to a handler.
+type MessageInfo struct {
+	// The time at which the message was received. Traps collapsed by SuppressDuplicateTraps are delivered with the
+	// time at which the first was received.
+	ReceivedAt time.Time
+	// The address which originated the message.
+	SourceAddress net.Addr
+}
+
+type messageInfoKey struct{}
+
+// MessageInfoFromContext delivers the MessageInfo carried by the context supplied to a ContextHandler or
+// ContextTrapHandler, or nil if none.
+func MessageInfoFromContext(ctx context.Context) *MessageInfo {
+	info, _ := ctx.Value(messageInfoKey{}).(*MessageInfo)
+	return info
+}
+
+// HandlerTimeout defines the time allowed for the handler to process a message, which must be positive. The context
+// supplied to a ContextHandler or ContextTrapHandler is done once the timeout elapses, after which the server
+// abandons the handler, reporting an error to the Error hook, and proceeds to receive further messages, so that
+// slow processing cannot stall the server. The handler should return promptly once the context is done; while an
+// abandoned handler is still running, further messages are not delivered to the handler, and are reported to the
+// Error hook as dropped, so that stuck handlers cannot accumulate.
+// Default is no timeout, in which case the handler is invoked on the receiving goroutine, so that the server does
+// not receive further messages until it returns.
+func HandlerTimeout(timeout time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		if timeout <= 0 {
+			c.err = errors.Errorf("invalid handler timeout %s, must be positive", timeout)
+			return
+		}
+		c.handlerTimeout = timeout
+	}
+}
+
+// Establishes the context that is the parent of handler contexts, which is cancelled when the server is closed.
+func (s *serverImpl) withContext(ctx context.Context) *serverImpl {
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	return s
+}
+
+// Cancels the contexts of handlers in progress.
+func (s *serverImpl) cancelHandlers() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// Invokes handle with a context carrying info, abandoning it once the context is done if HandlerTimeout is
+// configured.
+func (s *serverImpl) invoke(info *MessageInfo, handle func(ctx context.Context)) {
+	if atomic.LoadInt32(&s.abandoned) > 0 {
+		s.config.trace.Error(s.config, errors.Errorf("dropped message from %s, as an abandoned handler is still running",
+			info.SourceAddress))
+		return
+	}
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx := context.WithValue(parent, messageInfoKey{}, info)
+	if s.config.handlerTimeout == 0 {
+		handle(ctx)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.handlerTimeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handle(ctx)
+	}()
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	// The handler may return as the context is done.
+	select {
+	case <-done:
+	default:
+		s.config.trace.Error(s.config, errors.Wrapf(ctx.Err(), "abandoned handler of message from %s",
+			info.SourceAddress))
+		atomic.AddInt32(&s.abandoned, 1)
+		go func() {
+			<-done
+			atomic.AddInt32(&s.abandoned, -1)
+		}()
+	}
+}
@@ -0,0 +1,180 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type contextHandler struct {
+	handle func(ctx context.Context)
+	pdu    *PDU
+	trap   *TrapData
+}
+
+func (h *contextHandler) NewMessage(pdu *PDU, isInform bool, sourceAddr net.Addr) {
+	panic("NewMessage should not be called")
+}
+
+func (h *contextHandler) NewMessageContext(ctx context.Context, pdu *PDU, isInform bool, sourceAddr net.Addr) {
+	h.pdu = pdu
+	h.handle(ctx)
+}
+
+type contextTrapHandler struct {
+	contextHandler
+}
+
+func (h *contextTrapHandler) NewTrapContext(ctx context.Context, trap *TrapData) {
+	h.trap = trap
+	h.handle(ctx)
+}
+
+func newContextServer(ctx context.Context, t *testing.T, h Handler, opts ...ServerOption) (*serverImpl, *[]error) {
+	var errs []error
+	hooks := &ServerHooks{Error: func(config *serverConfig, err error) { errs = append(errs, err) }}
+	config, err := newServerConfig(append([]ServerOption{Hooks(hooks)}, opts...))
+	assert.NoError(t, err)
+	return (&serverImpl{config: config, handler: h}).withContext(ctx), &errs
+}
+
+func TestContextHandler(t *testing.T) {
+	source := &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5000}
+	received := time.Now()
+
+	var info *MessageInfo
+	var deadlineSet bool
+	h := &contextHandler{handle: func(ctx context.Context) {
+		info = MessageInfoFromContext(ctx)
+		_, deadlineSet = ctx.Deadline()
+	}}
+	s, errs := newContextServer(context.Background(), t, h)
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), source, received))
+	assert.NotNil(t, h.pdu)
+	assert.Equal(t, &MessageInfo{ReceivedAt: received, SourceAddress: source}, info)
+	assert.False(t, deadlineSet, "Expecting no deadline without a handler timeout")
+	assert.Empty(t, *errs)
+
+	th := &contextTrapHandler{contextHandler{handle: func(ctx context.Context) {
+		info = MessageInfoFromContext(ctx)
+		_, deadlineSet = ctx.Deadline()
+	}}}
+	s, _ = newContextServer(context.Background(), t, th, HandlerTimeout(time.Second))
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), source, received))
+	assert.Nil(t, th.pdu, "Expecting NewTrapContext to be invoked in place of NewMessageContext")
+	assert.Equal(t, source, th.trap.SourceAddress)
+	assert.Equal(t, received, info.ReceivedAt)
+	assert.True(t, deadlineSet)
+
+	assert.Nil(t, MessageInfoFromContext(context.Background()))
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handlerErr := make(chan error, 1)
+	h := &contextHandler{handle: func(ctx context.Context) {
+		<-ctx.Done()
+		handlerErr <- ctx.Err()
+		// Ignore the context, so that the handler is abandoned.
+		<-release
+	}}
+	s, errs := newContextServer(context.Background(), t, h, HandlerTimeout(50*time.Millisecond))
+
+	begin := time.Now()
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), nil, begin))
+	assert.True(t, time.Since(begin) < time.Second, "Expecting the handler to be abandoned")
+	assert.Len(t, *errs, 1)
+	assert.ErrorIs(t, (*errs)[0], context.DeadlineExceeded)
+	assert.Contains(t, (*errs)[0].Error(), "abandoned handler of message")
+	assert.ErrorIs(t, <-handlerErr, context.DeadlineExceeded)
+
+	// Messages are dropped while the abandoned handler is running.
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), nil, time.Now()))
+	assert.Len(t, *errs, 2)
+	assert.Contains(t, (*errs)[1].Error(), "dropped message")
+
+	_, err := NewPacketServer(h, HandlerTimeout(0))
+	assert.EqualError(t, err, "invalid handler timeout 0s, must be positive")
+}
+
+func TestHandlerCancelledOnClose(t *testing.T) {
+	var handlerErr error
+	h := &contextHandler{handle: func(ctx context.Context) {
+		<-ctx.Done()
+		handlerErr = ctx.Err()
+	}}
+	s, _ := newContextServer(context.Background(), t, h)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.cancelHandlers()
+	}()
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), nil, time.Now()))
+	assert.ErrorIs(t, handlerErr, context.Canceled)
+}
+
+func TestHandlerAbandonedThenCompleted(t *testing.T) {
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	calls := 0
+	h := &contextHandler{handle: func(ctx context.Context) {
+		calls++
+		if calls == 1 {
+			<-release
+			close(returned)
+		}
+	}}
+	s, errs := newContextServer(context.Background(), t, h, HandlerTimeout(10*time.Millisecond))
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), nil, time.Now()))
+	close(release)
+	<-returned
+
+	// Messages are delivered again once the abandoned handler returns.
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&s.abandoned) == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, s.processMessage(messageWithType(v2Trap), nil, time.Now()))
+	assert.Equal(t, 2, calls)
+	assert.Len(t, *errs, 1)
+}
+
+func TestNewServerContextNotParentOfHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := NewServerFactory().NewServer(ctx, nil, Address("127.0.0.1"), Port(0), Hooks(NoOpServerHooks))
+	assert.NoError(t, err)
+	cancel()
+	assert.NoError(t, s.(*serverImpl).ctx.Err(), "Expecting handler contexts to outlive ctx")
+	s.Close()
+	assert.Error(t, s.(*serverImpl).ctx.Err(), "Expecting handler contexts to be cancelled on close")
+}
+
+func TestPacketServerShutdownCancelsHandler(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	handling := make(chan struct{})
+	h := &contextHandler{handle: func(ctx context.Context) {
+		close(handling)
+		<-ctx.Done()
+	}}
+	s, err := NewPacketServer(h, Hooks(NoOpServerHooks))
+	assert.NoError(t, err)
+
+	served := make(chan error)
+	go func() {
+		served <- s.Serve(conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	assert.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write(messageWithType(v2Trap))
+	assert.NoError(t, err)
+
+	<-handling
+	assert.NoError(t, s.Shutdown(), "Expecting shutdown to complete once the handler is cancelled")
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+}
@@ -3,6 +3,7 @@ package snmp
 import (
 	"context"
 	"net"
+	"time"
 
 	"github.com/imdario/mergo"
 )
@@ -10,6 +11,10 @@ import (
 // SewrverFactory defines an interface for instantiating SNMP Trap/Inform servers.
 type ServerFactory interface {
 	// NewServer instantiates an SNMP Trap/Inform server.
+	// If objects are registered with the ServeScalar or ServeTable options, the server also responds to Get,
+	// GetNext and GetBulk requests; handler may be nil if the server is only required to act as an agent.
+	// The server runs until it is closed; the contexts supplied to handlers are derived from context.Background(),
+	// not ctx, and are cancelled when the server is closed.
 	NewServer(ctx context.Context, handler Handler, opts ...ServerOption) (Server, error)
 }
 
@@ -21,25 +26,37 @@ func NewServerFactory() ServerFactory {
 type serverFactoryImpl struct{}
 
 func (f *serverFactoryImpl) NewServer(ctx context.Context, handler Handler, opts ...ServerOption) (Server, error) {
-	config := defaultServerConfig
-	for _, opt := range opts {
-		opt(&config)
+	config, err := newServerConfig(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	config.resolveServerHooks()
-
 	addr := &net.UDPAddr{Port: config.port, IP: net.ParseIP(config.address)}
 	conn, err := net.ListenUDP("udp", addr)
 	if err != nil {
 		return nil, err
 	}
 
-	impl := &serverImpl{config: &config, conn: conn, handler: handler}
+	impl := (&serverImpl{config: config, conn: conn, handler: handler}).withContext(context.Background())
 	impl.handleMessages()
 
 	return impl, err
 }
 
+// Applies the options to the default server configuration.
+func newServerConfig(opts []ServerOption) (*serverConfig, error) {
+	config := defaultServerConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.err != nil {
+		return nil, config.err
+	}
+
+	config.resolveServerHooks()
+	return &config, nil
+}
+
 // ServerOption implements options for configuring server behaviour.
 type ServerOption func(*serverConfig)
 
@@ -85,6 +102,24 @@ type serverConfig struct {
 	port int
 	// Trace hooks
 	trace *ServerHooks
+	// Filters applied to incoming messages before handler invocation.
+	filter serverFilter
+	// Scalar objects and table entries served in response to Get, GetNext and GetBulk requests.
+	objects *OIDTree
+	// Proxy routes, keyed by community, mapping oid prefixes to the sessions to which requests are forwarded.
+	proxies map[string]*OIDTree
+	// Resolves the MIB definitions used to enrich traps; nil means no enrichment.
+	mibs MIBResolver
+	// The window within which identical traps are collapsed; zero means no suppression.
+	dedupWindow time.Duration
+	// Records received datagrams; nil means no capture.
+	capture *CaptureWriter
+	// The time allowed for the handler to process a message; zero means no timeout.
+	handlerTimeout time.Duration
+	// Maximum size of the responses sent by the agent; zero means the maximum udp payload.
+	maxMessageSize int
+	// Error detected whilst applying options.
+	err error
 }
 
 var defaultServerConfig = serverConfig{
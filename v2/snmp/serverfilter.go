@@ -0,0 +1,184 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Filtering of incoming messages, applied before the handler is invoked.
+// Filtered messages are dropped; in particular, filtered informs are not acknowledged.
+
+// FilterReason identifies why a message was filtered.
+type FilterReason int
+
+const (
+	// FilteredSource indicates the message originated from a denied source address.
+	FilteredSource FilterReason = iota
+	// FilteredCommunity indicates the message community is not allowed.
+	FilteredCommunity
+	// FilteredTrapOID indicates the message snmpTrapOID is not allowed.
+	FilteredTrapOID
+)
+
+func (r FilterReason) String() string {
+	switch r {
+	case FilteredSource:
+		return "source"
+	case FilteredCommunity:
+		return "community"
+	case FilteredTrapOID:
+		return "trap-oid"
+	}
+	return "unknown"
+}
+
+// FilterStats defines the number of messages filtered by a server, for each reason.
+type FilterStats struct {
+	Source    uint64
+	Community uint64
+	TrapOID   uint64
+}
+
+// FilterCounter is implemented by servers created by the ServerFactory, to deliver the number of filtered messages.
+type FilterCounter interface {
+	FilterStats() FilterStats
+}
+
+// AllowCommunities restricts the messages delivered to the handler to those with one of the communities.
+// Default is to allow all communities.
+func AllowCommunities(communities []string) ServerOption {
+	return func(c *serverConfig) {
+		c.filter.communities = make(map[string]bool, len(communities))
+		for _, community := range communities {
+			c.filter.communities[community] = true
+		}
+	}
+}
+
+// AllowTrapOIDs restricts the messages delivered to the handler to those whose snmpTrapOID.0 value equals, or is a
+// descendant of, one of the OID prefixes, for example "1.3.6.1.6.3.1.1.5".
+// SNMPv1 traps are matched using the snmpTrapOID.0 value derived as described in RFC3584.
+// Default is to allow all trap OIDs.
+func AllowTrapOIDs(prefixes []string) ServerOption {
+	return func(c *serverConfig) {
+		c.filter.trapOIDs = NewOIDTree()
+		for _, prefix := range prefixes {
+			oid, err := parseOID(prefix)
+			if err != nil {
+				c.err = errors.Wrapf(err, "invalid trap oid prefix %q", prefix)
+				return
+			}
+			c.filter.trapOIDs.Insert(oid, true)
+		}
+	}
+}
+
+// DenySources drops messages originating from addresses within any of the CIDR blocks, for example "10.0.0.0/8".
+// Default is to accept messages from all sources.
+func DenySources(cidrs []string) ServerOption {
+	return func(c *serverConfig) {
+		c.filter.deniedSources = nil
+		for _, cidr := range cidrs {
+			_, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				c.err = errors.Wrapf(err, "invalid source cidr %q", cidr)
+				return
+			}
+			c.filter.deniedSources = append(c.filter.deniedSources, ipnet)
+		}
+	}
+}
+
+// Defines the filters applied to incoming messages. Nil values indicate no filtering.
+type serverFilter struct {
+	communities   map[string]bool
+	trapOIDs      *OIDTree
+	deniedSources []*net.IPNet
+}
+
+func (f *serverFilter) allowSource(addr net.Addr) bool {
+	if len(f.deniedSources) == 0 {
+		return true
+	}
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.IPAddr:
+		ip = a.IP
+	default:
+		return true
+	}
+	for _, ipnet := range f.deniedSources {
+		if ipnet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *serverFilter) allowCommunity(community string) bool {
+	return f.communities == nil || f.communities[community]
+}
+
+func (f *serverFilter) allowTrapOID(pdu *PDU) bool {
+	if f.trapOIDs == nil {
+		return true
+	}
+	for i := range pdu.VarbindList {
+		vb := pdu.VarbindList[i]
+		if vb.OID.Equal(SnmpTrapOIDOID) && vb.TypedValue.Type == OID {
+			_, _, ok := f.trapOIDs.LongestPrefix(vb.TypedValue.OID())
+			return ok
+		}
+	}
+	return false
+}
+
+// Counts of filtered messages, updated atomically.
+type filterCounts struct {
+	source    uint64
+	community uint64
+	trapOID   uint64
+}
+
+func (s *serverImpl) FilterStats() FilterStats {
+	return FilterStats{
+		Source:    atomic.LoadUint64(&s.filtered.source),
+		Community: atomic.LoadUint64(&s.filtered.community),
+		TrapOID:   atomic.LoadUint64(&s.filtered.trapOID),
+	}
+}
+
+func (s *serverImpl) recordFiltered(addr net.Addr, reason FilterReason) {
+	switch reason {
+	case FilteredSource:
+		atomic.AddUint64(&s.filtered.source, 1)
+	case FilteredCommunity:
+		atomic.AddUint64(&s.filtered.community, 1)
+	case FilteredTrapOID:
+		atomic.AddUint64(&s.filtered.trapOID, 1)
+	}
+	s.config.trace.Filtered(s.config, addr, reason)
+}
+
+// Parses a dotted OID string, with optional leading period.
+func parseOID(input string) (asn1.ObjectIdentifier, error) {
+	values := strings.Split(strings.TrimPrefix(input, "."), ".")
+	oid := make(asn1.ObjectIdentifier, len(values))
+	for i, value := range values {
+		arc, err := strconv.Atoi(value)
+		if err != nil || arc < 0 {
+			return nil, errors.Errorf("invalid oid component %q", value)
+		}
+		oid[i] = arc
+	}
+	return oid, nil
+}
@@ -0,0 +1,93 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/golang/mock/gomock"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestMessageFiltering(t *testing.T) {
+	source := &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5000}
+
+	tests := []struct {
+		name      string
+		opts      []ServerOption
+		delivered bool
+		expected  FilterStats
+	}{
+		{"no filters", nil, true, FilterStats{}},
+		{"allowed community", []ServerOption{AllowCommunities([]string{"private", "public"})}, true, FilterStats{}},
+		{"denied community", []ServerOption{AllowCommunities([]string{"private"})}, false, FilterStats{Community: 1}},
+		{"allowed trap oid", []ServerOption{AllowTrapOIDs([]string{".1.3.6.1.1"})}, true, FilterStats{}},
+		{"exact trap oid", []ServerOption{AllowTrapOIDs([]string{"1.3.6.1.1.2.3"})}, true, FilterStats{}},
+		{"denied trap oid", []ServerOption{AllowTrapOIDs([]string{"1.3.6.1.6.3.1.1.5"})}, false, FilterStats{TrapOID: 1}},
+		{"allowed source", []ServerOption{DenySources([]string{"192.168.0.0/16"})}, true, FilterStats{}},
+		{"denied source", []ServerOption{DenySources([]string{"192.168.0.0/16", "10.0.0.0/8"})}, false, FilterStats{Source: 1}},
+		{"source before community", []ServerOption{DenySources([]string{"10.1.2.3/32"}), AllowCommunities(nil)}, false, FilterStats{Source: 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := defaultServerConfig
+			config.trace = NoOpServerHooks
+			for _, opt := range test.opts {
+				opt(&config)
+			}
+			assert.NoError(t, config.err)
+
+			h := newHandler()
+			if test.delivered {
+				h.wg.Add(1)
+			}
+			s := &serverImpl{config: &config, handler: h}
+
+			assert.NoError(t, s.processMessage(messageWithType(v2Trap), source, time.Now()))
+			assert.Equal(t, test.delivered, h.pdu != nil)
+			assert.Equal(t, test.expected, s.FilterStats())
+		})
+	}
+}
+
+func TestFilteredInformIsNotAcknowledged(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockPacketConn(mockCtrl)
+
+	var reasons []FilterReason
+	hooks := ServerHooks{
+		Filtered: func(config *serverConfig, addr net.Addr, reason FilterReason) {
+			reasons = append(reasons, reason)
+		},
+	}
+
+	config := defaultServerConfig
+	config.trace = &hooks
+	AllowCommunities([]string{"private"})(&config)
+	config.resolveServerHooks()
+
+	s := &serverImpl{config: &config, conn: mockConn, handler: newHandler()}
+
+	assert.NoError(t, s.processMessage(messageWithType(inform), nil, time.Now()))
+	assert.Equal(t, []FilterReason{FilteredCommunity}, reasons)
+	assert.Equal(t, uint64(1), s.FilterStats().Community)
+}
+
+func TestInvalidFilterOptions(t *testing.T) {
+	_, err := NewServerFactory().NewServer(context.Background(), newHandler(), AllowTrapOIDs([]string{"1.3.x"}))
+	assert.EqualError(t, err, `invalid trap oid prefix "1.3.x": invalid oid component "x"`)
+
+	_, err = NewServerFactory().NewServer(context.Background(), newHandler(), DenySources([]string{"10.0.0.0"}))
+	assert.EqualError(t, err, `invalid source cidr "10.0.0.0": invalid CIDR address: 10.0.0.0`)
+}
+
+func TestFilterCounterImplemented(t *testing.T) {
+	var s Server = &serverImpl{}
+	_, ok := s.(FilterCounter)
+	assert.True(t, ok)
+}
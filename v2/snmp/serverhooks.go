@@ -1,7 +1,6 @@
 package snmp
 
 import (
-	"encoding/hex"
 	"log"
 	"net"
 )
@@ -22,6 +21,9 @@ type ServerHooks struct {
 
 	// ReadComplete is called after a read has completed
 	ReadComplete func(config *serverConfig, addr net.Addr, input []byte, err error)
+
+	// Filtered is called when a message has been dropped by one of the configured filters.
+	Filtered func(config *serverConfig, addr net.Addr, reason FilterReason)
 }
 
 // DefaultServerHooks provides a default logging hook to report server errors.
@@ -53,10 +55,13 @@ var DiagnosticServerHooks = &ServerHooks{
 		log.Printf("Error err:%v\n", err)
 	},
 	WriteComplete: func(config *serverConfig, addr net.Addr, output []byte, err error) {
-		log.Printf("WriteComplete target:%s err:%v data:%s\n", addr, err, hex.EncodeToString(output))
+		log.Printf("WriteComplete target:%s err:%v data:\n%s\n", addr, err, FormatBER(output))
 	},
 	ReadComplete: func(config *serverConfig, addr net.Addr, input []byte, err error) {
-		log.Printf("ReadComplete source:%s err:%v data:%s\n", addr, err, hex.EncodeToString(input))
+		log.Printf("ReadComplete source:%s err:%v data:\n%s\n", addr, err, FormatBER(input))
+	},
+	Filtered: func(config *serverConfig, addr net.Addr, reason FilterReason) {
+		log.Printf("Filtered source:%s reason:%s\n", addr, reason)
 	},
 }
 
@@ -67,4 +72,5 @@ var NoOpServerHooks = &ServerHooks{
 	Error:          func(config *serverConfig, err error) {},
 	WriteComplete:  func(config *serverConfig, addr net.Addr, output []byte, err error) {},
 	ReadComplete:   func(config *serverConfig, addr net.Addr, input []byte, err error) {},
+	Filtered:       func(config *serverConfig, addr net.Addr, reason FilterReason) {},
 }
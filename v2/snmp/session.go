@@ -1,19 +1,26 @@
 package snmp
 
 import (
+	"bytes"
 	"context"
 	"encoding/asn1"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/geoffgarside/ber"
 )
 
 // Session provides an interface for SNMP device management.
+// A Session is safe for concurrent use, so may be shared by multiple pollers. Requests are serialised on the wire:
+// a request waits for any request in progress on the session to complete, including its retries. Walks issue each
+// of their requests in turn, so the requests of concurrent walks are interleaved; walker functions are called
+// without blocking other callers.
 type Session interface {
 	// Issues an SNMP GET request for the specified oids.
 	// Get request processing is described at https://tools.ietf.org/html/rfc1905#section-4.2.1.
@@ -27,20 +34,61 @@ type Session interface {
 	// Get Bulk request processing is described at https://tools.ietf.org/html/rfc1905#section-4.2.3
 	GetBulk(ctx context.Context, oids []string, nonRepeaters int, maxRepetitions int) (*PDU, error)
 
+	// Issues an SNMP SET request assigning the values of the variable bindings.
+	// Set request processing is described at https://tools.ietf.org/html/rfc1905#section-4.2.5.
+	// As a SET is not idempotent, a request that times out is neither retried nor sent to a fallback address, since
+	// the agent may have applied it; only a request that could not be written fails over.
+	Set(ctx context.Context, varbinds []Varbind) (*PDU, error)
+
 	// Issues SNMP GET NEXT requests starting from the specified root oid, invoking the function walker for each
 	// variable that is a descendant of the root oid.
+	// If WalkConsistencyCheck is enabled, the walk is verified before the variables are delivered, and
+	// ErrWalkInconsistent is returned if the walk could not be verified.
 	Walk(ctx context.Context, rootOid string, walker Walker) error
 
 	// Issues SNMP GET BULK requests starting from the specified root oid, invoking the function walker for each
 	// variable that is a descendant of the root oid.
+	// If WalkConsistencyCheck is enabled, the walk is verified before the variables are delivered, and
+	// ErrWalkInconsistent is returned if the walk could not be verified.
 	BulkWalk(ctx context.Context, rootOid string, maxRepetitions int, walker Walker) error
 
+	// WalkWithStatus behaves as Walk, but delivers any error-status reported by the agent for a variable binding to
+	// the walker, which decides whether the walk continues, the request is retried, or the walk is terminated.
+	WalkWithStatus(ctx context.Context, rootOid string, walker StatusWalker) error
+
+	// BulkWalkWithStatus behaves as BulkWalk, but delivers any error-status reported by the agent for a variable
+	// binding to the walker, as for WalkWithStatus. A tooBig status causes fewer repetitions to be requested, and is
+	// only delivered to the walker once a single repetition is requested.
+	BulkWalkWithStatus(ctx context.Context, rootOid string, maxRepetitions int, walker StatusWalker) error
+
+	// BulkWalkPlan issues SNMP GET BULK requests for the scalars and columns of the plan, requesting the scalars as
+	// non-repeaters alongside the columns, and invoking the walker of each scalar and column for its variables.
+	// The walk is terminated with a *VarbindError if the agent reports an error-status. Walks of a plan are neither
+	// cached nor verified by WalkConsistencyCheck.
+	BulkWalkPlan(ctx context.Context, plan *WalkPlan, maxRepetitions int) error
+
+	// UpdateCredentials replaces the credentials, such as the community, used for subsequent requests, optionally
+	// verifying them with a Get before they are adopted.
+	UpdateCredentials(ctx context.Context, opts ...CredentialOption) error
+
+	// Health delivers the rolling health statistics of the session target.
+	Health() HealthStats
+
+	// Latency delivers the latencies of the successful requests recorded by the session's latency histogram.
+	Latency() LatencySnapshot
+
+	// Discards delivers the number of responses discarded by the session, because they answered earlier or
+	// unknown requests, or had already been received.
+	Discards() DiscardStats
+
 	// Embed standard Close()
 	io.Closer
 }
 
 // Walker defines a function that will be called for each variable processed by the Walk/BulkWalk methods.
 // If the function returns an error, the walk will be terminated.
+// If the agent reports an error-status, the walk is terminated with a *VarbindError, other than for noSuchName,
+// with which SNMPv1 agents indicate the end of the MIB view, which ends the walk without error.
 type Walker func(vb *Varbind) error
 
 // PDU defines an SNMP PDU, as returned by the Get/GetNext methods.
@@ -53,6 +101,11 @@ type PDU struct {
 	// If Error is non-zero, identifies which variable binding in the list caused the exception
 	ErrorIndex  int
 	VarbindList []Varbind
+	// The address of the agent that served the response, which may be one of the session fallback addresses.
+	Address string
+	// Set if the agent truncated the response, for example on reaching its maximum message size, in which case
+	// VarbindList holds only the complete variable bindings that preceded the truncation.
+	Truncated bool
 }
 
 type Varbind struct {
@@ -61,9 +114,21 @@ type Varbind struct {
 }
 
 type sessionImpl struct {
+	// Serialises requests, guarding the fields that follow it.
+	mu sync.Mutex
+	// Also guards conn and config.address, which are changed by a failover, so that Close and Health can be called
+	// whilst a request is in progress.
+	connMu        sync.Mutex
 	conn          net.Conn
 	config        *SessionConfig
 	nextRequestID int32
+	// The request-ids recently issued, used to discard responses that do not answer the current request.
+	requests requestTracker
+	// Scratch variable bindings, reused across requests.
+	varbinds []rawVarbind
+	// The target address followed by any fallback addresses, and the index of the address in use.
+	addresses []string
+	current   int
 }
 
 // rawPDU defines the pdu that is used to passed to/from an SNMP agent.
@@ -96,15 +161,20 @@ const maxInputBufferSize = 65535
 type messageType byte
 
 const (
-	getMessage     = 0xA0
-	getNextMessage = 0xA1
-	getBulkMessage = 0xA5
-	getResponse    = 0xA2
-	inform         = 0xA6
-	v2Trap         = 0xA7
+	getMessage        = 0xA0
+	getNextMessage    = 0xA1
+	getBulkMessage    = 0xA5
+	getResponse       = 0xA2
+	setRequestMessage = 0xA3
+	v1Trap            = 0xA4
+	inform            = 0xA6
+	v2Trap            = 0xA7
 )
 
 func (m *sessionImpl) Get(ctx context.Context, oids []string) (*PDU, error) {
+	if m.config.cache != nil {
+		return m.cachedGet(ctx, oids)
+	}
 	return m.executeGet(ctx, getMessage, oids, 0, 0)
 }
 
@@ -116,15 +186,61 @@ func (m *sessionImpl) GetBulk(ctx context.Context, oids []string, nonRepeaters,
 	return m.executeGet(ctx, getBulkMessage, oids, nonRepeaters, maxRepetitions)
 }
 
+func (m *sessionImpl) Set(ctx context.Context, varbinds []Varbind) (*PDU, error) {
+	oids := make([]string, len(varbinds))
+	values := make([]asn1.RawValue, len(varbinds))
+	for i := range varbinds {
+		if varbinds[i].TypedValue == nil {
+			return nil, fmt.Errorf("no value for oid %s", varbinds[i].OID)
+		}
+		value, err := marshalVariable(varbinds[i].TypedValue)
+		if err != nil {
+			return nil, fmt.Errorf("oid %s: %w", varbinds[i].OID, err)
+		}
+		oids[i], values[i] = varbinds[i].OID.String(), value
+	}
+	if m.config.dryRun != nil {
+		m.config.dryRun(varbinds)
+		return &PDU{VarbindList: copyVarbinds(varbinds)}, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.execute(ctx, setRequestMessage, oids, values, 0, 0)
+}
+
 func (m *sessionImpl) Walk(ctx context.Context, rootOid string, walker Walker) error {
-	return m.executeWalk(ctx, getNextMessage, 0, rootOid, walker)
+	return m.executeWalk(ctx, getNextMessage, 0, rootOid, walker.withStatus())
 }
 
 func (m *sessionImpl) BulkWalk(ctx context.Context, rootOid string, maxRepetitions int, walker Walker) error {
-	return m.executeWalk(ctx, getBulkMessage, maxRepetitions, rootOid, walker)
+	return m.executeWalk(ctx, getBulkMessage, maxRepetitions, rootOid, walker.withStatus())
+}
+
+func (m *sessionImpl) Health() HealthStats {
+	if m.config.health == nil {
+		return HealthStats{}
+	}
+	m.connMu.Lock()
+	address := m.config.address
+	m.connMu.Unlock()
+	return m.config.health.Stats(address)
+}
+
+func (m *sessionImpl) Latency() LatencySnapshot {
+	if m.config.latency == nil {
+		return LatencySnapshot{}
+	}
+	return m.config.latency.Snapshot()
+}
+
+func (m *sessionImpl) Discards() DiscardStats {
+	return m.requests.stats()
 }
 
 func (m *sessionImpl) Close() error {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
 	return m.conn.Close()
 }
 
@@ -132,42 +248,199 @@ func (m *sessionImpl) Close() error {
 // Generates a packet to define the type of Get, the required oids and, in the case of a bulk get, the associated
 // non-repeaters and max-repetitions values.
 // Returns a PDU with the resolved variable bindings.
-func (m *sessionImpl) executeGet(_ context.Context, getType messageType, oids []string, nonRepeaters, maxRepetitions int) (*PDU, error) {
+func (m *sessionImpl) executeGet(ctx context.Context, getType messageType, oids []string,
+	nonRepeaters, maxRepetitions int) (pdu *PDU, err error) {
 	// TODO Validate OIDs on entry.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.execute(ctx, getType, oids, nil, nonRepeaters, maxRepetitions)
+}
 
-	// Keep trying until we succeed, a non-timeout error occurs or the retry limit is reached.
+// Executes a Get, as described by executeGet, or a Set assigning the values to the oids, whilst m.mu is held.
+func (m *sessionImpl) execute(ctx context.Context, mType messageType, oids []string, values []asn1.RawValue,
+	nonRepeaters, maxRepetitions int) (pdu *PDU, err error) {
+	// The time at which the request was first sent, from which its latency is measured.
+	var start time.Time
+	defer func() {
+		if start.IsZero() {
+			return
+		}
+		d := time.Since(start)
+		if err == nil && m.config.latency != nil {
+			m.config.latency.Observe(d)
+		}
+		m.config.trace.RequestDone(m.config, pdu, err, start, d)
+	}()
+
+	// Keep trying until we succeed, a non-timeout error occurs or the retry limit is reached on every address.
+	// A Set that has been sent may have been applied, so is not sent again.
+	resend := mType != setRequestMessage
+	failovers := 0
 	for i := 0; ; i++ {
+		if !m.allowRequest() {
+			return nil, ErrCircuitOpen
+		}
+
 		deadline := time.Now().Add(m.config.timeout)
 		err := m.conn.SetDeadline(deadline)
 		if err != nil {
 			return nil, err
 		}
 
-		b, err := m.buildPacket(oids, getType, nonRepeaters, maxRepetitions)
-		if err != nil {
-			return nil, err
+		begin := time.Now()
+		if start.IsZero() {
+			start = begin
 		}
-
-		err = m.writePacket(b)
+		id := m.nextID()
+		err = m.sendRequest(id, oids, values, mType, nonRepeaters, maxRepetitions)
 		if err != nil {
+			m.recordOutcome(err, time.Since(begin))
+			// A write fails if the address in use is unreachable, for example when it is of a family not routed.
+			var opErr *net.OpError
+			if errors.As(err, &opErr) && m.failover(ctx, &failovers, err) {
+				i = -1
+				continue
+			}
 			return nil, err
 		}
+		m.requests.sent(id, m.config.timeout*requestHistoryTimeouts)
 
-		input, err := m.readResponse()
+		pdu, err := m.receiveResponse(begin, id)
 		if err != nil {
 			// Check for a timeout and retry if allowed.
 			e, ok := err.(net.Error)
-			if ok && e.Timeout() && i < m.config.retries {
+			if ok && e.Timeout() && resend && i < m.config.retries {
+				continue
+			}
+			if ok && e.Timeout() && resend && m.failover(ctx, &failovers, err) {
+				i = -1
 				continue
 			}
 			return nil, err
 		}
-		return m.parseResponse(input)
+		pdu.Address = m.config.address
+		return pdu, nil
+	}
+}
+
+// Switches the session to the next address that can be connected to, returning false once each address has been
+// tried for the current request. The failovers count records the number of switches made for the request.
+func (m *sessionImpl) failover(ctx context.Context, failovers *int, cause error) bool {
+	for *failovers < len(m.addresses)-1 {
+		*failovers++
+		m.current = (m.current + 1) % len(m.addresses)
+
+		config := *m.config
+		config.address = m.addresses[m.current]
+		conn, _, err := newConnection(ctx, &config)
+		if err != nil {
+			m.config.trace.Error("Fallback Connection", &config, err)
+			continue
+		}
+
+		from := m.config.address
+		m.connMu.Lock()
+		_ = m.conn.Close()
+		m.conn, m.config.address = conn, config.address
+		m.connMu.Unlock()
+		m.config.trace.FailedOver(m.config, from, cause)
+		return true
+	}
+	return false
+}
+
+// Builds and writes a request packet, using a pooled marshal buffer.
+func (m *sessionImpl) sendRequest(id int32, oids []string, values []asn1.RawValue, mType messageType,
+	nonRepeaters, maxRepetitions int) error {
+	buf := getMarshalBuffer()
+	defer putMarshalBuffer(buf)
+
+	b, err := m.buildPacket(buf, id, oids, values, mType, nonRepeaters, maxRepetitions)
+	if err != nil {
+		return err
+	}
+	if limit := m.config.maxMessageSize; limit > 0 && len(b) > limit {
+		return fmt.Errorf("request of %d bytes exceeds maximum message size %d", len(b), limit)
+	}
+	return m.writePacket(b)
+}
+
+// Reads and parses the response to the request with the id, using a pooled read buffer.
+// Responses to other requests, such as a late response to a request that has since been retried, and datagrams that
+// cannot be parsed are discarded, and reading continues until the connection deadline.
+func (m *sessionImpl) receiveResponse(begin time.Time, id int32) (*PDU, error) {
+	buf := getReadBuffer()
+	defer putReadBuffer(buf)
+
+	for {
+		input, err := m.readResponse(*buf)
+		if err != nil {
+			m.recordOutcome(err, time.Since(begin))
+			return nil, err
+		}
+		pdu, err := m.parseResponse(input)
+		if err != nil {
+			m.recordOutcome(err, time.Since(begin))
+			m.requests.malformed()
+			m.config.trace.Error("Malformed Response", m.config, err)
+			continue
+		}
+		if reason, ok := m.requests.match(pdu.RequestID, id); !ok {
+			m.config.trace.ResponseDiscarded(m.config, pdu, reason)
+			continue
+		}
+		m.recordOutcome(nil, time.Since(begin))
+		return pdu, nil
+	}
+}
+
+// Determines whether the circuit breaker, if configured, allows a request to be sent to the target.
+func (m *sessionImpl) allowRequest() bool {
+	if m.config.breaker == nil || m.config.health == nil {
+		return true
+	}
+	if m.config.health.allow(m.config.address, m.config.breaker) {
+		return true
+	}
+	m.config.trace.Error("Circuit Breaker", m.config, ErrCircuitOpen)
+	return false
+}
+
+// Records the outcome of a request exchange in the health tracker, if configured.
+func (m *sessionImpl) recordOutcome(err error, d time.Duration) {
+	if m.config.health != nil {
+		m.config.health.record(m.config.address, err, d)
 	}
 }
 
 // Generic Walk execution.
-func (m *sessionImpl) executeWalk(ctx context.Context, mType messageType, maxRepetitions int, rootOid string, walker Walker) error {
+func (m *sessionImpl) executeWalk(ctx context.Context, mType messageType, maxRepetitions int, rootOid string,
+	walker StatusWalker) error {
+	if m.config.cache != nil {
+		return m.cachedWalk(ctx, mType, maxRepetitions, rootOid, walker)
+	}
+	return m.runWalk(ctx, mType, maxRepetitions, rootOid, walker)
+}
+
+// Executes the walk, verifying its consistency if required.
+func (m *sessionImpl) runWalk(ctx context.Context, mType messageType, maxRepetitions int, rootOid string,
+	walker StatusWalker) error {
+	progress := newWalkProgressReporter(m.config.progress, rootOid)
+	var err error
+	if m.config.consistency != nil {
+		err = m.executeConsistentWalk(ctx, mType, maxRepetitions, rootOid, walker, progress)
+	} else {
+		err = m.walk(ctx, mType, maxRepetitions, rootOid, walker, progress)
+	}
+	if err != nil {
+		return err
+	}
+	return progress.complete()
+}
+
+// Walks the root oid, delivering each variable to the walker as it is received.
+func (m *sessionImpl) walk(ctx context.Context, mType messageType, maxRepetitions int, rootOid string,
+	walker StatusWalker, progress *walkProgressReporter) error {
 	nextOid := rootOid
 	for {
 		pdu, err := m.executeGet(ctx, mType, []string{nextOid}, 0, maxRepetitions)
@@ -175,23 +448,84 @@ func (m *sessionImpl) executeWalk(ctx context.Context, mType messageType, maxRep
 			// TODO More intelligence!
 			return err
 		}
+		progress.Requests++
+		if pdu.Error != noError {
+			// Request fewer repetitions if the response would have been too big.
+			if ErrorStatus(pdu.Error) == TooBig && maxRepetitions > 1 {
+				maxRepetitions /= 2
+				continue
+			}
+			vb, status := responseStatus(pdu, nextOid)
+			if err = walker(vb, status); errors.Is(err, ErrRetryVarbind) {
+				continue
+			}
+			return err
+		}
+		if len(pdu.VarbindList) == 0 {
+			// Request fewer repetitions if the agent truncated the first variable binding of the response.
+			if pdu.Truncated && maxRepetitions > 1 {
+				maxRepetitions /= 2
+				continue
+			}
+			return fmt.Errorf("no variable bindings in response to request for %s", nextOid)
+		}
 		for i := range pdu.VarbindList {
 			vb := &pdu.VarbindList[i]
 			if !isOidDescendantOfRoot(vb.OID, rootOid) {
 				return nil
 			}
-			err = walker(vb)
+			err = walker(vb, nil)
 			if err != nil {
 				return err
 			}
+			progress.Varbinds++
 			if vb.TypedValue.Type == EndOfMib {
 				return nil
 			}
 		}
+		if err = progress.report(); err != nil {
+			return err
+		}
 		nextOid = pdu.VarbindList[len(pdu.VarbindList)-1].OID.String()
 	}
 }
 
+// Reports the progress of a walk, as configured by WalkProgressReporting.
+type walkProgressReporter struct {
+	WalkProgress
+	config   *walkProgressConfig
+	begin    time.Time
+	reported time.Time
+}
+
+func newWalkProgressReporter(config *walkProgressConfig, rootOid string) *walkProgressReporter {
+	now := time.Now()
+	return &walkProgressReporter{WalkProgress: WalkProgress{RootOID: rootOid}, config: config, begin: now, reported: now}
+}
+
+func (r *walkProgressReporter) report() error {
+	if r.config == nil {
+		return nil
+	}
+	now := time.Now()
+	if now.Sub(r.reported) < r.config.interval {
+		return nil
+	}
+	r.reported = now
+	r.Elapsed = now.Sub(r.begin)
+	return r.config.fn(r.WalkProgress)
+}
+
+// Reports the final progress of a completed walk, regardless of the interval.
+func (r *walkProgressReporter) complete() error {
+	if r.config == nil {
+		return nil
+	}
+	r.Done = true
+	r.Elapsed = time.Since(r.begin)
+	return r.config.fn(r.WalkProgress)
+}
+
 // Determines whether oid is a 'descendant' of the rootOid.
 func isOidDescendantOfRoot(oid asn1.ObjectIdentifier, rootOid string) bool {
 	return strings.HasPrefix(oid.String(), rootOid+".")
@@ -206,8 +540,8 @@ func (m *sessionImpl) writePacket(b []byte) (err error) {
 	return
 }
 
-func (m *sessionImpl) readResponse() (input []byte, err error) {
-	input = make([]byte, maxInputBufferSize)
+// Reads a response into the supplied buffer, which must be maxInputBufferSize in length.
+func (m *sessionImpl) readResponse(input []byte) (_ []byte, err error) {
 	var n int
 	defer func(begin time.Time) {
 		m.config.trace.ReadDone(m.config, input[0:n], err, time.Since(begin))
@@ -222,6 +556,9 @@ func (m *sessionImpl) readResponse() (input []byte, err error) {
 		// Never expect this to happen
 		return nil, fmt.Errorf("overflowing response buffer")
 	}
+	if limit := m.config.maxMessageSize; limit > 0 && n > limit {
+		return nil, fmt.Errorf("response of %d bytes exceeds maximum message size %d", n, limit)
+	}
 
 	return input[0:n], nil
 }
@@ -237,25 +574,34 @@ func (m *sessionImpl) parseResponse(input []byte) (*PDU, error) {
 	// Stage 3: get the datatype tag of each raw variable binding to determine what golang scalar type should be used to
 	// represent the variable, then replace the tag with the appropriate ASN1 tag and unmarshal the value.
 
+	loc := &decodeLocator{msg: input}
 	pkt := &packet{}
 	_, err := ber.Unmarshal(input, pkt)
 	if err != nil {
-		return nil, err
+		if pdu, ok := salvageResponse(input); ok {
+			m.config.trace.Error("Truncated Response", m.config, err)
+			return pdu, nil
+		}
+		return nil, loc.fail("envelope", input, err)
 	}
 
 	// Replace SNMP PDU Type with ASN1 sequence tag.
+	mType := pkt.RawPdu.FullBytes[0]
 	pkt.RawPdu.FullBytes[0] = 0x30
 
 	rawPDU := &rawPDU{}
 	_, err = ber.Unmarshal(pkt.RawPdu.FullBytes, rawPDU)
 	if err != nil {
-		return nil, err
+		pkt.RawPdu.FullBytes[0] = mType
+		return nil, loc.fail("pdu", pkt.RawPdu.FullBytes, err)
 	}
 
-	return unmarshalValues(rawPDU)
+	return unmarshalValues(rawPDU, loc)
 }
 
-func unmarshalValues(raw *rawPDU) (*PDU, error) {
+// Unmarshals the values of the variable bindings of a PDU; failures are located within the message by loc, if
+// defined.
+func unmarshalValues(raw *rawPDU, loc *decodeLocator) (*PDU, error) {
 	pdu := &PDU{
 		RequestID:   raw.RequestID,
 		Error:       raw.Error,
@@ -263,9 +609,12 @@ func unmarshalValues(raw *rawPDU) (*PDU, error) {
 		VarbindList: make([]Varbind, len(raw.VarbindList)),
 	}
 	for i := range raw.VarbindList {
+		element := raw.VarbindList[i].Value.FullBytes
+		tag := element[0]
 		value, err := unmarshalVariable(&raw.VarbindList[i].Value)
 		if err != nil {
-			return nil, err
+			element[0] = tag
+			return nil, loc.fail(fmt.Sprintf("varbind %d", i+1), element, err)
 		}
 		pdu.VarbindList[i].OID = raw.VarbindList[i].OID
 		pdu.VarbindList[i].TypedValue = value
@@ -273,10 +622,16 @@ func unmarshalValues(raw *rawPDU) (*PDU, error) {
 	return pdu, nil
 }
 
-func (m *sessionImpl) buildPacket(oids []string, mType messageType, nonRepeaters, maxRepetitions int) ([]byte, error) {
+// Builds a request packet in buf, returning the packet bytes. The variable bindings hold the values, if any, or are
+// otherwise null.
+// Only the PDU is marshaled generically; the packet envelope is written directly, to avoid a second marshal of
+// the PDU content.
+func (m *sessionImpl) buildPacket(buf *bytes.Buffer, id int32, oids []string, values []asn1.RawValue, mType messageType,
+	nonRepeaters, maxRepetitions int) ([]byte, error) {
+	m.varbinds = buildVarbindList(m.varbinds, oids, values)
 	pdu := rawPDU{
-		RequestID:   m.nextID(),
-		VarbindList: buildVarbindList(oids),
+		RequestID:   id,
+		VarbindList: m.varbinds,
 	}
 
 	if mType == getBulkMessage {
@@ -290,17 +645,22 @@ func (m *sessionImpl) buildPacket(oids []string, mType messageType, nonRepeaters
 
 	b[0] = byte(mType)
 
-	p := packet{
-		Version:   m.config.version,
-		Community: []byte(m.config.community),
-		RawPdu:    asn1.RawValue{FullBytes: b},
-	}
-
-	b, err = ber.Marshal(p)
-	if err != nil {
-		return nil, err
-	}
-	return b, nil
+	// Reserve space for the packet sequence header, which is filled in once the content length is known.
+	var header [maxHeaderSize]byte
+	buf.Write(header[:])
+	writeInteger(buf, int(m.config.version))
+	buf.WriteByte(asn1.TagOctetString)
+	writeLength(buf, len(m.config.community))
+	buf.WriteString(m.config.community)
+	buf.Write(b)
+
+	packet := buf.Bytes()
+	hdr := bytes.NewBuffer(header[:0])
+	hdr.WriteByte(asn1.TagSequence | compoundTag)
+	writeLength(hdr, len(packet)-maxHeaderSize)
+	start := maxHeaderSize - hdr.Len()
+	copy(packet[start:], hdr.Bytes())
+	return packet[start:], nil
 }
 
 func (m *sessionImpl) nextID() (id int32) {
@@ -309,11 +669,15 @@ func (m *sessionImpl) nextID() (id int32) {
 	return
 }
 
-func buildVarbindList(oids []string) []rawVarbind {
-	vbl := make([]rawVarbind, len(oids))
+// Builds the variable bindings for a request, reusing the capacity of vbl. Values is nil for a Get request.
+func buildVarbindList(vbl []rawVarbind, oids []string, values []asn1.RawValue) []rawVarbind {
+	vbl = vbl[:0]
 	for i := 0; i < len(oids); i++ {
-		vbl[i].OID = oidToInts(oids[i])
-		vbl[i].Value = asn1.NullRawValue
+		value := asn1.NullRawValue
+		if values != nil {
+			value = values[i]
+		}
+		vbl = append(vbl, rawVarbind{OID: oidToInts(oids[i]), Value: value})
 	}
 	return vbl
 }
@@ -4,7 +4,9 @@ package snmp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/damianoneill/net/v2/snmp/mocks"
 	"github.com/golang/mock/gomock"
@@ -84,7 +86,7 @@ func TestGet(t *testing.T) {
 		mockConn.EXPECT().Close().Return(nil),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = NoOpLoggingHooks
@@ -165,7 +167,7 @@ func TestGetNext(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = DiagnosticLoggingHooks
@@ -274,7 +276,7 @@ func TestGetBulk(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = DiagnosticLoggingHooks
@@ -420,7 +422,7 @@ func TestWalk(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = DiagnosticLoggingHooks
@@ -447,7 +449,7 @@ func TestNetworkWriteFailure(t *testing.T) {
 		mockConn.EXPECT().Write(gomock.Any()).Return(0, errors.New("snmp failure")),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = NoOpLoggingHooks
@@ -471,7 +473,7 @@ func TestSetDeadlineFailure(t *testing.T) {
 		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(errors.New("snmp failure")),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = NoOpLoggingHooks
@@ -522,7 +524,7 @@ func TestNetworkReadFailure(t *testing.T) {
 		mockConn.EXPECT().Read(gomock.Any()).Return(0, errors.New("snmp failure")),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = DiagnosticLoggingHooks
@@ -580,11 +582,13 @@ func TestUnmarshalPacketFailure(t *testing.T) {
 				copy(input, getResponse)
 				return len(getResponse), nil
 			}),
+		mockConn.EXPECT().Read(gomock.Any()).Return(0, &timeoutError{}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
+	config.retries = 0
 	config.trace = DiagnosticLoggingHooks
 	m := &sessionImpl{config: &config, conn: mockConn, nextRequestID: 1}
 
@@ -594,7 +598,8 @@ func TestUnmarshalPacketFailure(t *testing.T) {
 		return nil
 	}
 	err := m.Walk(context.Background(), "1.3.6.1.2.1.1.4", walker)
-	assert.Contains(t, err.Error(), "asn1: syntax error:")
+	assert.Error(t, err)
+	assert.Equal(t, DiscardStats{Malformed: 1}, m.Discards())
 }
 
 func TestWalkWalkerFailure(t *testing.T) {
@@ -663,7 +668,7 @@ func TestWalkWalkerFailure(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = DiagnosticLoggingHooks
@@ -750,7 +755,7 @@ func TestBulkWalk(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = MetricLoggingHooks
@@ -847,8 +852,8 @@ func TestRetry(t *testing.T) {
 		0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
 		// PDU Type = GetResponse, Length = 39
 		0xa2, 0x82, 0x00, 0x27,
-		// Request ID Type = Integer, Length = 1, Value = 1
-		0x02, 0x01, 0x01,
+		// Request ID Type = Integer, Length = 1, Value = 2
+		0x02, 0x01, 0x02,
 		// Error Type = Integer, Length = 1, Value = 0
 		0x02, 0x01, 0x00,
 		// Error Index Type = Integer, Length = 1, Value = 0
@@ -876,7 +881,7 @@ func TestRetry(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = public
 	config.trace = NoOpLoggingHooks
@@ -891,6 +896,104 @@ func TestRetry(t *testing.T) {
 	assert.Equal(t, "cisco-7513", string(tv.Value.([]uint8)))
 }
 
+func TestSetNotRetried(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockConn(mockCtrl)
+
+	gomock.InOrder(
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) { return len(b), nil }),
+		mockConn.EXPECT().Read(gomock.Any()).Return(0, &timeoutError{}),
+	)
+
+	config := DefaultConfig
+	config.address = localhost161
+	config.community = public
+	config.trace = NoOpLoggingHooks
+	m := &sessionImpl{config: &config, conn: mockConn, nextRequestID: 1,
+		addresses: []string{localhost161, "localhost:1161"}}
+
+	// The agent may have applied the request, so it is neither retried nor sent to the fallback address.
+	_, err := m.Set(context.Background(), []Varbind{stringVarbind("1.3.6.1.2.1.1.5.0", "router")})
+	assert.Error(t, err)
+	assert.Equal(t, localhost161, m.config.address)
+}
+
+// Delivers a copy of the response with the request id, which must be in the range 0..127, set to id.
+func withRequestID(response []byte, id byte) []byte {
+	r := append([]byte(nil), response...)
+	r[21] = id
+	return r
+}
+
+func TestDiscardedResponses(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockConn(mockCtrl)
+
+	getResponse := []byte{
+		0x30, 0x82, 0x00, 0x36,
+		0x02, 0x01, 0x01,
+		0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
+		0xa2, 0x82, 0x00, 0x27,
+		// Request ID Type = Integer, Length = 1, Value = 1
+		0x02, 0x01, 0x01,
+		0x02, 0x01, 0x00,
+		0x02, 0x01, 0x00,
+		0x30, 0x82, 0x00, 0x1a,
+		0x30, 0x82, 0x00, 0x16,
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x05, 0x00,
+		0x04, 0x0a, 0x63, 0x69, 0x73, 0x63, 0x6f, 0x2d, 0x37, 0x35, 0x31, 0x33,
+	}
+	respond := func(response []byte) func(input []byte) (int, error) {
+		return func(input []byte) (int, error) {
+			return copy(input, response), nil
+		}
+	}
+
+	gomock.InOrder(
+		// The first request times out, and its response arrives after the retry has been sent.
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).Return(0, &timeoutError{}),
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(respond(withRequestID(getResponse, 1))),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(respond(withRequestID(getResponse, 2))),
+		// The next request receives a duplicate of the response to the retry, and a response to an unknown request.
+		mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+		mockConn.EXPECT().Write(gomock.Any()).Return(40, nil),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(respond(withRequestID(getResponse, 2))),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(respond(withRequestID(getResponse, 99))),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(respond([]byte{0x30, 0x03, 0x02, 0x01})),
+		mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(respond(withRequestID(getResponse, 3))),
+	)
+
+	var discarded []string
+	config := DefaultConfig
+	config.address = localhost161
+	config.community = public
+	config.retries = 1
+	config.trace = &SessionTrace{}
+	*config.trace = *NoOpLoggingHooks
+	config.trace.ResponseDiscarded = func(config *SessionConfig, pdu *PDU, reason DiscardReason) {
+		discarded = append(discarded, fmt.Sprintf("%d:%s", pdu.RequestID, reason))
+	}
+	m := &sessionImpl{config: &config, conn: mockConn, nextRequestID: 1}
+
+	pdu, err := m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), pdu.RequestID)
+
+	pdu, err = m.Get(context.Background(), []string{"1.3.6.1.2.1.1.5.0"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), pdu.RequestID)
+
+	assert.Equal(t, []string{"1:late", "2:duplicate", "99:unknown"}, discarded)
+	assert.Equal(t, DiscardStats{Late: 1, Duplicate: 1, Unknown: 1, Malformed: 1}, m.Discards())
+}
+
 func TestEndOfMib(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -956,7 +1059,7 @@ func TestEndOfMib(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = private
 	config.trace = DiagnosticLoggingHooks
@@ -1038,7 +1141,7 @@ func TestNoSuchObject(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = private
 	config.trace = NoOpLoggingHooks
@@ -1118,7 +1221,7 @@ func TestNoSuchInstance(t *testing.T) {
 			}),
 	)
 
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = localhost161
 	config.community = private
 	config.trace = NoOpLoggingHooks
@@ -1133,6 +1236,80 @@ func TestNoSuchInstance(t *testing.T) {
 	assert.Nil(t, tv.Value)
 }
 
+func TestWalkProgressReporting(t *testing.T) {
+	var reports []WalkProgress
+	stop := errors.New("stop")
+	ses := newTestSession(t, newTestAgentServer(t, testAgentObjects...),
+		WalkProgressReporting(0, func(progress WalkProgress) error {
+			reports = append(reports, progress)
+			if progress.Varbinds == 3 {
+				return stop
+			}
+			return nil
+		}))
+
+	err := ses.Walk(context.Background(), ifEntry, func(vb *Varbind) error { return nil })
+	assert.Equal(t, stop, err, "Expecting progress function to terminate walk")
+	assert.Len(t, reports, 3)
+	for i, report := range reports {
+		assert.Equal(t, ifEntry, report.RootOID)
+		assert.Equal(t, i+1, report.Requests)
+		assert.Equal(t, i+1, report.Varbinds)
+	}
+	assert.True(t, reports[2].Elapsed >= reports[0].Elapsed)
+
+	reports = nil
+	err = ses.BulkWalk(context.Background(), ifEntry, 10, func(vb *Varbind) error { return nil })
+	assert.NoError(t, err)
+	assert.Len(t, reports, 1, "Expecting only the final report of a walk completed by its first response")
+	assert.True(t, reports[0].Done)
+}
+
+func TestWalkProgressInterval(t *testing.T) {
+	reports := 0
+	ses := newTestSession(t, newTestAgentServer(t, testAgentObjects...),
+		WalkProgressReporting(time.Hour, func(progress WalkProgress) error {
+			reports++
+			return nil
+		}))
+
+	err := ses.Walk(context.Background(), ifEntry, func(vb *Varbind) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reports, "Expecting only the final report within the interval")
+}
+
+func TestWalkProgressComplete(t *testing.T) {
+	var reports []WalkProgress
+	ses := newTestSession(t, newTestAgentServer(t, testAgentObjects...),
+		WalkProgressReporting(0, func(progress WalkProgress) error {
+			reports = append(reports, progress)
+			return nil
+		}))
+
+	var walked int
+	err := ses.Walk(context.Background(), ifEntry, func(vb *Varbind) error {
+		walked++
+		return nil
+	})
+	assert.NoError(t, err)
+	final := reports[len(reports)-1]
+	assert.True(t, final.Done)
+	assert.Equal(t, walked, final.Varbinds)
+	for _, report := range reports[:len(reports)-1] {
+		assert.False(t, report.Done)
+	}
+}
+
+func TestWalkProgressRemaining(t *testing.T) {
+	p := WalkProgress{Varbinds: 50, Elapsed: 5 * time.Second}
+	assert.Equal(t, 10.0, p.Rate())
+	assert.Equal(t, 15*time.Second, p.Remaining(200))
+	assert.Zero(t, p.Remaining(40), "Expecting no estimate once the total is reached")
+	assert.Zero(t, WalkProgress{}.Remaining(200), "Expecting no estimate without a rate")
+	p.Done = true
+	assert.Zero(t, p.Remaining(200))
+}
+
 //nolint: gocritic
 // Tests against real SNMP agent. Useful for diagnostics.
 //
@@ -4,9 +4,12 @@ import (
 	"context"
 	"math/rand"
 	"net"
+	"strings"
 	"time"
 
+	"github.com/damianoneill/net/v2/target"
 	"github.com/imdario/mergo"
+	"github.com/pkg/errors"
 )
 
 // Defines a factory method for instantiating SNMP Sessions.
@@ -20,41 +23,84 @@ func NewFactory() SessionFactory {
 	return &factoryImpl{}
 }
 
+// NewSessionForTarget uses the factory to instantiate an SNMP session for the target's snmp endpoint, using the
+// community resolved by r. Options that follow override the resolved community.
+func NewSessionForTarget(ctx context.Context, factory SessionFactory, t *target.Target, r target.CredentialResolver,
+	opts ...SessionOption) (Session, error) {
+	creds, err := target.ResolveCredentials(ctx, r, t, target.SNMP)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Community != "" {
+		opts = append([]SessionOption{Community(creds.Community)}, opts...)
+	}
+	return factory.NewSession(ctx, t.Endpoint(target.SNMP), opts...)
+}
+
 type factoryImpl struct{}
 
 func (f *factoryImpl) NewSession(ctx context.Context, target string, opts ...SessionOption) (Session, error) {
-	config := defaultConfig
+	config := DefaultConfig
 	config.address = target
 	for _, opt := range opts {
 		opt(&config)
 	}
+	if config.err != nil {
+		return nil, config.err
+	}
 
-	_ = mergo.Merge(config.trace, NoOpLoggingHooks)
+	// The hooks are copied, so that those supplied, which may be shared, such as DefaultLoggingHooks, are unchanged.
+	trace := *config.trace
+	_ = mergo.Merge(&trace, NoOpLoggingHooks)
+	config.trace = &trace
 
-	conn, err := newConnection(ctx, &config)
+	if config.health == nil {
+		config.health = NewHealthTracker(0)
+	}
+	if config.latency == nil {
+		config.latency = NewLatencyHistogram()
+	}
+
+	conn, alternates, err := newConnection(ctx, &config)
 	if err != nil {
 		config.trace.Error("Network Connection", &config, err)
 		return nil, err
 	}
 
-	return &sessionImpl{config: &config, conn: conn, nextRequestID: rand.Int31()}, nil //nolint: gosec
+	// The other addresses to which the target resolved are tried ahead of the fallback addresses.
+	addresses := append(append([]string{target}, alternates...), config.fallbacks...)
+	return &sessionImpl{config: &config, conn: conn, nextRequestID: rand.Int31(), //nolint: gosec
+		addresses: addresses}, nil
 }
 
 // SessionOption implements options for configuring session behaviour.
 type SessionOption func(*SessionConfig)
 
-// Timeout defines the timeout for receiving a response to a request
-// Default value is 1s.
+// Timeout defines the timeout for receiving a response to a request, which must not be negative; zero selects the
+// timeout of DefaultConfig.
+// Default value is 5s.
 func Timeout(timeout time.Duration) SessionOption {
 	return func(c *SessionConfig) {
-		c.timeout = timeout
+		switch {
+		case timeout < 0:
+			c.setErr(errors.Errorf("invalid timeout %s, must not be negative", timeout))
+		case timeout == 0:
+			c.timeout = DefaultConfig.timeout
+		default:
+			c.timeout = timeout
+		}
 	}
 }
 
-// Retries defines the number of times an unsuccessful request will be retried.
-// Default value is 0
+// Retries defines the number of times an unsuccessful request will be retried, which must not be negative.
+// SET requests are not retried.
+// Default value is 3.
 func Retries(value int) SessionOption {
 	return func(c *SessionConfig) {
+		if value < 0 {
+			c.setErr(errors.Errorf("invalid retries %d, must not be negative", value))
+			return
+		}
 		c.retries = value
 	}
 }
@@ -67,10 +113,14 @@ func Network(value string) SessionOption {
 	}
 }
 
-// WithVersion defines the SNMP version to use.
+// WithVersion defines the SNMP version to use, which must be SNMPV1 or SNMPV2C.
 // Default value is SNMPV2C
 func WithVersion(value Version) SessionOption {
 	return func(c *SessionConfig) {
+		if value != SNMPV1 && value != SNMPV2C {
+			c.setErr(errors.Errorf("unsupported snmp version %d", value))
+			return
+		}
 		c.version = value
 	}
 }
@@ -83,14 +133,148 @@ func Community(value string) SessionOption {
 	}
 }
 
-// LoggingHooks defines a set of logging hooks to be used by the session.
+// MaxMessageSize defines the maximum size in bytes of the messages exchanged with the agent, which must be between
+// 484, the minimum that agents are required to accept, and 65507, the maximum udp payload. Requests that exceed the
+// size are not sent, and responses that exceed it are rejected.
+// Default value is 65507.
+func MaxMessageSize(size int) SessionOption {
+	return func(c *SessionConfig) {
+		if size < minMessageSize || size > maxMessageSize {
+			c.setErr(errors.Errorf("invalid max message size %d, must be between %d and %d", size, minMessageSize, maxMessageSize))
+			return
+		}
+		c.maxMessageSize = size
+	}
+}
+
+// LoggingHooks defines a set of logging hooks to be used by the session, which must not be nil.
 // Default value is DefaultLoggingHooks.
 func LoggingHooks(trace *SessionTrace) SessionOption {
 	return func(c *SessionConfig) {
+		if trace == nil {
+			c.setErr(errors.New("invalid logging hooks, must not be nil"))
+			return
+		}
 		c.trace = trace
 	}
 }
 
+// Resolver defines the resolver used to look up the addresses of the target host.
+// The session uses the first of the addresses, IPv6 first, that can be dialed, and fails over to the others in turn,
+// ahead of any FallbackAddresses.
+// Default value is net.DefaultResolver.
+func Resolver(r *net.Resolver) SessionOption {
+	return func(c *SessionConfig) {
+		c.resolver = r
+	}
+}
+
+// HealthTracking defines the tracker used to maintain health statistics for the session target.
+// Sharing a tracker across sessions allows statistics to persist across sessions to the same target.
+// Default value is a tracker private to the session.
+func HealthTracking(tracker *HealthTracker) SessionOption {
+	return func(c *SessionConfig) {
+		c.health = tracker
+	}
+}
+
+// LatencyTracking defines the histogram used to record the latency of each successful request, measured from the
+// first write of the request to the decoding of its response, so including any retries.
+// Sharing a histogram across sessions aggregates the latencies of their targets.
+// Default value is a histogram private to the session, with DefaultLatencyBuckets.
+func LatencyTracking(histogram *LatencyHistogram) SessionOption {
+	return func(c *SessionConfig) {
+		c.latency = histogram
+	}
+}
+
+// CircuitBreaker enables fail-fast behaviour for targets deemed down.
+// Once threshold consecutive timeouts have been observed for the target, requests fail immediately with
+// ErrCircuitOpen for the cooldown period, after which a single trial request is allowed through.
+// Default is no circuit breaker.
+func CircuitBreaker(threshold int, cooldown time.Duration) SessionOption {
+	return func(c *SessionConfig) {
+		c.breaker = &circuitBreaker{threshold: threshold, cooldown: cooldown}
+	}
+}
+
+// FallbackAddresses defines alternate addresses of the target, for example an out-of-band management address.
+// When a request to the address in use has timed out on every retry, or cannot be written, the session switches to
+// the next address and the request is retried there; the session continues to use that address for subsequent requests.
+// Each request tries each address at most once. The PDU delivered by the session identifies the address that served
+// the response.
+// Default is no fallback addresses.
+func FallbackAddresses(addresses []string) SessionOption {
+	return func(c *SessionConfig) {
+		c.fallbacks = addresses
+	}
+}
+
+// WalkProgressFunc is called periodically during a Walk or BulkWalk to report its progress.
+// If the function returns an error, the walk will be terminated with that error.
+type WalkProgressFunc func(progress WalkProgress) error
+
+// WalkProgress defines the progress of a walk.
+type WalkProgress struct {
+	// The root OID of the walk.
+	RootOID string
+	// The number of variables delivered to the walker.
+	Varbinds int
+	// The number of requests issued.
+	Requests int
+	// The time elapsed since the walk began.
+	Elapsed time.Duration
+	// Set in the final report, made once the walk has completed successfully.
+	Done bool
+}
+
+// Rate delivers the number of variables delivered to the walker per second.
+func (p WalkProgress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Varbinds) / p.Elapsed.Seconds()
+}
+
+// Remaining estimates the time until total variables, such as the number of rows of a table multiplied by the
+// number of columns walked, have been delivered, from the rate at which variables have been delivered so far.
+// Zero is delivered if the walk is done, total has been reached, or no variables have yet been delivered.
+func (p WalkProgress) Remaining(total int) time.Duration {
+	rate := p.Rate()
+	if p.Done || rate == 0 || total <= p.Varbinds {
+		return 0
+	}
+	return time.Duration(float64(total-p.Varbinds) / rate * float64(time.Second))
+}
+
+// WalkProgressReporting defines a function that is called to report the progress of each Walk and BulkWalk, at
+// most once per interval, after a response has been processed, and once more when the walk completes successfully.
+// A zero interval reports progress after every response. A walk served from a ResultCache is not reported.
+// Default is no progress reporting.
+func WalkProgressReporting(interval time.Duration, fn WalkProgressFunc) SessionOption {
+	return func(c *SessionConfig) {
+		c.progress = &walkProgressConfig{interval: interval, fn: fn}
+	}
+}
+
+// WalkConsistencyCheck enables detection of changes to the variables walked by Walk and BulkWalk, such as rows
+// being added to or removed from a table, whilst the walk is in progress. Each walk is followed by a verification
+// walk, and if the oids walked differ, the walk is retried up to retries times. If no attempt is verified, the
+// variables of the final attempt are delivered and ErrWalkInconsistent is returned, so that the result can be
+// flagged as inconsistent.
+// As the variables are only delivered once the walk is complete, they are held in memory for the duration of the walk.
+// Default is no consistency check.
+func WalkConsistencyCheck(retries int) SessionOption {
+	return func(c *SessionConfig) {
+		c.consistency = &walkConsistencyConfig{retries: retries}
+	}
+}
+
+type walkProgressConfig struct {
+	interval time.Duration
+	fn       WalkProgressFunc
+}
+
 // SNMP Versions.
 type Version int
 
@@ -101,12 +285,76 @@ const (
 )
 
 // Deliver a new network connection to the address defined in the configuration.
-func newConnection(_ context.Context, c *SessionConfig) (conn net.Conn, err error) {
+// Host names are resolved using the context, and the resolved addresses are tried in turn, IPv6
+// first, until a connection is established. As dialing a connectionless network such as udp
+// succeeds whether or not the agent is reachable, the resolved addresses following the one
+// connected to are delivered as alternates, to which the session fails over if a request to
+// the address in use times out or cannot be written.
+func newConnection(ctx context.Context, c *SessionConfig) (conn net.Conn, alternates []string, err error) {
 	defer func(begin time.Time) {
 		c.trace.ConnectDone(c, err, time.Since(begin))
 	}(time.Now())
 	c.trace.ConnectStart(c)
-	return net.Dial(c.network, c.address)
+
+	host, port, err := net.SplitHostPort(c.address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialer := &net.Dialer{}
+	if net.ParseIP(host) != nil {
+		conn, err = dialer.DialContext(ctx, c.network, c.address)
+		return conn, nil, err
+	}
+
+	resolver := c.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addrs = orderAddrs(c.network, addrs)
+	if len(addrs) == 0 {
+		return nil, nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+
+	for i, addr := range addrs {
+		conn, err = dialer.DialContext(ctx, c.network, net.JoinHostPort(addr.IP.String(), port))
+		if err == nil {
+			for _, alternate := range addrs[i+1:] {
+				alternates = append(alternates, net.JoinHostPort(alternate.IP.String(), port))
+			}
+			return conn, alternates, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, err
+}
+
+// Delivers the addresses that are usable on the network, with IPv6 addresses ahead of IPv4 addresses.
+func orderAddrs(network string, addrs []net.IPAddr) []net.IPAddr {
+	var v6, v4 []net.IPAddr
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(network, "4"):
+		return v4
+	case strings.HasSuffix(network, "6"):
+		return v6
+	default:
+		return append(v6, v4...)
+	}
 }
 
 // SessionConfig defines properties controlling session behaviour.
@@ -125,15 +373,72 @@ type SessionConfig struct {
 	retries int
 	// Trace hooks
 	trace *SessionTrace
-	// TODO Define additional configuration properties as required.
+	// Health statistics for the target
+	health *HealthTracker
+	// Latencies of successful requests, nil if not recorded.
+	latency *LatencyHistogram
+	// Cache of the results of requests for semi-static objects, nil if disabled.
+	cache *ResultCache
+	// Circuit breaker configuration, nil if disabled.
+	breaker *circuitBreaker
+	// Resolver used to look up host addresses, nil for the default resolver.
+	resolver *net.Resolver
+	// Alternate addresses used when the target address persistently times out.
+	fallbacks []string
+	// Walk progress reporting, nil if disabled.
+	progress *walkProgressConfig
+	// Walk consistency checking, nil if disabled.
+	consistency *walkConsistencyConfig
+	// Maximum size of messages exchanged with the agent, zero if unlimited.
+	maxMessageSize int
+	// Records suppressed Set requests, nil unless dry-run is enabled.
+	dryRun func(varbinds []Varbind)
+	// The first error reported by an option, if any.
+	err error
 }
 
-var defaultConfig = SessionConfig{
-	network:   "udp",
-	address:   "",
-	community: "public",
-	version:   SNMPV2C,
-	timeout:   time.Second * 5,
-	retries:   3,
-	trace:     DefaultLoggingHooks,
+// Limits of the maximum message size.
+const (
+	minMessageSize = 484
+	maxMessageSize = 65507
+)
+
+// DefaultConfig defines the configuration of sessions to which options are applied.
+var DefaultConfig = SessionConfig{
+	network:        "udp",
+	address:        "",
+	community:      "public",
+	version:        SNMPV2C,
+	timeout:        time.Second * 5,
+	retries:        3,
+	trace:          DefaultLoggingHooks,
+	maxMessageSize: maxMessageSize,
 }
+
+// Records the first invalid option.
+func (c *SessionConfig) setErr(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Network delivers the transport network.
+func (c *SessionConfig) Network() string { return c.network }
+
+// Address delivers the address of the target agent.
+func (c *SessionConfig) Address() string { return c.address }
+
+// Version delivers the SNMP version.
+func (c *SessionConfig) Version() Version { return c.version }
+
+// Community delivers the community string.
+func (c *SessionConfig) Community() string { return c.community }
+
+// Timeout delivers the timeout for receiving a response to a request.
+func (c *SessionConfig) Timeout() time.Duration { return c.timeout }
+
+// Retries delivers the number of times an unsuccessful request will be retried.
+func (c *SessionConfig) Retries() int { return c.retries }
+
+// MaxMessageSize delivers the maximum size in bytes of the messages exchanged with the agent, zero if unlimited.
+func (c *SessionConfig) MaxMessageSize() int { return c.maxMessageSize }
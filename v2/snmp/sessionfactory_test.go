@@ -2,9 +2,15 @@ package snmp
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/damianoneill/net/v2/target"
 	assert "github.com/stretchr/testify/require"
 )
 
@@ -23,6 +29,7 @@ func TestNewSessionOptions(t *testing.T) {
 		Retries(5),
 		WithVersion(SNMPV2C),
 		Community("public"),
+		MaxMessageSize(1500),
 		LoggingHooks(DiagnosticLoggingHooks),
 	)
 	assert.NoError(t, err)
@@ -34,6 +41,77 @@ func TestNewSessionOptions(t *testing.T) {
 	assert.Equal(t, 5, impl.config.retries)
 	assert.Equal(t, SNMPV2C, impl.config.version)
 	assert.Equal(t, "public", impl.config.community)
+	assert.Equal(t, 1500, impl.config.MaxMessageSize())
+}
+
+func TestNewSessionInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  SessionOption
+		err  string
+	}{
+		{"Timeout", Timeout(-time.Millisecond), "invalid timeout -1ms, must not be negative"},
+		{"Retries", Retries(-1), "invalid retries -1, must not be negative"},
+		{"Version", WithVersion(SNMPV3), "unsupported snmp version 3"},
+		{"MaxMessageSizeSmall", MaxMessageSize(100), "invalid max message size 100, must be between 484 and 65507"},
+		{"MaxMessageSizeLarge", MaxMessageSize(70000), "invalid max message size 70000, must be between 484 and 65507"},
+		{"LoggingHooks", LoggingHooks(nil), "invalid logging hooks, must not be nil"},
+	}
+
+	//nolint: scopelint
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewFactory().NewSession(context.Background(), "localhost:161", Retries(1), tt.opt, Timeout(-time.Second))
+			assert.EqualError(t, err, tt.err, "Expecting the first invalid option to be reported")
+			assert.Nil(t, m)
+		})
+	}
+}
+
+func TestZeroTimeout(t *testing.T) {
+	m, err := NewFactory().NewSession(context.Background(), "localhost:161", Timeout(time.Second), Timeout(0))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultConfig.timeout, m.(*sessionImpl).config.Timeout(), "Expecting zero to select the default")
+}
+
+func TestSessionTraceNotShared(t *testing.T) {
+	hooks := &SessionTrace{}
+	m, err := NewFactory().NewSession(context.Background(), "localhost:161", LoggingHooks(hooks))
+	assert.NoError(t, err)
+	assert.Nil(t, hooks.Error, "Expecting the supplied hooks to be unchanged")
+	assert.NotNil(t, m.(*sessionImpl).config.trace.Error)
+
+	_, err = NewFactory().NewSession(context.Background(), "localhost:161")
+	assert.NoError(t, err)
+	assert.Nil(t, DefaultLoggingHooks.ConnectStart, "Expecting DefaultLoggingHooks to be unchanged")
+}
+
+func TestDefaultConfig(t *testing.T) {
+	m, err := NewFactory().NewSession(context.Background(), "localhost:161")
+	assert.NoError(t, err)
+	config := m.(*sessionImpl).config
+	assert.Equal(t, "localhost:161", config.Address())
+	assert.Equal(t, DefaultConfig.Network(), config.Network())
+	assert.Equal(t, SNMPV2C, config.Version())
+	assert.Equal(t, "public", config.Community())
+	assert.Equal(t, 5*time.Second, config.Timeout())
+	assert.Equal(t, 3, config.Retries())
+	assert.Equal(t, 65507, config.MaxMessageSize())
+}
+
+func TestMaxMessageSizeExceeded(t *testing.T) {
+	m, err := NewFactory().NewSession(context.Background(), "localhost:161", MaxMessageSize(484),
+		LoggingHooks(NoOpLoggingHooks))
+	assert.NoError(t, err)
+	defer m.Close()
+
+	oids := make([]string, 40)
+	for i := range oids {
+		oids[i] = "1.3.6.1.2.1.2.2.1.2.1000"
+	}
+	_, err = m.Get(context.Background(), oids)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds maximum message size 484")
 }
 
 func TestConnectionFailure(t *testing.T) {
@@ -42,3 +120,200 @@ func TestConnectionFailure(t *testing.T) {
 	assert.Error(t, err, "Expecting new session to fail - invalid port")
 	assert.Nil(t, m, "Session should be nil")
 }
+
+func TestNewSessionResolvesHostName(t *testing.T) {
+	f := NewFactory()
+	m, err := f.NewSession(context.Background(), "localhost:161", Resolver(&net.Resolver{}))
+	assert.NoError(t, err)
+	assert.NotNil(t, m, "Session should not be nil")
+	assert.Equal(t, 161, m.(*sessionImpl).conn.RemoteAddr().(*net.UDPAddr).Port, "Unexpected remote port")
+}
+
+func TestNewSessionResolutionCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	f := NewFactory()
+	m, err := f.NewSession(ctx, "localhost:161")
+	assert.ErrorIs(t, err, context.Canceled, "Expecting new session to fail - context cancelled")
+	assert.Nil(t, m, "Session should be nil")
+}
+
+func TestOrderAddrs(t *testing.T) {
+	v4 := net.IPAddr{IP: net.ParseIP("10.0.0.1")}
+	v6 := net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+	addrs := []net.IPAddr{v4, v6}
+
+	assert.Equal(t, []net.IPAddr{v6, v4}, orderAddrs("udp", addrs), "Expected IPv6 before IPv4")
+	assert.Equal(t, []net.IPAddr{v4}, orderAddrs("udp4", addrs), "Expected IPv4 only")
+	assert.Equal(t, []net.IPAddr{v6}, orderAddrs("udp6", addrs), "Expected IPv6 only")
+}
+
+func TestFallbackAddresses(t *testing.T) {
+	// The primary address accepts requests but never responds.
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer silent.Close()
+
+	agent := newTestAgentServer(t, testAgentObjects...)
+	fallback := agent.(*serverImpl).conn.LocalAddr().String()
+
+	var from []string
+	hooks := *NoOpLoggingHooks
+	hooks.FailedOver = func(config *SessionConfig, addr string, err error) {
+		from = append(from, addr)
+		assert.Equal(t, fallback, config.address)
+		assert.Error(t, err)
+	}
+
+	primary := silent.LocalAddr().String()
+	m, err := NewFactory().NewSession(context.Background(), primary, Timeout(50*time.Millisecond), Retries(1),
+		FallbackAddresses([]string{fallback}), LoggingHooks(&hooks))
+	assert.NoError(t, err)
+	defer m.Close()
+
+	for i := 0; i < 2; i++ {
+		pdu, err := m.Get(context.Background(), []string{sysDescr + ".0"})
+		assert.NoError(t, err)
+		assert.Equal(t, fallback, pdu.Address, "Expecting response from fallback address")
+	}
+	assert.Equal(t, []string{primary}, from, "Expecting a single failover")
+}
+
+func TestFallbackAddressesExhausted(t *testing.T) {
+	var addrs []string
+	for i := 0; i < 2; i++ {
+		silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer silent.Close()
+		addrs = append(addrs, silent.LocalAddr().String())
+	}
+
+	failovers := 0
+	hooks := *NoOpLoggingHooks
+	hooks.FailedOver = func(config *SessionConfig, from string, err error) { failovers++ }
+
+	m, err := NewFactory().NewSession(context.Background(), addrs[0], Timeout(20*time.Millisecond), Retries(0),
+		FallbackAddresses(addrs[1:]), LoggingHooks(&hooks))
+	assert.NoError(t, err)
+	defer m.Close()
+
+	_, err = m.Get(context.Background(), []string{sysDescr + ".0"})
+	assert.Error(t, err, "Expecting request to time out on every address")
+	assert.Equal(t, 1, failovers)
+
+	// The next request starts from the fallback address, and wraps around to the primary.
+	_, err = m.Get(context.Background(), []string{sysDescr + ".0"})
+	assert.Error(t, err)
+	assert.Equal(t, 2, failovers)
+	assert.Equal(t, addrs[0], m.(*sessionImpl).config.address)
+}
+
+func TestNewSessionFailsOverAcrossAddressFamilies(t *testing.T) {
+	agent := newTestAgentServer(t, testAgentObjects...)
+	port := strconv.Itoa(agent.(*serverImpl).conn.LocalAddr().(*net.UDPAddr).Port)
+
+	// The IPv6 address, which is used first, accepts requests but never responds.
+	silent, err := net.ListenPacket("udp6", net.JoinHostPort("::1", port))
+	if err != nil {
+		t.Skip("IPv6 loopback unavailable:", err)
+	}
+	defer silent.Close()
+
+	var from []string
+	hooks := *NoOpLoggingHooks
+	hooks.FailedOver = func(config *SessionConfig, addr string, err error) { from = append(from, addr) }
+
+	target := net.JoinHostPort("dualstack.example", port)
+	m, err := NewFactory().NewSession(context.Background(), target, Timeout(50*time.Millisecond), Retries(0),
+		Resolver(staticResolver(net.ParseIP("127.0.0.1"), net.ParseIP("::1"))), LoggingHooks(&hooks))
+	assert.NoError(t, err)
+	defer m.Close()
+
+	pdu, err := m.Get(context.Background(), []string{sysDescr + ".0"})
+	assert.NoError(t, err)
+	assert.Equal(t, net.JoinHostPort("127.0.0.1", port), pdu.Address, "Expecting response from the IPv4 address")
+	assert.Equal(t, []string{target}, from)
+}
+
+// Delivers a resolver that answers queries for any name with the addresses of the type queried.
+func staticResolver(addrs ...net.IP) *net.Resolver {
+	return &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		client, server := net.Pipe()
+		go serveDNS(server, addrs)
+		return client, nil
+	}}
+}
+
+// Answers the DNS queries read from the connection, which are framed as for tcp.
+func serveDNS(conn net.Conn, addrs []net.IP) {
+	defer conn.Close()
+	const typeAAAA = 28
+	for {
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		// The question follows the 12 byte header, and comprises the name, type and class.
+		end := 12
+		for query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+		qtype := binary.BigEndian.Uint16(query[end-4:])
+
+		response := append([]byte{}, query[:end]...)
+		response[2], response[3] = 0x81, 0x80
+		answers := 0
+		for _, ip := range addrs {
+			rdata := ip.To4()
+			if qtype == typeAAAA {
+				if rdata != nil {
+					continue
+				}
+				rdata = ip.To16()
+			} else if rdata == nil {
+				continue
+			}
+			answers++
+			response = append(response, 0xc0, 12, 0, byte(qtype), 0, 1, 0, 0, 0, 60, 0, byte(len(rdata)))
+			response = append(response, rdata...)
+		}
+		binary.BigEndian.PutUint16(response[6:], uint16(answers))
+		binary.BigEndian.PutUint32(response[8:], 0)
+
+		binary.BigEndian.PutUint16(length[:], uint16(len(response)))
+		if _, err := conn.Write(append(length[:], response...)); err != nil {
+			return
+		}
+	}
+}
+
+func TestNewSessionForTarget(t *testing.T) {
+	tgt := &target.Target{Address: "localhost", Ports: map[target.Protocol]int{target.SNMP: 1161}}
+	m, err := NewSessionForTarget(context.Background(), NewFactory(), tgt,
+		target.StaticCredentials(&target.Credentials{Community: "private"}), Retries(2))
+	assert.NoError(t, err)
+	impl := m.(*sessionImpl)
+	assert.Equal(t, "localhost:1161", impl.config.address)
+	assert.Equal(t, "private", impl.config.community)
+	assert.Equal(t, 2, impl.config.retries)
+
+	m, err = NewSessionForTarget(context.Background(), NewFactory(), tgt,
+		target.StaticCredentials(&target.Credentials{Community: "private"}), Community("override"))
+	assert.NoError(t, err)
+	assert.Equal(t, "override", m.(*sessionImpl).config.community)
+}
+
+func TestNewSessionForTargetResolveFailure(t *testing.T) {
+	resolver := target.CredentialResolverFunc(func(ctx context.Context, t *target.Target, p target.Protocol) (*target.Credentials, error) {
+		return nil, errors.New("vault sealed")
+	})
+	_, err := NewSessionForTarget(context.Background(), NewFactory(), &target.Target{Address: "localhost"}, resolver)
+	assert.EqualError(t, err, "failed to resolve snmp credentials for localhost: vault sealed")
+}
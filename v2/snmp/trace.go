@@ -1,7 +1,6 @@
 package snmp
 
 import (
-	"encoding/hex"
 	"log"
 	"time"
 )
@@ -18,12 +17,32 @@ type SessionTrace struct {
 	// Error is called after an error condition has been detected.
 	Error func(location string, config *SessionConfig, err error)
 
-	// WriteDone is called after a packet has been written
+	// WriteDone is called after a packet has been written.
+	// The output slice is only valid for the duration of the call, as the underlying buffer is reused.
 	WriteDone func(config *SessionConfig, output []byte, err error, d time.Duration)
 
-	// ReadDone is called after a read has completed
+	// ReadDone is called after a read has completed.
+	// The input slice is only valid for the duration of the call, as the underlying buffer is reused.
 	ReadDone func(config *SessionConfig, input []byte, err error, d time.Duration)
 
+	// FailedOver is called when the session has switched to a fallback address, following persistent timeouts
+	// from the address from. The config defines the address now in use.
+	FailedOver func(config *SessionConfig, from string, err error)
+
+	// ResponseDiscarded is called when a response is discarded because it does not answer the request being
+	// processed, for example a late response to a request that has since been retried.
+	ResponseDiscarded func(config *SessionConfig, pdu *PDU, reason DiscardReason)
+
+	// RequestDone is called when a Get, GetNext or GetBulk request completes, either successfully or having failed.
+	// The start time is that of the first write of the request, and carries a monotonic clock reading; d is the
+	// time from start until the response was decoded or the request failed, so includes any retries but excludes
+	// the time spent processing the response, for example by a walker.
+	RequestDone func(config *SessionConfig, pdu *PDU, err error, start time.Time, d time.Duration)
+
+	// WalkInconsistent is called when the variables walked from rootOid changed between a walk and its
+	// verification, with attempt identifying the walk attempt, from zero.
+	WalkInconsistent func(config *SessionConfig, rootOid string, attempt int)
+
 	// TODO Define other hooks
 }
 
@@ -46,6 +65,9 @@ var MetricLoggingHooks = &SessionTrace{
 	ReadDone: func(config *SessionConfig, input []byte, err error, d time.Duration) {
 		log.Printf("SNMP-ReadDone target:%s err:%v took:%dms\n", config.address, err, d.Milliseconds())
 	},
+	RequestDone: func(config *SessionConfig, pdu *PDU, err error, start time.Time, d time.Duration) {
+		log.Printf("SNMP-RequestDone target:%s err:%v took:%dus\n", config.address, err, d.Microseconds())
+	},
 }
 
 // DiagnosticLoggingHooks provides a set of hooks that log all events with all data.
@@ -56,18 +78,32 @@ var DiagnosticLoggingHooks = &SessionTrace{
 	ConnectDone: MetricLoggingHooks.ConnectDone,
 	Error:       DefaultLoggingHooks.Error,
 	WriteDone: func(config *SessionConfig, output []byte, err error, d time.Duration) {
-		log.Printf("SNMP-WriteDone target:%s err:%v took:%dms data:%s\n", config.address, err, d.Milliseconds(), hex.EncodeToString(output))
+		log.Printf("SNMP-WriteDone target:%s err:%v took:%dms data:\n%s\n", config.address, err, d.Milliseconds(), FormatBER(output))
 	},
 	ReadDone: func(config *SessionConfig, input []byte, err error, d time.Duration) {
-		log.Printf("SNMP-ReadDone target:%s err:%v took:%dms data:%s\n", config.address, err, d.Milliseconds(), hex.EncodeToString(input))
+		log.Printf("SNMP-ReadDone target:%s err:%v took:%dms data:\n%s\n", config.address, err, d.Milliseconds(), FormatBER(input))
+	},
+	FailedOver: func(config *SessionConfig, from string, err error) {
+		log.Printf("SNMP-FailedOver target:%s from:%s err:%v\n", config.address, from, err)
+	},
+	RequestDone: MetricLoggingHooks.RequestDone,
+	WalkInconsistent: func(config *SessionConfig, rootOid string, attempt int) {
+		log.Printf("SNMP-WalkInconsistent target:%s root:%s attempt:%d\n", config.address, rootOid, attempt)
+	},
+	ResponseDiscarded: func(config *SessionConfig, pdu *PDU, reason DiscardReason) {
+		log.Printf("SNMP-ResponseDiscarded target:%s request-id:%d reason:%s\n", config.address, pdu.RequestID, reason)
 	},
 }
 
 // NoOpLoggingHooks provides set of hooks that do nothing.
 var NoOpLoggingHooks = &SessionTrace{
-	ConnectStart: func(config *SessionConfig) {},
-	ConnectDone:  func(config *SessionConfig, err error, d time.Duration) {},
-	Error:        func(location string, config *SessionConfig, err error) {},
-	WriteDone:    func(config *SessionConfig, output []byte, err error, d time.Duration) {},
-	ReadDone:     func(config *SessionConfig, input []byte, err error, d time.Duration) {},
+	ConnectStart:      func(config *SessionConfig) {},
+	ConnectDone:       func(config *SessionConfig, err error, d time.Duration) {},
+	Error:             func(location string, config *SessionConfig, err error) {},
+	WriteDone:         func(config *SessionConfig, output []byte, err error, d time.Duration) {},
+	ReadDone:          func(config *SessionConfig, input []byte, err error, d time.Duration) {},
+	FailedOver:        func(config *SessionConfig, from string, err error) {},
+	ResponseDiscarded: func(config *SessionConfig, pdu *PDU, reason DiscardReason) {},
+	RequestDone:       func(config *SessionConfig, pdu *PDU, err error, start time.Time, d time.Duration) {},
+	WalkInconsistent:  func(config *SessionConfig, rootOid string, attempt int) {},
 }
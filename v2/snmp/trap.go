@@ -0,0 +1,176 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"net"
+
+	"github.com/geoffgarside/ber"
+	"github.com/pkg/errors"
+)
+
+// TrapHandler may be implemented by a server Handler that wishes to receive decoded trap data, rather than the
+// raw PDU. If the handler supplied to the server implements TrapHandler, NewTrap is invoked in place of NewMessage.
+type TrapHandler interface {
+	// NewTrap is called when a trap/inform message has been received.
+	// The same blocking constraints apply as for Handler.NewMessage.
+	NewTrap(trap *TrapData)
+}
+
+// TrapData defines the content of a received trap/inform message, with the standard fields extracted.
+// SNMPv1 traps are converted to the SNMPv2 format as described in https://tools.ietf.org/html/rfc3584#section-3.1,
+// so SysUpTime and SnmpTrapOID are always defined for valid messages.
+type TrapData struct {
+	// The SNMP version of the message.
+	Version Version
+	// The community string of the message.
+	Community string
+	// The address which originated the message.
+	SourceAddress net.Addr
+	// Indicates that the message is an inform, rather than a trap.
+	IsInform bool
+	// The request id of the message; zero for SNMPv1 traps.
+	RequestID int32
+	// The value of sysUpTime.0, in hundredths of a second.
+	SysUpTime uint32
+	// The value of snmpTrapOID.0, identifying the notification.
+	SnmpTrapOID asn1.ObjectIdentifier
+	// The enterprise of an SNMPv1 trap, or the value of snmpTrapEnterprise.0 if present in an SNMPv2 message.
+	Enterprise asn1.ObjectIdentifier
+	// The agent address of an SNMPv1 trap.
+	AgentAddress net.IP
+	// The generic and specific trap values of an SNMPv1 trap.
+	GenericTrap  int
+	SpecificTrap int
+	// The remaining variable bindings, excluding sysUpTime.0, snmpTrapOID.0 and snmpTrapEnterprise.0.
+	Varbinds []Varbind
+	// The MIB definitions resolved for the trap, if the server is configured with EnrichTraps; otherwise nil.
+	Enrichment *TrapEnrichment
+	// The number of identical traps suppressed after this one, reported when the window closes, if the server is
+	// configured with SuppressDuplicateTraps; otherwise zero.
+	Repeats int
+}
+
+// Standard trap related object identifiers.
+var (
+	SysUpTimeOID          = asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	SnmpTrapOIDOID        = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+	SnmpTrapEnterpriseOID = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 4, 3, 0}
+
+	ColdStartOID             = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 1}
+	WarmStartOID             = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 2}
+	LinkDownOID              = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 3}
+	LinkUpOID                = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 4}
+	AuthenticationFailureOID = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 5}
+	EgpNeighborLossOID       = asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 6}
+)
+
+// SNMPv1 generic trap values.
+const (
+	GenericColdStart = iota
+	GenericWarmStart
+	GenericLinkDown
+	GenericLinkUp
+	GenericAuthenticationFailure
+	GenericEgpNeighborLoss
+	GenericEnterpriseSpecific
+)
+
+// IsColdStart reports whether the trap is a coldStart notification.
+func (td *TrapData) IsColdStart() bool {
+	return td.SnmpTrapOID.Equal(ColdStartOID)
+}
+
+// IsWarmStart reports whether the trap is a warmStart notification.
+func (td *TrapData) IsWarmStart() bool {
+	return td.SnmpTrapOID.Equal(WarmStartOID)
+}
+
+// IsLinkDown reports whether the trap is a linkDown notification.
+func (td *TrapData) IsLinkDown() bool {
+	return td.SnmpTrapOID.Equal(LinkDownOID)
+}
+
+// IsLinkUp reports whether the trap is a linkUp notification.
+func (td *TrapData) IsLinkUp() bool {
+	return td.SnmpTrapOID.Equal(LinkUpOID)
+}
+
+// IsAuthenticationFailure reports whether the trap is an authenticationFailure notification.
+func (td *TrapData) IsAuthenticationFailure() bool {
+	return td.SnmpTrapOID.Equal(AuthenticationFailureOID)
+}
+
+// IsEgpNeighborLoss reports whether the trap is an egpNeighborLoss notification.
+func (td *TrapData) IsEgpNeighborLoss() bool {
+	return td.SnmpTrapOID.Equal(EgpNeighborLossOID)
+}
+
+// NewTrapData extracts the standard fields from a received trap/inform PDU.
+func NewTrapData(pdu *PDU, isInform bool, sourceAddr net.Addr) *TrapData {
+	td := &TrapData{IsInform: isInform, SourceAddress: sourceAddr, RequestID: pdu.RequestID, Version: SNMPV2C}
+	for i := range pdu.VarbindList {
+		vb := pdu.VarbindList[i]
+		switch {
+		case vb.OID.Equal(SysUpTimeOID) && vb.TypedValue.Type == Time:
+			td.SysUpTime = vb.TypedValue.Value.(uint32)
+		case vb.OID.Equal(SnmpTrapOIDOID) && vb.TypedValue.Type == OID:
+			td.SnmpTrapOID = vb.TypedValue.OID()
+		case vb.OID.Equal(SnmpTrapEnterpriseOID) && vb.TypedValue.Type == OID:
+			td.Enterprise = vb.TypedValue.OID()
+		default:
+			td.Varbinds = append(td.Varbinds, vb)
+		}
+	}
+	return td
+}
+
+// rawV1TrapPDU defines the SNMPv1 Trap-PDU, see https://tools.ietf.org/html/rfc1157#section-4.1.6.
+type rawV1TrapPDU struct {
+	Enterprise   asn1.ObjectIdentifier
+	AgentAddr    asn1.RawValue
+	GenericTrap  int
+	SpecificTrap int
+	Timestamp    asn1.RawValue
+	VarbindList  []rawVarbind
+}
+
+// Unmarshals an SNMPv1 Trap-PDU, whose message tag has been replaced by the ASN1 sequence tag.
+// The variable bindings are converted to the SNMPv2 format, as described in
+// https://tools.ietf.org/html/rfc3584#section-3.1.
+// Failures are located within the message by loc, if defined.
+func unmarshalV1Trap(input []byte, loc *decodeLocator) (*PDU, *rawV1TrapPDU, error) {
+	raw := &rawV1TrapPDU{}
+	if _, err := ber.Unmarshal(input, raw); err != nil {
+		return nil, nil, errors.Wrap(loc.fail("pdu", input, err), "failed to unmarshal v1 trap pdu")
+	}
+
+	timestamp, err := unmarshalVariable(&raw.Timestamp)
+	if err != nil || timestamp.Type != Time {
+		return nil, nil, errors.New("invalid v1 trap timestamp")
+	}
+
+	pdu, err := unmarshalValues(&rawPDU{VarbindList: raw.VarbindList}, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trapOID := v1TrapOID(raw)
+	pdu.VarbindList = append([]Varbind{
+		{OID: SysUpTimeOID, TypedValue: timestamp},
+		{OID: SnmpTrapOIDOID, TypedValue: &TypedValue{Type: OID, Value: trapOID}},
+	}, pdu.VarbindList...)
+	pdu.VarbindList = append(pdu.VarbindList,
+		Varbind{OID: SnmpTrapEnterpriseOID, TypedValue: &TypedValue{Type: OID, Value: raw.Enterprise}})
+	return pdu, raw, nil
+}
+
+// Delivers the snmpTrapOID corresponding to the generic and specific trap values of an SNMPv1 trap.
+func v1TrapOID(raw *rawV1TrapPDU) asn1.ObjectIdentifier {
+	if raw.GenericTrap >= GenericColdStart && raw.GenericTrap < GenericEnterpriseSpecific {
+		oid := append(asn1.ObjectIdentifier{}, ColdStartOID...)
+		oid[len(oid)-1] = raw.GenericTrap + 1
+		return oid
+	}
+	oid := append(asn1.ObjectIdentifier{}, raw.Enterprise...)
+	return append(oid, 0, raw.SpecificTrap)
+}
@@ -0,0 +1,239 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/golang/mock/gomock"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestNewTrapData(t *testing.T) {
+	pdu := &PDU{
+		RequestID: 42,
+		VarbindList: []Varbind{
+			{OID: SysUpTimeOID, TypedValue: &TypedValue{Type: Time, Value: uint32(12345)}},
+			{OID: SnmpTrapOIDOID, TypedValue: &TypedValue{Type: OID, Value: LinkDownOID}},
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 1, 3}, TypedValue: &TypedValue{Type: Integer, Value: int64(3)}},
+		},
+	}
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 162}
+
+	td := NewTrapData(pdu, true, addr)
+	assert.Equal(t, SNMPV2C, td.Version)
+	assert.True(t, td.IsInform)
+	assert.Equal(t, addr, td.SourceAddress)
+	assert.Equal(t, int32(42), td.RequestID)
+	assert.Equal(t, uint32(12345), td.SysUpTime)
+	assert.True(t, td.IsLinkDown())
+	assert.False(t, td.IsLinkUp())
+	assert.False(t, td.IsColdStart())
+	assert.Nil(t, td.Enterprise)
+	assert.Len(t, td.Varbinds, 1)
+	assert.Equal(t, "1.3.6.1.2.1.2.2.1.1.3", td.Varbinds[0].OID.String())
+}
+
+func TestGenericTrapHelpers(t *testing.T) {
+	tests := []struct {
+		oid   asn1.ObjectIdentifier
+		check func(td *TrapData) bool
+	}{
+		{ColdStartOID, (*TrapData).IsColdStart},
+		{WarmStartOID, (*TrapData).IsWarmStart},
+		{LinkDownOID, (*TrapData).IsLinkDown},
+		{LinkUpOID, (*TrapData).IsLinkUp},
+		{AuthenticationFailureOID, (*TrapData).IsAuthenticationFailure},
+		{EgpNeighborLossOID, (*TrapData).IsEgpNeighborLoss},
+	}
+	for _, tt := range tests {
+		for _, other := range tests {
+			td := &TrapData{SnmpTrapOID: other.oid}
+			assert.Equal(t, tt.oid.Equal(other.oid), tt.check(td), "%s vs %s", tt.oid, other.oid)
+		}
+	}
+}
+
+func TestHandleTrapData(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockPacketConn(mockCtrl)
+
+	trap := messageWithType(v2Trap)
+	mockConn.EXPECT().LocalAddr().Return(nil).AnyTimes()
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			copy(input, trap)
+			return len(trap), nil, nil
+		}).Times(1)
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			return 0, nil, errors.New("read failed")
+		}).MaxTimes(1)
+	mockConn.EXPECT().Close().Return(nil)
+
+	config := defaultServerConfig
+	config.trace = NoOpServerHooks
+	config.resolveServerHooks()
+	h := newTrapHandler()
+	h.wg.Add(1)
+	s := &serverImpl{config: &config, conn: mockConn, handler: h}
+	defer s.Close()
+
+	s.handleMessages()
+
+	h.wg.Wait()
+	assert.Nil(t, h.pdu, "NewMessage should not be called")
+	assert.Equal(t, SNMPV2C, h.trap.Version)
+	assert.Equal(t, public, h.trap.Community)
+	assert.False(t, h.trap.IsInform)
+	assert.Equal(t, int32(0x3dcda106), h.trap.RequestID)
+	assert.Equal(t, uint32(0x03017b89), h.trap.SysUpTime)
+	assert.Equal(t, "1.3.6.1.1.2.3", h.trap.SnmpTrapOID.String())
+	assert.Len(t, h.trap.Varbinds, 1)
+	assert.Equal(t, "123456", h.trap.Varbinds[0].TypedValue.String())
+}
+
+func TestHandleV1Trap(t *testing.T) {
+	tests := []struct {
+		name         string
+		generic      byte
+		specific     byte
+		expectedOID  string
+		expectedTrap func(td *TrapData) bool
+	}{
+		{"linkDown", GenericLinkDown, 0, "1.3.6.1.6.3.1.1.5.3", (*TrapData).IsLinkDown},
+		{"enterpriseSpecific", GenericEnterpriseSpecific, 17, "1.3.6.1.4.1.9.1.0.17", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockConn := mocks.NewMockPacketConn(mockCtrl)
+
+			trap := v1TrapMessage(tt.generic, tt.specific)
+			mockConn.EXPECT().LocalAddr().Return(nil).AnyTimes()
+			mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+				func(input []byte) (int, net.Addr, error) {
+					copy(input, trap)
+					return len(trap), nil, nil
+				}).Times(1)
+			mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+				func(input []byte) (int, net.Addr, error) {
+					return 0, nil, errors.New("read failed")
+				}).MaxTimes(1)
+			mockConn.EXPECT().Close().Return(nil)
+
+			config := defaultServerConfig
+			config.trace = NoOpServerHooks
+			config.resolveServerHooks()
+			h := newTrapHandler()
+			h.wg.Add(1)
+			s := &serverImpl{config: &config, conn: mockConn, handler: h}
+			defer s.Close()
+
+			s.handleMessages()
+
+			h.wg.Wait()
+			assert.Equal(t, SNMPV1, h.trap.Version)
+			assert.Equal(t, public, h.trap.Community)
+			assert.Equal(t, uint32(12345), h.trap.SysUpTime)
+			assert.Equal(t, tt.expectedOID, h.trap.SnmpTrapOID.String())
+			assert.Equal(t, "1.3.6.1.4.1.9.1", h.trap.Enterprise.String())
+			assert.Equal(t, "10.0.0.1", h.trap.AgentAddress.String())
+			assert.Equal(t, int(tt.generic), h.trap.GenericTrap)
+			assert.Equal(t, int(tt.specific), h.trap.SpecificTrap)
+			assert.Len(t, h.trap.Varbinds, 1)
+			assert.Equal(t, "1.3.6.1.7.8.9", h.trap.Varbinds[0].OID.String())
+			assert.Equal(t, "5", h.trap.Varbinds[0].TypedValue.String())
+			if tt.expectedTrap != nil {
+				assert.True(t, tt.expectedTrap(h.trap))
+			}
+		})
+	}
+}
+
+func TestHandleV1TrapAsMessage(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockPacketConn(mockCtrl)
+
+	trap := v1TrapMessage(GenericColdStart, 0)
+	mockConn.EXPECT().LocalAddr().Return(nil).AnyTimes()
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			copy(input, trap)
+			return len(trap), nil, nil
+		}).Times(1)
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			return 0, nil, errors.New("read failed")
+		}).MaxTimes(1)
+	mockConn.EXPECT().Close().Return(nil)
+
+	config := defaultServerConfig
+	config.trace = NoOpServerHooks
+	config.resolveServerHooks()
+	h := newHandler()
+	h.wg.Add(1)
+	s := &serverImpl{config: &config, conn: mockConn, handler: h}
+	defer s.Close()
+
+	s.handleMessages()
+
+	h.wg.Wait()
+	assert.Len(t, h.pdu.VarbindList, 4)
+	assert.Equal(t, SysUpTimeOID, h.pdu.VarbindList[0].OID)
+	assert.Equal(t, SnmpTrapOIDOID, h.pdu.VarbindList[1].OID)
+	assert.Equal(t, ColdStartOID.String(), h.pdu.VarbindList[1].TypedValue.String())
+	assert.Equal(t, SnmpTrapEnterpriseOID, h.pdu.VarbindList[3].OID)
+}
+
+func v1TrapMessage(generic, specific byte) []byte {
+	return []byte{
+		// Message Type = Sequence, Length = 53
+		0x30, 0x35,
+		// Version Type = Integer, Length = 1, Value = 0
+		0x02, 0x01, 0x00,
+		// Community String Type = Octet String, Length = 6, Value = public
+		0x04, 0x06, 0x70, 0x75, 0x62, 0x6c, 0x69, 0x63,
+		// PDU Type = Trap, Length = 40
+		0xa4, 0x28,
+		// Enterprise Type = Object Identifier, Length = 7, Value = 1.3.6.1.4.1.9.1
+		0x06, 0x07, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x09, 0x01,
+		// Agent Address Type = IpAddress, Length = 4, Value = 10.0.0.1
+		0x40, 0x04, 0x0a, 0x00, 0x00, 0x01,
+		// Generic Trap Type = Integer, Length = 1
+		0x02, 0x01, generic,
+		// Specific Trap Type = Integer, Length = 1
+		0x02, 0x01, specific,
+		// Timestamp Type = Time, Length = 2, Value = 12345
+		0x43, 0x02, 0x30, 0x39,
+		// Varbind List Type = Sequence, Length = 13
+		0x30, 0x0d,
+		// Varbind Type = Sequence, Length = 11
+		0x30, 0x0b,
+		// Object Identifier Type = Object Identifier, Length = 6, Value = 1.3.6.1.7.8.9
+		0x06, 0x06, 0x2b, 0x06, 0x01, 0x07, 0x08, 0x09,
+		// Value Type = Integer, Length = 1, Value = 5
+		0x02, 0x01, 0x05,
+	}
+}
+
+type trapHandler struct {
+	handler
+	trap *TrapData
+}
+
+func newTrapHandler() *trapHandler {
+	return &trapHandler{handler: handler{wg: &sync.WaitGroup{}}}
+}
+
+func (h *trapHandler) NewTrap(trap *TrapData) {
+	h.trap = trap
+	h.wg.Done()
+}
@@ -0,0 +1,129 @@
+package snmp
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SuppressDuplicateTraps collapses identical traps, having the same source address, snmpTrapOID.0 value and variable
+// bindings (other than sysUpTime.0), that are received within window of the first, so that a storm of traps from
+// a flapping interface invokes the handler at most twice per window.
+// The first trap is delivered on receipt, and the identical traps that follow it within the window are suppressed.
+// If any were suppressed, the first trap is delivered again when the window closes, from a separate goroutine, with
+// TrapData.Repeats holding the number of identical traps suppressed; handlers that do not implement TrapHandler
+// receive only the first message. Informs are acknowledged on receipt, as usual.
+// Open windows are closed without delay when the server stops listening.
+// Default is no suppression.
+func SuppressDuplicateTraps(window time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		if window < 0 {
+			c.err = errors.Errorf("invalid duplicate trap window %s", window)
+			return
+		}
+		c.dedupWindow = window
+	}
+}
+
+// Counts the identical traps suppressed within the window opened by the first.
+type heldTrap struct {
+	timer   *time.Timer
+	report  func(repeats int)
+	repeats int
+}
+
+// Collapses identical traps received within the window.
+type trapDeduplicator struct {
+	window time.Duration
+	mu     sync.Mutex
+	held   map[string]*heldTrap
+	// Counts the windows that have not yet been reported, including those being reported by their timers.
+	open sync.WaitGroup
+}
+
+func newTrapDeduplicator(window time.Duration) *trapDeduplicator {
+	return &trapDeduplicator{window: window, held: map[string]*heldTrap{}}
+}
+
+// Opens a window for a trap identified by key, reporting whether the trap is the first of its window and so
+// should be delivered. Otherwise, the trap is suppressed, and report is invoked with the number of suppressed
+// traps when the window closes.
+func (d *trapDeduplicator) add(key string, report func(repeats int)) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if h, ok := d.held[key]; ok {
+		h.repeats++
+		return false
+	}
+	h := &heldTrap{report: report}
+	d.open.Add(1)
+	h.timer = time.AfterFunc(d.window, func() {
+		if d.release(key, h) {
+			h.close()
+			d.open.Done()
+		}
+	})
+	d.held[key] = h
+	return true
+}
+
+// Reports the suppressed traps, if any.
+func (h *heldTrap) close() {
+	if h.repeats > 0 {
+		h.report(h.repeats)
+	}
+}
+
+// Releases the held trap, reporting whether it was still held.
+func (d *trapDeduplicator) release(key string, h *heldTrap) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.held[key] != h {
+		return false
+	}
+	delete(d.held, key)
+	return true
+}
+
+// Closes all the open windows, returning once the windows closed by their timers have also been reported.
+// Traps must not be added while flushing.
+func (d *trapDeduplicator) flush() {
+	d.mu.Lock()
+	held := d.held
+	d.held = map[string]*heldTrap{}
+	d.mu.Unlock()
+
+	// Traps removed from the map are no longer delivered by their timers.
+	for _, h := range held {
+		h.timer.Stop()
+		h.close()
+		d.open.Done()
+	}
+	d.open.Wait()
+}
+
+// Delivers the key identifying identical traps.
+func trapKey(td *TrapData) string {
+	sb := &strings.Builder{}
+	switch addr := td.SourceAddress.(type) {
+	case *net.UDPAddr:
+		// Ignore the source port, which may differ between traps from the same agent.
+		sb.WriteString(addr.IP.String())
+	case nil:
+	default:
+		sb.WriteString(addr.String())
+	}
+	sb.WriteString("|" + td.SnmpTrapOID.String())
+	for i := range td.Varbinds {
+		vb := &td.Varbinds[i]
+		sb.WriteString("|" + vb.OID.String() + "=")
+		if vb.TypedValue != nil {
+			sb.WriteString(strconv.Itoa(int(vb.TypedValue.Type)) + ":" + vb.TypedValue.String())
+		}
+	}
+	return sb.String()
+}
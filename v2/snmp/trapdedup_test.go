@@ -0,0 +1,133 @@
+package snmp
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+)
+
+// Collects the traps delivered to the handler.
+type collectingTrapHandler struct {
+	mu    sync.Mutex
+	traps []*TrapData
+	pdus  []*PDU
+}
+
+func (h *collectingTrapHandler) NewTrap(trap *TrapData) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.traps = append(h.traps, trap)
+}
+
+func (h *collectingTrapHandler) NewMessage(pdu *PDU, isInform bool, addr net.Addr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pdus = append(h.pdus, pdu)
+}
+
+// Delivers a server reading the messages from a mock connection, then failing once release is closed.
+func newServerWithMessages(t *testing.T, config *serverConfig, h Handler, release chan struct{},
+	messages ...[]byte) *serverImpl {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	mockConn := mocks.NewMockPacketConn(mockCtrl)
+	mockConn.EXPECT().LocalAddr().Return(nil).AnyTimes()
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}
+	for _, m := range messages {
+		message := m
+		mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+			func(input []byte) (int, net.Addr, error) {
+				copy(input, message)
+				// Agents may send traps from varying source ports.
+				addr.Port++
+				return len(message), &net.UDPAddr{IP: addr.IP, Port: addr.Port}, nil
+			})
+	}
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			<-release
+			return 0, nil, errors.New("read failed")
+		})
+
+	config.trace = NoOpServerHooks
+	config.resolveServerHooks()
+	return &serverImpl{config: config, conn: mockConn, handler: h}
+}
+
+func TestSuppressDuplicateTraps(t *testing.T) {
+	repeated := messageWithType(v2Trap)
+	laterUpTime := messageWithType(v2Trap)
+	laterUpTime[46]++
+	other := messageWithType(v2Trap)
+	other[len(other)-1]++
+
+	config := defaultServerConfig
+	SuppressDuplicateTraps(time.Hour)(&config)
+	h := &collectingTrapHandler{}
+	release := make(chan struct{})
+	close(release)
+	s := newServerWithMessages(t, &config, h, release, repeated, laterUpTime, other, repeated)
+
+	// The first traps are delivered on receipt, and the repeats reported when the server stops listening.
+	assert.Error(t, s.serve(nil))
+	assert.Len(t, h.traps, 3)
+	assert.Nil(t, h.pdus)
+	assert.Equal(t, "123456", h.traps[0].Varbinds[0].TypedValue.String())
+	assert.Equal(t, 0, h.traps[0].Repeats)
+	assert.Equal(t, "123457", h.traps[1].Varbinds[0].TypedValue.String())
+	assert.Equal(t, 0, h.traps[1].Repeats)
+	assert.Equal(t, "123456", h.traps[2].Varbinds[0].TypedValue.String())
+	assert.Equal(t, 2, h.traps[2].Repeats)
+	assert.Equal(t, uint32(0x03017b89), h.traps[2].SysUpTime, "Expecting the first trap to be reported")
+}
+
+func TestSuppressDuplicateTrapsWindowExpiry(t *testing.T) {
+	config := defaultServerConfig
+	SuppressDuplicateTraps(10 * time.Millisecond)(&config)
+	h := &collectingTrapHandler{}
+	release := make(chan struct{})
+	s := newServerWithMessages(t, &config, h, release, messageWithType(v2Trap), messageWithType(v2Trap))
+
+	done := make(chan error)
+	go func() {
+		done <- s.serve(nil)
+	}()
+	assert.Eventually(t, func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return len(h.traps) == 2
+	}, time.Second, time.Millisecond, "Expecting the repeats to be reported when the window expires")
+	close(release)
+	assert.Error(t, <-done)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	assert.Len(t, h.traps, 2)
+	assert.Equal(t, 0, h.traps[0].Repeats)
+	assert.Equal(t, 1, h.traps[1].Repeats)
+}
+
+func TestSuppressDuplicateMessages(t *testing.T) {
+	config := defaultServerConfig
+	SuppressDuplicateTraps(time.Hour)(&config)
+	h := newHandler()
+	h.wg.Add(1)
+	release := make(chan struct{})
+	close(release)
+	s := newServerWithMessages(t, &config, h, release, messageWithType(v2Trap), messageWithType(v2Trap))
+
+	assert.Error(t, s.serve(nil))
+	h.wg.Wait()
+	assert.NotNil(t, h.pdu, "Expecting a single message to be delivered")
+}
+
+func TestSuppressDuplicateTrapsInvalidWindow(t *testing.T) {
+	_, err := newServerConfig([]ServerOption{SuppressDuplicateTraps(-time.Second)})
+	assert.EqualError(t, err, "invalid duplicate trap window -1s")
+}
@@ -0,0 +1,140 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"strconv"
+)
+
+// Enrichment of received traps with MIB definitions, so that handlers receive names, enumerated value labels and
+// severities without looking them up.
+//
+// Definitions are supplied by a MIBResolver. MIBDefinitions is an in-memory resolver that may be populated from
+// MIB module files by mibgen.Definitions, or directly by the application.
+
+// ObjectDefinition defines the MIB definition of an object or notification.
+type ObjectDefinition struct {
+	// The object identifier of the definition.
+	OID asn1.ObjectIdentifier
+	// The qualified name of the definition, for example "IF-MIB::ifOperStatus".
+	Name string
+	// The labels of enumerated integer values, for example {1: "up", 2: "down"}.
+	Enums map[int]string
+	// The severity of a notification, as annotated in the MIB, for example "major".
+	Severity string
+}
+
+// MIBResolver resolves the MIB definitions of object identifiers.
+type MIBResolver interface {
+	// Lookup delivers the definition whose object identifier is the longest prefix of (or equal to) oid, along with
+	// the remaining arcs of oid (the instance index), and whether a definition was found.
+	Lookup(oid asn1.ObjectIdentifier) (def *ObjectDefinition, index asn1.ObjectIdentifier, ok bool)
+}
+
+// MIBDefinitions is a MIBResolver holding definitions in memory.
+// It is not safe for concurrent modification, so should be fully populated before being supplied to a server.
+type MIBDefinitions struct {
+	tree *OIDTree
+}
+
+// NewMIBDefinitions delivers a resolver holding the definitions.
+func NewMIBDefinitions(defs ...ObjectDefinition) *MIBDefinitions {
+	m := &MIBDefinitions{tree: NewOIDTree()}
+	m.Add(defs...)
+	return m
+}
+
+// Add adds the definitions, replacing any existing definitions with the same object identifiers.
+func (m *MIBDefinitions) Add(defs ...ObjectDefinition) {
+	for i := range defs {
+		def := defs[i]
+		m.tree.Insert(def.OID, &def)
+	}
+}
+
+// Lookup implements MIBResolver.
+func (m *MIBDefinitions) Lookup(oid asn1.ObjectIdentifier) (*ObjectDefinition, asn1.ObjectIdentifier, bool) {
+	prefix, value, ok := m.tree.LongestPrefix(oid)
+	if !ok {
+		return nil, nil, false
+	}
+	return value.(*ObjectDefinition), oid[len(prefix):], true
+}
+
+// StandardTrapDefinitions defines the generic notifications of SNMPv2-MIB and IF-MIB, and the interface objects
+// they carry.
+var StandardTrapDefinitions = []ObjectDefinition{
+	{OID: ColdStartOID, Name: "SNMPv2-MIB::coldStart", Severity: "warning"},
+	{OID: WarmStartOID, Name: "SNMPv2-MIB::warmStart", Severity: "warning"},
+	{OID: LinkDownOID, Name: "IF-MIB::linkDown", Severity: "major"},
+	{OID: LinkUpOID, Name: "IF-MIB::linkUp", Severity: "cleared"},
+	{OID: AuthenticationFailureOID, Name: "SNMPv2-MIB::authenticationFailure", Severity: "minor"},
+	{OID: EgpNeighborLossOID, Name: "RFC1213-MIB::egpNeighborLoss", Severity: "major"},
+	{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 1}, Name: "IF-MIB::ifIndex"},
+	{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 2}, Name: "IF-MIB::ifDescr"},
+	{
+		OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 7}, Name: "IF-MIB::ifAdminStatus",
+		Enums: map[int]string{1: "up", 2: "down", 3: "testing"},
+	},
+	{
+		OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 8}, Name: "IF-MIB::ifOperStatus",
+		Enums: map[int]string{1: "up", 2: "down", 3: "testing", 4: "unknown", 5: "dormant", 6: "notPresent", 7: "lowerLayerDown"},
+	},
+}
+
+// EnrichTraps enriches each trap delivered to a TrapHandler with the definitions resolved by r, available from
+// TrapData.Enrichment. Handlers that do not implement TrapHandler are unaffected.
+// Default is no enrichment.
+func EnrichTraps(r MIBResolver) ServerOption {
+	return func(c *serverConfig) {
+		c.mibs = r
+	}
+}
+
+// TrapEnrichment defines the MIB definitions resolved for a trap.
+type TrapEnrichment struct {
+	// The name of the notification, or the dotted snmpTrapOID if it is not resolved.
+	Name string
+	// The severity of the notification; empty if not annotated.
+	Severity string
+	// The resolved variable bindings, corresponding to TrapData.Varbinds.
+	Varbinds []EnrichedVarbind
+}
+
+// EnrichedVarbind defines the name and value of a variable binding, resolved using MIB definitions.
+type EnrichedVarbind struct {
+	// The name of the object with the instance index, for example "IF-MIB::ifOperStatus.3", or the dotted oid if it
+	// is not resolved.
+	Name string
+	// The value formatted without type, with enumerated values labelled as by snmpwalk, for example "down(2)".
+	Value string
+}
+
+// Enrich delivers the enrichment of the trap using the definitions resolved by r.
+func (td *TrapData) Enrich(r MIBResolver) *TrapEnrichment {
+	e := &TrapEnrichment{Name: td.SnmpTrapOID.String(), Varbinds: make([]EnrichedVarbind, len(td.Varbinds))}
+	if def, index, ok := r.Lookup(td.SnmpTrapOID); ok && len(index) == 0 {
+		e.Name, e.Severity = def.Name, def.Severity
+	}
+	for i := range td.Varbinds {
+		e.Varbinds[i] = enrichVarbind(r, &td.Varbinds[i])
+	}
+	return e
+}
+
+func enrichVarbind(r MIBResolver, vb *Varbind) EnrichedVarbind {
+	ev := EnrichedVarbind{Name: vb.OID.String(), Value: Format(vb.TypedValue, OmitType())}
+	def, index, ok := r.Lookup(vb.OID)
+	if !ok {
+		return ev
+	}
+	ev.Name = def.Name
+	if len(index) > 0 {
+		ev.Name += "." + index.String()
+	}
+	if vb.TypedValue.Type == Integer {
+		if label, ok := def.Enums[vb.TypedValue.Int()]; ok {
+			ev.Value = label + "(" + strconv.Itoa(vb.TypedValue.Int()) + ")"
+		}
+	}
+	return ev
+}
@@ -0,0 +1,101 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/golang/mock/gomock"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestMIBDefinitionsLookup(t *testing.T) {
+	m := NewMIBDefinitions(StandardTrapDefinitions...)
+
+	def, index, ok := m.Lookup(asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 8, 3})
+	assert.True(t, ok)
+	assert.Equal(t, "IF-MIB::ifOperStatus", def.Name)
+	assert.Equal(t, "3", index.String())
+
+	def, index, ok = m.Lookup(LinkDownOID)
+	assert.True(t, ok)
+	assert.Equal(t, "major", def.Severity)
+	assert.Empty(t, index)
+
+	_, _, ok = m.Lookup(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1})
+	assert.False(t, ok)
+
+	m.Add(ObjectDefinition{OID: LinkDownOID, Name: "IF-MIB::linkDown", Severity: "critical"})
+	def, _, _ = m.Lookup(LinkDownOID)
+	assert.Equal(t, "critical", def.Severity, "Expecting definition to be replaced")
+}
+
+func TestTrapEnrich(t *testing.T) {
+	td := &TrapData{
+		SnmpTrapOID: LinkDownOID,
+		Varbinds: []Varbind{
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 1, 3}, TypedValue: &TypedValue{Type: Integer, Value: int64(3)}},
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 7, 3}, TypedValue: &TypedValue{Type: Integer, Value: int64(1)}},
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 8, 3}, TypedValue: &TypedValue{Type: Integer, Value: int64(9)}},
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 3}, TypedValue: &TypedValue{Type: OctetString, Value: []byte("eth0")}},
+			{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99, 1}, TypedValue: &TypedValue{Type: Gauge32, Value: uint32(7)}},
+		},
+	}
+
+	e := td.Enrich(NewMIBDefinitions(StandardTrapDefinitions...))
+	assert.Equal(t, "IF-MIB::linkDown", e.Name)
+	assert.Equal(t, "major", e.Severity)
+	assert.Equal(t, []EnrichedVarbind{
+		{Name: "IF-MIB::ifIndex.3", Value: "3"},
+		{Name: "IF-MIB::ifAdminStatus.3", Value: "up(1)"},
+		{Name: "IF-MIB::ifOperStatus.3", Value: "9"},
+		{Name: "IF-MIB::ifDescr.3", Value: `"eth0"`},
+		{Name: "1.3.6.1.4.1.99.1", Value: "7"},
+	}, e.Varbinds)
+
+	e = (&TrapData{SnmpTrapOID: asn1.ObjectIdentifier{1, 3, 6, 1, 6, 3, 1, 1, 5, 3, 1}}).Enrich(NewMIBDefinitions(StandardTrapDefinitions...))
+	assert.Equal(t, "1.3.6.1.6.3.1.1.5.3.1", e.Name, "Expecting only exact notification matches to be resolved")
+	assert.Empty(t, e.Severity)
+}
+
+func TestHandleEnrichedTrap(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockPacketConn(mockCtrl)
+
+	trap := messageWithType(v2Trap)
+	mockConn.EXPECT().LocalAddr().Return(nil).AnyTimes()
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			copy(input, trap)
+			return len(trap), nil, nil
+		}).Times(1)
+	mockConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(
+		func(input []byte) (int, net.Addr, error) {
+			return 0, nil, errors.New("read failed")
+		}).MaxTimes(1)
+	mockConn.EXPECT().Close().Return(nil)
+
+	config := defaultServerConfig
+	config.trace = NoOpServerHooks
+	config.resolveServerHooks()
+	EnrichTraps(NewMIBDefinitions(
+		ObjectDefinition{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 1, 2, 3}, Name: "TEST-MIB::testTrap", Severity: "minor"},
+		ObjectDefinition{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 7, 8}, Name: "TEST-MIB::testObject", Enums: map[int]string{123456: "max"}},
+	))(&config)
+	h := newTrapHandler()
+	h.wg.Add(1)
+	s := &serverImpl{config: &config, conn: mockConn, handler: h}
+	defer s.Close()
+
+	s.handleMessages()
+
+	h.wg.Wait()
+	assert.NotNil(t, h.trap.Enrichment)
+	assert.Equal(t, "TEST-MIB::testTrap", h.trap.Enrichment.Name)
+	assert.Equal(t, "minor", h.trap.Enrichment.Severity)
+	assert.Equal(t, []EnrichedVarbind{{Name: "TEST-MIB::testObject.9", Value: "max(123456)"}}, h.trap.Enrichment.Varbinds)
+}
@@ -0,0 +1,101 @@
+package snmp
+
+import (
+	"encoding/asn1"
+
+	"github.com/geoffgarside/ber"
+)
+
+// Recovery of the variable bindings from responses that agents have truncated, typically when the response reaches
+// the agent's maximum message size, which leave the final variable binding incomplete.
+
+// Salvages the complete variable bindings from a response whose encoding ends before the length declared by its
+// message header. Returns false if the response is otherwise malformed.
+func salvageResponse(input []byte) (*PDU, bool) {
+	msg, truncated, ok := berContent(input, asn1.TagSequence|compoundTag)
+	if !ok || !truncated {
+		return nil, false
+	}
+
+	// Skip the version and community.
+	for i := 0; i < 2; i++ {
+		if msg, ok = skipElement(msg); !ok {
+			return nil, false
+		}
+	}
+
+	body, _, ok := berContent(msg, getResponse)
+	if !ok {
+		return nil, false
+	}
+	var fields [3]int64 // request-id, error-status, error-index
+	for i := range fields {
+		if body, ok = integerElement(body, &fields[i]); !ok {
+			return nil, false
+		}
+	}
+	pdu := &PDU{RequestID: int32(fields[0]), Error: int(fields[1]), ErrorIndex: int(fields[2]), Truncated: true}
+
+	vbl, _, ok := berContent(body, asn1.TagSequence|compoundTag)
+	if !ok {
+		return nil, false
+	}
+	for len(vbl) > 0 {
+		length, hdr, ok := berLength(vbl[1:])
+		if !ok || 1+hdr+length > len(vbl) {
+			break
+		}
+		var raw rawVarbind
+		if _, err := ber.Unmarshal(vbl[:1+hdr+length], &raw); err != nil {
+			break
+		}
+		value, err := unmarshalVariable(&raw.Value)
+		if err != nil {
+			break
+		}
+		pdu.VarbindList = append(pdu.VarbindList, Varbind{OID: raw.OID, TypedValue: value})
+		vbl = vbl[1+hdr+length:]
+	}
+	return pdu, true
+}
+
+// Delivers the content of the element with the tag at the start of b, which is truncated if b ends before the
+// declared length of the element.
+func berContent(b []byte, tag byte) (content []byte, truncated, ok bool) {
+	if len(b) == 0 || b[0] != tag {
+		return nil, false, false
+	}
+	length, hdr, ok := berLength(b[1:])
+	if !ok {
+		return nil, false, false
+	}
+	content = b[1+hdr:]
+	if length > len(content) {
+		return content, true, true
+	}
+	return content[:length], false, true
+}
+
+// Delivers the octets that follow the complete element at the start of b.
+func skipElement(b []byte) ([]byte, bool) {
+	if len(b) == 0 {
+		return nil, false
+	}
+	length, hdr, ok := berLength(b[1:])
+	if !ok || 1+hdr+length > len(b) {
+		return nil, false
+	}
+	return b[1+hdr+length:], true
+}
+
+// Unmarshals the complete integer element at the start of b into v, delivering the octets that follow it.
+func integerElement(b []byte, v *int64) ([]byte, bool) {
+	rest, ok := skipElement(b)
+	if !ok || b[0] != asn1.TagInteger {
+		return nil, false
+	}
+	if _, err := ber.Unmarshal(b[:len(b)-len(rest)], v); err != nil {
+		return nil, false
+	}
+	return rest, true
+}
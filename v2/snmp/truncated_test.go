@@ -0,0 +1,121 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"testing"
+
+	"github.com/damianoneill/net/v2/snmp/mocks"
+	"github.com/geoffgarside/ber"
+	"github.com/golang/mock/gomock"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Delivers an encoded response to request id, with a string variable binding for each oid.
+func testResponse(t *testing.T, id int32, oids ...string) []byte {
+	response := rawPDU{RequestID: id}
+	for _, oid := range oids {
+		o, err := parseOID(oid)
+		assert.NoError(t, err)
+		vb, err := valueVarbind(o, &TypedValue{Type: OctetString, Value: []byte("value of " + oid)})
+		assert.NoError(t, err)
+		response.VarbindList = append(response.VarbindList, vb)
+	}
+	b, err := ber.Marshal(response)
+	assert.NoError(t, err)
+	b[0] = getResponse
+	resp, err := ber.Marshal(packet{Version: SNMPV2C, Community: []byte(public), RawPdu: asn1.RawValue{FullBytes: b}})
+	assert.NoError(t, err)
+	return resp
+}
+
+func TestSalvageTruncatedResponse(t *testing.T) {
+	m := &sessionImpl{config: &SessionConfig{trace: NoOpLoggingHooks}}
+
+	full := testResponse(t, 7, "1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.1.6.0")
+	pdu, err := m.parseResponse(full)
+	assert.NoError(t, err)
+	assert.False(t, pdu.Truncated)
+	assert.Len(t, pdu.VarbindList, 3)
+
+	truncated := testResponse(t, 7, "1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.1.6.0")
+	pdu, err = m.parseResponse(truncated[:len(truncated)-5])
+	assert.NoError(t, err, "Expecting truncated response to be salvaged")
+	assert.True(t, pdu.Truncated)
+	assert.Equal(t, int32(7), pdu.RequestID)
+	assert.Len(t, pdu.VarbindList, 2, "Expecting only the complete variable bindings")
+	assert.Equal(t, "1.3.6.1.2.1.1.5.0", pdu.VarbindList[1].OID.String())
+	assert.Equal(t, "value of 1.3.6.1.2.1.1.5.0", pdu.VarbindList[1].TypedValue.String())
+
+	// Truncation within the pdu header cannot be salvaged.
+	header := testResponse(t, 7, "1.3.6.1.2.1.1.4.0")
+	_, err = m.parseResponse(header[:20])
+	assert.Error(t, err)
+
+	// Malformed responses that are not truncated are rejected.
+	_, err = m.parseResponse([]byte{0xff, 0xff, 0xff})
+	assert.Error(t, err)
+}
+
+func TestBulkWalkTruncatedResponse(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockConn := mocks.NewMockConn(mockCtrl)
+
+	var requests [][]byte
+	responses := [][]byte{
+		// The first variable binding is truncated, so nothing can be salvaged.
+		testResponse(t, 1, "1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0"),
+		// The second variable binding is truncated.
+		testResponse(t, 2, "1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0"),
+		testResponse(t, 3, "1.3.6.1.2.1.1.5.0", "1.3.6.1.2.1.2.1.0"),
+	}
+	responses[0] = responses[0][:30]
+	responses[1] = responses[1][:len(responses[1])-5]
+	for _, response := range responses {
+		response := response
+		gomock.InOrder(
+			mockConn.EXPECT().SetDeadline(gomock.Any()).Return(nil),
+			mockConn.EXPECT().Write(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				requests = append(requests, append([]byte{}, b...))
+				return len(b), nil
+			}),
+			mockConn.EXPECT().Read(gomock.Any()).DoAndReturn(func(input []byte) (int, error) {
+				return copy(input, response), nil
+			}),
+		)
+	}
+
+	config := DefaultConfig
+	config.address = localhost161
+	config.community = public
+	config.trace = NoOpLoggingHooks
+	m := &sessionImpl{config: &config, conn: mockConn, nextRequestID: 1}
+
+	var oids []string
+	err := m.BulkWalk(context.Background(), "1.3.6.1.2.1.1", 4, func(vb *Varbind) error {
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1.3.6.1.2.1.1.4.0", "1.3.6.1.2.1.1.5.0"}, oids)
+
+	// The walk requests fewer repetitions after the unsalvageable response, and continues from the last good oid.
+	assert.Len(t, requests, 3)
+	for i, expected := range []struct {
+		oid            string
+		maxRepetitions int
+	}{{"1.3.6.1.2.1.1", 4}, {"1.3.6.1.2.1.1", 2}, {"1.3.6.1.2.1.1.4.0", 2}} {
+		pkt := &packet{}
+		_, err = ber.Unmarshal(requests[i], pkt)
+		assert.NoError(t, err)
+		pkt.RawPdu.FullBytes[0] = 0x30
+		pdu := &rawPDU{}
+		_, err = ber.Unmarshal(pkt.RawPdu.FullBytes, pdu)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.oid, pdu.VarbindList[0].OID.String(), fmt.Sprintf("request %d", i))
+		assert.Equal(t, expected.maxRepetitions, pdu.ErrorIndex, fmt.Sprintf("request %d", i))
+	}
+}
@@ -58,6 +58,16 @@ const (
 	EndOfMib
 	NoSuchObject
 	NoSuchInstance
+
+	// Types embedded within Opaque values; Float and Double values are float64, Integer64 values are int64 and
+	// Unsigned64 values are uint64.
+	Float
+	Double
+	Integer64
+	Unsigned64
+
+	// Null values are returned by agents in the variable bindings of responses that report an error-status.
+	Null
 )
 
 // Unmarshals an asn1 RawValue contqining a single variable to deliver a TypedValue that encapsulates the variable type
@@ -73,6 +83,8 @@ func unmarshalVariable(raw *asn1.RawValue) (*TypedValue, error) {
 			return unmarshalOctetString(raw, OctetString)
 		case asn1.TagOID:
 			return unmarshalOID(raw)
+		case asn1.TagNull:
+			return &TypedValue{Type: Null}, nil
 		}
 
 	case asn1.ClassApplication:
@@ -88,7 +100,7 @@ func unmarshalVariable(raw *asn1.RawValue) (*TypedValue, error) {
 		case resolvedTimeTag:
 			return unmarshalInteger(raw, Time)
 		case resolvedOpaqueTag:
-			return unmarshalOctetString(raw, Opaque)
+			return unmarshalOpaque(raw)
 		}
 	case asn1.ClassContextSpecific:
 		switch raw.Tag {
@@ -133,10 +145,13 @@ func unmarshalOctetString(raw *asn1.RawValue, dataType DataType) (*TypedValue, e
 	value := &TypedValue{Type: dataType, Value: []byte{}}
 	// Replace SNMP-tag with the generic OctetString tag, so ASN1 unmarshalling works.
 	raw.FullBytes[0] = asn1.TagOctetString
-	_, err := ber.Unmarshal(raw.FullBytes, &value.Value)
+	var octets []byte
+	_, err := ber.Unmarshal(raw.FullBytes, &octets)
 	if err != nil {
 		return nil, err
 	}
+	// Copy the octets, as the unmarshalled value shares memory with the (pooled) input buffer.
+	value.Value = append([]byte{}, octets...)
 	return value, nil
 }
 
@@ -167,8 +182,7 @@ func (tv *TypedValue) String() string {
 	case OID:
 		return tv.Value.(asn1.ObjectIdentifier).String()
 	case Time:
-		t := int64(tv.Value.(uint32)) * 10000
-		return time.Duration(t).String()
+		return formatTicks(tv.Value.(uint32))
 	case Counter32, Gauge32:
 		return strconv.FormatInt(int64(tv.Value.(uint32)), base10)
 	case Counter64:
@@ -182,6 +196,14 @@ func (tv *TypedValue) String() string {
 		return strings.Join(str, ".")
 	case Opaque:
 		return hex.EncodeToString(tv.Value.([]uint8))
+	case Float:
+		return strconv.FormatFloat(tv.Value.(float64), 'g', -1, 32)
+	case Double:
+		return strconv.FormatFloat(tv.Value.(float64), 'g', -1, 64)
+	case Integer64:
+		return strconv.FormatInt(tv.Value.(int64), base10)
+	case Unsigned64:
+		return strconv.FormatUint(tv.Value.(uint64), base10)
 
 	case EndOfMib:
 		return "End of Mib"
@@ -189,6 +211,8 @@ func (tv *TypedValue) String() string {
 		return "No such Object"
 	case NoSuchInstance:
 		return "No such Instance"
+	case Null:
+		return "Null"
 	}
 	return fmt.Sprintf("unrecognised data type %d", tv.Type)
 }
@@ -199,13 +223,26 @@ func (tv *TypedValue) OID() asn1.ObjectIdentifier {
 	return tv.Value.(asn1.ObjectIdentifier)
 }
 
+// Delivers value of a typed value as a duration.
+// Value type must be Time, which is measured in hundredths of a second.
+func (tv *TypedValue) Duration() time.Duration {
+	const tick = 10 * time.Millisecond
+	return time.Duration(tv.Value.(uint32)) * tick
+}
+
+// Delivers value of a typed value as a time, decoded as defined by the DateAndTime textual convention.
+// Value type must be OctetString.
+func (tv *TypedValue) DateAndTime() (time.Time, error) {
+	return ParseDateAndTime(tv.Value.([]byte))
+}
+
 // Delivers value of a typed value as an int.
 // Value type must be integer-based.
 func (tv *TypedValue) Int() int {
 	switch tv.Type { //nolint:exhaustive
-	case Integer:
+	case Integer, Integer64:
 		return int(tv.Value.(int64))
-	case Counter64:
+	case Counter64, Unsigned64:
 		return int(tv.Value.(uint64))
 	case Counter32, Gauge32, Time:
 		return int(tv.Value.(uint32))
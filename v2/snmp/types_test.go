@@ -64,6 +64,48 @@ func TestUnmarshalVariable(t *testing.T) {
 			[]byte{0xff, 0xfe, 0xfd},
 			false,
 		},
+		{
+			"OpaqueFloat", &asn1.RawValue{
+				Tag: resolvedOpaqueTag, Class: asn1.ClassApplication,
+				FullBytes: []byte{opaqueTag, 7, 0x9f, 0x78, 4, 0x3f, 0xc0, 0x00, 0x00},
+			},
+			Float, float64(1.5), false,
+		},
+		{
+			"OpaqueDouble", &asn1.RawValue{
+				Tag: resolvedOpaqueTag, Class: asn1.ClassApplication,
+				FullBytes: []byte{opaqueTag, 11, 0x9f, 0x79, 8, 0xc0, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18},
+			},
+			Double, -3.141592653589793, false,
+		},
+		{
+			"OpaqueInteger64", &asn1.RawValue{
+				Tag: resolvedOpaqueTag, Class: asn1.ClassApplication,
+				FullBytes: []byte{opaqueTag, 5, 0x9f, 0x7a, 2, 0xff, 0x38},
+			},
+			Integer64, int64(-200), false,
+		},
+		{
+			"OpaqueUnsigned64", &asn1.RawValue{
+				Tag: resolvedOpaqueTag, Class: asn1.ClassApplication,
+				FullBytes: []byte{opaqueTag, 12, 0x9f, 0x7b, 9, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe},
+			},
+			Unsigned64, uint64(0xfffffffffffffffe), false,
+		},
+		{
+			"OpaqueUnknownSubType", &asn1.RawValue{
+				Tag: resolvedOpaqueTag, Class: asn1.ClassApplication,
+				FullBytes: []byte{opaqueTag, 4, 0x9f, 0x7c, 1, 0x01},
+			},
+			Opaque, []byte{0x9f, 0x7c, 1, 0x01}, false,
+		},
+		{
+			"OpaqueInvalidFloat", &asn1.RawValue{
+				Tag: resolvedOpaqueTag, Class: asn1.ClassApplication,
+				FullBytes: []byte{opaqueTag, 5, 0x9f, 0x78, 2, 0x3f, 0xc0},
+			},
+			Opaque, []byte{0x9f, 0x78, 2, 0x3f, 0xc0}, false,
+		},
 		{
 			"EndOfMib", &asn1.RawValue{Tag: resolvedEndOfMibTag, Class: asn1.ClassContextSpecific, FullBytes: []byte{endOfMibTag, 0}},
 			EndOfMib, nil, false,
@@ -126,8 +168,12 @@ func TestTypedVariableStringRepresentation(t *testing.T) {
 		{"IpAddress", &TypedValue{IPAdddress, []uint8{0x0a, 0x12, 0x55, 0x27}}, "10.18.85.39"},
 		{"Counter64", &TypedValue{Counter64, uint64(91919111919)}, "91919111919"},
 		{"Counter32", &TypedValue{Counter32, uint32(29292)}, "29292"},
-		{"Time", &TypedValue{Time, uint32(18532)}, "185.32ms"},
+		{"Time", &TypedValue{Time, uint32(18532)}, "0:03:05.32"},
+		{"TimeDays", &TypedValue{Time, uint32(2322054929)}, "268 days, 18:09:09.29"},
 		{"Opaque", &TypedValue{Opaque, []uint8{0x01, 0xFF, 0xFE}}, "01fffe"},
+		{"Float", &TypedValue{Float, float64(float32(1.1))}, "1.1"},
+		{"Double", &TypedValue{Double, 1.1}, "1.1"},
+		{"Integer64", &TypedValue{Integer64, int64(-9007199254740993)}, "-9007199254740993"},
 		{"EndOfMib", &TypedValue{EndOfMib, nil}, "End of Mib"},
 		{"NoSuchObject", &TypedValue{NoSuchObject, nil}, "No such Object"},
 		{"NoSuchInstance", &TypedValue{NoSuchInstance, nil}, "No such Instance"},
@@ -0,0 +1,85 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrWalkInconsistent is returned by Walk and BulkWalk when consistency checking is enabled, and the variables
+// walked changed between the walk and its verification on every attempt. The variables of the final attempt are
+// delivered to the walker before the error is returned.
+var ErrWalkInconsistent = errors.New("walk inconsistent: table changed during walk")
+
+// Used to terminate a verification pass once a difference has been found.
+var errWalkChanged = errors.New("walk changed")
+
+// Records the status reported by the agent that ended a walk.
+type walkStatus struct {
+	vb  *Varbind
+	err error
+}
+
+type walkConsistencyConfig struct {
+	retries int
+}
+
+// Walks the root oid, then walks it again to verify that the same variables, such as the rows of a table, are
+// present, retrying the walk as configured until they are. The variables of the walk are only delivered to the
+// walker once the walk has been verified, or the retries are exhausted.
+// A status reported by the agent ends each walk; it is delivered to the walker following the variables, and a walker
+// that returns ErrRetryVarbind terminates the walk with that error, since the walk has already completed.
+func (m *sessionImpl) executeConsistentWalk(ctx context.Context, mType messageType, maxRepetitions int, rootOid string,
+	walker StatusWalker, progress *walkProgressReporter) error {
+	for attempt := 0; ; attempt++ {
+		var varbinds []*Varbind
+		var status *walkStatus
+		err := m.walk(ctx, mType, maxRepetitions, rootOid, func(vb *Varbind, err error) error {
+			if err != nil {
+				status = &walkStatus{vb: vb, err: err}
+				return nil
+			}
+			varbinds = append(varbinds, vb)
+			return nil
+		}, progress)
+		if err != nil {
+			return err
+		}
+
+		// Only the oids are compared, as values such as counters are expected to change.
+		verified := 0
+		err = m.walk(ctx, mType, maxRepetitions, rootOid, func(vb *Varbind, err error) error {
+			if err != nil {
+				return nil
+			}
+			if verified == len(varbinds) || !vb.OID.Equal(varbinds[verified].OID) {
+				return errWalkChanged
+			}
+			verified++
+			return nil
+		}, progress)
+		if err != nil && err != errWalkChanged {
+			return err
+		}
+
+		consistent := err == nil && verified == len(varbinds)
+		if !consistent {
+			m.config.trace.WalkInconsistent(m.config, rootOid, attempt)
+		}
+		if consistent || attempt >= m.config.consistency.retries {
+			for _, vb := range varbinds {
+				if err = walker(vb, nil); err != nil {
+					return err
+				}
+			}
+			if status != nil {
+				if err = walker(status.vb, status.err); err != nil {
+					return err
+				}
+			}
+			if !consistent {
+				return ErrWalkInconsistent
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+// Delivers a session to an agent serving a table whose row count is defined by rows, given the number of
+// requests completed by the session. Each bulk walk of the table is completed by a single request.
+func newMutatingTableSession(t *testing.T, rows func(requests int32) int, retries int) (Session, *[]int) {
+	var requests int32
+	s := newTestAgentServer(t, ServeTable(ifEntry, []int{1}, func() []asn1.ObjectIdentifier {
+		var indexes []asn1.ObjectIdentifier
+		for i := 1; i <= rows(atomic.LoadInt32(&requests)); i++ {
+			indexes = append(indexes, asn1.ObjectIdentifier{i})
+		}
+		return indexes
+	}, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		return &TypedValue{Type: Integer, Value: int64(oid[len(oid)-1])}, nil
+	}))
+
+	var inconsistent []int
+	trace := &SessionTrace{
+		RequestDone: func(config *SessionConfig, pdu *PDU, err error, start time.Time, d time.Duration) {
+			atomic.AddInt32(&requests, 1)
+		},
+		WalkInconsistent: func(config *SessionConfig, rootOid string, attempt int) {
+			assert.Equal(t, ifEntry, rootOid)
+			inconsistent = append(inconsistent, attempt)
+		},
+	}
+	return newTestSession(t, s, LoggingHooks(trace), WalkConsistencyCheck(retries)), &inconsistent
+}
+
+func walkedRows(t *testing.T, ses Session) ([]int64, error) {
+	var values []int64
+	err := ses.BulkWalk(context.Background(), ifEntry, 20, func(vb *Varbind) error {
+		if vb.TypedValue.Type != EndOfMib {
+			values = append(values, vb.TypedValue.Value.(int64))
+		}
+		return nil
+	})
+	return values, err
+}
+
+func TestWalkConsistent(t *testing.T) {
+	ses, inconsistent := newMutatingTableSession(t, func(requests int32) int { return 2 }, 1)
+
+	values, err := walkedRows(t, ses)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, values)
+	assert.Empty(t, *inconsistent)
+}
+
+func TestWalkConsistencyRetry(t *testing.T) {
+	// A row is added once the first walk has completed, so is seen by its verification.
+	ses, inconsistent := newMutatingTableSession(t, func(requests int32) int {
+		if requests == 0 {
+			return 2
+		}
+		return 3
+	}, 1)
+
+	values, err := walkedRows(t, ses)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values, "Expecting rows of the retried walk")
+	assert.Equal(t, []int{0}, *inconsistent)
+}
+
+func TestWalkInconsistent(t *testing.T) {
+	// A row is added and removed by alternate walks.
+	ses, inconsistent := newMutatingTableSession(t, func(requests int32) int {
+		return 2 + int(requests%2)
+	}, 2)
+
+	values, err := walkedRows(t, ses)
+	assert.Equal(t, ErrWalkInconsistent, err)
+	assert.Equal(t, []int64{1, 2}, values, "Expecting rows of the final attempt")
+	assert.Equal(t, []int{0, 1, 2}, *inconsistent)
+
+	// Rows removed are also detected.
+	ses, inconsistent = newMutatingTableSession(t, func(requests int32) int {
+		return 3 - int(requests%2)
+	}, 0)
+	values, err = walkedRows(t, ses)
+	assert.Equal(t, ErrWalkInconsistent, err)
+	assert.Equal(t, []int64{1, 2, 3}, values)
+	assert.Equal(t, []int{0}, *inconsistent)
+}
@@ -0,0 +1,143 @@
+package snmp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// WalkPlan defines a set of scalar objects and table columns retrieved together by BulkWalkPlan, in the manner of
+// efficient pollers that gather scalars such as sysUpTime in the same GetBulk request as the columns of a table.
+// Scalars are requested as the non-repeaters of the first request, and columns as its repeaters, so that the
+// columns are walked in parallel, each request retrieving maxRepetitions rows.
+// A plan holds no walk state, so may be reused and shared.
+type WalkPlan struct {
+	scalars []planEntry
+	columns []planEntry
+}
+
+type planEntry struct {
+	oid    string
+	walker Walker
+}
+
+// NewWalkPlan delivers an empty plan.
+func NewWalkPlan() *WalkPlan {
+	return &WalkPlan{}
+}
+
+// Scalar adds the scalar object oid, for example sysUpTime 1.3.6.1.2.1.1.3, to the plan. The walker is called once
+// with the instance oid.0; if the agent has no instance, it is delivered with a NoSuchObject value.
+func (p *WalkPlan) Scalar(oid string, walker Walker) *WalkPlan {
+	p.scalars = append(p.scalars, planEntry{oid: oid, walker: walker})
+	return p
+}
+
+// Column adds the table column rootOid, for example ifDescr 1.3.6.1.2.1.2.2.1.2, to the plan. The walker is called
+// for each instance of the column, in order.
+func (p *WalkPlan) Column(rootOid string, walker Walker) *WalkPlan {
+	p.columns = append(p.columns, planEntry{oid: rootOid, walker: walker})
+	return p
+}
+
+// The walk state of a column.
+type columnWalk struct {
+	planEntry
+	next string
+}
+
+func (m *sessionImpl) BulkWalkPlan(ctx context.Context, plan *WalkPlan, maxRepetitions int) error {
+	if len(plan.scalars) == 0 && len(plan.columns) == 0 {
+		return errors.New("walk plan is empty")
+	}
+	scalars := plan.scalars
+	active := make([]*columnWalk, len(plan.columns))
+	for i := range plan.columns {
+		active[i] = &columnWalk{planEntry: plan.columns[i], next: plan.columns[i].oid}
+	}
+
+	for len(scalars) > 0 || len(active) > 0 {
+		oids := make([]string, 0, len(scalars)+len(active))
+		for _, s := range scalars {
+			oids = append(oids, s.oid)
+		}
+		for _, c := range active {
+			oids = append(oids, c.next)
+		}
+		pdu, err := m.executeGet(ctx, getBulkMessage, oids, len(scalars), maxRepetitions)
+		if err != nil {
+			return err
+		}
+		if pdu.Error != noError {
+			// Request fewer repetitions if the response would have been too big.
+			if ErrorStatus(pdu.Error) == TooBig && maxRepetitions > 1 {
+				maxRepetitions /= 2
+				continue
+			}
+			_, status := responseStatus(pdu, "")
+			return status
+		}
+		if len(pdu.VarbindList) < len(scalars) || (len(scalars) == 0 && len(pdu.VarbindList) == 0) {
+			// Request fewer repetitions if the agent truncated the response before delivering a row.
+			if pdu.Truncated && maxRepetitions > 1 {
+				maxRepetitions /= 2
+				continue
+			}
+			return fmt.Errorf("insufficient variable bindings in response to walk plan request for %v", oids)
+		}
+
+		for i, s := range scalars {
+			if err = s.walker(scalarVarbind(&pdu.VarbindList[i], s.oid)); err != nil {
+				return err
+			}
+		}
+		if active, err = deliverRows(active, pdu.VarbindList[len(scalars):]); err != nil {
+			return err
+		}
+		scalars = nil
+	}
+	return nil
+}
+
+// Delivers the variable binding returned for a scalar object, or a NoSuchObject binding for its instance if the
+// variable returned is not an instance of the object.
+func scalarVarbind(vb *Varbind, oid string) *Varbind {
+	if isOidDescendantOfRoot(vb.OID, oid) {
+		return vb
+	}
+	instance, _ := parseOID(oid + ".0")
+	return &Varbind{OID: instance, TypedValue: &TypedValue{Type: NoSuchObject}}
+}
+
+// Delivers the rows of repeaters to the walkers of the active columns, in the order they were requested, and
+// delivers the columns that remain active. A column ends with the first variable that is not one of its instances.
+func deliverRows(active []*columnWalk, repeaters []Varbind) ([]*columnWalk, error) {
+	if len(active) == 0 {
+		return active, nil
+	}
+	ended := make([]bool, len(active))
+	for i := range repeaters {
+		col := i % len(active)
+		if ended[col] {
+			continue
+		}
+		c, vb := active[col], &repeaters[i]
+		if vb.TypedValue.Type == EndOfMib || !isOidDescendantOfRoot(vb.OID, c.oid) {
+			ended[col] = true
+			continue
+		}
+		if err := c.walker(vb); err != nil {
+			return nil, err
+		}
+		c.next = vb.OID.String()
+	}
+
+	remaining := active[:0]
+	for i, c := range active {
+		if !ended[i] {
+			remaining = append(remaining, c)
+		}
+	}
+	return remaining, nil
+}
@@ -0,0 +1,75 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestBulkWalkPlan(t *testing.T) {
+	ses := newTestAgent(t)
+
+	for _, maxRepetitions := range []int{1, 10} {
+		var scalars, indexes, names []string
+		recordScalar := func(vb *Varbind) error {
+			scalars = append(scalars, fmt.Sprintf("%s=%d", vb.OID, vb.TypedValue.Type))
+			return nil
+		}
+		plan := NewWalkPlan().
+			Scalar(sysDescr, recordScalar).
+			Scalar("1.3.6.1.2.1.1.99", recordScalar).
+			Column(ifEntry+".1", func(vb *Varbind) error {
+				indexes = append(indexes, fmt.Sprintf("%s=%d", vb.OID, vb.TypedValue.Value))
+				return nil
+			}).
+			Column(ifEntry+".2", func(vb *Varbind) error {
+				names = append(names, fmt.Sprintf("%s=%s", vb.OID, vb.TypedValue.Value))
+				return nil
+			})
+
+		assert.NoError(t, ses.BulkWalkPlan(context.Background(), plan, maxRepetitions))
+		assert.Equal(t, []string{
+			fmt.Sprintf("%s.0=%d", sysDescr, OctetString), fmt.Sprintf("1.3.6.1.2.1.1.99.0=%d", NoSuchObject),
+		}, scalars)
+		assert.Equal(t, []string{ifEntry + ".1.1=1", ifEntry + ".1.2=2"}, indexes)
+		assert.Equal(t, []string{ifEntry + ".2.1=eth1", ifEntry + ".2.2=eth2"}, names)
+	}
+}
+
+func TestBulkWalkPlanColumnsOnly(t *testing.T) {
+	ses := newTestAgent(t)
+
+	var oids []string
+	plan := NewWalkPlan().Column(ifEntry+".2", func(vb *Varbind) error {
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	assert.NoError(t, ses.BulkWalkPlan(context.Background(), plan, 1))
+	assert.Equal(t, []string{ifEntry + ".2.1", ifEntry + ".2.2"}, oids, "Expecting the walk to end at the last column")
+}
+
+func TestBulkWalkPlanErrors(t *testing.T) {
+	ses := newTestAgent(t)
+
+	err := ses.BulkWalkPlan(context.Background(), NewWalkPlan(), 10)
+	assert.EqualError(t, err, "walk plan is empty")
+
+	stop := errors.New("stop")
+	var delivered int
+	plan := NewWalkPlan().Column(ifEntry+".1", func(vb *Varbind) error {
+		delivered++
+		return stop
+	})
+	assert.ErrorIs(t, ses.BulkWalkPlan(context.Background(), plan, 10), stop)
+	assert.Equal(t, 1, delivered)
+
+	// The provider of sysName fails, so the agent reports a genErr.
+	plan = NewWalkPlan().Scalar("1.3.6.1.2.1.1.4", func(vb *Varbind) error { return nil })
+	err = ses.BulkWalkPlan(context.Background(), plan, 10)
+	var verr *VarbindError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, GenErr, verr.Status)
+}
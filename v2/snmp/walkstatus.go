@@ -0,0 +1,110 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// ErrorStatus defines the error-status values reported by an agent in a response, as defined by
+// https://tools.ietf.org/html/rfc3416#section-3.
+type ErrorStatus int
+
+const (
+	NoError ErrorStatus = iota
+	TooBig
+	NoSuchName
+	BadValue
+	ReadOnly
+	GenErr
+	NoAccess
+	WrongType
+	WrongLength
+	WrongEncoding
+	WrongValue
+	NoCreation
+	InconsistentValue
+	ResourceUnavailable
+	CommitFailed
+	UndoFailed
+	AuthorizationError
+	NotWritable
+	InconsistentName
+)
+
+var errorStatusNames = []string{
+	"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr", "noAccess", "wrongType", "wrongLength",
+	"wrongEncoding", "wrongValue", "noCreation", "inconsistentValue", "resourceUnavailable", "commitFailed",
+	"undoFailed", "authorizationError", "notWritable", "inconsistentName",
+}
+
+func (s ErrorStatus) String() string {
+	if s >= 0 && int(s) < len(errorStatusNames) {
+		return errorStatusNames[s]
+	}
+	return fmt.Sprintf("errorStatus(%d)", int(s))
+}
+
+// VarbindError is the status delivered to a StatusWalker when the agent reports an error-status in response to a
+// walk request, and the cause reported by a ConfigSetter when the agent rejects a request.
+type VarbindError struct {
+	// The OID of the variable binding identified by the error-index; for a walk, this is the OID that was requested,
+	// rather than the OID of the variable that could not be retrieved.
+	OID asn1.ObjectIdentifier
+	// The error-status reported by the agent.
+	Status ErrorStatus
+	// The error-index reported by the agent.
+	Index int
+}
+
+func (e *VarbindError) Error() string {
+	return fmt.Sprintf("agent reported %s for %s", e.Status, e.OID)
+}
+
+// ErrRetryVarbind may be returned by a StatusWalker for a variable binding with a non-nil status, to reissue the
+// request for the variable binding. The walker determines how many times a request is retried.
+var ErrRetryVarbind = errors.New("retry variable binding")
+
+// StatusWalker defines a function that will be called for each variable processed by the WalkWithStatus and
+// BulkWalkWithStatus methods, with a non-nil status if the agent reported an error for the variable binding.
+//
+// For a variable binding with a non-nil status, the walker may return nil to record the gap, in which case the walk
+// ends without error since the agent cannot deliver the variables that follow; ErrRetryVarbind to reissue the
+// request; or any other error to terminate the walk, returning the error.
+type StatusWalker func(vb *Varbind, status error) error
+
+func (m *sessionImpl) WalkWithStatus(ctx context.Context, rootOid string, walker StatusWalker) error {
+	return m.executeWalk(ctx, getNextMessage, 0, rootOid, walker)
+}
+
+func (m *sessionImpl) BulkWalkWithStatus(ctx context.Context, rootOid string, maxRepetitions int,
+	walker StatusWalker) error {
+	return m.executeWalk(ctx, getBulkMessage, maxRepetitions, rootOid, walker)
+}
+
+// Adapts the walker for delivery of statuses. A noSuchName status, with which SNMPv1 agents indicate the end of the
+// MIB view, ends the walk; any other status terminates the walk, returning the *VarbindError.
+func (w Walker) withStatus() StatusWalker {
+	return func(vb *Varbind, status error) error {
+		if status == nil {
+			return w(vb)
+		}
+		var verr *VarbindError
+		if errors.As(status, &verr) && verr.Status == NoSuchName {
+			return nil
+		}
+		return status
+	}
+}
+
+// Delivers the status of the response to a walk request for nextOid, and the variable binding it applies to.
+func responseStatus(pdu *PDU, nextOid string) (*Varbind, *VarbindError) {
+	vb := &Varbind{TypedValue: &TypedValue{Type: Null}}
+	if i := pdu.ErrorIndex - 1; i >= 0 && i < len(pdu.VarbindList) {
+		vb = &pdu.VarbindList[i]
+	} else if oid, err := parseOID(nextOid); err == nil {
+		vb.OID = oid
+	}
+	return vb, &VarbindError{OID: vb.OID, Status: ErrorStatus(pdu.Error), Index: pdu.ErrorIndex}
+}
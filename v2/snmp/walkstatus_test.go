@@ -0,0 +1,122 @@
+package snmp
+
+import (
+	"context"
+	"encoding/asn1"
+	"errors"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+const systemGroup = "1.3.6.1.2.1.1"
+
+func newStatusTestAgent(t *testing.T, provider VariableProvider, opts ...SessionOption) Session {
+	s := newTestAgentServer(t,
+		ServeScalar(sysDescr, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+			return &TypedValue{Type: OctetString, Value: []byte("test agent")}, nil
+		}),
+		ServeScalar(sysName, provider))
+	return newTestSession(t, s, opts...)
+}
+
+func failingProvider(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+	return nil, errors.New("provider failed")
+}
+
+func TestWalkErrorStatus(t *testing.T) {
+	ses := newStatusTestAgent(t, failingProvider)
+
+	var oids []string
+	err := ses.Walk(context.Background(), systemGroup, func(vb *Varbind) error {
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	var verr *VarbindError
+	assert.True(t, errors.As(err, &verr), "Expecting walk to be terminated by the error status")
+	assert.Equal(t, GenErr, verr.Status)
+	assert.Equal(t, 1, verr.Index)
+	assert.Equal(t, "agent reported genErr for "+sysDescr+".0", err.Error())
+	assert.Equal(t, []string{sysDescr + ".0"}, oids, "Expecting variable to be delivered once")
+
+	// The walker records the gap, ending the walk.
+	var statuses []error
+	oids = nil
+	err = ses.BulkWalkWithStatus(context.Background(), systemGroup, 1, func(vb *Varbind, status error) error {
+		if status != nil {
+			statuses = append(statuses, status)
+			assert.Equal(t, Null, vb.TypedValue.Type)
+			return nil
+		}
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{sysDescr + ".0"}, oids)
+	assert.Equal(t, []error{&VarbindError{OID: asn1.ObjectIdentifier{1, 3, 6, 1, 2, 1, 1, 1, 0}, Status: GenErr,
+		Index: 1}}, statuses)
+}
+
+func TestWalkErrorStatusRetry(t *testing.T) {
+	failures := 2
+	ses := newStatusTestAgent(t, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		if failures > 0 {
+			failures--
+			return nil, errors.New("provider failed")
+		}
+		return &TypedValue{Type: OctetString, Value: []byte("test")}, nil
+	})
+
+	var oids []string
+	retries := 0
+	err := ses.WalkWithStatus(context.Background(), systemGroup, func(vb *Varbind, status error) error {
+		if status != nil {
+			retries++
+			return ErrRetryVarbind
+		}
+		if vb.TypedValue.Type != EndOfMib {
+			oids = append(oids, vb.OID.String())
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, []string{sysDescr + ".0", sysName + ".0"}, oids)
+}
+
+func TestWalkErrorStatusConsistencyCheck(t *testing.T) {
+	ses := newStatusTestAgent(t, failingProvider, WalkConsistencyCheck(1))
+
+	var delivered []string
+	err := ses.WalkWithStatus(context.Background(), systemGroup, func(vb *Varbind, status error) error {
+		if status != nil {
+			delivered = append(delivered, status.Error())
+			return nil
+		}
+		delivered = append(delivered, vb.OID.String())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{sysDescr + ".0", "agent reported genErr for " + sysDescr + ".0"}, delivered,
+		"Expecting status to be delivered after the variables")
+}
+
+func TestWalkV1EndOfMibView(t *testing.T) {
+	ses := newStatusTestAgent(t, func(oid asn1.ObjectIdentifier) (*TypedValue, error) {
+		return &TypedValue{Type: OctetString, Value: []byte("test")}, nil
+	}, WithVersion(SNMPV1))
+
+	var oids []string
+	err := ses.Walk(context.Background(), systemGroup, func(vb *Varbind) error {
+		oids = append(oids, vb.OID.String())
+		return nil
+	})
+	assert.NoError(t, err, "Expecting noSuchName to end the walk")
+	assert.Equal(t, []string{sysDescr + ".0", sysName + ".0"}, oids)
+}
+
+func TestErrorStatusString(t *testing.T) {
+	assert.Equal(t, "noSuchName", NoSuchName.String())
+	assert.Equal(t, "inconsistentName", InconsistentName.String())
+	assert.Equal(t, "errorStatus(99)", ErrorStatus(99).String())
+}
@@ -0,0 +1,235 @@
+package target
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyPolicy defines how the host keys presented by devices are verified when establishing ssh connections, for
+// both netconf and cli sessions.
+type HostKeyPolicy interface {
+	// HostKeyCallback delivers the callback used to verify host keys.
+	HostKeyCallback() (ssh.HostKeyCallback, error)
+}
+
+// HostKeyPolicyFunc allows a function to be used as a HostKeyPolicy.
+type HostKeyPolicyFunc func() (ssh.HostKeyCallback, error)
+
+// HostKeyCallback calls f().
+func (f HostKeyPolicyFunc) HostKeyCallback() (ssh.HostKeyCallback, error) {
+	return f()
+}
+
+// InsecureHostKeys delivers a policy that accepts any host key. It should only be used for testing.
+func InsecureHostKeys() HostKeyPolicy {
+	return HostKeyPolicyFunc(func() (ssh.HostKeyCallback, error) {
+		return ssh.InsecureIgnoreHostKey(), nil //nolint: gosec
+	})
+}
+
+// KnownHostsFiles delivers a policy that verifies host keys against the OpenSSH known_hosts files. The files are
+// read each time the policy is applied, so that changes are seen by subsequent connections.
+func KnownHostsFiles(files ...string) HostKeyPolicy {
+	return HostKeyPolicyFunc(func() (ssh.HostKeyCallback, error) {
+		cb, err := knownhosts.New(files...)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read known hosts")
+		}
+		return cb, nil
+	})
+}
+
+// HostKeyStore holds the fingerprints of the host keys pinned for hosts, identified in the form used by the
+// known_hosts file, which is the host name or address, qualified by the port if it is not 22, such as
+// [10.0.0.1]:830. Fingerprints are in the SHA256 form delivered by ssh.FingerprintSHA256.
+type HostKeyStore interface {
+	// Lookup delivers the fingerprints pinned for the host, which are empty if the host is not known.
+	Lookup(host string) ([]string, error)
+	// Pin adds the fingerprint to those pinned for the host.
+	Pin(host, fingerprint string) error
+}
+
+// HostKeyError is returned when a host key cannot be verified by a pinning policy.
+type HostKeyError struct {
+	// The host, in the form used by HostKeyStore.
+	Host string
+	// The fingerprint of the key presented by the host.
+	Fingerprint string
+	// The fingerprints pinned for the host, empty if the host is not known.
+	Pinned []string
+}
+
+func (e *HostKeyError) Error() string {
+	if len(e.Pinned) == 0 {
+		return fmt.Sprintf("host key %s of %s is not pinned", e.Fingerprint, e.Host)
+	}
+	return fmt.Sprintf("host key %s of %s does not match pinned keys %s", e.Fingerprint, e.Host,
+		strings.Join(e.Pinned, ", "))
+}
+
+// FirstUseCallback is called when a host with no pinned keys presents its host key to a policy defined with
+// LearnOnFirstUse. The key is pinned if nil is returned, and otherwise the connection is rejected with the error.
+type FirstUseCallback func(host string, remote net.Addr, key ssh.PublicKey) error
+
+// PinOption implements options for configuring a pinning policy.
+type PinOption func(*pinPolicy)
+
+// LearnOnFirstUse pins the key presented by a host with no pinned keys, after it has been accepted by the callback,
+// which may be nil to accept all keys. Keys presented by hosts that have pinned keys must match them.
+func LearnOnFirstUse(callback FirstUseCallback) PinOption {
+	return func(p *pinPolicy) {
+		p.learn = true
+		p.firstUse = callback
+	}
+}
+
+// PinnedHostKeys delivers a policy that accepts the host keys whose fingerprints are pinned for the host in the store.
+// By default, hosts with no pinned keys are rejected; see LearnOnFirstUse.
+func PinnedHostKeys(store HostKeyStore, opts ...PinOption) HostKeyPolicy {
+	p := &pinPolicy{store: store}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+type pinPolicy struct {
+	store    HostKeyStore
+	learn    bool
+	firstUse FirstUseCallback
+	// Serialises learning, so that concurrent connections to a new host pin a single key.
+	mu sync.Mutex
+}
+
+func (p *pinPolicy) HostKeyCallback() (ssh.HostKeyCallback, error) {
+	return p.verify, nil
+}
+
+func (p *pinPolicy) verify(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	host := knownhosts.Normalize(hostname)
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	if p.learn {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+	}
+	pinned, err := p.store.Lookup(host)
+	if err != nil {
+		return errors.Wrapf(err, "failed to look up pinned host keys of %s", host)
+	}
+	for _, pin := range pinned {
+		if pin == fingerprint {
+			return nil
+		}
+	}
+	if len(pinned) > 0 || !p.learn {
+		return &HostKeyError{Host: host, Fingerprint: fingerprint, Pinned: pinned}
+	}
+
+	if p.firstUse != nil {
+		if err = p.firstUse(host, remote, key); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(p.store.Pin(host, fingerprint), "failed to pin host key of %s", host)
+}
+
+// MemoryHostKeyStore is a HostKeyStore that holds fingerprints in memory. It is safe for concurrent use.
+type MemoryHostKeyStore struct {
+	mu   sync.Mutex
+	pins map[string][]string
+}
+
+// NewMemoryHostKeyStore delivers a store holding the fingerprints, keyed by host, which may be nil.
+func NewMemoryHostKeyStore(pins map[string][]string) *MemoryHostKeyStore {
+	s := &MemoryHostKeyStore{pins: map[string][]string{}}
+	for host, fingerprints := range pins {
+		s.pins[host] = append([]string(nil), fingerprints...)
+	}
+	return s
+}
+
+// Lookup delivers the fingerprints pinned for the host.
+func (s *MemoryHostKeyStore) Lookup(host string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.pins[host]...), nil
+}
+
+// Pin adds the fingerprint to those pinned for the host.
+func (s *MemoryHostKeyStore) Pin(host, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pins[host] = append(s.pins[host], fingerprint)
+	return nil
+}
+
+// FileHostKeyStore is a HostKeyStore that holds fingerprints in a file, with a line for each pinned key holding the
+// host and fingerprint separated by whitespace. Blank lines and lines beginning with # are ignored. It is safe for
+// concurrent use within a process.
+type FileHostKeyStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileHostKeyStore delivers a store holding fingerprints in the file at path, which is created when the first key
+// is pinned if it does not exist.
+func NewFileHostKeyStore(path string) *FileHostKeyStore {
+	return &FileHostKeyStore{path: path}
+}
+
+// Lookup delivers the fingerprints pinned for the host.
+func (s *FileHostKeyStore) Lookup(host string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pinned []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("%s:%d: expected host and fingerprint", s.path, line)
+		}
+		if fields[0] == host {
+			pinned = append(pinned, fields[1])
+		}
+	}
+	return pinned, scanner.Err()
+}
+
+// Pin appends the fingerprint for the host to the file.
+func (s *FileHostKeyStore) Pin(host, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(f, "%s %s\n", host, fingerprint); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
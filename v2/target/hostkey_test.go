@@ -0,0 +1,118 @@
+package target
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var remoteAddr = &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 830}
+
+func generatePublicKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestPinnedHostKeys(t *testing.T) {
+	key, other := generatePublicKey(t), generatePublicKey(t)
+	store := NewMemoryHostKeyStore(map[string][]string{"[10.0.0.1]:830": {ssh.FingerprintSHA256(key)}})
+
+	cb, err := PinnedHostKeys(store).HostKeyCallback()
+	assert.NoError(t, err)
+	assert.NoError(t, cb("10.0.0.1:830", remoteAddr, key))
+
+	err = cb("10.0.0.1:830", remoteAddr, other)
+	var hkerr *HostKeyError
+	assert.ErrorAs(t, err, &hkerr)
+	assert.Equal(t, "[10.0.0.1]:830", hkerr.Host)
+	assert.Equal(t, ssh.FingerprintSHA256(other), hkerr.Fingerprint)
+	assert.Equal(t, []string{ssh.FingerprintSHA256(key)}, hkerr.Pinned)
+
+	err = cb("10.0.0.2:22", remoteAddr, key)
+	assert.EqualError(t, err, "host key "+ssh.FingerprintSHA256(key)+" of 10.0.0.2 is not pinned")
+}
+
+func TestLearnOnFirstUse(t *testing.T) {
+	key, other := generatePublicKey(t), generatePublicKey(t)
+	store := NewMemoryHostKeyStore(nil)
+
+	var learned []string
+	cb, err := PinnedHostKeys(store, LearnOnFirstUse(func(host string, remote net.Addr, key ssh.PublicKey) error {
+		learned = append(learned, host)
+		if host == "rejected" {
+			return errors.New("operator declined")
+		}
+		return nil
+	})).HostKeyCallback()
+	assert.NoError(t, err)
+
+	assert.NoError(t, cb("router:22", remoteAddr, key))
+	assert.NoError(t, cb("router:22", remoteAddr, key), "Expecting the learned key to be accepted")
+	assert.Error(t, cb("router:22", remoteAddr, other), "Expecting a different key to be rejected")
+	assert.Equal(t, []string{"router"}, learned)
+
+	assert.EqualError(t, cb("rejected:22", remoteAddr, key), "operator declined")
+	pinned, _ := store.Lookup("rejected")
+	assert.Empty(t, pinned)
+}
+
+func TestFileHostKeyStore(t *testing.T) {
+	key := generatePublicKey(t)
+	path := filepath.Join(t.TempDir(), "pins")
+	store := NewFileHostKeyStore(path)
+
+	pinned, err := store.Lookup("router")
+	assert.NoError(t, err)
+	assert.Empty(t, pinned)
+
+	cb, err := PinnedHostKeys(store, LearnOnFirstUse(nil)).HostKeyCallback()
+	assert.NoError(t, err)
+	assert.NoError(t, cb("router:22", remoteAddr, key))
+	assert.NoError(t, store.Pin("switch", "SHA256:abc"))
+
+	pinned, err = NewFileHostKeyStore(path).Lookup("router")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ssh.FingerprintSHA256(key)}, pinned)
+
+	assert.NoError(t, os.WriteFile(path, []byte("# comment\n\nrouter\n"), 0o600))
+	_, err = store.Lookup("router")
+	assert.EqualError(t, err, path+":3: expected host and fingerprint")
+}
+
+func TestKnownHostsFiles(t *testing.T) {
+	key, other := generatePublicKey(t), generatePublicKey(t)
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	assert.NoError(t, os.WriteFile(path, []byte(knownhosts.Line([]string{"[10.0.0.1]:830"}, key)+"\n"), 0o600))
+
+	cb, err := KnownHostsFiles(path).HostKeyCallback()
+	assert.NoError(t, err)
+	assert.NoError(t, cb("10.0.0.1:830", remoteAddr, key))
+	assert.Error(t, cb("10.0.0.1:830", remoteAddr, other))
+
+	_, err = KnownHostsFiles(filepath.Join(t.TempDir(), "missing")).HostKeyCallback()
+	assert.Error(t, err)
+}
+
+func TestSSHClientConfigHostKeyPolicy(t *testing.T) {
+	creds := StaticCredentials(&Credentials{Username: "u"})
+	tgt := &Target{Address: "host", HostKeyPolicy: InsecureHostKeys()}
+	cfg, err := SSHClientConfig(context.Background(), creds, tgt, CLI)
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.HostKeyCallback("host:22", remoteAddr, generatePublicKey(t)))
+
+	tgt.HostKeyPolicy = KnownHostsFiles(filepath.Join(t.TempDir(), "missing"))
+	_, err = SSHClientConfig(context.Background(), creds, tgt, CLI)
+	assert.Error(t, err)
+}
@@ -0,0 +1,159 @@
+// Package target defines the managed devices accessed by the netconf, snmp and cli packages, and the resolution of
+// the credentials used to access them, so that applications can describe a device once for all protocols.
+package target
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// Protocol identifies a management protocol.
+type Protocol string
+
+// Supported protocols.
+const (
+	NETCONF  Protocol = "netconf"
+	SNMP     Protocol = "snmp"
+	CLI      Protocol = "cli"
+	RESTCONF Protocol = "restconf"
+)
+
+// DefaultPorts defines the port used for each protocol when the target does not define one.
+var DefaultPorts = map[Protocol]int{
+	NETCONF:  830,
+	SNMP:     161,
+	CLI:      22,
+	RESTCONF: 443,
+}
+
+// Target defines a managed device.
+type Target struct {
+	// The host name or IP address of the device.
+	Address string
+	// The port used for each protocol, where it differs from DefaultPorts.
+	Ports map[Protocol]int
+	// The protocols supported by the device, in order of preference. Empty means all protocols, with no preference.
+	Protocols []Protocol
+	// Identifies the credentials of the device to a CredentialResolver, for example a vault path. Empty means the
+	// resolver identifies the credentials from the address.
+	CredentialRef string
+	// Verifies the host key of the device for protocols carried over ssh. Either HostKeyCallback or HostKeyPolicy
+	// must be defined to establish ssh connections; HostKeyCallback takes precedence.
+	HostKeyCallback ssh.HostKeyCallback
+	// Defines how the host key of the device is verified, typically shared by all targets.
+	HostKeyPolicy HostKeyPolicy
+}
+
+// Port delivers the port used to access the target with the protocol.
+func (t *Target) Port(p Protocol) int {
+	if port, ok := t.Ports[p]; ok {
+		return port
+	}
+	return DefaultPorts[p]
+}
+
+// Endpoint delivers the address and port, in the form host:port, used to access the target with the protocol.
+func (t *Target) Endpoint(p Protocol) string {
+	return net.JoinHostPort(t.Address, strconv.Itoa(t.Port(p)))
+}
+
+// Preferred delivers the protocol preferred by the target amongst those offered, which are in the application's
+// order of preference. False is returned if the target supports none of the protocols.
+func (t *Target) Preferred(offered ...Protocol) (Protocol, bool) {
+	if len(t.Protocols) == 0 {
+		if len(offered) == 0 {
+			return "", false
+		}
+		return offered[0], true
+	}
+	for _, p := range t.Protocols {
+		for _, o := range offered {
+			if p == o {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Credentials defines the credentials used to access a target.
+type Credentials struct {
+	// The user name and password used by protocols carried over ssh, and for http basic authentication by RESTCONF.
+	Username string
+	Password string
+	// Signers used for public key authentication by protocols carried over ssh.
+	Signers []ssh.Signer
+	// The community used by SNMP v1 and v2c.
+	Community string
+}
+
+// CredentialResolver resolves the credentials used to access a target with a protocol, for example by
+// retrieving them from a vault or prompting an operator.
+type CredentialResolver interface {
+	Resolve(ctx context.Context, t *Target, p Protocol) (*Credentials, error)
+}
+
+// CredentialResolverFunc allows a function to be used as a CredentialResolver.
+type CredentialResolverFunc func(ctx context.Context, t *Target, p Protocol) (*Credentials, error)
+
+// Resolve calls f(ctx, t, p).
+func (f CredentialResolverFunc) Resolve(ctx context.Context, t *Target, p Protocol) (*Credentials, error) {
+	return f(ctx, t, p)
+}
+
+// StaticCredentials delivers a resolver that resolves the same credentials for all targets and protocols.
+func StaticCredentials(c *Credentials) CredentialResolver {
+	return CredentialResolverFunc(func(ctx context.Context, t *Target, p Protocol) (*Credentials, error) {
+		return c, nil
+	})
+}
+
+// ResolveCredentials resolves the credentials used to access the target with the protocol.
+func ResolveCredentials(ctx context.Context, r CredentialResolver, t *Target, p Protocol) (*Credentials, error) {
+	c, err := r.Resolve(ctx, t, p)
+	switch {
+	case err != nil:
+		return nil, errors.Wrapf(err, "failed to resolve %s credentials for %s", p, t.Address)
+	case c == nil:
+		return nil, errors.Errorf("no %s credentials for %s", p, t.Address)
+	}
+	return c, nil
+}
+
+// SSHClientConfig delivers the ssh configuration used to access the target with the protocol, using the credentials
+// resolved by r. Public key authentication is offered if signers are resolved, followed by password and
+// keyboard-interactive authentication if a password is resolved. Host keys are verified by the HostKeyCallback or
+// HostKeyPolicy of the target.
+func SSHClientConfig(ctx context.Context, r CredentialResolver, t *Target, p Protocol) (*ssh.ClientConfig, error) {
+	c, err := ResolveCredentials(ctx, r, t, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	if len(c.Signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(c.Signers...))
+	}
+	if c.Password != "" {
+		password := c.Password
+		auth = append(auth, ssh.Password(password),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}))
+	}
+	hostKeyCallback := t.HostKeyCallback
+	if hostKeyCallback == nil && t.HostKeyPolicy != nil {
+		if hostKeyCallback, err = t.HostKeyPolicy.HostKeyCallback(); err != nil {
+			return nil, errors.Wrapf(err, "failed to apply host key policy for %s", t.Address)
+		}
+	}
+	return &ssh.ClientConfig{User: c.Username, Auth: auth, HostKeyCallback: hostKeyCallback}, nil
+}
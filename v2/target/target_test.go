@@ -0,0 +1,80 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestEndpoint(t *testing.T) {
+	tgt := &Target{Address: "10.0.0.1", Ports: map[Protocol]int{NETCONF: 2022}}
+	assert.Equal(t, "10.0.0.1:2022", tgt.Endpoint(NETCONF))
+	assert.Equal(t, "10.0.0.1:161", tgt.Endpoint(SNMP))
+	assert.Equal(t, "10.0.0.1:22", tgt.Endpoint(CLI))
+
+	tgt = &Target{Address: "::1"}
+	assert.Equal(t, "[::1]:830", tgt.Endpoint(NETCONF))
+}
+
+func TestPreferred(t *testing.T) {
+	tgt := &Target{}
+	p, ok := tgt.Preferred(NETCONF, CLI)
+	assert.True(t, ok)
+	assert.Equal(t, NETCONF, p)
+
+	_, ok = tgt.Preferred()
+	assert.False(t, ok)
+
+	tgt = &Target{Protocols: []Protocol{CLI, NETCONF}}
+	p, ok = tgt.Preferred(NETCONF, CLI)
+	assert.True(t, ok)
+	assert.Equal(t, CLI, p)
+
+	_, ok = tgt.Preferred(SNMP)
+	assert.False(t, ok)
+}
+
+func TestResolveCredentials(t *testing.T) {
+	var resolved []Protocol
+	r := CredentialResolverFunc(func(ctx context.Context, t *Target, p Protocol) (*Credentials, error) {
+		resolved = append(resolved, p)
+		switch t.CredentialRef {
+		case "missing":
+			return nil, nil
+		case "failing":
+			return nil, errors.New("vault sealed")
+		}
+		return &Credentials{Username: t.CredentialRef}, nil
+	})
+
+	c, err := ResolveCredentials(context.Background(), r, &Target{Address: "host", CredentialRef: "admin"}, CLI)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", c.Username)
+	assert.Equal(t, []Protocol{CLI}, resolved)
+
+	_, err = ResolveCredentials(context.Background(), r, &Target{Address: "host", CredentialRef: "missing"}, CLI)
+	assert.EqualError(t, err, "no cli credentials for host")
+
+	_, err = ResolveCredentials(context.Background(), r, &Target{Address: "host", CredentialRef: "failing"}, NETCONF)
+	assert.EqualError(t, err, "failed to resolve netconf credentials for host: vault sealed")
+}
+
+func TestSSHClientConfig(t *testing.T) {
+	tgt := &Target{Address: "host", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+
+	cfg, err := SSHClientConfig(context.Background(), StaticCredentials(&Credentials{Username: "u", Password: "p"}), tgt, NETCONF)
+	assert.NoError(t, err)
+	assert.Equal(t, "u", cfg.User)
+	assert.Len(t, cfg.Auth, 2, "Expected password and keyboard-interactive methods")
+	assert.NotNil(t, cfg.HostKeyCallback)
+
+	cfg, err = SSHClientConfig(context.Background(), StaticCredentials(&Credentials{Username: "u"}), tgt, CLI)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Auth)
+
+	_, err = SSHClientConfig(context.Background(), StaticCredentials(nil), tgt, CLI)
+	assert.Error(t, err)
+}